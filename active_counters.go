@@ -0,0 +1,37 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"cmp"
+	"slices"
+)
+
+// ActiveCounters 返回当前已添加到各主机查询中的计数器，按主机和路径排序。
+// 启用 DeferRemoteOpen 时尚未打开查询的远程主机的计数器不包含在内。与采集互斥，会等待正在进行的采集结束。
+func (m *WinPerfCounters) ActiveCounters() []CounterDescriptor {
+	m.gatherLock.Lock()
+	defer m.gatherLock.Unlock()
+
+	var descriptors []CounterDescriptor
+	for _, hostInfo := range m.hostCounters {
+		for _, metric := range hostInfo.counters {
+			descriptors = append(descriptors, CounterDescriptor{
+				Computer:    hostInfo.computer,
+				Source:      hostInfo.tag,
+				Object:      metric.objectName,
+				Instance:    metric.instance,
+				Counter:     metric.name,
+				Field:       metric.counter,
+				Measurement: metric.measurement,
+				Path:        metric.counterPath,
+				Raw:         metric.useRawValue,
+				Quarantined: metric.quarantined || hostInfo.quarantined,
+			})
+		}
+	}
+	slices.SortFunc(descriptors, func(a, b CounterDescriptor) int {
+		return cmp.Or(cmp.Compare(a.Computer, b.Computer), cmp.Compare(a.Path, b.Path))
+	})
+	return descriptors
+}
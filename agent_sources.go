@@ -0,0 +1,77 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// validateAgentSources 校验 Sources 中的远程采集代理，对象的 Sources 中不能使用采集代理。
+func (m *WinPerfCounters) validateAgentSources(sources []string, objects []ObjectConfig) error {
+	for _, source := range sources {
+		if err := validateAgentSource(source); err != nil {
+			return err
+		}
+	}
+	for _, object := range objects {
+		for _, source := range object.Sources {
+			if isAgentSource(source) || validateAgentSource(source) != nil {
+				return fmt.Errorf("agent source %q of object %q is not supported, agents can only be listed in the global Sources", source, object.ObjectName)
+			}
+		}
+	}
+	return nil
+}
+
+// hostSources 返回 Sources 中通过 PDH 或 WMI 直接采集的主机，不包含远程采集代理。
+func (m *WinPerfCounters) hostSources() []string {
+	if !slices.ContainsFunc(m.Sources, isAgentSource) {
+		return m.Sources
+	}
+	return slices.DeleteFunc(slices.Clone(m.Sources), isAgentSource)
+}
+
+// agentSources 返回 Sources 中的远程采集代理。
+func (m *WinPerfCounters) agentSources() []string {
+	var agents []string
+	for _, source := range m.Sources {
+		if isAgentSource(source) {
+			agents = append(agents, source)
+		}
+	}
+	return agents
+}
+
+// gatherAgent 从远程采集代理拉取一次采集的指标并按本地采集的指标分发，指标保留代理输出的标签与时间戳。
+func (m *WinPerfCounters) gatherAgent(ctx context.Context, source string) error {
+	metrics, err := m.agentClient(source).Fetch(ctx)
+	if err != nil {
+		m.stats.incr(map[string]string{"source": source}, "agent_errors", 1)
+		return wrapCounterError("collect", source, "", "", err)
+	}
+	// 已放弃等待的采集不再输出数据
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, metric := range metrics {
+		m.emit(metric.Measurement, metric.Fields, metric.Tags, metric.Timestamp)
+	}
+	return nil
+}
+
+// AgentHandler 返回采集代理的 HTTP 端点，GET 请求执行一次采集并以 JSON 输出本次的全部指标，
+// 供配置了 Sources = ["http://主机:端口"] 的远程采集器拉取，一秒内的重复请求返回上一次采集的结果。
+// 端点本身不做认证，对外提供时需通过 RequireToken 包装并只监听受信任的地址，例如：
+//
+//	curl -H "Authorization: Bearer $TOKEN" "http://127.0.0.1:7090/v1/metrics"
+func (m *WinPerfCounters) AgentHandler() http.Handler {
+	return agentHandler(m.GatherMetrics, agentSnapshotInterval)
+}
+
+// agentClient 返回从远程采集代理 source 拉取指标的客户端，带有 AgentToken。
+func (m *WinPerfCounters) agentClient(source string) *AgentClient {
+	return &AgentClient{URL: source, Token: string(m.AgentToken)}
+}
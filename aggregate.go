@@ -0,0 +1,216 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// validAggregates Aggregate 支持的聚合函数。
+var validAggregates = map[string]bool{"sum": true, "avg": true, "min": true, "max": true}
+
+// derivativeSuffix Derivative 计算的每秒变化率字段的后缀。
+const derivativeSuffix = "_persec"
+
+// validatePostProcessing 校验所有对象的 Derivative 与 Aggregate 配置。
+func (m *WinPerfCounters) validatePostProcessing(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].validatePostProcessing(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePostProcessing 校验对象的 Derivative 与 Aggregate 配置。
+func (o *ObjectConfig) validatePostProcessing() error {
+	if len(o.Derivative) > 0 && !o.UseRawValues {
+		return fmt.Errorf("derivative of object %q requires UseRawValues", o.ObjectName)
+	}
+	for counter, function := range o.Aggregate {
+		if !validAggregates[function] {
+			return fmt.Errorf("invalid aggregate %q for counter %q of object %q, expected one of sum, avg, min or max", function, counter, o.ObjectName)
+		}
+	}
+	return nil
+}
+
+// derivativeSample 序列中一个字段上一次的原始值。
+type derivativeSample struct {
+	value     float64
+	timestamp time.Time
+}
+
+// derivatives 记录计算每秒变化率所需的上一次原始值。
+type derivatives struct {
+	lock    sync.Mutex
+	samples map[string]derivativeSample
+}
+
+// rate 记录本次的值并返回与上一次的值相比的每秒变化率，没有上一次的值或计数器被重置时 ok 为 false。
+func (d *derivatives) rate(key string, value float64, timestamp time.Time) (rate float64, ok bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.samples == nil {
+		d.samples = make(map[string]derivativeSample)
+	}
+	previous, found := d.samples[key]
+	d.samples[key] = derivativeSample{value: value, timestamp: timestamp}
+	elapsed := timestamp.Sub(previous.timestamp).Seconds()
+	if !found || elapsed <= 0 || value < previous.value {
+		return 0, false
+	}
+	return (value - previous.value) / elapsed, true
+}
+
+// prune 丢弃长时间未更新的值，例如已退出的进程。
+func (d *derivatives) prune(now time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for key, sample := range d.samples {
+		if now.Sub(sample.timestamp) > previousValueTimeout {
+			delete(d.samples, key)
+		}
+	}
+}
+
+// derivativeFields 返回对象中需要计算变化率的字段名称，配置了 "*" 时返回 nil 表示所有数值字段。
+func (o *ObjectConfig) derivativeFields() (fields []string, all bool) {
+	for _, counter := range o.Derivative {
+		if counter == "*" {
+			return nil, true
+		}
+		fields = append(fields, o.fieldName(counter))
+	}
+	return fields, false
+}
+
+// applyDerivatives 为配置了 Derivative 的对象追加 "<字段>_persec" 字段，其值为原始值相对于上一次采集的每秒变化率。
+// 每条序列的第一次采集以及计数器被重置时不输出该字段。
+func (m *WinPerfCounters) applyDerivatives(hostInfo *hostCountersInfo, collectedFields fieldGrouping, groupObjects map[instanceGrouping]*ObjectConfig) {
+	for grouping, fields := range collectedFields {
+		object := groupObjects[grouping]
+		if object == nil || len(object.Derivative) == 0 {
+			continue
+		}
+		names, all := object.derivativeFields()
+		for field, value := range fields {
+			if !all && !slices.Contains(names, field) {
+				continue
+			}
+			v, ok := toFloat(value)
+			if !ok {
+				continue
+			}
+			key := strings.Join([]string{hostInfo.computer, grouping.name, grouping.objectName, grouping.instance, field}, "\x00")
+			if rate, ok := m.derivatives.rate(key, v, hostInfo.timestamp); ok {
+				fields[field+derivativeSuffix] = rate
+			}
+		}
+	}
+}
+
+// aggregateFunction 返回字段使用的聚合函数，变化率字段使用其原始字段的聚合函数，未配置时返回空字符串。
+func (o *ObjectConfig) aggregateFunction(field string) string {
+	for counter, function := range o.Aggregate {
+		if counter != "*" && o.fieldName(counter) == field {
+			return function
+		}
+	}
+	if base, ok := strings.CutSuffix(field, derivativeSuffix); ok {
+		if function := o.aggregateFunction(base); function != "" {
+			return function
+		}
+	}
+	return o.Aggregate["*"]
+}
+
+// aggregateValue 累计一个字段在各实例上的值。
+type aggregateValue struct {
+	function string
+	count    int
+	sum      float64
+	min      float64
+	max      float64
+}
+
+func (a *aggregateValue) add(v float64) {
+	if a.count == 0 || v < a.min {
+		a.min = v
+	}
+	if a.count == 0 || v > a.max {
+		a.max = v
+	}
+	a.sum += v
+	a.count++
+}
+
+func (a *aggregateValue) result() float64 {
+	switch a.function {
+	case "avg":
+		return a.sum / float64(a.count)
+	case "min":
+		return a.min
+	case "max":
+		return a.max
+	}
+	return a.sum
+}
+
+// applyAggregation 将配置了 Aggregate 的对象的各实例合并为一个不带 instance 标签的实例组（AggregateBy 为 processor_group 时每个处理器组一个），
+// 只输出配置了聚合函数的字段，以及参与聚合的实例数量 instance_count。_Total 实例不参与聚合，按原样输出。
+func (m *WinPerfCounters) applyAggregation(collectedFields fieldGrouping, groupObjects map[instanceGrouping]*ObjectConfig) {
+	aggregates := make(map[instanceGrouping]map[string]*aggregateValue)
+	instances := make(map[instanceGrouping]int64)
+	objects := make(map[instanceGrouping]*ObjectConfig)
+	for grouping, fields := range collectedFields {
+		object := groupObjects[grouping]
+		if object == nil || len(object.Aggregate) == 0 {
+			continue
+		}
+		keyInstance, ok := object.aggregateKeyInstance(grouping.instance)
+		if !ok {
+			continue
+		}
+		key := instanceGrouping{name: grouping.name, instance: keyInstance, objectName: grouping.objectName}
+		if aggregates[key] == nil {
+			aggregates[key] = make(map[string]*aggregateValue)
+		}
+		for field, value := range fields {
+			v, ok := toFloat(value)
+			if !ok {
+				continue
+			}
+			aggregate, ok := aggregates[key][field]
+			if !ok {
+				function := object.aggregateFunction(field)
+				if function == "" {
+					continue
+				}
+				aggregate = &aggregateValue{function: function}
+				aggregates[key][field] = aggregate
+			}
+			aggregate.add(v)
+		}
+		instances[key]++
+		objects[key] = object
+		delete(collectedFields, grouping)
+		delete(groupObjects, grouping)
+	}
+
+	for key, values := range aggregates {
+		fields := make(map[string]interface{}, len(values)+1)
+		for field, aggregate := range values {
+			fields[field] = aggregate.result()
+		}
+		fields["instance_count"] = instances[key]
+		collectedFields[key] = fields
+		groupObjects[key] = objects[key]
+	}
+}
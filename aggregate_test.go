@@ -0,0 +1,85 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyAggregation(t *testing.T) {
+	object := &ObjectConfig{
+		ObjectName:   "Process",
+		IncludeTotal: true,
+		Aggregate:    map[string]string{"*": "sum", "Working Set": "max"},
+	}
+	workingSet := object.fieldName("Working Set")
+	threads := object.fieldName("Thread Count")
+	grouping := func(instance string) instanceGrouping {
+		return instanceGrouping{name: "win_proc", instance: instance, objectName: "Process"}
+	}
+	collected := fieldGrouping{
+		grouping("a"):      {workingSet: 100.0, threads: 2.0},
+		grouping("b"):      {workingSet: 300.0, threads: 3.0},
+		grouping("c"):      {workingSet: 200.0, threads: int64(5)},
+		grouping("_Total"): {workingSet: 600.0, threads: 10.0},
+	}
+	objects := map[instanceGrouping]*ObjectConfig{}
+	for key := range collected {
+		objects[key] = object
+	}
+
+	m := &WinPerfCounters{}
+	m.applyAggregation(collected, objects)
+
+	// _Total 不计入 sum，按原样输出
+	require.Equal(t, fieldGrouping{
+		grouping(""):       {workingSet: 300.0, threads: 10.0, "instance_count": int64(3)},
+		grouping("_Total"): {workingSet: 600.0, threads: 10.0},
+	}, collected)
+	require.Same(t, object, objects[grouping("")])
+	require.Same(t, object, objects[grouping("_Total")])
+}
+
+func TestApplyAggregationByProcessorGroup(t *testing.T) {
+	object := &ObjectConfig{
+		ObjectName:  "Processor Information",
+		Aggregate:   map[string]string{"*": "avg"},
+		AggregateBy: aggregateByProcessorGroup,
+	}
+	field := object.fieldName("% Processor Time")
+	grouping := func(instance string) instanceGrouping {
+		return instanceGrouping{name: "win_cpu", instance: instance, objectName: "Processor Information"}
+	}
+	collected := fieldGrouping{
+		grouping("0,0"):      {field: 10.0},
+		grouping("0,1"):      {field: 30.0},
+		grouping("0,_Total"): {field: 20.0},
+		grouping("1,0"):      {field: 50.0},
+		grouping("_Total"):   {field: 30.0},
+	}
+	objects := map[instanceGrouping]*ObjectConfig{}
+	for key := range collected {
+		objects[key] = object
+	}
+
+	m := &WinPerfCounters{}
+	m.applyAggregation(collected, objects)
+
+	require.Equal(t, fieldGrouping{
+		grouping("0"):        {field: 20.0, "instance_count": int64(2)},
+		grouping("1"):        {field: 50.0, "instance_count": int64(1)},
+		grouping("0,_Total"): {field: 20.0},
+		grouping("_Total"):   {field: 30.0},
+	}, collected)
+}
+
+func TestAggregateFunction(t *testing.T) {
+	object := &ObjectConfig{Aggregate: map[string]string{"*": "sum", "Working Set": "max"}}
+	workingSet := object.fieldName("Working Set")
+	require.Equal(t, "max", object.aggregateFunction(workingSet))
+	require.Equal(t, "max", object.aggregateFunction(workingSet+derivativeSuffix))
+	require.Equal(t, "sum", object.aggregateFunction(object.fieldName("Thread Count")))
+	require.Empty(t, (&ObjectConfig{Aggregate: map[string]string{"Working Set": "max"}}).aggregateFunction("other"))
+}
@@ -0,0 +1,141 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"strings"
+)
+
+// counterAliases 内置的常用性能对象及计数器名称词典，按语言记录本地化名称到英文名称的映射。
+//
+// 在不支持 AddEnglishCounter 的系统（Vista 之前）上，PDH 只接受本地化名称，
+// 借助该词典可以让使用英文名称编写的配置在德语、法语、日语和简体中文系统上正常工作。
+// 词典只覆盖常用名称，可通过 CounterAliases 配置补充或覆盖。
+var counterAliases = map[string]map[string]string{
+	"de": {
+		"Prozessor":                     "Processor",
+		"Prozessorinformationen":        "Processor Information",
+		"Prozessorzeit (%)":             "% Processor Time",
+		"Leerlaufzeit (%)":              "% Idle Time",
+		"Benutzerzeit (%)":              "% User Time",
+		"Privilegierte Zeit (%)":        "% Privileged Time",
+		"Speicher":                      "Memory",
+		"Verfügbare Bytes":              "Available Bytes",
+		"Seiten/s":                      "Pages/sec",
+		"Logischer Datenträger":         "LogicalDisk",
+		"Physikalischer Datenträger":    "PhysicalDisk",
+		"Freier Speicherplatz (%)":      "% Free Space",
+		"Prozess":                       "Process",
+		"Arbeitsseiten":                 "Working Set",
+		"Netzwerkschnittstelle":         "Network Interface",
+		"Bytes gesendet/s":              "Bytes Sent/sec",
+		"Empfangene Bytes/s":            "Bytes Received/sec",
+		"System":                        "System",
+		"Prozessor-Warteschlangenlänge": "Processor Queue Length",
+	},
+	"fr": {
+		"Processeur":                        "Processor",
+		"Informations sur le processeur":    "Processor Information",
+		"% temps processeur":                "% Processor Time",
+		"% d'inactivité":                    "% Idle Time",
+		"% temps utilisateur":               "% User Time",
+		"% temps privilégié":                "% Privileged Time",
+		"Mémoire":                           "Memory",
+		"Octets disponibles":                "Available Bytes",
+		"Pages/s":                           "Pages/sec",
+		"Disque logique":                    "LogicalDisk",
+		"Disque physique":                   "PhysicalDisk",
+		"% d'espace libre":                  "% Free Space",
+		"Processus":                         "Process",
+		"Plage de travail":                  "Working Set",
+		"Interface réseau":                  "Network Interface",
+		"Octets envoyés/s":                  "Bytes Sent/sec",
+		"Octets reçus/s":                    "Bytes Received/sec",
+		"Système":                           "System",
+		"Longueur de la file du processeur": "Processor Queue Length",
+	},
+	"ja": {
+		"プロセッサ":           "Processor",
+		"プロセッサ情報":         "Processor Information",
+		"% プロセッサ時間":       "% Processor Time",
+		"% アイドル時間":        "% Idle Time",
+		"% ユーザー時間":        "% User Time",
+		"% 特権時間":          "% Privileged Time",
+		"メモリ":             "Memory",
+		"使用可能バイト":         "Available Bytes",
+		"ページ/秒":           "Pages/sec",
+		"論理ディスク":          "LogicalDisk",
+		"物理ディスク":          "PhysicalDisk",
+		"% 空き領域":          "% Free Space",
+		"プロセス":            "Process",
+		"ワーキング セット":       "Working Set",
+		"ネットワーク インターフェイス": "Network Interface",
+		"送信バイト/秒":         "Bytes Sent/sec",
+		"受信バイト/秒":         "Bytes Received/sec",
+		"システム":            "System",
+		"プロセッサ キューの長さ":    "Processor Queue Length",
+	},
+	"zh-CN": {
+		"处理器":     "Processor",
+		"处理器信息":   "Processor Information",
+		"% 处理器时间": "% Processor Time",
+		"% 空闲时间":  "% Idle Time",
+		"% 用户时间":  "% User Time",
+		"% 特权时间":  "% Privileged Time",
+		"内存":      "Memory",
+		"可用字节":    "Available Bytes",
+		"页/秒":     "Pages/sec",
+		"逻辑磁盘":    "LogicalDisk",
+		"物理磁盘":    "PhysicalDisk",
+		"% 可用空间":  "% Free Space",
+		"进程":      "Process",
+		"工作集":     "Working Set",
+		"网络接口":    "Network Interface",
+		"发送字节/秒":  "Bytes Sent/sec",
+		"接收字节/秒":  "Bytes Received/sec",
+		"系统":      "System",
+		"处理器队列长度": "Processor Queue Length",
+	},
+}
+
+// languageIDs 系统界面语言的 LANGID 到词典语言的映射，主语言相同的其它地区使用同一词典。
+var languageIDs = map[uint16]string{
+	0x07: "de",
+	0x0c: "fr",
+	0x11: "ja",
+}
+
+// initAliases 根据 CounterLanguage（未配置时为系统界面语言）生成英文名称到本地化名称的映射，
+// CounterAliases 中的条目会覆盖内置词典。
+func (m *WinPerfCounters) initAliases() {
+	language := m.CounterLanguage
+	if language == "" {
+		language = systemLanguage()
+	}
+	m.localizedNames = nil
+	for _, aliases := range []map[string]string{counterAliases[language], m.CounterAliases[language]} {
+		for localized, english := range aliases {
+			if m.localizedNames == nil {
+				m.localizedNames = make(map[string]string)
+			}
+			m.localizedNames[strings.ToLower(english)] = localized
+		}
+	}
+}
+
+// localizeName 返回英文名称对应的本地化名称，词典中没有时原样返回。
+func (m *WinPerfCounters) localizeName(name string) string {
+	if localized, ok := m.localizedNames[strings.ToLower(name)]; ok {
+		return localized
+	}
+	return name
+}
+
+// systemLanguage 返回系统界面语言对应的词典语言，没有对应词典时返回空字符串。
+func systemLanguage() string {
+	langID := userDefaultUILanguage()
+	if langID == 0x0804 {
+		return "zh-CN"
+	}
+	return languageIDs[langID&0x3ff]
+}
@@ -0,0 +1,87 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"sync"
+)
+
+// arrayBufferPool 在各次数组读取之间复用 PDH 数组调用的缓冲区，避免每个计数器每次采集都重新分配。
+var arrayBufferPool sync.Pool
+
+// getArrayBuffer 从缓冲池中取得至少 size 字节的缓冲区。
+func getArrayBuffer(size uint32) *[]byte {
+	if buf, ok := arrayBufferPool.Get().(*[]byte); ok && uint32(cap(*buf)) >= size {
+		*buf = (*buf)[:size]
+		return buf
+	}
+	buf := make([]byte, size)
+	return &buf
+}
+
+// arraySizes 记录各计数器上一次成功读取数组时所需的缓冲区大小，下一次直接按该大小分配，省去逐步加倍的重试。
+type arraySizes struct {
+	lock  sync.Mutex
+	sizes map[pdhCounterHandle]uint32
+}
+
+func (a *arraySizes) get(hCounter pdhCounterHandle) uint32 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.sizes[hCounter]
+}
+
+func (a *arraySizes) set(hCounter pdhCounterHandle, size uint32) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.sizes == nil {
+		a.sizes = make(map[pdhCounterHandle]uint32)
+	}
+	a.sizes[hCounter] = size
+}
+
+// reset 清空记录，关闭查询后计数器句柄可能被重新使用。
+func (a *arraySizes) reset() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.sizes = nil
+}
+
+// arrayFunc 是 PdhGetFormattedCounterArray 与 PdhGetRawCounterArray 系列函数的签名。
+type arrayFunc func(hCounter pdhCounterHandle, lpdwBufferSize, lpdwBufferCount *uint32, itemBuffer *byte) uint32
+
+// getCounterArray 调用 get 读取计数器数组，并在缓冲区归还缓冲池之前由 decode 复制出结果。
+// 缓冲区从上一次成功读取的大小开始，不足时按 PDH 返回的大小或加倍重试，不超过 maxBufferSize。
+func (m *performanceQueryImpl) getCounterArray(hCounter pdhCounterHandle, get arrayFunc, decode func(buf *byte, itemCount uint32)) error {
+	buflen := max(uint32(initialBufferSize), m.arraySizes.get(hCounter))
+	var required uint32
+	for ; buflen <= m.maxBufferSize; buflen *= 2 {
+		buf := getArrayBuffer(buflen)
+
+		// Get the info with the current buffer size
+		var itemCount uint32
+		size := buflen
+		ret := get(hCounter, &size, &itemCount, &(*buf)[0])
+		if ret == errorSuccess {
+			decode(&(*buf)[0], itemCount)
+			arrayBufferPool.Put(buf)
+			m.arraySizes.set(hCounter, buflen)
+			return nil
+		}
+		arrayBufferPool.Put(buf)
+
+		// Use the size as a hint if it exceeds the current buffer size
+		if size > buflen {
+			buflen = size
+		}
+
+		// We got a non-recoverable error so exit here
+		if ret != pdhMoreData {
+			return m.stats.counterError(hCounter, newPdhError(ret))
+		}
+		required = size
+		m.stats.retried()
+	}
+
+	return m.stats.counterError(hCounter, m.bufferLimitError(m.counterPathOf(hCounter), required))
+}
@@ -0,0 +1,131 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"sync"
+)
+
+// instanceNames 缓存 PDH 数组中实例名称由 UTF-16 到字符串的转换。同一对象的多个计数器返回相同的实例名称，
+// 数千个进程实例时逐个转换的开销很大。名称解码到复用的缓冲区后在缓存中查找，只有新出现的名称才分配内存。
+// 每次采集数据时淘汰上一次采集中没有出现的实例名称。
+type instanceNames struct {
+	lock    sync.Mutex
+	cycle   uint64
+	entries map[string]*instanceName
+	decoder utf16Decoder
+}
+
+// instanceName 缓存的实例名称及其最近一次出现的采集轮次。
+type instanceName struct {
+	name  string
+	cycle uint64
+}
+
+// name 返回以 0 结尾的 UTF-16 字符串对应的 Go 字符串，命中缓存时不分配内存。
+func (n *instanceNames) name(s *uint16) string {
+	if s == nil {
+		return ""
+	}
+	units := utf16Units(s)
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	decoded := n.decoder.decodeBytes(units)
+	if entry, ok := n.entries[string(decoded)]; ok {
+		entry.cycle = n.cycle
+		return entry.name
+	}
+	if n.entries == nil {
+		n.entries = make(map[string]*instanceName)
+	}
+	name := string(decoded)
+	n.entries[name] = &instanceName{name: name, cycle: n.cycle}
+	return name
+}
+
+// rotate 在每次采集数据时调用，淘汰上一次采集中没有再出现的实例名称。
+func (n *instanceNames) rotate() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for key, entry := range n.entries {
+		if entry.cycle < n.cycle {
+			delete(n.entries, key)
+		}
+	}
+	n.cycle++
+}
+
+// readCounterArray 按计数器的读取方式读取未展开通配符的计数器的所有实例的值。
+func readCounterArray(query PerformanceQuery, metric *counter) ([]counterValue, error) {
+	if metric.useRawValue {
+		return query.GetRawCounterArray(metric.counterHandle)
+	}
+	if metric.integer {
+		largeValues, err := query.GetFormattedCounterArrayLarge(metric.counterHandle)
+		if err != nil {
+			return nil, err
+		}
+		counterValues := make([]counterValue, len(largeValues))
+		for i, v := range largeValues {
+			counterValues[i] = counterValue{Name: v.Name, Value: v.Value}
+		}
+		return counterValues, nil
+	}
+	doubleValues, err := query.GetFormattedCounterArrayDouble(metric.counterHandle)
+	if err != nil {
+		return nil, err
+	}
+	counterValues := make([]counterValue, len(doubleValues))
+	for i, v := range doubleValues {
+		counterValues[i] = counterValue{Name: v.Name, Value: v.Value}
+	}
+	return counterValues, nil
+}
+
+// arrayResult 预先读取的一个计数器的数组值。
+type arrayResult struct {
+	values []counterValue
+	err    error
+}
+
+// prefetchArrays 未启用 UseWildcardsExpansion 且 ArrayWorkers 大于 1 时，用 ArrayWorkers 个 goroutine 并行读取主机上
+// 本次需要采集的计数器的数组值，结果与 hostInfo.counters 一一对应。未启用时返回 nil，由采集循环逐个读取。
+func (m *WinPerfCounters) prefetchArrays(hostInfo *hostCountersInfo, due dueObjects) []arrayResult {
+	if m.UseWildcardsExpansion || m.ArrayWorkers <= 1 {
+		return nil
+	}
+
+	results := make([]arrayResult, len(hostInfo.counters))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(m.ArrayWorkers, len(hostInfo.counters)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = readArraySafe(hostInfo.query, hostInfo.counters[i])
+			}
+		}()
+	}
+	for i, metric := range hostInfo.counters {
+		if !metric.quarantined && due.contains(metric.object) {
+			indexes <- i
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// readArraySafe 读取计数器的数组值，将读取过程中的 panic 转换为错误，避免并行读取的 goroutine 使整个进程退出。
+func readArraySafe(query PerformanceQuery, metric *counter) (result arrayResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = arrayResult{err: fmt.Errorf("panic while reading counter %q: %v", metric.counterPath, r)}
+		}
+	}()
+	values, err := readCounterArray(query, metric)
+	return arrayResult{values: values, err: err}
+}
@@ -0,0 +1,72 @@
+//go:build windows
+
+package win_perf_counters
+
+// defaultBackpressureCycles 未配置 BackpressureCycles 时，连续出现背压多少次采集后开始降低采集频率。
+const defaultBackpressureCycles = 3
+
+// backpressureState 记录连续出现背压的采集次数。
+type backpressureState struct {
+	// cycles 连续出现背压的采集次数。
+	cycles int
+	// slowed 是否正在降低低优先级对象的采集频率。
+	slowed bool
+}
+
+// AddBackpressureFunc 注册背压检测函数，每次采集前调用，任一函数返回 true 即认为存在背压。
+// 背压持续 BackpressureCycles 次采集后，LowPriority 对象的采集频率降低为原来的 1/BackpressureSlowdown。
+func (m *WinPerfCounters) AddBackpressureFunc(backpressureFunc BackpressureFunc) {
+	m.routesLock.Lock()
+	defer m.routesLock.Unlock()
+
+	m.backpressureFuncs = append(m.backpressureFuncs, backpressureFunc)
+}
+
+// updateBackpressure 检查各背压检测函数并更新背压状态，返回本次采集低优先级对象的降频倍数，未降频时返回 1。
+func (m *WinPerfCounters) updateBackpressure() int {
+	if m.BackpressureSlowdown <= 1 {
+		return 1
+	}
+
+	m.routesLock.RLock()
+	pressured := false
+	for _, backpressureFunc := range m.backpressureFuncs {
+		if backpressureFunc() {
+			pressured = true
+			break
+		}
+	}
+	m.routesLock.RUnlock()
+
+	if pressured {
+		m.backpressure.cycles++
+	} else {
+		m.backpressure.cycles = 0
+	}
+
+	threshold := m.BackpressureCycles
+	if threshold <= 0 {
+		threshold = defaultBackpressureCycles
+	}
+	slowed := m.backpressure.cycles >= threshold
+	if slowed != m.backpressure.slowed {
+		if slowed {
+			m.Log.Warnf("Output backpressure persisted for %d gathers, reducing gather frequency of low priority objects by %dx", m.backpressure.cycles, m.BackpressureSlowdown)
+		} else {
+			m.Log.Infof("Output backpressure cleared, restoring gather frequency of low priority objects")
+		}
+		m.backpressure.slowed = slowed
+	}
+
+	slowdown := 1
+	if slowed {
+		slowdown = m.BackpressureSlowdown
+	}
+	m.stats.set(map[string]string{}, "backpressure_slowdown", int64(slowdown))
+	return slowdown
+}
+
+// throttled 判断低优先级对象在第 cycle 次采集时是否因背压被跳过。
+func (o *ObjectConfig) throttled(cycle uint64, slowdown int) bool {
+	return o.LowPriority && slowdown > 1 && cycle%uint64(slowdown) != 0
+}
@@ -0,0 +1,181 @@
+package win_perf_counters
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"time"
+)
+
+// ObjectBuilder 以链式调用构造一个对象的配置，由 AddObject 或 NewObjectBuilder 创建，
+// 最后调用 Add 添加到采集器，或调用 Build 取得校验后的 ObjectConfig。
+type ObjectBuilder struct {
+	m      *WinPerfCounters
+	object ObjectConfig
+}
+
+// NewObjectBuilder 创建采集 objectName 对象的构造器，未调用 Instances 时采集所有实例（"*"）。
+// 构造的配置可用于 Options.Objects 或 ReloadObjects。
+func NewObjectBuilder(objectName string) *ObjectBuilder {
+	return &ObjectBuilder{object: NewObjectConfig(objectName, nil)}
+}
+
+// AddObject 创建采集 objectName 对象的构造器，调用其 Add 时将配置添加到采集器，需要在 Init 之前调用。
+// 例如 m.AddObject("LogicalDisk").Counters("% Free Space", "Free Megabytes").Instances("*").IncludeTotal().Add()。
+func (m *WinPerfCounters) AddObject(objectName string) *ObjectBuilder {
+	builder := NewObjectBuilder(objectName)
+	builder.m = m
+	return builder
+}
+
+// Counters 追加需要采集的计数器。
+func (b *ObjectBuilder) Counters(counters ...string) *ObjectBuilder {
+	b.object.Counters = append(b.object.Counters, counters...)
+	return b
+}
+
+// Instances 设置需要采集的实例，替换默认的 "*"。
+func (b *ObjectBuilder) Instances(instances ...string) *ObjectBuilder {
+	b.object.Instances = instances
+	return b
+}
+
+// ExcludeInstances 追加需要排除的实例，支持 "re:" 前缀的正则表达式。
+func (b *ObjectBuilder) ExcludeInstances(instances ...string) *ObjectBuilder {
+	b.object.InstancesExclude = append(b.object.InstancesExclude, instances...)
+	return b
+}
+
+// IncludeTotal 包含 _Total 实例。
+func (b *ObjectBuilder) IncludeTotal() *ObjectBuilder {
+	b.object.IncludeTotal = true
+	return b
+}
+
+// TotalOnly 只采集 _Total 实例。
+func (b *ObjectBuilder) TotalOnly() *ObjectBuilder {
+	b.object.TotalOnly = true
+	return b
+}
+
+// Measurement 设置测量名称。
+func (b *ObjectBuilder) Measurement(measurement string) *ObjectBuilder {
+	b.object.Measurement = measurement
+	return b
+}
+
+// Sources 设置采集该对象的主机，未设置时使用全局的 Sources。
+func (b *ObjectBuilder) Sources(sources ...string) *ObjectBuilder {
+	b.object.Sources = sources
+	return b
+}
+
+// UseRawValues 采集原始值。
+func (b *ObjectBuilder) UseRawValues() *ObjectBuilder {
+	b.object.UseRawValues = true
+	return b
+}
+
+// WarnOnMissing 缺失计数器时给出警告。
+func (b *ObjectBuilder) WarnOnMissing() *ObjectBuilder {
+	b.object.WarnOnMissing = true
+	return b
+}
+
+// FailOnMissing 缺失计数器时报错并终止。
+func (b *ObjectBuilder) FailOnMissing() *ObjectBuilder {
+	b.object.FailOnMissing = true
+	return b
+}
+
+// Interval 设置该对象的采集间隔。
+func (b *ObjectBuilder) Interval(interval time.Duration) *ObjectBuilder {
+	b.object.Interval = Duration(interval)
+	return b
+}
+
+// Alias 将计数器 counter 输出为字段 field。
+func (b *ObjectBuilder) Alias(counter, field string) *ObjectBuilder {
+	if b.object.CounterAliases == nil {
+		b.object.CounterAliases = make(map[string]string)
+	}
+	b.object.CounterAliases[counter] = field
+	return b
+}
+
+// Tag 为对象的所有指标添加或覆盖标签。
+func (b *ObjectBuilder) Tag(key, value string) *ObjectBuilder {
+	if b.object.TagOverrides == nil {
+		b.object.TagOverrides = make(map[string]string)
+	}
+	b.object.TagOverrides[key] = value
+	return b
+}
+
+// ExtraTag 为对象的指标添加静态标签，不会覆盖标准标签。
+func (b *ObjectBuilder) ExtraTag(key, value string) *ObjectBuilder {
+	if b.object.ExtraTags == nil {
+		b.object.ExtraTags = make(map[string]string)
+	}
+	b.object.ExtraTags[key] = value
+	return b
+}
+
+// FieldType 将字段 field 转换为 typ 类型（int、uint、float、bool）输出。
+func (b *ObjectBuilder) FieldType(field, typ string) *ObjectBuilder {
+	if b.object.FieldTypes == nil {
+		b.object.FieldTypes = make(map[string]string)
+	}
+	b.object.FieldTypes[field] = typ
+	return b
+}
+
+// EmitAsBool 将状态类计数器以布尔值输出。
+func (b *ObjectBuilder) EmitAsBool(counters ...string) *ObjectBuilder {
+	b.object.EmitAsBool = append(b.object.EmitAsBool, counters...)
+	return b
+}
+
+// Threshold 追加布尔字段 field，其值为阈值表达式 expression（例如 "Pages/sec > 1000"）的结果。
+func (b *ObjectBuilder) Threshold(field, expression string) *ObjectBuilder {
+	if b.object.Thresholds == nil {
+		b.object.Thresholds = make(map[string]string)
+	}
+	b.object.Thresholds[field] = expression
+	return b
+}
+
+// Build 校验并返回构造的对象配置，构造器可以继续修改而不影响返回的配置。
+func (b *ObjectBuilder) Build() (ObjectConfig, error) {
+	object := b.object
+	if err := object.Validate(); err != nil {
+		return ObjectConfig{}, err
+	}
+	object.Counters = slices.Clone(object.Counters)
+	object.Instances = slices.Clone(object.Instances)
+	object.InstancesExclude = slices.Clone(object.InstancesExclude)
+	object.Sources = slices.Clone(object.Sources)
+	object.CounterAliases = maps.Clone(object.CounterAliases)
+	object.TagOverrides = maps.Clone(object.TagOverrides)
+	object.ExtraTags = maps.Clone(object.ExtraTags)
+	object.FieldTypes = maps.Clone(object.FieldTypes)
+	object.EmitAsBool = slices.Clone(object.EmitAsBool)
+	object.Thresholds = maps.Clone(object.Thresholds)
+	object.MeasurementRules = slices.Clone(object.MeasurementRules)
+	return object, nil
+}
+
+// Add 校验构造的对象配置并添加到 AddObject 所属的采集器。
+func (b *ObjectBuilder) Add() error {
+	if b.m == nil {
+		return errors.New("object builder was not created by AddObject")
+	}
+	object, err := b.Build()
+	if err != nil {
+		return err
+	}
+	b.m.configLock.Lock()
+	defer b.m.configLock.Unlock()
+	b.m.Object = append(b.m.Object, object)
+	return nil
+}
@@ -0,0 +1,138 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// burstTrigger 表示一条突发采集触发规则：指标字段越过阈值时临时切换到更密集的采集档位。
+type burstTrigger struct {
+	// Profile 触发后切换到的采集档位。
+	Profile string `toml:"Profile"`
+	// Duration 突发采集持续时间，期间再次触发会延长至最近一次触发后的 Duration。
+	Duration Duration `toml:"Duration"`
+	// Measurement 匹配的测量名称，为空时不做限制。
+	Measurement string `toml:"Measurement"`
+	// Tags 需要完全匹配的标签。
+	Tags map[string]string `toml:"Tags"`
+	// Field 用于比较的字段名称。
+	Field string `toml:"Field"`
+	// Above 字段值大于该值时触发。
+	Above *float64 `toml:"Above"`
+	// Below 字段值小于该值时触发。
+	Below *float64 `toml:"Below"`
+}
+
+// matches 判断指标是否满足触发条件。
+func (t *burstTrigger) matches(measurement string, fields map[string]interface{}, tags map[string]string) bool {
+	if t.Measurement != "" && t.Measurement != measurement {
+		return false
+	}
+	for k, v := range t.Tags {
+		if tags[k] != v {
+			return false
+		}
+	}
+	value, ok := toFloat(fields[t.Field])
+	if !ok {
+		return false
+	}
+	return (t.Above != nil && value > *t.Above) || (t.Below != nil && value < *t.Below)
+}
+
+// initBursts 校验突发采集触发规则。
+func (m *WinPerfCounters) initBursts() error {
+	for i, trigger := range m.Burst {
+		if !m.hasProfile(trigger.Profile) {
+			return fmt.Errorf("burst %d references unknown profile %q", i, trigger.Profile)
+		}
+		if trigger.Duration <= 0 {
+			return fmt.Errorf("burst %d should have a positive duration", i)
+		}
+		if trigger.Field == "" {
+			return fmt.Errorf("burst %d has no field", i)
+		}
+		if trigger.Above == nil && trigger.Below == nil {
+			return fmt.Errorf("burst %d needs at least one of 'Above' or 'Below'", i)
+		}
+	}
+	return nil
+}
+
+// TriggerBurst 临时切换到 profile 档位，duration 后自动切回触发前的档位。
+// 可供外部信号（例如告警回调）调用；突发期间再次触发会延长持续时间。
+func (m *WinPerfCounters) TriggerBurst(profile string, duration time.Duration) error {
+	if !m.hasProfile(profile) {
+		return fmt.Errorf("unknown profile %q", profile)
+	}
+	if duration <= 0 {
+		return errors.New("burst duration should be positive")
+	}
+
+	m.profileLock.Lock()
+	defer m.profileLock.Unlock()
+	if !m.burstActive {
+		m.burstActive = true
+		m.burstPrevious = m.activeProfile
+		m.Log.Infof("Starting burst collection with profile %q for %v", profile, duration)
+	}
+	if until := time.Now().Add(duration); until.After(m.burstUntil) {
+		m.burstUntil = until
+	}
+	m.switchProfile(profile)
+	return nil
+}
+
+// checkBurstTriggers 检查指标是否满足任一触发规则，满足时开始或延长突发采集。
+func (m *WinPerfCounters) checkBurstTriggers(measurement string, fields map[string]interface{}, tags map[string]string) {
+	for i := range m.Burst {
+		trigger := &m.Burst[i]
+		if trigger.matches(measurement, fields, tags) {
+			if err := m.TriggerBurst(trigger.Profile, time.Duration(trigger.Duration)); err != nil {
+				m.Log.Errorf("Triggering burst failed: %v", err)
+			}
+		}
+	}
+}
+
+// expireBurst 在突发采集到期后切回触发前的档位。
+func (m *WinPerfCounters) expireBurst() {
+	m.profileLock.Lock()
+	defer m.profileLock.Unlock()
+	if !m.burstActive || time.Now().Before(m.burstUntil) {
+		return
+	}
+	m.Log.Infof("Burst collection expired, reverting to profile %q", m.burstPrevious)
+	m.burstActive = false
+	m.burstUntil = time.Time{}
+	m.switchProfile(m.burstPrevious)
+}
+
+// toFloat 将数值类型的字段值转换为 float64。
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// hasProfile 判断是否配置了名为 name 的采集档位。
+func (m *WinPerfCounters) hasProfile(name string) bool {
+	return slices.ContainsFunc(m.Profiles, func(p collectionProfile) bool { return p.Name == name })
+}
@@ -0,0 +1,66 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// clockOffsetSmoothing 估计主机时钟偏差时新样本的权重，平滑单次采集的网络延迟抖动。
+	clockOffsetSmoothing = 0.2
+	// maxClockSampleRoundTrip 采集耗时超过该值时不用于估计时钟偏差，此时无法判断远程时间戳对应本机的哪个时刻。
+	maxClockSampleRoundTrip = 2 * time.Second
+)
+
+// clockOffsets 按主机估计远程主机 PDH 时间戳与本机时钟之间的偏差，用于 CompensateClockSkew。
+type clockOffsets struct {
+	lock    sync.Mutex
+	offsets map[string]time.Duration
+}
+
+// update 以一次采集的远程时间戳和本机在采集前后的时间更新主机的偏差估计，返回当前的估计值。
+func (c *clockOffsets) update(computer string, remote, before, after time.Time) (time.Duration, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	offset, ok := c.offsets[computer]
+	roundTrip := after.Sub(before)
+	if roundTrip > maxClockSampleRoundTrip {
+		return offset, ok
+	}
+	// 远程时间戳对应本机采集耗时的中点
+	sample := remote.Sub(before.Add(roundTrip / 2))
+	if ok {
+		offset += time.Duration(clockOffsetSmoothing * float64(sample-offset))
+	} else {
+		offset = sample
+	}
+	if c.offsets == nil {
+		c.offsets = make(map[string]time.Duration)
+	}
+	c.offsets[computer] = offset
+	return offset, true
+}
+
+// compensatesClockSkew 判断是否校正主机的时间戳：只对使用 PDH 时间戳的远程主机生效，日志数据源使用记录的时间戳，
+// SourceGroups 分组的时间戳由分组内的多个主机共用，无法对应到单个主机的时钟。
+func (m *WinPerfCounters) compensatesClockSkew(hostInfo *hostCountersInfo, withTime bool) bool {
+	if !m.CompensateClockSkew || !withTime || hostInfo.computer == "localhost" || logSourcePath(hostInfo.computer) != "" {
+		return false
+	}
+	_, shared := hostInfo.query.(*sharedQuery)
+	return !shared
+}
+
+// compensateClockSkew 按估计的主机时钟偏差将远程主机的 PDH 时间戳换算为本机时钟的时间，
+// 启用 SelfMetrics 时 clock_offset_ms 字段记录当前的估计值。
+func (m *WinPerfCounters) compensateClockSkew(hostInfo *hostCountersInfo, before, after time.Time) {
+	offset, ok := m.clockOffsets.update(hostInfo.computer, hostInfo.timestamp, before, after)
+	if !ok {
+		return
+	}
+	hostInfo.timestamp = hostInfo.timestamp.Add(-offset)
+	m.stats.set(map[string]string{"source": hostInfo.tag}, "clock_offset_ms", float64(offset)/float64(time.Millisecond))
+}
@@ -0,0 +1,139 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// closeTimeout Close 等待被放弃等待的采集返回的最长时间。
+	closeTimeout = 10 * time.Second
+	// defaultShutdownTimeout 未配置 ShutdownTimeout 时 Close 进行最后一次采集和刷新输出的最长时间。
+	defaultShutdownTimeout = 10 * time.Second
+)
+
+var _ io.Closer = (*WinPerfCounters)(nil)
+
+// Close 停止内部调度器和远程主机保活，关闭 LogOutputPath 日志以及所有主机的查询，并断开使用 Credential 建立的远程会话。
+// 可以与 Gather 并发调用：Close 等待正在进行的采集结束后再释放查询，之后的采集返回 ErrClosed。
+// 超过 CollectTimeout 被放弃等待的采集最多再等待 10 秒，仍未返回的主机的查询在其返回后才关闭，避免释放正在使用的句柄。
+// 启用 FinalGather 时先进行最后一次采集，再调用 AddFlushFunc 注册的函数，两者总共不超过 ShutdownTimeout。
+// 不能在采集回调中调用 Close，多次调用是安全的。
+func (m *WinPerfCounters) Close() error {
+	m.Stop()
+	m.stopKeepAlive()
+
+	m.gatherLock.Lock()
+	defer m.gatherLock.Unlock()
+
+	var errs []error
+	if !m.closed {
+		errs = append(errs, m.shutdown())
+	}
+	m.closed = true
+	if m.logWriter != nil {
+		errs = append(errs, m.logWriter.Close())
+		m.logWriter = nil
+	}
+	if m.pendingHostCounters != nil {
+		errs = append(errs, m.closeHosts(m.pendingHostCounters))
+		m.pendingHostCounters = nil
+	}
+	if m.hostCounters != nil {
+		errs = append(errs, m.closeHosts(m.hostCounters))
+		m.hostCounters = nil
+	}
+	m.lastRefreshed = time.Time{}
+	m.refreshTuning = refreshTuning{}
+	m.lastRecycled = time.Time{}
+	m.resetInitialRefresh()
+	errs = append(errs, m.disconnectSources())
+	m.releaseObjects()
+	return errors.Join(errs...)
+}
+
+// AddFlushFunc 注册在 Close 时调用的刷新函数，用于在退出前将输出端缓存的数据发送出去。
+func (m *WinPerfCounters) AddFlushFunc(flushFunc FlushFunc) {
+	m.routesLock.Lock()
+	defer m.routesLock.Unlock()
+
+	m.flushFuncs = append(m.flushFuncs, flushFunc)
+}
+
+// shutdown 在关闭前按 FinalGather 进行最后一次采集并调用刷新函数，总耗时不超过 ShutdownTimeout，调用时需持有 gatherLock。
+func (m *WinPerfCounters) shutdown() error {
+	timeout := time.Duration(m.ShutdownTimeout)
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	if m.FinalGather {
+		if err := m.gatherLocked(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("final gather failed: %w", err))
+		}
+	}
+
+	m.routesLock.RLock()
+	flushFuncs := m.flushFuncs
+	m.routesLock.RUnlock()
+	for _, flushFunc := range flushFuncs {
+		if err := flushFunc(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("flushing output failed: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closeHosts 关闭 hosts 中所有主机的查询，仍在进行的采集最多等待 closeTimeout。
+func (m *WinPerfCounters) closeHosts(hosts map[string]*hostCountersInfo) error {
+	deadline := time.Now().Add(closeTimeout)
+	var errs []error
+	for _, hostInfo := range hosts {
+		for hostInfo.busy.Load() && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if hostInfo.busy.Load() {
+			errs = append(errs, wrapCounterError("close", hostInfo.computer, "", "", fmt.Errorf("collection still running after %v, query is closed when it returns", closeTimeout)))
+		}
+		if err := hostInfo.closeQuery(); err != nil {
+			errs = append(errs, wrapCounterError("close", hostInfo.computer, "", "", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closeQuery 关闭主机的查询。被放弃等待的采集仍在使用查询时不立即关闭，而是交给采集的 goroutine 在返回时关闭，
+// 之后刷新计数器或 Close 不会释放正在使用的 PDH 句柄。
+func (h *hostCountersInfo) closeQuery() error {
+	h.releaseLock.Lock()
+	defer h.releaseLock.Unlock()
+
+	if h.query == nil {
+		return nil
+	}
+	if h.busy.Load() {
+		h.closeWhenIdle = true
+		return nil
+	}
+	return h.query.Close()
+}
+
+// finishCollect 在主机的采集 goroutine 返回时调用，清除 busy，并关闭采集期间被 closeQuery 放弃的查询。
+func (h *hostCountersInfo) finishCollect() {
+	h.releaseLock.Lock()
+	defer h.releaseLock.Unlock()
+
+	h.busy.Store(false)
+	if h.closeWhenIdle {
+		h.closeWhenIdle = false
+		_ = h.query.Close()
+	}
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloseQueryWhileBusy(t *testing.T) {
+	query := &sourceQuery{source: &fakeSource{}}
+	require.NoError(t, query.Open())
+	hostInfo := &hostCountersInfo{computer: "hostA", query: query}
+
+	// 被放弃等待的采集仍在使用查询，关闭交给采集的 goroutine
+	hostInfo.busy.Store(true)
+	require.NoError(t, hostInfo.closeQuery())
+	require.True(t, query.open)
+
+	hostInfo.finishCollect()
+	require.False(t, hostInfo.busy.Load())
+	require.False(t, query.open)
+
+	// 空闲的主机立即关闭
+	require.NoError(t, query.Open())
+	require.NoError(t, hostInfo.closeQuery())
+	require.False(t, query.open)
+	hostInfo.finishCollect()
+}
@@ -0,0 +1,108 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rokukoo/win_perf_counters"
+)
+
+const (
+	// defaultAdminAddr refresh 命令未指定 -admin 时连接的管理端点地址。
+	defaultAdminAddr = "127.0.0.1:8089"
+	// adminTokenEnv 未指定 -admin-token 时读取管理端点令牌的环境变量。
+	adminTokenEnv = "WIN_PERF_ADMIN_TOKEN"
+)
+
+// serveAdmin 在 addr 上提供管理端点 /admin/profile、/admin/refresh、/admin/resolve 与 /admin/health，addr 为空时不提供。
+// addr 只有端口时绑定 127.0.0.1；token 不为空时所有端点都要求 "Authorization: Bearer <token>"，
+// 为空时只能监听回环地址，避免任何能访问该端口的人触发刷新等开销较大的操作。
+func serveAdmin(m *win_perf_counters.WinPerfCounters, addr, token string, log win_perf_counters.Logger) (io.Closer, error) {
+	if addr == "" {
+		return io.NopCloser(nil), nil
+	}
+	addr = loopbackDefault(addr)
+	if token == "" && !isLoopback(addr) {
+		return nil, fmt.Errorf("refusing to serve admin endpoints on %q without -admin-token, set a token or use a loopback address", addr)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listening on admin address %q failed: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/admin/profile", m.ProfileHandler())
+	mux.Handle("/admin/refresh", m.RefreshHandler())
+	mux.Handle("/admin/resolve", m.ResolveHandler())
+	mux.Handle("/admin/health", m.HealthHandler())
+	server := &http.Server{Handler: win_perf_counters.RequireToken(token, mux), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Serving admin endpoints failed: %v", err)
+		}
+	}()
+	return server, nil
+}
+
+// refreshAgent 请求正在运行的采集代理立即刷新计数器。
+func refreshAgent(args []string) error {
+	flags := flag.NewFlagSet("refresh", flag.ContinueOnError)
+	addr := flags.String("admin", defaultAdminAddr, "admin address of the running agent")
+	token := flags.String("admin-token", os.Getenv(adminTokenEnv), "bearer token of the admin endpoints, defaults to $"+adminTokenEnv)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "http://"+loopbackDefault(*addr)+"/admin/refresh", nil)
+	if err != nil {
+		return err
+	}
+	if *token != "" {
+		req.Header.Set("Authorization", "Bearer "+*token)
+	}
+	// 刷新会等待正在进行的采集结束并重新完成首次采样
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("refresh failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	fmt.Println("Counters refreshed")
+	return nil
+}
+
+// loopbackDefault 为只有端口的地址（"8089" 或 ":8089"）补上 127.0.0.1，其它地址原样返回。
+func loopbackDefault(addr string) string {
+	if !strings.Contains(addr, ":") {
+		return net.JoinHostPort("127.0.0.1", addr)
+	}
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		return net.JoinHostPort("127.0.0.1", port)
+	}
+	return addr
+}
+
+// isLoopback 判断监听地址是否只能从本机访问。
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,110 @@
+//go:build windows
+
+// agent 在 Windows 主机上运行的采集代理，通过 HTTP+JSON 提供采集到的指标，
+// 供配置了 Sources = ["http://winhost:7090"] 的采集器（可以运行在 Linux 上）拉取，不需要防火墙放行 DCOM/RPC 远程 PDH。
+// 每次请求执行一次采集（一秒内的重复请求返回上一次的结果），采集间隔由拉取方决定。
+//
+// 默认只监听本机回环地址。监听其它地址时必须通过 -token（或环境变量 WIN_PERF_AGENT_TOKEN）配置令牌，
+// 拉取方在 AgentToken 中配置相同的令牌；可以通过 -tls-cert 与 -tls-key 启用 HTTPS。
+//
+//	go run ./cmd/agent -config config.toml -listen :7090 -token s3cr3t -tls-cert agent.crt -tls-key agent.key
+//	curl -H "Authorization: Bearer s3cr3t" "https://winhost:7090/v1/metrics"
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/rokukoo/win_perf_counters"
+)
+
+func main() {
+	configPath := flag.String("config", "", "configuration file (.toml, .conf, .yaml, .yml or .json)")
+	listen := flag.String("listen", "127.0.0.1:7090", "address to serve "+win_perf_counters.AgentMetricsPath+" on")
+	token := flag.String("token", os.Getenv(tokenEnv), "bearer token required from clients, defaults to $"+tokenEnv+"; required unless listening on a loopback address")
+	tlsCert := flag.String("tls-cert", "", "certificate file to serve HTTPS with")
+	tlsKey := flag.String("tls-key", "", "private key file of -tls-cert")
+	version := flag.Bool("version", false, "print the build information and exit")
+	flag.Parse()
+
+	if *version {
+		fmt.Println(win_perf_counters.Version())
+		return
+	}
+
+	if err := run(*configPath, *listen, *token, *tlsCert, *tlsKey); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// tokenEnv 未指定 -token 时读取令牌的环境变量，避免令牌出现在命令行中。
+const tokenEnv = "WIN_PERF_AGENT_TOKEN"
+
+// run 加载配置并在 listen 上提供指标端点，直到收到 Ctrl+C。
+func run(configPath, listen, token, tlsCert, tlsKey string) error {
+	if configPath == "" {
+		return errors.New("-config is required")
+	}
+	if (tlsCert == "") != (tlsKey == "") {
+		return errors.New("-tls-cert and -tls-key must be set together")
+	}
+	if token == "" && !isLoopback(listen) {
+		return fmt.Errorf("refusing to serve on %q without -token, set a token or listen on a loopback address", listen)
+	}
+	m, err := win_perf_counters.LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	m.Log = win_perf_counters.Logger{Name: "win_perf_counters"}
+	if err := m.Init(); err != nil {
+		return err
+	}
+	defer m.Close()
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("listening on %q failed: %w", listen, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(win_perf_counters.AgentMetricsPath, win_perf_counters.RequireToken(token, m.AgentHandler()))
+	mux.Handle("/health", win_perf_counters.RequireToken(token, m.HealthHandler()))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	m.Log.Infof("Serving metrics on %s%s, version %s", listener.Addr(), win_perf_counters.AgentMetricsPath, win_perf_counters.Version())
+	if tlsCert != "" {
+		err = server.ServeTLS(listener, tlsCert, tlsKey)
+	} else {
+		err = server.Serve(listener)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// isLoopback 判断监听地址是否只能从本机访问，未指定主机（例如 ":7090"）时监听所有接口。
+func isLoopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil || host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
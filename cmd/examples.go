@@ -0,0 +1,250 @@
+//go:build windows
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rokukoo/win_perf_counters"
+	"github.com/rokukoo/win_perf_counters/encoders"
+)
+
+// example 一个可运行的示例，只使用包的公开 API，同时作为公开接口的冒烟测试。
+type example struct {
+	// description 示例的说明。
+	description string
+	// run 以解析后的命令行参数运行示例。
+	run func(options exampleFlags) error
+}
+
+// examples 按名称列出的示例。
+var examples = map[string]example{
+	"cpu-to-stdout": {
+		description: "collect the Processor object and write InfluxDB line protocol to stdout",
+		run:         cpuToStdout,
+	},
+	"process-top10": {
+		description: "print the 10 processes using the most CPU after each sample",
+		run:         processTop10,
+	},
+	"remote-two-hosts": {
+		description: "collect the System object from two hosts and print the metrics per source",
+		run:         remoteTwoHosts,
+	},
+}
+
+// exampleFlags 示例共用的命令行参数。
+type exampleFlags struct {
+	// samples 采集的次数。
+	samples int
+	// interval 两次采集之间的间隔。
+	interval time.Duration
+	// simulate 是否使用合成数据，不依赖本机的计数器或远程主机。
+	simulate bool
+	// hosts remote-two-hosts 采集的主机。
+	hosts []string
+}
+
+// runExample 运行 args[0] 指定的示例，没有指定时列出所有示例。
+func runExample(args []string) error {
+	if len(args) == 0 || args[0] == "" || args[0][0] == '-' {
+		listExamples()
+		return errors.New("missing example name")
+	}
+	name := args[0]
+	ex, ok := examples[name]
+	if !ok {
+		listExamples()
+		return fmt.Errorf("unknown example %q", name)
+	}
+
+	flags := flag.NewFlagSet("example "+name, flag.ContinueOnError)
+	samples := flags.Int("samples", 3, "number of samples to collect")
+	interval := flags.Duration("interval", time.Second, "interval between two samples")
+	simulate := flags.Bool("simulate", false, "use synthetic data instead of the performance counters of the hosts")
+	hosts := flags.String("hosts", "", "comma separated hosts of remote-two-hosts, e.g. SQL-SERVER-01,SQL-SERVER-02")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *samples <= 0 || *interval <= 0 {
+		return errors.New("-samples and -interval should be positive")
+	}
+	options := exampleFlags{samples: *samples, interval: *interval, simulate: *simulate}
+	if *hosts != "" {
+		options.hosts = strings.Split(*hosts, ",")
+	}
+	return ex.run(options)
+}
+
+// listExamples 在标准错误中列出所有示例。
+func listExamples() {
+	w := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "examples:")
+	for _, name := range slices.Sorted(maps.Keys(examples)) {
+		fmt.Fprintf(w, "  %s\t%s\n", name, examples[name].description)
+	}
+	w.Flush()
+}
+
+// sampleLoop 调用 gather options.samples 次，两次之间等待 options.interval，收到 Ctrl+C 时提前结束。
+func sampleLoop(options exampleFlags, gather func(ctx context.Context, sample int) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ticker := time.NewTicker(options.interval)
+	defer ticker.Stop()
+	for i := 0; i < options.samples; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+		if err := gather(ctx, i+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cpuToStdout 以构造器配置 Processor 对象，通过 encoders 将指标以行协议写入标准输出。
+func cpuToStdout(options exampleFlags) error {
+	processor, err := win_perf_counters.NewObjectBuilder("Processor").
+		Counters("% Processor Time", "% User Time", "% Privileged Time").
+		Instances("*").
+		IncludeTotal().
+		Measurement("win_cpu").
+		Build()
+	if err != nil {
+		return err
+	}
+	write := encoders.NewWriterCollectFunc(os.Stdout, encoders.EncodeLineProtocol, func(err error) {
+		logger.Errorf("Writing metric failed: %v", err)
+	})
+	m, err := win_perf_counters.New(win_perf_counters.Options{
+		Objects:  []win_perf_counters.ObjectConfig{processor},
+		Simulate: options.simulate,
+	}, write)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	return sampleLoop(options, func(ctx context.Context, _ int) error {
+		return m.GatherContext(ctx)
+	})
+}
+
+// processTop10 通过 GatherMetrics 取得每次采集的全部进程指标，按 CPU 使用率排序后输出前 10 个进程。
+func processTop10(options exampleFlags) error {
+	process, err := win_perf_counters.NewObjectBuilder("Process").
+		Counters("% Processor Time", "Working Set", "ID Process").
+		Instances("*").
+		ExcludeInstances("Idle").
+		Measurement("win_proc").
+		Build()
+	if err != nil {
+		return err
+	}
+	m, err := win_perf_counters.New(win_perf_counters.Options{
+		Objects:  []win_perf_counters.ObjectConfig{process},
+		Simulate: options.simulate,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	cpuField := m.SanitizeFieldName("% Processor Time")
+	memoryField := m.SanitizeFieldName("Working Set")
+	pidField := m.SanitizeFieldName("ID Process")
+	return sampleLoop(options, func(_ context.Context, sample int) error {
+		metrics, err := m.GatherMetrics()
+		if err != nil {
+			return err
+		}
+		slices.SortFunc(metrics, func(a, b win_perf_counters.Metric) int {
+			return cmp.Compare(fieldValue(b, cpuField), fieldValue(a, cpuField))
+		})
+		fmt.Printf("sample %d:\n", sample)
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PROCESS\tPID\tCPU %\tWORKING SET (MB)")
+		for _, metric := range metrics[:min(len(metrics), 10)] {
+			fmt.Fprintf(w, "%s\t%.0f\t%.1f\t%.1f\n", metric.Tags["instance"], fieldValue(metric, pidField),
+				fieldValue(metric, cpuField), fieldValue(metric, memoryField)/(1<<20))
+		}
+		return w.Flush()
+	})
+}
+
+// remoteTwoHosts 从 -hosts 指定的两台主机采集 System 对象，通过 GatherBySource 按主机输出。
+// 一台主机失败不影响另一台，失败的主机从返回的 CounterError 中取得。
+func remoteTwoHosts(options exampleFlags) error {
+	hosts := options.hosts
+	if len(hosts) == 0 && options.simulate {
+		hosts = []string{"host-a", "host-b"}
+	}
+	if len(hosts) != 2 {
+		return errors.New("remote-two-hosts needs -hosts with two comma separated hosts")
+	}
+	system, err := win_perf_counters.NewObjectBuilder("System").
+		Counters("Processor Queue Length", "Context Switches/sec", "Processes").
+		Instances("------").
+		Measurement("win_system").
+		Build()
+	if err != nil {
+		return err
+	}
+	m, err := win_perf_counters.New(win_perf_counters.Options{
+		Sources:        hosts,
+		Objects:        []win_perf_counters.ObjectConfig{system},
+		CollectTimeout: 10 * time.Second,
+		Simulate:       options.simulate,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	return sampleLoop(options, func(_ context.Context, sample int) error {
+		bySource, err := m.GatherBySource()
+		var counterErr *win_perf_counters.CounterError
+		if errors.As(err, &counterErr) {
+			logger.Warnf("Host %q failed: %v", counterErr.Host, counterErr.Err)
+		} else if err != nil {
+			return err
+		}
+		fmt.Printf("sample %d:\n", sample)
+		for _, source := range slices.Sorted(maps.Keys(bySource)) {
+			for _, metric := range bySource[source] {
+				fmt.Printf("  %s %s %v\n", source, metric.Measurement, metric.Fields)
+			}
+		}
+		return nil
+	})
+}
+
+// fieldValue 以 float64 返回指标中字段的值，字段不存在或不是数值时返回 0。
+func fieldValue(metric win_perf_counters.Metric, field string) float64 {
+	switch v := metric.Fields[field].(type) {
+	case float64:
+		return v
+	case int64:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	}
+	return 0
+}
@@ -1,20 +1,46 @@
 //go:build windows
 
+// 采集代理示例：在控制台中运行，或注册为 Windows 服务运行。
+//
+//	main.exe [run] [-config path] [-admin addr]     在控制台中运行，Ctrl+C 退出
+//	main.exe run -samples N [-interval 30s]         采集 N 次后退出，适用于计划任务
+//	main.exe install [-config path] [-admin addr]   注册为自动启动的服务，并注册事件日志源
+//	main.exe uninstall                              删除服务及事件日志源
+//	main.exe refresh [-admin addr] [-admin-token t] 请求正在运行的代理立即刷新计数器
+//	main.exe resolve [-config path]                 列出配置的计数器路径匹配到的具体路径
+//	main.exe version                                输出构建信息
+//	main.exe example <name> [-samples N] [-simulate] 运行使用公开 API 的示例，不指定名称时列出所有示例
+//
+// 未指定 -config 时使用内嵌的 config.conf，指定 -admin 时在该地址上提供 /admin/profile、/admin/refresh、/admin/resolve 与 /admin/health 管理端点。
+// 管理端点默认只监听回环地址（-admin 只指定端口时绑定 127.0.0.1），监听其它地址时必须通过 -admin-token
+// （或环境变量 WIN_PERF_ADMIN_TOKEN）配置令牌，请求需带有 "Authorization: Bearer <令牌>"。
 package main
 
 import (
+	"context"
 	_ "embed"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
 	"time"
 
-	"github.com/BurntSushi/toml"
+	"golang.org/x/sys/windows/svc"
+
 	"github.com/rokukoo/win_perf_counters"
 )
 
 //go:embed config.conf
 var config string
 
+// defaultSampleInterval -samples 未指定 -interval 且配置中没有采集间隔时两次采集之间的间隔。
+const defaultSampleInterval = 10 * time.Second
+
+// serviceName 注册的服务名称，同时作为事件日志源的名称。
+const serviceName = "win_perf_counters"
+
 var logger = win_perf_counters.Logger{
-	Name: "win_perf_counters",
+	Name:  "win_perf_counters",
 	Quiet: false,
 }
 
@@ -23,17 +49,184 @@ func collectFunc(measurement string, fields map[string]interface{}, tags map[str
 	logger.Infof("[采集时间]%v [测量]%s [标签]%v [字段]%v\n", timestamp, measurement, tags, fields)
 }
 
+// agentFlags 运行采集代理的命令行参数。
+type agentFlags struct {
+	// configPath 配置文件路径，为空时使用内嵌的 config.conf。
+	configPath string
+	// admin 管理端点的监听地址，为空时不提供。
+	admin string
+	// adminToken 管理端点要求的令牌，为空时只能监听回环地址。
+	adminToken string
+	// samples 采集的次数，大于 0 时采集完成后退出，仅用于 run。
+	samples int
+	// interval samples 大于 0 时两次采集之间的间隔，为 0 时使用配置的采集间隔。
+	interval time.Duration
+}
+
+// parseFlags 从命令行参数中解析 -config、-admin、-admin-token、-samples 与 -interval。
+func parseFlags(name string, args []string) (agentFlags, error) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := flags.String("config", "", "configuration file (.toml, .conf, .yaml, .yml or .json), the embedded config.conf when empty")
+	admin := flags.String("admin", "", "address of the admin endpoints /admin/profile, /admin/refresh, /admin/resolve and /admin/health, e.g. 127.0.0.1:8089, a bare port binds to 127.0.0.1, disabled when empty")
+	adminToken := flags.String("admin-token", os.Getenv(adminTokenEnv), "bearer token required by the admin endpoints, defaults to $"+adminTokenEnv+"; required unless -admin is a loopback address")
+	samples := flags.Int("samples", 0, "number of samples to collect before exiting, run until interrupted when 0")
+	interval := flags.Duration("interval", 0, "interval between the samples of -samples, the configured Interval when 0")
+	if err := flags.Parse(args); err != nil {
+		return agentFlags{}, err
+	}
+	if *samples < 0 || *interval < 0 {
+		return agentFlags{}, fmt.Errorf("-samples and -interval should not be negative")
+	}
+	return agentFlags{configPath: *configPath, admin: *admin, adminToken: *adminToken, samples: *samples, interval: *interval}, nil
+}
+
+// newAgent 加载配置并初始化采集器，collect 为 nil 时指标只发送给配置中的输出（如 LogOutputPath）。
+func newAgent(configPath string, log win_perf_counters.Logger, collect win_perf_counters.CollectFunc) (*win_perf_counters.WinPerfCounters, error) {
+	var m *win_perf_counters.WinPerfCounters
+	var err error
+	if configPath == "" {
+		m, err = win_perf_counters.ParseConfig([]byte(config), win_perf_counters.ConfigTOML)
+	} else {
+		m, err = win_perf_counters.LoadConfig(configPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.Log = log
+	m.AddCollectFunc(nil, collect)
+	if err := m.Init(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// runConsole 在控制台中运行，直到收到 Ctrl+C。
+func runConsole(options agentFlags) error {
+	m, err := newAgent(options.configPath, logger, collectFunc)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	admin, err := serveAdmin(m, options.admin, options.adminToken, logger)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if options.samples > 0 {
+		return collectSamples(ctx, m, options.samples, options.interval)
+	}
+	if err := m.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// collectSamples 采集 samples 次后返回，两次采集之间等待 interval。单次采集的错误写入日志，不影响之后的采集；
+// 收到 Ctrl+C 时提前结束。第一次采集会先添加计数器并完成首次采样，速率类计数器在第一次采集中就有值。
+func collectSamples(ctx context.Context, m *win_perf_counters.WinPerfCounters, samples int, interval time.Duration) error {
+	if interval == 0 {
+		interval = m.GatherInterval()
+	}
+	if interval == 0 {
+		interval = time.Duration(m.Interval)
+	}
+	if interval == 0 {
+		interval = defaultSampleInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 0; i < samples; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+		if err := m.GatherContext(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			logger.Errorf("Gathering sample %d of %d failed: %v", i+1, samples, err)
+		}
+	}
+	logger.Infof("Collected %d samples", samples)
+	return nil
+}
+
+// printVersion 输出采集器的构建信息。
+func printVersion() error {
+	info := win_perf_counters.BuildInfo()
+	fmt.Printf("win_perf_counters %s\n", info.Version)
+	if info.Commit != "" {
+		modified := ""
+		if info.Modified {
+			modified = " (modified)"
+		}
+		fmt.Printf("commit:     %s%s\n", info.Commit, modified)
+	}
+	if info.BuildDate != "" {
+		fmt.Printf("build date: %s\n", info.BuildDate)
+	}
+	fmt.Printf("go version: %s\n", info.GoVersion)
+	return nil
+}
+
+func run() error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+	if isService {
+		options, err := parseFlags(serviceName, os.Args[1:])
+		if err != nil {
+			return err
+		}
+		return runService(options)
+	}
+
+	command, args := "run", os.Args[1:]
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		command, args = args[0], args[1:]
+	}
+	switch command {
+	case "run":
+		options, err := parseFlags(command, args)
+		if err != nil {
+			return err
+		}
+		return runConsole(options)
+	case "install":
+		options, err := parseFlags(command, args)
+		if err != nil {
+			return err
+		}
+		if options.samples > 0 {
+			return fmt.Errorf("-samples is only supported by the run command")
+		}
+		return installService(options)
+	case "uninstall":
+		return uninstallService()
+	case "refresh":
+		return refreshAgent(args)
+	case "resolve":
+		return resolveConfig(args)
+	case "version":
+		return printVersion()
+	case "example":
+		return runExample(args)
+	}
+	return fmt.Errorf("unknown command %q, expected run, install, uninstall, refresh, resolve, version or example", command)
+}
+
 func main() {
-	winPerfCounters := win_perf_counters.NewWinPerfCounters(collectFunc)
-	if _, err := toml.Decode(config, winPerfCounters); err != nil {
-		panic(err)
-	}
-	winPerfCounters.Init()
-
-	ticker := time.NewTicker(1 * time.Second)
-    defer ticker.Stop()
-    for {
-        <-ticker.C
-        winPerfCounters.Gather()
-    }
-}
\ No newline at end of file
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,55 @@
+//go:build windows
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// resolveConfig 加载配置并列出每个计数器路径模式匹配到的具体路径，存在没有匹配的模式时返回错误，可用于部署前校验配置。
+func resolveConfig(args []string) error {
+	flags := flag.NewFlagSet("resolve", flag.ContinueOnError)
+	configPath := flags.String("config", "", "configuration file (.toml, .conf, .yaml, .yml or .json), the embedded config.conf when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	m, err := newAgent(*configPath, logger, nil)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	resolved, err := m.ResolveConfig()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	unmatched := 0
+	for _, pattern := range resolved {
+		switch {
+		case pattern.Error != "":
+			unmatched++
+			fmt.Fprintf(w, "%s\t%s\n", pattern.Pattern, "ERROR: "+pattern.Error)
+		case len(pattern.Paths) == 0:
+			unmatched++
+			fmt.Fprintf(w, "%s\t%s\n", pattern.Pattern, "no match")
+		default:
+			fmt.Fprintf(w, "%s\t%d paths\n", pattern.Pattern, len(pattern.Paths))
+			for _, path := range pattern.Paths {
+				fmt.Fprintf(w, "  %s\t\n", path)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if unmatched > 0 {
+		return fmt.Errorf("%d of %d patterns matched no counters", unmatched, len(resolved))
+	}
+	fmt.Printf("All %d patterns resolved\n", len(resolved))
+	return nil
+}
@@ -0,0 +1,167 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+
+	"github.com/rokukoo/win_perf_counters"
+)
+
+// agentService 以 Windows 服务方式运行采集器。
+type agentService struct {
+	// options 注册服务时指定的命令行参数。
+	options agentFlags
+	log     win_perf_counters.Logger
+}
+
+// runService 在服务控制管理器中运行，日志写入事件日志。
+func runService(options agentFlags) error {
+	events, err := win_perf_counters.NewEventLogger(serviceName)
+	if err != nil {
+		return err
+	}
+	defer events.Close()
+	return svc.Run(serviceName, &agentService{options: options, log: win_perf_counters.Logger{Handler: events}})
+}
+
+// Execute 实现 svc.Handler：启动调度器并响应停止、关机、暂停和继续请求。
+// 启动服务时传入的 -config、-admin、-admin-token 参数优先于注册服务时指定的参数。
+func (s *agentService) Execute(args []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	const accepts = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptPauseAndContinue
+	changes <- svc.Status{State: svc.StartPending}
+
+	options := s.options
+	if len(args) > 1 {
+		started, err := parseFlags(serviceName, args[1:])
+		if err != nil {
+			s.log.Errorf("Parsing service arguments failed: %v", err)
+			return true, 1
+		}
+		if started.configPath != "" {
+			options.configPath = started.configPath
+		}
+		if started.admin != "" {
+			options.admin = started.admin
+		}
+		if started.adminToken != "" {
+			options.adminToken = started.adminToken
+		}
+	}
+	m, err := newAgent(options.configPath, s.log, nil)
+	if err != nil {
+		s.log.Errorf("Starting agent failed: %v", err)
+		return true, 2
+	}
+	defer m.Close()
+	admin, err := serveAdmin(m, options.admin, options.adminToken, s.log)
+	if err != nil {
+		s.log.Errorf("Starting admin endpoints failed: %v", err)
+		return true, 4
+	}
+	defer admin.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.Start(ctx); err != nil {
+		s.log.Errorf("Starting scheduler failed: %v", err)
+		return true, 3
+	}
+	changes <- svc.Status{State: svc.Running, Accepts: accepts}
+	s.log.Infof("Service started")
+
+	for request := range requests {
+		switch request.Cmd {
+		case svc.Interrogate:
+			changes <- request.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			changes <- svc.Status{State: svc.StopPending}
+			s.log.Infof("Service stopping")
+			return false, 0
+		case svc.Pause:
+			changes <- svc.Status{State: svc.PausePending}
+			m.Stop()
+			changes <- svc.Status{State: svc.Paused, Accepts: accepts}
+		case svc.Continue:
+			changes <- svc.Status{State: svc.ContinuePending}
+			if err := m.Start(ctx); err != nil {
+				s.log.Errorf("Resuming scheduler failed: %v", err)
+			}
+			changes <- svc.Status{State: svc.Running, Accepts: accepts}
+		default:
+			s.log.Warnf("Unexpected service control request %d", request.Cmd)
+		}
+	}
+	return false, 0
+}
+
+// installService 将当前程序注册为自动启动的服务，-config、-admin 与 -admin-token 作为服务的启动参数，并注册事件日志源。
+func installService(options agentFlags) error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	var args []string
+	if options.configPath != "" {
+		configPath, err := filepath.Abs(options.configPath)
+		if err != nil {
+			return err
+		}
+		args = append(args, "-config", configPath)
+	}
+	if options.admin != "" {
+		args = append(args, "-admin", options.admin)
+	}
+	if options.adminToken != "" {
+		args = append(args, "-admin-token", options.adminToken)
+	}
+
+	manager, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer manager.Disconnect()
+	if service, err := manager.OpenService(serviceName); err == nil {
+		service.Close()
+		return fmt.Errorf("service %q already exists", serviceName)
+	}
+	service, err := manager.CreateService(serviceName, exePath, mgr.Config{
+		DisplayName: "Windows Performance Counters Agent",
+		Description: "Collects Windows performance counters.",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	defer service.Close()
+	if err := eventlog.InstallAsEventCreate(serviceName, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		_ = service.Delete()
+		return fmt.Errorf("registering event log source failed: %w", err)
+	}
+	return nil
+}
+
+// uninstallService 删除服务及其事件日志源。
+func uninstallService() error {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("service %q is not installed: %w", serviceName, err)
+	}
+	defer service.Close()
+	if err := service.Delete(); err != nil {
+		return err
+	}
+	return eventlog.Remove(serviceName)
+}
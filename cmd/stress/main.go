@@ -0,0 +1,166 @@
+//go:build windows
+
+// stress 以较高的频率长时间采集大量计数器，报告内存增长、句柄数量和采集耗时的分位数，
+// 用于在机群部署前评估采集代理所需的资源。
+//
+//	go run ./cmd/stress -config config.toml -interval 200ms -duration 10m
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/rokukoo/win_perf_counters"
+)
+
+// processMemoryCounters 对应 PROCESS_MEMORY_COUNTERS。
+type processMemoryCounters struct {
+	cb                         uint32
+	pageFaultCount             uint32
+	peakWorkingSetSize         uintptr
+	workingSetSize             uintptr
+	quotaPeakPagedPoolUsage    uintptr
+	quotaPagedPoolUsage        uintptr
+	quotaPeakNonPagedPoolUsage uintptr
+	quotaNonPagedPoolUsage     uintptr
+	pagefileUsage              uintptr
+	peakPagefileUsage          uintptr
+}
+
+var (
+	kernel32                  = windows.NewLazySystemDLL("kernel32.dll")
+	procGetProcessHandleCount = kernel32.NewProc("GetProcessHandleCount")
+	procGetProcessMemoryInfo  = kernel32.NewProc("K32GetProcessMemoryInfo")
+)
+
+// usage 进程在某一时刻的资源占用。
+type usage struct {
+	heapBytes    uint64
+	privateBytes uint64
+	workingSet   uint64
+	handles      uint32
+	goroutines   int
+}
+
+func measure() usage {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	u := usage{heapBytes: stats.HeapAlloc, goroutines: runtime.NumGoroutine()}
+
+	process := windows.CurrentProcess()
+	var handles uint32
+	if r, _, _ := procGetProcessHandleCount.Call(uintptr(process), uintptr(unsafe.Pointer(&handles))); r != 0 { //nolint:gosec // G103: Valid use of unsafe call to pass handles
+		u.handles = handles
+	}
+	counters := processMemoryCounters{}
+	counters.cb = uint32(unsafe.Sizeof(counters))
+	if r, _, _ := procGetProcessMemoryInfo.Call(uintptr(process), uintptr(unsafe.Pointer(&counters)), uintptr(counters.cb)); r != 0 { //nolint:gosec // G103: Valid use of unsafe call to pass counters
+		u.privateBytes = uint64(counters.pagefileUsage)
+		u.workingSet = uint64(counters.workingSetSize)
+	}
+	return u
+}
+
+// defaultObjects 未指定配置文件时采集的对象：常用的预置以及所有进程。
+func defaultObjects(m *win_perf_counters.WinPerfCounters) error {
+	m.Presets = []string{"cpu", "memory", "disk", "network", "system"}
+	return m.AddObject("Process").
+		Counters("% Processor Time", "Working Set", "Private Bytes", "Handle Count", "Thread Count", "IO Read Bytes/sec", "IO Write Bytes/sec").
+		Measurement("win_proc").
+		Add()
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
+
+func mib(bytes uint64) float64 {
+	return float64(bytes) / (1 << 20)
+}
+
+func report(label string, start, current usage, latencies []time.Duration, gathers, metrics, errors int64) {
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	fmt.Printf("[%s] gathers=%d errors=%d metrics=%d\n", label, gathers, errors, metrics)
+	fmt.Printf("  latency   p50=%v p90=%v p99=%v max=%v\n",
+		percentile(sorted, 0.5), percentile(sorted, 0.9), percentile(sorted, 0.99), percentile(sorted, 1))
+	fmt.Printf("  go heap   %.1f MiB (%+.1f MiB)\n", mib(current.heapBytes), mib(current.heapBytes)-mib(start.heapBytes))
+	fmt.Printf("  private   %.1f MiB (%+.1f MiB)\n", mib(current.privateBytes), mib(current.privateBytes)-mib(start.privateBytes))
+	fmt.Printf("  working   %.1f MiB (%+.1f MiB)\n", mib(current.workingSet), mib(current.workingSet)-mib(start.workingSet))
+	fmt.Printf("  handles   %d (%+d)\n", current.handles, int64(current.handles)-int64(start.handles))
+	fmt.Printf("  goroutines %d (%+d)\n", current.goroutines, current.goroutines-start.goroutines)
+}
+
+func main() {
+	configPath := flag.String("config", "", "configuration file (.toml, .conf, .yaml, .yml or .json); common presets and all processes when empty")
+	interval := flag.Duration("interval", 200*time.Millisecond, "time between the starts of two gathers")
+	duration := flag.Duration("duration", time.Minute, "total duration of the test")
+	reportEvery := flag.Duration("report", 10*time.Second, "interval of progress reports, 0 to only report at the end")
+	simulate := flag.Bool("simulate", false, "use synthetic data instead of real performance counters")
+	flag.Parse()
+
+	var m *win_perf_counters.WinPerfCounters
+	var err error
+	if *configPath != "" {
+		m, err = win_perf_counters.LoadConfig(*configPath)
+	} else {
+		m = win_perf_counters.NewWinPerfCounters(nil)
+		err = defaultObjects(m)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	m.Simulate = m.Simulate || *simulate
+	m.Log = win_perf_counters.Logger{Name: "win_perf_counters", Quiet: true}
+
+	var metrics atomic.Int64
+	m.AddCollectFunc(nil, func(string, map[string]interface{}, map[string]string, time.Time) {
+		metrics.Add(1)
+	})
+	if err := m.Init(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer m.Close()
+
+	// 第一次采集添加计数器，不计入统计
+	if err := m.Gather(); err != nil {
+		fmt.Fprintf(os.Stderr, "first gather: %v\n", err)
+	}
+	metrics.Store(0)
+	start := measure()
+
+	var latencies []time.Duration
+	var gathers, errors int64
+	deadline := time.Now().Add(*duration)
+	nextReport := time.Now().Add(*reportEvery)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for time.Now().Before(deadline) {
+		began := time.Now()
+		if err := m.Gather(); err != nil {
+			errors++
+		}
+		latencies = append(latencies, time.Since(began))
+		gathers++
+		if *reportEvery > 0 && time.Now().After(nextReport) {
+			report("progress", start, measure(), latencies, gathers, metrics.Load(), errors)
+			nextReport = time.Now().Add(*reportEvery)
+		}
+		<-ticker.C
+	}
+	report("result", start, measure(), latencies, gathers, metrics.Load(), errors)
+}
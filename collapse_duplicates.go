@@ -0,0 +1,105 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validateCollapseDuplicates 校验所有对象的 CollapseDuplicates 配置。
+func (m *WinPerfCounters) validateCollapseDuplicates(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].validateCollapseDuplicates(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateCollapseDuplicates 校验对象的 CollapseDuplicates 配置。合并后的实例不再对应单个进程，
+// 不能与按实例区分进程的 InstanceIDCounter、ProcessPID、ProcessPath 以及 PerFieldTimestamps 同时使用。
+func (o *ObjectConfig) validateCollapseDuplicates() error {
+	switch o.CollapseDuplicates {
+	case "":
+		return nil
+	case "sum", "avg":
+	default:
+		return fmt.Errorf("invalid CollapseDuplicates %q of object %q, expected sum or avg", o.CollapseDuplicates, o.ObjectName)
+	}
+	if o.InstanceIDCounter != "" || o.ProcessPID || o.ProcessPath || o.PerFieldTimestamps {
+		return fmt.Errorf("CollapseDuplicates of object %q cannot be combined with InstanceIDCounter, ProcessPID, ProcessPath or PerFieldTimestamps", o.ObjectName)
+	}
+	return nil
+}
+
+// duplicateBaseName 返回 "名称#索引" 形式的重复实例名称中的名称部分，不是重复实例时 ok 为 false。
+func duplicateBaseName(instance string) (name string, ok bool) {
+	i := strings.LastIndexByte(instance, '#')
+	if i <= 0 || i == len(instance)-1 {
+		return instance, false
+	}
+	for _, c := range instance[i+1:] {
+		if c < '0' || c > '9' {
+			return instance, false
+		}
+	}
+	return instance[:i], true
+}
+
+// applyDuplicateCollapse 将配置了 CollapseDuplicates 的对象中 "名称"、"名称#1"、"名称#2" 等同名实例合并为一个实例 "名称"，
+// 数值字段按 sum 或 avg 合并，并追加参与合并的实例数量 duplicate_count。重复实例随进程启停出现和消失时，
+// 合并后的序列保持不变，不会产生大量短命的序列。没有重复的实例保持原样。
+//
+// 同时启用 NormalizeInstanceNames 时按清理后的名称合并，清理后相同的实例（例如 GUID 不同的同名网络接口）
+// 也合并为一个实例，避免输出标签相同的多条指标。
+func (m *WinPerfCounters) applyDuplicateCollapse(hostInfo *hostCountersInfo, collectedFields fieldGrouping, groupObjects map[instanceGrouping]*ObjectConfig) {
+	members := make(map[instanceGrouping][]instanceGrouping)
+	indexed := make(map[instanceGrouping]bool)
+	for grouping := range collectedFields {
+		object := groupObjects[grouping]
+		if object == nil || object.CollapseDuplicates == "" {
+			continue
+		}
+		name, ok := duplicateBaseName(grouping.instance)
+		if object.NormalizeInstanceNames && grouping.instance != "_Total" {
+			if normalized := m.normalizeInstance(hostInfo.computer, object.ObjectName, grouping.instance); normalized != "" {
+				name = normalized
+			}
+		}
+		key := instanceGrouping{name: grouping.name, instance: name, objectName: grouping.objectName}
+		members[key] = append(members[key], grouping)
+		indexed[key] = indexed[key] || ok
+	}
+
+	for key, groupings := range members {
+		// 单个没有序号的实例保持原样，清理名称在输出标签时完成
+		if len(groupings) == 1 && !indexed[key] {
+			continue
+		}
+		object := groupObjects[groupings[0]]
+		values := make(map[string]*aggregateValue)
+		for _, grouping := range groupings {
+			for field, value := range collectedFields[grouping] {
+				v, ok := toFloat(value)
+				if !ok {
+					continue
+				}
+				if values[field] == nil {
+					values[field] = &aggregateValue{function: object.CollapseDuplicates}
+				}
+				values[field].add(v)
+			}
+			delete(collectedFields, grouping)
+			delete(groupObjects, grouping)
+		}
+
+		fields := make(map[string]interface{}, len(values)+1)
+		for field, value := range values {
+			fields[field] = value.result()
+		}
+		fields["duplicate_count"] = int64(len(groupings))
+		collectedFields[key] = fields
+		groupObjects[key] = object
+	}
+}
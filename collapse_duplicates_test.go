@@ -0,0 +1,140 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateBaseName(t *testing.T) {
+	tests := []struct {
+		instance string
+		name     string
+		ok       bool
+	}{
+		{"chrome#1", "chrome", true},
+		{"chrome#12", "chrome", true},
+		{"my#app#3", "my#app", true},
+		{"chrome", "chrome", false},
+		{"chrome#", "chrome#", false},
+		{"#1", "#1", false},
+		{"chrome#a1", "chrome#a1", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		name, ok := duplicateBaseName(tt.instance)
+		require.Equal(t, tt.name, name, tt.instance)
+		require.Equal(t, tt.ok, ok, tt.instance)
+	}
+}
+
+func TestValidateCollapseDuplicates(t *testing.T) {
+	tests := []struct {
+		name    string
+		object  ObjectConfig
+		wantErr string
+	}{
+		{name: "disabled", object: ObjectConfig{ProcessPID: true}},
+		{name: "sum", object: ObjectConfig{CollapseDuplicates: "sum"}},
+		{name: "avg", object: ObjectConfig{CollapseDuplicates: "avg"}},
+		{
+			name:    "unknown function",
+			object:  ObjectConfig{ObjectName: "Process", CollapseDuplicates: "max"},
+			wantErr: `invalid CollapseDuplicates "max" of object "Process"`,
+		},
+		{
+			name:    "with ProcessPID",
+			object:  ObjectConfig{ObjectName: "Process", CollapseDuplicates: "sum", ProcessPID: true},
+			wantErr: `CollapseDuplicates of object "Process" cannot be combined`,
+		},
+		{
+			name:    "with InstanceIDCounter",
+			object:  ObjectConfig{ObjectName: "Process", CollapseDuplicates: "sum", InstanceIDCounter: "ID Process"},
+			wantErr: `CollapseDuplicates of object "Process" cannot be combined`,
+		},
+	}
+	for _, tt := range tests {
+		err := tt.object.validateCollapseDuplicates()
+		if tt.wantErr != "" {
+			require.ErrorContains(t, err, tt.wantErr, tt.name)
+			continue
+		}
+		require.NoError(t, err, tt.name)
+	}
+}
+
+func TestApplyDuplicateCollapse(t *testing.T) {
+	grouping := func(instance string) instanceGrouping {
+		return instanceGrouping{name: "win_proc", instance: instance, objectName: "Process"}
+	}
+	tests := []struct {
+		name     string
+		function string
+		fields   fieldGrouping
+		want     fieldGrouping
+	}{
+		{
+			name:     "sum",
+			function: "sum",
+			fields: fieldGrouping{
+				grouping("chrome"):   {"Working_Set": 100.0, "Name": "chrome"},
+				grouping("chrome#1"): {"Working_Set": 200.0},
+				grouping("chrome#2"): {"Working_Set": int64(300)},
+				grouping("svchost"):  {"Working_Set": 50.0},
+			},
+			want: fieldGrouping{
+				grouping("chrome"):  {"Working_Set": 600.0, "duplicate_count": int64(3)},
+				grouping("svchost"): {"Working_Set": 50.0},
+			},
+		},
+		{
+			name:     "avg",
+			function: "avg",
+			fields: fieldGrouping{
+				grouping("chrome"):   {"Working_Set": 100.0},
+				grouping("chrome#1"): {"Working_Set": 300.0},
+			},
+			want: fieldGrouping{
+				grouping("chrome"): {"Working_Set": 200.0, "duplicate_count": int64(2)},
+			},
+		},
+		{
+			// 只剩下带序号的实例时仍合并为不带序号的名称，序列保持不变
+			name:     "single indexed instance",
+			function: "sum",
+			fields: fieldGrouping{
+				grouping("chrome#3"): {"Working_Set": 100.0},
+			},
+			want: fieldGrouping{
+				grouping("chrome"): {"Working_Set": 100.0, "duplicate_count": int64(1)},
+			},
+		},
+		{
+			name: "disabled",
+			fields: fieldGrouping{
+				grouping("chrome"):   {"Working_Set": 100.0},
+				grouping("chrome#1"): {"Working_Set": 300.0},
+			},
+			want: fieldGrouping{
+				grouping("chrome"):   {"Working_Set": 100.0},
+				grouping("chrome#1"): {"Working_Set": 300.0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		object := &ObjectConfig{ObjectName: "Process", CollapseDuplicates: tt.function}
+		groupObjects := make(map[instanceGrouping]*ObjectConfig)
+		for grouping := range tt.fields {
+			groupObjects[grouping] = object
+		}
+		m := &WinPerfCounters{}
+		m.applyDuplicateCollapse(&hostCountersInfo{}, tt.fields, groupObjects)
+		require.Equal(t, tt.want, tt.fields, tt.name)
+		require.Len(t, groupObjects, len(tt.want), tt.name)
+		for grouping := range tt.want {
+			require.Same(t, object, groupObjects[grouping], tt.name)
+		}
+	}
+}
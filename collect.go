@@ -0,0 +1,156 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// collectRoute 表示一个带过滤条件的采集回调。
+type collectRoute struct {
+	// predicate 过滤条件，为 nil 时接收全部指标。
+	predicate CollectPredicate
+	// collect 采集回调。
+	collect CollectFunc
+}
+
+// AddCollectFunc 注册一个额外的采集回调。
+//
+// 每条指标会依次与所有已注册回调的 predicate 进行匹配，匹配成功的回调都会收到该指标。
+// predicate 为 nil 时该回调接收全部指标。通过 NewWinPerfCounters 传入的回调始终接收全部指标。
+func (m *WinPerfCounters) AddCollectFunc(predicate CollectPredicate, collectFunc CollectFunc) {
+	if collectFunc == nil {
+		return
+	}
+	m.routesLock.Lock()
+	defer m.routesLock.Unlock()
+	m.routes = append(m.routes, collectRoute{predicate: predicate, collect: collectFunc})
+}
+
+// AddEnrichFunc 注册一个指标增强函数，多个增强函数按注册顺序依次执行。
+func (m *WinPerfCounters) AddEnrichFunc(enrichFunc EnrichFunc) {
+	if enrichFunc == nil {
+		return
+	}
+	m.routesLock.Lock()
+	defer m.routesLock.Unlock()
+	m.enrichers = append(m.enrichers, enrichFunc)
+}
+
+// AddGatherDoneFunc 注册采集结束回调，每次采集的全部指标输出之后调用。
+func (m *WinPerfCounters) AddGatherDoneFunc(gatherDoneFunc GatherDoneFunc) {
+	if gatherDoneFunc == nil {
+		return
+	}
+	m.routesLock.Lock()
+	defer m.routesLock.Unlock()
+	m.gatherDoneFuncs = append(m.gatherDoneFuncs, gatherDoneFunc)
+}
+
+// notifyGatherDone 调用所有采集结束回调，回调中可以再注册回调。
+func (m *WinPerfCounters) notifyGatherDone() {
+	m.routesLock.RLock()
+	gatherDoneFuncs := m.gatherDoneFuncs
+	m.routesLock.RUnlock()
+	for _, gatherDoneFunc := range gatherDoneFuncs {
+		gatherDoneFunc()
+	}
+}
+
+// emit 依次执行增强函数，然后将指标分发给主回调以及所有匹配的附加回调。
+// 回调在释放 routesLock 之后调用，回调中可以再注册回调。
+func (m *WinPerfCounters) emit(measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time) {
+	m.routesLock.RLock()
+	enrichers, capture, collect, routes := m.enrichers, m.capture, m.collect, m.routes
+	m.routesLock.RUnlock()
+
+	if len(enrichers) > 0 {
+		metric := &Metric{Measurement: measurement, Tags: tags, Fields: fields, Timestamp: timestamp}
+		for _, enrich := range enrichers {
+			if !enrich(metric) {
+				return
+			}
+		}
+		measurement, tags, fields, timestamp = metric.Measurement, metric.Tags, metric.Fields, metric.Timestamp
+	}
+	// 历史样本、突发触发和过滤条件使用标准的标签名称，回调收到改名后的标签
+	output := m.renameTags(tags)
+	if m.InternTags {
+		output = m.tagInterner.intern(output, time.Now())
+	}
+	if len(m.TagKeyOverrides) == 0 {
+		tags = output
+	}
+
+	if len(m.Burst) > 0 {
+		m.checkBurstTriggers(measurement, fields, tags)
+	}
+	if m.History > 0 {
+		m.history.record(time.Duration(m.History), measurement, fields, tags, timestamp)
+	}
+	if capture != nil {
+		capture(measurement, fields, output, timestamp)
+	}
+	if collect != nil {
+		collect(measurement, fields, output, timestamp)
+	}
+	for _, route := range routes {
+		if route.predicate == nil || route.predicate(measurement, tags) {
+			route.collect(measurement, fields, output, timestamp)
+		}
+	}
+	for _, sink := range m.matchingSinks(measurement, tags) {
+		sink(measurement, fields, output, timestamp)
+	}
+	m.emitWithPrevious(measurement, fields, tags, output, timestamp)
+}
+
+// GatherMetrics 执行一次采集，并返回本次输出的全部指标，便于调用方自行批量处理、过滤和转发，
+// 而不必使用回调。已注册的采集回调仍会照常收到这些指标。
+func (m *WinPerfCounters) GatherMetrics() ([]Metric, error) {
+	if err := m.initialRefreshPending(); err != nil {
+		return nil, err
+	}
+	// 持有 gatherLock 直到采集结束，避免同时进行的其它采集的指标混入本次结果
+	m.gatherLock.Lock()
+	defer m.gatherLock.Unlock()
+
+	var lock sync.Mutex
+	var metrics []Metric
+
+	m.routesLock.Lock()
+	m.capture = func(measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time) {
+		lock.Lock()
+		defer lock.Unlock()
+		metrics = append(metrics, Metric{
+			Measurement: measurement,
+			Tags:        tags,
+			Fields:      fields,
+			Timestamp:   timestamp,
+			Metadata:    m.metricMetadata(measurement, fields),
+		})
+	}
+	m.routesLock.Unlock()
+	defer func() {
+		m.routesLock.Lock()
+		m.capture = nil
+		m.routesLock.Unlock()
+	}()
+
+	err := m.gatherLocked(context.Background())
+	return metrics, err
+}
+
+// GatherBySource 执行一次采集，并按 source 标签分组返回本次输出的全部指标，
+// 便于多主机部署时分别处理每个数据源的结果。已注册的采集回调仍会照常收到这些指标。
+func (m *WinPerfCounters) GatherBySource() (map[string][]Metric, error) {
+	metrics, err := m.GatherMetrics()
+	result := make(map[string][]Metric)
+	for _, metric := range metrics {
+		source := metric.Tags[m.tagKey("source")]
+		result[source] = append(result[source], metric)
+	}
+	return result, err
+}
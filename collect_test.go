@@ -0,0 +1,51 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmitCallbacksCanRegister(t *testing.T) {
+	m := NewWinPerfCounters(func(string, map[string]interface{}, map[string]string, time.Time) {})
+	noop := func(string, map[string]interface{}, map[string]string, time.Time) {}
+
+	var collected, enriched, previous int
+	// 回调在释放 routesLock 之后调用，回调中注册回调不会死锁
+	m.AddEnrichFunc(func(*Metric) bool {
+		enriched++
+		m.AddEnrichFunc(func(*Metric) bool { return true })
+		return true
+	})
+	m.AddCollectFunc(nil, func(string, map[string]interface{}, map[string]string, time.Time) {
+		collected++
+		m.AddCollectFunc(nil, noop)
+		m.RegisterSink("late", noop)
+	})
+	m.AddCollectWithPreviousFunc(nil, func(string, map[string]interface{}, map[string]PreviousValue, map[string]string, time.Time) {
+		previous++
+		m.AddCollectWithPreviousFunc(nil, func(string, map[string]interface{}, map[string]PreviousValue, map[string]string, time.Time) {})
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.emit("win_cpu", map[string]interface{}{"value": 1.0}, map[string]string{"host": "a"}, time.Now())
+		m.emit("win_cpu", map[string]interface{}{"value": 2.0}, map[string]string{"host": "a"}, time.Now())
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "emit deadlocked")
+	}
+	require.Equal(t, 2, enriched)
+	require.Equal(t, 2, collected)
+	require.Equal(t, 2, previous)
+	// 第一条指标输出期间注册的回调从下一条指标开始生效
+	require.Len(t, m.routes, 3)
+	require.Len(t, m.enrichers, 3)
+	require.Len(t, m.previousRoutes, 3)
+}
@@ -0,0 +1,90 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// collectionErrorBuffer Errors 返回的通道的容量，通道已满时新的错误被丢弃。
+const collectionErrorBuffer = 256
+
+// Errors 返回采集错误的通道，每次 Gather、GatherContext 或内部调度器采集返回的错误被拆分为单个错误后发送到该通道，
+// 被 IgnoredErrors 忽略的错误不会发送。通道在第一次调用时创建，之前出现的错误不会发送；
+// 通道已满时新的错误被丢弃并计入自身状态指标 errors_dropped，通道不会被关闭。
+func (m *WinPerfCounters) Errors() <-chan CollectionError {
+	m.collectionErrorsLock.Lock()
+	defer m.collectionErrorsLock.Unlock()
+
+	if m.collectionErrors == nil {
+		m.collectionErrors = make(chan CollectionError, collectionErrorBuffer)
+	}
+	return m.collectionErrors
+}
+
+// reportErrors 将一次采集返回的错误拆分后逐个传给 OnError，并发送到 Errors 返回的通道（未调用 Errors 时不发送）。
+func (m *WinPerfCounters) reportErrors(err error) {
+	if err == nil {
+		return
+	}
+	m.collectionErrorsLock.Lock()
+	collectionErrors := m.collectionErrors
+	m.collectionErrorsLock.Unlock()
+	if collectionErrors == nil && m.OnError == nil {
+		return
+	}
+
+	now := time.Now()
+	for _, e := range splitErrors(err) {
+		collectionErr := newCollectionError(e, now)
+		if m.OnError != nil {
+			m.OnError(collectionErr.Host, e)
+		}
+		if collectionErrors == nil {
+			continue
+		}
+		select {
+		case collectionErrors <- collectionErr:
+		default:
+			m.stats.incr(map[string]string{}, "errors_dropped", 1)
+		}
+	}
+}
+
+// splitErrors 展开 errors.Join 合并的错误。
+func splitErrors(err error) []error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		if e != nil {
+			errs = append(errs, splitErrors(e)...)
+		}
+	}
+	return errs
+}
+
+// newCollectionError 从错误中提取主机、计数器路径和 PDH 状态码。
+func newCollectionError(err error, timestamp time.Time) CollectionError {
+	collectionErr := CollectionError{Time: timestamp, Message: err.Error(), Err: err}
+	var counterErr *CounterError
+	if errors.As(err, &counterErr) {
+		collectionErr.Host = counterErr.Host
+		collectionErr.Object = counterErr.Object
+		collectionErr.CounterPath = counterErr.CounterPath
+		collectionErr.Op = counterErr.Op
+	}
+	var pdhErr *pdhError
+	if errors.As(err, &pdhErr) {
+		collectionErr.Code = pdhErr.errorCode
+		collectionErr.CodeName = pdhErrors[pdhErr.errorCode]
+		if collectionErr.CodeName == "" {
+			collectionErr.CodeName = fmt.Sprintf("0x%08X", pdhErr.errorCode)
+		}
+	}
+	return collectionErr
+}
@@ -0,0 +1,155 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// hasConditions 判断对象是否配置了采集条件。
+func (o *ObjectConfig) hasConditions() bool {
+	return o.RequireService != "" || o.RequireObjectExists
+}
+
+// evaluateConditions 在刷新计数器时检查各对象在各主机上的采集条件，不满足条件的对象在本次刷新期间被跳过，
+// 同一份配置可以包含只在部分主机上存在的角色（SQL Server、IIS 等）而不会产生缺失计数器的警告。
+func (m *WinPerfCounters) evaluateConditions() {
+	previous := m.unmetObjects
+	m.unmetObjects = nil
+	if m.Simulate {
+		return
+	}
+	profile := m.ActiveProfile()
+	checks := make(conditionChecks)
+	for i := range m.Object {
+		object := &m.Object[i]
+		if !object.hasConditions() || !object.inProfile(profile) {
+			continue
+		}
+		computers := object.Sources
+		if len(computers) == 0 {
+			computers = m.hostSources()
+		}
+		for _, computer := range computers {
+			if computer == "" {
+				computer = "localhost"
+			}
+			key := objectHostKey{computer: computer, id: object.id}
+			reason := m.unmetCondition(object, computer, checks)
+			if reason == "" {
+				if previous[key] {
+					m.Log.Infof("Conditions of object %q are now met on host %q", object.ObjectName, computer)
+				}
+				continue
+			}
+			if m.unmetObjects == nil {
+				m.unmetObjects = make(map[objectHostKey]bool)
+			}
+			m.unmetObjects[key] = true
+			if !previous[key] {
+				m.Log.Debugf("Skipping object %q on host %q: %s", object.ObjectName, computer, reason)
+			}
+		}
+	}
+}
+
+// conditionKey 标识一个主机上的一项采集条件，service 与 object 只设置其中之一。
+type conditionKey struct {
+	computer string
+	service  string
+	object   string
+}
+
+// conditionChecks 记录一次刷新中各项采集条件的检查结果，即不满足的原因，满足时为空字符串。
+// 多个对象在同一主机上要求同一服务或对象时只检查一次，不会为每个对象重复连接服务控制管理器。
+type conditionChecks map[conditionKey]string
+
+// check 返回条件 key 的检查结果，本次刷新尚未检查时调用 evaluate 并记录结果。
+func (c conditionChecks) check(key conditionKey, evaluate func() string) string {
+	if reason, ok := c[key]; ok {
+		return reason
+	}
+	reason := evaluate()
+	c[key] = reason
+	return reason
+}
+
+// unmetCondition 返回对象在主机上不满足的条件，全部满足时返回空字符串。
+// 无法确定条件是否满足时（例如没有访问权限）在日志中给出警告并视为不满足。
+func (m *WinPerfCounters) unmetCondition(object *ObjectConfig, computer string, checks conditionChecks) string {
+	if object.RequireService != "" {
+		reason := checks.check(conditionKey{computer: computer, service: object.RequireService}, func() string {
+			installed, err := serviceInstalled(computer, object.RequireService)
+			if err != nil {
+				m.Log.Warnf("Checking service %q of host %q failed: %v", object.RequireService, computer, err)
+				return fmt.Sprintf("service %q could not be checked", object.RequireService)
+			}
+			if !installed {
+				return fmt.Sprintf("service %q is not installed", object.RequireService)
+			}
+			return ""
+		})
+		if reason != "" {
+			return reason
+		}
+	}
+	if object.RequireObjectExists && object.usesPDH() {
+		return checks.check(conditionKey{computer: computer, object: object.ObjectName}, func() string {
+			exists, err := m.objectExists(computer, object.ObjectName)
+			if err != nil {
+				m.Log.Warnf("Checking object %q of host %q failed: %v", object.ObjectName, computer, err)
+				return "object could not be checked"
+			}
+			if !exists {
+				return "object does not exist"
+			}
+			return ""
+		})
+	}
+	return ""
+}
+
+// serviceInstalled 判断主机 computer 上是否安装了服务 name，不要求服务正在运行。
+func serviceInstalled(computer, name string) (bool, error) {
+	manager, err := openServiceManager(computer)
+	if err != nil {
+		return false, err
+	}
+	defer windows.CloseServiceHandle(manager)
+
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return false, err
+	}
+	service, err := windows.OpenService(manager, namePtr, windows.SERVICE_QUERY_STATUS)
+	if errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	windows.CloseServiceHandle(service)
+	return true, nil
+}
+
+// objectExists 判断主机 computer 上是否存在性能对象 objectName，英文名称不存在时再尝试本地化名称。
+func (m *WinPerfCounters) objectExists(computer, objectName string) (bool, error) {
+	names := []string{objectName}
+	if localized := m.localizePerfName(computer, objectName); localized != objectName {
+		names = append(names, localized)
+	}
+	for _, name := range names {
+		_, _, err := enumObjectItems(computer, name)
+		if err == nil {
+			return true, nil
+		}
+		var pdhErr *pdhError
+		if !errors.As(err, &pdhErr) || pdhErr.errorCode != pdhCstatusNoObject {
+			return false, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,35 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionChecks(t *testing.T) {
+	checks := make(conditionChecks)
+	calls := 0
+	evaluate := func(reason string) func() string {
+		return func() string {
+			calls++
+			return reason
+		}
+	}
+
+	sql := conditionKey{computer: "hostA", service: "MSSQLSERVER"}
+	require.Equal(t, `service "MSSQLSERVER" is not installed`, checks.check(sql, evaluate(`service "MSSQLSERVER" is not installed`)))
+	// 同一次刷新中相同的条件只检查一次，满足条件的结果同样记录
+	require.Equal(t, `service "MSSQLSERVER" is not installed`, checks.check(sql, evaluate("")))
+	require.Equal(t, 1, calls)
+
+	iis := conditionKey{computer: "hostA", object: "Web Service"}
+	require.Empty(t, checks.check(iis, evaluate("")))
+	require.Empty(t, checks.check(iis, evaluate("object does not exist")))
+	require.Equal(t, 2, calls)
+
+	// 其它主机上的相同条件单独检查
+	require.Empty(t, checks.check(conditionKey{computer: "hostB", service: "MSSQLSERVER"}, evaluate("")))
+	require.Equal(t, 3, calls)
+}
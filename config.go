@@ -0,0 +1,169 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Validate 校验全局配置项以及所有对象的配置。
+func (o Options) Validate() error {
+	if o.MaxBufferSize < Size(initialBufferSize) {
+		return fmt.Errorf("maximum buffer size should be at least %d", initialBufferSize)
+	}
+	if o.MaxBufferSize > math.MaxUint32 {
+		return fmt.Errorf("maximum buffer size should be smaller than %d", uint32(math.MaxUint32))
+	}
+	if len(o.Objects) == 0 && len(o.Presets) == 0 && !slices.ContainsFunc(o.Sources, isAgentSource) {
+		return errors.New("no performance objects configured")
+	}
+	for i := range o.Objects {
+		if err := o.Objects[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// New 按 options 创建并初始化 WinPerfCounters，options 校验失败或初始化失败时返回错误。
+func New(options Options, collectFunc CollectFunc) (*WinPerfCounters, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	m := NewWinPerfCounters(collectFunc)
+	m.Sources = options.Sources
+	m.Object = options.Objects
+	m.Presets = options.Presets
+	m.PrintValid = options.PrintValid
+	m.UsePerfCounterTime = options.UsePerfCounterTime
+	m.UseWildcardsExpansion = options.UseWildcardsExpansion
+	m.LocalizeWildcardsExpansion = options.LocalizeWildcardsExpansion
+	m.TranslateObjectName = options.TranslateObjectName
+	m.TwoPhaseRefresh = options.TwoPhaseRefresh
+	m.CountersRefreshInterval = Duration(options.CountersRefreshInterval)
+	m.IgnoredErrors = options.IgnoredErrors
+	m.MaxBufferSize = options.MaxBufferSize
+	m.Interval = Duration(options.Interval)
+	m.CollectTimeout = Duration(options.CollectTimeout)
+	m.History = Duration(options.History)
+	m.SelfMetrics = options.SelfMetrics
+	m.CounterLanguage = options.CounterLanguage
+	m.Simulate = options.Simulate
+	if options.QueryCreator != nil {
+		m.WithQueryCreator(options.QueryCreator)
+	}
+	if err := m.Init(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Validate 校验对象的配置。
+func (o *ObjectConfig) Validate() error {
+	if len(o.Paths) > 0 {
+		if err := o.validatePaths(); err != nil {
+			return err
+		}
+	} else if o.Preset != "" {
+		if _, ok := objectPresets[strings.ToLower(o.Preset)]; !ok {
+			return fmt.Errorf("unknown preset %q for object %q", o.Preset, o.ObjectName)
+		}
+	} else {
+		if o.ObjectName == "" {
+			return errors.New("object name is required")
+		}
+		if len(o.Counters) == 0 {
+			return fmt.Errorf("no counters configured for object %q", o.ObjectName)
+		}
+		for i, counter := range o.Counters {
+			if strings.TrimSpace(counter) == "" {
+				return fmt.Errorf("counter %d of object %q is empty", i+1, o.ObjectName)
+			}
+		}
+		if len(o.Instances) == 0 && len(o.Services) == 0 && o.InstancesFile == "" {
+			return fmt.Errorf("no instances configured for object %q", o.ObjectName)
+		}
+	}
+	if _, err := o.expandedInstances(o.Instances); err != nil {
+		return err
+	}
+	if _, err := o.expandedInstances(o.InstancesExclude); err != nil {
+		return err
+	}
+	if o.GatherEvery < 0 || o.EmitEvery < 0 {
+		return fmt.Errorf("GatherEvery and EmitEvery of object %q must not be negative", o.ObjectName)
+	}
+	if o.Interval < 0 {
+		return fmt.Errorf("interval of object %q must not be negative", o.ObjectName)
+	}
+	if o.FlushInterval < 0 {
+		return fmt.Errorf("FlushInterval of object %q must not be negative", o.ObjectName)
+	}
+	if o.FlushInterval > 0 && o.EmitEvery > 1 {
+		return fmt.Errorf("FlushInterval and EmitEvery of object %q cannot be used together", o.ObjectName)
+	}
+	if err := o.validateFieldTypes(); err != nil {
+		return err
+	}
+	if err := o.validateMetadata(); err != nil {
+		return err
+	}
+	if err := o.validatePostProcessing(); err != nil {
+		return err
+	}
+	if err := o.validateServices(); err != nil {
+		return err
+	}
+	if err := o.validateProcessTags(); err != nil {
+		return err
+	}
+	if err := o.validateExpandWildcards(); err != nil {
+		return err
+	}
+	if err := o.validateCollapseDuplicates(); err != nil {
+		return err
+	}
+	if err := o.validateTransforms(); err != nil {
+		return err
+	}
+	if err := o.validateMissingInstances(); err != nil {
+		return err
+	}
+	if err := o.validateInstanceFormat(); err != nil {
+		return err
+	}
+	if err := o.validateProcessorGroups(); err != nil {
+		return err
+	}
+	if err := o.validateSeparateQuery(); err != nil {
+		return err
+	}
+	if err := o.validateExtraTags(); err != nil {
+		return err
+	}
+	if err := o.compileMeasurementRules(); err != nil {
+		return err
+	}
+	_, err := o.compileInstanceFilter()
+	return err
+}
+
+// MarshalConfig 将当前生效的配置序列化为本插件的 TOML 配置，可直接作为 Init 之前 toml.Decode 的输入。
+// Credential 中的密码输出为空字符串，需要时由调用方另行补上。
+func (m *WinPerfCounters) MarshalConfig() ([]byte, error) {
+	m.configLock.RLock()
+	defer m.configLock.RUnlock()
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,92 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ExportTelegrafConfig 将当前生效的配置渲染为 Telegraf 可直接使用的
+// [[inputs.win_perf_counters]] TOML 片段，便于在独立采集器与 Telegraf 部署之间迁移。
+//
+// 只导出 Telegraf 支持的配置项，本项目特有的配置项会被忽略；值为默认值或空值的配置项同样不会输出。
+func (m *WinPerfCounters) ExportTelegrafConfig() (string, error) {
+	m.configLock.RLock()
+	defer m.configLock.RUnlock()
+
+	plugin := make(map[string]interface{})
+	if m.PrintValid {
+		plugin["PrintValid"] = true
+	}
+	if m.UsePerfCounterTime {
+		plugin["UsePerfCounterTime"] = true
+	}
+	if m.UseWildcardsExpansion {
+		plugin["UseWildcardsExpansion"] = true
+	}
+	if !m.LocalizeWildcardsExpansion {
+		plugin["LocalizeWildcardsExpansion"] = false
+	}
+	if m.CountersRefreshInterval != 0 {
+		plugin["CountersRefreshInterval"] = time.Duration(m.CountersRefreshInterval).String()
+	}
+	if len(m.IgnoredErrors) > 0 {
+		plugin["IgnoredErrors"] = m.IgnoredErrors
+	}
+	if m.MaxBufferSize != 0 && m.MaxBufferSize != defaultMaxBufferSize {
+		plugin["MaxBufferSize"] = int64(m.MaxBufferSize)
+	}
+	if len(m.Sources) > 0 {
+		plugin["Sources"] = m.Sources
+	}
+
+	objects := make([]map[string]interface{}, 0, len(m.Object))
+	profile := m.ActiveProfile()
+	for _, object := range m.Object {
+		if !object.inProfile(profile) {
+			continue
+		}
+		o := map[string]interface{}{
+			"ObjectName": object.ObjectName,
+			"Counters":   object.Counters,
+			"Instances":  object.Instances,
+		}
+		if object.Measurement != "" {
+			o["Measurement"] = object.Measurement
+		}
+		if len(object.Sources) > 0 {
+			o["Sources"] = object.Sources
+		}
+		if len(object.InstancesExclude) > 0 {
+			o["InstancesExclude"] = object.InstancesExclude
+		}
+		if object.WarnOnMissing {
+			o["WarnOnMissing"] = true
+		}
+		if object.FailOnMissing {
+			o["FailOnMissing"] = true
+		}
+		if object.IncludeTotal {
+			o["IncludeTotal"] = true
+		}
+		if object.UseRawValues {
+			o["UseRawValues"] = true
+		}
+		objects = append(objects, o)
+	}
+	plugin["object"] = objects
+
+	config := map[string]interface{}{
+		"inputs": map[string]interface{}{
+			"win_perf_counters": []map[string]interface{}{plugin},
+		},
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(config); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,96 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFormats 配置文件扩展名对应的格式。
+var configFormats = map[string]ConfigFormat{
+	".toml": ConfigTOML,
+	".conf": ConfigTOML,
+	".yaml": ConfigYAML,
+	".yml":  ConfigYAML,
+	".json": ConfigJSON,
+}
+
+// LoadConfig 读取配置文件 path 并按扩展名（.toml、.conf、.yaml、.yml、.json）确定格式，参见 ParseConfig。
+func LoadConfig(path string) (*WinPerfCounters, error) {
+	format, ok := configFormats[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, fmt.Errorf("unknown format of config file %q", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseConfig(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("loading config file %q failed: %w", path, err)
+	}
+	return m, nil
+}
+
+// ParseConfig 按 format 解析配置并校验所有对象，返回未初始化的 WinPerfCounters，未配置的选项使用 NewWinPerfCounters 的默认值。
+// YAML 和 JSON 使用与 TOML 相同的键名（如 "object"、"ObjectName"），时长使用 "10s" 等字符串形式。
+// 对象的错误配置会返回错误。未知的配置项（例如拼错的 Intances）在配置了 StrictConfig = true 时全部通过
+// *UnknownConfigKeysError 返回，否则只记录一条警告，TOML 配置中附带其行号和最接近的已知键名。
+// 调用方可通过 AddCollectFunc 注册回调后再调用 Init。
+func ParseConfig(data []byte, format ConfigFormat) (*WinPerfCounters, error) {
+	if format != ConfigTOML {
+		var err error
+		if data, err = convertToTOML(data, format); err != nil {
+			return nil, err
+		}
+	}
+
+	m := NewWinPerfCounters(nil)
+	meta, err := toml.Decode(string(data), m)
+	if err != nil {
+		return nil, fmt.Errorf("decoding config failed: %w", err)
+	}
+	if err := m.checkUnknownKeys(data, format == ConfigTOML, meta.Undecoded()); err != nil {
+		return nil, err
+	}
+	for i := range m.Object {
+		if err := m.Object[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid object %d: %w", i+1, err)
+		}
+	}
+	return m, nil
+}
+
+// convertToTOML 将 YAML 或 JSON 格式的配置转换为 TOML，使所有格式共享同一套键名和类型转换。
+func convertToTOML(data []byte, format ConfigFormat) ([]byte, error) {
+	var config map[string]interface{}
+	switch format {
+	case ConfigYAML:
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("decoding YAML config failed: %w", err)
+		}
+	case ConfigJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		// 保留整数，避免整数选项被解析为浮点数
+		decoder.UseNumber()
+		if err := decoder.Decode(&config); err != nil {
+			return nil, fmt.Errorf("decoding JSON config failed: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown config format %d", format)
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(config); err != nil {
+		return nil, fmt.Errorf("converting config failed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
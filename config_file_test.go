@@ -0,0 +1,134 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testConfigTOML = `
+CountersRefreshInterval = "1m"
+MaxBufferSize = 1048576
+
+[[object]]
+  ObjectName = "Processor"
+  Counters = ["% Idle Time"]
+  Instances = ["*"]
+  Measurement = "win_cpu"
+  UseRawValues = true
+`
+	testConfigYAML = `
+CountersRefreshInterval: 1m
+MaxBufferSize: 1048576
+object:
+  - ObjectName: Processor
+    Counters: ["% Idle Time"]
+    Instances: ["*"]
+    Measurement: win_cpu
+    UseRawValues: true
+`
+	testConfigJSON = `{
+  "CountersRefreshInterval": "1m",
+  "MaxBufferSize": 1048576,
+  "object": [{
+    "ObjectName": "Processor",
+    "Counters": ["% Idle Time"],
+    "Instances": ["*"],
+    "Measurement": "win_cpu",
+    "UseRawValues": true
+  }]
+}`
+)
+
+func TestConvertToTOML(t *testing.T) {
+	want := map[string]interface{}{
+		"CountersRefreshInterval": "1m",
+		"MaxBufferSize":           int64(1048576),
+		"object": []map[string]interface{}{{
+			"ObjectName":   "Processor",
+			"Counters":     []interface{}{"% Idle Time"},
+			"Instances":    []interface{}{"*"},
+			"Measurement":  "win_cpu",
+			"UseRawValues": true,
+		}},
+	}
+	tests := []struct {
+		name    string
+		data    string
+		format  ConfigFormat
+		wantErr string
+	}{
+		{name: "yaml", data: testConfigYAML, format: ConfigYAML},
+		{name: "json", data: testConfigJSON, format: ConfigJSON},
+		{name: "invalid yaml", data: "object: [", format: ConfigYAML, wantErr: "decoding YAML config failed"},
+		{name: "invalid json", data: `{"object": [`, format: ConfigJSON, wantErr: "decoding JSON config failed"},
+		{name: "toml is not converted", data: testConfigTOML, format: ConfigTOML, wantErr: "unknown config format 0"},
+	}
+	for _, tt := range tests {
+		converted, err := convertToTOML([]byte(tt.data), tt.format)
+		if tt.wantErr != "" {
+			require.ErrorContains(t, err, tt.wantErr, tt.name)
+			continue
+		}
+		require.NoError(t, err, tt.name)
+		var got map[string]interface{}
+		_, err = toml.Decode(string(converted), &got)
+		require.NoError(t, err, tt.name)
+		// JSON 中的整数仍是整数而不是浮点数
+		require.Equal(t, want, got, tt.name)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		data   string
+		format ConfigFormat
+	}{
+		{"toml", testConfigTOML, ConfigTOML},
+		{"yaml", testConfigYAML, ConfigYAML},
+		{"json", testConfigJSON, ConfigJSON},
+	} {
+		m, err := ParseConfig([]byte(tt.data), tt.format)
+		require.NoError(t, err, tt.name)
+		require.Equal(t, Duration(time.Minute), m.CountersRefreshInterval, tt.name)
+		require.Equal(t, Size(1048576), m.MaxBufferSize, tt.name)
+		require.Len(t, m.Object, 1, tt.name)
+		require.Equal(t, "Processor", m.Object[0].ObjectName, tt.name)
+		require.Equal(t, []string{"% Idle Time"}, m.Object[0].Counters, tt.name)
+		require.True(t, m.Object[0].UseRawValues, tt.name)
+	}
+
+	_, err := ParseConfig([]byte("[[object]]\n  ObjectName = \"Processor\"\n"), ConfigTOML)
+	require.ErrorContains(t, err, "invalid object 1")
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"agent.conf": testConfigTOML,
+		"agent.YML":  testConfigYAML,
+		"agent.json": testConfigJSON,
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		m, err := LoadConfig(path)
+		require.NoError(t, err, name)
+		require.Len(t, m.Object, 1, name)
+	}
+
+	_, err := LoadConfig(filepath.Join(dir, "agent.ini"))
+	require.ErrorContains(t, err, "unknown format of config file")
+
+	path := filepath.Join(dir, "broken.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("object: ["), 0o600))
+	_, err = LoadConfig(path)
+	require.ErrorContains(t, err, `loading config file "`+path+`" failed`)
+}
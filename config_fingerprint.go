@@ -0,0 +1,43 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// ConfigFingerprint 返回当前生效配置的指纹，配置相同的采集器指纹相同，可用于确认配置在机群中的下发状态。
+func (m *WinPerfCounters) ConfigFingerprint() string {
+	m.reloadLock.Lock()
+	defer m.reloadLock.Unlock()
+	return m.configFingerprint
+}
+
+// updateConfigFingerprint 重新计算生效配置的指纹并记录到自身状态指标中，指纹变化时输出日志。
+// 在 Init 和热更新的配置生效时调用。
+func (m *WinPerfCounters) updateConfigFingerprint() {
+	config, err := m.MarshalConfig()
+	if err != nil {
+		m.Log.Debugf("Computing configuration fingerprint failed: %v", err)
+		return
+	}
+	sum := sha256.Sum256(config)
+	fingerprint := hex.EncodeToString(sum[:16])
+
+	m.reloadLock.Lock()
+	previous := m.configFingerprint
+	m.configFingerprint = fingerprint
+	m.reloadLock.Unlock()
+
+	tags := map[string]string{}
+	m.stats.set(tags, "config_fingerprint", fingerprint)
+	switch previous {
+	case fingerprint:
+	case "":
+		m.Log.Infof("Configuration fingerprint is %s", fingerprint)
+	default:
+		m.stats.incr(tags, "config_changes", 1)
+		m.Log.Infof("Configuration fingerprint changed from %s to %s", previous, fingerprint)
+	}
+}
@@ -0,0 +1,206 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// UnknownConfigKey 配置中无法识别的一个键。
+type UnknownConfigKey struct {
+	// Key 完整的键名，例如 "object.Intances"。
+	Key string
+	// Line 键在 TOML 配置中所在的行号，从 1 开始；无法确定或配置为 YAML、JSON 时为 0。
+	Line int
+	// Suggestion 与之最接近的已知键名，没有足够接近的键时为空。
+	Suggestion string
+}
+
+func (k UnknownConfigKey) String() string {
+	s := fmt.Sprintf("%q", k.Key)
+	if k.Line > 0 {
+		s += fmt.Sprintf(" at line %d", k.Line)
+	}
+	if k.Suggestion != "" {
+		s += fmt.Sprintf(" (did you mean %q?)", k.Suggestion)
+	}
+	return s
+}
+
+// UnknownConfigKeysError 配置中存在无法识别的键时由 ParseConfig、LoadConfig 与 Reload 返回，
+// 可通过 errors.As 取得全部未知的键，例如拼错的 Intances 或 CountersRefreshInterva。
+type UnknownConfigKeysError struct {
+	Keys []UnknownConfigKey
+}
+
+func (e *UnknownConfigKeysError) Error() string {
+	keys := make([]string, 0, len(e.Keys))
+	for _, key := range e.Keys {
+		keys = append(keys, key.String())
+	}
+	if len(keys) == 1 {
+		return "unknown config key " + keys[0]
+	}
+	return "unknown config keys " + strings.Join(keys, ", ")
+}
+
+// checkUnknownKeys 在 undecoded 中有键时，启用 StrictConfig 则返回 *UnknownConfigKeysError，否则记录一条警告后忽略这些键。
+func (m *WinPerfCounters) checkUnknownKeys(data []byte, withLines bool, undecoded []toml.Key) error {
+	err := unknownKeysError(data, withLines, undecoded)
+	if err == nil || m.StrictConfig {
+		return err
+	}
+	m.Log.Warnf("Ignoring %v, set StrictConfig = true to reject them", err)
+	return nil
+}
+
+// unknownKeysError 在 undecoded 中有键时返回 *UnknownConfigKeysError，data 为 TOML 配置时附加各键所在的行号。
+// 只报告未知的表本身，不再逐个报告其中的键。
+func unknownKeysError(data []byte, withLines bool, undecoded []toml.Key) error {
+	var unknown []UnknownConfigKey
+	var tables []toml.Key
+	for _, key := range undecoded {
+		if slices.ContainsFunc(tables, func(table toml.Key) bool { return isKeyPrefix(table, key) }) {
+			continue
+		}
+		tables = append(tables, key)
+		entry := UnknownConfigKey{Key: strings.Join(key, "."), Suggestion: suggestConfigKey(key)}
+		if withLines {
+			entry.Line = configKeyLine(data, key)
+		}
+		unknown = append(unknown, entry)
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	slices.SortStableFunc(unknown, func(a, b UnknownConfigKey) int { return a.Line - b.Line })
+	return &UnknownConfigKeysError{Keys: unknown}
+}
+
+// isKeyPrefix 判断 table 是否为 key 的上级表。
+func isKeyPrefix(table, key toml.Key) bool {
+	return len(table) < len(key) && slices.Equal(table, key[:len(table)])
+}
+
+// configKeyLine 返回键在 TOML 配置中第一次出现的行号，按表头（[table]、[[table]]）跟踪当前所在的表，找不到时返回 0。
+func configKeyLine(data []byte, key toml.Key) int {
+	path := strings.Join(key, ".")
+	table := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			header, _, _ := strings.Cut(line, "#")
+			table = normalizeKeyPath(strings.Trim(strings.TrimSpace(header), "[]"))
+			if table == path {
+				return i + 1
+			}
+			continue
+		}
+		name, _, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		full := normalizeKeyPath(name)
+		if table != "" {
+			full = table + "." + full
+		}
+		if full == path {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// normalizeKeyPath 去掉键名各部分两侧的空白和引号，例如 ` object . "Instances" ` 转换为 "object.Instances"。
+func normalizeKeyPath(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
+
+// suggestConfigKey 按所在表对应的结构体的 toml 标签，返回与未知键最接近的已知键名，编辑距离超过键名长度的三分之一时不建议。
+func suggestConfigKey(key toml.Key) string {
+	t := reflect.TypeOf(WinPerfCounters{})
+	for _, part := range key[:len(key)-1] {
+		field, ok := tomlField(t, part)
+		if !ok {
+			return ""
+		}
+		t = field.Type
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+	}
+	if t.Kind() != reflect.Struct {
+		return ""
+	}
+
+	name := key[len(key)-1]
+	best, bestDistance := "", len(name)/3+1
+	for i := 0; i < t.NumField(); i++ {
+		tag := tomlTag(t.Field(i))
+		if tag == "" {
+			continue
+		}
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(tag)); distance < bestDistance {
+			best, bestDistance = tag, distance
+		}
+	}
+	return best
+}
+
+// tomlField 按 toml 标签（不区分大小写，与解码时一致）查找结构体字段。
+func tomlField(t reflect.Type, name string) (reflect.StructField, bool) {
+	if t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if strings.EqualFold(tomlTag(t.Field(i)), name) {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// tomlTag 返回导出字段的 toml 键名，被忽略的字段返回空字符串。
+func tomlTag(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	tag, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+	switch tag {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return tag
+}
+
+// editDistance 返回两个字符串之间的 Levenshtein 编辑距离。
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,119 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"instances", "instances", 0},
+		{"intances", "instances", 1},
+		{"instnaces", "instances", 2},
+		{"kitten", "sitting", 3},
+		{"countersrefreshinterva", "countersrefreshinterval", 1},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, editDistance(tt.a, tt.b), "%q -> %q", tt.a, tt.b)
+		require.Equal(t, tt.expected, editDistance(tt.b, tt.a), "%q -> %q", tt.b, tt.a)
+	}
+}
+
+func TestSuggestConfigKey(t *testing.T) {
+	tests := []struct {
+		key      toml.Key
+		expected string
+	}{
+		{toml.Key{"CountersRefreshInterva"}, "CountersRefreshInterval"},
+		{toml.Key{"usewildcardexpansion"}, "UseWildcardsExpansion"},
+		{toml.Key{"object", "Intances"}, "Instances"},
+		{toml.Key{"object", "ObjName"}, ""},
+		// 距离超过键名长度的三分之一时不建议
+		{toml.Key{"Xyzzy"}, ""},
+		// 未知的表中的键无法建议
+		{toml.Key{"objects", "Intances"}, ""},
+		// 不是表的键下没有字段
+		{toml.Key{"Sources", "x"}, ""},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, suggestConfigKey(tt.key), tt.key.String())
+	}
+}
+
+func TestConfigKeyLine(t *testing.T) {
+	data := []byte(`# Intances = ["commented"]
+CountersRefreshInterva = "10s"
+
+[[object]]
+  ObjectName = "Processor"
+  Intances = ["*"]
+
+[[ object ]] # second object
+  "Countrs" = ["% Idle Time"]
+
+[unknown.table]
+  x = 1
+`)
+	tests := []struct {
+		key      toml.Key
+		expected int
+	}{
+		{toml.Key{"CountersRefreshInterva"}, 2},
+		{toml.Key{"object", "Intances"}, 6},
+		{toml.Key{"object", "Countrs"}, 9},
+		{toml.Key{"unknown", "table"}, 11},
+		{toml.Key{"unknown", "table", "x"}, 12},
+		{toml.Key{"Intances"}, 0},
+		{toml.Key{"missing"}, 0},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, configKeyLine(data, tt.key), tt.key.String())
+	}
+}
+
+func TestUnknownKeysError(t *testing.T) {
+	data := []byte("[[object]]\n  Intances = [\"*\"]\n[extra]\n  a = 1\n  b = 2\n")
+	undecoded := []toml.Key{{"extra"}, {"extra", "a"}, {"extra", "b"}, {"object", "Intances"}}
+	err := unknownKeysError(data, true, undecoded)
+	var unknown *UnknownConfigKeysError
+	require.ErrorAs(t, err, &unknown)
+	// 未知表中的键不单独报告，按行号排序
+	require.Equal(t, []UnknownConfigKey{
+		{Key: "object.Intances", Line: 2, Suggestion: "Instances"},
+		{Key: "extra", Line: 3},
+	}, unknown.Keys)
+	require.Equal(t, `unknown config keys "object.Intances" at line 2 (did you mean "Instances"?), "extra" at line 3`, err.Error())
+
+	err = unknownKeysError(data, false, undecoded[3:])
+	require.Equal(t, `unknown config key "object.Intances" (did you mean "Instances"?)`, err.Error())
+
+	require.NoError(t, unknownKeysError(data, true, nil))
+}
+
+func TestCheckUnknownKeysStrict(t *testing.T) {
+	var buf bytes.Buffer
+	m := &WinPerfCounters{Log: Logger{Name: "test", Output: log.New(&buf, "", 0)}}
+	undecoded := []toml.Key{{"Intances"}}
+
+	// 默认只记录警告
+	require.NoError(t, m.checkUnknownKeys(nil, false, undecoded))
+	require.Contains(t, buf.String(), `unknown config key "Intances"`)
+
+	m.StrictConfig = true
+	var unknown *UnknownConfigKeysError
+	require.True(t, errors.As(m.checkUnknownKeys(nil, false, undecoded), &unknown))
+	require.NoError(t, m.checkUnknownKeys(nil, false, nil))
+}
@@ -0,0 +1,56 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	object := ObjectConfig{ObjectName: "Processor", Counters: []string{"% Processor Time"}, Instances: []string{"_Total"}}
+	tests := []struct {
+		name    string
+		options Options
+		wantErr string
+	}{
+		{
+			name:    "buffer too small",
+			options: Options{MaxBufferSize: Size(initialBufferSize) - 1, Objects: []ObjectConfig{object}},
+			wantErr: "maximum buffer size should be at least 1024",
+		},
+		{
+			name:    "smallest buffer",
+			options: Options{MaxBufferSize: Size(initialBufferSize), Objects: []ObjectConfig{object}},
+		},
+		{
+			name:    "buffer too large",
+			options: Options{MaxBufferSize: math.MaxUint32 + 1, Objects: []ObjectConfig{object}},
+			wantErr: "maximum buffer size should be smaller than 4294967295",
+		},
+		{
+			name:    "no objects",
+			options: Options{MaxBufferSize: defaultMaxBufferSize},
+			wantErr: "no performance objects configured",
+		},
+		{
+			name:    "agent sources only",
+			options: Options{MaxBufferSize: defaultMaxBufferSize, Sources: []string{"https://agent:9273"}},
+		},
+		{
+			name:    "invalid object",
+			options: Options{MaxBufferSize: defaultMaxBufferSize, Objects: []ObjectConfig{{ObjectName: "Processor"}}},
+			wantErr: `no counters configured for object "Processor"`,
+		},
+	}
+	for _, tt := range tests {
+		err := tt.options.Validate()
+		if tt.wantErr == "" {
+			require.NoError(t, err, tt.name)
+			continue
+		}
+		require.ErrorContains(t, err, tt.wantErr, tt.name)
+	}
+}
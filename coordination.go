@@ -0,0 +1,133 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// duplicateWarn 发现其它进程采集相同的对象时给出警告，仍然照常采集。
+	duplicateWarn = "warn"
+	// duplicateSkip 发现其它进程采集相同的对象时不再采集，由先开始采集的进程负责。
+	duplicateSkip = "skip"
+)
+
+// collectorMutexPrefix 标识进程采集的对象的命名互斥体名称前缀。
+const collectorMutexPrefix = "win_perf_counters_"
+
+// validateDuplicateCollectors 校验 DuplicateCollectors 配置。
+func (m *WinPerfCounters) validateDuplicateCollectors() error {
+	switch m.DuplicateCollectors {
+	case "", duplicateWarn, duplicateSkip:
+		return nil
+	}
+	return fmt.Errorf("invalid DuplicateCollectors %q, expected %q or %q", m.DuplicateCollectors, duplicateWarn, duplicateSkip)
+}
+
+// collectorKey 返回主机 computer 上对象 object 的标识，对象名称、实例、计数器与原始值设置相同的配置标识相同。
+func collectorKey(object *ObjectConfig, computer string) string {
+	instances := slices.Sorted(slices.Values(object.Instances))
+	counters := slices.Sorted(slices.Values(object.Counters))
+	key := fmt.Sprintf("%s\n%s\n%s\n%s\n%t", strings.ToLower(computer), strings.ToLower(object.ObjectName),
+		strings.Join(instances, "\x00"), strings.Join(counters, "\x00"), object.UseRawValues)
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// openCollectorMutex 创建标识 key 的命名互斥体，互斥体已被其它进程创建时关闭句柄并返回 exists 为 true。
+// 优先使用 Global 命名空间以发现其它会话中的进程，没有权限时改用当前会话的 Local 命名空间。
+func openCollectorMutex(key string) (handle windows.Handle, exists bool, err error) {
+	for _, namespace := range []string{`Global\`, `Local\`} {
+		var name *uint16
+		if name, err = windows.UTF16PtrFromString(namespace + collectorMutexPrefix + key); err != nil {
+			return 0, false, err
+		}
+		handle, err = windows.CreateMutex(nil, false, name)
+		if errors.Is(err, windows.ERROR_ALREADY_EXISTS) {
+			_ = windows.CloseHandle(handle)
+			return 0, true, nil
+		}
+		if err == nil {
+			return handle, false, nil
+		}
+	}
+	return 0, false, err
+}
+
+// claimObjects 在刷新计数器时为每个主机上采集的对象持有一个命名互斥体，以发现同一台机器上采集相同对象的其它进程。
+// 之前被其它进程采集的对象在该进程退出后的下一次刷新时由本进程接管。
+func (m *WinPerfCounters) claimObjects() {
+	held := m.collectorMutexes
+	m.collectorMutexes = make(map[string]windows.Handle, len(held))
+	duplicates := make(map[string]bool)
+	profile := m.ActiveProfile()
+	for i := range m.Object {
+		object := &m.Object[i]
+		if !object.inProfile(profile) {
+			continue
+		}
+		computers := object.Sources
+		if len(computers) == 0 {
+			computers = m.hostSources()
+		}
+		for _, computer := range computers {
+			if computer == "" {
+				computer = "localhost"
+			}
+			key := collectorKey(object, computer)
+			if handle, ok := held[key]; ok {
+				m.collectorMutexes[key] = handle
+				delete(held, key)
+				continue
+			}
+			if _, ok := m.collectorMutexes[key]; ok {
+				continue
+			}
+			handle, exists, err := openCollectorMutex(key)
+			switch {
+			case err != nil:
+				m.Log.Debugf("Creating coordination mutex for object %q of host %q failed: %v", object.ObjectName, computer, err)
+			case exists:
+				duplicates[key] = true
+				if !m.duplicateObjects[key] {
+					m.Log.Warnf("Object %q of host %q is already collected by another process on this machine", object.ObjectName, computer)
+				}
+			default:
+				m.collectorMutexes[key] = handle
+				if m.duplicateObjects[key] {
+					m.Log.Infof("Object %q of host %q is no longer collected by another process", object.ObjectName, computer)
+				}
+			}
+		}
+	}
+	// 不再采集的对象
+	for _, handle := range held {
+		_ = windows.CloseHandle(handle)
+	}
+	m.duplicateObjects = duplicates
+}
+
+// skipDuplicate 判断 DuplicateCollectors 为 skip 时是否跳过由其它进程采集的对象。
+func (m *WinPerfCounters) skipDuplicate(object *ObjectConfig, computer string) bool {
+	if m.DuplicateCollectors != duplicateSkip {
+		return false
+	}
+	return m.duplicateObjects[collectorKey(object, computer)]
+}
+
+// releaseObjects 关闭持有的所有命名互斥体。
+func (m *WinPerfCounters) releaseObjects() {
+	for _, handle := range m.collectorMutexes {
+		_ = windows.CloseHandle(handle)
+	}
+	m.collectorMutexes = nil
+	m.duplicateObjects = nil
+}
@@ -0,0 +1,89 @@
+//go:build windows
+
+package win_perf_counters
+
+import "errors"
+
+var errCounterMetaUnsupported = errors.New("query does not support reading counter metadata")
+
+// counterSubtypeClasses PERF_TYPE_COUNTER 计数器各子类型的名称。
+var counterSubtypeClasses = map[uint32]string{
+	0x00000000: "value",
+	0x00010000: "rate",
+	0x00020000: "fraction",
+	0x00030000: "base",
+	0x00040000: "elapsed",
+	0x00050000: "queue_length",
+	0x00060000: "histogram",
+	0x00070000: "precision",
+}
+
+// counterTypeClass 返回计数器类型的简短分类，例如 "number"、"rate"、"fraction"，用于在仪表盘中说明字段的含义。
+func counterTypeClass(counterType uint32) string {
+	switch counterType & perfTypeMask {
+	case perfTypeNumber:
+		return "number"
+	case perfTypeCounter:
+		if class, ok := counterSubtypeClasses[counterType&perfCounterSubMask]; ok {
+			return class
+		}
+		return "counter"
+	case perfTypeText:
+		return "text"
+	}
+	return "zero"
+}
+
+// CounterInfo 返回计数器路径 counterPath 的类型、比例和说明文字，可以是英文或本地化的路径，
+// 路径中包含主机名时从该主机获取。用于自动生成指标文档。
+func (m *WinPerfCounters) CounterInfo(counterPath string) (CounterMeta, error) {
+	computer, objectName, _, _, err := extractCounterInfoFromCounterPath(counterPath)
+	if err != nil {
+		return CounterMeta{}, err
+	}
+	if computer == "" {
+		computer = "localhost"
+	}
+
+	query := m.queryCreator.newPerformanceQuery(computer, uint32(m.MaxBufferSize))
+	if err := query.Open(); err != nil {
+		return CounterMeta{}, wrapCounterError("open", computer, objectName, counterPath, err)
+	}
+	defer query.Close()
+
+	counterHandle, err := query.AddEnglishCounterToQuery(counterPath)
+	if err != nil {
+		var localizedErr error
+		if counterHandle, localizedErr = query.AddCounterToQuery(counterPath); localizedErr != nil {
+			return CounterMeta{}, wrapCounterError("add", computer, objectName, counterPath, err)
+		}
+	}
+	meta, err := counterMeta(query, counterHandle)
+	if err != nil {
+		return CounterMeta{}, wrapCounterError("read", computer, objectName, counterPath, err)
+	}
+	return meta, nil
+}
+
+// applyCounterMetadata 为启用 IncludeCounterMetadata 的对象获取计数器的类型分类和说明文字。
+func (m *WinPerfCounters) applyCounterMetadata(hostInfo *hostCountersInfo, item *counter) {
+	if item.object == nil || !item.object.IncludeCounterMetadata {
+		return
+	}
+	meta, err := counterMeta(hostInfo.query, item.counterHandle)
+	if err != nil {
+		m.Log.Warnf("Getting metadata of counter %q failed: %v", item.counterPath, err)
+		return
+	}
+	item.typeClass = meta.TypeClass
+	item.description = meta.ExplainText
+}
+
+// counterMeta 读取计数器的元数据，查询未实现 CounterMetaReader 时返回 errCounterMetaUnsupported。
+func counterMeta(query PerformanceQuery, counterHandle pdhCounterHandle) (CounterMeta, error) {
+	reader, ok := query.(CounterMetaReader)
+	if !ok {
+		return CounterMeta{}, errCounterMetaUnsupported
+	}
+	return reader.GetCounterMeta(counterHandle)
+}
@@ -0,0 +1,96 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ParseCounterPath 解析计数器路径，例如 typeperf 使用的 `\Processor(_Total)\% Processor Time`。
+func ParseCounterPath(counterPath string) (CounterPath, error) {
+	computer, object, instance, counter, err := extractCounterInfoFromCounterPath(counterPath)
+	if err != nil {
+		return CounterPath{}, err
+	}
+	if object == "" || counter == "" {
+		return CounterPath{}, errors.New("cannot parse counter from: " + counterPath)
+	}
+	return CounterPath{Computer: computer, Object: object, Instance: instance, Counter: counter}, nil
+}
+
+// validatePaths 校验对象的 Paths 配置，配置了 Paths 的对象不能再配置 ObjectName、Counters、Instances 或 Preset。
+func (o *ObjectConfig) validatePaths() error {
+	if len(o.Paths) == 0 {
+		return nil
+	}
+	if o.ObjectName != "" || len(o.Counters) > 0 || len(o.Instances) > 0 || o.InstancesFile != "" || o.Preset != "" || o.TotalOnly {
+		return fmt.Errorf("paths of measurement %q cannot be combined with ObjectName, Counters, Instances, InstancesFile, Preset or TotalOnly", o.Measurement)
+	}
+	for _, counterPath := range o.Paths {
+		if _, err := ParseCounterPath(counterPath); err != nil {
+			return fmt.Errorf("invalid path of measurement %q: %w", o.Measurement, err)
+		}
+	}
+	return nil
+}
+
+// pathGroup 同一主机、对象和实例的计数器路径。
+type pathGroup struct {
+	computer string
+	object   string
+	instance string
+	counters []string
+}
+
+// expandPaths 将配置了 Paths 的对象按主机、对象和实例拆分为普通的对象配置，其余配置项保持不变，
+// 拆分后每个对象只包含路径中列出的计数器，不会产生路径以外的实例与计数器组合。
+func (m *WinPerfCounters) expandPaths(configured []ObjectConfig) ([]ObjectConfig, error) {
+	if !slices.ContainsFunc(configured, func(object ObjectConfig) bool { return len(object.Paths) > 0 }) {
+		return configured, nil
+	}
+
+	objects := make([]ObjectConfig, 0, len(configured))
+	for _, object := range configured {
+		if len(object.Paths) == 0 {
+			objects = append(objects, object)
+			continue
+		}
+		if err := object.validatePaths(); err != nil {
+			return nil, err
+		}
+
+		var groups []*pathGroup
+		for _, counterPath := range object.Paths {
+			parsed, _ := ParseCounterPath(counterPath)
+			instance := parsed.Instance
+			if instance == "" {
+				instance = emptyInstance
+			}
+			i := slices.IndexFunc(groups, func(g *pathGroup) bool {
+				return g.computer == parsed.Computer && g.object == parsed.Object && g.instance == instance
+			})
+			if i < 0 {
+				groups = append(groups, &pathGroup{computer: parsed.Computer, object: parsed.Object, instance: instance})
+				i = len(groups) - 1
+			}
+			if !slices.Contains(groups[i].counters, parsed.Counter) {
+				groups[i].counters = append(groups[i].counters, parsed.Counter)
+			}
+		}
+
+		for _, group := range groups {
+			expanded := object
+			expanded.Paths = nil
+			expanded.ObjectName = group.object
+			expanded.Instances = []string{group.instance}
+			expanded.Counters = group.counters
+			if group.computer != "" {
+				expanded.Sources = []string{group.computer}
+			}
+			objects = append(objects, expanded)
+		}
+	}
+	return objects, nil
+}
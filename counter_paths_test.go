@@ -0,0 +1,103 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCounterPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    CounterPath
+		wantErr string
+	}{
+		{
+			path: `\Processor(_Total)\% Processor Time`,
+			want: CounterPath{Object: "Processor", Instance: "_Total", Counter: "% Processor Time"},
+		},
+		{
+			path: `\\SERVER01\Memory\Available Bytes`,
+			want: CounterPath{Computer: "SERVER01", Object: "Memory", Counter: "Available Bytes"},
+		},
+		{
+			path: `\Process(svchost (netsvcs))\ID Process`,
+			want: CounterPath{Object: "Process", Instance: "svchost (netsvcs)", Counter: "ID Process"},
+		},
+		{
+			path: `\Process(chrome#2)\Working Set - Private`,
+			want: CounterPath{Object: "Process", Instance: "chrome#2", Counter: "Working Set - Private"},
+		},
+		{
+			path: `\Network Interface(*)\Bytes Total/sec`,
+			want: CounterPath{Object: "Network Interface", Instance: "*", Counter: "Bytes Total/sec"},
+		},
+		{path: `Processor\% Processor Time`, wantErr: "cannot parse object"},
+		{path: `\Processor`, wantErr: "cannot parse object"},
+		{path: `\Memory\`, wantErr: "cannot parse counter"},
+		{path: `\\\Memory\Available Bytes`, wantErr: "cannot parse computer"},
+		{path: ``, wantErr: "cannot parse object"},
+	}
+	for _, tt := range tests {
+		got, err := ParseCounterPath(tt.path)
+		if tt.wantErr != "" {
+			require.ErrorContains(t, err, tt.wantErr, tt.path)
+			continue
+		}
+		require.NoError(t, err, tt.path)
+		require.Equal(t, tt.want, got, tt.path)
+	}
+}
+
+func TestValidatePaths(t *testing.T) {
+	object := ObjectConfig{Measurement: "win_typeperf", Paths: []string{`\Memory\Available Bytes`}}
+	require.NoError(t, object.validatePaths())
+
+	object.Counters = []string{"Committed Bytes"}
+	require.ErrorContains(t, object.validatePaths(), "cannot be combined")
+
+	object = ObjectConfig{Measurement: "win_typeperf", Paths: []string{`\Memory`}}
+	require.ErrorContains(t, object.validatePaths(), `invalid path of measurement "win_typeperf"`)
+}
+
+func TestExpandPaths(t *testing.T) {
+	m := &WinPerfCounters{Object: []ObjectConfig{
+		{ObjectName: "System", Counters: []string{"Processes"}, Instances: []string{emptyInstance}},
+		{
+			Measurement: "win_typeperf",
+			Paths: []string{
+				`\Processor(_Total)\% Processor Time`,
+				`\Processor(_Total)\% Idle Time`,
+				`\Processor(0)\% Processor Time`,
+				`\Memory\Available Bytes`,
+				`\\SERVER01\Memory\Available Bytes`,
+				`\Memory\Available Bytes`,
+			},
+		},
+	}}
+	objects, err := m.expandPaths(m.Object)
+	require.NoError(t, err)
+
+	type expanded struct {
+		object    string
+		instances []string
+		counters  []string
+		sources   []string
+	}
+	got := make([]expanded, 0, len(objects))
+	for _, object := range objects {
+		got = append(got, expanded{object.ObjectName, object.Instances, object.Counters, object.Sources})
+		require.Empty(t, object.Paths)
+	}
+	// 每个对象只包含路径中列出的计数器，不产生路径以外的实例与计数器组合
+	require.Equal(t, []expanded{
+		{"System", []string{emptyInstance}, []string{"Processes"}, nil},
+		{"Processor", []string{"_Total"}, []string{"% Processor Time", "% Idle Time"}, nil},
+		{"Processor", []string{"0"}, []string{"% Processor Time"}, nil},
+		{"Memory", []string{emptyInstance}, []string{"Available Bytes"}, nil},
+		{"Memory", []string{emptyInstance}, []string{"Available Bytes"}, []string{"SERVER01"}},
+	}, got)
+	require.Equal(t, "win_typeperf", objects[1].Measurement)
+}
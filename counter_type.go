@@ -0,0 +1,23 @@
+//go:build windows
+
+package win_perf_counters
+
+// isIntegerCounterType 判断计数器类型是否为直接显示的整数计数，例如 PERF_COUNTER_RAWCOUNT 与 PERF_COUNTER_LARGE_RAWCOUNT。
+// 速率、比例和计时类计数器以及按 1/1000 显示的 PERF_NUMBER_DEC_1000 计数器不属于此类。
+func isIntegerCounterType(counterType uint32) bool {
+	return counterType&perfTypeMask == perfTypeNumber && counterType&perfNumberMask != perfNumberDec1000
+}
+
+// applyCounterType 为启用 FormatByCounterType 的对象查询计数器类型，整数计数类计数器改为读取 64 位整数格式化值。
+func (m *WinPerfCounters) applyCounterType(hostInfo *hostCountersInfo, item *counter) {
+	if item.object == nil || !item.object.FormatByCounterType || item.useRawValue {
+		return
+	}
+	counterType, err := hostInfo.query.GetCounterType(item.counterHandle)
+	if err != nil {
+		m.Log.Warnf("Getting type of counter %q failed, formatting it as double: %v", item.counterPath, err)
+		return
+	}
+	item.counterType = counterType
+	item.integer = isIntegerCounterType(counterType)
+}
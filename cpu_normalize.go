@@ -0,0 +1,68 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"strings"
+)
+
+// processorInformationObject 按 "组,编号" 列出所有处理器组中逻辑处理器的性能对象。
+const processorInformationObject = "Processor Information"
+
+// applyCPUNormalization 为启用 NormalizeCPU 的对象追加 cpu_count 字段，
+// 并为每个格式化的 "% ... Time" 字段追加除以逻辑 CPU 数后的 "<字段名>_normalized" 字段。
+//
+// Process 等对象的 % Processor Time 在多核主机上可以超过 100，归一化后与 Processor(_Total)、
+// Processor Information 一样落在 0~100 之间，仪表盘无需再按主机做换算。
+// 逻辑 CPU 数按主机获取，包含所有处理器组。
+func (m *WinPerfCounters) applyCPUNormalization(hostInfo *hostCountersInfo, object *ObjectConfig, fields map[string]interface{}) {
+	if object == nil || !object.NormalizeCPU {
+		return
+	}
+	cpuCount := m.hostCPUCount(hostInfo)
+	if cpuCount <= 0 {
+		return
+	}
+
+	for name, value := range fields {
+		v, ok := value.(float64)
+		if !ok || !strings.HasPrefix(name, "Percent_") || !strings.HasSuffix(name, "_Time") {
+			continue
+		}
+		fields[name+"_normalized"] = v / float64(cpuCount)
+	}
+	fields["cpu_count"] = int64(cpuCount)
+}
+
+// hostCPUCount 返回主机所有处理器组的逻辑 CPU 数，首次使用时获取并记录在 hostInfo 中，刷新计数器后重新获取。
+// 本机通过 GetActiveProcessorCount 获取，远程主机统计其 Processor Information 对象的逻辑处理器实例，
+// 日志数据源或获取失败时返回 0。
+func (m *WinPerfCounters) hostCPUCount(hostInfo *hostCountersInfo) int {
+	hostInfo.cpuCountOnce.Do(func() {
+		if hostInfo.computer == "localhost" || strings.EqualFold(hostInfo.computer, m.hostname()) {
+			hostInfo.cpuCount = activeProcessorCount()
+			return
+		}
+		if logSourcePath(hostInfo.computer) != "" {
+			return
+		}
+		_, instances, err := enumObjectItems(hostInfo.computer, m.localizePerfName(hostInfo.computer, processorInformationObject))
+		if err != nil {
+			m.Log.Warnf("Getting the logical CPU count of %q failed, NormalizeCPU is skipped: %v", hostInfo.computer, err)
+			return
+		}
+		hostInfo.cpuCount = countLogicalProcessors(instances)
+	})
+	return hostInfo.cpuCount
+}
+
+// countLogicalProcessors 统计 Processor Information 中 "组,编号" 形式的逻辑处理器实例，各组的 _Total 与全部处理器的 _Total 除外。
+func countLogicalProcessors(instances []string) int {
+	var count int
+	for _, instance := range instances {
+		if _, index, ok := parseProcessorInstance(instance); ok && index != "_Total" {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,49 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountLogicalProcessors(t *testing.T) {
+	// 两个处理器组，各组与全部处理器的 _Total 不计入
+	instances := []string{"0,0", "0,1", "0,_Total", "1,0", "1,1", "1,2", "1,_Total", "_Total"}
+	require.Equal(t, 5, countLogicalProcessors(instances))
+	require.Zero(t, countLogicalProcessors([]string{"_Total", "0", "x,1"}))
+	require.Zero(t, countLogicalProcessors(nil))
+}
+
+func TestApplyCPUNormalization(t *testing.T) {
+	m := &WinPerfCounters{}
+	hostInfo := &hostCountersInfo{computer: "hostA"}
+	// 跳过实际获取，直接使用记录的 CPU 数
+	hostInfo.cpuCountOnce.Do(func() {})
+	hostInfo.cpuCount = 80
+
+	object := &ObjectConfig{NormalizeCPU: true}
+	fields := map[string]interface{}{
+		"Percent_Processor_Time": 400.0,
+		"Percent_User_Time":      int64(8),
+		"Handle_Count":           10.0,
+	}
+	m.applyCPUNormalization(hostInfo, object, fields)
+	require.Equal(t, map[string]interface{}{
+		"Percent_Processor_Time":            400.0,
+		"Percent_Processor_Time_normalized": 5.0,
+		"Percent_User_Time":                 int64(8),
+		"Handle_Count":                      10.0,
+		"cpu_count":                         int64(80),
+	}, fields)
+
+	// 未启用或没有 CPU 数时不添加字段
+	fields = map[string]interface{}{"Percent_Processor_Time": 400.0}
+	m.applyCPUNormalization(hostInfo, &ObjectConfig{}, fields)
+	require.Len(t, fields, 1)
+	unknown := &hostCountersInfo{computer: "hostB"}
+	unknown.cpuCountOnce.Do(func() {})
+	m.applyCPUNormalization(unknown, object, fields)
+	require.Len(t, fields, 1)
+}
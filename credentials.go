@@ -0,0 +1,152 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	resourceTypeAny          = 0x00000000
+	errorSessionCredConflict = 1219
+)
+
+// netResource mirrors NETRESOURCEW
+type netResource struct {
+	dwScope       uint32
+	dwType        uint32
+	dwDisplayType uint32
+	dwUsage       uint32
+	lpLocalName   *uint16
+	lpRemoteName  *uint16
+	lpComment     *uint16
+	lpProvider    *uint16
+}
+
+var (
+	// Library
+	libMprDll *syscall.DLL
+
+	// Functions
+	mprWNetAddConnection2W    *syscall.Proc
+	mprWNetCancelConnection2W *syscall.Proc
+)
+
+func init() {
+	var err error
+	if libMprDll, err = syscall.LoadDLL("mpr.dll"); err != nil {
+		return
+	}
+	mprWNetAddConnection2W, _ = libMprDll.FindProc("WNetAddConnection2W")
+	mprWNetCancelConnection2W, _ = libMprDll.FindProc("WNetCancelConnection2W")
+}
+
+// sourceCredential 描述访问远程主机时使用的凭据。
+type sourceCredential struct {
+	// Source 使用该凭据的主机，与 Sources 中的名称一致。
+	Source string `toml:"Source"`
+	// Username 用户名，可以写成 "DOMAIN\user"。
+	Username string `toml:"Username"`
+	// Password 密码，MarshalConfig 输出的配置以及配置指纹中不包含密码。
+	Password secretString `toml:"Password"`
+	// Domain 用户所属的域，Username 中已包含域时可以省略。
+	Domain string `toml:"Domain"`
+}
+
+// user 返回 WNetAddConnection2 使用的用户名。
+func (c *sourceCredential) user() string {
+	if c.Domain == "" || strings.Contains(c.Username, `\`) || strings.Contains(c.Username, "@") {
+		return c.Username
+	}
+	return c.Domain + `\` + c.Username
+}
+
+// ipcShare 返回主机的 IPC$ 共享路径，PDH 通过该会话访问远程注册表和性能数据。
+func ipcShare(computer string) string {
+	return `\\` + strings.TrimPrefix(computer, `\\`) + `\IPC$`
+}
+
+// sourceCredential 返回主机 computer 配置的凭据，未配置时返回 nil。
+func (m *WinPerfCounters) sourceCredential(computer string) *sourceCredential {
+	for i := range m.Credential {
+		if strings.EqualFold(strings.TrimPrefix(m.Credential[i].Source, `\\`), strings.TrimPrefix(computer, `\\`)) {
+			return &m.Credential[i]
+		}
+	}
+	return nil
+}
+
+// connectSource 在首次访问配置了凭据的主机前，使用该凭据建立到其 IPC$ 共享的会话，
+// 之后同一进程中对该主机的 PDH 查询都会使用这一会话。已建立会话或未配置凭据时不做任何事。
+func (m *WinPerfCounters) connectSource(computer string) error {
+	credential := m.sourceCredential(computer)
+	if credential == nil {
+		return nil
+	}
+
+	m.connectionsLock.Lock()
+	defer m.connectionsLock.Unlock()
+
+	remote := ipcShare(computer)
+	if m.connections[remote] {
+		return nil
+	}
+	if mprWNetAddConnection2W == nil {
+		return fmt.Errorf("connecting to %q failed: WNetAddConnection2 is not supported on this system", remote)
+	}
+
+	remoteName, err := syscall.UTF16PtrFromString(remote)
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(credential.user())
+	if err != nil {
+		return err
+	}
+	password, err := syscall.UTF16PtrFromString(string(credential.Password))
+	if err != nil {
+		return err
+	}
+	resource := netResource{dwType: resourceTypeAny, lpRemoteName: remoteName}
+	ret, _, _ := mprWNetAddConnection2W.Call(
+		uintptr(unsafe.Pointer(&resource)), //nolint:gosec // G103: Valid use of unsafe call to pass resource
+		uintptr(unsafe.Pointer(password)),  //nolint:gosec // G103: Valid use of unsafe call to pass password
+		uintptr(unsafe.Pointer(user)),      //nolint:gosec // G103: Valid use of unsafe call to pass user
+		0)
+	if ret != errorSuccess {
+		if ret == errorSessionCredConflict {
+			return fmt.Errorf("connecting to %q failed, a session with different credentials already exists: %w", remote, syscall.Errno(ret))
+		}
+		return fmt.Errorf("connecting to %q failed: %w", remote, syscall.Errno(ret))
+	}
+	if m.connections == nil {
+		m.connections = make(map[string]bool)
+	}
+	m.connections[remote] = true
+	return nil
+}
+
+// disconnectSources 断开 connectSource 建立的所有会话。
+func (m *WinPerfCounters) disconnectSources() error {
+	m.connectionsLock.Lock()
+	defer m.connectionsLock.Unlock()
+
+	var errs []string
+	for remote := range m.connections {
+		remoteName, err := syscall.UTF16PtrFromString(remote)
+		if err == nil && mprWNetCancelConnection2W != nil {
+			//nolint:gosec // G103: Valid use of unsafe call to pass remoteName
+			if ret, _, _ := mprWNetCancelConnection2W.Call(uintptr(unsafe.Pointer(remoteName)), 0, 1); ret != errorSuccess {
+				errs = append(errs, fmt.Sprintf("%s: %v", remote, syscall.Errno(ret)))
+			}
+		}
+		delete(m.connections, remote)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("disconnecting sources failed: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
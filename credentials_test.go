@@ -0,0 +1,34 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialPasswordRedacted(t *testing.T) {
+	m := NewWinPerfCounters(nil)
+	_, err := toml.Decode(`
+[[credential]]
+  Source = "SQLHOST01"
+  Username = "perfmon"
+  Password = "s3cr3t"
+`, m)
+	require.NoError(t, err)
+	require.Equal(t, secretString("s3cr3t"), m.Credential[0].Password)
+
+	config, err := m.MarshalConfig()
+	require.NoError(t, err)
+	require.NotContains(t, string(config), "s3cr3t")
+	require.Contains(t, string(config), "perfmon")
+
+	// 只有密码不同的配置指纹相同
+	m.updateConfigFingerprint()
+	fingerprint := m.ConfigFingerprint()
+	m.Credential[0].Password = "other"
+	m.updateConfigFingerprint()
+	require.Equal(t, fingerprint, m.ConfigFingerprint())
+}
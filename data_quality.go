@@ -0,0 +1,222 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultDataQualityMeasurement 未配置 DataQualityMeasurement 时数据质量报告的测量名称。
+	defaultDataQualityMeasurement = "win_perf_quality"
+	// defaultDataQualityFlatline 未配置 DataQualityFlatlineGathers 时，字段连续多少次采集为 0 后判定为持平。
+	defaultDataQualityFlatline = 5
+	// defaultDataQualityJumpFactor 未配置 DataQualityJumpFactor 时，相邻两次采集的值相差多少倍判定为跳变。
+	defaultDataQualityJumpFactor = 100
+)
+
+// 数据质量报告中的问题类型，用作明细指标的 issue 标签。
+const (
+	qualityDisappeared = "disappeared"
+	qualityFlatlined   = "flatlined"
+	qualityJumped      = "jumped"
+)
+
+// qualitySeries 记录一条序列上一次采集到的数值字段以及各字段连续为 0 的次数。
+type qualitySeries struct {
+	series staleSeries
+	zeros  map[string]int
+}
+
+// qualityIssue 表示一条序列在本次采集中发现的问题。
+type qualityIssue struct {
+	kind   string
+	series staleSeries
+	field  string
+}
+
+// qualityReport 一个对象本次采集的数据质量统计。
+type qualityReport struct {
+	object string
+	series int
+	issues []qualityIssue
+}
+
+// qualityObject 记录一个对象上一次采集到的序列。
+type qualityObject struct {
+	name   string
+	series map[string]*qualitySeries
+}
+
+// qualityTracker 按对象的标识记录各主机、各对象上一次采集到的序列，用于比较相邻两次采集的数据质量。
+type qualityTracker struct {
+	lock  sync.Mutex
+	hosts map[string]map[objectID]*qualityObject
+}
+
+// update 比较本次与上一次采集的序列，返回本次已采集对象的数据质量统计。
+func (t *qualityTracker) update(computer string, due dueObjects, seen seenSeries, flatline int, jumpFactor float64) []qualityReport {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.hosts == nil {
+		t.hosts = make(map[string]map[objectID]*qualityObject)
+	}
+	objects, ok := t.hosts[computer]
+	if !ok {
+		objects = make(map[objectID]*qualityObject)
+		t.hosts[computer] = objects
+	}
+
+	present := make(map[objectID]bool, len(seen))
+	for object := range seen {
+		present[object.identity()] = true
+	}
+	var reports []qualityReport
+	for id, previous := range objects {
+		if !due.has(id) || present[id] {
+			continue
+		}
+		// 对象本次没有任何序列，上一次的序列全部视为消失
+		report := qualityReport{object: previous.name}
+		for _, last := range previous.series {
+			report.issues = append(report.issues, qualityIssue{kind: qualityDisappeared, series: last.series})
+		}
+		reports = append(reports, report)
+		delete(objects, id)
+	}
+
+	for object, series := range seen {
+		if object == nil {
+			continue
+		}
+		var previous map[string]*qualitySeries
+		if last := objects[object.id]; last != nil {
+			previous = last.series
+		}
+		current := make(map[string]*qualitySeries, len(series))
+		report := qualityReport{object: object.ObjectName, series: len(series)}
+		for key, last := range previous {
+			if _, ok := series[key]; !ok {
+				report.issues = append(report.issues, qualityIssue{kind: qualityDisappeared, series: last.series})
+			}
+		}
+		for key, s := range series {
+			q := &qualitySeries{series: s, zeros: make(map[string]int)}
+			last := previous[key]
+			for _, field := range slices.Sorted(maps.Keys(s.values)) {
+				value := s.values[field]
+				if value == 0 {
+					if last != nil {
+						q.zeros[field] = last.zeros[field]
+					}
+					q.zeros[field]++
+					if flatline > 0 && q.zeros[field] == flatline {
+						report.issues = append(report.issues, qualityIssue{kind: qualityFlatlined, series: s, field: field})
+					}
+				}
+				if last == nil || jumpFactor <= 0 {
+					continue
+				}
+				if before, ok := last.series.values[field]; ok && jumped(before, value, jumpFactor) {
+					report.issues = append(report.issues, qualityIssue{kind: qualityJumped, series: s, field: field})
+				}
+			}
+			current[key] = q
+		}
+		objects[object.id] = &qualityObject{name: object.ObjectName, series: current}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// retain 只保留 ids 中的对象的记录，Reload 后配置未变化的对象继续与上一次采集比较。
+func (t *qualityTracker) retain(ids map[objectID]bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, objects := range t.hosts {
+		for id := range objects {
+			if !ids[id] {
+				delete(objects, id)
+			}
+		}
+	}
+}
+
+// jumped 判断相邻两次采集的值是否相差 factor 倍以上，两次的值都接近 0 时不判定为跳变。
+func jumped(before, after, factor float64) bool {
+	before, after = math.Abs(before), math.Abs(after)
+	if before < 1 && after < 1 {
+		return false
+	}
+	low, high := math.Min(before, after), math.Max(before, after)
+	if low == 0 {
+		return high >= factor
+	}
+	return high/low >= factor
+}
+
+// validateDataQuality 校验数据质量报告配置。
+func (m *WinPerfCounters) validateDataQuality() error {
+	if m.DataQualityFlatlineGathers < 0 {
+		return errors.New("DataQualityFlatlineGathers should not be negative")
+	}
+	if m.DataQualityJumpFactor < 0 || (m.DataQualityJumpFactor > 0 && m.DataQualityJumpFactor <= 1) {
+		return errors.New("DataQualityJumpFactor should be greater than 1")
+	}
+	return nil
+}
+
+// reportQuality 比较相邻两次采集并输出数据质量报告，未启用 DataQuality 时不做任何处理。
+func (m *WinPerfCounters) reportQuality(hostInfo *hostCountersInfo, key string, due dueObjects, seen seenSeries, timestamp time.Time) {
+	if !m.DataQuality {
+		return
+	}
+	measurement := m.DataQualityMeasurement
+	if measurement == "" {
+		measurement = defaultDataQualityMeasurement
+	}
+	flatline := m.DataQualityFlatlineGathers
+	if flatline == 0 {
+		flatline = defaultDataQualityFlatline
+	}
+	jumpFactor := m.DataQualityJumpFactor
+	if jumpFactor == 0 {
+		jumpFactor = defaultDataQualityJumpFactor
+	}
+
+	for _, report := range m.quality.update(key, due, seen, flatline, jumpFactor) {
+		counts := map[string]int{}
+		for _, issue := range report.issues {
+			counts[issue.kind]++
+		}
+		tags := map[string]string{"objectname": report.object}
+		if hostInfo.tag != "" {
+			tags["source"] = hostInfo.tag
+		}
+		m.emit(measurement, map[string]interface{}{
+			"series":           int64(report.series),
+			qualityDisappeared: int64(counts[qualityDisappeared]),
+			qualityFlatlined:   int64(counts[qualityFlatlined]),
+			qualityJumped:      int64(counts[qualityJumped]),
+		}, tags, timestamp)
+
+		if !m.DataQualityDetails {
+			continue
+		}
+		for _, issue := range report.issues {
+			detailTags := maps.Clone(issue.series.tags)
+			detailTags["issue"] = issue.kind
+			detailTags["measurement"] = issue.series.measurement
+			if issue.field != "" {
+				detailTags["field"] = issue.field
+			}
+			m.emit(measurement, map[string]interface{}{"count": int64(1)}, detailTags, timestamp)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJumped(t *testing.T) {
+	tests := []struct {
+		before, after float64
+		want          bool
+	}{
+		{1, 100, true},
+		{100, 1, true},
+		{1, 99, false},
+		{0, 100, true},
+		{0, 99, false},
+		{0.5, 0.001, false},
+		{-1, -100, true},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, jumped(tt.before, tt.after, 100), "%v -> %v", tt.before, tt.after)
+	}
+}
+
+func TestQualityTrackerUpdate(t *testing.T) {
+	objects := []ObjectConfig{{ObjectName: "Process"}}
+	require.NoError(t, assignObjectIDs(objects))
+	seenOf := func(object *ObjectConfig, values map[string]float64) seenSeries {
+		seen := make(seenSeries)
+		for instance, value := range values {
+			seen.add(object, "win_proc", map[string]interface{}{"value": value}, map[string]string{"instance": instance}, true)
+		}
+		return seen
+	}
+	due := make(dueObjects)
+	due.add(&objects[0])
+
+	var tracker qualityTracker
+	reports := tracker.update("local", due, seenOf(&objects[0], map[string]float64{"a": 1, "b": 1}), 2, 100)
+	require.Len(t, reports, 1)
+	require.Equal(t, qualityReport{object: "Process", series: 2}, reports[0])
+
+	reports = tracker.update("local", due, seenOf(&objects[0], map[string]float64{"a": 1000}), 2, 100)
+	require.Len(t, reports, 1)
+	kinds := make([]string, 0, len(reports[0].issues))
+	for _, issue := range reports[0].issues {
+		kinds = append(kinds, issue.kind)
+	}
+	require.ElementsMatch(t, []string{qualityDisappeared, qualityJumped}, kinds)
+
+	// Reload 重新分配对象后，配置未变化的对象继续与上一次采集比较
+	reloaded := slices.Clone(objects)
+	require.NoError(t, assignObjectIDs(reloaded))
+	tracker.retain(objectIDSet(reloaded))
+	due = make(dueObjects)
+	due.add(&reloaded[0])
+	reports = tracker.update("local", due, seenOf(&reloaded[0], map[string]float64{"a": 1}), 2, 100)
+	require.Len(t, reports, 1)
+	require.Len(t, reports[0].issues, 1)
+	require.Equal(t, qualityJumped, reports[0].issues[0].kind)
+
+	// 对象本次没有任何序列时上一次的序列全部视为消失
+	reports = tracker.update("local", due, make(seenSeries), 2, 100)
+	require.Len(t, reports, 1)
+	require.Equal(t, "Process", reports[0].object)
+	require.Len(t, reports[0].issues, 1)
+	require.Equal(t, qualityDisappeared, reports[0].issues[0].kind)
+
+	// 已删除或被修改的对象的记录被丢弃
+	tracker.update("local", due, seenOf(&reloaded[0], map[string]float64{"a": 1}), 2, 100)
+	tracker.retain(map[objectID]bool{})
+	require.Empty(t, tracker.update("local", due, make(seenSeries), 2, 100))
+}
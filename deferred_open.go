@@ -0,0 +1,110 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+)
+
+// deferredItem 等待主机查询打开后再添加的计数器，字段与 addItem 的参数一一对应。
+type deferredItem struct {
+	counterPath  string
+	objectName   string
+	instance     string
+	counterName  string
+	measurement  string
+	includeTotal bool
+	useRawValue  bool
+	object       *ObjectConfig
+}
+
+// deferOpen 判断是否推迟打开主机 computer 的查询：启用 DeferRemoteOpen 时，远程主机的查询在首次采集时才打开。
+func (m *WinPerfCounters) deferOpen(computer string, object *ObjectConfig) bool {
+	if !m.DeferRemoteOpen || computer == "localhost" || logSourcePath(computer) != "" {
+		return false
+	}
+	hostInfo, ok := m.hostCounters[queryKey(computer, object)]
+	return !ok || hostInfo.query == nil
+}
+
+// deferItem 记录远程主机上等待添加的计数器。
+//
+//nolint:revive //argument-limit mirrors addItem
+func (m *WinPerfCounters) deferItem(counterPath, computer, objectName, instance, counterName, measurement string, includeTotal, useRawValue bool, object *ObjectConfig) {
+	if m.hostCounters == nil {
+		m.hostCounters = make(map[string]*hostCountersInfo)
+	}
+	key := queryKey(computer, object)
+	hostInfo, ok := m.hostCounters[key]
+	if !ok {
+		hostInfo = &hostCountersInfo{computer: computer, tag: computer}
+		if object != nil && object.SeparateQuery {
+			hostInfo.separateObject = object.ObjectName
+		}
+		m.hostCounters[key] = hostInfo
+	}
+	hostInfo.deferred = append(hostInfo.deferred, deferredItem{
+		counterPath:  counterPath,
+		objectName:   objectName,
+		instance:     instance,
+		counterName:  counterName,
+		measurement:  measurement,
+		includeTotal: includeTotal,
+		useRawValue:  useRawValue,
+		object:       object,
+	})
+}
+
+// openDeferred 在采集时打开远程主机的查询并添加推迟的计数器，完成首次采样后返回，
+// 速率类计数器从下一次采集开始输出。失败时关闭查询，下一次采集时重试。
+//
+// addItem 会修改 hostCounters 与各个缓存，因此在持有 gatherLock 的采集 goroutine 中、启动各主机的采集之前逐个调用，
+// 不受 CollectTimeout 限制；ctx 已取消时不再打开，留到下一次采集。
+func (m *WinPerfCounters) openDeferred(ctx context.Context, hostInfo *hostCountersInfo) error {
+	computer := hostInfo.computer
+	if err := ctx.Err(); err != nil {
+		return wrapCounterError("open", computer, "", "", err)
+	}
+	if err := m.connectSource(computer); err != nil {
+		return wrapCounterError("open", computer, "", "", err)
+	}
+	query := m.queryCreator.newPerformanceQuery(computer, uint32(m.MaxBufferSize))
+	if err := query.Open(); err != nil {
+		return wrapCounterError("open", computer, "", "", err)
+	}
+	hostInfo.query = query
+	hostInfo.counters = make([]*counter, 0)
+
+	reset := func() {
+		_ = query.Close()
+		hostInfo.query = nil
+		hostInfo.counters = nil
+		hostInfo.fieldNames = nil
+		hostInfo.retries = nil
+	}
+	for _, item := range hostInfo.deferred {
+		err := m.addItem(item.counterPath, computer, item.objectName, item.instance, item.counterName,
+			item.measurement, item.includeTotal, item.useRawValue, item.object)
+		if err != nil {
+			err = wrapCounterError("add", computer, item.objectName, item.counterPath, err)
+			if item.object.FailOnMissing || item.object.WarnOnMissing {
+				m.Log.Errorf("Invalid counterPath %q: %s", item.counterPath, err.Error())
+			}
+			if item.object.FailOnMissing || errors.Is(err, errDuplicateField) {
+				reset()
+				return err
+			}
+			m.retryLater(computer, item, err)
+		}
+	}
+
+	// some counters need two data samples before computing a value
+	if err := query.CollectData(); err != nil {
+		reset()
+		return wrapCounterError("collect", computer, "", "", err)
+	}
+	hostInfo.deferred = nil
+	m.Log.Debugf("Opened query of host %q with %d counters", computer, len(hostInfo.counters))
+	return nil
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeferRemoteOpenTwoHosts 两个推迟打开的远程主机在同一次采集中打开查询，需配合 -race 运行。
+func TestDeferRemoteOpenTwoHosts(t *testing.T) {
+	source := &fakeSource{value: 1}
+	m := newFakeSourcePlugin(source)
+	m.Sources = []string{"hostA", "hostB"}
+	m.DeferRemoteOpen = true
+
+	var lock sync.Mutex
+	sources := make(map[string]int)
+	m.collect = func(_ string, _ map[string]interface{}, tags map[string]string, _ time.Time) {
+		lock.Lock()
+		defer lock.Unlock()
+		sources[tags["source"]]++
+	}
+	require.NoError(t, m.Init())
+	t.Cleanup(func() { _ = m.Close() })
+
+	// 第一次采集打开查询并完成首次采样，第二次开始输出
+	for range 2 {
+		require.NoError(t, m.Gather())
+	}
+	for _, hostInfo := range m.hostCounters {
+		require.NotNil(t, hostInfo.query)
+		require.Empty(t, hostInfo.deferred)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, map[string]int{"hostA": 1, "hostB": 1}, sources)
+}
@@ -0,0 +1,162 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"slices"
+	"strings"
+)
+
+// maxEnumRetries 枚举过程中列表发生变化导致缓冲区再次不足时的最大重试次数。
+const maxEnumRetries = 3
+
+// machineName 将主机名转换为 PDH 枚举函数需要的机器名，本机返回空字符串。
+func machineName(computer string) string {
+	if computer == "" || computer == "localhost" {
+		return ""
+	}
+	return `\\` + computer
+}
+
+// ListObjects 返回 computer 上可用的性能对象名称（本地化名称），computer 为空或 localhost 时枚举本机。
+// 可用于在编写配置前浏览可采集的对象。
+func ListObjects(computer string) ([]string, error) {
+	machine := machineName(computer)
+	var size uint32
+	ret := pdhEnumObjects(machine, nil, &size, perfDetailWizard, true)
+	for range maxEnumRetries {
+		if ret != pdhMoreData {
+			break
+		}
+		buf := make([]uint16, size+1)
+		if ret = pdhEnumObjects(machine, &buf[0], &size, perfDetailWizard, false); ret == errorSuccess {
+			return utf16ToStringArray(buf), nil
+		}
+	}
+	if ret == errorSuccess {
+		return nil, nil
+	}
+	return nil, newPdhError(ret)
+}
+
+// ListCounters 返回 computer 上性能对象 object 的全部计数器。
+func ListCounters(computer, object string) ([]CounterSpec, error) {
+	counters, instances, err := enumObjectItems(computer, object)
+	if err != nil {
+		return nil, err
+	}
+
+	hasInstances := len(instances) > 0
+	instance := emptyInstance
+	if hasInstances {
+		instance = "*"
+	}
+	specs := make([]CounterSpec, 0, len(counters))
+	for _, name := range counters {
+		specs = append(specs, CounterSpec{
+			Object:       object,
+			Name:         name,
+			Path:         formatPath(computer, object, instance, name),
+			HasInstances: hasInstances,
+		})
+	}
+	return specs, nil
+}
+
+// ListInstances 返回 computer 上性能对象 object 当前的实例名称，单实例对象返回空列表。
+func ListInstances(computer, object string) ([]string, error) {
+	_, instances, err := enumObjectItems(computer, object)
+	return instances, err
+}
+
+// enumObjectItems 枚举性能对象的计数器和实例。
+func enumObjectItems(computer, object string) (counters []string, instances []string, err error) {
+	machine := machineName(computer)
+	var counterSize, instanceSize uint32
+	ret := pdhEnumObjectItems(machine, object, nil, &counterSize, nil, &instanceSize, perfDetailWizard)
+	for range maxEnumRetries {
+		if ret != pdhMoreData {
+			break
+		}
+		counterBuf := make([]uint16, counterSize+1)
+		var instanceBuf []uint16
+		var instancePtr *uint16
+		if instanceSize > 0 {
+			instanceBuf = make([]uint16, instanceSize+1)
+			instancePtr = &instanceBuf[0]
+		}
+		ret = pdhEnumObjectItems(machine, object, &counterBuf[0], &counterSize, instancePtr, &instanceSize, perfDetailWizard)
+		if ret == errorSuccess {
+			counters = utf16ToStringArray(counterBuf)
+			if instanceBuf != nil {
+				instances = utf16ToStringArray(instanceBuf)
+			}
+			return counters, instances, nil
+		}
+	}
+	if ret == errorSuccess {
+		return nil, nil, nil
+	}
+	return nil, nil, newPdhError(ret)
+}
+
+// EnumerateMachines 返回 PDH 在当前进程中已连接过的主机名称（不含前导的 \\），
+// 可用于浏览可远程采集的主机。
+func EnumerateMachines() ([]string, error) {
+	var size uint32
+	ret := pdhEnumMachines(nil, &size)
+	for range maxEnumRetries {
+		if ret != pdhMoreData {
+			break
+		}
+		buf := make([]uint16, size+1)
+		if ret = pdhEnumMachines(&buf[0], &size); ret == errorSuccess {
+			machines := utf16ToStringArray(buf)
+			for i, machine := range machines {
+				machines[i] = strings.TrimPrefix(machine, `\\`)
+			}
+			return machines, nil
+		}
+	}
+	if ret == errorSuccess {
+		return nil, nil
+	}
+	return nil, newPdhError(ret)
+}
+
+// CheckSources 尝试通过 PDH 连接配置中的所有远程主机，返回无法连接或未出现在 EnumerateMachines 中的主机，
+// 并为每个主机输出一条警告。本机和日志数据源不做检查。
+func (m *WinPerfCounters) CheckSources() []string {
+	m.configLock.RLock()
+	sources := slices.Clone(m.Sources)
+	for _, object := range m.Object {
+		sources = append(sources, object.Sources...)
+	}
+	m.configLock.RUnlock()
+
+	var unreachable []string
+	checked := make(map[string]bool)
+	for _, source := range sources {
+		machine := machineName(source)
+		if machine == "" || logSourcePath(source) != "" || isAgentSource(source) || strings.EqualFold(source, m.hostname()) || checked[strings.ToLower(source)] {
+			continue
+		}
+		checked[strings.ToLower(source)] = true
+
+		if ret := pdhConnectMachine(machine); ret != errorSuccess {
+			m.Log.Warnf("Source %q is not reachable via PDH: %v", source, newPdhError(ret))
+			unreachable = append(unreachable, source)
+			continue
+		}
+		machines, err := EnumerateMachines()
+		if err != nil {
+			m.Log.Warnf("Enumerating machines failed: %v", err)
+			continue
+		}
+		if !slices.ContainsFunc(machines, func(name string) bool { return strings.EqualFold(name, source) }) {
+			m.Log.Warnf("Source %q is not listed by PDH after connecting", source)
+			unreachable = append(unreachable, source)
+		}
+	}
+	return unreachable
+}
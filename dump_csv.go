@@ -0,0 +1,161 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// perfmonTimeLayout 是 perfmon CSV 中时间戳列的格式。
+const perfmonTimeLayout = "01/02/2006 15:04:05.000"
+
+// counterNameIndex 记录清洗后的字段名到原始计数器名称的映射，用于还原 PDH 计数器路径。
+type counterNameIndex struct {
+	lock  sync.RWMutex
+	names map[string]string
+}
+
+func (i *counterNameIndex) set(objectName, field, counterName string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.names == nil {
+		i.names = make(map[string]string)
+	}
+	i.names[objectName+"\x00"+field] = counterName
+}
+
+func (i *counterNameIndex) get(objectName, field string) (string, bool) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	name, ok := i.names[objectName+"\x00"+field]
+	return name, ok
+}
+
+// perfmonColumn 表示 CSV 中的一列，对应一条序列的一个字段。
+type perfmonColumn struct {
+	path   string
+	series int
+	field  string
+}
+
+// DumpPerfmonCSV 将 History 中保留的历史样本以 perfmon CSV 格式（与 relog -f csv 兼容）写入 w，
+// 便于交给习惯 perfmon 格式的微软技术支持分析。selector 为 nil 时导出全部序列。
+//
+// 每个计数器字段对应一列，列名为 `\\主机\对象(实例)\计数器` 形式的 PDH 路径；
+// 无法还原为计数器的派生字段不会导出。时间戳统一使用 UTC。
+func (m *WinPerfCounters) DumpPerfmonCSV(w io.Writer, selector CollectPredicate, timeRange TimeRange) error {
+	series := m.Query(selector, timeRange)
+
+	var columns []perfmonColumn
+	timestamps := make(map[time.Time]struct{})
+	for i, s := range series {
+		fields := make(map[string]struct{})
+		for _, sample := range s.Samples {
+			timestamps[sample.Timestamp.UTC().Truncate(time.Millisecond)] = struct{}{}
+			for field, value := range sample.Fields {
+				if _, ok := toFloat(value); ok {
+					fields[field] = struct{}{}
+				}
+			}
+		}
+		for field := range fields {
+			counterName, ok := m.counterNames.get(s.Tags["objectname"], field)
+			if !ok {
+				continue
+			}
+			columns = append(columns, perfmonColumn{path: m.perfmonPath(s.Tags, counterName), series: i, field: field})
+		}
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].path < columns[j].path })
+
+	times := make([]time.Time, 0, len(timestamps))
+	for t := range timestamps {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	// 按序列建立时间戳到样本的索引
+	samples := make([]map[time.Time]map[string]interface{}, len(series))
+	for i, s := range series {
+		samples[i] = make(map[time.Time]map[string]interface{}, len(s.Samples))
+		for _, sample := range s.Samples {
+			samples[i][sample.Timestamp.UTC().Truncate(time.Millisecond)] = sample.Fields
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	row := make([]string, 0, len(columns)+1)
+	row = append(row, "(PDH-CSV 4.0) (Coordinated Universal Time)(0)")
+	for _, column := range columns {
+		row = append(row, column.path)
+	}
+	writePerfmonRow(bw, row)
+	for _, t := range times {
+		row = append(row[:0], t.Format(perfmonTimeLayout))
+		for _, column := range columns {
+			cell := " "
+			if value, ok := toFloat(samples[column.series][t][column.field]); ok {
+				cell = strconv.FormatFloat(value, 'f', -1, 64)
+			}
+			row = append(row, cell)
+		}
+		writePerfmonRow(bw, row)
+	}
+	return bw.Flush()
+}
+
+// perfmonPath 根据标签和计数器名称生成 PDH 计数器路径。
+func (m *WinPerfCounters) perfmonPath(tags map[string]string, counterName string) string {
+	host := tags["source"]
+	if host == "" {
+		host = m.hostname()
+	}
+	if instance, ok := tags["instance"]; ok {
+		return fmt.Sprintf(`\\%s\%s(%s)\%s`, host, tags["objectname"], instance, counterName)
+	}
+	return fmt.Sprintf(`\\%s\%s\%s`, host, tags["objectname"], counterName)
+}
+
+// writePerfmonRow 以 perfmon 的格式写入一行：所有单元格都加引号，行尾为 CRLF。
+func writePerfmonRow(w *bufio.Writer, cells []string) {
+	for i, cell := range cells {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.WriteByte('"')
+		w.WriteString(strings.ReplaceAll(cell, `"`, `""`))
+		w.WriteByte('"')
+	}
+	w.WriteString("\r\n")
+}
+
+// DumpHandler 返回以 perfmon CSV 格式下载历史样本的 HTTP 端点。
+// 查询参数 since 指定导出最近多长时间的数据，例如 `?since=10m`，默认导出全部保留的样本。
+func (m *WinPerfCounters) DumpHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var timeRange TimeRange
+		if since := r.URL.Query().Get("since"); since != "" {
+			d, err := time.ParseDuration(since)
+			if err != nil {
+				http.Error(w, "invalid since duration", http.StatusBadRequest)
+				return
+			}
+			timeRange.Start = time.Now().Add(-d)
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="win_perf_counters.csv"`)
+		if err := m.DumpPerfmonCSV(w, nil, timeRange); err != nil {
+			m.Log.Errorf("Dumping perfmon CSV failed: %v", err)
+		}
+	})
+}
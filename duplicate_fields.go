@@ -0,0 +1,66 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// errDuplicateField DuplicateFields 为 "error" 时字段名称冲突返回的错误，总是会中止配置解析。
+var errDuplicateField = errors.New("duplicate field")
+
+// DuplicateFields 支持的策略。
+const (
+	// duplicateFieldsSuffix 为后出现的计数器字段追加 "_2"、"_3" 等后缀。
+	duplicateFieldsSuffix = "suffix"
+	// duplicateFieldsError 解析配置时返回错误。
+	duplicateFieldsError = "error"
+	// duplicateFieldsKeepFirst 保留先出现的计数器，忽略后出现的计数器。
+	duplicateFieldsKeepFirst = "keep_first"
+)
+
+// validateDuplicateFields 校验 DuplicateFields 配置，为空时使用 "suffix"。
+func (m *WinPerfCounters) validateDuplicateFields() error {
+	switch m.DuplicateFields {
+	case "":
+		m.DuplicateFields = duplicateFieldsSuffix
+	case duplicateFieldsSuffix, duplicateFieldsError, duplicateFieldsKeepFirst:
+	default:
+		return fmt.Errorf("invalid DuplicateFields %q, expected one of suffix, error or keep_first", m.DuplicateFields)
+	}
+	return nil
+}
+
+// claimFieldName 检查计数器的字段名称是否与同一主机、测量、对象和实例中的其它计数器冲突，
+// 并按 DuplicateFields 策略处理。返回 false 时不应采集该计数器。
+func (m *WinPerfCounters) claimFieldName(hostInfo *hostCountersInfo, item *counter) (bool, error) {
+	if hostInfo.fieldNames == nil {
+		hostInfo.fieldNames = make(map[string]string)
+	}
+	prefix := item.measurement + "\x00" + item.objectName + "\x00" + item.instance + "\x00"
+	owner, ok := hostInfo.fieldNames[prefix+item.counter]
+	if !ok || owner == item.name {
+		hostInfo.fieldNames[prefix+item.counter] = item.name
+		return true, nil
+	}
+
+	switch m.DuplicateFields {
+	case duplicateFieldsError:
+		return false, fmt.Errorf("%w: counters %q and %q of object %q both map to field %q", errDuplicateField, owner, item.name, item.objectName, item.counter)
+	case duplicateFieldsKeepFirst:
+		m.Log.Warnf("Counter %q of object %q maps to field %q already used by counter %q, skipping it", item.name, item.objectName, item.counter, owner)
+		return false, nil
+	}
+	for i := 2; ; i++ {
+		field := item.counter + "_" + strconv.Itoa(i)
+		if _, ok := hostInfo.fieldNames[prefix+field]; ok {
+			continue
+		}
+		m.Log.Warnf("Counter %q of object %q maps to field %q already used by counter %q, using %q instead", item.name, item.objectName, item.counter, owner, field)
+		item.counter = field
+		hostInfo.fieldNames[prefix+field] = item.name
+		return true, nil
+	}
+}
@@ -0,0 +1,107 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDuplicateFields(t *testing.T) {
+	tests := []struct {
+		policy  string
+		want    string
+		wantErr bool
+	}{
+		{"", duplicateFieldsSuffix, false},
+		{"suffix", duplicateFieldsSuffix, false},
+		{"error", duplicateFieldsError, false},
+		{"keep_first", duplicateFieldsKeepFirst, false},
+		{"Suffix", "", true},
+		{"drop", "", true},
+	}
+	for _, tt := range tests {
+		m := &WinPerfCounters{DuplicateFields: tt.policy}
+		err := m.validateDuplicateFields()
+		if tt.wantErr {
+			require.ErrorContains(t, err, "invalid DuplicateFields", tt.policy)
+			continue
+		}
+		require.NoError(t, err, tt.policy)
+		require.Equal(t, tt.want, m.DuplicateFields, tt.policy)
+	}
+}
+
+func TestClaimFieldName(t *testing.T) {
+	// "% Disk Time" 与 "%_Disk_Time" 清洗后都映射到字段 "Percent_Disk_Time"
+	first := counter{measurement: "win_disk", objectName: "LogicalDisk", instance: "C:", name: "% Disk Time", counter: "Percent_Disk_Time"}
+	second := counter{measurement: "win_disk", objectName: "LogicalDisk", instance: "C:", name: "%_Disk_Time", counter: "Percent_Disk_Time"}
+	third := counter{measurement: "win_disk", objectName: "LogicalDisk", instance: "C:", name: "%  Disk Time", counter: "Percent_Disk_Time"}
+
+	tests := []struct {
+		policy  string
+		items   []counter
+		want    []string
+		claimed []bool
+		wantErr bool
+	}{
+		{
+			policy:  duplicateFieldsSuffix,
+			items:   []counter{first, second, third},
+			want:    []string{"Percent_Disk_Time", "Percent_Disk_Time_2", "Percent_Disk_Time_3"},
+			claimed: []bool{true, true, true},
+		},
+		{
+			policy:  duplicateFieldsKeepFirst,
+			items:   []counter{first, second},
+			want:    []string{"Percent_Disk_Time", "Percent_Disk_Time"},
+			claimed: []bool{true, false},
+		},
+		{
+			policy:  duplicateFieldsError,
+			items:   []counter{first, second},
+			want:    []string{"Percent_Disk_Time", "Percent_Disk_Time"},
+			claimed: []bool{true, false},
+			wantErr: true,
+		},
+		{
+			// 同一计数器重复添加时不是冲突
+			policy:  duplicateFieldsError,
+			items:   []counter{first, first},
+			want:    []string{"Percent_Disk_Time", "Percent_Disk_Time"},
+			claimed: []bool{true, true},
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		m := &WinPerfCounters{DuplicateFields: tt.policy, Log: Logger{Output: log.New(&buf, "", 0)}}
+		hostInfo := &hostCountersInfo{}
+		for i, item := range tt.items {
+			ok, err := m.claimFieldName(hostInfo, &item)
+			if tt.wantErr && i == len(tt.items)-1 {
+				require.ErrorIs(t, err, errDuplicateField, tt.policy)
+			} else {
+				require.NoError(t, err, tt.policy)
+			}
+			require.Equal(t, tt.claimed[i], ok, "%s: counter %d", tt.policy, i)
+			require.Equal(t, tt.want[i], item.counter, "%s: counter %d", tt.policy, i)
+		}
+	}
+
+	// 不同实例、对象或测量中的同名字段不冲突
+	m := &WinPerfCounters{DuplicateFields: duplicateFieldsError}
+	hostInfo := &hostCountersInfo{}
+	for _, item := range []counter{
+		first,
+		{measurement: "win_disk", objectName: "LogicalDisk", instance: "D:", name: "%_Disk_Time", counter: "Percent_Disk_Time"},
+		{measurement: "win_disk", objectName: "PhysicalDisk", instance: "C:", name: "%_Disk_Time", counter: "Percent_Disk_Time"},
+		{measurement: "win_disk2", objectName: "LogicalDisk", instance: "C:", name: "%_Disk_Time", counter: "Percent_Disk_Time"},
+	} {
+		ok, err := m.claimFieldName(hostInfo, &item)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+}
@@ -0,0 +1,145 @@
+// Package encoders 将采集到的指标编码为 InfluxDB 行协议或 JSON 行，并提供写入 io.Writer 或 UDP/TCP 套接字的 CollectFunc，
+// 便于直接转发给 InfluxDB、Vector、Fluent Bit 等，而不必在回调中自行序列化。
+package encoders
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rokukoo/win_perf_counters"
+)
+
+// Encoder 将一条指标编码为以换行结尾的一行数据。
+type Encoder func(measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time) ([]byte, error)
+
+// errNoFields 指标没有可以编码的字段。
+var errNoFields = errors.New("metric has no encodable fields")
+
+var (
+	// measurementEscaper 转义行协议测量名称中的特殊字符。
+	measurementEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, " ", `\ `, "\n", "", "\r", "")
+	// keyEscaper 转义行协议标签键、标签值与字段键中的特殊字符。
+	keyEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`, " ", `\ `, "\n", "", "\r", "")
+	// stringEscaper 转义行协议字符串字段值中的特殊字符。
+	stringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", "", "\r", "")
+)
+
+// EncodeLineProtocol 将指标编码为一行 InfluxDB 行协议，时间戳精度为纳秒，标签与字段按名称排序。
+// 整数字段带 "i" 后缀，无符号整数带 "u" 后缀，字符串字段加引号；NaN、Inf 以及不支持的类型的字段被省略，值为空的标签被省略。
+// 行协议没有换行的转义写法，名称、标签值与字符串字段值中的换行符和回车符被删除。
+func EncodeLineProtocol(measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time) ([]byte, error) {
+	var line strings.Builder
+	line.WriteString(measurementEscaper.Replace(measurement))
+	for _, key := range sortedKeys(tags) {
+		value := keyEscaper.Replace(tags[key])
+		if value == "" {
+			continue
+		}
+		line.WriteByte(',')
+		line.WriteString(keyEscaper.Replace(key))
+		line.WriteByte('=')
+		line.WriteString(value)
+	}
+
+	separator := byte(' ')
+	for _, key := range sortedKeys(fields) {
+		value, ok := lineProtocolValue(fields[key])
+		if !ok {
+			continue
+		}
+		line.WriteByte(separator)
+		separator = ','
+		line.WriteString(keyEscaper.Replace(key))
+		line.WriteByte('=')
+		line.WriteString(value)
+	}
+	if separator == ' ' {
+		return nil, fmt.Errorf("encoding %q failed: %w", measurement, errNoFields)
+	}
+	line.WriteByte(' ')
+	line.WriteString(strconv.FormatInt(timestamp.UnixNano(), 10))
+	line.WriteByte('\n')
+	return []byte(line.String()), nil
+}
+
+// lineProtocolValue 返回字段值在行协议中的表示。
+func lineProtocolValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case float32:
+		return lineProtocolValue(float64(v))
+	case int64:
+		return strconv.FormatInt(v, 10) + "i", true
+	case int:
+		return strconv.Itoa(v) + "i", true
+	case int32:
+		return strconv.FormatInt(int64(v), 10) + "i", true
+	case uint64:
+		return strconv.FormatUint(v, 10) + "u", true
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10) + "u", true
+	case bool:
+		return strconv.FormatBool(v), true
+	case string:
+		return `"` + stringEscaper.Replace(v) + `"`, true
+	}
+	return "", false
+}
+
+// EncodeJSON 将指标编码为一行 JSON，格式与 win_perf_counters.Metric 的 JSON 表示相同，JSON 无法表示的 NaN 与 Inf 字段被省略。
+func EncodeJSON(measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time) ([]byte, error) {
+	finite := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if f, ok := value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			continue
+		}
+		finite[key] = value
+	}
+	if len(finite) == 0 {
+		return nil, fmt.Errorf("encoding %q failed: %w", measurement, errNoFields)
+	}
+	data, err := json.Marshal(win_perf_counters.Metric{Measurement: measurement, Tags: tags, Fields: finite, Timestamp: timestamp})
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// NewWriterCollectFunc 返回将每条指标以 encode 编码后写入 w 的 CollectFunc，可以在多个 goroutine 中同时调用。
+// 编码或写入失败时调用 onError，onError 为 nil 时忽略错误。
+func NewWriterCollectFunc(w io.Writer, encode Encoder, onError func(error)) win_perf_counters.CollectFunc {
+	var lock sync.Mutex
+	return func(measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time) {
+		data, err := encode(measurement, fields, tags, timestamp)
+		if err == nil {
+			lock.Lock()
+			_, err = w.Write(data)
+			lock.Unlock()
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// sortedKeys 返回映射按名称排序的键。
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,108 @@
+package encoders
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Unix(1700000000, 123)
+
+func TestEncodeLineProtocol(t *testing.T) {
+	tests := []struct {
+		name        string
+		measurement string
+		fields      map[string]interface{}
+		tags        map[string]string
+		want        string
+	}{
+		{
+			name:        "types",
+			measurement: "win_cpu",
+			fields: map[string]interface{}{
+				"f":   1.5,
+				"f32": float32(0.5),
+				"i":   int64(-3),
+				"n":   7,
+				"n32": int32(8),
+				"u":   uint64(9),
+				"u32": uint32(10),
+				"b":   true,
+				"s":   "ok",
+			},
+			tags: map[string]string{"instance": "_Total", "host": "srv1"},
+			want: `win_cpu,host=srv1,instance=_Total b=true,f=1.5,f32=0.5,i=-3i,n=7i,n32=8i,s="ok",u=9u,u32=10u 1700000000000000123` + "\n",
+		},
+		{
+			name:        "escaping",
+			measurement: "win cpu,x",
+			fields:      map[string]interface{}{"a b=c": `say "hi" \ now`},
+			tags:        map[string]string{"k,1": `a=b c\d`},
+			want:        `win\ cpu\,x,k\,1=a\=b\ c\\d a\ b\=c="say \"hi\" \\ now" 1700000000000000123` + "\n",
+		},
+		{
+			name:        "newlines are stripped",
+			measurement: "win\n_cpu",
+			fields:      map[string]interface{}{"va\r\nlue": 1.0, "s": "line1\nline2"},
+			tags:        map[string]string{"instance": "disk\n0", "only": "\r\n"},
+			want:        `win_cpu,instance=disk0 s="line1line2",value=1 1700000000000000123` + "\n",
+		},
+		{
+			name:        "skipped fields and tags",
+			measurement: "win_mem",
+			fields: map[string]interface{}{
+				"nan":   math.NaN(),
+				"inf":   math.Inf(1),
+				"other": []int{1},
+				"value": 2.0,
+			},
+			tags: map[string]string{"empty": ""},
+			want: "win_mem value=2 1700000000000000123\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := EncodeLineProtocol(tt.measurement, tt.fields, tt.tags, testTime)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(line))
+			require.Equal(t, 1, bytes.Count(line, []byte("\n")))
+		})
+	}
+
+	_, err := EncodeLineProtocol("win_cpu", map[string]interface{}{"nan": math.NaN()}, nil, testTime)
+	require.ErrorIs(t, err, errNoFields)
+}
+
+func TestEncodeJSON(t *testing.T) {
+	line, err := EncodeJSON("win_cpu", map[string]interface{}{"value": 1.5, "nan": math.NaN()}, map[string]string{"instance": "_Total"}, testTime)
+	require.NoError(t, err)
+	require.Equal(t, byte('\n'), line[len(line)-1])
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(line, &decoded))
+	require.Equal(t, "win_cpu", decoded["measurement"])
+	require.Equal(t, map[string]interface{}{"value": 1.5}, decoded["fields"])
+	require.Equal(t, map[string]interface{}{"instance": "_Total"}, decoded["tags"])
+
+	_, err = EncodeJSON("win_cpu", map[string]interface{}{"inf": math.Inf(-1)}, nil, testTime)
+	require.ErrorIs(t, err, errNoFields)
+}
+
+func TestNewWriterCollectFunc(t *testing.T) {
+	var buffer bytes.Buffer
+	var errs []error
+	collect := NewWriterCollectFunc(&buffer, EncodeLineProtocol, func(err error) { errs = append(errs, err) })
+
+	collect("win_cpu", map[string]interface{}{"value": 1.0}, nil, testTime)
+	collect("win_cpu", map[string]interface{}{"nan": math.NaN()}, nil, testTime)
+	require.Equal(t, "win_cpu value=1 1700000000000000123\n", buffer.String())
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[0], errNoFields)
+
+	// onError 为 nil 时忽略错误
+	NewWriterCollectFunc(&buffer, EncodeLineProtocol, nil)("win_cpu", nil, nil, testTime)
+}
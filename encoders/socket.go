@@ -0,0 +1,83 @@
+package encoders
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rokukoo/win_perf_counters"
+)
+
+// socketDialTimeout 连接 TCP 地址的超时时间。
+const socketDialTimeout = 5 * time.Second
+
+// SocketWriter 写入 UDP 或 TCP 地址的 io.Writer。UDP 每次 Write 发送一个数据报；
+// TCP 连接断开后在下一次 Write 时重新连接，重新连接失败时丢弃该次数据并返回错误。
+type SocketWriter struct {
+	network string
+	address string
+
+	lock sync.Mutex
+	conn net.Conn
+}
+
+// NewSocketWriter 连接 network（"udp"、"udp4"、"udp6"、"tcp"、"tcp4" 或 "tcp6"）上的 address，例如 "127.0.0.1:8094"。
+func NewSocketWriter(network, address string) (*SocketWriter, error) {
+	switch network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("unsupported network %q, expected udp or tcp", network)
+	}
+	s := &SocketWriter{network: network, address: address}
+	conn, err := net.DialTimeout(network, address, socketDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+	return s, nil
+}
+
+// Write 将 data 写入套接字。
+func (s *SocketWriter) Write(data []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, socketDialTimeout)
+		if err != nil {
+			return 0, err
+		}
+		s.conn = conn
+	}
+	n, err := s.conn.Write(data)
+	if err != nil {
+		// 连接已失效，下一次写入时重新连接
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	return n, err
+}
+
+// Close 关闭套接字。
+func (s *SocketWriter) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// NewSocketCollectFunc 返回将每条指标以 encode 编码后发送到 network 上 address 的 CollectFunc，
+// 例如发送行协议到 Telegraf socket_listener 或 Vector 的 socket 源。使用结束后应关闭返回的 SocketWriter。
+func NewSocketCollectFunc(network, address string, encode Encoder, onError func(error)) (win_perf_counters.CollectFunc, *SocketWriter, error) {
+	writer, err := NewSocketWriter(network, address)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewWriterCollectFunc(writer, encode, onError), writer, nil
+}
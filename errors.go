@@ -0,0 +1,142 @@
+//go:generate go run pdh_errors_gen.go
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrUnsupportedPlatform 在非 Windows 平台调用依赖 PDH 的功能时返回，多平台程序可据此在运行时决定是否启用采集器。
+var ErrUnsupportedPlatform = errors.New("win_perf_counters: not supported on this platform")
+
+// CounterError 为采集和解析过程中的错误附加来源主机、性能对象和计数器路径，
+// 便于日志聚合时按维度分组，可通过 errors.As 获取。
+type CounterError struct {
+	// Host 出错的主机。
+	Host string
+	// Object 出错的性能对象名称，可能为空。
+	Object string
+	// CounterPath 出错的计数器路径，可能为空。
+	CounterPath string
+	// Op 出错时执行的操作，例如 "add"、"collect"、"read"。
+	Op string
+	// Err 原始错误。
+	Err error
+}
+
+func (e *CounterError) Error() string {
+	var parts []string
+	if e.Host != "" {
+		parts = append(parts, fmt.Sprintf("host=%q", e.Host))
+	}
+	if e.Object != "" {
+		parts = append(parts, fmt.Sprintf("object=%q", e.Object))
+	}
+	if e.CounterPath != "" {
+		parts = append(parts, fmt.Sprintf("counter=%q", e.CounterPath))
+	}
+	return fmt.Sprintf("%s failed [%s]: %v", e.Op, strings.Join(parts, " "), e.Err)
+}
+
+func (e *CounterError) Unwrap() error {
+	return e.Err
+}
+
+// wrapCounterError 为错误附加上下文，err 为 nil 时返回 nil。
+// 已经是 *CounterError 的错误只补全缺失的字段，不会重复包装。
+func wrapCounterError(op, host, object, counterPath string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var counterErr *CounterError
+	if errors.As(err, &counterErr) {
+		if counterErr.Host == "" {
+			counterErr.Host = host
+		}
+		if counterErr.Object == "" {
+			counterErr.Object = object
+		}
+		if counterErr.CounterPath == "" {
+			counterErr.CounterPath = counterPath
+		}
+		return err
+	}
+	return &CounterError{Host: host, Object: object, CounterPath: counterPath, Op: op, Err: err}
+}
+
+// DescribePDHError 返回 PDH 错误码的名称及 Windows SDK 中的完整说明，例如
+// "PDH_CSTATUS_NO_OBJECT (0xC0000BB8): The specified object was not found on the computer."，
+// 不依赖 Windows，可用于在其它平台上解释日志中的错误码。未知的错误码只返回其十六进制值。
+func DescribePDHError(code uint32) string {
+	name, ok := pdhErrors[code]
+	if !ok {
+		return fmt.Sprintf("unknown PDH error (0x%08X)", code)
+	}
+	if description := stripMessageInserts(pdhErrorDescriptions[code]); description != "" {
+		return fmt.Sprintf("%s (0x%08X): %s", name, code, description)
+	}
+	return fmt.Sprintf("%s (0x%08X)", name, code)
+}
+
+var (
+	// messageInsert 消息文本中的插入项，例如 %1、"%1!s!"，连同包围它的引号。
+	messageInsert = regexp.MustCompile(`"?%\d+(?:![^!]*!)?"?`)
+	// spaceBeforePunct 删除插入项后留在标点前的空白。
+	spaceBeforePunct = regexp.MustCompile(`\s+([.,;:])`)
+)
+
+// stripMessageInserts 删除 FormatMessage 以 FORMAT_MESSAGE_IGNORE_INSERTS 返回或 pdhmsg.h 中的消息文本里未填充的插入项，
+// 并整理留下的空白，例如 "Call to SQLFetch failed with %1." 变为 "Call to SQLFetch failed with."。
+func stripMessageInserts(message string) string {
+	if !strings.Contains(message, "%") {
+		return strings.TrimSpace(message)
+	}
+	message = messageInsert.ReplaceAllString(message, "")
+	message = spaceBeforePunct.ReplaceAllString(message, "$1")
+	return strings.Join(strings.Fields(message), " ")
+}
+
+// ErrBufferLimit 在计数器数据需要的缓冲区超过 MaxBufferSize 时返回，记录了出错的计数器路径、所需的大小和配置的上限，
+// 可通过 errors.As 获取，据此为对应的对象调大 MaxBufferSize。
+type ErrBufferLimit struct {
+	// CounterPath 出错的计数器路径，无法获取时为空。
+	CounterPath string
+	// Required PDH 最后一次提示的所需缓冲区大小，PDH 未提示时为 0。
+	Required uint32
+	// Limit 配置的缓冲区上限。
+	Limit uint32
+	// Suggested 建议配置的 MaxBufferSize，不小于 Required 且大于 Limit。
+	Suggested uint64
+}
+
+func (e *ErrBufferLimit) Error() string {
+	var b strings.Builder
+	b.WriteString("buffer limit reached")
+	if e.CounterPath != "" {
+		fmt.Fprintf(&b, " for counter %q", e.CounterPath)
+	}
+	if e.Required > 0 {
+		fmt.Fprintf(&b, ": %d bytes required", e.Required)
+	} else {
+		b.WriteString(": required size unknown")
+	}
+	fmt.Fprintf(&b, ", limit is %d", e.Limit)
+	if e.Suggested > 0 {
+		fmt.Fprintf(&b, ", try MaxBufferSize = %d", e.Suggested)
+	}
+	return b.String()
+}
+
+// suggestBufferSize 返回不小于 required 且大于 limit 的建议缓冲区上限：从 initial 开始按采集时的加倍顺序取第一个满足的大小，
+// required 未知时为 limit 的两倍。
+func suggestBufferSize(initial, required, limit uint32) uint64 {
+	target := max(uint64(required), uint64(limit)+1)
+	size := max(uint64(initial), 1)
+	for size < target {
+		size *= 2
+	}
+	return size
+}
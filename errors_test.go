@@ -0,0 +1,33 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapCounterError(t *testing.T) {
+	require.NoError(t, wrapCounterError("read", "localhost", "", "", nil))
+
+	pdhErr := newPdhError(pdhCstatusNoCounter)
+	err := wrapCounterError("read", "localhost", "Processor", `\Processor(_Total)\% Processor Time`, pdhErr)
+
+	var counterErr *CounterError
+	require.ErrorAs(t, err, &counterErr)
+	require.Equal(t, "localhost", counterErr.Host)
+	require.Equal(t, "Processor", counterErr.Object)
+	require.Equal(t, `\Processor(_Total)\% Processor Time`, counterErr.CounterPath)
+	require.ErrorIs(t, err, pdhErr)
+
+	var target *pdhError
+	require.True(t, errors.As(err, &target))
+	require.Equal(t, uint32(pdhCstatusNoCounter), target.errorCode)
+
+	// Wrapping again only fills in missing context
+	rewrapped := wrapCounterError("add", "remote", "Memory", `\Memory\Available Bytes`, err)
+	require.Same(t, err, rewrapped)
+	require.Equal(t, "localhost", counterErr.Host)
+}
@@ -0,0 +1,436 @@
+//go:build windows && (amd64 || arm64)
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// etwMeasurement ETW 事件的测量名称。
+const etwMeasurement = "win_etw"
+
+const (
+	wnodeFlagTracedGUID           = 0x00020000
+	eventTraceRealTimeMode        = 0x00000100
+	eventTraceControlStop         = 1
+	eventControlCodeEnable        = 1
+	processTraceModeRealTime      = 0x00000100
+	processTraceModeEventRecord   = 0x10000000
+	invalidProcessTraceHandle     = ^uint64(0)
+	errorAlreadyExists            = 183
+	errorCancelled                = 1223
+	errorCtxClosePending          = 7007
+	etwDefaultLevel               = 4 // TRACE_LEVEL_INFORMATION
+	etwClientContextQueryPerfFreq = 1
+)
+
+// etwKnownProviders 常用 ETW 提供程序名称到 GUID 的映射，其它提供程序需直接配置 GUID。
+var etwKnownProviders = map[string]string{
+	"microsoft-windows-kernel-network": "{7DD42A49-5329-4832-8DFD-43D979153A88}",
+	"microsoft-windows-kernel-process": "{22FB2CD6-0E7B-422B-A0C7-2FAD1FD0E716}",
+	"microsoft-windows-kernel-file":    "{EDD08927-9CC4-4E65-B970-C2560FB5C289}",
+	"microsoft-windows-dns-client":     "{1C95126E-7EEA-49A9-A3FE-A378B03DDB4D}",
+	"microsoft-windows-tcpip":          "{2F07E2EE-15DB-40F1-90EF-9D7BA282188A}",
+	"microsoft-windows-winhttp":        "{7D44233D-3055-4B9C-BA64-0D47CA40A232}",
+}
+
+// wnodeHeader mirrors WNODE_HEADER
+type wnodeHeader struct {
+	BufferSize        uint32
+	ProviderID        uint32
+	HistoricalContext uint64
+	TimeStamp         int64
+	GUID              windows.GUID
+	ClientContext     uint32
+	Flags             uint32
+}
+
+// eventTraceProperties mirrors EVENT_TRACE_PROPERTIES
+type eventTraceProperties struct {
+	Wnode               wnodeHeader
+	BufferSize          uint32
+	MinimumBuffers      uint32
+	MaximumBuffers      uint32
+	MaximumFileSize     uint32
+	LogFileMode         uint32
+	FlushTimer          uint32
+	EnableFlags         uint32
+	AgeLimit            int32
+	NumberOfBuffers     uint32
+	FreeBuffers         uint32
+	EventsLost          uint32
+	BuffersWritten      uint32
+	LogBuffersLost      uint32
+	RealTimeBuffersLost uint32
+	LoggerThreadID      uintptr
+	LogFileNameOffset   uint32
+	LoggerNameOffset    uint32
+}
+
+// eventTraceLogfile mirrors EVENT_TRACE_LOGFILEW, the members not used by real-time
+// sessions (CurrentEvent and LogfileHeader) are kept opaque.
+type eventTraceLogfile struct {
+	LogFileName         *uint16
+	LoggerName          *uint16
+	CurrentTime         int64
+	BuffersRead         uint32
+	ProcessTraceMode    uint32
+	CurrentEvent        [88]byte
+	LogfileHeader       [280]byte
+	BufferCallback      uintptr
+	BufferSize          uint32
+	Filled              uint32
+	EventsLost          uint32
+	EventRecordCallback uintptr
+	IsKernelTrace       uint32
+	Context             uintptr
+}
+
+// eventDescriptor mirrors EVENT_DESCRIPTOR
+type eventDescriptor struct {
+	ID      uint16
+	Version uint8
+	Channel uint8
+	Level   uint8
+	Opcode  uint8
+	Task    uint16
+	Keyword uint64
+}
+
+// eventHeader mirrors EVENT_HEADER
+type eventHeader struct {
+	Size            uint16
+	HeaderType      uint16
+	Flags           uint16
+	EventProperty   uint16
+	ThreadID        uint32
+	ProcessID       uint32
+	TimeStamp       int64
+	ProviderID      windows.GUID
+	EventDescriptor eventDescriptor
+	ProcessorTime   uint64
+	ActivityID      windows.GUID
+}
+
+// eventRecord mirrors EVENT_RECORD
+type eventRecord struct {
+	EventHeader       eventHeader
+	BufferContext     uint32
+	ExtendedDataCount uint16
+	UserDataLength    uint16
+	ExtendedData      uintptr
+	UserData          unsafe.Pointer
+	UserContext       uintptr
+}
+
+var (
+	// Library
+	libAdvapi32Dll *syscall.DLL
+
+	// Functions
+	advapiStartTraceW    *syscall.Proc
+	advapiControlTraceW  *syscall.Proc
+	advapiEnableTraceEx2 *syscall.Proc
+	advapiOpenTraceW     *syscall.Proc
+	advapiProcessTrace   *syscall.Proc
+	advapiCloseTrace     *syscall.Proc
+
+	// etwRecordCallback 所有会话共用的事件回调，通过 UserContext 找到对应的会话。
+	etwRecordCallback = syscall.NewCallback(etwEventRecordCallback)
+	// etwSessions 运行中的会话，键为传给 OpenTrace 的 Context。
+	etwSessions sync.Map
+	// etwSessionID 分配会话 Context 的计数器。
+	etwSessionID atomic.Uintptr
+)
+
+func init() {
+	var err error
+	if libAdvapi32Dll, err = syscall.LoadDLL("advapi32.dll"); err != nil {
+		return
+	}
+	advapiStartTraceW, _ = libAdvapi32Dll.FindProc("StartTraceW")
+	advapiControlTraceW, _ = libAdvapi32Dll.FindProc("ControlTraceW")
+	advapiEnableTraceEx2, _ = libAdvapi32Dll.FindProc("EnableTraceEx2")
+	advapiOpenTraceW, _ = libAdvapi32Dll.FindProc("OpenTraceW")
+	advapiProcessTrace, _ = libAdvapi32Dll.FindProc("ProcessTrace")
+	advapiCloseTrace, _ = libAdvapi32Dll.FindProc("CloseTrace")
+}
+
+// ETWProvider 描述 ETW 会话中启用的一个提供程序。
+type ETWProvider struct {
+	// Name 提供程序的 GUID（形如 "{7DD42A49-...}"）或常用提供程序的名称，例如 "Microsoft-Windows-DNS-Client"。
+	Name string `toml:"Name"`
+	// Level 启用的最高事件级别（1 严重 ~ 5 详细），为 0 时使用 4（信息）。
+	Level uint8 `toml:"Level"`
+	// MatchAnyKeyword 事件关键字需至少包含其中一位，为 0 时不按关键字过滤。
+	MatchAnyKeyword uint64 `toml:"MatchAnyKeyword"`
+	// EventIDs 只输出这些事件 ID 的事件，为空时输出全部事件。
+	EventIDs []uint16 `toml:"EventIDs"`
+
+	guid windows.GUID
+}
+
+// resolve 解析提供程序的 GUID。
+func (p *ETWProvider) resolve() error {
+	name := p.Name
+	if guid, ok := etwKnownProviders[strings.ToLower(name)]; ok {
+		name = guid
+	}
+	if !strings.HasPrefix(name, "{") {
+		name = "{" + name + "}"
+	}
+	guid, err := windows.GUIDFromString(name)
+	if err != nil {
+		return fmt.Errorf("unknown ETW provider %q, expected a GUID or one of the known provider names", p.Name)
+	}
+	p.guid = guid
+	return nil
+}
+
+// ETWSession 是一个实时 ETW 会话，将订阅的提供程序的事件以 win_etw 测量通过 CollectFunc 输出，
+// 用于采集没有对应 PDH 计数器的遥测数据，例如网络连接和 DNS 查询。
+//
+// 每个事件输出一个数据点：标签为 provider、event_id、opcode 和 level，字段为 process_id、thread_id
+// 以及通过 TDH 解码的事件属性。需要管理员权限或 Performance Log Users 组成员身份。
+type ETWSession struct {
+	// Log 日志记录器。
+	Log Logger
+
+	name      string
+	providers []ETWProvider
+	collect   CollectFunc
+	id        uintptr
+
+	handle      uint64
+	traceHandle uint64
+	properties  []uint64
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewETWSession 创建名为 name 的实时 ETW 会话并开始接收事件，同名会话已存在时先将其停止。
+// 使用完毕后需调用 Close，否则会话会在进程退出后继续存在于系统中。
+func NewETWSession(name string, providers []ETWProvider, collectFunc CollectFunc) (*ETWSession, error) {
+	if advapiStartTraceW == nil || advapiEnableTraceEx2 == nil || advapiOpenTraceW == nil || advapiProcessTrace == nil {
+		return nil, errors.New("ETW real-time sessions are not supported on this system")
+	}
+	if name == "" {
+		return nil, errors.New("ETW session name is required")
+	}
+	if len(providers) == 0 {
+		return nil, errors.New("no ETW providers configured")
+	}
+	providers = slices.Clone(providers)
+	for i := range providers {
+		if err := providers[i].resolve(); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &ETWSession{
+		Log:       Logger{Name: "win_perf_counters.etw"},
+		name:      name,
+		providers: providers,
+		collect:   collectFunc,
+		id:        etwSessionID.Add(1),
+		done:      make(chan struct{}),
+	}
+	if err := s.start(); err != nil {
+		return nil, err
+	}
+	for i := range s.providers {
+		if err := s.enable(&s.providers[i]); err != nil {
+			_ = s.stop()
+			return nil, err
+		}
+	}
+	if err := s.open(); err != nil {
+		_ = s.stop()
+		return nil, err
+	}
+
+	etwSessions.Store(s.id, s)
+	go func() {
+		defer close(s.done)
+		handle := s.traceHandle
+		ret, _, _ := advapiProcessTrace.Call(uintptr(unsafe.Pointer(&handle)), 1, 0, 0) //nolint:gosec // G103: Valid use of unsafe call to pass the trace handle array
+		if ret != 0 && ret != errorCancelled {
+			s.Log.Errorf("Processing ETW session %q stopped: %v", s.name, syscall.Errno(ret))
+		}
+	}()
+	return s, nil
+}
+
+// newProperties 构造 StartTrace 和 ControlTrace 使用的 EVENT_TRACE_PROPERTIES，会话名称紧随其后。
+func (s *ETWSession) newProperties() *eventTraceProperties {
+	size := unsafe.Sizeof(eventTraceProperties{}) + uintptr(len(s.name)+1)*2
+	s.properties = make([]uint64, (size+7)/8)
+	properties := (*eventTraceProperties)(unsafe.Pointer(&s.properties[0])) //nolint:gosec // G103: Valid use of unsafe call to create EVENT_TRACE_PROPERTIES
+	properties.Wnode.BufferSize = uint32(size)
+	properties.Wnode.Flags = wnodeFlagTracedGUID
+	properties.Wnode.ClientContext = etwClientContextQueryPerfFreq
+	properties.LogFileMode = eventTraceRealTimeMode
+	properties.LoggerNameOffset = uint32(unsafe.Sizeof(eventTraceProperties{}))
+	return properties
+}
+
+// start 创建实时会话。
+func (s *ETWSession) start() error {
+	name, err := syscall.UTF16PtrFromString(s.name)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		properties := s.newProperties()
+		ret, _, _ := advapiStartTraceW.Call(
+			uintptr(unsafe.Pointer(&s.handle)),  //nolint:gosec // G103: Valid use of unsafe call to pass the session handle
+			uintptr(unsafe.Pointer(name)),       //nolint:gosec // G103: Valid use of unsafe call to pass the session name
+			uintptr(unsafe.Pointer(properties))) //nolint:gosec // G103: Valid use of unsafe call to pass EVENT_TRACE_PROPERTIES
+		if ret == 0 {
+			return nil
+		}
+		if ret != errorAlreadyExists || attempt > 0 {
+			return fmt.Errorf("starting ETW session %q failed: %w", s.name, syscall.Errno(ret))
+		}
+		// 上次运行未正常关闭的同名会话，停止后重试
+		s.control(0, eventTraceControlStop)
+	}
+}
+
+// enable 在会话中启用提供程序。
+func (s *ETWSession) enable(provider *ETWProvider) error {
+	level := provider.Level
+	if level == 0 {
+		level = etwDefaultLevel
+	}
+	ret, _, _ := advapiEnableTraceEx2.Call(
+		uintptr(s.handle),
+		uintptr(unsafe.Pointer(&provider.guid)), //nolint:gosec // G103: Valid use of unsafe call to pass the provider GUID
+		eventControlCodeEnable,
+		uintptr(level),
+		uintptr(provider.MatchAnyKeyword),
+		0,
+		0,
+		0)
+	if ret != 0 {
+		return fmt.Errorf("enabling ETW provider %q failed: %w", provider.Name, syscall.Errno(ret))
+	}
+	return nil
+}
+
+// open 打开会话的实时消费者。
+func (s *ETWSession) open() error {
+	name, err := syscall.UTF16PtrFromString(s.name)
+	if err != nil {
+		return err
+	}
+	logfile := eventTraceLogfile{
+		LoggerName:          name,
+		ProcessTraceMode:    processTraceModeRealTime | processTraceModeEventRecord,
+		EventRecordCallback: etwRecordCallback,
+		Context:             s.id,
+	}
+	ret, _, callErr := advapiOpenTraceW.Call(uintptr(unsafe.Pointer(&logfile))) //nolint:gosec // G103: Valid use of unsafe call to pass EVENT_TRACE_LOGFILEW
+	if uint64(ret) == invalidProcessTraceHandle {
+		return fmt.Errorf("opening ETW session %q failed: %w", s.name, callErr)
+	}
+	s.traceHandle = uint64(ret)
+	return nil
+}
+
+// control 对会话执行 ControlTrace 操作，handle 为 0 时按名称查找会话。
+func (s *ETWSession) control(handle uint64, code uint32) uintptr {
+	name, err := syscall.UTF16PtrFromString(s.name)
+	if err != nil {
+		return uintptr(syscall.EINVAL)
+	}
+	properties := s.newProperties()
+	ret, _, _ := advapiControlTraceW.Call(
+		uintptr(handle),
+		uintptr(unsafe.Pointer(name)),       //nolint:gosec // G103: Valid use of unsafe call to pass the session name
+		uintptr(unsafe.Pointer(properties)), //nolint:gosec // G103: Valid use of unsafe call to pass EVENT_TRACE_PROPERTIES
+		uintptr(code))
+	return ret
+}
+
+// stop 停止会话。
+func (s *ETWSession) stop() error {
+	if ret := s.control(s.handle, eventTraceControlStop); ret != 0 {
+		return fmt.Errorf("stopping ETW session %q failed: %w", s.name, syscall.Errno(ret))
+	}
+	return nil
+}
+
+// Close 停止会话并等待事件处理结束。
+func (s *ETWSession) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.stop()
+		// 实时会话停止后 ProcessTrace 才会返回，此时 CloseTrace 返回 ERROR_CTX_CLOSE_PENDING
+		if ret, _, _ := advapiCloseTrace.Call(uintptr(s.traceHandle)); ret != 0 && ret != errorCtxClosePending && err == nil {
+			err = fmt.Errorf("closing ETW session %q failed: %w", s.name, syscall.Errno(ret))
+		}
+		<-s.done
+		etwSessions.Delete(s.id)
+	})
+	return err
+}
+
+// etwEventRecordCallback 是传给 OpenTrace 的 EVENT_RECORD_CALLBACK。
+func etwEventRecordCallback(record *eventRecord) uintptr {
+	if value, ok := etwSessions.Load(record.UserContext); ok {
+		value.(*ETWSession).handleEvent(record)
+	}
+	return 0
+}
+
+// handleEvent 将事件转换为指标并输出。
+func (s *ETWSession) handleEvent(record *eventRecord) {
+	header := &record.EventHeader
+	var provider *ETWProvider
+	for i := range s.providers {
+		if s.providers[i].guid == header.ProviderID {
+			provider = &s.providers[i]
+			break
+		}
+	}
+	if provider == nil {
+		return
+	}
+	if len(provider.EventIDs) > 0 && !slices.Contains(provider.EventIDs, header.EventDescriptor.ID) {
+		return
+	}
+
+	tags := map[string]string{
+		"provider": provider.Name,
+		"event_id": strconv.Itoa(int(header.EventDescriptor.ID)),
+		"opcode":   strconv.Itoa(int(header.EventDescriptor.Opcode)),
+		"level":    strconv.Itoa(int(header.EventDescriptor.Level)),
+	}
+	fields := map[string]interface{}{
+		"process_id": int64(header.ProcessID),
+		"thread_id":  int64(header.ThreadID),
+	}
+	if err := decodeEventProperties(record, fields); err != nil {
+		s.Log.Debugf("Decoding properties of event %d from provider %q failed: %v", header.EventDescriptor.ID, provider.Name, err)
+	}
+	if s.collect != nil {
+		s.collect(etwMeasurement, fields, tags, fileTimeToTime(header.TimeStamp))
+	}
+}
+
+// fileTimeToTime 将 UTC 的 FILETIME 转换为时间。
+func fileTimeToTime(value int64) time.Time {
+	return time.Unix(0, (value-epochDifferenceMicros*10)*100)
+}
@@ -0,0 +1,263 @@
+//go:build windows && (amd64 || arm64)
+
+package win_perf_counters
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// TDH_IN_TYPE values of the event properties that can be decoded
+const (
+	tdhInTypeUnicodeString = 1
+	tdhInTypeAnsiString    = 2
+	tdhInTypeInt8          = 3
+	tdhInTypeUint8         = 4
+	tdhInTypeInt16         = 5
+	tdhInTypeUint16        = 6
+	tdhInTypeInt32         = 7
+	tdhInTypeUint32        = 8
+	tdhInTypeInt64         = 9
+	tdhInTypeUint64        = 10
+	tdhInTypeFloat         = 11
+	tdhInTypeDouble        = 12
+	tdhInTypeBoolean       = 13
+	tdhInTypeGUID          = 15
+	tdhInTypePointer       = 16
+	tdhInTypeHexInt32      = 20
+	tdhInTypeHexInt64      = 21
+)
+
+const (
+	// PROPERTY_FLAGS that prevent decoding a property as a single scalar
+	propertyStruct      = 0x1
+	propertyParamLength = 0x2
+	propertyParamCount  = 0x4
+
+	eventHeaderFlag32BitHeader = 0x0020
+	errorInsufficientBuffer    = 122
+)
+
+// traceEventInfo mirrors the fixed part of TRACE_EVENT_INFO, followed by PropertyCount eventPropertyInfo entries
+type traceEventInfo struct {
+	ProviderGUID                windows.GUID
+	EventGUID                   windows.GUID
+	EventDescriptor             eventDescriptor
+	DecodingSource              uint32
+	ProviderNameOffset          uint32
+	LevelNameOffset             uint32
+	ChannelNameOffset           uint32
+	KeywordsNameOffset          uint32
+	TaskNameOffset              uint32
+	OpcodeNameOffset            uint32
+	EventMessageOffset          uint32
+	ProviderMessageOffset       uint32
+	BinaryXMLOffset             uint32
+	BinaryXMLSize               uint32
+	EventNameOffset             uint32
+	RelatedActivityIDNameOffset uint32
+	PropertyCount               uint32
+	TopLevelPropertyCount       uint32
+	Flags                       uint32
+}
+
+// eventPropertyInfo mirrors EVENT_PROPERTY_INFO for non-struct properties
+type eventPropertyInfo struct {
+	Flags         uint32
+	NameOffset    uint32
+	InType        uint16
+	OutType       uint16
+	MapNameOffset uint32
+	Count         uint16
+	Length        uint16
+	Reserved      uint32
+}
+
+var (
+	// Library
+	libTdhDll *syscall.DLL
+
+	// Functions
+	tdhGetEventInformation *syscall.Proc
+)
+
+func init() {
+	var err error
+	if libTdhDll, err = syscall.LoadDLL("tdh.dll"); err != nil {
+		return
+	}
+	tdhGetEventInformation, _ = libTdhDll.FindProc("TdhGetEventInformation")
+}
+
+// errUnsupportedProperty 属性类型无法按标量解码，之后的属性偏移未知，停止解码。
+var errUnsupportedProperty = errors.New("unsupported property type")
+
+// decodeEventProperties 通过 TDH 获取事件的属性定义，按顺序将顶层的标量属性解码到 fields 中，
+// 遇到结构体、数组或不支持的类型时停止，已解码的属性保留。
+func decodeEventProperties(record *eventRecord, fields map[string]interface{}) error {
+	if tdhGetEventInformation == nil || record.UserDataLength == 0 {
+		return nil
+	}
+
+	size := uint32(4096)
+	var buf []uint64
+	for {
+		buf = make([]uint64, (size+7)/8)
+		ret, _, _ := tdhGetEventInformation.Call(
+			uintptr(unsafe.Pointer(record)), //nolint:gosec // G103: Valid use of unsafe call to pass EVENT_RECORD
+			0,
+			0,
+			uintptr(unsafe.Pointer(&buf[0])), //nolint:gosec // G103: Valid use of unsafe call to pass TRACE_EVENT_INFO
+			uintptr(unsafe.Pointer(&size)))   //nolint:gosec // G103: Valid use of unsafe call to pass the buffer size
+		if ret == 0 {
+			break
+		}
+		if ret != errorInsufficientBuffer {
+			return syscall.Errno(ret)
+		}
+	}
+
+	base := unsafe.Pointer(&buf[0])
+	info := (*traceEventInfo)(base)
+	properties := unsafe.Slice((*eventPropertyInfo)(unsafe.Add(base, unsafe.Sizeof(traceEventInfo{}))), info.TopLevelPropertyCount) //nolint:gosec // G103: Valid use of unsafe call to access EVENT_PROPERTY_INFO array
+	data := unsafe.Slice((*byte)(record.UserData), record.UserDataLength)                                                           //nolint:gosec // G103: Valid use of unsafe call to access the event user data
+
+	pointerSize := 8
+	if record.EventHeader.Flags&eventHeaderFlag32BitHeader != 0 {
+		pointerSize = 4
+	}
+	for _, property := range properties {
+		name := utf16PtrToString((*uint16)(unsafe.Add(base, property.NameOffset))) //nolint:gosec // G103: Valid use of unsafe call to read the property name
+		if property.Flags&(propertyStruct|propertyParamLength|propertyParamCount) != 0 || property.Count > 1 {
+			return fmt.Errorf("%w: property %q", errUnsupportedProperty, name)
+		}
+		value, n, err := decodeProperty(data, property.InType, int(property.Length), pointerSize)
+		if err != nil {
+			return fmt.Errorf("property %q: %w", name, err)
+		}
+		fields[name] = value
+		data = data[n:]
+	}
+	return nil
+}
+
+// decodeProperty 解码 data 开头的一个标量属性，返回其值和占用的字节数。
+func decodeProperty(data []byte, inType uint16, length, pointerSize int) (interface{}, int, error) {
+	need := func(n int) error {
+		if len(data) < n {
+			return errors.New("user data too short")
+		}
+		return nil
+	}
+	switch inType {
+	case tdhInTypeUnicodeString:
+		chars := make([]uint16, 0, len(data)/2)
+		n := 0
+		for ; n+1 < len(data); n += 2 {
+			c := binary.LittleEndian.Uint16(data[n:])
+			if length == 0 && c == 0 {
+				n += 2
+				break
+			}
+			chars = append(chars, c)
+			if length > 0 && len(chars) == length {
+				n += 2
+				break
+			}
+		}
+		return string(utf16.Decode(chars)), n, nil
+	case tdhInTypeAnsiString:
+		n := 0
+		for ; n < len(data); n++ {
+			if length == 0 && data[n] == 0 {
+				return string(data[:n]), n + 1, nil
+			}
+			if length > 0 && n == length {
+				break
+			}
+		}
+		return string(data[:n]), n, nil
+	case tdhInTypeInt8:
+		if err := need(1); err != nil {
+			return nil, 0, err
+		}
+		return int64(int8(data[0])), 1, nil
+	case tdhInTypeUint8:
+		if err := need(1); err != nil {
+			return nil, 0, err
+		}
+		return uint64(data[0]), 1, nil
+	case tdhInTypeInt16:
+		if err := need(2); err != nil {
+			return nil, 0, err
+		}
+		return int64(int16(binary.LittleEndian.Uint16(data))), 2, nil
+	case tdhInTypeUint16:
+		if err := need(2); err != nil {
+			return nil, 0, err
+		}
+		return uint64(binary.LittleEndian.Uint16(data)), 2, nil
+	case tdhInTypeInt32:
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		return int64(int32(binary.LittleEndian.Uint32(data))), 4, nil
+	case tdhInTypeUint32, tdhInTypeHexInt32:
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		return uint64(binary.LittleEndian.Uint32(data)), 4, nil
+	case tdhInTypeInt64:
+		if err := need(8); err != nil {
+			return nil, 0, err
+		}
+		return int64(binary.LittleEndian.Uint64(data)), 8, nil
+	case tdhInTypeUint64, tdhInTypeHexInt64:
+		if err := need(8); err != nil {
+			return nil, 0, err
+		}
+		return binary.LittleEndian.Uint64(data), 8, nil
+	case tdhInTypeFloat:
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(data))), 4, nil
+	case tdhInTypeDouble:
+		if err := need(8); err != nil {
+			return nil, 0, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(data)), 8, nil
+	case tdhInTypeBoolean:
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		return binary.LittleEndian.Uint32(data) != 0, 4, nil
+	case tdhInTypeGUID:
+		if err := need(16); err != nil {
+			return nil, 0, err
+		}
+		guid := windows.GUID{
+			Data1: binary.LittleEndian.Uint32(data),
+			Data2: binary.LittleEndian.Uint16(data[4:]),
+			Data3: binary.LittleEndian.Uint16(data[6:]),
+		}
+		copy(guid.Data4[:], data[8:16])
+		return guid.String(), 16, nil
+	case tdhInTypePointer:
+		if err := need(pointerSize); err != nil {
+			return nil, 0, err
+		}
+		if pointerSize == 4 {
+			return uint64(binary.LittleEndian.Uint32(data)), 4, nil
+		}
+		return binary.LittleEndian.Uint64(data), 8, nil
+	}
+	return nil, 0, fmt.Errorf("%w %d", errUnsupportedProperty, inType)
+}
@@ -0,0 +1,190 @@
+//go:build windows && (amd64 || arm64)
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeProperty(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		inType      uint16
+		length      int
+		pointerSize int
+		want        interface{}
+		wantSize    int
+		wantErr     string
+	}{
+		{
+			name:     "null-terminated unicode string",
+			data:     []byte{'h', 0, 'i', 0, 0, 0, 'x', 0},
+			inType:   tdhInTypeUnicodeString,
+			want:     "hi",
+			wantSize: 6,
+		},
+		{
+			name:     "counted unicode string",
+			data:     []byte{'a', 0, 'b', 0, 'c', 0},
+			inType:   tdhInTypeUnicodeString,
+			length:   2,
+			want:     "ab",
+			wantSize: 4,
+		},
+		{
+			name:     "unterminated unicode string",
+			data:     []byte{'a', 0, 'b', 0},
+			inType:   tdhInTypeUnicodeString,
+			want:     "ab",
+			wantSize: 4,
+		},
+		{
+			name:     "null-terminated ansi string",
+			data:     []byte("dns\x00rest"),
+			inType:   tdhInTypeAnsiString,
+			want:     "dns",
+			wantSize: 4,
+		},
+		{
+			name:     "counted ansi string",
+			data:     []byte("abcdef"),
+			inType:   tdhInTypeAnsiString,
+			length:   3,
+			want:     "abc",
+			wantSize: 3,
+		},
+		{
+			name:     "int8",
+			data:     []byte{0xff},
+			inType:   tdhInTypeInt8,
+			want:     int64(-1),
+			wantSize: 1,
+		},
+		{
+			name:     "uint16",
+			data:     []byte{0x34, 0x12},
+			inType:   tdhInTypeUint16,
+			want:     uint64(0x1234),
+			wantSize: 2,
+		},
+		{
+			name:     "int32",
+			data:     []byte{0xfe, 0xff, 0xff, 0xff},
+			inType:   tdhInTypeInt32,
+			want:     int64(-2),
+			wantSize: 4,
+		},
+		{
+			name:     "hex int32",
+			data:     []byte{0x01, 0x00, 0x00, 0x80},
+			inType:   tdhInTypeHexInt32,
+			want:     uint64(0x80000001),
+			wantSize: 4,
+		},
+		{
+			name:     "uint64",
+			data:     []byte{1, 0, 0, 0, 0, 0, 0, 0},
+			inType:   tdhInTypeUint64,
+			want:     uint64(1),
+			wantSize: 8,
+		},
+		{
+			name:     "float",
+			data:     []byte{0x00, 0x00, 0xc0, 0x3f},
+			inType:   tdhInTypeFloat,
+			want:     1.5,
+			wantSize: 4,
+		},
+		{
+			name:     "double",
+			data:     []byte{0, 0, 0, 0, 0, 0, 0x04, 0x40},
+			inType:   tdhInTypeDouble,
+			want:     2.5,
+			wantSize: 8,
+		},
+		{
+			name:     "boolean",
+			data:     []byte{2, 0, 0, 0},
+			inType:   tdhInTypeBoolean,
+			want:     true,
+			wantSize: 4,
+		},
+		{
+			name:     "guid",
+			data:     []byte{0x49, 0x2a, 0xd4, 0x7d, 0x29, 0x53, 0x32, 0x48, 0x8d, 0xfd, 0x43, 0xd9, 0x79, 0x15, 0x3a, 0x88},
+			inType:   tdhInTypeGUID,
+			want:     "{7DD42A49-5329-4832-8DFD-43D979153A88}",
+			wantSize: 16,
+		},
+		{
+			name:        "32-bit pointer",
+			data:        []byte{4, 3, 2, 1, 9, 9, 9, 9},
+			inType:      tdhInTypePointer,
+			pointerSize: 4,
+			want:        uint64(0x01020304),
+			wantSize:    4,
+		},
+		{
+			name:        "64-bit pointer",
+			data:        []byte{4, 3, 2, 1, 0, 0, 0, 0},
+			inType:      tdhInTypePointer,
+			pointerSize: 8,
+			want:        uint64(0x01020304),
+			wantSize:    8,
+		},
+		{
+			name:    "short data",
+			data:    []byte{1, 2},
+			inType:  tdhInTypeUint32,
+			wantErr: "user data too short",
+		},
+		{
+			name:    "unsupported type",
+			data:    []byte{1, 2, 3, 4},
+			inType:  14,
+			wantErr: "unsupported property type 14",
+		},
+	}
+	for _, tt := range tests {
+		value, size, err := decodeProperty(tt.data, tt.inType, tt.length, tt.pointerSize)
+		if tt.wantErr != "" {
+			require.ErrorContains(t, err, tt.wantErr, tt.name)
+			continue
+		}
+		require.NoError(t, err, tt.name)
+		require.Equal(t, tt.want, value, tt.name)
+		require.Equal(t, tt.wantSize, size, tt.name)
+	}
+}
+
+func TestETWProviderResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"Microsoft-Windows-DNS-Client", "{1C95126E-7EEA-49A9-A3FE-A378B03DDB4D}", false},
+		{"microsoft-windows-kernel-network", "{7DD42A49-5329-4832-8DFD-43D979153A88}", false},
+		{"{22FB2CD6-0E7B-422B-A0C7-2FAD1FD0E716}", "{22FB2CD6-0E7B-422B-A0C7-2FAD1FD0E716}", false},
+		{"22fb2cd6-0e7b-422b-a0c7-2fad1fd0e716", "{22FB2CD6-0E7B-422B-A0C7-2FAD1FD0E716}", false},
+		{"Microsoft-Windows-Unknown", "", true},
+	}
+	for _, tt := range tests {
+		provider := ETWProvider{Name: tt.name}
+		err := provider.resolve()
+		if tt.wantErr {
+			require.ErrorContains(t, err, "unknown ETW provider", tt.name)
+			continue
+		}
+		require.NoError(t, err, tt.name)
+		require.Equal(t, tt.want, provider.guid.String(), tt.name)
+	}
+}
+
+func TestFileTimeToTime(t *testing.T) {
+	require.Equal(t, int64(0), fileTimeToTime(epochDifferenceMicros*10).UnixNano())
+	require.Equal(t, int64(1_700_000_000_000_000_000), fileTimeToTime(epochDifferenceMicros*10+17_000_000_000_000_000).UnixNano())
+}
@@ -0,0 +1,58 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// 事件日志中各级别日志的事件 ID。
+const (
+	EventIDError   = 1
+	EventIDWarning = 2
+	EventIDInfo    = 3
+	EventIDDebug   = 4
+)
+
+// EventLogger 将日志写入 Windows 事件日志的 LogHandler，用于以服务方式运行、没有控制台输出的部署。
+// 事件源需要预先通过 eventlog.InstallAsEventCreate 等方式注册。
+type EventLogger struct {
+	// Debug 是否以信息级别写入调试日志，默认丢弃调试日志，避免事件日志被大量写入。
+	Debug bool
+
+	log *eventlog.Log
+}
+
+// NewEventLogger 打开本机事件源 source，返回写入该事件源的 EventLogger，不再使用时需要调用 Close。
+func NewEventLogger(source string) (*EventLogger, error) {
+	log, err := eventlog.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("opening event log source %q failed: %w", source, err)
+	}
+	return &EventLogger{log: log}, nil
+}
+
+// Close 关闭事件源。
+func (l *EventLogger) Close() error {
+	return l.log.Close()
+}
+
+func (l *EventLogger) Errorf(format string, args ...interface{}) {
+	_ = l.log.Error(EventIDError, fmt.Sprintf(format, args...))
+}
+
+func (l *EventLogger) Warnf(format string, args ...interface{}) {
+	_ = l.log.Warning(EventIDWarning, fmt.Sprintf(format, args...))
+}
+
+func (l *EventLogger) Infof(format string, args ...interface{}) {
+	_ = l.log.Info(EventIDInfo, fmt.Sprintf(format, args...))
+}
+
+func (l *EventLogger) Debugf(format string, args ...interface{}) {
+	if l.Debug {
+		_ = l.log.Info(EventIDDebug, fmt.Sprintf(format, args...))
+	}
+}
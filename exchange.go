@@ -0,0 +1,31 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"strings"
+)
+
+// exchangeStorePrefix Information Store 进程中数据库实例名称的前缀。
+const exchangeStorePrefix = "Information Store - "
+
+// parseExchangeDatabase 将 "MSExchange Database ==> Instances" 对象 "数据库/实例" 形式的实例名称
+// 解析为数据库与存储实例名称，分隔符可以是 "/" 或 "\"，数据库名称去掉 "Information Store - " 前缀，
+// 例如 "Information Store - DB01/_Total" 解析为 "DB01" 与 "_Total"。没有分隔符时 ok 为 false。
+func parseExchangeDatabase(instance string) (database, store string, ok bool) {
+	i := strings.IndexAny(instance, `/\`)
+	if i < 0 {
+		return "", "", false
+	}
+	database, store = instance[:i], instance[i+1:]
+	database = strings.TrimPrefix(database, exchangeStorePrefix)
+	return database, store, database != "" && store != ""
+}
+
+// applyExchangeDatabaseTags 为 Exchange 数据库实例添加 database 与 store 标签。
+func applyExchangeDatabaseTags(_ *ObjectConfig, instance string, tags map[string]string) {
+	if database, store, ok := parseExchangeDatabase(instance); ok {
+		tags["database"] = database
+		tags["store"] = store
+	}
+}
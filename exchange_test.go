@@ -0,0 +1,46 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExchangeDatabase(t *testing.T) {
+	tests := []struct {
+		instance string
+		database string
+		store    string
+		ok       bool
+	}{
+		{"Information Store - DB01/_Total", "DB01", "_Total", true},
+		{"Information Store - DB01/1", "DB01", "1", true},
+		{`Information Store - Mailbox Database 0123456789\2`, "Mailbox Database 0123456789", "2", true},
+		{"DB02/edgetransport", "DB02", "edgetransport", true},
+		// 只按第一个分隔符拆分
+		{"DB03/a/b", "DB03", "a/b", true},
+		{"_Total", "", "", false},
+		{"Information Store - /1", "", "1", false},
+		{"DB04/", "DB04", "", false},
+	}
+	for _, tt := range tests {
+		database, store, ok := parseExchangeDatabase(tt.instance)
+		require.Equal(t, tt.ok, ok, tt.instance)
+		if tt.ok {
+			require.Equal(t, tt.database, database, tt.instance)
+			require.Equal(t, tt.store, store, tt.instance)
+		}
+	}
+}
+
+func TestApplyExchangeDatabaseTags(t *testing.T) {
+	tags := map[string]string{}
+	applyExchangeDatabaseTags(nil, "Information Store - DB01/_Total", tags)
+	require.Equal(t, map[string]string{"database": "DB01", "store": "_Total"}, tags)
+
+	tags = map[string]string{}
+	applyExchangeDatabaseTags(nil, "_Total", tags)
+	require.Empty(t, tags)
+}
@@ -0,0 +1,77 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExpandWildcards 的可选值。
+const (
+	expandAll       = "all"
+	expandInstances = "instances"
+	expandCounters  = "counters"
+)
+
+// validateExpandWildcards 校验所有对象的 ExpandWildcards 配置。
+func (m *WinPerfCounters) validateExpandWildcards(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].validateExpandWildcards(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateExpandWildcards 校验对象的 ExpandWildcards 配置，不展开的一侧不能包含通配符，
+// 否则展开结果中仍带有通配符的路径无法按单个值读取。
+func (o *ObjectConfig) validateExpandWildcards() error {
+	switch strings.ToLower(o.ExpandWildcards) {
+	case "", expandAll:
+	case expandInstances:
+		for _, counter := range o.Counters {
+			if strings.ContainsAny(counter, "*?") {
+				return fmt.Errorf("counter %q of object %q contains wildcards but ExpandWildcards is %q", counter, o.ObjectName, o.ExpandWildcards)
+			}
+		}
+	case expandCounters:
+		if len(o.Instances) == 0 && len(o.Services) > 0 {
+			return fmt.Errorf("services of object %q query all instances but ExpandWildcards is %q", o.ObjectName, o.ExpandWildcards)
+		}
+		for _, instance := range o.Instances {
+			if strings.ContainsAny(instance, "*?") || strings.HasPrefix(instance, regexInstancePrefix) {
+				return fmt.Errorf("instance %q of object %q contains wildcards but ExpandWildcards is %q", instance, o.ObjectName, o.ExpandWildcards)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown ExpandWildcards %q of object %q, expected %q, %q or %q",
+			o.ExpandWildcards, o.ObjectName, expandAll, expandInstances, expandCounters)
+	}
+	return nil
+}
+
+// expandFlags 返回展开对象的通配符路径时传给 PdhExpandWildCardPath 的标志，只展开一侧时 PDH 不必枚举另一侧，
+// 可以缩小大对象（例如上千个实例的 Process）的展开结果并缩短刷新计数器的时间。
+func (o *ObjectConfig) expandFlags() uint32 {
+	if o == nil {
+		return 0
+	}
+	switch strings.ToLower(o.ExpandWildcards) {
+	case expandInstances:
+		return pdhNoExpandCounters
+	case expandCounters:
+		return pdhNoExpandInstances
+	default:
+		return 0
+	}
+}
+
+// expandWildCardPath 按 flags 展开通配符路径。查询未实现 WildCardFlagsExpander 时忽略 flags 完整展开，
+// validateExpandWildcards 保证不展开的一侧不含通配符，因此两者的展开结果相同，只是 PDH 需要枚举的更多。
+func expandWildCardPath(query PerformanceQuery, counterPath string, flags uint32) ([]string, error) {
+	if expander, ok := query.(WildCardFlagsExpander); ok && flags != 0 {
+		return expander.ExpandWildCardPathWithFlags(counterPath, flags)
+	}
+	return query.ExpandWildCardPath(counterPath)
+}
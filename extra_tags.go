@@ -0,0 +1,50 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+)
+
+// validateExtraTags 校验 ExtraTags、SourceTags 以及各对象的 ExtraTags 中不存在空的标签名。
+func (m *WinPerfCounters) validateExtraTags(objects []ObjectConfig) error {
+	if _, ok := m.ExtraTags[""]; ok {
+		return errors.New("extra tags contain an empty tag key")
+	}
+	for source, tags := range m.SourceTags {
+		if _, ok := tags[""]; ok {
+			return fmt.Errorf("tags of source %q contain an empty tag key", source)
+		}
+	}
+	for i := range objects {
+		if err := objects[i].validateExtraTags(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateExtraTags 校验对象的 ExtraTags 中不存在空的标签名。
+func (o *ObjectConfig) validateExtraTags() error {
+	if _, ok := o.ExtraTags[""]; ok {
+		return fmt.Errorf("extra tags of object %q contain an empty tag key", o.ObjectName)
+	}
+	return nil
+}
+
+// applyExtraTags 添加静态标签，优先级从高到低依次为对象的 ExtraTags、主机的 SourceTags 和全局的 ExtraTags，
+// 已经存在的标签（source、objectname、instance 等）不会被覆盖。
+func (m *WinPerfCounters) applyExtraTags(hostInfo *hostCountersInfo, object *ObjectConfig, tags map[string]string) {
+	var objectTags map[string]string
+	if object != nil {
+		objectTags = object.ExtraTags
+	}
+	for _, extra := range []map[string]string{objectTags, m.SourceTags[hostInfo.computer], m.ExtraTags} {
+		for key, value := range extra {
+			if _, ok := tags[key]; !ok {
+				tags[key] = value
+			}
+		}
+	}
+}
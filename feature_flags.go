@@ -0,0 +1,69 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// featureFlag 描述一个可以通过环境变量在 Init 时覆盖的配置项。
+type featureFlag struct {
+	// env 环境变量名称。
+	env string
+	// apply 将环境变量的值应用到配置上。
+	apply func(m *WinPerfCounters, value string) error
+}
+
+// featureFlags 支持通过环境变量覆盖的实验性配置，便于在不重新下发配置的情况下在机群中对比性能。
+var featureFlags = []featureFlag{
+	{
+		env: "WIN_PERF_COUNTERS_TWO_PHASE_REFRESH",
+		apply: func(m *WinPerfCounters, value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			m.TwoPhaseRefresh = enabled
+			return nil
+		},
+	},
+	{
+		env: "WIN_PERF_COUNTERS_MAX_BUFFER_SIZE",
+		apply: func(m *WinPerfCounters, value string) error {
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return err
+			}
+			m.MaxBufferSize = Size(size)
+			return nil
+		},
+	},
+	{
+		env: "WIN_PERF_COUNTERS_SELF_METRICS",
+		apply: func(m *WinPerfCounters, value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			m.SelfMetrics = enabled
+			return nil
+		},
+	},
+}
+
+// applyFeatureFlags 按环境变量覆盖对应的配置项，未设置的环境变量保持配置文件中的值。
+func (m *WinPerfCounters) applyFeatureFlags() error {
+	for _, flag := range featureFlags {
+		value, ok := os.LookupEnv(flag.env)
+		if !ok || value == "" {
+			continue
+		}
+		if err := flag.apply(m, value); err != nil {
+			return fmt.Errorf("invalid value %q for environment variable %s: %w", value, flag.env, err)
+		}
+		m.Log.Infof("Configuration overridden by environment variable %s=%s", flag.env, value)
+	}
+	return nil
+}
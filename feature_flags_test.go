@@ -0,0 +1,81 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyFeatureFlags(t *testing.T) {
+	tests := []struct {
+		name              string
+		env               map[string]string
+		wantTwoPhase      bool
+		wantMaxBufferSize Size
+		wantSelfMetrics   bool
+		wantErr           string
+	}{
+		{
+			name:              "nothing set",
+			wantMaxBufferSize: 4096,
+		},
+		{
+			name: "all overridden",
+			env: map[string]string{
+				"WIN_PERF_COUNTERS_TWO_PHASE_REFRESH": "true",
+				"WIN_PERF_COUNTERS_MAX_BUFFER_SIZE":   "1048576",
+				"WIN_PERF_COUNTERS_SELF_METRICS":      "1",
+			},
+			wantTwoPhase:      true,
+			wantMaxBufferSize: 1048576,
+			wantSelfMetrics:   true,
+		},
+		{
+			name:              "disabled explicitly",
+			env:               map[string]string{"WIN_PERF_COUNTERS_SELF_METRICS": "false"},
+			wantMaxBufferSize: 4096,
+		},
+		{
+			name:              "empty values are ignored",
+			env:               map[string]string{"WIN_PERF_COUNTERS_MAX_BUFFER_SIZE": ""},
+			wantMaxBufferSize: 4096,
+		},
+		{
+			name:    "invalid boolean",
+			env:     map[string]string{"WIN_PERF_COUNTERS_TWO_PHASE_REFRESH": "maybe"},
+			wantErr: `invalid value "maybe" for environment variable WIN_PERF_COUNTERS_TWO_PHASE_REFRESH`,
+		},
+		{
+			name:    "invalid size",
+			env:     map[string]string{"WIN_PERF_COUNTERS_MAX_BUFFER_SIZE": "1MB"},
+			wantErr: `invalid value "1MB" for environment variable WIN_PERF_COUNTERS_MAX_BUFFER_SIZE`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, flag := range featureFlags {
+				t.Setenv(flag.env, tt.env[flag.env])
+			}
+			var buf bytes.Buffer
+			m := &WinPerfCounters{MaxBufferSize: 4096, Log: Logger{Output: log.New(&buf, "", 0)}}
+			err := m.applyFeatureFlags()
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantTwoPhase, m.TwoPhaseRefresh)
+			require.Equal(t, tt.wantMaxBufferSize, m.MaxBufferSize)
+			require.Equal(t, tt.wantSelfMetrics, m.SelfMetrics)
+			for env := range tt.env {
+				if tt.env[env] != "" {
+					require.Contains(t, buf.String(), env)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"maps"
+	"time"
+)
+
+// fieldTimes 记录启用 PerFieldTimestamps 的实例组中各字段的提供程序时间戳。
+type fieldTimes map[instanceGrouping]map[string]time.Time
+
+// record 在计数器所属对象启用了 PerFieldTimestamps 且采集的是原始值时记录字段的时间戳。
+func (t fieldTimes) record(metric *counter, grouping instanceGrouping, timestamp time.Time) {
+	if timestamp.IsZero() || !metric.useRawValue || metric.object == nil || !metric.object.PerFieldTimestamps {
+		return
+	}
+	if t[grouping] == nil {
+		t[grouping] = make(map[string]time.Time)
+	}
+	t[grouping][metric.counter] = timestamp
+}
+
+// emitPerField 将带有提供程序时间戳的字段分别作为单独的数据点输出，
+// 其余字段（例如派生字段）仍以主机时间戳合并为一个数据点输出。
+func (m *WinPerfCounters) emitPerField(measurement string, fields map[string]interface{}, tags map[string]string, times map[string]time.Time, timestamp time.Time) {
+	rest := make(map[string]interface{}, len(fields))
+	for field, value := range fields {
+		fieldTime, ok := times[field]
+		if !ok {
+			rest[field] = value
+			continue
+		}
+		m.emit(measurement, map[string]interface{}{field: value}, maps.Clone(tags), fieldTime)
+	}
+	if len(rest) > 0 {
+		m.emit(measurement, rest, tags, timestamp)
+	}
+}
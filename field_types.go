@@ -0,0 +1,152 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// validFieldTypes FieldTypes 支持的类型。
+var validFieldTypes = map[string]bool{"int": true, "uint": true, "float": true, "bool": true}
+
+// validateFieldTypes 校验所有对象的 FieldTypes 配置。
+func (m *WinPerfCounters) validateFieldTypes(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].validateFieldTypes(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateFieldTypes 校验对象的 FieldTypes 与 Thresholds 配置。
+func (o *ObjectConfig) validateFieldTypes() error {
+	for field, typ := range o.FieldTypes {
+		if !validFieldTypes[typ] {
+			return fmt.Errorf("invalid type %q for field %q of object %q, expected one of int, uint, float or bool", typ, field, o.ObjectName)
+		}
+	}
+	for field, expression := range o.Thresholds {
+		if _, err := parseThreshold(expression); err != nil {
+			return fmt.Errorf("invalid threshold for field %q of object %q: %w", field, o.ObjectName, err)
+		}
+	}
+	return nil
+}
+
+// thresholdOperators Thresholds 支持的比较运算符，两个字符的运算符在前，避免 ">=" 被识别为 ">"。
+var thresholdOperators = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// threshold 解析后的阈值表达式 "<计数器> <运算符> <数值>"。
+type threshold struct {
+	counter  string
+	operator string
+	value    float64
+}
+
+// parseThreshold 解析阈值表达式，例如 "Pages/sec > 1000"。
+func parseThreshold(expression string) (threshold, error) {
+	for _, operator := range thresholdOperators {
+		counter, value, found := strings.Cut(expression, " "+operator+" ")
+		if !found {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return threshold{}, fmt.Errorf("invalid value %q in %q", strings.TrimSpace(value), expression)
+		}
+		return threshold{counter: strings.TrimSpace(counter), operator: operator, value: v}, nil
+	}
+	return threshold{}, fmt.Errorf("expected \"<counter> <operator> <value>\" with one of >, >=, <, <=, == or != in %q", expression)
+}
+
+// matches 判断 value 是否满足阈值条件。
+func (t threshold) matches(value float64) bool {
+	switch t.operator {
+	case ">":
+		return value > t.value
+	case ">=":
+		return value >= t.value
+	case "<":
+		return value < t.value
+	case "<=":
+		return value <= t.value
+	case "==":
+		return value == t.value
+	}
+	return value != t.value
+}
+
+// applyBooleans 按对象的 Thresholds 追加布尔字段，并按 EmitAsBool 将状态类计数器转换为布尔值（非 0 为 true）。
+// 阈值使用转换前的数值，引用的计数器本次没有数据时不输出对应的字段。
+func applyBooleans(object *ObjectConfig, fields map[string]interface{}) {
+	if object == nil {
+		return
+	}
+	for field, expression := range object.Thresholds {
+		t, err := parseThreshold(expression)
+		if err != nil {
+			continue
+		}
+		if v, ok := toFloat(fields[object.fieldName(t.counter)]); ok {
+			fields[field] = t.matches(v)
+		}
+	}
+	for _, counter := range object.EmitAsBool {
+		field := object.fieldName(counter)
+		if value, ok := fields[field]; ok {
+			if converted, ok := convertFieldType(value, "bool"); ok {
+				fields[field] = converted
+			}
+		}
+	}
+}
+
+// applyFieldTypes 按对象的 FieldTypes 配置转换字段值的类型，使其符合下游的表结构，并避免整数计数器出现浮点误差。
+func applyFieldTypes(object *ObjectConfig, fields map[string]interface{}) {
+	applyBooleans(object, fields)
+	if object == nil || len(object.FieldTypes) == 0 {
+		return
+	}
+	for field, typ := range object.FieldTypes {
+		value, ok := fields[field]
+		if !ok {
+			continue
+		}
+		if converted, ok := convertFieldType(value, typ); ok {
+			fields[field] = converted
+		}
+	}
+}
+
+// convertFieldType 将数值或布尔值转换为 typ 指定的类型，整数类型按四舍五入取整，uint 中的负数按 0 处理。
+func convertFieldType(value interface{}, typ string) (interface{}, bool) {
+	f, ok := toFloat(value)
+	if !ok {
+		b, isBool := value.(bool)
+		if !isBool {
+			return nil, false
+		}
+		if b {
+			f = 1
+		}
+	}
+
+	switch typ {
+	case "int":
+		return int64(math.Round(f)), true
+	case "uint":
+		if f <= 0 || math.IsNaN(f) {
+			return uint64(0), true
+		}
+		return uint64(math.Round(f)), true
+	case "float":
+		return f, true
+	case "bool":
+		return f != 0, true
+	}
+	return nil, false
+}
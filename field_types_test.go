@@ -0,0 +1,97 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       threshold
+		wantErr    string
+	}{
+		{"Pages/sec > 1000", threshold{counter: "Pages/sec", operator: ">", value: 1000}, ""},
+		{"% Free Space >= 10.5", threshold{counter: "% Free Space", operator: ">=", value: 10.5}, ""},
+		{"  Current Disk Queue Length <= 2  ", threshold{counter: "Current Disk Queue Length", operator: "<=", value: 2}, ""},
+		{"State == 1", threshold{counter: "State", operator: "==", value: 1}, ""},
+		{"State != 0", threshold{counter: "State", operator: "!=", value: 0}, ""},
+		{"Available MBytes < -1e3", threshold{counter: "Available MBytes", operator: "<", value: -1000}, ""},
+		{"Pages/sec > high", threshold{}, `invalid value "high" in "Pages/sec > high"`},
+		{"Pages/sec>1000", threshold{}, `expected "<counter> <operator> <value>"`},
+		{"Pages/sec", threshold{}, `expected "<counter> <operator> <value>"`},
+		{"", threshold{}, `expected "<counter> <operator> <value>"`},
+	}
+	for _, tt := range tests {
+		got, err := parseThreshold(tt.expression)
+		if tt.wantErr != "" {
+			require.ErrorContains(t, err, tt.wantErr, tt.expression)
+			continue
+		}
+		require.NoError(t, err, tt.expression)
+		require.Equal(t, tt.want, got, tt.expression)
+	}
+}
+
+func TestThresholdMatches(t *testing.T) {
+	tests := []struct {
+		operator string
+		below    bool
+		equal    bool
+		above    bool
+	}{
+		{">", false, false, true},
+		{">=", false, true, true},
+		{"<", true, false, false},
+		{"<=", true, true, false},
+		{"==", false, true, false},
+		{"!=", true, false, true},
+	}
+	for _, tt := range tests {
+		threshold := threshold{operator: tt.operator, value: 10}
+		require.Equal(t, tt.below, threshold.matches(9), "9 %s 10", tt.operator)
+		require.Equal(t, tt.equal, threshold.matches(10), "10 %s 10", tt.operator)
+		require.Equal(t, tt.above, threshold.matches(11), "11 %s 10", tt.operator)
+	}
+}
+
+func TestApplyBooleans(t *testing.T) {
+	object := &ObjectConfig{
+		ObjectName: "Memory",
+		EmitAsBool: []string{"State"},
+		Thresholds: map[string]string{
+			"paging_high": "Pages/sec > 1000",
+			"state_up":    "State == 1",
+			"missing":     "Cache Bytes > 0",
+		},
+	}
+	fields := map[string]interface{}{"Pages_persec": 1500.0, "State": 1.0}
+	applyBooleans(object, fields)
+	// 阈值使用转换为布尔值之前的数值，引用的计数器没有数据时不输出字段
+	require.Equal(t, map[string]interface{}{
+		"Pages_persec": 1500.0,
+		"State":        true,
+		"paging_high":  true,
+		"state_up":     true,
+	}, fields)
+
+	fields = map[string]interface{}{"Pages_persec": int64(10), "State": 0.0}
+	applyBooleans(object, fields)
+	require.Equal(t, false, fields["paging_high"])
+	require.Equal(t, false, fields["State"])
+
+	fields = map[string]interface{}{"State": 1.0}
+	applyBooleans(nil, fields)
+	require.Equal(t, map[string]interface{}{"State": 1.0}, fields)
+}
+
+func TestValidateFieldTypesThresholds(t *testing.T) {
+	object := &ObjectConfig{ObjectName: "Memory", Thresholds: map[string]string{"paging_high": "Pages/sec > 1000"}}
+	require.NoError(t, object.validateFieldTypes())
+
+	object.Thresholds["paging_high"] = "Pages/sec over 1000"
+	require.ErrorContains(t, object.validateFieldTypes(), `invalid threshold for field "paging_high" of object "Memory"`)
+}
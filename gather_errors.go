@@ -0,0 +1,117 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// lateErrors 记录超时被放弃等待的主机在之后完成时返回的错误，在下一次采集返回。
+type lateErrors struct {
+	lock sync.Mutex
+	errs []error
+}
+
+// checkErrors 对合并的错误逐个应用 checkError，只去掉被 IgnoredErrors 忽略的错误，其余错误合并后返回。
+func (m *WinPerfCounters) checkErrors(err error) error {
+	if err == nil {
+		return nil
+	}
+	var kept []error
+	for _, e := range splitErrors(err) {
+		if e = m.checkError(e); e != nil {
+			kept = append(kept, e)
+		}
+	}
+	return errors.Join(kept...)
+}
+
+// abandonedCollections 主机被放弃等待的采集，由每个主机最多一个的 goroutine 依次等待，
+// 主机持续无响应时不会为每次超时的采集各启动一个 goroutine。
+type abandonedCollections struct {
+	lock     sync.Mutex
+	pending  []<-chan error
+	watching bool
+}
+
+// awaitAbandoned 在放弃等待主机后继续等待其采集结束，结束时的错误（上下文取消除外）记入自身状态指标 late_errors，
+// 并在下一次采集时返回，不会因为采集超时而被丢弃。主机已有等待中的 goroutine 时复用该 goroutine。
+func (m *WinPerfCounters) awaitAbandoned(hostInfo *hostCountersInfo, done <-chan error) {
+	abandoned := &hostInfo.abandoned
+	abandoned.lock.Lock()
+	defer abandoned.lock.Unlock()
+	abandoned.pending = append(abandoned.pending, done)
+	if abandoned.watching {
+		return
+	}
+	abandoned.watching = true
+	go m.watchAbandoned(hostInfo)
+}
+
+// watchAbandoned 依次等待主机被放弃等待的采集，没有剩余的采集时返回。
+func (m *WinPerfCounters) watchAbandoned(hostInfo *hostCountersInfo) {
+	abandoned := &hostInfo.abandoned
+	for {
+		abandoned.lock.Lock()
+		if len(abandoned.pending) == 0 {
+			abandoned.watching = false
+			abandoned.lock.Unlock()
+			return
+		}
+		done := abandoned.pending[0]
+		abandoned.pending = abandoned.pending[1:]
+		abandoned.lock.Unlock()
+
+		m.recordLateError(hostInfo, <-done)
+	}
+}
+
+// recordLateError 记录被放弃等待的采集结束时返回的错误。
+func (m *WinPerfCounters) recordLateError(hostInfo *hostCountersInfo, err error) {
+	err = m.checkErrors(err)
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	m.Log.Warnf("Abandoned collection from %q finished with error: %v", hostInfo.computer, err)
+	m.stats.incr(map[string]string{"source": hostInfo.tag}, "late_errors", 1)
+	m.lateErrors.lock.Lock()
+	m.lateErrors.errs = append(m.lateErrors.errs, err)
+	m.lateErrors.lock.Unlock()
+}
+
+// takeLateErrors 返回并清空之前被放弃等待的主机返回的错误。
+func (m *WinPerfCounters) takeLateErrors() error {
+	m.lateErrors.lock.Lock()
+	defer m.lateErrors.lock.Unlock()
+	err := errors.Join(m.lateErrors.errs...)
+	m.lateErrors.errs = nil
+	return err
+}
+
+// recordGatherErrors 将本次采集返回的错误按主机计入自身状态指标 gather_errors，本次没有出错的主机记为 0，
+// 无法确定主机的错误计入不带 source 标签的序列。
+func (m *WinPerfCounters) recordGatherErrors(err error) {
+	counts := make(map[string]int64)
+	for _, hostInfo := range m.hostCounters {
+		counts[hostInfo.tag] = 0
+	}
+	if err != nil {
+		for _, e := range splitErrors(err) {
+			var source string
+			var counterErr *CounterError
+			if errors.As(e, &counterErr) && counterErr.Host != "" {
+				source = m.sourceTag(counterErr.Host)
+			}
+			counts[source]++
+		}
+	}
+	for source, count := range counts {
+		tags := map[string]string{}
+		if source != "" {
+			tags["source"] = source
+		}
+		m.stats.set(tags, "gather_errors", count)
+	}
+}
@@ -0,0 +1,56 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAwaitAbandoned(t *testing.T) {
+	m := NewWinPerfCounters(func(string, map[string]interface{}, map[string]string, time.Time) {})
+	hostInfo := &hostCountersInfo{computer: "hostA", tag: "hostA"}
+	watching := func() bool {
+		hostInfo.abandoned.lock.Lock()
+		defer hostInfo.abandoned.lock.Unlock()
+		return hostInfo.abandoned.watching
+	}
+
+	errA := errors.New("a failed")
+	errB := errors.New("b failed")
+	dones := make([]chan error, 3)
+	for i := range dones {
+		dones[i] = make(chan error, 1)
+		m.awaitAbandoned(hostInfo, dones[i])
+	}
+	// 同一主机只有一个等待的 goroutine，其余的采集排队等待
+	hostInfo.abandoned.lock.Lock()
+	require.True(t, hostInfo.abandoned.watching)
+	require.GreaterOrEqual(t, len(hostInfo.abandoned.pending), len(dones)-1)
+	hostInfo.abandoned.lock.Unlock()
+
+	dones[0] <- errA
+	dones[1] <- fmt.Errorf("collect: %w", context.Canceled)
+	dones[2] <- errB
+	require.Eventually(t, func() bool { return !watching() }, time.Second, time.Millisecond)
+
+	err := m.takeLateErrors()
+	require.ErrorIs(t, err, errA)
+	require.ErrorIs(t, err, errB)
+	require.NotErrorIs(t, err, context.Canceled)
+	require.Equal(t, int64(2), m.stats.value(map[string]string{"source": "hostA"}, "late_errors"))
+	require.NoError(t, m.takeLateErrors())
+
+	// 等待结束后再次放弃等待时重新启动 goroutine
+	done := make(chan error, 1)
+	m.awaitAbandoned(hostInfo, done)
+	require.True(t, watching())
+	done <- nil
+	require.Eventually(t, func() bool { return !watching() }, time.Second, time.Millisecond)
+	require.NoError(t, m.takeLateErrors())
+}
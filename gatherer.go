@@ -0,0 +1,78 @@
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Gatherer 采集后端的公共接口，WinPerfCounters 实现了该接口，其它采集后端（如 WMI、ETW、注册表）实现后
+// 可以通过 MultiGatherer 组合，共用同一套调度与输出流程。
+type Gatherer interface {
+	// Init 校验配置并初始化后端，在第一次采集之前调用。
+	Init() error
+	// GatherContext 采集一次数据，ctx 取消时不再等待。
+	GatherContext(ctx context.Context) error
+	// Close 释放后端持有的资源。
+	Close() error
+}
+
+var _ Gatherer = (*WinPerfCounters)(nil)
+
+// MultiGatherer 将多个 Gatherer 组合为一个 Gatherer：按顺序初始化，并发采集，按相反的顺序关闭，
+// 各后端的错误合并后返回，一个后端失败不影响其它后端的采集。
+type MultiGatherer struct {
+	gatherers []Gatherer
+}
+
+var _ Gatherer = (*MultiGatherer)(nil)
+
+// NewMultiGatherer 组合 gatherers，nil 会被忽略。
+func NewMultiGatherer(gatherers ...Gatherer) *MultiGatherer {
+	g := &MultiGatherer{}
+	for _, gatherer := range gatherers {
+		if gatherer != nil {
+			g.gatherers = append(g.gatherers, gatherer)
+		}
+	}
+	return g
+}
+
+// Init 按顺序初始化各后端，某个后端失败时关闭已经初始化的后端并返回错误。
+func (g *MultiGatherer) Init() error {
+	for i, gatherer := range g.gatherers {
+		if err := gatherer.Init(); err != nil {
+			errs := []error{fmt.Errorf("initializing gatherer %d failed: %w", i, err)}
+			for j := i - 1; j >= 0; j-- {
+				errs = append(errs, g.gatherers[j].Close())
+			}
+			return errors.Join(errs...)
+		}
+	}
+	return nil
+}
+
+// GatherContext 并发调用各后端的 GatherContext，等待全部结束后返回合并的错误。
+func (g *MultiGatherer) GatherContext(ctx context.Context) error {
+	errs := make([]error, len(g.gatherers))
+	var wg sync.WaitGroup
+	for i, gatherer := range g.gatherers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = gatherer.GatherContext(ctx)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Close 按与初始化相反的顺序关闭各后端，返回合并的错误。
+func (g *MultiGatherer) Close() error {
+	var errs []error
+	for i := len(g.gatherers) - 1; i >= 0; i-- {
+		errs = append(errs, g.gatherers[i].Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,103 @@
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGatherer 记录调用顺序的 Gatherer，各方法返回配置的错误。
+type fakeGatherer struct {
+	name      string
+	calls     *[]string
+	lock      *sync.Mutex
+	initErr   error
+	gatherErr error
+	closeErr  error
+}
+
+func (g *fakeGatherer) record(call string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	*g.calls = append(*g.calls, g.name+"."+call)
+}
+
+func (g *fakeGatherer) Init() error {
+	g.record("Init")
+	return g.initErr
+}
+
+func (g *fakeGatherer) GatherContext(context.Context) error {
+	g.record("Gather")
+	return g.gatherErr
+}
+
+func (g *fakeGatherer) Close() error {
+	g.record("Close")
+	return g.closeErr
+}
+
+// newFakeGatherers 创建共用调用记录的 fakeGatherer。
+func newFakeGatherers(names ...string) ([]*fakeGatherer, *[]string) {
+	calls := new([]string)
+	lock := new(sync.Mutex)
+	gatherers := make([]*fakeGatherer, 0, len(names))
+	for _, name := range names {
+		gatherers = append(gatherers, &fakeGatherer{name: name, calls: calls, lock: lock})
+	}
+	return gatherers, calls
+}
+
+func TestMultiGathererInit(t *testing.T) {
+	gatherers, calls := newFakeGatherers("a", "b", "c")
+	initErr := errors.New("init failed")
+	closeErr := errors.New("close failed")
+	gatherers[2].initErr = initErr
+	gatherers[0].closeErr = closeErr
+
+	err := NewMultiGatherer(gatherers[0], nil, gatherers[1], gatherers[2]).Init()
+	require.ErrorIs(t, err, initErr)
+	require.ErrorIs(t, err, closeErr)
+	require.ErrorContains(t, err, "initializing gatherer 2 failed")
+	// 失败后按相反的顺序关闭已经初始化的后端，失败的后端不关闭
+	require.Equal(t, []string{"a.Init", "b.Init", "c.Init", "b.Close", "a.Close"}, *calls)
+
+	gatherers, calls = newFakeGatherers("a", "b")
+	require.NoError(t, NewMultiGatherer(gatherers[0], gatherers[1]).Init())
+	require.Equal(t, []string{"a.Init", "b.Init"}, *calls)
+}
+
+func TestMultiGathererGatherContext(t *testing.T) {
+	gatherers, calls := newFakeGatherers("a", "b", "c")
+	errA := errors.New("a failed")
+	errC := errors.New("c failed")
+	gatherers[0].gatherErr = errA
+	gatherers[2].gatherErr = errC
+
+	g := NewMultiGatherer(gatherers[0], gatherers[1], gatherers[2])
+	err := g.GatherContext(context.Background())
+	require.ErrorIs(t, err, errA)
+	require.ErrorIs(t, err, errC)
+	// 一个后端失败不影响其它后端的采集
+	require.ElementsMatch(t, []string{"a.Gather", "b.Gather", "c.Gather"}, *calls)
+
+	gatherers[0].gatherErr, gatherers[2].gatherErr = nil, nil
+	require.NoError(t, g.GatherContext(context.Background()))
+	require.NoError(t, NewMultiGatherer().GatherContext(context.Background()))
+}
+
+func TestMultiGathererClose(t *testing.T) {
+	gatherers, calls := newFakeGatherers("a", "b", "c")
+	errA := errors.New("a failed")
+	errB := errors.New("b failed")
+	gatherers[0].closeErr = errA
+	gatherers[1].closeErr = errB
+
+	err := NewMultiGatherer(gatherers[0], gatherers[1], gatherers[2]).Close()
+	require.ErrorIs(t, err, errA)
+	require.ErrorIs(t, err, errB)
+	require.Equal(t, []string{"c.Close", "b.Close", "a.Close"}, *calls)
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// recordBuildInfo 将构建信息记录到自身状态指标中，不带 source 标签，便于在指标后端确认数据来自哪个构建。
+func (m *WinPerfCounters) recordBuildInfo() {
+	info := BuildInfo()
+	tags := map[string]string{}
+	m.stats.set(tags, "version", info.Version)
+	if info.Commit != "" {
+		m.stats.set(tags, "commit", info.Commit)
+	}
+}
+
+// HealthHandler 返回健康检查的 HTTP 端点，仅接受 GET，以 JSON 返回 Health，不触发采集，也不等待正在进行的采集。
+//
+// 端点本身不做认证，暴露在回环地址以外时应使用 RequireToken 包装。
+func (m *WinPerfCounters) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		health := Health{
+			Status:            "ok",
+			Build:             BuildInfo(),
+			ConfigFingerprint: m.ConfigFingerprint(),
+			Profile:           m.ActiveProfile(),
+		}
+		if report := m.RefreshDiagnostics(); !report.Time.IsZero() {
+			health.Refresh = &report
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(health)
+	})
+}
@@ -0,0 +1,88 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// contains 判断时间点是否落在范围内。
+func (r TimeRange) contains(t time.Time) bool {
+	return (r.Start.IsZero() || !t.Before(r.Start)) && (r.End.IsZero() || !t.After(r.End))
+}
+
+// historyBuffer 在内存中保存每条时间序列最近一段时间的样本。
+type historyBuffer struct {
+	lock   sync.RWMutex
+	series map[string]*Series
+}
+
+// record 追加一个样本，并丢弃该序列中超出保留时长的样本。
+func (h *historyBuffer) record(retention time.Duration, measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.series == nil {
+		h.series = make(map[string]*Series)
+	}
+	key := snapshotKey(measurement, tags)
+	series, ok := h.series[key]
+	if !ok {
+		series = &Series{Measurement: measurement, Tags: tags}
+		h.series[key] = series
+	}
+	series.Samples = append(series.Samples, Sample{Timestamp: timestamp, Fields: fields})
+
+	cutoff := timestamp.Add(-retention)
+	drop := 0
+	for drop < len(series.Samples) && series.Samples[drop].Timestamp.Before(cutoff) {
+		drop++
+	}
+	if drop > 0 {
+		series.Samples = append(series.Samples[:0], series.Samples[drop:]...)
+	}
+}
+
+// prune 删除超出保留时长未再更新的序列，例如已退出的进程。
+func (h *historyBuffer) prune(retention time.Duration, now time.Time) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	cutoff := now.Add(-retention)
+	for key, series := range h.series {
+		if len(series.Samples) == 0 || series.Samples[len(series.Samples)-1].Timestamp.Before(cutoff) {
+			delete(h.series, key)
+		}
+	}
+}
+
+// Query 返回满足 selector 的时间序列在 timeRange 内的样本，selector 为 nil 时返回全部序列。
+//
+// 需要配置 History 才会保存历史样本，可配合 MatchMeasurement、MatchObject、MatchTag 等过滤条件使用，
+// 在无法访问外部时序数据库时为健康检查端点或终端界面提供最近的历史数据。
+func (m *WinPerfCounters) Query(selector CollectPredicate, timeRange TimeRange) []Series {
+	m.history.lock.RLock()
+	defer m.history.lock.RUnlock()
+
+	result := make([]Series, 0)
+	for _, series := range m.history.series {
+		if selector != nil && !selector(series.Measurement, series.Tags) {
+			continue
+		}
+		var samples []Sample
+		for _, sample := range series.Samples {
+			if timeRange.contains(sample.Timestamp) {
+				samples = append(samples, sample)
+			}
+		}
+		if len(samples) > 0 {
+			result = append(result, Series{Measurement: series.Measurement, Tags: series.Tags, Samples: samples})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return snapshotKey(result[i].Measurement, result[i].Tags) < snapshotKey(result[j].Measurement, result[j].Tags)
+	})
+	return result
+}
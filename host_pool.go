@@ -0,0 +1,36 @@
+//go:build windows
+
+package win_perf_counters
+
+import "context"
+
+// hostPool 限制同时采集的主机数量，为 nil 时不限制。
+type hostPool chan struct{}
+
+// newHostPool 创建最多允许 size 个主机同时采集的池，size 小于等于 0 时返回 nil。
+func newHostPool(size int) hostPool {
+	if size <= 0 {
+		return nil
+	}
+	return make(hostPool, size)
+}
+
+// acquire 等待空闲的采集位置，ctx 被取消时返回其错误。
+func (p hostPool) acquire(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	select {
+	case p <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release 归还 acquire 获得的采集位置。
+func (p hostPool) release() {
+	if p != nil {
+		<-p
+	}
+}
@@ -0,0 +1,73 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// initIgnoredCounters 校验并编译 IgnoredCounters 中的通配符模式。
+//
+// 计数器路径中的反斜杠在 path.Match 中是转义字符，匹配前统一替换为分隔符 /（路径中原有的 / 先替换为其它字符，
+// 例如 "Context Switches/sec"），并且不区分大小写。
+func (m *WinPerfCounters) initIgnoredCounters() error {
+	m.ignoredCounters = nil
+	for _, pattern := range m.IgnoredCounters {
+		if pattern == "" {
+			return errors.New("IgnoredCounters contains an empty pattern")
+		}
+		compiled := ignoredCounterPattern{
+			pattern:  normalizeCounterPath(pattern),
+			computer: strings.HasPrefix(pattern, `\\`),
+		}
+		if _, err := path.Match(compiled.pattern, ""); err != nil {
+			return fmt.Errorf("invalid IgnoredCounters pattern %q: %w", pattern, err)
+		}
+		m.ignoredCounters = append(m.ignoredCounters, compiled)
+	}
+	return nil
+}
+
+// ignoredCounterPattern 编译后的 IgnoredCounters 模式。
+type ignoredCounterPattern struct {
+	// pattern 经 normalizeCounterPath 处理后的模式。
+	pattern string
+	// computer 模式是否以 \\computer 开头，否则匹配时去掉计数器路径中的主机名。
+	computer bool
+}
+
+// counterIgnored 判断计数器路径是否匹配 IgnoredCounters 中的任一模式。
+func (m *WinPerfCounters) counterIgnored(counterPath string) bool {
+	if len(m.ignoredCounters) == 0 {
+		return false
+	}
+	full := normalizeCounterPath(counterPath)
+	local := full
+	if strings.HasPrefix(local, "//") {
+		if i := strings.Index(local[2:], "/"); i >= 0 {
+			local = local[2+i:]
+		}
+	}
+	for _, p := range m.ignoredCounters {
+		text := local
+		if p.computer {
+			text = full
+		}
+		if matched, _ := path.Match(p.pattern, text); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// counterSlash 匹配时替换计数器路径中原有的 /，避免被 path.Match 当作分隔符。
+const counterSlash = "\x00"
+
+// normalizeCounterPath 将计数器路径或模式转换为 path.Match 使用的形式：小写，反斜杠替换为 /。
+func normalizeCounterPath(counterPath string) string {
+	counterPath = strings.ReplaceAll(counterPath, "/", counterSlash)
+	return strings.ToLower(strings.ReplaceAll(counterPath, `\`, "/"))
+}
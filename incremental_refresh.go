@@ -0,0 +1,161 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+)
+
+// reusingQuery 在增量刷新期间包装主机已有的查询：路径已存在的计数器直接返回原有句柄，
+// 新添加到查询中的句柄被记录下来，刷新结束后移除其中未被使用的句柄。
+type reusingQuery struct {
+	PerformanceQuery
+	// existing 刷新前的计数器路径到句柄的映射。
+	existing map[string]pdhCounterHandle
+	// added 刷新期间新添加的句柄。
+	added []pdhCounterHandle
+}
+
+func (q *reusingQuery) AddCounterToQuery(counterPath string) (pdhCounterHandle, error) {
+	if counterHandle, ok := q.existing[counterPath]; ok {
+		return counterHandle, nil
+	}
+	counterHandle, err := q.PerformanceQuery.AddCounterToQuery(counterPath)
+	if err == nil {
+		q.added = append(q.added, counterHandle)
+	}
+	return counterHandle, err
+}
+
+func (q *reusingQuery) AddEnglishCounterToQuery(counterPath string) (pdhCounterHandle, error) {
+	if counterHandle, ok := q.existing[counterPath]; ok {
+		return counterHandle, nil
+	}
+	counterHandle, err := q.PerformanceQuery.AddEnglishCounterToQuery(counterPath)
+	if err == nil {
+		q.added = append(q.added, counterHandle)
+	}
+	return counterHandle, err
+}
+
+func (q *reusingQuery) GetCounterMeta(counterHandle pdhCounterHandle) (CounterMeta, error) {
+	return counterMeta(q.PerformanceQuery, counterHandle)
+}
+
+func (q *reusingQuery) ExpandWildCardPathWithFlags(counterPath string, flags uint32) ([]string, error) {
+	return expandWildCardPath(q.PerformanceQuery, counterPath, flags)
+}
+
+// hostsBusy 判断是否有主机仍在进行被放弃等待的采集，此时不能修改其查询。
+func (m *WinPerfCounters) hostsBusy() bool {
+	for _, hostInfo := range m.hostCounters {
+		if hostInfo.busy.Load() {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshIncremental 在不关闭查询的情况下刷新计数器：重新展开配置的通配符路径，与现有计数器比较，
+// 只向查询添加新出现的计数器并移除消失的计数器。已有计数器保留句柄和采样，不需要重新等待首次采样，
+// 新出现的计数器在下一次采集后才有速率类的值。新出现的主机首次采样失败不影响刷新，其错误合并后作为 hostErrs 返回。
+//
+// 与 prepareRefresh 相同，新的计数器集合在单独的映射中构建，解析成功后才替换 m.hostCounters；
+// 失败时撤销向已有查询添加的计数器并关闭新建的查询，继续使用原有的计数器集合。
+func (m *WinPerfCounters) refreshIncremental() (hostErrs error, err error) {
+	current := m.hostCounters
+	queries := make(map[string]*reusingQuery, len(current))
+	next := make(map[string]*hostCountersInfo, len(current))
+	for key, hostInfo := range current {
+		if hostInfo.query == nil {
+			// 查询尚未打开的主机重新记录推迟添加的计数器
+			continue
+		}
+		existing := make(map[string]pdhCounterHandle, len(hostInfo.counters))
+		for _, c := range hostInfo.counters {
+			existing[c.counterPath] = c.counterHandle
+		}
+		query := &reusingQuery{PerformanceQuery: hostInfo.query, existing: existing}
+		queries[key] = query
+		next[key] = &hostCountersInfo{computer: hostInfo.computer, tag: hostInfo.tag, query: query, separateObject: hostInfo.separateObject}
+	}
+
+	m.hostCounters = next
+	err = m.parseConfig()
+	next = m.hostCounters
+	m.hostCounters = current
+
+	if err != nil {
+		for key, hostInfo := range next {
+			query, ok := queries[key]
+			if !ok {
+				if hostInfo.query != nil {
+					_ = hostInfo.query.Close()
+				}
+				continue
+			}
+			for _, counterHandle := range query.added {
+				_ = query.PerformanceQuery.RemoveCounter(counterHandle)
+			}
+		}
+		return nil, err
+	}
+
+	for key, hostInfo := range next {
+		query, ok := queries[key]
+		if !ok {
+			if hostInfo.query == nil {
+				continue
+			}
+			// 新出现的主机使用新建的查询，先完成首次采样
+			if err := hostInfo.query.CollectData(); err != nil {
+				m.countPdhError(hostInfo, err)
+				hostErrs = errors.Join(hostErrs, m.checkError(wrapCounterError("collect", hostInfo.computer, "", "", err)))
+			}
+			continue
+		}
+		hostInfo.query = query.PerformanceQuery
+
+		used := make(map[pdhCounterHandle]bool, len(hostInfo.counters))
+		for _, c := range hostInfo.counters {
+			used[c.counterHandle] = true
+		}
+		unused := make(map[pdhCounterHandle]bool)
+		added := 0
+		for _, counterHandle := range query.added {
+			if used[counterHandle] {
+				added++
+			} else {
+				unused[counterHandle] = true
+			}
+		}
+		removed := 0
+		for _, c := range current[key].counters {
+			if !used[c.counterHandle] && !unused[c.counterHandle] {
+				unused[c.counterHandle] = true
+				removed++
+			}
+		}
+		for counterHandle := range unused {
+			if err := hostInfo.query.RemoveCounter(counterHandle); err != nil {
+				m.Log.Debugf("Removing counter from query of host %q failed: %v", hostInfo.computer, err)
+			}
+		}
+
+		tags := hostInfo.statsTags()
+		m.stats.set(tags, "refresh_added_counters", int64(added))
+		m.stats.set(tags, "refresh_removed_counters", int64(removed))
+		m.Log.Debugf("Refreshed counters of host %q incrementally, %d added, %d removed", hostInfo.computer, added, removed)
+	}
+	m.hostCounters = next
+
+	// 不再采集任何计数器的主机，新的计数器集合已生效，关闭失败与首次采样失败一样只作为该主机的错误返回
+	for key, hostInfo := range current {
+		if _, ok := next[key]; !ok {
+			if err := hostInfo.closeQuery(); err != nil {
+				hostErrs = errors.Join(hostErrs, wrapCounterError("close", hostInfo.computer, "", "", err))
+			}
+		}
+	}
+	return hostErrs, nil
+}
@@ -0,0 +1,51 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshIncremental(t *testing.T) {
+	source := &fakeSource{value: 1}
+	m := newFakeSourcePlugin(source)
+	m.Sources = []string{"hostA", "hostB"}
+	m.IncrementalRefresh = true
+	require.NoError(t, m.Init())
+	t.Cleanup(func() { _ = m.Close() })
+	require.NoError(t, m.Gather())
+
+	hosts := make(map[string]*hostCountersInfo)
+	for _, hostInfo := range m.hostCounters {
+		hosts[hostInfo.computer] = hostInfo
+	}
+	require.Len(t, hosts, 2)
+
+	// 解析失败时保留原有的计数器集合
+	objects := m.Object
+	m.Object = nil
+	m.lastRefreshed = time.Time{}
+	require.Error(t, m.Gather())
+	require.Len(t, m.hostCounters, 2)
+	for _, hostInfo := range m.hostCounters {
+		require.Same(t, hosts[hostInfo.computer], hostInfo)
+		require.True(t, hostInfo.query.(*sourceQuery).open)
+		require.Len(t, hostInfo.counters, 1)
+	}
+
+	// hostB 不再采集，其查询被关闭，hostA 继续使用原有的查询
+	m.Object = objects
+	m.Sources = []string{"hostA"}
+	m.lastRefreshed = time.Time{}
+	require.NoError(t, m.Gather())
+	require.Len(t, m.hostCounters, 1)
+	for _, hostInfo := range m.hostCounters {
+		require.Equal(t, "hostA", hostInfo.computer)
+		require.Same(t, hosts["hostA"].query, hostInfo.query)
+		require.Len(t, hostInfo.counters, 1)
+	}
+	require.False(t, hosts["hostB"].query.(*sourceQuery).open)
+}
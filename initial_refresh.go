@@ -0,0 +1,110 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// initialRefresh 记录 BackgroundInitialRefresh 在后台进行的首次解析配置。
+type initialRefresh struct {
+	// running 后台解析是否正在进行，采集时不持有 gatherLock 读取。
+	running atomic.Bool
+	// done 后台解析已结束，结果尚未被采集取用，由 gatherLock 保护。
+	done bool
+	// hostErrs 个别主机首次采样失败的错误。
+	hostErrs error
+	// err 解析配置失败的错误。
+	err error
+	// started 后台解析开始的时间。
+	started time.Time
+}
+
+// startInitialRefresh 启用 BackgroundInitialRefresh 且尚未添加计数器时，在后台解析配置并完成首次采样，
+// 返回是否已经开始或正在进行。后台解析期间持有 gatherLock（按 RefreshBatchDelay 等待时除外），
+// 调用方持有 gatherLock 时解析在其释放后开始。
+func (m *WinPerfCounters) startInitialRefresh() bool {
+	if !m.BackgroundInitialRefresh || m.initialRefresh.done {
+		return false
+	}
+	if !m.initialRefresh.running.CompareAndSwap(false, true) {
+		return true
+	}
+	m.initialRefresh.started = time.Now()
+	m.Log.Infof("Adding counters in the background")
+	go func() {
+		m.gatherLock.Lock()
+		defer m.gatherLock.Unlock()
+		defer m.initialRefresh.running.Store(false)
+
+		if m.closed || m.hostCounters != nil {
+			// 已关闭，或等待期间已由其它调用添加了计数器
+			return
+		}
+		hostErrs, err := m.refreshInBackground()
+		if m.closed {
+			// 在批次之间等待时被关闭，Close 已释放当时的查询
+			if m.hostCounters != nil {
+				_ = m.closeHosts(m.hostCounters)
+				m.hostCounters = nil
+			}
+			return
+		}
+		m.initialRefresh.done = true
+		m.initialRefresh.hostErrs, m.initialRefresh.err = hostErrs, err
+		if err != nil {
+			m.Log.Errorf("Adding counters in the background failed: %v", err)
+			// 丢弃已部分添加的计数器，取用该错误之后的采集重新在后台添加
+			if m.hostCounters != nil {
+				_ = m.closeHosts(m.hostCounters)
+				m.hostCounters = nil
+			}
+			return
+		}
+		m.Log.Infof("Counters ready after %s", time.Since(m.initialRefresh.started).Round(time.Millisecond))
+	}()
+	return true
+}
+
+// refreshInBackground 解析配置并完成首次采样，与 refreshAll 相同但不等待第二次采样，下一次采集时自然满足间隔。
+func (m *WinPerfCounters) refreshInBackground() (hostErrs error, err error) {
+	if err := m.cleanQueries(); err != nil {
+		return nil, err
+	}
+	if err := m.parseConfig(); err != nil {
+		return nil, err
+	}
+	return m.collectInitialSamples(m.hostCounters), nil
+}
+
+// takeInitialRefresh 取用后台解析的结果，之后的采集按常规方式刷新。
+func (m *WinPerfCounters) takeInitialRefresh() (hostErrs error, err error) {
+	hostErrs, err = m.initialRefresh.hostErrs, m.initialRefresh.err
+	m.initialRefresh.done = false
+	m.initialRefresh.hostErrs, m.initialRefresh.err = nil, nil
+	return hostErrs, err
+}
+
+// initialRefreshPending 后台解析正在进行时返回包装了 ErrNotReady 的错误，其中带有添加计数器的进度，
+// 采集不等待 gatherLock 而是立即返回，不会在解析期间阻塞调度器或调用方。
+func (m *WinPerfCounters) initialRefreshPending() error {
+	if !m.initialRefresh.running.Load() {
+		return nil
+	}
+	return m.notReadyError()
+}
+
+// notReadyError 记录一次未就绪的采集，返回包装了 ErrNotReady 的错误，其中带有本次刷新已添加和需要添加的计数器数量。
+func (m *WinPerfCounters) notReadyError() error {
+	m.stats.incr(map[string]string{}, "not_ready_gathers", 1)
+	done, queued := m.refreshProgress()
+	return fmt.Errorf("%w: %d of %d counters added", ErrNotReady, done, queued)
+}
+
+// resetInitialRefresh 丢弃尚未取用的后台解析结果，在 Close 时调用。
+func (m *WinPerfCounters) resetInitialRefresh() {
+	m.initialRefresh.done = false
+	m.initialRefresh.hostErrs, m.initialRefresh.err = nil, nil
+}
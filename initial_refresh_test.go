@@ -0,0 +1,62 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// waitInitialRefresh 等待后台添加结束。
+func waitInitialRefresh(t *testing.T, m *WinPerfCounters) {
+	t.Helper()
+	require.Eventually(t, func() bool { return !m.initialRefresh.running.Load() }, 5*time.Second, time.Millisecond)
+}
+
+func TestBackgroundInitialRefreshCycle(t *testing.T) {
+	source := &fakeSource{value: 1}
+	m := newFakeSourcePlugin(source)
+	m.BackgroundInitialRefresh = true
+	m.UseWildcardsExpansion = true
+	m.Object[0].FailOnMissing = true
+	require.NoError(t, m.Init())
+	t.Cleanup(func() { _ = m.Close() })
+
+	// 第一次采集开始后台添加，立即返回带有进度的 ErrNotReady
+	notRegistered := errors.New("object not registered yet")
+	source.failExpand(notRegistered)
+	err := m.Gather()
+	require.ErrorIs(t, err, ErrNotReady)
+	require.ErrorContains(t, err, "counters added")
+	waitInitialRefresh(t, m)
+
+	// 后台添加失败时下一次采集返回该错误，部分添加的计数器被丢弃
+	err = m.Gather()
+	require.ErrorIs(t, err, notRegistered)
+	require.NotErrorIs(t, err, ErrNotReady)
+	require.Nil(t, m.hostCounters)
+
+	// 之后的采集重新在后台添加，成功后正常采集
+	source.failExpand(nil)
+	require.ErrorIs(t, m.Gather(), ErrNotReady)
+	waitInitialRefresh(t, m)
+	require.NoError(t, m.Gather())
+	require.NotNil(t, m.hostCounters)
+	require.NoError(t, m.Gather())
+
+	require.Equal(t, int64(2), m.stats.value(map[string]string{}, "not_ready_gathers"))
+}
+
+func TestSelfMetricsValueDoesNotCreateSeries(t *testing.T) {
+	var stats selfMetrics
+	require.Nil(t, stats.value(map[string]string{"source": "hostA"}, "refresh_counters_done"))
+	require.Empty(t, stats.series)
+
+	stats.set(map[string]string{}, "refreshes", int64(1))
+	require.Equal(t, int64(1), stats.value(map[string]string{}, "refreshes"))
+	require.Nil(t, stats.value(map[string]string{}, "missing"))
+	require.Len(t, stats.series, 1)
+}
@@ -0,0 +1,115 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// instanceFilter 保存对象编译后的实例过滤规则。
+type instanceFilter struct {
+	// all Instances 中是否包含 "*"。
+	all bool
+	// include Instances 中的正则表达式。
+	include []*regexp.Regexp
+	// exclude InstancesExclude 中的精确名称。
+	exclude []string
+	// excludeRegex InstancesExclude 中的正则表达式。
+	excludeRegex []*regexp.Regexp
+}
+
+// initInstanceFilters 编译所有对象的 Instances 与 InstancesExclude 中的正则表达式。
+func (m *WinPerfCounters) initInstanceFilters(objects []ObjectConfig) error {
+	for i := range objects {
+		filter, err := objects[i].compileInstanceFilter()
+		if err != nil {
+			return err
+		}
+		objects[i].instanceFilter = filter
+	}
+	return nil
+}
+
+// compileInstanceFilter 编译对象的实例过滤规则，没有正则表达式和排除项时返回 nil。
+func (o *ObjectConfig) compileInstanceFilter() (*instanceFilter, error) {
+	filter := &instanceFilter{}
+	hasRegex := false
+	for _, instance := range o.Instances {
+		pattern, ok := strings.CutPrefix(instance, regexInstancePrefix)
+		if !ok {
+			filter.all = filter.all || instance == "*"
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid instance pattern %q for object %q: %w", instance, o.ObjectName, err)
+		}
+		filter.include = append(filter.include, re)
+		hasRegex = true
+	}
+	for _, instance := range o.InstancesExclude {
+		pattern, ok := strings.CutPrefix(instance, regexInstancePrefix)
+		if !ok {
+			filter.exclude = append(filter.exclude, instance)
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid excluded instance pattern %q for object %q: %w", instance, o.ObjectName, err)
+		}
+		filter.excludeRegex = append(filter.excludeRegex, re)
+	}
+	if !hasRegex && len(o.InstancesExclude) == 0 {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+// queryInstances 返回需要向 PDH 查询的实例名称，正则表达式条目以 "*" 查询并在采集时过滤。
+func (o *ObjectConfig) queryInstances() []string {
+	configured := o.instances()
+	if len(configured) == 0 && len(o.Services) > 0 {
+		return []string{"*"}
+	}
+	if o.instanceFilter == nil || len(o.instanceFilter.include) == 0 {
+		return configured
+	}
+	instances := make([]string, 0, len(configured))
+	for _, instance := range configured {
+		if strings.HasPrefix(instance, regexInstancePrefix) {
+			instance = "*"
+		}
+		if !slices.Contains(instances, instance) {
+			instances = append(instances, instance)
+		}
+	}
+	return instances
+}
+
+// acceptInstance 判断以 queried 查询得到的实例 instance 是否满足对象的实例过滤规则。
+func (o *ObjectConfig) acceptInstance(queried, instance string) bool {
+	if o == nil || o.instanceFilter == nil || instance == "" || instance == emptyInstance {
+		return true
+	}
+	filter := o.instanceFilter
+	if slices.Contains(filter.exclude, instance) {
+		return false
+	}
+	for _, re := range filter.excludeRegex {
+		if re.MatchString(instance) {
+			return false
+		}
+	}
+	if queried != "*" || filter.all {
+		return true
+	}
+	for _, re := range filter.include {
+		if re.MatchString(instance) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileInstanceFilter(t *testing.T) {
+	filter, err := (&ObjectConfig{Instances: []string{"*"}}).compileInstanceFilter()
+	require.NoError(t, err)
+	require.Nil(t, filter, "no filter is needed without patterns and exclusions")
+
+	_, err = (&ObjectConfig{ObjectName: "Process", Instances: []string{"re:("}}).compileInstanceFilter()
+	require.ErrorContains(t, err, `invalid instance pattern "re:(" for object "Process"`)
+
+	_, err = (&ObjectConfig{ObjectName: "Process", InstancesExclude: []string{"re:["}}).compileInstanceFilter()
+	require.ErrorContains(t, err, `invalid excluded instance pattern "re:[" for object "Process"`)
+}
+
+func TestQueryInstances(t *testing.T) {
+	tests := []struct {
+		name   string
+		object ObjectConfig
+		want   []string
+	}{
+		{
+			name:   "plain instances",
+			object: ObjectConfig{Instances: []string{"C:", "D:"}},
+			want:   []string{"C:", "D:"},
+		},
+		{
+			name:   "patterns are queried with a wildcard",
+			object: ObjectConfig{Instances: []string{"re:^sql", "_Total", "re:^w3wp"}},
+			want:   []string{"*", "_Total"},
+		},
+		{
+			name:   "wildcard and pattern",
+			object: ObjectConfig{Instances: []string{"*", "re:^sql"}},
+			want:   []string{"*"},
+		},
+		{
+			name:   "services without instances",
+			object: ObjectConfig{Services: []string{"MSSQLSERVER"}},
+			want:   []string{"*"},
+		},
+	}
+	for _, tt := range tests {
+		filter, err := tt.object.compileInstanceFilter()
+		require.NoError(t, err, tt.name)
+		tt.object.instanceFilter = filter
+		require.Equal(t, tt.want, tt.object.queryInstances(), tt.name)
+	}
+}
+
+func TestAcceptInstance(t *testing.T) {
+	tests := []struct {
+		name     string
+		object   ObjectConfig
+		queried  string
+		instance string
+		want     bool
+	}{
+		{
+			name:     "no filter",
+			object:   ObjectConfig{Instances: []string{"*"}},
+			queried:  "*",
+			instance: "sqlservr",
+			want:     true,
+		},
+		{
+			name:     "pattern matches",
+			object:   ObjectConfig{Instances: []string{"re:^sql"}},
+			queried:  "*",
+			instance: "sqlservr",
+			want:     true,
+		},
+		{
+			name:     "pattern does not match",
+			object:   ObjectConfig{Instances: []string{"re:^sql"}},
+			queried:  "*",
+			instance: "w3wp",
+		},
+		{
+			name:     "explicitly queried instance",
+			object:   ObjectConfig{Instances: []string{"re:^sql", "w3wp"}},
+			queried:  "w3wp",
+			instance: "w3wp",
+			want:     true,
+		},
+		{
+			name:     "wildcard next to a pattern",
+			object:   ObjectConfig{Instances: []string{"*", "re:^sql"}},
+			queried:  "*",
+			instance: "w3wp",
+			want:     true,
+		},
+		{
+			name:     "excluded by name",
+			object:   ObjectConfig{Instances: []string{"*"}, InstancesExclude: []string{"Idle"}},
+			queried:  "*",
+			instance: "Idle",
+		},
+		{
+			name:     "exclusion is case sensitive",
+			object:   ObjectConfig{Instances: []string{"*"}, InstancesExclude: []string{"Idle"}},
+			queried:  "*",
+			instance: "idle",
+			want:     true,
+		},
+		{
+			name:     "excluded by pattern",
+			object:   ObjectConfig{Instances: []string{"re:^sql"}, InstancesExclude: []string{"re:#\\d+$"}},
+			queried:  "*",
+			instance: "sqlservr#1",
+		},
+		{
+			name:     "exclusion wins over an explicit instance",
+			object:   ObjectConfig{Instances: []string{"Idle"}, InstancesExclude: []string{"Idle"}},
+			queried:  "Idle",
+			instance: "Idle",
+		},
+		{
+			name:     "objects without instances",
+			object:   ObjectConfig{Instances: []string{"re:^sql"}, InstancesExclude: []string{"re:.*"}},
+			queried:  "*",
+			instance: emptyInstance,
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		filter, err := tt.object.compileInstanceFilter()
+		require.NoError(t, err, tt.name)
+		tt.object.instanceFilter = filter
+		require.Equal(t, tt.want, tt.object.acceptInstance(tt.queried, tt.instance), tt.name)
+	}
+
+	var object *ObjectConfig
+	require.True(t, object.acceptInstance("*", "w3wp"))
+}
@@ -0,0 +1,52 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// validateInstanceFormats 校验所有对象的 InstanceFormat 配置。
+func (m *WinPerfCounters) validateInstanceFormats(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].validateInstanceFormat(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateInstanceFormat 校验对象的 InstanceFormat 配置，格式中必须有且只有一个整数动词，例如 "%02d" 或 "core%03d"。
+func (o *ObjectConfig) validateInstanceFormat() error {
+	if o.InstanceFormat == "" {
+		return nil
+	}
+	if formatted := fmt.Sprintf(o.InstanceFormat, 1); strings.Contains(formatted, "%!") {
+		return fmt.Errorf("invalid InstanceFormat %q of object %q, expected a single integer verb such as \"%%02d\"", o.InstanceFormat, o.ObjectName)
+	}
+	return nil
+}
+
+// formatInstance 按 InstanceFormat 格式化数字实例名称，"0,3" 等以逗号分隔的名称（如 Processor Information）
+// 分别格式化每个数字部分，"_Total" 等非数字部分保持不变。
+func (o *ObjectConfig) formatInstance(instance string) string {
+	parts := strings.Split(instance, ",")
+	for i, part := range parts {
+		if n, err := strconv.ParseUint(part, 10, 64); err == nil {
+			parts[i] = fmt.Sprintf(o.InstanceFormat, n)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// applyInstanceFormat 为配置了 InstanceFormat 的对象格式化 instance 标签中的数字实例名称。
+func applyInstanceFormat(object *ObjectConfig, tags map[string]string) {
+	if object == nil || object.InstanceFormat == "" {
+		return
+	}
+	if instance, ok := tags["instance"]; ok {
+		tags["instance"] = object.formatInstance(instance)
+	}
+}
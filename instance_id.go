@@ -0,0 +1,123 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// instanceIDCache 记录 "名称#索引" 形式的实例到稳定标识（例如进程 PID）的映射，
+// 在刷新计数器后依然保留，用于在某次采集未能读取到标识计数器时补全标签。
+// 每次采集结束时丢弃本次采集没有出现的实例，进程频繁启停的主机上缓存不会无限增长。
+type instanceIDCache struct {
+	lock sync.Mutex
+	ids  map[string]*instanceID
+}
+
+// instanceID 实例的稳定标识，seen 记录本次采集是否出现过该实例。
+type instanceID struct {
+	id   string
+	seen bool
+}
+
+func (c *instanceIDCache) key(host, objectName, instance string) string {
+	return host + "\x00" + objectName + "\x00" + instance
+}
+
+func (c *instanceIDCache) get(host, objectName, instance string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.ids[c.key(host, objectName, instance)]
+	if !ok {
+		return "", false
+	}
+	entry.seen = true
+	return entry.id, true
+}
+
+func (c *instanceIDCache) set(host, objectName, instance, id string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.ids == nil {
+		c.ids = make(map[string]*instanceID)
+	}
+	c.ids[c.key(host, objectName, instance)] = &instanceID{id: id, seen: true}
+}
+
+// prune 丢弃上一次 prune 之后没有出现过的实例。
+func (c *instanceIDCache) prune() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for key, entry := range c.ids {
+		if !entry.seen {
+			delete(c.ids, key)
+			continue
+		}
+		entry.seen = false
+	}
+}
+
+// counterNames 返回需要采集的计数器名称，配置了 InstanceIDCounter 时自动追加该计数器，
+// 配置了 Services、ProcessPID 或 ProcessPath 时自动追加 ID Process 计数器。
+func (o *ObjectConfig) counterNames() []string {
+	counters := o.Counters
+	for _, counter := range []string{o.InstanceIDCounter, o.serviceIDCounter(), o.processPIDCounter()} {
+		if counter != "" && !slices.Contains(counters, counter) {
+			counters = append(slices.Clone(counters), counter)
+		}
+	}
+	return counters
+}
+
+// serviceIDCounter 返回按 Services 选择进程时需要的计数器，未配置 Services 时返回空字符串。
+func (o *ObjectConfig) serviceIDCounter() string {
+	if len(o.Services) == 0 {
+		return ""
+	}
+	return processIDCounter
+}
+
+// applyInstanceID 为实例添加 instance_id 标签，并在启用 RewriteInstance 时改写 instance 标签。
+//
+// 进程退出后 "名称#索引" 会在不同进程间漂移，稳定标识可以避免下游时间序列在 #1 与 #2 之间来回切换。
+func (m *WinPerfCounters) applyInstanceID(hostInfo *hostCountersInfo, object *ObjectConfig, grouping instanceGrouping, fields map[string]interface{}, tags map[string]string) {
+	if object == nil || object.InstanceIDCounter == "" || grouping.instance == "" {
+		return
+	}
+
+	field := object.fieldName(object.InstanceIDCounter)
+	var id string
+	if value, ok := fields[field]; ok {
+		id = formatInstanceID(value)
+		m.instanceIDs.set(hostInfo.computer, grouping.objectName, grouping.instance, id)
+	} else if cached, ok := m.instanceIDs.get(hostInfo.computer, grouping.objectName, grouping.instance); ok {
+		id = cached
+	} else {
+		return
+	}
+
+	tags["instance_id"] = id
+	if object.RewriteInstance {
+		name, _, _ := strings.Cut(grouping.instance, "#")
+		tags["instance"] = name + "_" + id
+	}
+}
+
+// formatInstanceID 将标识计数器的值格式化为字符串，浮点数按整数输出。
+func formatInstanceID(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,60 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceIDCachePrune(t *testing.T) {
+	var cache instanceIDCache
+	cache.set("localhost", "Process", "w3wp", "100")
+	cache.set("localhost", "Process", "w3wp#1", "200")
+	cache.set("hostA", "Process", "w3wp", "300")
+	cache.prune()
+	require.Len(t, cache.ids, 3)
+
+	// 本次采集只出现了其中两个实例，其中一个未读取到标识计数器
+	cache.set("localhost", "Process", "w3wp", "101")
+	id, ok := cache.get("hostA", "Process", "w3wp")
+	require.True(t, ok)
+	require.Equal(t, "300", id)
+	cache.prune()
+
+	_, ok = cache.get("localhost", "Process", "w3wp#1")
+	require.False(t, ok)
+	id, ok = cache.get("localhost", "Process", "w3wp")
+	require.True(t, ok)
+	require.Equal(t, "101", id)
+	require.Len(t, cache.ids, 2)
+
+	// 连续两次采集都没有出现的实例被丢弃
+	cache.prune()
+	cache.prune()
+	require.Empty(t, cache.ids)
+}
+
+func TestApplyInstanceID(t *testing.T) {
+	m := &WinPerfCounters{}
+	hostInfo := &hostCountersInfo{computer: "localhost"}
+	object := &ObjectConfig{ObjectName: "Process", InstanceIDCounter: "ID Process", RewriteInstance: true}
+	field := object.fieldName("ID Process")
+	grouping := instanceGrouping{name: "win_proc", instance: "w3wp#1", objectName: "Process"}
+
+	tags := map[string]string{"instance": "w3wp#1"}
+	m.applyInstanceID(hostInfo, object, grouping, map[string]interface{}{field: 4242.0}, tags)
+	require.Equal(t, map[string]string{"instance": "w3wp_4242", "instance_id": "4242"}, tags)
+
+	// 未读取到标识计数器时使用记录的标识
+	tags = map[string]string{"instance": "w3wp#1"}
+	m.applyInstanceID(hostInfo, object, grouping, map[string]interface{}{}, tags)
+	require.Equal(t, "4242", tags["instance_id"])
+
+	m.instanceIDs.prune()
+	m.instanceIDs.prune()
+	tags = map[string]string{"instance": "w3wp#1"}
+	m.applyInstanceID(hostInfo, object, grouping, map[string]interface{}{}, tags)
+	require.Equal(t, map[string]string{"instance": "w3wp#1"}, tags)
+}
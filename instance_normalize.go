@@ -0,0 +1,113 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// volumeRescanInterval 遇到未知的卷时重新枚举盘符的最短间隔。
+const volumeRescanInterval = time.Minute
+
+var (
+	// instanceIndexPattern PDH 为同名实例追加的 "#1"、"#2" 等序号。
+	instanceIndexPattern = regexp.MustCompile(`#\d+$`)
+	// instanceVolumePattern 没有盘符或盘符未被 PDH 使用的卷实例，例如 "HarddiskVolume3"。
+	instanceVolumePattern = regexp.MustCompile(`(?i)^HarddiskVolume\d+$`)
+	// interfaceGUIDPattern 网络接口实例名称中的接口 GUID 及其前面的分隔符，例如 "isatap.{4A3F...}"。
+	interfaceGUIDPattern = regexp.MustCompile(`[._ ]?\{[0-9A-Fa-f]{8}(-[0-9A-Fa-f]{4}){3}-[0-9A-Fa-f]{12}\}`)
+	// interfaceBrackets PDH 将网络接口名称中的圆括号替换为方括号，例如 "Intel[R] Ethernet"，还原为圆括号。
+	interfaceBrackets = strings.NewReplacer("[", "(", "]", ")")
+)
+
+// volumeLetters 记录本机卷设备名称（如 "harddiskvolume3"）到盘符的映射，遇到未知的卷时重新枚举。
+type volumeLetters struct {
+	lock    sync.Mutex
+	letters map[string]string
+	scanned time.Time
+}
+
+// get 返回卷对应的盘符，例如 "C:"，没有盘符的卷返回 false。
+func (v *volumeLetters) get(volume string) (string, bool) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	key := strings.ToLower(volume)
+	letter, ok := v.letters[key]
+	if !ok && time.Since(v.scanned) >= volumeRescanInterval {
+		v.letters = queryVolumeLetters()
+		v.scanned = time.Now()
+		letter, ok = v.letters[key]
+	}
+	return letter, ok
+}
+
+// queryVolumeLetters 通过 QueryDosDevice 查询每个盘符对应的卷设备。
+func queryVolumeLetters() map[string]string {
+	letters := make(map[string]string)
+	drives, err := windows.GetLogicalDrives()
+	if err != nil {
+		return letters
+	}
+	buf := make([]uint16, windows.MAX_PATH)
+	for i := 0; i < 26; i++ {
+		if drives&(1<<i) == 0 {
+			continue
+		}
+		letter := string(rune('A'+i)) + ":"
+		name, err := windows.UTF16PtrFromString(letter)
+		if err != nil {
+			continue
+		}
+		n, err := windows.QueryDosDevice(name, &buf[0], uint32(len(buf)))
+		if err != nil || n == 0 {
+			continue
+		}
+		// 结果是以两个 NUL 结尾的字符串列表，第一项为当前的映射
+		if volume, ok := strings.CutPrefix(windows.UTF16ToString(buf[:n]), `\Device\`); ok {
+			letters[strings.ToLower(volume)] = letter
+		}
+	}
+	return letters
+}
+
+// isNetworkObject 判断对象是否为实例名称带有接口修饰的网络对象。
+func isNetworkObject(objectName string) bool {
+	return strings.EqualFold(objectName, "Network Interface") || strings.EqualFold(objectName, "Network Adapter")
+}
+
+// normalizeInstance 清理实例名称：去掉 "#序号" 后缀，将本机的 "HarddiskVolumeN" 映射为盘符，
+// 并去掉网络接口名称中的 GUID、还原被替换的括号。
+func (m *WinPerfCounters) normalizeInstance(computer, objectName, instance string) string {
+	instance = instanceIndexPattern.ReplaceAllString(instance, "")
+	// 远程主机的卷无法通过 QueryDosDevice 查询
+	if computer == "localhost" && instanceVolumePattern.MatchString(instance) {
+		if letter, ok := m.volumeLetters.get(instance); ok {
+			instance = letter
+		}
+	}
+	if isNetworkObject(objectName) {
+		instance = interfaceBrackets.Replace(interfaceGUIDPattern.ReplaceAllString(instance, ""))
+		instance = strings.TrimSpace(instance)
+	}
+	return instance
+}
+
+// applyInstanceNormalization 为启用 NormalizeInstanceNames 的对象清理 instance 标签，实例的选择与过滤仍使用原始名称。
+func (m *WinPerfCounters) applyInstanceNormalization(hostInfo *hostCountersInfo, object *ObjectConfig, tags map[string]string) {
+	if object == nil || !object.NormalizeInstanceNames {
+		return
+	}
+	instance, ok := tags["instance"]
+	if !ok || instance == "_Total" {
+		return
+	}
+	if normalized := m.normalizeInstance(hostInfo.computer, object.ObjectName, instance); normalized != "" {
+		tags["instance"] = normalized
+	}
+}
@@ -0,0 +1,74 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeInstance(t *testing.T) {
+	m := NewWinPerfCounters(func(string, map[string]interface{}, map[string]string, time.Time) {})
+	tests := []struct {
+		name       string
+		objectName string
+		instance   string
+		expected   string
+	}{
+		{"index suffix", "Process", "svchost#3", "svchost"},
+		{"index only at the end", "Process", "a#1b", "a#1b"},
+		{"no index", "Process", "svchost", "svchost"},
+		{"hash without digits", "Process", "name#", "name#"},
+		// 远程主机的卷不查询盘符
+		{"remote volume", "LogicalDisk", "HarddiskVolume3", "HarddiskVolume3"},
+		{"interface guid with dot", "Network Interface", "isatap.{4A3F1C2D-1111-2222-3333-444455556666}", "isatap"},
+		{"interface guid with space", "Network Adapter", "Teredo {4a3f1c2d-1111-2222-3333-444455556666}", "Teredo"},
+		{"interface brackets", "Network Interface", "Intel[R] Ethernet Connection #2", "Intel(R) Ethernet Connection"},
+		{"malformed guid kept", "Network Interface", "vpn.{1234}", "vpn.{1234}"},
+		{"guid outside network objects", "Process", "app.{4A3F1C2D-1111-2222-3333-444455556666}", "app.{4A3F1C2D-1111-2222-3333-444455556666}"},
+		{"brackets outside network objects", "Process", "Intel[R]", "Intel[R]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, m.normalizeInstance("remotehost", tt.objectName, tt.instance))
+		})
+	}
+}
+
+func TestInstanceVolumePattern(t *testing.T) {
+	for instance, expected := range map[string]bool{
+		"HarddiskVolume3":  true,
+		"harddiskvolume12": true,
+		"HarddiskVolume":   false,
+		"C:":               false,
+		"HarddiskVolume3x": false,
+	} {
+		require.Equal(t, expected, instanceVolumePattern.MatchString(instance), instance)
+	}
+}
+
+func TestDuplicateCollapseNormalized(t *testing.T) {
+	m := NewWinPerfCounters(func(string, map[string]interface{}, map[string]string, time.Time) {})
+	object := &ObjectConfig{ObjectName: "Network Interface", CollapseDuplicates: "sum", NormalizeInstanceNames: true}
+	grouping := func(instance string) instanceGrouping {
+		return instanceGrouping{name: "win_net", instance: instance, objectName: object.ObjectName}
+	}
+	collectedFields := fieldGrouping{
+		grouping("isatap.{4A3F1C2D-1111-2222-3333-444455556666}"): {"Bytes_Total_persec": 1.0},
+		grouping("isatap.{5B3F1C2D-1111-2222-3333-444455556666}"): {"Bytes_Total_persec": 2.0},
+		grouping("Intel[R] Ethernet"):                             {"Bytes_Total_persec": 4.0},
+	}
+	groupObjects := make(map[instanceGrouping]*ObjectConfig)
+	for g := range collectedFields {
+		groupObjects[g] = object
+	}
+
+	m.applyDuplicateCollapse(&hostCountersInfo{computer: "remotehost"}, collectedFields, groupObjects)
+	require.Equal(t, fieldGrouping{
+		grouping("isatap"):            {"Bytes_Total_persec": 3.0, "duplicate_count": int64(2)},
+		grouping("Intel[R] Ethernet"): {"Bytes_Total_persec": 4.0},
+	}, collectedFields)
+	require.Equal(t, object, groupObjects[grouping("isatap")])
+}
@@ -0,0 +1,76 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// maxInstanceRange 一个实例范围最多展开的实例数量，避免误写的范围产生大量计数器。
+const maxInstanceRange = 4096
+
+// instanceRangePattern 匹配 "0-15" 形式的实例编号范围。
+var instanceRangePattern = regexp.MustCompile(`^(\d+)-(\d+)$`)
+
+// expandInstanceRanges 将所有对象的 Instances 与 InstancesExclude 中的编号范围展开为单独的实例名称。
+func (m *WinPerfCounters) expandInstanceRanges(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].expandInstanceRanges(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandInstanceRanges 将对象的 Instances 与 InstancesExclude 中的编号范围展开为单独的实例名称，
+// 例如 "0-3" 展开为 "0"、"1"、"2"、"3"，起始编号带前导零时展开的名称保持相同的位数。
+func (o *ObjectConfig) expandInstanceRanges() error {
+	instances, err := o.expandedInstances(o.Instances)
+	if err != nil {
+		return err
+	}
+	excluded, err := o.expandedInstances(o.InstancesExclude)
+	if err != nil {
+		return err
+	}
+	o.Instances, o.InstancesExclude = instances, excluded
+	return nil
+}
+
+// expandedInstances 返回展开编号范围后的实例名称，没有范围时原样返回。
+func (o *ObjectConfig) expandedInstances(instances []string) ([]string, error) {
+	var expanded []string
+	for i, instance := range instances {
+		match := instanceRangePattern.FindStringSubmatch(instance)
+		if match == nil {
+			if expanded != nil {
+				expanded = append(expanded, instance)
+			}
+			continue
+		}
+		start, errStart := strconv.Atoi(match[1])
+		end, errEnd := strconv.Atoi(match[2])
+		if errStart != nil || errEnd != nil || start > end {
+			return nil, fmt.Errorf("invalid instance range %q of object %q", instance, o.ObjectName)
+		}
+		if end-start >= maxInstanceRange {
+			return nil, fmt.Errorf("instance range %q of object %q exceeds %d instances", instance, o.ObjectName, maxInstanceRange)
+		}
+		if expanded == nil {
+			expanded = append(make([]string, 0, len(instances)+end-start), instances[:i]...)
+		}
+		width := 0
+		if len(match[1]) > 1 && match[1][0] == '0' {
+			width = len(match[1])
+		}
+		for n := start; n <= end; n++ {
+			expanded = append(expanded, fmt.Sprintf("%0*d", width, n))
+		}
+	}
+	if expanded == nil {
+		return instances, nil
+	}
+	return expanded, nil
+}
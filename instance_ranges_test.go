@@ -0,0 +1,96 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandedInstances(t *testing.T) {
+	tests := []struct {
+		name      string
+		instances []string
+		want      []string
+		wantErr   string
+	}{
+		{
+			name:      "no ranges",
+			instances: []string{"_Total", "C:"},
+			want:      []string{"_Total", "C:"},
+		},
+		{
+			name:      "range",
+			instances: []string{"0-3"},
+			want:      []string{"0", "1", "2", "3"},
+		},
+		{
+			name:      "range between other instances",
+			instances: []string{"_Total", "0-1", "C:"},
+			want:      []string{"_Total", "0", "1", "C:"},
+		},
+		{
+			name:      "leading zeros keep the width",
+			instances: []string{"08-11"},
+			want:      []string{"08", "09", "10", "11"},
+		},
+		{
+			name:      "single number range",
+			instances: []string{"5-5"},
+			want:      []string{"5"},
+		},
+		{
+			name:      "not a range",
+			instances: []string{"0,1-2", "a-b", "1-", "-1", "1 - 2"},
+			want:      []string{"0,1-2", "a-b", "1-", "-1", "1 - 2"},
+		},
+		{
+			name:      "reversed range",
+			instances: []string{"3-0"},
+			wantErr:   `invalid instance range "3-0" of object "Processor"`,
+		},
+		{
+			name:      "too many instances",
+			instances: []string{"0-4096"},
+			wantErr:   `instance range "0-4096" of object "Processor" exceeds 4096 instances`,
+		},
+		{
+			name:      "number out of range",
+			instances: []string{"0-99999999999999999999"},
+			wantErr:   `invalid instance range "0-99999999999999999999"`,
+		},
+	}
+	object := &ObjectConfig{ObjectName: "Processor"}
+	for _, tt := range tests {
+		got, err := object.expandedInstances(tt.instances)
+		if tt.wantErr != "" {
+			require.ErrorContains(t, err, tt.wantErr, tt.name)
+			continue
+		}
+		require.NoError(t, err, tt.name)
+		require.Equal(t, tt.want, got, tt.name)
+	}
+
+	got, err := object.expandedInstances([]string{"1-4096"})
+	require.NoError(t, err)
+	require.Len(t, got, maxInstanceRange)
+
+	// 展开不修改传入的切片
+	instances := []string{"_Total", "0-1"}
+	_, err = object.expandedInstances(instances)
+	require.NoError(t, err)
+	require.Equal(t, []string{"_Total", "0-1"}, instances)
+}
+
+func TestExpandInstanceRanges(t *testing.T) {
+	object := &ObjectConfig{ObjectName: "Processor", Instances: []string{"0-2"}, InstancesExclude: []string{"1-1"}}
+	require.NoError(t, object.expandInstanceRanges())
+	require.Equal(t, []string{"0", "1", "2"}, object.Instances)
+	require.Equal(t, []string{"1"}, object.InstancesExclude)
+
+	object = &ObjectConfig{ObjectName: "Processor", Instances: []string{"0-2"}, InstancesExclude: []string{"2-1"}}
+	require.ErrorContains(t, object.expandInstanceRanges(), `invalid instance range "2-1"`)
+	// 出错时不修改配置
+	require.Equal(t, []string{"0-2"}, object.Instances)
+}
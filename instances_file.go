@@ -0,0 +1,139 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// instancesFileCheckInterval 检查 InstancesFile 是否被修改的最短间隔，高频采集时不必每次都访问文件系统。
+const instancesFileCheckInterval = 10 * time.Second
+
+// instancesFileState 对象的 InstancesFile 最近一次读取的内容和修改时间。
+type instancesFileState struct {
+	instances []string
+	modTime   time.Time
+	size      int64
+}
+
+// readInstancesFile 读取对象的 InstancesFile，每行一个实例名称，忽略空行和以 "#" 开头的注释行，
+// "0-15" 形式的编号范围与 Instances 中一样展开。
+func (o *ObjectConfig) readInstancesFile() (instancesFileState, error) {
+	info, err := os.Stat(o.InstancesFile)
+	if err != nil {
+		return instancesFileState{}, fmt.Errorf("reading instances file of object %q failed: %w", o.ObjectName, err)
+	}
+	data, err := os.ReadFile(o.InstancesFile)
+	if err != nil {
+		return instancesFileState{}, fmt.Errorf("reading instances file of object %q failed: %w", o.ObjectName, err)
+	}
+	var instances []string
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	for line := 1; scanner.Scan(); line++ {
+		instance := strings.TrimSpace(scanner.Text())
+		if instance == "" || strings.HasPrefix(instance, "#") {
+			continue
+		}
+		if strings.HasPrefix(instance, regexInstancePrefix) {
+			return instancesFileState{}, fmt.Errorf("line %d of instances file %q: regular expressions are not supported", line, o.InstancesFile)
+		}
+		if !slices.Contains(instances, instance) {
+			instances = append(instances, instance)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return instancesFileState{}, fmt.Errorf("reading instances file of object %q failed: %w", o.ObjectName, err)
+	}
+	instances, err = o.expandedInstances(instances)
+	if err != nil {
+		return instancesFileState{}, err
+	}
+	return instancesFileState{instances: instances, modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// loadInstancesFiles 读取所有对象的 InstancesFile，文件不存在或内容无效时返回错误。
+func (m *WinPerfCounters) loadInstancesFiles(objects []ObjectConfig) error {
+	for i := range objects {
+		o := &objects[i]
+		if o.InstancesFile == "" {
+			continue
+		}
+		state, err := o.readInstancesFile()
+		if err != nil {
+			return err
+		}
+		o.instancesFile = state
+	}
+	return nil
+}
+
+// reloadInstancesFiles 在刷新计数器时重新读取所有对象的 InstancesFile，
+// 读取失败时记录警告并继续使用上一次读取的实例。
+func (m *WinPerfCounters) reloadInstancesFiles() {
+	for i := range m.Object {
+		o := &m.Object[i]
+		if o.InstancesFile == "" {
+			continue
+		}
+		state, err := o.readInstancesFile()
+		if err != nil {
+			m.Log.Warnf("%v, keeping the previous instances", err)
+			continue
+		}
+		o.instancesFile = state
+	}
+}
+
+// instancesFilesChanged 判断是否有对象的 InstancesFile 在上一次读取后被修改，修改后立即刷新计数器。
+// 每 instancesFileCheckInterval 最多检查一次，多个对象使用同一个文件时只读取一次文件信息。
+func (m *WinPerfCounters) instancesFilesChanged(now time.Time) bool {
+	if now.Sub(m.instancesFilesChecked) < instancesFileCheckInterval {
+		return false
+	}
+	m.instancesFilesChecked = now
+
+	infos := make(map[string]os.FileInfo)
+	for i := range m.Object {
+		o := &m.Object[i]
+		if o.InstancesFile == "" {
+			continue
+		}
+		info, ok := infos[o.InstancesFile]
+		if !ok {
+			var err error
+			if info, err = os.Stat(o.InstancesFile); err != nil {
+				info = nil
+			}
+			infos[o.InstancesFile] = info
+		}
+		if info == nil {
+			// 文件暂时不可用时保持当前的实例，等到文件恢复后再刷新
+			continue
+		}
+		if !info.ModTime().Equal(o.instancesFile.modTime) || info.Size() != o.instancesFile.size {
+			return true
+		}
+	}
+	return false
+}
+
+// instances 返回 Instances 与 InstancesFile 中的实例名称，TotalOnly 将实例改为 _Total 后忽略 InstancesFile。
+func (o *ObjectConfig) instances() []string {
+	if o.InstancesFile == "" || len(o.instancesFile.instances) == 0 ||
+		o.TotalOnly && slices.Equal(o.Instances, []string{"_Total"}) {
+		return o.Instances
+	}
+	instances := slices.Clone(o.Instances)
+	for _, instance := range o.instancesFile.instances {
+		if !slices.Contains(instances, instance) {
+			instances = append(instances, instance)
+		}
+	}
+	return instances
+}
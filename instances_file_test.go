@@ -0,0 +1,169 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeInstancesFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "instances.txt")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestReadInstancesFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "comments and blank lines",
+			content: "\ufeff# disks\r\nC:\r\n\r\n  D:  \n# E:\n",
+			want:    []string{"C:", "D:"},
+		},
+		{
+			name:    "duplicates",
+			content: "C:\nD:\nC:\n",
+			want:    []string{"C:", "D:"},
+		},
+		{
+			name:    "ranges",
+			content: "0-2\n_Total\n",
+			want:    []string{"0", "1", "2", "_Total"},
+		},
+		{
+			name:    "empty",
+			content: "# nothing here\n",
+		},
+		{
+			name:    "regular expressions",
+			content: "C:\nre:^D\n",
+			wantErr: "line 2 of instances file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &ObjectConfig{ObjectName: "LogicalDisk", InstancesFile: writeInstancesFile(t, tt.content)}
+			state, err := o.readInstancesFile()
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, state.instances)
+			require.Equal(t, int64(len(tt.content)), state.size)
+			require.False(t, state.modTime.IsZero())
+		})
+	}
+
+	o := &ObjectConfig{ObjectName: "LogicalDisk", InstancesFile: filepath.Join(t.TempDir(), "missing.txt")}
+	_, err := o.readInstancesFile()
+	require.ErrorContains(t, err, `reading instances file of object "LogicalDisk" failed`)
+}
+
+func TestObjectInstances(t *testing.T) {
+	tests := []struct {
+		name   string
+		object ObjectConfig
+		want   []string
+	}{
+		{
+			name:   "no file",
+			object: ObjectConfig{Instances: []string{"C:"}},
+			want:   []string{"C:"},
+		},
+		{
+			name: "merged without duplicates",
+			object: ObjectConfig{
+				Instances:     []string{"C:", "D:"},
+				InstancesFile: "disks.txt",
+				instancesFile: instancesFileState{instances: []string{"D:", "E:"}},
+			},
+			want: []string{"C:", "D:", "E:"},
+		},
+		{
+			name: "file only",
+			object: ObjectConfig{
+				InstancesFile: "disks.txt",
+				instancesFile: instancesFileState{instances: []string{"E:"}},
+			},
+			want: []string{"E:"},
+		},
+		{
+			name: "empty file",
+			object: ObjectConfig{
+				Instances:     []string{"C:"},
+				InstancesFile: "disks.txt",
+			},
+			want: []string{"C:"},
+		},
+		{
+			name: "TotalOnly ignores the file",
+			object: ObjectConfig{
+				Instances:     []string{"_Total"},
+				TotalOnly:     true,
+				InstancesFile: "disks.txt",
+				instancesFile: instancesFileState{instances: []string{"E:"}},
+			},
+			want: []string{"_Total"},
+		},
+		{
+			name: "TotalOnly without wildcards keeps the file",
+			object: ObjectConfig{
+				Instances:     []string{"C:"},
+				TotalOnly:     true,
+				InstancesFile: "disks.txt",
+				instancesFile: instancesFileState{instances: []string{"E:"}},
+			},
+			want: []string{"C:", "E:"},
+		},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, tt.object.instances(), tt.name)
+	}
+
+	// 合并不修改配置中的 Instances
+	object := ObjectConfig{
+		Instances:     make([]string, 1, 4),
+		InstancesFile: "disks.txt",
+		instancesFile: instancesFileState{instances: []string{"E:"}},
+	}
+	object.Instances[0] = "C:"
+	_ = object.instances()
+	require.Equal(t, []string{"C:"}, object.Instances)
+}
+
+func TestInstancesFilesChanged(t *testing.T) {
+	path := writeInstancesFile(t, "C:\n")
+	m := NewWinPerfCounters(func(string, map[string]interface{}, map[string]string, time.Time) {})
+	m.Object = []ObjectConfig{
+		{ObjectName: "LogicalDisk", InstancesFile: path},
+		{ObjectName: "PhysicalDisk", InstancesFile: path},
+	}
+	require.NoError(t, m.loadInstancesFiles(m.Object))
+
+	now := time.Now()
+	require.False(t, m.instancesFilesChanged(now))
+
+	require.NoError(t, os.WriteFile(path, []byte("C:\nD:\n"), 0o600))
+	// 间隔内不再检查文件
+	require.False(t, m.instancesFilesChanged(now.Add(instancesFileCheckInterval/2)))
+	require.True(t, m.instancesFilesChanged(now.Add(instancesFileCheckInterval)))
+
+	m.reloadInstancesFiles()
+	require.Equal(t, []string{"C:", "D:"}, m.Object[1].instancesFile.instances)
+	require.False(t, m.instancesFilesChanged(now.Add(2*instancesFileCheckInterval)))
+
+	// 文件暂时不可用时不刷新
+	require.NoError(t, os.Remove(path))
+	require.False(t, m.instancesFilesChanged(now.Add(3*instancesFileCheckInterval)))
+}
@@ -0,0 +1,21 @@
+# Builds the integration test image and runs the end-to-end tests against
+# real PDH inside a Windows Server Core container. Requires Docker in Windows
+# containers mode; -WindowsVersion must match the host for process isolation.
+param(
+    [string]$GoVersion = "1.24",
+    [string]$WindowsVersion = "ltsc2022",
+    [string]$Isolation = "process"
+)
+
+$ErrorActionPreference = "Stop"
+$root = Split-Path -Parent $PSScriptRoot
+$image = "win_perf_counters-integration"
+
+docker build --isolation $Isolation `
+    --build-arg GO_VERSION=$GoVersion `
+    --build-arg WINDOWS_VERSION=$WindowsVersion `
+    -f (Join-Path $PSScriptRoot "Dockerfile") -t $image $root
+if ($LASTEXITCODE -ne 0) { exit $LASTEXITCODE }
+
+docker run --rm --isolation $Isolation $image
+exit $LASTEXITCODE
@@ -0,0 +1,92 @@
+//go:build windows && integration
+
+package win_perf_counters
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestGatherPipelineIntegration 对真实的 PDH 运行完整的采集流程，校验已知对象的指标被输出。
+// 需要 integration 构建标签，可通过 integration/run.ps1 在 Windows Server Core 容器中运行。
+func TestGatherPipelineIntegration(t *testing.T) {
+	var lock sync.Mutex
+	metrics := make(map[string]Metric)
+	collect := func(measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time) {
+		lock.Lock()
+		defer lock.Unlock()
+		metrics[measurement] = Metric{Measurement: measurement, Tags: tags, Fields: fields, Timestamp: timestamp}
+	}
+
+	m := NewWinPerfCounters(collect)
+	m.Object = []ObjectConfig{
+		{
+			ObjectName:  "Processor Information",
+			Instances:   []string{"_Total"},
+			Counters:    []string{"% Processor Time"},
+			Measurement: "win_cpu",
+		},
+		{
+			ObjectName:  "Memory",
+			Instances:   []string{emptyInstance},
+			Counters:    []string{"Available Bytes"},
+			Measurement: "win_mem",
+		},
+		{
+			ObjectName:  "System",
+			Instances:   []string{emptyInstance},
+			Counters:    []string{"Processes", "Threads"},
+			Measurement: "win_system",
+		},
+	}
+	require.NoError(t, m.Init())
+	defer m.Close()
+
+	// 第一次采集添加计数器并完成首次采样
+	require.NoError(t, m.Gather())
+	time.Sleep(time.Second)
+	require.NoError(t, m.Gather())
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	cpu, ok := metrics["win_cpu"]
+	require.True(t, ok, "missing win_cpu metric")
+	require.Equal(t, "Processor Information", cpu.Tags["objectname"])
+	require.Equal(t, "_Total", cpu.Tags["instance"])
+	require.NotEmpty(t, cpu.Tags["source"])
+	require.Contains(t, cpu.Fields, "Percent_Processor_Time")
+
+	mem, ok := metrics["win_mem"]
+	require.True(t, ok, "missing win_mem metric")
+	available, ok := mem.Fields["Available_Bytes"].(float64)
+	require.True(t, ok)
+	require.Positive(t, available)
+
+	system, ok := metrics["win_system"]
+	require.True(t, ok, "missing win_system metric")
+	require.Contains(t, system.Fields, "Processes")
+	require.Contains(t, system.Fields, "Threads")
+}
+
+// TestWMISessionIntegration 在同一个会话中依次执行多个查询，只初始化一次 COM 并连接一次。
+func TestWMISessionIntegration(t *testing.T) {
+	session, err := openWMISession(wmiNamespace, "", "")
+	require.NoError(t, err)
+	defer session.close()
+
+	rows, err := session.query("SELECT Name, NumberOfProcesses FROM Win32_PerfFormattedData_PerfOS_System", []string{"NumberOfProcesses"})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Contains(t, rows[0], "NumberOfProcesses")
+
+	rows, err = session.query("SELECT Name FROM Win32_PerfFormattedData_PerfOS_Processor", []string{wmiInstanceProperty})
+	require.NoError(t, err)
+	require.NotEmpty(t, rows)
+
+	_, err = session.query("SELECT Name FROM Win32_NoSuchClass", []string{wmiInstanceProperty})
+	require.Error(t, err)
+}
@@ -0,0 +1,52 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+// internedTags 记录一个共享的标签映射及其最近一次使用的时间。
+type internedTags struct {
+	tags map[string]string
+	seen time.Time
+}
+
+// tagInterner 为内容相同的标签映射返回同一个实例，启用 InternTags 时避免下游长期持有大量重复的映射。
+type tagInterner struct {
+	lock    sync.Mutex
+	entries map[string]*internedTags
+}
+
+// intern 返回与 tags 内容相同的共享映射，首次出现时保存 tags 的副本。返回的映射不得修改。
+func (t *tagInterner) intern(tags map[string]string, now time.Time) map[string]string {
+	key := snapshotKey("", tags)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.entries == nil {
+		t.entries = make(map[string]*internedTags)
+	}
+	entry, ok := t.entries[key]
+	if !ok {
+		entry = &internedTags{tags: maps.Clone(tags)}
+		t.entries[key] = entry
+	}
+	entry.seen = now
+	return entry.tags
+}
+
+// prune 丢弃长时间未使用的标签映射，例如已退出的进程。
+func (t *tagInterner) prune(now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for key, entry := range t.entries {
+		if now.Sub(entry.seen) > previousValueTimeout {
+			delete(t.entries, key)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 保活时采集的计数器，开销很小且所有系统上都存在。
+const (
+	keepAliveObject  = "System"
+	keepAliveCounter = "Processes"
+)
+
+// keepAliveHost 记录一个远程主机的保活状态。
+type keepAliveHost struct {
+	// gathered 最近一次正常采集的时间。
+	gathered time.Time
+	// active 最近一次正常采集或保活的时间。
+	active time.Time
+	// query 保活使用的查询，只在保活协程中使用。
+	query PerformanceQuery
+}
+
+// keepAliveState 在两次采集之间定期访问远程主机，避免 RPC 会话因空闲而断开。
+type keepAliveState struct {
+	lock   sync.Mutex
+	hosts  map[string]*keepAliveHost
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// touchKeepAlive 记录远程主机完成了一次采集，并在需要时启动保活协程。
+func (m *WinPerfCounters) touchKeepAlive(computer string, now time.Time) {
+	if m.KeepAliveInterval <= 0 || computer == "" || computer == "localhost" || logSourcePath(computer) != "" {
+		return
+	}
+
+	m.keepAlive.lock.Lock()
+	defer m.keepAlive.lock.Unlock()
+
+	if m.keepAlive.hosts == nil {
+		m.keepAlive.hosts = make(map[string]*keepAliveHost)
+	}
+	host, ok := m.keepAlive.hosts[computer]
+	if !ok {
+		host = &keepAliveHost{}
+		m.keepAlive.hosts[computer] = host
+	}
+	host.gathered = now
+	host.active = now
+
+	if m.keepAlive.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		m.keepAlive.cancel = cancel
+		m.keepAlive.done = done
+		go func() {
+			defer close(done)
+			m.runKeepAlive(ctx)
+		}()
+	}
+}
+
+// runKeepAlive 每个 KeepAliveInterval 检查一次，为空闲超过 KeepAliveInterval 的远程主机采集一次保活计数器。
+func (m *WinPerfCounters) runKeepAlive(ctx context.Context) {
+	interval := time.Duration(m.KeepAliveInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for computer, host := range m.idleKeepAliveHosts(now, interval) {
+				m.pingHost(computer, host)
+			}
+		}
+	}
+}
+
+// idleKeepAliveHosts 返回空闲超过 interval 的远程主机，并移除超过 previousValueTimeout 未采集的主机。
+func (m *WinPerfCounters) idleKeepAliveHosts(now time.Time, interval time.Duration) map[string]*keepAliveHost {
+	m.keepAlive.lock.Lock()
+	defer m.keepAlive.lock.Unlock()
+
+	idle := make(map[string]*keepAliveHost)
+	for computer, host := range m.keepAlive.hosts {
+		if now.Sub(host.gathered) > previousValueTimeout {
+			if host.query != nil {
+				_ = host.query.Close()
+			}
+			delete(m.keepAlive.hosts, computer)
+			continue
+		}
+		if now.Sub(host.active) >= interval-intervalTolerance {
+			idle[computer] = host
+		}
+	}
+	return idle
+}
+
+// pingHost 在主机上采集一次保活计数器，失败时关闭保活查询，下次重新建立。
+func (m *WinPerfCounters) pingHost(computer string, host *keepAliveHost) {
+	tags := map[string]string{"source": computer}
+	if host.query == nil {
+		query := m.queryCreator.newPerformanceQuery(computer, uint32(m.MaxBufferSize))
+		if err := query.Open(); err != nil {
+			m.Log.Warnf("Opening keep-alive query for host %q failed: %v", computer, err)
+			m.stats.incr(tags, "keepalive_failures", 1)
+			return
+		}
+		path := formatPath(computer, keepAliveObject, emptyInstance, keepAliveCounter)
+		if _, err := query.AddEnglishCounterToQuery(path); err != nil {
+			m.Log.Warnf("Adding keep-alive counter %q failed: %v", path, err)
+			m.stats.incr(tags, "keepalive_failures", 1)
+			_ = query.Close()
+			return
+		}
+		host.query = query
+	}
+
+	if err := host.query.CollectData(); err != nil {
+		m.Log.Warnf("Keep-alive of host %q failed: %v", computer, err)
+		m.stats.incr(tags, "keepalive_failures", 1)
+		_ = host.query.Close()
+		host.query = nil
+		return
+	}
+	m.stats.incr(tags, "keepalives", 1)
+
+	m.keepAlive.lock.Lock()
+	host.active = time.Now()
+	m.keepAlive.lock.Unlock()
+}
+
+// stopKeepAlive 停止保活协程并关闭所有保活查询。
+func (m *WinPerfCounters) stopKeepAlive() {
+	m.keepAlive.lock.Lock()
+	cancel, done := m.keepAlive.cancel, m.keepAlive.done
+	m.keepAlive.cancel = nil
+	m.keepAlive.done = nil
+	m.keepAlive.lock.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+
+	m.keepAlive.lock.Lock()
+	defer m.keepAlive.lock.Unlock()
+	for _, host := range m.keepAlive.hosts {
+		if host.query != nil {
+			_ = host.query.Close()
+		}
+	}
+	m.keepAlive.hosts = nil
+}
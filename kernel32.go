@@ -4,6 +4,7 @@ package win_perf_counters
 
 import (
 	"syscall"
+	"unsafe"
 )
 
 type fileTime struct {
@@ -11,16 +12,73 @@ type fileTime struct {
 	dwHighDateTime uint32
 }
 
+type memoryStatusEx struct {
+	dwLength                uint32
+	dwMemoryLoad            uint32
+	ullTotalPhys            uint64
+	ullAvailPhys            uint64
+	ullTotalPageFile        uint64
+	ullAvailPageFile        uint64
+	ullTotalVirtual         uint64
+	ullAvailVirtual         uint64
+	ullAvailExtendedVirtual uint64
+}
+
 var (
 	// Library
 	libKernelDll *syscall.DLL
 
 	// Functions
-	kernelLocalFileTimeToFileTime *syscall.Proc
+	kernelLocalFileTimeToFileTime   *syscall.Proc
+	kernelQueryPerformanceFrequency *syscall.Proc
+	kernelGetActiveProcessorCount   *syscall.Proc
+	kernelGlobalMemoryStatusEx      *syscall.Proc
+	kernelGetUserDefaultUILanguage  *syscall.Proc
 )
 
 func init() {
 	libKernelDll = syscall.MustLoadDLL("Kernel32.dll")
 
 	kernelLocalFileTimeToFileTime = libKernelDll.MustFindProc("LocalFileTimeToFileTime")
-}
\ No newline at end of file
+	kernelQueryPerformanceFrequency = libKernelDll.MustFindProc("QueryPerformanceFrequency")
+	kernelGetActiveProcessorCount = libKernelDll.MustFindProc("GetActiveProcessorCount")
+	kernelGlobalMemoryStatusEx = libKernelDll.MustFindProc("GlobalMemoryStatusEx")
+	kernelGetUserDefaultUILanguage = libKernelDll.MustFindProc("GetUserDefaultUILanguage")
+}
+
+// queryPerformanceFrequency returns the frequency of the performance counter in ticks per second, or 0 on failure.
+func queryPerformanceFrequency() int64 {
+	var frequency int64
+	ret, _, _ := kernelQueryPerformanceFrequency.Call(uintptr(unsafe.Pointer(&frequency))) //nolint:gosec // G103: Valid use of unsafe call to pass frequency
+	if ret == 0 {
+		return 0
+	}
+	return frequency
+}
+
+// allProcessorGroups makes GetActiveProcessorCount count the processors of all processor groups.
+const allProcessorGroups = 0xFFFF
+
+// activeProcessorCount returns the number of active logical processors in all processor groups,
+// GetSystemInfo only reports the processors of the calling thread's group (at most 64).
+func activeProcessorCount() int {
+	ret, _, _ := kernelGetActiveProcessorCount.Call(allProcessorGroups)
+	return int(uint32(ret))
+}
+
+// totalPhysicalMemory returns the amount of physical memory in bytes reported by GlobalMemoryStatusEx.
+func totalPhysicalMemory() (uint64, error) {
+	info := memoryStatusEx{}
+	info.dwLength = uint32(unsafe.Sizeof(info))
+	ret, _, err := kernelGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&info))) //nolint:gosec // G103: Valid use of unsafe call to pass info
+	if ret == 0 {
+		return 0, err
+	}
+	return info.ullTotalPhys, nil
+}
+
+// userDefaultUILanguage returns the LANGID of the user interface language of the current user.
+func userDefaultUILanguage() uint16 {
+	ret, _, _ := kernelGetUserDefaultUILanguage.Call()
+	return uint16(ret)
+}
@@ -0,0 +1,75 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"strings"
+	"sync"
+)
+
+// localizedName 英文对象与计数器名称在主机上对应的本地化名称。
+type localizedName struct {
+	object  string
+	counter string
+}
+
+// localizedNameCache 按主机缓存英文对象与计数器名称到本地化名称的翻译，在多次刷新之间复用，
+// 用于 CacheLocalizedNames。
+type localizedNameCache struct {
+	lock  sync.Mutex
+	hosts map[string]map[string]localizedName
+}
+
+// localizedNameKey 返回英文对象与计数器名称的缓存键，名称不区分大小写。
+func localizedNameKey(object, counter string) string {
+	return strings.ToLower(object) + "\x00" + strings.ToLower(counter)
+}
+
+// get 返回主机上英文对象与计数器名称对应的本地化名称。
+func (c *localizedNameCache) get(computer, object, counter string) (localizedName, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	name, ok := c.hosts[computer][localizedNameKey(object, counter)]
+	return name, ok
+}
+
+// set 记录主机上英文对象与计数器名称对应的本地化名称。
+func (c *localizedNameCache) set(computer, object, counter string, name localizedName) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.hosts == nil {
+		c.hosts = make(map[string]map[string]localizedName)
+	}
+	if c.hosts[computer] == nil {
+		c.hosts[computer] = make(map[string]localizedName)
+	}
+	c.hosts[computer][localizedNameKey(object, counter)] = name
+}
+
+// reset 丢弃所有缓存的翻译，计数器注册表重建后名称可能变化。
+func (c *localizedNameCache) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.hosts = nil
+}
+
+// cachesLocalizedNames 判断是否缓存本地化名称，只在 UseWildcardsExpansion 且未启用 LocalizeWildcardsExpansion 时生效。
+func (m *WinPerfCounters) cachesLocalizedNames() bool {
+	return m.CacheLocalizedNames && m.UseWildcardsExpansion && !m.LocalizeWildcardsExpansion
+}
+
+// addCachedLocalizedCounter 使用缓存的本地化名称直接添加计数器，省去 PDH 对英文名称的翻译，没有缓存或添加失败时 ok 为 false。
+func (m *WinPerfCounters) addCachedLocalizedCounter(query PerformanceQuery, computer, objectName, instance, counterName string) (pdhCounterHandle, bool) {
+	if !m.cachesLocalizedNames() {
+		return 0, false
+	}
+	name, ok := m.localizedPaths.get(computer, objectName, counterName)
+	if !ok {
+		return 0, false
+	}
+	counterHandle, err := query.AddCounterToQuery(formatPath(computer, name.object, instance, name.counter))
+	if err != nil {
+		return 0, false
+	}
+	return counterHandle, true
+}
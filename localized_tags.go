@@ -0,0 +1,60 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"strings"
+	"sync"
+)
+
+// localizedObjectNames 按主机缓存对象名称的本地化名称，每个序列输出时不再重复翻译，
+// 无法访问主机名称表时也只尝试一次。计数器注册表重建后清空。
+type localizedObjectNames struct {
+	lock  sync.Mutex
+	hosts map[string]map[string]string
+}
+
+// get 返回主机上对象名称的本地化名称，没有缓存时通过 translate 翻译并缓存。
+func (c *localizedObjectNames) get(computer, objectName string, translate func() string) string {
+	key := strings.ToLower(objectName)
+	c.lock.Lock()
+	localized, ok := c.hosts[computer][key]
+	c.lock.Unlock()
+	if ok {
+		return localized
+	}
+
+	localized = translate()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.hosts == nil {
+		c.hosts = make(map[string]map[string]string)
+	}
+	if c.hosts[computer] == nil {
+		c.hosts[computer] = make(map[string]string)
+	}
+	c.hosts[computer][key] = localized
+	return localized
+}
+
+// reset 丢弃所有缓存的名称。
+func (c *localizedObjectNames) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.hosts = nil
+}
+
+// applyLocalizedNameTags 在启用 LocalizedNameTags 时附加主机语言的对象名称 objectname_localized，
+// 供使用本地语言 perfmon 的运维人员对照，下游仍按 objectname 处理。objectname 以及已有的同名标签保持不变，
+// 日志数据源没有名称表，不附加。
+func (m *WinPerfCounters) applyLocalizedNameTags(hostInfo *hostCountersInfo, objectName string, tags map[string]string) {
+	if !m.LocalizedNameTags || logSourcePath(hostInfo.computer) != "" {
+		return
+	}
+	if _, ok := tags["objectname_localized"]; ok {
+		return
+	}
+	tags["objectname_localized"] = m.localizedObjects.get(hostInfo.computer, objectName, func() string {
+		return m.localizePerfName(hostInfo.computer, m.englishPerfName(hostInfo.computer, objectName))
+	})
+}
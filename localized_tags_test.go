@@ -0,0 +1,48 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalizedObjectNamesCache(t *testing.T) {
+	var names localizedObjectNames
+	calls := 0
+	translate := func(localized string) func() string {
+		return func() string {
+			calls++
+			return localized
+		}
+	}
+	require.Equal(t, "Prozessor", names.get("hostA", "Processor", translate("Prozessor")))
+	require.Equal(t, "Prozessor", names.get("hostA", "PROCESSOR", translate("ignored")))
+	require.Equal(t, "Processeur", names.get("hostB", "Processor", translate("Processeur")))
+	require.Equal(t, 2, calls)
+
+	names.reset()
+	require.Equal(t, "Prozessor", names.get("hostA", "Processor", translate("Prozessor")))
+	require.Equal(t, 3, calls)
+}
+
+func TestApplyLocalizedNameTags(t *testing.T) {
+	m := NewWinPerfCounters(func(string, map[string]interface{}, map[string]string, time.Time) {})
+	hostInfo := &hostCountersInfo{computer: "hostA"}
+	m.localizedObjects.get("hostA", "Processor", func() string { return "Prozessor" })
+
+	tags := map[string]string{"objectname": "Processor"}
+	m.applyLocalizedNameTags(hostInfo, "Processor", tags)
+	require.Equal(t, map[string]string{"objectname": "Processor"}, tags)
+
+	m.LocalizedNameTags = true
+	m.applyLocalizedNameTags(hostInfo, "Processor", tags)
+	require.Equal(t, map[string]string{"objectname": "Processor", "objectname_localized": "Prozessor"}, tags)
+
+	// 已有的标签不被覆盖
+	tags = map[string]string{"objectname": "Processor", "objectname_localized": "custom"}
+	m.applyLocalizedNameTags(hostInfo, "Processor", tags)
+	require.Equal(t, "custom", tags["objectname_localized"])
+}
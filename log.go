@@ -1,12 +1,49 @@
 package win_perf_counters
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"log/slog"
 )
 
+// LogHandler 接收本包输出的日志，可以通过 NewSlogLogger、NewEventLogger 接入应用自身的日志系统，
+// 也可以自行实现后设置到 Logger.Handler。
+type LogHandler interface {
+	Errorf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+}
+
 type Logger struct {
 	Name  string // Name is the plugin name, will be printed in the `[]`.
 	Quiet bool
+	// Output is the logger to print to, the standard logger of the log package is used if nil.
+	Output *log.Logger
+	// Handler receives the messages instead of Output if set, Name is not added to the messages.
+	Handler LogHandler
+}
+
+// NewStdLogger 返回通过 logger 输出日志的 Logger，logger 为 nil 时使用 log 包的标准 logger。
+func NewStdLogger(logger *log.Logger, name string) Logger {
+	return Logger{Name: name, Output: logger}
+}
+
+func (l Logger) printf(format string, args ...interface{}) {
+	if l.Output != nil {
+		l.Output.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func (l Logger) print(args ...interface{}) {
+	if l.Output != nil {
+		l.Output.Print(args...)
+		return
+	}
+	log.Print(args...)
 }
 
 // We always want to output at debug level during testing to find issues easier
@@ -18,54 +55,132 @@ type Logger struct {
 func (Logger) AddAttribute(string, interface{}) {}
 
 func (l Logger) Errorf(format string, args ...interface{}) {
-	log.Printf("[ERROR] ["+l.Name+"] "+format, args...)
+	if l.Handler != nil {
+		l.Handler.Errorf(format, args...)
+		return
+	}
+	l.printf("[ERROR] ["+l.Name+"] "+format, args...)
 }
 
 func (l Logger) Error(args ...interface{}) {
-	log.Print(append([]interface{}{"[ERROR] [" + l.Name + "] "}, args...)...)
+	if l.Handler != nil {
+		l.Handler.Errorf("%s", fmt.Sprint(args...))
+		return
+	}
+	l.print(append([]interface{}{"[ERROR] [" + l.Name + "] "}, args...)...)
 }
 
 func (l Logger) Warnf(format string, args ...interface{}) {
-	log.Printf("[WARN] ["+l.Name+"] "+format, args...)
+	if l.Handler != nil {
+		l.Handler.Warnf(format, args...)
+		return
+	}
+	l.printf("[WARN] ["+l.Name+"] "+format, args...)
 }
 
 func (l Logger) Warn(args ...interface{}) {
-	log.Print(append([]interface{}{"[WARN] [" + l.Name + "] "}, args...)...)
+	if l.Handler != nil {
+		l.Handler.Warnf("%s", fmt.Sprint(args...))
+		return
+	}
+	l.print(append([]interface{}{"[WARN] [" + l.Name + "] "}, args...)...)
 }
 
 func (l Logger) Infof(format string, args ...interface{}) {
-	if !l.Quiet {
-		log.Printf("[INFO] ["+l.Name+"] "+format, args...)
+	switch {
+	case l.Quiet:
+	case l.Handler != nil:
+		l.Handler.Infof(format, args...)
+	default:
+		l.printf("[INFO] ["+l.Name+"] "+format, args...)
 	}
 }
 
 func (l Logger) Info(args ...interface{}) {
-	if !l.Quiet {
-		log.Print(append([]interface{}{"[INFO] [" + l.Name + "] "}, args...)...)
+	switch {
+	case l.Quiet:
+	case l.Handler != nil:
+		l.Handler.Infof("%s", fmt.Sprint(args...))
+	default:
+		l.print(append([]interface{}{"[INFO] [" + l.Name + "] "}, args...)...)
 	}
 }
 
 func (l Logger) Debugf(format string, args ...interface{}) {
-	if !l.Quiet {
-		log.Printf("[DEBUG] ["+l.Name+"] "+format, args...)
+	switch {
+	case l.Quiet:
+	case l.Handler != nil:
+		l.Handler.Debugf(format, args...)
+	default:
+		l.printf("[DEBUG] ["+l.Name+"] "+format, args...)
 	}
 }
 
 func (l Logger) Debug(args ...interface{}) {
-	if !l.Quiet {
-		log.Print(append([]interface{}{"[DEBUG] [" + l.Name + "] "}, args...)...)
+	switch {
+	case l.Quiet:
+	case l.Handler != nil:
+		l.Handler.Debugf("%s", fmt.Sprint(args...))
+	default:
+		l.print(append([]interface{}{"[DEBUG] [" + l.Name + "] "}, args...)...)
 	}
 }
 
+// Tracef logs a trace message, forwarded to Handler at debug level.
 func (l Logger) Tracef(format string, args ...interface{}) {
-	if !l.Quiet {
-		log.Printf("[TRACE] ["+l.Name+"] "+format, args...)
+	switch {
+	case l.Quiet:
+	case l.Handler != nil:
+		l.Handler.Debugf(format, args...)
+	default:
+		l.printf("[TRACE] ["+l.Name+"] "+format, args...)
 	}
 }
 
 // Trace logs a trace message, patterned after log.Print.
 func (l Logger) Trace(args ...interface{}) {
-	if !l.Quiet {
-		log.Print(append([]interface{}{"[TRACE] [" + l.Name + "] "}, args...)...)
+	switch {
+	case l.Quiet:
+	case l.Handler != nil:
+		l.Handler.Debugf("%s", fmt.Sprint(args...))
+	default:
+		l.print(append([]interface{}{"[TRACE] [" + l.Name + "] "}, args...)...)
 	}
-}
\ No newline at end of file
+}
+
+// slogLogger 将日志转发给 *slog.Logger。
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger 返回将日志转发给 logger 的 Logger，消息按 fmt.Sprintf 格式化，级别对应 slog 的同名级别。
+// logger 为 nil 时使用 slog.Default()。
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return Logger{Handler: slogLogger{logger: logger}}
+}
+
+func (l slogLogger) logf(level slog.Level, format string, args ...interface{}) {
+	ctx := context.Background()
+	if l.logger.Enabled(ctx, level) {
+		l.logger.Log(ctx, level, fmt.Sprintf(format, args...))
+	}
+}
+
+func (l slogLogger) Errorf(format string, args ...interface{}) {
+	l.logf(slog.LevelError, format, args...)
+}
+
+func (l slogLogger) Warnf(format string, args ...interface{}) {
+	l.logf(slog.LevelWarn, format, args...)
+}
+
+func (l slogLogger) Infof(format string, args ...interface{}) {
+	l.logf(slog.LevelInfo, format, args...)
+}
+
+func (l slogLogger) Debugf(format string, args ...interface{}) {
+	l.logf(slog.LevelDebug, format, args...)
+}
@@ -0,0 +1,44 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// logSourcePrefix 标记 Sources 中的性能日志文件（.blg、.csv、.tsv），例如 "file://C:\perf\baseline.blg"。
+const logSourcePrefix = "file://"
+
+// logSourcePath 返回日志数据源的文件路径，computer 不是日志数据源时返回空字符串。
+func logSourcePath(computer string) string {
+	path, ok := strings.CutPrefix(computer, logSourcePrefix)
+	if !ok {
+		return ""
+	}
+	return path
+}
+
+// IsEndOfLog 判断 Gather 返回的错误是否表示日志数据源中的记录已全部读取完毕。
+func IsEndOfLog(err error) bool {
+	var pdhErr *pdhError
+	return errors.As(err, &pdhErr) && pdhErr.errorCode == pdhNoMoreData
+}
+
+// warnLogSourceRefresh 日志数据源在刷新计数器时会重新打开并从头读取，配置了刷新间隔时给出提示。
+func (m *WinPerfCounters) warnLogSourceRefresh(sources []string, objects []ObjectConfig) {
+	if m.CountersRefreshInterval <= 0 {
+		return
+	}
+	sources = slices.Clone(sources)
+	for _, object := range objects {
+		sources = append(sources, object.Sources...)
+	}
+	for _, source := range sources {
+		if logSourcePath(source) != "" {
+			m.Log.Warnf("Log source %q is read again from the start on every counter refresh, consider setting CountersRefreshInterval to 0", source)
+			return
+		}
+	}
+}
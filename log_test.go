@@ -0,0 +1,85 @@
+package win_perf_counters
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHandler 记录收到的日志的 LogHandler。
+type recordingHandler struct {
+	messages []string
+}
+
+func (h *recordingHandler) Errorf(format string, args ...interface{}) {
+	h.messages = append(h.messages, "E "+fmt.Sprintf(format, args...))
+}
+
+func (h *recordingHandler) Warnf(format string, args ...interface{}) {
+	h.messages = append(h.messages, "W "+fmt.Sprintf(format, args...))
+}
+
+func (h *recordingHandler) Infof(format string, args ...interface{}) {
+	h.messages = append(h.messages, "I "+fmt.Sprintf(format, args...))
+}
+
+func (h *recordingHandler) Debugf(format string, args ...interface{}) {
+	h.messages = append(h.messages, "D "+fmt.Sprintf(format, args...))
+}
+
+func TestLoggerOutput(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStdLogger(log.New(&buf, "", 0), "plugin")
+	logger.Errorf("failed %d", 1)
+	logger.Warn("careful")
+	logger.Infof("hello")
+	require.Equal(t, "[ERROR] [plugin] failed 1\n[WARN] [plugin] careful\n[INFO] [plugin] hello\n", buf.String())
+
+	buf.Reset()
+	logger.Quiet = true
+	logger.Infof("hidden")
+	logger.Debug("hidden")
+	logger.Warnf("shown")
+	require.Equal(t, "[WARN] [plugin] shown\n", buf.String())
+}
+
+func TestLoggerHandler(t *testing.T) {
+	handler := &recordingHandler{}
+	logger := Logger{Name: "plugin", Handler: handler}
+	logger.Errorf("failed %d", 1)
+	logger.Warn("careful ", 2)
+	logger.Info("hello")
+	logger.Debugf("details")
+	logger.Tracef("trace %s", "me")
+	require.Equal(t, []string{"E failed 1", "W careful 2", "I hello", "D details", "D trace me"}, handler.messages)
+
+	handler.messages = nil
+	logger.Quiet = true
+	logger.Infof("hidden")
+	logger.Errorf("shown")
+	require.Equal(t, []string{"E shown"}, handler.messages)
+}
+
+func TestNewSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})))
+	logger.Warnf("disk %q is full", "C:")
+	logger.Debugf("dropped")
+	require.Equal(t, "level=WARN msg=\"disk \\\"C:\\\" is full\"\n", buf.String())
+
+	// 以零值 Logger 为默认值的配置仍然可以直接赋值为结构体
+	m := &WinPerfCounters{Log: Logger{Name: "custom"}}
+	require.Equal(t, "custom", m.Log.Name)
+}
@@ -0,0 +1,124 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// PdhLogWriter 通过 PdhOpenLog/PdhUpdateLog 将计数器写入 perfmon 可以打开的 .blg、.csv 或 .tsv 日志文件。
+//
+// PdhUpdateLog 会自行采集数据，因此日志使用独立的查询，不影响插件本身的采集。
+type PdhLogWriter struct {
+	lock  sync.Mutex
+	query pdhQueryHandle
+	log   pdhLogHandle
+}
+
+// logType 返回日志格式对应的 PDH 日志类型，format 为空时按文件扩展名判断，默认为二进制（.blg）。
+func logType(path, format string) (uint32, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch strings.ToLower(format) {
+	case "csv":
+		return pdhLogTypeCSV, nil
+	case "tsv":
+		return pdhLogTypeTSV, nil
+	case "binary", "blg", "":
+		return pdhLogTypeBinary, nil
+	}
+	return 0, fmt.Errorf("unknown log format %q, expected one of binary, csv or tsv", format)
+}
+
+// NewPdhLogWriter 创建日志文件 path（已存在时覆盖）并将 counterPaths 中的计数器加入日志。
+// format 可以是 "binary"、"csv" 或 "tsv"，为空时按文件扩展名判断。
+func NewPdhLogWriter(path, format string, counterPaths []string) (*PdhLogWriter, error) {
+	if len(counterPaths) == 0 {
+		return nil, errors.New("no counters to log")
+	}
+	typ, err := logType(path, format)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &PdhLogWriter{}
+	if ret := pdhOpenQuery(0, 0, &w.query); ret != errorSuccess {
+		return nil, newPdhError(ret)
+	}
+	for _, counterPath := range counterPaths {
+		var counterHandle pdhCounterHandle
+		ret := uint32(errorInvalidFunction)
+		if pdhAddEnglishCounterSupported() {
+			ret = pdhAddEnglishCounter(w.query, counterPath, 0, &counterHandle)
+		}
+		if ret != errorSuccess {
+			ret = pdhAddCounter(w.query, counterPath, 0, &counterHandle)
+		}
+		if ret != errorSuccess {
+			pdhCloseQuery(w.query)
+			return nil, wrapCounterError("add", "", "", counterPath, newPdhError(ret))
+		}
+	}
+	if ret := pdhOpenLog(path, pdhLogWriteAccess|pdhLogCreateAlways, typ, w.query, &w.log); ret != errorSuccess {
+		pdhCloseQuery(w.query)
+		return nil, fmt.Errorf("opening log %q failed: %w", path, newPdhError(ret))
+	}
+	return w, nil
+}
+
+// Update 采集一次数据并作为一条新记录写入日志。
+func (w *PdhLogWriter) Update() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.log == 0 {
+		return errUninitializedQuery
+	}
+	if ret := pdhUpdateLog(w.log); ret != errorSuccess {
+		return newPdhError(ret)
+	}
+	return nil
+}
+
+// Close 关闭日志文件及其查询。
+func (w *PdhLogWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.log == 0 {
+		return nil
+	}
+	ret := pdhCloseLog(w.log)
+	w.log = 0
+	w.query = 0
+	if ret != errorSuccess {
+		return newPdhError(ret)
+	}
+	return nil
+}
+
+// openLogWriter 按当前的计数器集合创建 LogOutputPath 对应的日志，日志数据源中的计数器不会写入。
+func (m *WinPerfCounters) openLogWriter() error {
+	var counterPaths []string
+	for _, hostInfo := range m.hostCounters {
+		if logSourcePath(hostInfo.computer) != "" {
+			continue
+		}
+		for _, metric := range hostInfo.counters {
+			counterPaths = append(counterPaths, metric.counterPath)
+		}
+	}
+	slices.Sort(counterPaths)
+	writer, err := NewPdhLogWriter(m.LogOutputPath, m.LogFormat, slices.Compact(counterPaths))
+	if err != nil {
+		return err
+	}
+	m.logWriter = writer
+	return nil
+}
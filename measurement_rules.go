@@ -0,0 +1,83 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// measurementPlaceholder 匹配 MeasurementRules 模板中的 {标签名} 占位符。
+var measurementPlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// initMeasurementRules 编译所有对象的 MeasurementRules 中的正则表达式。
+func (m *WinPerfCounters) initMeasurementRules(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].compileMeasurementRules(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileMeasurementRules 校验对象的 MeasurementRules 并编译其中的正则表达式。
+func (o *ObjectConfig) compileMeasurementRules() error {
+	for i := range o.MeasurementRules {
+		rule := &o.MeasurementRules[i]
+		if rule.Measurement == "" {
+			return fmt.Errorf("measurement rule %d of object %q has no measurement", i, o.ObjectName)
+		}
+		rule.patterns = nil
+		for _, value := range rule.Values {
+			pattern, ok := strings.CutPrefix(value, regexInstancePrefix)
+			if !ok {
+				continue
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %q in measurement rule %d of object %q: %w", value, i, o.ObjectName, err)
+			}
+			rule.patterns = append(rule.patterns, re)
+		}
+	}
+	return nil
+}
+
+// matches 判断标签是否满足规则。
+func (r *MeasurementRule) matches(tags map[string]string) bool {
+	key := r.Tag
+	if key == "" {
+		key = "instance"
+	}
+	value, ok := tags[key]
+	if !ok {
+		return false
+	}
+	if len(r.Values) == 0 || slices.Contains(r.Values, value) {
+		return true
+	}
+	return slices.ContainsFunc(r.patterns, func(re *regexp.Regexp) bool { return re.MatchString(value) })
+}
+
+// applyMeasurementRules 返回实例组使用的测量名称，第一条满足的规则生效，没有满足的规则时返回 measurement。
+func applyMeasurementRules(object *ObjectConfig, measurement string, tags map[string]string) string {
+	if object == nil {
+		return measurement
+	}
+	for i := range object.MeasurementRules {
+		rule := &object.MeasurementRules[i]
+		if !rule.matches(tags) {
+			continue
+		}
+		return measurementPlaceholder.ReplaceAllStringFunc(rule.Measurement, func(placeholder string) string {
+			key := placeholder[1 : len(placeholder)-1]
+			if key == "measurement" {
+				return measurement
+			}
+			return tags[key]
+		})
+	}
+	return measurement
+}
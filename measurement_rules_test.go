@@ -0,0 +1,103 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyMeasurementRules(t *testing.T) {
+	rules := []MeasurementRule{
+		{Values: []string{"_Total"}, Measurement: "{measurement}_total"},
+		{Values: []string{"re:^sql"}, Measurement: "win_sql"},
+		{Tag: "role", Measurement: "win_{role}"},
+	}
+	tests := []struct {
+		name string
+		tags map[string]string
+		want string
+	}{
+		{
+			name: "exact value",
+			tags: map[string]string{"instance": "_Total"},
+			want: "win_proc_total",
+		},
+		{
+			name: "pattern",
+			tags: map[string]string{"instance": "sqlservr"},
+			want: "win_sql",
+		},
+		{
+			name: "first matching rule wins",
+			tags: map[string]string{"instance": "sqlservr", "role": "db"},
+			want: "win_sql",
+		},
+		{
+			name: "rule without values only requires the tag",
+			tags: map[string]string{"instance": "w3wp", "role": "web"},
+			want: "win_web",
+		},
+		{
+			name: "no matching rule",
+			tags: map[string]string{"instance": "w3wp"},
+			want: "win_proc",
+		},
+		{
+			name: "values are case sensitive",
+			tags: map[string]string{"instance": "_total"},
+			want: "win_proc",
+		},
+	}
+	object := &ObjectConfig{ObjectName: "Process", MeasurementRules: rules}
+	require.NoError(t, object.compileMeasurementRules())
+	for _, tt := range tests {
+		require.Equal(t, tt.want, applyMeasurementRules(object, "win_proc", tt.tags), tt.name)
+	}
+
+	// 模板中不存在的标签替换为空字符串
+	object = &ObjectConfig{MeasurementRules: []MeasurementRule{{Measurement: "win_{site}_{instance}"}}}
+	require.NoError(t, object.compileMeasurementRules())
+	require.Equal(t, "win__C:", applyMeasurementRules(object, "win_disk", map[string]string{"instance": "C:"}))
+
+	require.Equal(t, "win_proc", applyMeasurementRules(nil, "win_proc", map[string]string{"instance": "_Total"}))
+}
+
+func TestCompileMeasurementRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []MeasurementRule
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			rules: []MeasurementRule{{Values: []string{"C:", "re:^[D-Z]:$"}, Measurement: "win_disk_data"}},
+		},
+		{
+			name:    "missing measurement",
+			rules:   []MeasurementRule{{Values: []string{"C:"}}},
+			wantErr: `measurement rule 0 of object "LogicalDisk" has no measurement`,
+		},
+		{
+			name:    "invalid pattern",
+			rules:   []MeasurementRule{{Values: []string{"re:("}, Measurement: "win_disk_data"}},
+			wantErr: `invalid pattern "re:(" in measurement rule 0 of object "LogicalDisk"`,
+		},
+	}
+	for _, tt := range tests {
+		object := &ObjectConfig{ObjectName: "LogicalDisk", MeasurementRules: tt.rules}
+		err := object.compileMeasurementRules()
+		if tt.wantErr != "" {
+			require.ErrorContains(t, err, tt.wantErr, tt.name)
+			continue
+		}
+		require.NoError(t, err, tt.name)
+	}
+
+	// 重复编译不会累积正则表达式
+	object := &ObjectConfig{MeasurementRules: []MeasurementRule{{Values: []string{"re:^D"}, Measurement: "win_d"}}}
+	require.NoError(t, object.compileMeasurementRules())
+	require.NoError(t, object.compileMeasurementRules())
+	require.Len(t, object.MeasurementRules[0].patterns, 1)
+}
@@ -0,0 +1,108 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+)
+
+// validMetricKinds FieldMetadata.Kind 支持的指标类型。
+var validMetricKinds = map[string]bool{"": true, "gauge": true, "counter": true}
+
+// merge 返回以 override 中非空的项覆盖后的元数据。
+func (f FieldMetadata) merge(override FieldMetadata) FieldMetadata {
+	if override.Description != "" {
+		f.Description = override.Description
+	}
+	if override.Unit != "" {
+		f.Unit = override.Unit
+	}
+	if override.Kind != "" {
+		f.Kind = override.Kind
+	}
+	return f
+}
+
+// validateMetadata 校验所有对象的 Metadata 与 CounterMetadata 配置。
+func (m *WinPerfCounters) validateMetadata(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].validateMetadata(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateMetadata 校验对象的 Metadata 与 CounterMetadata 配置。
+func (o *ObjectConfig) validateMetadata() error {
+	if !validMetricKinds[o.Metadata.Kind] {
+		return fmt.Errorf("invalid metadata kind %q of object %q, expected gauge or counter", o.Metadata.Kind, o.ObjectName)
+	}
+	for counterName, metadata := range o.CounterMetadata {
+		if !validMetricKinds[metadata.Kind] {
+			return fmt.Errorf("invalid metadata kind %q for counter %q of object %q, expected gauge or counter", metadata.Kind, counterName, o.ObjectName)
+		}
+	}
+	return nil
+}
+
+// measurementName 返回对象输出时使用的测量名称。
+func (m *WinPerfCounters) measurementName(object *ObjectConfig) string {
+	measurement := object.NameOverride
+	if measurement == "" {
+		if measurement = object.sanitizeName(object.Measurement); measurement == "" {
+			measurement = "win_perf_counters"
+		}
+	}
+	if m.Transliterate {
+		measurement = transliterate(measurement)
+	}
+	return measurement
+}
+
+// initMetadata 按测量名称和字段名称建立元数据索引。对象级的 Metadata 以空字段名记录，作为该测量中其它字段的默认值；
+// 同一测量名称的多个对象共享元数据，后配置的对象优先。
+func (m *WinPerfCounters) initMetadata() {
+	m.fieldMetadata = nil
+	for i := range m.Object {
+		object := &m.Object[i]
+		if object.Metadata == (FieldMetadata{}) && len(object.CounterMetadata) == 0 {
+			continue
+		}
+		if m.fieldMetadata == nil {
+			m.fieldMetadata = make(map[string]map[string]FieldMetadata)
+		}
+		measurement := m.measurementName(object)
+		fields, ok := m.fieldMetadata[measurement]
+		if !ok {
+			fields = make(map[string]FieldMetadata)
+			m.fieldMetadata[measurement] = fields
+		}
+		if object.Metadata != (FieldMetadata{}) {
+			fields[""] = object.Metadata
+		}
+		for counterName, metadata := range object.CounterMetadata {
+			fields[object.fieldName(counterName)] = object.Metadata.merge(metadata)
+		}
+	}
+}
+
+// metricMetadata 返回指标中各字段的元数据，没有配置任何元数据时返回 nil。
+func (m *WinPerfCounters) metricMetadata(measurement string, fields map[string]interface{}) map[string]FieldMetadata {
+	known, ok := m.fieldMetadata[measurement]
+	if !ok {
+		return nil
+	}
+	metadata := make(map[string]FieldMetadata, len(fields))
+	for field := range fields {
+		if fieldMetadata, ok := known[field]; ok {
+			metadata[field] = fieldMetadata
+		} else if fieldMetadata, ok := known[""]; ok {
+			metadata[field] = fieldMetadata
+		}
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
@@ -0,0 +1,202 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"sync"
+	"time"
+)
+
+// ReportMissingInstancesAs 支持的取值。
+const (
+	// missingAsZero 以 0 输出消失实例的数值字段。
+	missingAsZero = "zero"
+	// missingAsNull 以 NaN 输出消失实例的浮点字段，支持空值的输出端可以将其写为 null，整数字段省略。
+	missingAsNull = "null"
+	// missingAsDrop 不输出消失的实例，只为出现的实例添加 instance_state 标签。
+	missingAsDrop = "drop"
+)
+
+// instanceStateTag 启用 ReportMissingInstancesAs 时标记实例是否存在的标签。
+const instanceStateTag = "instance_state"
+
+// instance_state 标签的取值。
+const (
+	instancePresent = "present"
+	instanceMissing = "missing"
+)
+
+// defaultMissingInstanceTimeout 未配置 MissingInstanceTimeout 时持续报告消失实例的时长。
+const defaultMissingInstanceTimeout = 10 * time.Minute
+
+// validateMissingInstances 校验所有对象的 ReportMissingInstancesAs 配置。
+func (m *WinPerfCounters) validateMissingInstances(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].validateMissingInstances(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateMissingInstances 校验对象的 ReportMissingInstancesAs 与 MissingInstanceTimeout 配置。
+func (o *ObjectConfig) validateMissingInstances() error {
+	switch o.ReportMissingInstancesAs {
+	case "", missingAsZero, missingAsNull, missingAsDrop:
+	default:
+		return fmt.Errorf("invalid ReportMissingInstancesAs %q of object %q, expected zero, null or drop", o.ReportMissingInstancesAs, o.ObjectName)
+	}
+	if o.MissingInstanceTimeout < 0 {
+		return fmt.Errorf("MissingInstanceTimeout of object %q must not be negative", o.ObjectName)
+	}
+	return nil
+}
+
+// missingInstanceTimeout 返回对象持续报告消失实例的时长。
+func (o *ObjectConfig) missingInstanceTimeout() time.Duration {
+	if o.MissingInstanceTimeout > 0 {
+		return time.Duration(o.MissingInstanceTimeout)
+	}
+	return defaultMissingInstanceTimeout
+}
+
+// missingValues 返回实例消失时输出的字段：数值字段按 ReportMissingInstancesAs 置为 0 并保持原来的类型，
+// 或只将浮点字段置为 NaN（整数字段省略，避免同一字段在整数与浮点之间变换类型），
+// 字符串字段（例如计数器路径）保持不变，布尔字段为 false 或省略。
+func missingValues(mode string, fields map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{}, len(fields))
+	for field, value := range fields {
+		switch v := value.(type) {
+		case string:
+			values[field] = v
+		case bool:
+			if mode == missingAsZero {
+				values[field] = false
+			}
+		case float64, float32:
+			if mode == missingAsZero {
+				values[field] = float64(0)
+			} else {
+				values[field] = math.NaN()
+			}
+		case int64:
+			if mode == missingAsZero {
+				values[field] = int64(0)
+			}
+		case uint64:
+			if mode == missingAsZero {
+				values[field] = uint64(0)
+			}
+		default:
+			if _, ok := toFloat(v); ok && mode == missingAsZero {
+				values[field] = float64(0)
+			}
+		}
+	}
+	return values
+}
+
+// missingSeries 一条启用了 ReportMissingInstancesAs 的序列。
+type missingSeries struct {
+	// id 序列所属对象的标识。
+	id objectID
+	// drop 对象的 ReportMissingInstancesAs 为 drop。
+	drop bool
+	// timeout 对象的 MissingInstanceTimeout。
+	timeout     time.Duration
+	measurement string
+	tags        map[string]string
+	values      map[string]interface{}
+	// lastSeen 最近一次采集到该序列的时间。
+	lastSeen time.Time
+}
+
+// missingTracker 按对象的标识记录各主机上启用了 ReportMissingInstancesAs 的对象出现过的序列，跨越多次采集与计数器刷新。
+type missingTracker struct {
+	lock  sync.Mutex
+	hosts map[string]map[string]*missingSeries
+}
+
+// update 记录本次采集中出现的序列，返回本次已采集的对象中消失且未超过 MissingInstanceTimeout 的序列，
+// 超时的序列不再报告。
+func (t *missingTracker) update(computer string, due dueObjects, seen seenSeries, now time.Time) []*missingSeries {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.hosts == nil {
+		t.hosts = make(map[string]map[string]*missingSeries)
+	}
+	series, ok := t.hosts[computer]
+	if !ok {
+		series = make(map[string]*missingSeries)
+		t.hosts[computer] = series
+	}
+
+	present := make(map[string]bool)
+	for object, objectSeries := range seen {
+		if object == nil || object.ReportMissingInstancesAs == "" {
+			continue
+		}
+		for key, s := range objectSeries {
+			present[key] = true
+			series[key] = &missingSeries{
+				id:          object.id,
+				drop:        object.ReportMissingInstancesAs == missingAsDrop,
+				timeout:     object.missingInstanceTimeout(),
+				measurement: s.measurement,
+				tags:        s.tags,
+				values:      s.missing,
+				lastSeen:    now,
+			}
+		}
+	}
+
+	var missing []*missingSeries
+	for key, s := range series {
+		if present[key] || !due.has(s.id) {
+			continue
+		}
+		if s.drop || now.Sub(s.lastSeen) > s.timeout {
+			delete(series, key)
+			continue
+		}
+		missing = append(missing, s)
+	}
+	return missing
+}
+
+// retain 只保留 ids 中的对象的序列，Reload 后配置未变化的对象继续报告之前消失的实例。
+func (t *missingTracker) retain(ids map[objectID]bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, series := range t.hosts {
+		for key, s := range series {
+			if !ids[s.id] {
+				delete(series, key)
+			}
+		}
+	}
+}
+
+// applyInstanceState 为启用了 ReportMissingInstancesAs 的对象添加 instance_state=present 标签。
+func applyInstanceState(object *ObjectConfig, tags map[string]string) {
+	if object != nil && object.ReportMissingInstancesAs != "" {
+		tags[instanceStateTag] = instancePresent
+	}
+}
+
+// reportMissingInstances 为本次采集中消失的实例按 ReportMissingInstancesAs 输出 instance_state=missing 的数据，
+// 使下游告警可以区分 "值为 0" 与 "实例已消失"。实例重新出现或超过 MissingInstanceTimeout 后不再输出。
+func (m *WinPerfCounters) reportMissingInstances(computer string, due dueObjects, seen seenSeries, timestamp time.Time) {
+	for _, series := range m.missing.update(computer, due, seen, timestamp) {
+		if len(series.values) == 0 {
+			continue
+		}
+		tags := maps.Clone(series.tags)
+		tags[instanceStateTag] = instanceMissing
+		m.emit(series.measurement, maps.Clone(series.values), tags, timestamp)
+	}
+}
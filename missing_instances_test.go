@@ -0,0 +1,82 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissingValues(t *testing.T) {
+	fields := map[string]interface{}{
+		"ratio":   0.5,
+		"small":   float32(1.5),
+		"count":   int64(3),
+		"bytes":   uint64(4),
+		"handles": int32(5),
+		"path":    `\Process(w3wp)\Working Set`,
+		"ok":      true,
+	}
+
+	require.Equal(t, map[string]interface{}{
+		"ratio":   float64(0),
+		"small":   float64(0),
+		"count":   int64(0),
+		"bytes":   uint64(0),
+		"handles": float64(0),
+		"path":    `\Process(w3wp)\Working Set`,
+		"ok":      false,
+	}, missingValues(missingAsZero, fields))
+
+	values := missingValues(missingAsNull, fields)
+	require.Len(t, values, 3)
+	require.True(t, math.IsNaN(values["ratio"].(float64)))
+	require.True(t, math.IsNaN(values["small"].(float64)))
+	require.Equal(t, `\Process(w3wp)\Working Set`, values["path"])
+}
+
+func TestMissingTrackerUpdate(t *testing.T) {
+	objects := []ObjectConfig{{ObjectName: "Process", ReportMissingInstancesAs: missingAsZero, MissingInstanceTimeout: Duration(time.Minute)}}
+	require.NoError(t, assignObjectIDs(objects))
+	seenOf := func(object *ObjectConfig, instances ...string) seenSeries {
+		seen := make(seenSeries)
+		for _, instance := range instances {
+			seen.add(object, "win_proc", map[string]interface{}{"value": 1.0}, map[string]string{"instance": instance}, false)
+		}
+		return seen
+	}
+	due := make(dueObjects)
+	due.add(&objects[0])
+
+	var tracker missingTracker
+	now := time.Now()
+	require.Empty(t, tracker.update("local", due, seenOf(&objects[0], "a", "b"), now))
+	missing := tracker.update("local", due, seenOf(&objects[0], "a"), now.Add(time.Second))
+	require.Len(t, missing, 1)
+	require.Equal(t, "b", missing[0].tags["instance"])
+	require.Equal(t, map[string]interface{}{"value": float64(0)}, missing[0].values)
+
+	// 本次未采集的对象不报告
+	require.Empty(t, tracker.update("local", make(dueObjects), make(seenSeries), now.Add(2*time.Second)))
+
+	// Reload 重新分配对象后，配置未变化的对象继续报告之前消失的实例
+	reloaded := slices.Clone(objects)
+	require.NoError(t, assignObjectIDs(reloaded))
+	tracker.retain(objectIDSet(reloaded))
+	due = make(dueObjects)
+	due.add(&reloaded[0])
+	missing = tracker.update("local", due, seenOf(&reloaded[0], "a"), now.Add(3*time.Second))
+	require.Len(t, missing, 1)
+	require.Equal(t, "b", missing[0].tags["instance"])
+
+	// 超过 MissingInstanceTimeout 后不再报告
+	require.Empty(t, tracker.update("local", due, seenOf(&reloaded[0], "a"), now.Add(2*time.Minute)))
+
+	// 已删除或被修改的对象的序列被丢弃
+	tracker.retain(map[objectID]bool{})
+	require.Empty(t, tracker.update("local", due, make(seenSeries), now.Add(2*time.Minute)))
+}
@@ -0,0 +1,65 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"strings"
+)
+
+// MSMQ Queue 实例对应的队列类型。
+const (
+	msmqQueuePrivate  = "private"
+	msmqQueuePublic   = "public"
+	msmqQueueOutgoing = "outgoing"
+	msmqQueueSystem   = "system"
+)
+
+// msmqComputerQueues MSMQ Queue 对象中汇总本机所有队列的实例。
+const msmqComputerQueues = "Computer Queues"
+
+// parseMSMQQueue 将 MSMQ Queue 的实例名称解析为队列名称和队列类型，例如
+// "host\private$\orders" 解析为 "orders" 与 "private"，"host\orders" 解析为 "orders" 与 "public"，
+// "DIRECT=OS:host\private$\orders" 等出站队列解析为 "host\private$\orders" 与 "outgoing"。
+// 汇总实例 "Computer Queues" 以及以 "$" 结尾的系统队列（例如 "admin_queue$"）的类型为 "system"。
+func parseMSMQQueue(instance string) (name, queueType string) {
+	if strings.EqualFold(instance, msmqComputerQueues) {
+		return instance, msmqQueueSystem
+	}
+	if format, path, ok := strings.Cut(instance, "="); ok && !strings.Contains(format, `\`) {
+		// 出站队列的实例名称为格式名，保留目标主机以区分不同主机上的同名队列
+		if _, target, ok := strings.Cut(path, ":"); ok {
+			path = target
+		}
+		return path, msmqQueueOutgoing
+	}
+	parts := strings.Split(instance, `\`)
+	switch {
+	case len(parts) >= 3 && strings.EqualFold(parts[1], "private$"):
+		name, queueType = strings.Join(parts[2:], `\`), msmqQueuePrivate
+	case len(parts) >= 2:
+		name, queueType = strings.Join(parts[1:], `\`), msmqQueuePublic
+	default:
+		name, queueType = instance, msmqQueuePublic
+	}
+	if strings.HasSuffix(name, "$") {
+		queueType = msmqQueueSystem
+	}
+	return name, queueType
+}
+
+// applyMSMQTags 为 MSMQ Queue 的实例添加 queue_name 与 queue_type 标签。
+func applyMSMQTags(_ *ObjectConfig, instance string, tags map[string]string) {
+	if instance == "" {
+		return
+	}
+	tags["queue_name"], tags["queue_type"] = parseMSMQQueue(instance)
+}
+
+// acceptMSMQQueue 在对象启用 ExcludeSystemQueues 时丢弃系统队列和 "Computer Queues" 汇总实例。
+func acceptMSMQQueue(object *ObjectConfig, instance string) bool {
+	if !object.ExcludeSystemQueues || instance == "" {
+		return true
+	}
+	_, queueType := parseMSMQQueue(instance)
+	return queueType != msmqQueueSystem
+}
@@ -0,0 +1,57 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMSMQQueue(t *testing.T) {
+	tests := []struct {
+		instance  string
+		name      string
+		queueType string
+	}{
+		{`host\private$\orders`, "orders", msmqQueuePrivate},
+		{`HOST\PRIVATE$\Orders`, "Orders", msmqQueuePrivate},
+		{`host\orders`, "orders", msmqQueuePublic},
+		{`DIRECT=OS:host\private$\orders`, `host\private$\orders`, msmqQueueOutgoing},
+		{`DIRECT=TCP:10.0.0.5\private$\orders`, `10.0.0.5\private$\orders`, msmqQueueOutgoing},
+		{`PUBLIC=6d2e0a39-1d4f-4f2b-9a0c-0a1b2c3d4e5f`, "6d2e0a39-1d4f-4f2b-9a0c-0a1b2c3d4e5f", msmqQueueOutgoing},
+		{`host\private$\admin_queue$`, "admin_queue$", msmqQueueSystem},
+		{`host\private$\order_queue$`, "order_queue$", msmqQueueSystem},
+		{"Computer Queues", "Computer Queues", msmqQueueSystem},
+		{"computer queues", "computer queues", msmqQueueSystem},
+		// 公共队列名称中的 "=" 不是格式名
+		{`host\a=b`, "a=b", msmqQueuePublic},
+		{"orders", "orders", msmqQueuePublic},
+	}
+	for _, tt := range tests {
+		name, queueType := parseMSMQQueue(tt.instance)
+		require.Equal(t, tt.name, name, tt.instance)
+		require.Equal(t, tt.queueType, queueType, tt.instance)
+	}
+}
+
+func TestAcceptMSMQQueue(t *testing.T) {
+	object := &ObjectConfig{ExcludeSystemQueues: true}
+	require.True(t, acceptMSMQQueue(object, `host\private$\orders`))
+	require.True(t, acceptMSMQQueue(object, `DIRECT=OS:host\private$\orders`))
+	require.True(t, acceptMSMQQueue(object, ""))
+	require.False(t, acceptMSMQQueue(object, `host\private$\admin_queue$`))
+	require.False(t, acceptMSMQQueue(object, "Computer Queues"))
+
+	require.True(t, acceptMSMQQueue(&ObjectConfig{}, "Computer Queues"))
+}
+
+func TestApplyMSMQTags(t *testing.T) {
+	tags := map[string]string{"instance": `host\private$\orders`}
+	applyMSMQTags(nil, `host\private$\orders`, tags)
+	require.Equal(t, map[string]string{"instance": `host\private$\orders`, "queue_name": "orders", "queue_type": "private"}, tags)
+
+	tags = map[string]string{}
+	applyMSMQTags(nil, "", tags)
+	require.Empty(t, tags)
+}
@@ -0,0 +1,51 @@
+//go:build windows
+
+package win_perf_counters
+
+// standardTagKeys 不参与转换的标准标签，TagKeyOverrides 等按这些名称配置。
+var standardTagKeys = map[string]bool{"objectname": true, "instance": true, "source": true}
+
+// initNameSanitizers 按 FieldNameSanitizer 或 NamePolicy 为对象设置名称转换函数，未配置时保持默认的 telegraf 转换，
+// 同时按 UnitSuffixes 设置是否将字段名称中的单位移到末尾。
+func (m *WinPerfCounters) initNameSanitizers(objects []ObjectConfig) error {
+	names, tagKeys, err := m.nameSanitizers()
+	if err != nil {
+		return err
+	}
+	for i := range objects {
+		objects[i].nameSanitizer = names
+		objects[i].tagKeySanitizer = tagKeys
+		objects[i].unitSuffixes = m.UnitSuffixes
+	}
+	return nil
+}
+
+// sanitizeName 按名称转换策略转换测量名称或计数器名称。
+func (o *ObjectConfig) sanitizeName(name string) string {
+	if o.nameSanitizer != nil {
+		return o.nameSanitizer(name)
+	}
+	return sanitizedChars.Replace(name)
+}
+
+// applyTagKeySanitizer 按名称转换策略转换非标准标签（ExtraTags、TagOverrides 等）的名称。
+func applyTagKeySanitizer(object *ObjectConfig, tags map[string]string) {
+	if object == nil || object.tagKeySanitizer == nil {
+		return
+	}
+	var keys []string
+	for key := range tags {
+		if !standardTagKeys[key] {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		if sanitized := object.tagKeySanitizer(key); sanitized != key {
+			value := tags[key]
+			delete(tags, key)
+			if sanitized != "" {
+				tags[sanitized] = value
+			}
+		}
+	}
+}
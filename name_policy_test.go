@@ -0,0 +1,69 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyTagKeySanitizer(t *testing.T) {
+	tests := []struct {
+		name      string
+		sanitizer func(string) string
+		tags      map[string]string
+		want      map[string]string
+	}{
+		{
+			name:      "prometheus",
+			sanitizer: prometheusName,
+			tags:      map[string]string{"instance": "C:", "objectname": "LogicalDisk", "source": "host", "Disk Role": "data"},
+			want:      map[string]string{"instance": "C:", "objectname": "LogicalDisk", "source": "host", "disk_role": "data"},
+		},
+		{
+			name:      "unchanged keys",
+			sanitizer: prometheusName,
+			tags:      map[string]string{"instance": "C:", "site": "east"},
+			want:      map[string]string{"instance": "C:", "site": "east"},
+		},
+		{
+			name:      "keys sanitized to nothing are dropped",
+			sanitizer: prometheusName,
+			tags:      map[string]string{"instance": "C:", "(-)": "x"},
+			want:      map[string]string{"instance": "C:"},
+		},
+		{
+			name:      "custom sanitizer",
+			sanitizer: strings.ToUpper,
+			tags:      map[string]string{"instance": "C:", "site": "east"},
+			want:      map[string]string{"instance": "C:", "SITE": "east"},
+		},
+		{
+			name: "no sanitizer",
+			tags: map[string]string{"instance": "C:", "Disk Role": "data"},
+			want: map[string]string{"instance": "C:", "Disk Role": "data"},
+		},
+	}
+	for _, tt := range tests {
+		applyTagKeySanitizer(&ObjectConfig{tagKeySanitizer: tt.sanitizer}, tt.tags)
+		require.Equal(t, tt.want, tt.tags, tt.name)
+	}
+
+	tags := map[string]string{"Disk Role": "data"}
+	applyTagKeySanitizer(nil, tags)
+	require.Equal(t, map[string]string{"Disk Role": "data"}, tags)
+}
+
+func TestInitNameSanitizers(t *testing.T) {
+	objects := []ObjectConfig{{ObjectName: "Processor"}, {ObjectName: "Memory"}}
+	m := &WinPerfCounters{NamePolicy: "prometheus", UnitSuffixes: true}
+	require.NoError(t, m.initNameSanitizers(objects))
+	for _, object := range objects {
+		require.Equal(t, "processor_time_percent", object.fieldName("% Processor Time"), object.ObjectName)
+		require.True(t, object.unitSuffixes, object.ObjectName)
+	}
+
+	require.ErrorContains(t, (&WinPerfCounters{NamePolicy: "bogus"}).initNameSanitizers(objects), `invalid NamePolicy "bogus"`)
+}
@@ -0,0 +1,35 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// nameRetryDelay 第一次重试解析计数器名称前的平均等待时间，之后每次加倍。
+const nameRetryDelay = 200 * time.Millisecond
+
+// isNameResolutionError 判断错误是否为对象或计数器名称无法解析，服务刚启动或性能库重建期间可能暂时出现。
+func isNameResolutionError(err error) bool {
+	var pdhErr *pdhError
+	return errors.As(err, &pdhErr) && (pdhErr.errorCode == pdhCstatusNoCountername ||
+		pdhErr.errorCode == pdhCstatusNoCounter ||
+		pdhErr.errorCode == pdhCstatusNoObject)
+}
+
+// retryNameResolution 调用 call，名称无法解析时按 NameRetries 重试，等待时间按指数增长并带有随机抖动，
+// 避免多个采集器在性能库重建后同时重试。
+func (m *WinPerfCounters) retryNameResolution(counterPath string, call func() error) error {
+	err := call()
+	for attempt := 0; attempt < m.NameRetries && isNameResolutionError(err); attempt++ {
+		delay := nameRetryDelay << attempt
+		delay = delay/2 + rand.N(delay) //nolint:gosec // G404: jitter does not need a secure random source
+		m.Log.Debugf("Resolving counter path %q failed, retrying in %v: %v", counterPath, delay, err)
+		m.stats.incr(map[string]string{}, "name_retries", 1)
+		time.Sleep(delay)
+		err = call()
+	}
+	return err
+}
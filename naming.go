@@ -0,0 +1,48 @@
+//go:build windows
+
+package win_perf_counters
+
+// fieldName 返回计数器对应的字段名称：配置了 CounterAliases 时使用别名，
+// 否则为按名称转换策略处理的计数器名称（启用 UnitSuffixes 时单位移到末尾），采集原始值时追加 "_Raw" 后缀。
+func (o *ObjectConfig) fieldName(counterName string) string {
+	if alias, ok := o.CounterAliases[counterName]; ok {
+		return alias
+	}
+	if o.unitSuffixes {
+		counterName = unitSuffixed(counterName)
+	}
+	name := o.sanitizeName(counterName)
+	if o.UseRawValues {
+		name += "_Raw"
+	}
+	return name
+}
+
+// applyNaming 按对象的 CounterAliases 与 NameOverride 设置计数器的字段名称和测量名称。
+func (o *ObjectConfig) applyNaming(c *counter) {
+	if o == nil {
+		return
+	}
+	if o.nameSanitizer != nil || o.unitSuffixes {
+		c.counter = o.fieldName(c.name)
+		if c.measurement = o.sanitizeName(o.Measurement); c.measurement == "" {
+			c.measurement = "win_perf_counters"
+		}
+	}
+	if alias, ok := o.CounterAliases[c.name]; ok {
+		c.counter = alias
+	}
+	if o.NameOverride != "" {
+		c.measurement = o.NameOverride
+	}
+}
+
+// applyTagOverrides 使用对象的 TagOverrides 添加或覆盖标签。
+func applyTagOverrides(object *ObjectConfig, tags map[string]string) {
+	if object == nil {
+		return
+	}
+	for key, value := range object.TagOverrides {
+		tags[key] = value
+	}
+}
@@ -0,0 +1,50 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+)
+
+// objectOutcome 一个主机本次采集中每个对象的样本数和读取错误，用于 OnObjectGathered。
+type objectOutcome struct {
+	samples map[*ObjectConfig]int
+	errs    map[*ObjectConfig][]error
+}
+
+// sampled 记录对象采集到一个样本。
+func (o *objectOutcome) sampled(object *ObjectConfig) {
+	if o.samples == nil {
+		o.samples = make(map[*ObjectConfig]int)
+	}
+	o.samples[object]++
+}
+
+// failed 记录对象的一个读取错误。
+func (o *objectOutcome) failed(object *ObjectConfig, err error) {
+	if o.errs == nil {
+		o.errs = make(map[*ObjectConfig][]error)
+	}
+	o.errs[object] = append(o.errs[object], err)
+}
+
+// notifyObjectsGathered 为主机本次需要采集的每个对象调用一次 OnObjectGathered。hostErr 不为 nil 时
+// （CollectQueryData 失败或采集超时）所有对象都以该错误通知，样本数为 0；outcome 为 nil 时样本数同样为 0。
+func (m *WinPerfCounters) notifyObjectsGathered(hostInfo *hostCountersInfo, due dueObjects, outcome *objectOutcome, hostErr error) {
+	if m.OnObjectGathered == nil {
+		return
+	}
+	notified := make(map[*ObjectConfig]bool)
+	for _, c := range hostInfo.counters {
+		object := c.object
+		if object == nil || !due.contains(object) || notified[object] {
+			continue
+		}
+		notified[object] = true
+		if hostErr != nil || outcome == nil {
+			m.OnObjectGathered(hostInfo.computer, object.ObjectName, 0, hostErr)
+			continue
+		}
+		m.OnObjectGathered(hostInfo.computer, object.ObjectName, outcome.samples[object], errors.Join(outcome.errs[object]...))
+	}
+}
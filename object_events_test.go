@@ -0,0 +1,118 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource 是测试用的 QuerySource，每个计数器都返回 value，collectErr 或 expandErr 不为 nil 时采集或展开失败。
+type fakeSource struct {
+	lock       sync.Mutex
+	value      float64
+	collectErr error
+	// collectPanic 不为 nil 时 Collect 以该值 panic
+	collectPanic interface{}
+	expandErr    error
+	collects     int
+}
+
+func (s *fakeSource) Expand(counterPath string) ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.expandErr != nil {
+		return nil, s.expandErr
+	}
+	return []string{counterPath}, nil
+}
+
+func (s *fakeSource) Collect() (time.Time, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.collects++
+	if s.collectPanic != nil {
+		panic(s.collectPanic)
+	}
+	return time.Now(), s.collectErr
+}
+
+func (s *fakeSource) Value(string) (float64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.value, nil
+}
+
+func (s *fakeSource) failCollect(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.collectErr = err
+}
+
+func (s *fakeSource) panicCollect(value interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.collectPanic = value
+}
+
+func (s *fakeSource) failExpand(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.expandErr = err
+}
+
+// newFakeSourcePlugin 创建采集 Processor(_Total) 的插件，所有主机都由 source 提供数据。
+func newFakeSourcePlugin(source *fakeSource) *WinPerfCounters {
+	m := NewWinPerfCounters(func(string, map[string]interface{}, map[string]string, time.Time) {})
+	m.WithQueryCreator(func(string) QuerySource { return source })
+	m.Object = []ObjectConfig{{
+		ObjectName:  "Processor",
+		Counters:    []string{"% Processor Time"},
+		Instances:   []string{"_Total"},
+		Measurement: "win_cpu",
+	}}
+	return m
+}
+
+func TestOnObjectGatheredIgnoredCollectError(t *testing.T) {
+	source := &fakeSource{value: 42}
+	m := newFakeSourcePlugin(source)
+	m.IgnoredErrors = []string{"PDH_NO_DATA"}
+
+	type notification struct {
+		samples int
+		err     error
+	}
+	var lock sync.Mutex
+	var notifications []notification
+	m.OnObjectGathered = func(_, objectName string, samples int, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		require.Equal(t, "Processor", objectName)
+		notifications = append(notifications, notification{samples: samples, err: err})
+	}
+	require.NoError(t, m.Init())
+	t.Cleanup(func() { _ = m.Close() })
+
+	require.NoError(t, m.Gather())
+	source.failCollect(newPdhError(pdhNoData))
+	require.NoError(t, m.Gather())
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Len(t, notifications, 2)
+	require.Equal(t, notification{samples: 1}, notifications[0])
+	require.Equal(t, notification{}, notifications[1])
+
+	// 被忽略的收集错误只计数一次
+	var ignored int64
+	for _, series := range m.stats.series {
+		if value, ok := series.fields["ignored_errors"].(int64); ok {
+			ignored += value
+		}
+	}
+	require.Equal(t, int64(1), ignored)
+}
@@ -0,0 +1,54 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+)
+
+// objectID 对象配置的稳定标识，由生效的对象配置计算得到。Reload 或预置展开重新分配对象后，
+// 配置相同的对象保持相同的标识，跨越多次采集的状态按标识记录，不会因对象的地址变化而失效。
+type objectID string
+
+// assignObjectIDs 为 objects 中的对象计算标识，配置完全相同的对象按出现的顺序追加序号加以区分。
+func assignObjectIDs(objects []ObjectConfig) error {
+	seen := make(map[objectID]int, len(objects))
+	for i := range objects {
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(&objects[i]); err != nil {
+			return fmt.Errorf("computing identity of object %q failed: %w", objects[i].ObjectName, err)
+		}
+		sum := sha256.Sum256(buf.Bytes())
+		id := objectID(hex.EncodeToString(sum[:16]))
+		if n := seen[id]; n > 0 {
+			objects[i].id = id + objectID("#"+strconv.Itoa(n))
+		} else {
+			objects[i].id = id
+		}
+		seen[id]++
+	}
+	return nil
+}
+
+// identity 返回对象的标识，未关联对象配置时返回空标识。
+func (o *ObjectConfig) identity() objectID {
+	if o == nil {
+		return ""
+	}
+	return o.id
+}
+
+// objectIDSet 返回 objects 中对象的标识。
+func objectIDSet(objects []ObjectConfig) map[objectID]bool {
+	ids := make(map[objectID]bool, len(objects))
+	for i := range objects {
+		ids[objects[i].id] = true
+	}
+	return ids
+}
@@ -0,0 +1,82 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssignObjectIDs(t *testing.T) {
+	objects := []ObjectConfig{
+		{ObjectName: "Processor", Counters: []string{"% Processor Time"}, Instances: []string{"_Total"}},
+		{ObjectName: "Memory", Counters: []string{"Available Bytes"}, Instances: []string{emptyInstance}},
+		{ObjectName: "Processor", Counters: []string{"% Processor Time"}, Instances: []string{"_Total"}},
+	}
+	require.NoError(t, assignObjectIDs(objects))
+	require.NotEmpty(t, objects[0].id)
+	require.NotEqual(t, objects[0].id, objects[1].id)
+	// 配置相同的对象按出现的顺序区分
+	require.Equal(t, objects[0].id+"#1", objects[2].id)
+
+	// 重新分配后配置相同的对象标识不变
+	reloaded := slices.Clone(objects)
+	reloaded[1].Counters = []string{"Available Bytes", "Committed Bytes"}
+	require.NoError(t, assignObjectIDs(reloaded))
+	require.Equal(t, objects[0].id, reloaded[0].id)
+	require.Equal(t, objects[2].id, reloaded[2].id)
+	require.NotEqual(t, objects[1].id, reloaded[1].id)
+
+	ids := objectIDSet(reloaded)
+	require.Len(t, ids, 3)
+	require.False(t, ids[objects[1].id])
+}
+
+func TestDueObjectsAfterReload(t *testing.T) {
+	m := NewWinPerfCounters(func(string, map[string]interface{}, map[string]string, time.Time) {})
+	m.Object = []ObjectConfig{
+		{ObjectName: "Processor", Counters: []string{"% Processor Time"}, Instances: []string{"_Total"}, Interval: Duration(time.Minute)},
+		{ObjectName: "Memory", Counters: []string{"Available Bytes"}, Instances: []string{emptyInstance}, Interval: Duration(time.Minute)},
+	}
+	require.NoError(t, assignObjectIDs(m.Object))
+	now := time.Now()
+	require.Len(t, m.dueObjects(0, now), 2)
+
+	// Reload 重新分配对象后，未修改的对象继续按上一次的采集时间计算间隔
+	reloaded := slices.Clone(m.Object)
+	reloaded[1].Counters = []string{"Committed Bytes"}
+	require.NoError(t, assignObjectIDs(reloaded))
+	m.pendingReload = &reloadConfig{Object: reloaded}
+	replaced, ok := m.applyReload()
+	require.True(t, ok)
+	require.Len(t, replaced, 2)
+	require.Len(t, m.lastGathered, 1)
+
+	due := m.dueObjects(1, now.Add(time.Second))
+	require.Len(t, due, 1)
+	require.True(t, due.contains(&m.Object[1]))
+	require.False(t, due.contains(&m.Object[0]))
+	// 被替换的对象与配置相同的新对象是同一个对象
+	require.False(t, due.contains(&replaced[0]))
+	require.True(t, due.contains(nil))
+}
+
+func TestDueObjectsWithout(t *testing.T) {
+	objects := []ObjectConfig{{ObjectName: "A"}, {ObjectName: "B"}}
+	require.NoError(t, assignObjectIDs(objects))
+	due := make(dueObjects)
+	due.add(&objects[0])
+	due.add(&objects[1])
+	due.add(nil)
+	require.Len(t, due, 2)
+
+	failed := make(dueObjects)
+	failed.add(&objects[1])
+	rest := due.without(failed)
+	require.True(t, rest.contains(&objects[0]))
+	require.False(t, rest.contains(&objects[1]))
+	require.Len(t, due, 2)
+}
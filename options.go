@@ -0,0 +1,107 @@
+package win_perf_counters
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// ConfigFormat 配置文件的格式。
+type ConfigFormat int
+
+const (
+	// ConfigTOML TOML 格式，与 Telegraf 插件的配置相同。
+	ConfigTOML ConfigFormat = iota
+	// ConfigYAML YAML 格式。
+	ConfigYAML
+	// ConfigJSON JSON 格式。
+	ConfigJSON
+)
+
+// Size is an int64
+type Size int64
+
+var defaultMaxBufferSize = Size(100 * 1024 * 1024)
+
+// Options 描述插件的全局配置项，用于在代码中构造配置而无需编写 TOML。
+// 应从 DefaultOptions 返回的默认值开始修改，未列出的配置项可在 New 返回后直接设置 WinPerfCounters 的同名字段。
+type Options struct {
+	// Sources 数据源主机列表，为空时采集本机。
+	Sources []string
+	// Objects 需要采集的性能对象。
+	Objects []ObjectConfig
+	// Presets 内置预置的名称列表，每个预置展开为一个或多个对象。
+	Presets []string
+	// PrintValid 是否打印有效的计数器路径。
+	PrintValid bool
+	// UsePerfCounterTime 是否使用性能计数器的时间戳。
+	UsePerfCounterTime bool
+	// UseWildcardsExpansion 是否启用通配符展开。
+	UseWildcardsExpansion bool
+	// LocalizeWildcardsExpansion 是否本地化通配符展开，默认为 true。
+	LocalizeWildcardsExpansion bool
+	// TranslateObjectName 本地化通配符展开时是否将 objectname 标签翻译为英文。
+	TranslateObjectName bool
+	// TwoPhaseRefresh 刷新计数器时是否先在后台准备新的计数器集合。
+	TwoPhaseRefresh bool
+	// CountersRefreshInterval 性能计数器刷新间隔，默认为 1 分钟。
+	CountersRefreshInterval time.Duration
+	// IgnoredErrors 需要忽略的 PDH 错误名称。
+	IgnoredErrors []string
+	// MaxBufferSize 最大缓冲区大小，默认为 100MiB。
+	MaxBufferSize Size
+	// Interval 调度器中未配置 Interval 的对象的默认采集间隔。
+	Interval time.Duration
+	// CollectTimeout 每个主机单次采集的超时时间，为 0 时不限制。
+	CollectTimeout time.Duration
+	// History 在内存中保留历史样本的时长，为 0 时不保留。
+	History time.Duration
+	// SelfMetrics 是否输出插件自身的运行状态指标。
+	SelfMetrics bool
+	// CounterLanguage 不支持 AddEnglishCounter 时用于翻译名称的词典语言。
+	CounterLanguage string
+	// Simulate 是否使用合成数据代替真实的性能计数器。
+	Simulate bool
+	// QueryCreator 不为 nil 时使用其创建的 QuerySource 代替 PDH 查询，参见 WithQueryCreator。
+	QueryCreator QueryCreator
+}
+
+// DefaultOptions 返回与 NewWinPerfCounters 默认值一致的 Options。
+func DefaultOptions() Options {
+	return Options{
+		LocalizeWildcardsExpansion: true,
+		CountersRefreshInterval:    time.Minute,
+		MaxBufferSize:              defaultMaxBufferSize,
+	}
+}
+
+// NewObjectConfig 创建采集 objectName 对象中 counters 计数器的配置，未指定 instances 时采集所有实例（"*"）。
+func NewObjectConfig(objectName string, counters []string, instances ...string) ObjectConfig {
+	if len(instances) == 0 {
+		instances = []string{"*"}
+	}
+	return ObjectConfig{
+		ObjectName: objectName,
+		Counters:   counters,
+		Instances:  instances,
+	}
+}
+
+// regexInstancePrefix 标记 Instances 与 InstancesExclude 中的正则表达式条目。
+const regexInstancePrefix = "re:"
+
+// isWildcardInstance 判断实例名称是否为通配符或正则表达式。
+func isWildcardInstance(instance string) bool {
+	return strings.ContainsAny(instance, "*?") || strings.HasPrefix(instance, regexInstancePrefix)
+}
+
+// applyTotalOnly 将启用 TotalOnly 且实例中包含通配符的对象的实例改为只有 _Total，
+// 单实例对象以及只列出具体实例名称的对象保持不变。
+func (o *ObjectConfig) applyTotalOnly() {
+	if !o.TotalOnly || !slices.ContainsFunc(o.Instances, isWildcardInstance) {
+		return
+	}
+	o.Instances = []string{"_Total"}
+	o.InstancesExclude = nil
+	o.IncludeTotal = true
+}
@@ -0,0 +1,61 @@
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyTotalOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		object  ObjectConfig
+		want    []string
+		exclude []string
+	}{
+		{
+			name:   "disabled",
+			object: ObjectConfig{Instances: []string{"*"}, InstancesExclude: []string{"C:"}},
+			want:   []string{"*"}, exclude: []string{"C:"},
+		},
+		{
+			name:   "wildcard",
+			object: ObjectConfig{Instances: []string{"*"}, InstancesExclude: []string{"C:"}, TotalOnly: true},
+			want:   []string{"_Total"},
+		},
+		{
+			name:   "pattern",
+			object: ObjectConfig{Instances: []string{"C:", "Disk?"}, TotalOnly: true},
+			want:   []string{"_Total"},
+		},
+		{
+			name:   "regular expression",
+			object: ObjectConfig{Instances: []string{"re:^sql"}, TotalOnly: true},
+			want:   []string{"_Total"},
+		},
+		{
+			name:   "single instance",
+			object: ObjectConfig{Instances: []string{emptyInstance}, TotalOnly: true},
+			want:   []string{emptyInstance},
+		},
+		{
+			name:   "explicit instances",
+			object: ObjectConfig{Instances: []string{"C:", "D:"}, InstancesExclude: []string{"D:"}, TotalOnly: true},
+			want:   []string{"C:", "D:"}, exclude: []string{"D:"},
+		},
+		{
+			name:   "no instances",
+			object: ObjectConfig{TotalOnly: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object := tt.object
+			object.applyTotalOnly()
+			require.Equal(t, tt.want, object.Instances)
+			require.Equal(t, tt.exclude, object.InstancesExclude)
+			rewritten := len(tt.want) == 1 && tt.want[0] == "_Total"
+			require.Equal(t, rewritten, object.IncludeTotal)
+		})
+	}
+}
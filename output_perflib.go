@@ -0,0 +1,269 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// PerfLib V2 constants, taken from perflib.h and winperf.h
+const (
+	perfCountersetMultiInstances = 2
+	perfCounterLargeRawcount     = 0x00010100 // PERF_SIZE_LARGE | PERF_TYPE_NUMBER | PERF_NUMBER_DECIMAL
+	perfDetailNovice             = 100
+)
+
+var (
+	// Library
+	libAdvapiDll *syscall.DLL
+
+	// Functions
+	perfStartProviderProc            *syscall.Proc
+	perfStopProviderProc             *syscall.Proc
+	perfSetCounterSetInfoProc        *syscall.Proc
+	perfCreateInstanceProc           *syscall.Proc
+	perfDeleteInstanceProc           *syscall.Proc
+	perfSetULongLongCounterValueProc *syscall.Proc
+)
+
+func init() {
+	libAdvapiDll = syscall.MustLoadDLL("advapi32.dll")
+
+	perfStartProviderProc, _ = libAdvapiDll.FindProc("PerfStartProvider")
+	perfStopProviderProc, _ = libAdvapiDll.FindProc("PerfStopProvider")
+	perfSetCounterSetInfoProc, _ = libAdvapiDll.FindProc("PerfSetCounterSetInfo")
+	perfCreateInstanceProc, _ = libAdvapiDll.FindProc("PerfCreateInstance")
+	perfDeleteInstanceProc, _ = libAdvapiDll.FindProc("PerfDeleteInstance")
+	perfSetULongLongCounterValueProc, _ = libAdvapiDll.FindProc("PerfSetULongLongCounterValue")
+}
+
+var errPerfLibNotSupported = errors.New("PerfLib V2 provider API is not supported on this system")
+
+// perfCountersetInfo mirrors PERF_COUNTERSET_INFO
+type perfCountersetInfo struct {
+	CounterSetGUID windows.GUID
+	ProviderGUID   windows.GUID
+	NumCounters    uint32
+	InstanceType   uint32
+}
+
+// perfCounterInfo mirrors PERF_COUNTER_INFO
+type perfCounterInfo struct {
+	CounterID   uint32
+	Type        uint32
+	Attrib      uint64
+	Size        uint32
+	DetailLevel uint32
+	Scale       int32
+	Offset      uint32
+}
+
+// PerfCounterPublisher 通过 PerfLib V2 提供程序 API 将指标发布为本机的自定义性能计数器，
+// 发布后的计数器可在 perfmon 以及其它 PDH 使用方中看到。
+type PerfCounterPublisher struct {
+	config       PerfCounterPublisherConfig
+	counterSetID windows.GUID
+	provider     windows.Handle
+
+	lock      sync.Mutex
+	instances map[string]uintptr
+	nextID    uint32
+}
+
+// NewPerfCounterPublisher 启动提供程序并注册计数器集合。
+func NewPerfCounterPublisher(config PerfCounterPublisherConfig) (*PerfCounterPublisher, error) {
+	if perfStartProviderProc == nil || perfSetULongLongCounterValueProc == nil {
+		return nil, errPerfLibNotSupported
+	}
+	if len(config.Counters) == 0 {
+		return nil, errors.New("no counters configured for publishing")
+	}
+	if config.InstanceTag == "" {
+		config.InstanceTag = "instance"
+	}
+
+	providerID, err := windows.GUIDFromString(config.ProviderGUID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid provider GUID %q: %w", config.ProviderGUID, err)
+	}
+	counterSetID, err := windows.GUIDFromString(config.CounterSetGUID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid counter set GUID %q: %w", config.CounterSetGUID, err)
+	}
+
+	p := &PerfCounterPublisher{
+		config:       config,
+		counterSetID: counterSetID,
+		instances:    make(map[string]uintptr),
+	}
+	ret, _, _ := perfStartProviderProc.Call(
+		uintptr(unsafe.Pointer(&providerID)), //nolint:gosec // G103: Valid use of unsafe call to pass providerID
+		0,
+		uintptr(unsafe.Pointer(&p.provider))) //nolint:gosec // G103: Valid use of unsafe call to pass provider
+	if ret != errorSuccess {
+		return nil, fmt.Errorf("starting perf provider failed: %w", syscall.Errno(ret))
+	}
+
+	template, err := counterSetTemplate(providerID, counterSetID, config.Counters)
+	if err != nil {
+		p.stop()
+		return nil, err
+	}
+	ret, _, _ = perfSetCounterSetInfoProc.Call(
+		uintptr(p.provider),
+		uintptr(unsafe.Pointer(&template[0])), //nolint:gosec // G103: Valid use of unsafe call to pass template
+		uintptr(len(template)))
+	if ret != errorSuccess {
+		p.stop()
+		return nil, fmt.Errorf("setting counter set info failed: %w", syscall.Errno(ret))
+	}
+	return p, nil
+}
+
+// counterSetTemplate 构造 PerfSetCounterSetInfo 所需的 PERF_COUNTERSET_INFO 及其后续的 PERF_COUNTER_INFO 数组。
+// 每个计数器占用实例数据块中的 8 字节。
+func counterSetTemplate(providerID, counterSetID windows.GUID, counters map[string]uint32) ([]byte, error) {
+	var buf bytes.Buffer
+	header := perfCountersetInfo{
+		CounterSetGUID: counterSetID,
+		ProviderGUID:   providerID,
+		NumCounters:    uint32(len(counters)),
+		InstanceType:   perfCountersetMultiInstances,
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, header); err != nil {
+		return nil, err
+	}
+	var offset uint32
+	for _, id := range counters {
+		info := perfCounterInfo{
+			CounterID:   id,
+			Type:        perfCounterLargeRawcount,
+			Size:        8,
+			DetailLevel: perfDetailNovice,
+			Offset:      offset,
+		}
+		if err := binary.Write(&buf, binary.LittleEndian, info); err != nil {
+			return nil, err
+		}
+		offset += 8
+	}
+	return buf.Bytes(), nil
+}
+
+// Collect 更新匹配测量的计数器值，可直接作为 CollectFunc 注册。
+func (p *PerfCounterPublisher) Collect(measurement string, fields map[string]interface{}, tags map[string]string, _ time.Time) {
+	if measurement != p.config.Measurement {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.provider == 0 {
+		return
+	}
+	instance, err := p.instance(tags[p.config.InstanceTag])
+	if err != nil {
+		return
+	}
+	for field, id := range p.config.Counters {
+		value, ok := fields[field]
+		if !ok {
+			continue
+		}
+		args := append([]uintptr{uintptr(p.provider), instance, uintptr(id)}, ulongLongArgs(toRawCounterValue(value))...)
+		_, _, _ = perfSetULongLongCounterValueProc.Call(args...)
+	}
+}
+
+// instance 返回指定名称的计数器实例，不存在时创建，调用方需持有 lock。
+func (p *PerfCounterPublisher) instance(name string) (uintptr, error) {
+	if name == "" {
+		name = "_Default"
+	}
+	if instance, ok := p.instances[name]; ok {
+		return instance, nil
+	}
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+	instance, _, callErr := perfCreateInstanceProc.Call(
+		uintptr(p.provider),
+		uintptr(unsafe.Pointer(&p.counterSetID)), //nolint:gosec // G103: Valid use of unsafe call to pass counterSetID
+		uintptr(unsafe.Pointer(namePtr)),         //nolint:gosec // G103: Valid use of unsafe call to pass namePtr
+		uintptr(p.nextID))
+	if instance == 0 {
+		return 0, fmt.Errorf("creating counter instance %q failed: %w", name, callErr)
+	}
+	p.nextID++
+	p.instances[name] = instance
+	return instance, nil
+}
+
+// toRawCounterValue 将字段值转换为原始计数器值，负数按 0 处理。
+func toRawCounterValue(value interface{}) uint64 {
+	var f float64
+	switch v := value.(type) {
+	case float64:
+		f = v
+	case float32:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case int32:
+		f = float64(v)
+	case int:
+		f = float64(v)
+	case uint64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	default:
+		return 0
+	}
+	if f <= 0 || math.IsNaN(f) {
+		return 0
+	}
+	if f >= math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return uint64(math.Round(f))
+}
+
+// Close 删除所有计数器实例并停止提供程序。
+func (p *PerfCounterPublisher) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.provider == 0 {
+		return nil
+	}
+	for name, instance := range p.instances {
+		_, _, _ = perfDeleteInstanceProc.Call(uintptr(p.provider), instance)
+		delete(p.instances, name)
+	}
+	return p.stop()
+}
+
+// stop 停止提供程序。
+func (p *PerfCounterPublisher) stop() error {
+	ret, _, _ := perfStopProviderProc.Call(uintptr(p.provider))
+	p.provider = 0
+	if ret != errorSuccess {
+		return fmt.Errorf("stopping perf provider failed: %w", syscall.Errno(ret))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"math"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestToRawCounterValue(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  uint64
+	}{
+		{12.4, 12},
+		{12.5, 13},
+		{float32(3), 3},
+		{int64(7), 7},
+		{int32(8), 8},
+		{9, 9},
+		{uint64(math.MaxUint64), math.MaxUint64},
+		{-5.0, 0},
+		{math.NaN(), 0},
+		{math.Inf(1), math.MaxUint64},
+		{true, 1},
+		{false, 0},
+		{"text", 0},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, toRawCounterValue(tt.value), "%v", tt.value)
+	}
+}
+
+func TestULongLongArgs(t *testing.T) {
+	for _, value := range []uint64{0, 1, 1<<32 + 5, math.MaxUint64} {
+		args := ulongLongArgs(value)
+		if unsafe.Sizeof(uintptr(0)) == 8 {
+			require.Equal(t, []uintptr{uintptr(value)}, args)
+			continue
+		}
+		// 32 位平台按低、高两个参数传递，不丢失高 32 位
+		require.Len(t, args, 2)
+		require.Equal(t, value, uint64(args[0])|uint64(args[1])<<32)
+	}
+}
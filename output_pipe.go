@@ -0,0 +1,283 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	defaultPipeBufferSize  = 64 * 1024
+	defaultPipeClientQueue = 1024
+	// pipeCloseTimeout Close 等待连接处理 goroutine 退出的最长时间。
+	pipeCloseTimeout = 5 * time.Second
+)
+
+// predicate 将过滤条件转换为 CollectPredicate。
+func (f PipeFilter) predicate() CollectPredicate {
+	measurement := MatchMeasurement(f.Measurements...)
+	object := MatchObject(f.Objects...)
+	return func(name string, tags map[string]string) bool {
+		if len(f.Measurements) > 0 && !measurement(name, tags) {
+			return false
+		}
+		if len(f.Objects) > 0 && !object(name, tags) {
+			return false
+		}
+		for k, v := range f.Tags {
+			if tags[k] != v {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// pipeClient 表示一个已连接的命名管道客户端。
+type pipeClient struct {
+	predicate CollectPredicate
+	queue     chan []byte
+}
+
+// NamedPipeOutput 通过 Windows 命名管道向本机客户端推送 JSON Lines 格式的指标。
+//
+// 客户端连接后需先发送一行 PipeFilter JSON（空行表示订阅全部指标），之后每行收到一条指标。
+// 客户端消费过慢时，超出队列长度的指标会被丢弃，不会阻塞采集。
+type NamedPipeOutput struct {
+	// Log 日志记录器。
+	Log Logger
+
+	name string
+
+	lock    sync.Mutex
+	clients map[*pipeClient]struct{}
+	// conns 所有已连接实例的句柄，包括尚未发送过滤条件的客户端，Close 时用于中断阻塞中的读写。
+	conns   map[windows.Handle]struct{}
+	closed  bool
+	pending windows.Handle
+	wg      sync.WaitGroup
+}
+
+// NewNamedPipeOutput 创建命名管道输出并开始接受连接，name 形如 `\\.\pipe\win_perf_counters`。
+func NewNamedPipeOutput(name string) (*NamedPipeOutput, error) {
+	o := &NamedPipeOutput{
+		Log:     Logger{Name: "win_perf_counters"},
+		name:    name,
+		clients: make(map[*pipeClient]struct{}),
+		conns:   make(map[windows.Handle]struct{}),
+	}
+	// 先创建一个实例以便尽早暴露名称冲突等错误
+	h, err := o.createInstance()
+	if err != nil {
+		return nil, err
+	}
+	o.wg.Add(1)
+	go o.serve(h)
+	return o, nil
+}
+
+// createInstance 创建一个新的命名管道实例。
+func (o *NamedPipeOutput) createInstance() (windows.Handle, error) {
+	namePtr, err := windows.UTF16PtrFromString(o.name)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+	h, err := windows.CreateNamedPipe(
+		namePtr,
+		windows.PIPE_ACCESS_DUPLEX,
+		windows.PIPE_TYPE_BYTE|windows.PIPE_READMODE_BYTE|windows.PIPE_WAIT|windows.PIPE_REJECT_REMOTE_CLIENTS,
+		windows.PIPE_UNLIMITED_INSTANCES,
+		defaultPipeBufferSize,
+		defaultPipeBufferSize,
+		0,
+		nil,
+	)
+	if err != nil {
+		return windows.InvalidHandle, fmt.Errorf("creating named pipe %q failed: %w", o.name, err)
+	}
+	return h, nil
+}
+
+// serve 循环等待客户端连接，每个连接由单独的 goroutine 处理。
+func (o *NamedPipeOutput) serve(h windows.Handle) {
+	defer o.wg.Done()
+	for {
+		o.lock.Lock()
+		if o.closed {
+			o.lock.Unlock()
+			_ = windows.CloseHandle(h)
+			return
+		}
+		o.pending = h
+		o.lock.Unlock()
+
+		err := windows.ConnectNamedPipe(h, nil)
+		if err != nil && !errors.Is(err, windows.ERROR_PIPE_CONNECTED) {
+			o.lock.Lock()
+			closed := o.closed
+			o.lock.Unlock()
+			_ = windows.CloseHandle(h)
+			if closed {
+				return
+			}
+			o.Log.Errorf("Accepting named pipe client on %q failed: %v", o.name, err)
+		} else {
+			o.lock.Lock()
+			if o.closed {
+				o.lock.Unlock()
+				_ = windows.CloseHandle(h)
+				return
+			}
+			o.conns[h] = struct{}{}
+			o.wg.Add(1)
+			o.lock.Unlock()
+			go o.handle(h, os.NewFile(uintptr(h), o.name))
+		}
+
+		if h, err = o.createInstance(); err != nil {
+			o.Log.Errorf("%v", err)
+			return
+		}
+	}
+}
+
+// handle 读取客户端的过滤条件，然后把队列中的指标写入管道直至连接断开。
+func (o *NamedPipeOutput) handle(h windows.Handle, file *os.File) {
+	defer o.wg.Done()
+	defer func() {
+		// 先从 conns 中移除再关闭，Close 不会操作已关闭（可能被复用）的句柄
+		o.lock.Lock()
+		delete(o.conns, h)
+		o.lock.Unlock()
+		_ = file.Close()
+	}()
+
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	var filter PipeFilter
+	if len(line) > 1 {
+		if err := json.Unmarshal(line, &filter); err != nil {
+			o.Log.Warnf("Invalid filter from named pipe client: %v", err)
+			return
+		}
+	}
+
+	client := &pipeClient{
+		predicate: filter.predicate(),
+		queue:     make(chan []byte, defaultPipeClientQueue),
+	}
+	o.lock.Lock()
+	if o.closed {
+		o.lock.Unlock()
+		return
+	}
+	o.clients[client] = struct{}{}
+	o.lock.Unlock()
+
+	for data := range client.queue {
+		if _, err := file.Write(data); err != nil {
+			break
+		}
+	}
+
+	o.lock.Lock()
+	if _, ok := o.clients[client]; ok {
+		delete(o.clients, client)
+		close(client.queue)
+	}
+	o.lock.Unlock()
+}
+
+// Collect 将指标编码为一行 JSON 推送给所有匹配的客户端，可直接作为 CollectFunc 注册。
+func (o *NamedPipeOutput) Collect(measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if len(o.clients) == 0 {
+		return
+	}
+	var data []byte
+	for client := range o.clients {
+		if !client.predicate(measurement, tags) {
+			continue
+		}
+		if data == nil {
+			var err error
+			data, err = json.Marshal(Metric{Measurement: measurement, Tags: tags, Fields: fields, Timestamp: timestamp})
+			if err != nil {
+				o.Log.Errorf("Encoding metric %q failed: %v", measurement, err)
+				return
+			}
+			data = append(data, '\n')
+		}
+		select {
+		case client.queue <- data:
+		default:
+			// 客户端消费过慢，丢弃该指标
+		}
+	}
+}
+
+// Backpressure 判断是否有客户端的队列已超过一半，可作为 BackpressureFunc 注册。
+func (o *NamedPipeOutput) Backpressure() bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	for client := range o.clients {
+		if len(client.queue) > cap(client.queue)/2 {
+			return true
+		}
+	}
+	return false
+}
+
+// Close 停止接受新连接并断开所有客户端，最多等待 pipeCloseTimeout。
+func (o *NamedPipeOutput) Close() error {
+	o.lock.Lock()
+	if o.closed {
+		o.lock.Unlock()
+		return nil
+	}
+	o.closed = true
+	for client := range o.clients {
+		delete(o.clients, client)
+		close(client.queue)
+	}
+	// 同步句柄上阻塞的 ReadFile/WriteFile 不会因 os.File.Close 返回（关闭要等读写结束），
+	// 断开连接并取消 I/O 使其立即失败，包括仍在等待过滤条件和写满管道缓冲区的客户端
+	for h := range o.conns {
+		_ = windows.DisconnectNamedPipe(h)
+		_ = windows.CancelIoEx(h, nil)
+	}
+	pending := o.pending
+	o.lock.Unlock()
+
+	// 连接一次自身以唤醒阻塞在 ConnectNamedPipe 上的 goroutine
+	_ = windows.DisconnectNamedPipe(pending)
+	if namePtr, err := windows.UTF16PtrFromString(o.name); err == nil {
+		if h, err := windows.CreateFile(namePtr, windows.GENERIC_READ, 0, nil, windows.OPEN_EXISTING, 0, 0); err == nil {
+			_ = windows.CloseHandle(h)
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		o.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(pipeCloseTimeout):
+		return fmt.Errorf("closing named pipe %q timed out after %s", o.name, pipeCloseTimeout)
+	}
+}
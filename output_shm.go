@@ -0,0 +1,288 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// 共享内存段的头部布局（小端序）：
+//
+//	偏移 0  uint32 magic，固定为 shmMagic
+//	偏移 4  uint32 version，固定为 shmVersion
+//	偏移 8  uint64 sequence，写入期间为奇数，写入完成后为偶数
+//	偏移 16 uint32 payload 长度
+//	偏移 20 uint32 保留
+//	偏移 24 payload，JSON 编码的 []Metric
+//
+// 读取方应先读取 sequence，若为奇数则重试；复制 payload 后再次读取 sequence，
+// 两次结果一致时复制的数据才是完整的快照。
+const (
+	shmMagic      = uint32(0x57504346) // "WPCF"
+	shmVersion    = uint32(1)
+	shmHeaderSize = 24
+
+	defaultSharedMemorySize = uint32(4 * 1024 * 1024)
+)
+
+var errSharedMemoryTooSmall = errors.New("snapshot does not fit into shared memory section")
+
+// SharedMemoryOutput 将所有指标的最新快照写入命名共享内存段，供本机的旁路进程直接读取。
+type SharedMemoryOutput struct {
+	// Log 日志记录器。
+	Log Logger
+
+	name    string
+	mapping windows.Handle
+	addr    uintptr
+	view    []byte
+
+	lock sync.Mutex
+	// entries 按首次写入顺序排列的快照条目，index 为快照键到 entries 下标的映射。
+	entries []shmEntry
+	index   map[string]int
+	// dirty 尚未写入共享内存的第一个条目的下标，之前的条目与共享内存中的内容一致。
+	dirty int
+	// gather 当前采集的序号，groups 为各条目分组最近一次出现时的采集序号。
+	gather uint64
+	groups map[string]uint64
+	// writeFailed 上一次写入是否失败，用于只在开始失败时记录一次日志。
+	writeFailed bool
+}
+
+// shmEntry 快照中一个指标编码后的 JSON 及其在 payload 中的偏移。
+type shmEntry struct {
+	key     string
+	encoded []byte
+	offset  int
+	// group 条目所属的分组（测量名称、来源与对象），gather 为条目最近一次更新时的采集序号。
+	group  string
+	gather uint64
+}
+
+// NewSharedMemoryOutput 创建名为 name 的共享内存段，size 为 0 时使用默认大小。
+//
+// name 可以带 "Global\" 或 "Local\" 前缀，创建 Global 命名空间的段需要 SeCreateGlobalPrivilege 权限。
+func NewSharedMemoryOutput(name string, size uint32) (*SharedMemoryOutput, error) {
+	if size == 0 {
+		size = defaultSharedMemorySize
+	}
+	if size <= shmHeaderSize {
+		return nil, fmt.Errorf("shared memory size should be larger than %d", shmHeaderSize)
+	}
+
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := windows.CreateFileMapping(windows.InvalidHandle, nil, windows.PAGE_READWRITE, 0, size, namePtr)
+	if err != nil {
+		return nil, fmt.Errorf("creating file mapping %q failed: %w", name, err)
+	}
+	addr, err := windows.MapViewOfFile(mapping, windows.FILE_MAP_WRITE, 0, 0, uintptr(size))
+	if err != nil {
+		_ = windows.CloseHandle(mapping)
+		return nil, fmt.Errorf("mapping view of %q failed: %w", name, err)
+	}
+
+	o := &SharedMemoryOutput{
+		Log:     Logger{Name: "win_perf_counters"},
+		name:    name,
+		mapping: mapping,
+		addr:    addr,
+		view:    unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&addr))), size), //nolint:gosec // G103: Valid use of unsafe call to access the mapped view
+		index:   make(map[string]int),
+		groups:  make(map[string]uint64),
+	}
+	binary.LittleEndian.PutUint32(o.view[0:], shmMagic)
+	binary.LittleEndian.PutUint32(o.view[4:], shmVersion)
+	return o, nil
+}
+
+// Collect 更新快照并写入共享内存，可直接作为 CollectFunc 注册。只编码本次的指标，
+// 共享内存中只重写从第一个变化的条目开始的部分。同时通过 AddGatherDoneFunc 注册 GatherDone，
+// 快照中才不会保留已经消失的实例。
+func (o *SharedMemoryOutput) Collect(measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.view == nil {
+		return
+	}
+	encoded, err := json.Marshal(Metric{
+		Measurement: measurement,
+		Tags:        tags,
+		Fields:      fields,
+		Timestamp:   timestamp,
+	})
+	if err != nil {
+		return
+	}
+	key := snapshotKey(measurement, tags)
+	i, ok := o.index[key]
+	if !ok {
+		i = len(o.entries)
+		o.index[key] = i
+		o.entries = append(o.entries, shmEntry{key: key, group: measurement + "\x00" + tags["source"] + "\x00" + tags["objectname"]})
+	}
+	o.update(i, encoded)
+	o.entries[i].gather = o.gather
+	o.groups[o.entries[i].group] = o.gather
+	o.writeSnapshot()
+}
+
+// GatherDone 从快照中移除本次采集输出了同组指标、自身却没有出现的序列，例如已退出的进程，
+// 本次没有到期的对象的序列保持不变。可直接作为 GatherDoneFunc 注册。
+func (o *SharedMemoryOutput) GatherDone() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.view == nil {
+		return
+	}
+	kept := o.entries[:0]
+	removed := -1
+	for i, entry := range o.entries {
+		if o.groups[entry.group] == o.gather && entry.gather != o.gather {
+			delete(o.index, entry.key)
+			if removed < 0 {
+				removed = i
+			}
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	// 本次出现的分组至少保留了本次更新的条目，groups 不会留下没有条目的分组
+	o.entries = kept
+	o.gather++
+	if removed < 0 {
+		return
+	}
+
+	// 移除的条目之后的条目下标和偏移都发生了变化，从该处开始重写
+	for i := removed; i < len(o.entries); i++ {
+		entry := &o.entries[i]
+		o.index[entry.key] = i
+		if i == 0 {
+			entry.offset = 1
+		} else {
+			previous := o.entries[i-1]
+			entry.offset = previous.offset + len(previous.encoded) + 1
+		}
+	}
+	o.dirty = min(o.dirty, removed)
+	o.writeSnapshot()
+}
+
+// writeSnapshot 写入快照，快照超出共享内存大小时保留上一次完整写入的内容，并在开始失败时记录一次错误。调用方需持有 lock。
+func (o *SharedMemoryOutput) writeSnapshot() {
+	err := o.write()
+	switch {
+	case err != nil && !o.writeFailed:
+		o.Log.Errorf("Writing snapshot of %d metrics (%d bytes) to shared memory %q failed, keeping the previous snapshot: %v", len(o.entries), o.payloadSize(), o.name, err)
+	case err == nil && o.writeFailed:
+		o.Log.Infof("Writing snapshot to shared memory %q succeeded again", o.name)
+	}
+	o.writeFailed = err != nil
+}
+
+// update 替换第 i 个条目的编码，长度变化时之后的条目偏移随之移动，需要重写。调用方需持有 lock。
+func (o *SharedMemoryOutput) update(i int, encoded []byte) {
+	entry := &o.entries[i]
+	if len(entry.encoded) != len(encoded) {
+		for j := i + 1; j < len(o.entries); j++ {
+			o.entries[j].offset += len(encoded) - len(entry.encoded)
+		}
+	}
+	if i == 0 {
+		entry.offset = 1
+	} else if entry.encoded == nil {
+		previous := o.entries[i-1]
+		entry.offset = previous.offset + len(previous.encoded) + 1
+	}
+	entry.encoded = encoded
+	o.dirty = min(o.dirty, i)
+}
+
+// payloadSize 返回当前快照编码后的长度。
+func (o *SharedMemoryOutput) payloadSize() int {
+	if len(o.entries) == 0 {
+		return 0
+	}
+	last := o.entries[len(o.entries)-1]
+	return last.offset + len(last.encoded) + 1
+}
+
+// write 在序列锁保护下将从 dirty 开始的条目写入共享内存，调用方需持有 lock。
+func (o *SharedMemoryOutput) write() error {
+	size := o.payloadSize()
+	if size > len(o.view)-shmHeaderSize {
+		return errSharedMemoryTooSmall
+	}
+	payload := o.view[shmHeaderSize:]
+
+	seq := (*uint64)(unsafe.Pointer(&o.view[8])) //nolint:gosec // G103: Valid use of unsafe call to access the sequence counter
+	atomic.AddUint64(seq, 1)
+	for i := o.dirty; i < len(o.entries); i++ {
+		entry := o.entries[i]
+		if i == 0 {
+			payload[0] = '['
+		} else {
+			payload[entry.offset-1] = ','
+		}
+		copy(payload[entry.offset:], entry.encoded)
+	}
+	if size > 0 {
+		payload[size-1] = ']'
+	}
+	binary.LittleEndian.PutUint32(o.view[16:], uint32(size))
+	atomic.AddUint64(seq, 1)
+	o.dirty = len(o.entries)
+	return nil
+}
+
+// Close 解除映射并关闭共享内存段。
+func (o *SharedMemoryOutput) Close() error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.view == nil {
+		return nil
+	}
+	o.view = nil
+	err := windows.UnmapViewOfFile(o.addr)
+	if cerr := windows.CloseHandle(o.mapping); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// snapshotKey 根据测量名称和排序后的标签生成快照键。
+func snapshotKey(measurement string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(measurement)
+	for _, k := range keys {
+		sb.WriteByte(',')
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(tags[k])
+	}
+	return sb.String()
+}
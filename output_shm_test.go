@@ -0,0 +1,137 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSharedMemoryOutput 创建使用普通内存作为共享内存段的输出。
+func newTestSharedMemoryOutput(size int) *SharedMemoryOutput {
+	return &SharedMemoryOutput{view: make([]byte, size), index: make(map[string]int), groups: make(map[string]uint64)}
+}
+
+// readSharedMemory 按读取方的方式解析共享内存中的快照。
+func readSharedMemory(t *testing.T, o *SharedMemoryOutput) []Metric {
+	t.Helper()
+	require.Zero(t, binary.LittleEndian.Uint64(o.view[8:])%2, "sequence is odd after write")
+	size := binary.LittleEndian.Uint32(o.view[16:])
+	var metrics []Metric
+	require.NoError(t, json.Unmarshal(o.view[shmHeaderSize:shmHeaderSize+int(size)], &metrics))
+	return metrics
+}
+
+func TestSharedMemoryOutputCollect(t *testing.T) {
+	o := newTestSharedMemoryOutput(4096)
+	now := time.Unix(1700000000, 0).UTC()
+	collect := func(instance string, value float64) {
+		o.Collect("win_cpu", map[string]interface{}{"value": value}, map[string]string{"instance": instance}, now)
+	}
+	want := func(values ...interface{}) []Metric {
+		var metrics []Metric
+		for i := 0; i < len(values); i += 2 {
+			metrics = append(metrics, Metric{
+				Measurement: "win_cpu",
+				Tags:        map[string]string{"instance": values[i].(string)},
+				Fields:      map[string]interface{}{"value": values[i+1]},
+				Timestamp:   now,
+			})
+		}
+		return metrics
+	}
+
+	collect("0", 1)
+	require.Equal(t, want("0", 1.0), readSharedMemory(t, o))
+	collect("1", 2)
+	collect("2", 3)
+	require.Equal(t, want("0", 1.0, "1", 2.0, "2", 3.0), readSharedMemory(t, o))
+
+	// 长度相同与长度变化的更新
+	collect("1", 5)
+	require.Equal(t, want("0", 1.0, "1", 5.0, "2", 3.0), readSharedMemory(t, o))
+	collect("0", 12.5)
+	require.Equal(t, want("0", 12.5, "1", 5.0, "2", 3.0), readSharedMemory(t, o))
+	collect("0", 4)
+	require.Equal(t, want("0", 4.0, "1", 5.0, "2", 3.0), readSharedMemory(t, o))
+	require.Equal(t, 3, o.dirty)
+}
+
+func TestSharedMemoryOutputTooSmall(t *testing.T) {
+	o := newTestSharedMemoryOutput(shmHeaderSize + 300)
+	var buf bytes.Buffer
+	o.Log = Logger{Output: log.New(&buf, "", 0)}
+	now := time.Unix(1700000000, 0).UTC()
+	o.Collect("win_cpu", map[string]interface{}{"value": 1.0}, map[string]string{"instance": "0"}, now)
+	first := readSharedMemory(t, o)
+	require.Len(t, first, 1)
+
+	// 超出大小时保留上一次完整写入的内容
+	o.Collect("win_cpu", map[string]interface{}{"value": strings.Repeat("x", 300)}, map[string]string{"instance": "1"}, now)
+	require.Equal(t, first, readSharedMemory(t, o))
+	require.Equal(t, 1, o.dirty)
+	// 写入失败只在开始失败时记录一次
+	o.Collect("win_cpu", map[string]interface{}{"value": strings.Repeat("y", 300)}, map[string]string{"instance": "1"}, now)
+	require.Equal(t, 1, strings.Count(buf.String(), "failed"))
+
+	// 条目变小后重写之前未写入的部分
+	o.Collect("win_cpu", map[string]interface{}{"value": 2.0}, map[string]string{"instance": "1"}, now)
+	metrics := readSharedMemory(t, o)
+	require.Len(t, metrics, 2)
+	require.Equal(t, map[string]interface{}{"value": 2.0}, metrics[1].Fields)
+	require.Contains(t, buf.String(), "succeeded again")
+}
+
+func TestSharedMemoryOutputClosed(t *testing.T) {
+	o := newTestSharedMemoryOutput(4096)
+	o.view = nil
+	o.Collect("win_cpu", map[string]interface{}{"value": 1.0}, nil, time.Now())
+	require.Empty(t, o.entries)
+}
+
+func TestSharedMemoryOutputGatherDone(t *testing.T) {
+	o := newTestSharedMemoryOutput(4096)
+	now := time.Unix(1700000000, 0).UTC()
+	collect := func(measurement, object, instance string) {
+		o.Collect(measurement, map[string]interface{}{"value": 1.0}, map[string]string{"objectname": object, "instance": instance, "source": "hostA"}, now)
+	}
+	instances := func() []string {
+		var names []string
+		for _, metric := range readSharedMemory(t, o) {
+			names = append(names, metric.Tags["objectname"]+"/"+metric.Tags["instance"])
+		}
+		return names
+	}
+
+	collect("win_proc", "Process", "a")
+	collect("win_proc", "Process", "b")
+	collect("win_proc", "Process", "c")
+	collect("win_mem", "Memory", "------")
+	o.GatherDone()
+	require.Equal(t, []string{"Process/a", "Process/b", "Process/c", "Memory/------"}, instances())
+
+	// 进程 b 退出后从快照中移除，本次没有到期的 Memory 对象保持不变
+	collect("win_proc", "Process", "a")
+	collect("win_proc", "Process", "c")
+	collect("win_proc", "Process", "d")
+	o.GatherDone()
+	require.Equal(t, []string{"Process/a", "Process/c", "Memory/------", "Process/d"}, instances())
+	require.Len(t, o.index, 4)
+	for key, i := range o.index {
+		require.Equal(t, key, o.entries[i].key)
+	}
+
+	// 不断变化的实例不会使快照持续增长
+	for i := range 100 {
+		collect("win_proc", "Process", strings.Repeat("x", i%10)+"p")
+		o.GatherDone()
+	}
+	require.Equal(t, []string{"Memory/------", "Process/xxxxxxxxxp"}, instances())
+}
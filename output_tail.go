@@ -0,0 +1,136 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultTailClientQueue = 256
+
+// tailClient 表示一个正在实时查看指标的 HTTP 客户端。
+type tailClient struct {
+	predicate CollectPredicate
+	sample    float64
+	queue     chan []byte
+}
+
+// TailHandler 是用于调试的 HTTP 端点，以 JSON Lines 格式实时推送匹配过滤条件的指标，
+// 便于在生产主机上确认某个计数器或标签是否被采集，而无需查询下游存储。
+//
+// 支持的查询参数：
+//
+//	measurement 测量名称，可重复
+//	object      性能对象名称，可重复
+//	tag         形如 key:value 的标签条件，可重复
+//	sample      采样比例，取值 (0, 1]，默认为 1
+//
+// 例如：GET /debug/tail?object=Processor&tag=instance:_Total&sample=0.5
+type TailHandler struct {
+	lock    sync.Mutex
+	clients map[*tailClient]struct{}
+}
+
+// NewTailHandler 创建实时查看端点，需通过 AddCollectFunc 注册其 Collect 方法，并挂载到 HTTP 服务上。
+func NewTailHandler() *TailHandler {
+	return &TailHandler{clients: make(map[*tailClient]struct{})}
+}
+
+// ServeHTTP 持续向客户端推送指标，直至客户端断开连接。
+func (h *TailHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := PipeFilter{
+		Measurements: query["measurement"],
+		Objects:      query["object"],
+		Tags:         make(map[string]string),
+	}
+	for _, tag := range query["tag"] {
+		key, value, found := strings.Cut(tag, ":")
+		if !found {
+			http.Error(w, "tag filter must be in the form key:value", http.StatusBadRequest)
+			return
+		}
+		filter.Tags[key] = value
+	}
+	sample := 1.0
+	if s := query.Get("sample"); s != "" {
+		var err error
+		sample, err = strconv.ParseFloat(s, 64)
+		if err != nil || sample <= 0 || sample > 1 {
+			http.Error(w, "sample must be in the range (0, 1]", http.StatusBadRequest)
+			return
+		}
+	}
+
+	client := &tailClient{
+		predicate: filter.predicate(),
+		sample:    sample,
+		queue:     make(chan []byte, defaultTailClientQueue),
+	}
+	h.lock.Lock()
+	h.clients[client] = struct{}{}
+	h.lock.Unlock()
+	defer func() {
+		h.lock.Lock()
+		delete(h.clients, client)
+		h.lock.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data := <-client.queue:
+			if _, err := w.Write(data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Collect 将指标推送给所有匹配的客户端，可直接作为 CollectFunc 注册。
+func (h *TailHandler) Collect(measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var data []byte
+	for client := range h.clients {
+		if !client.predicate(measurement, tags) {
+			continue
+		}
+		if client.sample < 1 && rand.Float64() >= client.sample { //nolint:gosec // G404: sampling does not need a secure random source
+			continue
+		}
+		if data == nil {
+			var err error
+			data, err = json.Marshal(Metric{Measurement: measurement, Tags: tags, Fields: fields, Timestamp: timestamp})
+			if err != nil {
+				return
+			}
+			data = append(data, '\n')
+		}
+		select {
+		case client.queue <- data:
+		default:
+			// 客户端消费过慢，丢弃该指标
+		}
+	}
+}
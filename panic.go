@@ -0,0 +1,37 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"runtime/debug"
+)
+
+// gatherSafe 调用采集一个主机或数据源的 gather 并将其中的 panic 转换为 *PanicError，
+// 各提供程序的主机采集 goroutine 都通过它运行，出错的提供程序不会使宿主进程崩溃。
+//
+// hostInfo 为 PDH 主机时，发生 panic 时正在读取的计数器会被隔离；无法定位到计数器时隔离整个主机。
+// 隔离在下一次刷新计数器时解除。其它提供程序的 hostInfo 为 nil，只记录 panic。
+func (m *WinPerfCounters) gatherSafe(host, tag string, hostInfo *hostCountersInfo, gather func() error) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		panicErr := &PanicError{Host: host, Value: r, Stack: string(debug.Stack())}
+		if hostInfo != nil {
+			if hostInfo.current != nil {
+				panicErr.CounterPath = hostInfo.current.counterPath
+				hostInfo.current.quarantined = true
+			} else {
+				hostInfo.quarantined = true
+			}
+			hostInfo.current = nil
+		}
+
+		tags := map[string]string{"source": tag}
+		m.stats.incr(tags, "panics", 1)
+		m.stats.set(tags, "last_panic", panicErr.Error()+"\n"+panicErr.Stack)
+		err = panicErr
+	}()
+	return gather()
+}
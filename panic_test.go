@@ -0,0 +1,63 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherSafe(t *testing.T) {
+	m := &WinPerfCounters{}
+	require.NoError(t, m.gatherSafe("hostA", "hostA", nil, func() error { return nil }))
+
+	errGather := errors.New("gather failed")
+	require.ErrorIs(t, m.gatherSafe("hostA", "hostA", nil, func() error { return errGather }), errGather)
+
+	// 没有 PDH 主机信息的提供程序只记录 panic
+	err := m.gatherSafe("http://hostB:7090", "http://hostB:7090", nil, func() error { panic("agent failed") })
+	var panicErr *PanicError
+	require.ErrorAs(t, err, &panicErr)
+	require.Equal(t, "http://hostB:7090", panicErr.Host)
+	require.Equal(t, "agent failed", panicErr.Value)
+	require.Empty(t, panicErr.CounterPath)
+	require.NotEmpty(t, panicErr.Stack)
+
+	// 正在读取计数器时只隔离该计数器
+	metric := &counter{counterPath: `\Processor(_Total)\% Processor Time`}
+	hostInfo := &hostCountersInfo{computer: "hostA", tag: "hostA", current: metric}
+	err = m.gatherSafe("hostA", "hostA", hostInfo, func() error { panic("read failed") })
+	require.ErrorAs(t, err, &panicErr)
+	require.Equal(t, metric.counterPath, panicErr.CounterPath)
+	require.True(t, metric.quarantined)
+	require.False(t, hostInfo.quarantined)
+	require.Nil(t, hostInfo.current)
+
+	// 无法定位到计数器时隔离整个主机
+	err = m.gatherSafe("hostA", "hostA", hostInfo, func() error { panic("collect failed") })
+	require.ErrorAs(t, err, &panicErr)
+	require.True(t, hostInfo.quarantined)
+}
+
+func TestGatherCollectPanic(t *testing.T) {
+	source := &fakeSource{value: 42}
+	m := newFakeSourcePlugin(source)
+	require.NoError(t, m.Init())
+	t.Cleanup(func() { _ = m.Close() })
+	require.NoError(t, m.Gather())
+
+	// CollectData 中的 panic 不会使进程崩溃，主机被隔离直到下一次刷新计数器
+	source.panicCollect("collect failed")
+	err := m.Gather()
+	var panicErr *PanicError
+	require.ErrorAs(t, err, &panicErr)
+	require.Equal(t, "localhost", panicErr.Host)
+	require.Equal(t, "collect failed", panicErr.Value)
+
+	source.panicCollect(nil)
+	collects := source.collects
+	require.NoError(t, m.Gather())
+	require.Equal(t, collects, source.collects)
+}
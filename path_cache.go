@@ -0,0 +1,323 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	// perflibKey 计数器注册表的根键，其中 Last Counter 与 Last Help 在安装或卸载计数器时变化。
+	perflibKey = `SOFTWARE\Microsoft\Windows NT\CurrentVersion\Perflib`
+	// perflibVersionTimeout 读取计数器注册表版本的最长时间，远程主机不可达时打开远程注册表可能阻塞很久。
+	perflibVersionTimeout = 10 * time.Second
+)
+
+var errPerflibVersionTimeout = errors.New("reading perflib version timed out")
+
+// pathCacheEntry 一个通配符路径的展开结果。
+type pathCacheEntry struct {
+	Pattern string   `json:"pattern"`
+	Flags   uint32   `json:"flags"`
+	Paths   []string `json:"paths"`
+}
+
+// pathCacheHost 一个主机的展开结果及写入时的计数器注册表版本。
+type pathCacheHost struct {
+	Version string           `json:"version"`
+	Entries []pathCacheEntry `json:"entries"`
+}
+
+// pathCacheFile CounterPathCache 文件的内容，Machine 为写入文件的本机主机名。
+type pathCacheFile struct {
+	Machine string                    `json:"machine"`
+	Hosts   map[string]*pathCacheHost `json:"hosts"`
+}
+
+// counterPathCache 持久化通配符的展开结果，重启后的首次刷新直接使用磁盘上的结果，只在添加计数器时校验路径。
+// 文件由其它机器写入或主机的计数器注册表版本变化时，相应的结果被丢弃。
+type counterPathCache struct {
+	lock sync.Mutex
+	// warm 是否处于重启后的首次刷新，只有此时使用磁盘上的结果
+	warm bool
+	// stored 从文件读取的各主机的展开结果，键为 pathCacheKey
+	stored map[string]map[string][]string
+	// storedVersions 文件中各主机的计数器注册表版本
+	storedVersions map[string]string
+	// versions 本次运行读取到的各主机的计数器注册表版本，读取失败的主机为空，不缓存
+	versions map[string]string
+	// current 本次运行各主机的展开结果，刷新后写入文件
+	current map[string]map[string][]string
+}
+
+// pathCacheKey 返回通配符路径及展开标志的缓存键。
+func pathCacheKey(pattern string, flags uint32) string {
+	return fmt.Sprintf("%d\x00%s", flags, strings.ToLower(pattern))
+}
+
+// cachesCounterPaths 判断是否持久化通配符的展开结果，只在使用 PDH 展开通配符时生效。
+func (m *WinPerfCounters) cachesCounterPaths() bool {
+	if m.CounterPathCache == "" || !m.UseWildcardsExpansion {
+		return false
+	}
+	_, ok := m.queryCreator.(*performanceQueryCreatorImpl)
+	return ok
+}
+
+// loadCounterPaths 读取 CounterPathCache 文件，文件不存在或由其它机器写入时从空缓存开始。
+func (m *WinPerfCounters) loadCounterPaths() error {
+	c := &m.counterPaths
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.warm = true
+	c.stored = nil
+	c.storedVersions = nil
+	c.versions = make(map[string]string)
+	c.current = make(map[string]map[string][]string)
+
+	data, err := os.ReadFile(m.CounterPathCache)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading counter path cache failed: %w", err)
+	}
+	var file pathCacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		m.Log.Warnf("Ignoring corrupt counter path cache %q: %v", m.CounterPathCache, err)
+		return nil
+	}
+	if !strings.EqualFold(file.Machine, m.hostname()) {
+		m.Log.Infof("Ignoring counter path cache %q written by %q", m.CounterPathCache, file.Machine)
+		return nil
+	}
+	c.stored = make(map[string]map[string][]string, len(file.Hosts))
+	c.storedVersions = make(map[string]string, len(file.Hosts))
+	for computer, host := range file.Hosts {
+		entries := make(map[string][]string, len(host.Entries))
+		for _, entry := range host.Entries {
+			entries[pathCacheKey(entry.Pattern, entry.Flags)] = entry.Paths
+		}
+		c.stored[computer] = entries
+		c.storedVersions[computer] = host.Version
+	}
+	return nil
+}
+
+// hostVersion 返回主机的计数器注册表版本，每次运行只读取一次，读取失败时为空。
+// 读取远程注册表期间不持有锁，最多等待 perflibVersionTimeout。
+func (c *counterPathCache) hostVersion(computer string) string {
+	c.lock.Lock()
+	version, ok := c.versions[computer]
+	c.lock.Unlock()
+	if ok {
+		return version
+	}
+
+	version, err := perflibVersionWithTimeout(computer, perflibVersionTimeout)
+	if err != nil {
+		version = ""
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.versions != nil {
+		c.versions[computer] = version
+	}
+	return version
+}
+
+// cachedCounterPaths 返回重启后首次刷新时磁盘上记录的展开结果，主机的计数器注册表版本变化时 ok 为 false。
+func (m *WinPerfCounters) cachedCounterPaths(computer, pattern string, flags uint32) ([]string, bool) {
+	if !m.cachesCounterPaths() || logSourcePath(computer) != "" {
+		return nil, false
+	}
+	c := &m.counterPaths
+	c.lock.Lock()
+	warm := c.warm && c.stored[computer] != nil
+	c.lock.Unlock()
+	if !warm {
+		return nil, false
+	}
+	version := c.hostVersion(computer)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.stored[computer] == nil {
+		return nil, false
+	}
+	if version == "" || version != c.storedVersions[computer] {
+		delete(c.stored, computer)
+		return nil, false
+	}
+	paths, ok := c.stored[computer][pathCacheKey(pattern, flags)]
+	return paths, ok
+}
+
+// reset 丢弃文件中的展开结果，计数器注册表重建后所有路径都需重新展开。
+func (c *counterPathCache) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.warm = false
+	c.stored = nil
+	c.versions = make(map[string]string)
+}
+
+// storeCounterPaths 记录通配符的展开结果，在刷新后写入文件。
+func (m *WinPerfCounters) storeCounterPaths(computer, pattern string, flags uint32, paths []string) {
+	if !m.cachesCounterPaths() || logSourcePath(computer) != "" {
+		return
+	}
+	c := &m.counterPaths
+	if c.hostVersion(computer) == "" {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.current == nil {
+		return
+	}
+	if c.current[computer] == nil {
+		c.current[computer] = make(map[string][]string)
+	}
+	c.current[computer][pathCacheKey(pattern, flags)] = paths
+}
+
+// saveCounterPaths 在刷新计数器后将本次的展开结果合并进 CounterPathCache 文件，之后的刷新不再使用磁盘上的结果。
+// 本次没有展开的主机，以及计数器注册表版本未变的主机中本次没有展开的通配符路径保留文件中原有的结果，
+// 例如刷新时暂时不可达的远程主机不会丢失缓存。先写入临时文件再替换，写入中途退出不会留下不完整的文件。
+func (m *WinPerfCounters) saveCounterPaths() error {
+	if !m.cachesCounterPaths() {
+		return nil
+	}
+	previous := m.readCounterPathFile()
+
+	c := &m.counterPaths
+	c.lock.Lock()
+	c.warm = false
+	c.stored = nil
+	file := pathCacheFile{Machine: m.hostname(), Hosts: make(map[string]*pathCacheHost, len(c.current))}
+	for computer, host := range previous {
+		if _, ok := c.current[computer]; !ok {
+			file.Hosts[computer] = host
+		}
+	}
+	for computer, entries := range c.current {
+		host := &pathCacheHost{Version: c.versions[computer]}
+		if old := previous[computer]; old != nil && old.Version == host.Version {
+			for _, entry := range old.Entries {
+				if _, ok := entries[pathCacheKey(entry.Pattern, entry.Flags)]; !ok {
+					host.Entries = append(host.Entries, entry)
+				}
+			}
+		}
+		for key, paths := range entries {
+			flags, pattern, _ := strings.Cut(key, "\x00")
+			var entry pathCacheEntry
+			if _, err := fmt.Sscan(flags, &entry.Flags); err != nil {
+				continue
+			}
+			entry.Pattern = pattern
+			entry.Paths = paths
+			host.Entries = append(host.Entries, entry)
+		}
+		sort.Slice(host.Entries, func(i, j int) bool { return host.Entries[i].Pattern < host.Entries[j].Pattern })
+		file.Hosts[computer] = host
+	}
+	c.current = make(map[string]map[string][]string)
+	c.lock.Unlock()
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.CounterPathCache), filepath.Base(m.CounterPathCache)+".*")
+	if err != nil {
+		return fmt.Errorf("writing counter path cache failed: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing counter path cache failed: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing counter path cache failed: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), m.CounterPathCache); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing counter path cache failed: %w", err)
+	}
+	return nil
+}
+
+// readCounterPathFile 读取 CounterPathCache 文件中本机写入的各主机的展开结果，文件不存在、损坏或由其它机器写入时返回 nil。
+func (m *WinPerfCounters) readCounterPathFile() map[string]*pathCacheHost {
+	data, err := os.ReadFile(m.CounterPathCache)
+	if err != nil {
+		return nil
+	}
+	var file pathCacheFile
+	if err := json.Unmarshal(data, &file); err != nil || !strings.EqualFold(file.Machine, m.hostname()) {
+		return nil
+	}
+	return file.Hosts
+}
+
+// perflibVersionWithTimeout 在 timeout 内读取主机计数器注册表的版本，超时后读取在后台继续并被丢弃。
+func perflibVersionWithTimeout(computer string, timeout time.Duration) (string, error) {
+	type result struct {
+		version string
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		version, err := perflibVersion(computer)
+		done <- result{version, err}
+	}()
+	select {
+	case r := <-done:
+		return r.version, r.err
+	case <-time.After(timeout):
+		return "", fmt.Errorf("%w after %s for %q", errPerflibVersionTimeout, timeout, computer)
+	}
+}
+
+// perflibVersion 返回主机计数器注册表的版本（Last Counter 与 Last Help），远程主机读取其自身的注册表。
+func perflibVersion(computer string) (string, error) {
+	root := registry.LOCAL_MACHINE
+	if computer != "localhost" {
+		remote, err := registry.OpenRemoteKey(`\\`+strings.TrimPrefix(computer, `\\`), registry.LOCAL_MACHINE)
+		if err != nil {
+			return "", err
+		}
+		defer remote.Close()
+		root = remote
+	}
+	key, err := registry.OpenKey(root, perflibKey, registry.QUERY_VALUE)
+	if err != nil {
+		return "", err
+	}
+	defer key.Close()
+	lastCounter, _, err := key.GetIntegerValue("Last Counter")
+	if err != nil {
+		return "", err
+	}
+	lastHelp, _, err := key.GetIntegerValue("Last Help")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d.%d", lastCounter, lastHelp), nil
+}
@@ -0,0 +1,140 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newPathCachePlugin 创建使用 path 作为 CounterPathCache 的插件，versions 预置各主机的计数器注册表版本，不读取注册表。
+func newPathCachePlugin(t *testing.T, path string, versions map[string]string) *WinPerfCounters {
+	t.Helper()
+	m := NewWinPerfCounters(func(string, map[string]interface{}, map[string]string, time.Time) {})
+	m.queryCreator = &performanceQueryCreatorImpl{}
+	m.UseWildcardsExpansion = true
+	m.CounterPathCache = path
+	require.NoError(t, m.loadCounterPaths())
+	for computer, version := range versions {
+		m.counterPaths.versions[computer] = version
+	}
+	return m
+}
+
+func readPathCacheFile(t *testing.T, path string) pathCacheFile {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var file pathCacheFile
+	require.NoError(t, json.Unmarshal(data, &file))
+	return file
+}
+
+func TestPathCacheKey(t *testing.T) {
+	require.Equal(t, pathCacheKey(`\Process(*)\ID Process`, 0), pathCacheKey(`\PROCESS(*)\id process`, 0))
+	require.NotEqual(t, pathCacheKey(`\Process(*)\ID Process`, 0), pathCacheKey(`\Process(*)\ID Process`, 1))
+	require.NotEqual(t, pathCacheKey(`\Process(*)\ID Process`, 0), pathCacheKey(`\Process(*)\Working Set`, 0))
+}
+
+func TestCounterPathCacheSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "paths.json")
+	pattern := `\\hostA\Process(*)\ID Process`
+	paths := []string{`\\hostA\Process(a)\ID Process`, `\\hostA\Process(b)\ID Process`}
+
+	m := newPathCachePlugin(t, path, map[string]string{"hostA": "10.11"})
+	_, cached := m.cachedCounterPaths("hostA", pattern, 0)
+	require.False(t, cached)
+	m.storeCounterPaths("hostA", pattern, 0, paths)
+	require.NoError(t, m.saveCounterPaths())
+
+	file := readPathCacheFile(t, path)
+	require.Equal(t, m.hostname(), file.Machine)
+	require.Equal(t, &pathCacheHost{
+		Version: "10.11",
+		Entries: []pathCacheEntry{{Pattern: strings.ToLower(pattern), Paths: paths}},
+	}, file.Hosts["hostA"])
+
+	// 重启后版本未变时使用磁盘上的结果，键不区分大小写
+	m = newPathCachePlugin(t, path, map[string]string{"hostA": "10.11"})
+	cachedPaths, cached := m.cachedCounterPaths("hostA", `\\HOSTA\Process(*)\ID Process`, 0)
+	require.True(t, cached)
+	require.Equal(t, paths, cachedPaths)
+	_, cached = m.cachedCounterPaths("hostA", pattern, 1)
+	require.False(t, cached)
+
+	// 计数器注册表版本变化时丢弃
+	m = newPathCachePlugin(t, path, map[string]string{"hostA": "12.13"})
+	_, cached = m.cachedCounterPaths("hostA", pattern, 0)
+	require.False(t, cached)
+
+	// 首次刷新保存后不再使用磁盘上的结果
+	m = newPathCachePlugin(t, path, map[string]string{"hostA": "10.11"})
+	require.NoError(t, m.saveCounterPaths())
+	_, cached = m.cachedCounterPaths("hostA", pattern, 0)
+	require.False(t, cached)
+}
+
+func TestCounterPathCacheOtherMachine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "paths.json")
+	data, err := json.Marshal(pathCacheFile{
+		Machine: "other-machine",
+		Hosts: map[string]*pathCacheHost{"hostA": {
+			Version: "10.11",
+			Entries: []pathCacheEntry{{Pattern: `\\hosta\process(*)\id process`, Paths: []string{"x"}}},
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	m := newPathCachePlugin(t, path, map[string]string{"hostA": "10.11"})
+	_, cached := m.cachedCounterPaths("hostA", `\\hostA\Process(*)\ID Process`, 0)
+	require.False(t, cached)
+
+	// 其它机器写入的结果在保存时不合并
+	require.NoError(t, m.saveCounterPaths())
+	require.Empty(t, readPathCacheFile(t, path).Hosts)
+}
+
+func TestCounterPathCacheMerge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "paths.json")
+	processes := `\\hostA\Process(*)\ID Process`
+	disks := `\\hostA\LogicalDisk(*)\Free Megabytes`
+	remote := `\\hostB\Process(*)\ID Process`
+
+	m := newPathCachePlugin(t, path, map[string]string{"hostA": "10.11", "hostB": "20.21"})
+	m.storeCounterPaths("hostA", processes, 0, []string{"a1"})
+	m.storeCounterPaths("hostA", disks, 0, []string{"d1"})
+	m.storeCounterPaths("hostB", remote, 0, []string{"b1"})
+	require.NoError(t, m.saveCounterPaths())
+
+	// 之后的刷新只展开了 hostA 的一个通配符路径，hostB 暂时不可达
+	m = newPathCachePlugin(t, path, map[string]string{"hostA": "10.11", "hostB": ""})
+	m.storeCounterPaths("hostA", processes, 0, []string{"a1", "a2"})
+	m.storeCounterPaths("hostB", remote, 0, []string{"ignored"})
+	require.NoError(t, m.saveCounterPaths())
+
+	m = newPathCachePlugin(t, path, map[string]string{"hostA": "10.11", "hostB": "20.21"})
+	for pattern, expected := range map[string][]string{processes: {"a1", "a2"}, disks: {"d1"}} {
+		paths, cached := m.cachedCounterPaths("hostA", pattern, 0)
+		require.True(t, cached, pattern)
+		require.Equal(t, expected, paths, pattern)
+	}
+	paths, cached := m.cachedCounterPaths("hostB", remote, 0)
+	require.True(t, cached)
+	require.Equal(t, []string{"b1"}, paths)
+
+	// hostA 的计数器注册表版本变化后只保留本次展开的结果
+	m = newPathCachePlugin(t, path, map[string]string{"hostA": "30.31"})
+	m.storeCounterPaths("hostA", processes, 0, []string{"a3"})
+	require.NoError(t, m.saveCounterPaths())
+	hostA := readPathCacheFile(t, path).Hosts["hostA"]
+	require.Equal(t, "30.31", hostA.Version)
+	require.Len(t, hostA.Entries, 1)
+	require.Contains(t, readPathCacheFile(t, path).Hosts, "hostB")
+}
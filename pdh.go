@@ -113,95 +113,6 @@ const (
 	pdhQueryPerfDataTimeout               = 0xC0000BFE
 )
 
-var pdhErrors = map[uint32]string{
-	pdhCstatusValidData:                   "PDH_CSTATUS_VALID_DATA",
-	pdhCstatusNewData:                     "PDH_CSTATUS_NEW_DATA",
-	pdhCstatusNoMachine:                   "PDH_CSTATUS_NO_MACHINE",
-	pdhCstatusNoInstance:                  "PDH_CSTATUS_NO_INSTANCE",
-	pdhMoreData:                           "PDH_MORE_DATA",
-	pdhCstatusItemNotValidated:            "PDH_CSTATUS_ITEM_NOT_VALIDATED",
-	pdhRetry:                              "PDH_RETRY",
-	pdhNoData:                             "PDH_NO_DATA",
-	pdhCalcNegativeDenominator:            "PDH_CALC_NEGATIVE_DENOMINATOR",
-	pdhCalcNegativeTimebase:               "PDH_CALC_NEGATIVE_TIMEBASE",
-	pdhCalcNegativeValue:                  "PDH_CALC_NEGATIVE_VALUE",
-	pdhDialogCancelled:                    "PDH_DIALOG_CANCELLED",
-	pdhEndOfLogFile:                       "PDH_END_OF_LOG_FILE",
-	pdhAsyncQueryTimeout:                  "PDH_ASYNC_QUERY_TIMEOUT",
-	pdhCannotSetDefaultRealtimeDatasource: "PDH_CANNOT_SET_DEFAULT_REALTIME_DATASOURCE",
-	pdhCstatusNoObject:                    "PDH_CSTATUS_NO_OBJECT",
-	pdhCstatusNoCounter:                   "PDH_CSTATUS_NO_COUNTER",
-	pdhCstatusInvalidData:                 "PDH_CSTATUS_INVALID_DATA",
-	pdhMemoryAllocationFailure:            "PDH_MEMORY_ALLOCATION_FAILURE",
-	pdhInvalidHandle:                      "PDH_INVALID_HANDLE",
-	pdhInvalidArgument:                    "PDH_INVALID_ARGUMENT",
-	pdhFunctionNotFound:                   "PDH_FUNCTION_NOT_FOUND",
-	pdhCstatusNoCountername:               "PDH_CSTATUS_NO_COUNTERNAME",
-	pdhCstatusBadCountername:              "PDH_CSTATUS_BAD_COUNTERNAME",
-	pdhInvalidBuffer:                      "PDH_INVALID_BUFFER",
-	pdhInsufficientBuffer:                 "PDH_INSUFFICIENT_BUFFER",
-	pdhCannotConnectMachine:               "PDH_CANNOT_CONNECT_MACHINE",
-	pdhInvalidPath:                        "PDH_INVALID_PATH",
-	pdhInvalidInstance:                    "PDH_INVALID_INSTANCE",
-	pdhInvalidData:                        "PDH_INVALID_DATA",
-	pdhNoDialogData:                       "PDH_NO_DIALOG_DATA",
-	pdhCannotReadNameStrings:              "PDH_CANNOT_READ_NAME_STRINGS",
-	pdhLogFileCreateError:                 "PDH_LOG_FILE_CREATE_ERROR",
-	pdhLogFileOpenError:                   "PDH_LOG_FILE_OPEN_ERROR",
-	pdhLogTypeNotFound:                    "PDH_LOG_TYPE_NOT_FOUND",
-	pdhNoMoreData:                         "PDH_NO_MORE_DATA",
-	pdhEntryNotInLogFile:                  "PDH_ENTRY_NOT_IN_LOG_FILE",
-	pdhDataSourceIsLogFile:                "PDH_DATA_SOURCE_IS_LOG_FILE",
-	pdhDataSourceIsRealTime:               "PDH_DATA_SOURCE_IS_REAL_TIME",
-	pdhUnableReadLogHeader:                "PDH_UNABLE_READ_LOG_HEADER",
-	pdhFileNotFound:                       "PDH_FILE_NOT_FOUND",
-	pdhFileAlreadyExists:                  "PDH_FILE_ALREADY_EXISTS",
-	pdhNotImplemented:                     "PDH_NOT_IMPLEMENTED",
-	pdhStringNotFound:                     "PDH_STRING_NOT_FOUND",
-	pdhUnableMapNameFiles:                 "PDH_UNABLE_MAP_NAME_FILES",
-	pdhUnknownLogFormat:                   "PDH_UNKNOWN_LOG_FORMAT",
-	pdhUnknownLogsvcCommand:               "PDH_UNKNOWN_LOGSVC_COMMAND",
-	pdhLogsvcQueryNotFound:                "PDH_LOGSVC_QUERY_NOT_FOUND",
-	pdhLogsvcNotOpened:                    "PDH_LOGSVC_NOT_OPENED",
-	pdhWbemError:                          "PDH_WBEM_ERROR",
-	pdhAccessDenied:                       "PDH_ACCESS_DENIED",
-	pdhLogFileTooSmall:                    "PDH_LOG_FILE_TOO_SMALL",
-	pdhInvalidDatasource:                  "PDH_INVALID_DATASOURCE",
-	pdhInvalidSqldb:                       "PDH_INVALID_SQLDB",
-	pdhNoCounters:                         "PDH_NO_COUNTERS",
-	pdhSQLAllocFailed:                     "PDH_SQL_ALLOC_FAILED",
-	pdhSQLAllocconFailed:                  "PDH_SQL_ALLOCCON_FAILED",
-	pdhSQLExecDirectFailed:                "PDH_SQL_EXEC_DIRECT_FAILED",
-	pdhSQLFetchFailed:                     "PDH_SQL_FETCH_FAILED",
-	pdhSQLRowcountFailed:                  "PDH_SQL_ROWCOUNT_FAILED",
-	pdhSQLMoreResultsFailed:               "PDH_SQL_MORE_RESULTS_FAILED",
-	pdhSQLConnectFailed:                   "PDH_SQL_CONNECT_FAILED",
-	pdhSQLBindFailed:                      "PDH_SQL_BIND_FAILED",
-	pdhCannotConnectWmiServer:             "PDH_CANNOT_CONNECT_WMI_SERVER",
-	pdhPlaCollectionAlreadyRunning:        "PDH_PLA_COLLECTION_ALREADY_RUNNING",
-	pdhPlaErrorScheduleOverlap:            "PDH_PLA_ERROR_SCHEDULE_OVERLAP",
-	pdhPlaCollectionNotFound:              "PDH_PLA_COLLECTION_NOT_FOUND",
-	pdhPlaErrorScheduleElapsed:            "PDH_PLA_ERROR_SCHEDULE_ELAPSED",
-	pdhPlaErrorNostart:                    "PDH_PLA_ERROR_NOSTART",
-	pdhPlaErrorAlreadyExists:              "PDH_PLA_ERROR_ALREADY_EXISTS",
-	pdhPlaErrorTypeMismatch:               "PDH_PLA_ERROR_TYPE_MISMATCH",
-	pdhPlaErrorFilepath:                   "PDH_PLA_ERROR_FILEPATH",
-	pdhPlaServiceError:                    "PDH_PLA_SERVICE_ERROR",
-	pdhPlaValidationError:                 "PDH_PLA_VALIDATION_ERROR",
-	pdhPlaValidationWarning:               "PDH_PLA_VALIDATION_WARNING",
-	pdhPlaErrorNameTooLong:                "PDH_PLA_ERROR_NAME_TOO_LONG",
-	pdhInvalidSQLLogFormat:                "PDH_INVALID_SQL_LOG_FORMAT",
-	pdhCounterAlreadyInQuery:              "PDH_COUNTER_ALREADY_IN_QUERY",
-	pdhBinaryLogCorrupt:                   "PDH_BINARY_LOG_CORRUPT",
-	pdhLogSampleTooSmall:                  "PDH_LOG_SAMPLE_TOO_SMALL",
-	pdhOsLaterVersion:                     "PDH_OS_LATER_VERSION",
-	pdhOsEarlierVersion:                   "PDH_OS_EARLIER_VERSION",
-	pdhIncorrectAppendTime:                "PDH_INCORRECT_APPEND_TIME",
-	pdhUnmatchedAppendCounter:             "PDH_UNMATCHED_APPEND_COUNTER",
-	pdhSQLAlterDetailFailed:               "PDH_SQL_ALTER_DETAIL_FAILED",
-	pdhQueryPerfDataTimeout:               "PDH_QUERY_PERF_DATA_TIMEOUT",
-}
-
 // Formatting options for GetFormattedCounterValue().
 const (
 	pdhFmtRaw          = 0x00000010
@@ -218,9 +129,43 @@ const (
 	perfDetailStandard = 0x0000FFFF
 )
 
+// Counter type bits from winperf.h, as returned in the DwType field of pdhCounterInfo.
+const (
+	perfSizeMask        = 0x00000300
+	perfSizeLarge       = 0x00000100
+	perfTypeMask        = 0x00000C00
+	perfTypeNumber      = 0x00000000
+	perfTypeCounter     = 0x00000400
+	perfTypeText        = 0x00000800
+	perfCounterSubMask  = 0x000F0000 // PERF_COUNTER_VALUE, PERF_COUNTER_RATE, ... of PERF_TYPE_COUNTER
+	perfNumberMask      = 0x00030000
+	perfNumberDec1000   = 0x00020000
+	perfCounterRawcount = 0x00010000 // PERF_SIZE_DWORD | PERF_TYPE_NUMBER | PERF_NUMBER_DECIMAL
+	perfCounterCounter  = 0x10410400 // per-second rate of a DWORD counter
+)
+
+// Flags for PdhExpandWildCardPath() limiting which wildcards of the path are expanded.
+const (
+	pdhNoExpandCounters  = 1 // do not expand wildcards in the counter name
+	pdhNoExpandInstances = 2 // do not expand wildcards in the instance name
+)
+
+// perfDetailWizard is the detail level for PdhEnumObjects() and PdhEnumObjectItems() returning all counters.
+const perfDetailWizard = 400
+
+// Log file access flags and types for PdhOpenLog()
+const (
+	pdhLogWriteAccess  = 0x00020000
+	pdhLogCreateAlways = 0x00000002
+	pdhLogTypeCSV      = 1
+	pdhLogTypeTSV      = 2
+	pdhLogTypeBinary   = 8
+)
+
 type (
 	pdhQueryHandle   handle // query handle
 	pdhCounterHandle handle // counter handle
+	pdhLogHandle     handle // log handle
 )
 
 var (
@@ -231,16 +176,28 @@ var (
 	pdhAddCounterWProc               *syscall.Proc
 	pdhAddEnglishCounterWProc        *syscall.Proc
 	pdhCloseQueryProc                *syscall.Proc
+	pdhRemoveCounterProc             *syscall.Proc
 	pdhCollectQueryDataProc          *syscall.Proc
 	pdhCollectQueryDataWithTimeProc  *syscall.Proc
 	pdhGetFormattedCounterValueProc  *syscall.Proc
 	pdhGetFormattedCounterArrayWProc *syscall.Proc
 	pdhOpenQueryProc                 *syscall.Proc
 	pdhExpandWildCardPathWProc       *syscall.Proc
+	pdhExpandWildCardPathHWProc      *syscall.Proc
 	pdhGetCounterInfoWProc           *syscall.Proc
 	pdhGetRawCounterValueProc        *syscall.Proc
 	pdhGetRawCounterArrayWProc       *syscall.Proc
 	pdhValidatePathWProc             *syscall.Proc
+	pdhLookupPerfIndexByNameWProc    *syscall.Proc
+	pdhLookupPerfNameByIndexWProc    *syscall.Proc
+	pdhEnumObjectsWProc              *syscall.Proc
+	pdhEnumObjectItemsWProc          *syscall.Proc
+	pdhEnumMachinesWProc             *syscall.Proc
+	pdhConnectMachineWProc           *syscall.Proc
+	pdhOpenLogWProc                  *syscall.Proc
+	pdhUpdateLogWProc                *syscall.Proc
+	pdhCloseLogProc                  *syscall.Proc
+	pdhCalculateCounterFromRawProc   *syscall.Proc
 )
 
 func init() {
@@ -251,16 +208,28 @@ func init() {
 	pdhAddCounterWProc = libPdhDll.MustFindProc("PdhAddCounterW")
 	pdhAddEnglishCounterWProc, _ = libPdhDll.FindProc("PdhAddEnglishCounterW") // XXX: only supported on versions > Vista.
 	pdhCloseQueryProc = libPdhDll.MustFindProc("PdhCloseQuery")
+	pdhRemoveCounterProc = libPdhDll.MustFindProc("PdhRemoveCounter")
 	pdhCollectQueryDataProc = libPdhDll.MustFindProc("PdhCollectQueryData")
 	pdhCollectQueryDataWithTimeProc, _ = libPdhDll.FindProc("PdhCollectQueryDataWithTime")
 	pdhGetFormattedCounterValueProc = libPdhDll.MustFindProc("PdhGetFormattedCounterValue")
 	pdhGetFormattedCounterArrayWProc = libPdhDll.MustFindProc("PdhGetFormattedCounterArrayW")
 	pdhOpenQueryProc = libPdhDll.MustFindProc("PdhOpenQuery")
 	pdhExpandWildCardPathWProc = libPdhDll.MustFindProc("PdhExpandWildCardPathW")
+	pdhExpandWildCardPathHWProc, _ = libPdhDll.FindProc("PdhExpandWildCardPathHW") // XXX: only supported on versions > Vista.
 	pdhGetCounterInfoWProc = libPdhDll.MustFindProc("PdhGetCounterInfoW")
 	pdhGetRawCounterValueProc = libPdhDll.MustFindProc("PdhGetRawCounterValue")
 	pdhGetRawCounterArrayWProc = libPdhDll.MustFindProc("PdhGetRawCounterArrayW")
 	pdhValidatePathWProc = libPdhDll.MustFindProc("PdhValidatePathW")
+	pdhLookupPerfIndexByNameWProc = libPdhDll.MustFindProc("PdhLookupPerfIndexByNameW")
+	pdhLookupPerfNameByIndexWProc = libPdhDll.MustFindProc("PdhLookupPerfNameByIndexW")
+	pdhEnumObjectsWProc = libPdhDll.MustFindProc("PdhEnumObjectsW")
+	pdhEnumObjectItemsWProc = libPdhDll.MustFindProc("PdhEnumObjectItemsW")
+	pdhEnumMachinesWProc = libPdhDll.MustFindProc("PdhEnumMachinesW")
+	pdhConnectMachineWProc = libPdhDll.MustFindProc("PdhConnectMachineW")
+	pdhOpenLogWProc = libPdhDll.MustFindProc("PdhOpenLogW")
+	pdhUpdateLogWProc = libPdhDll.MustFindProc("PdhUpdateLogW")
+	pdhCloseLogProc = libPdhDll.MustFindProc("PdhCloseLog")
+	pdhCalculateCounterFromRawProc = libPdhDll.MustFindProc("PdhCalculateCounterFromRawValue")
 }
 
 // pdhAddCounter adds the specified counter to the query. This is the internationalized version. Preferably, use the
@@ -343,6 +312,14 @@ func pdhCloseQuery(hQuery pdhQueryHandle) uint32 {
 	return uint32(ret)
 }
 
+// pdhRemoveCounter removes a counter from a query. The counter handle is invalid after the call,
+// the remaining counters of the query keep their handles and collected samples.
+func pdhRemoveCounter(hCounter pdhCounterHandle) uint32 {
+	ret, _, _ := pdhRemoveCounterProc.Call(uintptr(hCounter))
+
+	return uint32(ret)
+}
+
 // pdhCollectQueryData collects the current raw data value for all counters in the specified query and updates the status
 // code of each counter. With some counters, this function needs to be repeatedly called before the value
 // of the counter can be extracted with PdhGetFormattedCounterValue(). For example, the following code
@@ -370,35 +347,52 @@ func pdhCollectQueryData(hQuery pdhQueryHandle) uint32 {
 	return uint32(ret)
 }
 
+// pdhCollectQueryDataWithTimeSupported returns true if PdhCollectQueryDataWithTime Win API function was found in pdh.dll.
+// PdhCollectQueryDataWithTime function is not supported on pre-Windows Vista systems
+func pdhCollectQueryDataWithTimeSupported() bool {
+	return pdhCollectQueryDataWithTimeProc != nil
+}
+
 // pdhCollectQueryDataWithTime queries data from perfmon, retrieving the device/windows timestamp from the node it was collected on.
 // Converts the filetime structure to a GO time class and returns the native time.
 func pdhCollectQueryDataWithTime(hQuery pdhQueryHandle) (uint32, time.Time) {
+	if pdhCollectQueryDataWithTimeProc == nil {
+		return errorInvalidFunction, time.Now()
+	}
+
 	var localFileTime fileTime
 	//nolint:gosec // G103: Valid use of unsafe call to pass localFileTime
 	ret, _, _ := pdhCollectQueryDataWithTimeProc.Call(uintptr(hQuery), uintptr(unsafe.Pointer(&localFileTime)))
 
 	if ret == errorSuccess {
-		var utcFileTime fileTime
-		ret, _, _ := kernelLocalFileTimeToFileTime.Call(
-			uintptr(unsafe.Pointer(&localFileTime)), //nolint:gosec // G103: Valid use of unsafe call to pass localFileTime
-			uintptr(unsafe.Pointer(&utcFileTime)))   //nolint:gosec // G103: Valid use of unsafe call to pass utcFileTime
-
-		if ret == 0 {
+		retTime, ok := localFileTimeToTime(localFileTime)
+		if !ok {
 			return uint32(errorFailure), time.Now()
 		}
+		return uint32(errorSuccess), retTime
+	}
 
-		// First convert 100-ns intervals to microseconds, then adjust for the
-		// epoch difference
-		var totalMicroSeconds int64
-		totalMicroSeconds = ((int64(utcFileTime.dwHighDateTime) << 32) | int64(utcFileTime.dwLowDateTime)) / 10
-		totalMicroSeconds -= epochDifferenceMicros
+	return uint32(ret), time.Now()
+}
 
-		retTime := time.Unix(0, totalMicroSeconds*1000)
+// localFileTimeToTime converts a FILETIME in local time, as returned by PDH, to a GO time.
+func localFileTimeToTime(localFileTime fileTime) (time.Time, bool) {
+	var utcFileTime fileTime
+	ret, _, _ := kernelLocalFileTimeToFileTime.Call(
+		uintptr(unsafe.Pointer(&localFileTime)), //nolint:gosec // G103: Valid use of unsafe call to pass localFileTime
+		uintptr(unsafe.Pointer(&utcFileTime)))   //nolint:gosec // G103: Valid use of unsafe call to pass utcFileTime
 
-		return uint32(errorSuccess), retTime
+	if ret == 0 {
+		return time.Time{}, false
 	}
 
-	return uint32(ret), time.Now()
+	// First convert 100-ns intervals to microseconds, then adjust for the
+	// epoch difference
+	var totalMicroSeconds int64
+	totalMicroSeconds = ((int64(utcFileTime.dwHighDateTime) << 32) | int64(utcFileTime.dwLowDateTime)) / 10
+	totalMicroSeconds -= epochDifferenceMicros
+
+	return time.Unix(0, totalMicroSeconds*1000), true
 }
 
 // pdhGetFormattedCounterValueLong Formats the given hCounter using a 'long'. The result is set into the specialized union struct pValue.
@@ -562,15 +556,37 @@ func pdhOpenQuery(szDataSource, dwUserData uintptr, phQuery *pdhQueryHandle) uin
 // If a wildcard character is specified in the counter name, all counters of the specified object are returned.
 //
 // Partial counter path string matches (for example, "pro*") are supported.
-func pdhExpandWildCardPath(szWildCardPath string, mszExpandedPathList *uint16, pcchPathListLength *uint32) uint32 {
+//
+// szDataSource is the log file to search, if empty the counters on the local computer are searched.
+// dwFlags is 0 to expand instances and counters, or pdhNoExpandCounters / pdhNoExpandInstances to leave that part of the path as is.
+func pdhExpandWildCardPath(szDataSource, szWildCardPath string, mszExpandedPathList *uint16, pcchPathListLength *uint32, dwFlags uint32) uint32 {
+	var dataSource uintptr
+	if szDataSource != "" {
+		psrc, _ := syscall.UTF16PtrFromString(szDataSource)
+		dataSource = uintptr(unsafe.Pointer(psrc)) //nolint:gosec // G103: Valid use of unsafe call to pass psrc
+	}
 	ptxt, _ := syscall.UTF16PtrFromString(szWildCardPath)
-	flags := uint32(0) // expand instances and counters
 	ret, _, _ := pdhExpandWildCardPathWProc.Call(
-		0,                             // search counters on local computer
+		dataSource,
 		uintptr(unsafe.Pointer(ptxt)), //nolint:gosec // G103: Valid use of unsafe call to pass ptxt
 		uintptr(unsafe.Pointer(mszExpandedPathList)), //nolint:gosec // G103: Valid use of unsafe call to pass mszExpandedPathList
 		uintptr(unsafe.Pointer(pcchPathListLength)),  //nolint:gosec // G103: Valid use of unsafe call to pass pcchPathListLength
-		uintptr(unsafe.Pointer(&flags)))              //nolint:gosec // G103: Valid use of unsafe call to pass flags
+		uintptr(dwFlags))
+
+	return uint32(ret)
+}
+
+// pdhExpandWildCardPathH is the handle-based version of pdhExpandWildCardPath. hDataSource is the data source handle
+// returned by PdhBindInputDataSource, 0 (H_REALTIME_DATASOURCE) searches the computer specified in the wildcard path,
+// which needs to be machine-qualified (\\computer\object(*)\counter) to expand against a remote computer.
+func pdhExpandWildCardPathH(hDataSource pdhLogHandle, szWildCardPath string, mszExpandedPathList *uint16, pcchPathListLength *uint32, dwFlags uint32) uint32 {
+	ptxt, _ := syscall.UTF16PtrFromString(szWildCardPath)
+	ret, _, _ := pdhExpandWildCardPathHWProc.Call(
+		uintptr(hDataSource),
+		uintptr(unsafe.Pointer(ptxt)),                //nolint:gosec // G103: Valid use of unsafe call to pass ptxt
+		uintptr(unsafe.Pointer(mszExpandedPathList)), //nolint:gosec // G103: Valid use of unsafe call to pass mszExpandedPathList
+		uintptr(unsafe.Pointer(pcchPathListLength)),  //nolint:gosec // G103: Valid use of unsafe call to pass pcchPathListLength
+		uintptr(dwFlags))
 
 	return uint32(ret)
 }
@@ -580,7 +596,11 @@ func pdhFormatError(msgID uint32) string {
 	buf := make([]uint16, 300)
 	_, err := windows.FormatMessage(flags, uintptr(libPdhDll.Handle), msgID, 0, buf, nil)
 	if err == nil {
-		return utf16PtrToString(&buf[0])
+		return stripMessageInserts(utf16PtrToString(&buf[0]))
+	}
+	// pdh.dll 中没有消息资源时（例如精简的系统镜像）使用生成的说明文字
+	if description := stripMessageInserts(pdhErrorDescriptions[msgID]); description != "" {
+		return description
 	}
 	return fmt.Sprintf("(pdhErr=%d) %s", msgID, err.Error())
 }
@@ -634,6 +654,28 @@ func pdhGetRawCounterValue(hCounter pdhCounterHandle, lpdwType *uint32, pValue *
 	return uint32(ret)
 }
 
+// pdhCalculateCounterFromRawValue calculates the displayable value of a counter from two raw counter values,
+// using the counter type and scale of the given counter. The result is set into the specialized union struct pValue.
+//
+// hCounter [in]
+// Handle of the counter whose type and scale are used to calculate the value.
+//
+// rawValue1 [in]
+// The newer raw counter value.
+//
+// rawValue2 [in]
+// The older raw counter value. May be nil for counters that only require one value.
+func pdhCalculateCounterFromRawValue(hCounter pdhCounterHandle, rawValue1, rawValue2 *pdhRawCounter, pValue *pdhFmtCounterValueDouble) uint32 {
+	ret, _, _ := pdhCalculateCounterFromRawProc.Call(
+		uintptr(hCounter),
+		uintptr(pdhFmtDouble|pdhFmtNocap100),
+		uintptr(unsafe.Pointer(rawValue1)), //nolint:gosec // G103: Valid use of unsafe call to pass rawValue1
+		uintptr(unsafe.Pointer(rawValue2)), //nolint:gosec // G103: Valid use of unsafe call to pass rawValue2
+		uintptr(unsafe.Pointer(pValue)))    //nolint:gosec // G103: Valid use of unsafe call to pass pValue
+
+	return uint32(ret)
+}
+
 // pdhGetRawCounterArray returns an array of raw values from the specified counter. Use this function when you want to retrieve the raw counter values
 // of a counter that contains a wildcard character for the instance name.
 // hCounter
@@ -667,3 +709,149 @@ func pdhValidatePath(path string) uint32 {
 
 	return uint32(ret)
 }
+
+// pdhLookupPerfIndexByName returns the counter index corresponding to the specified counter name.
+// szMachineName is the computer on which the name is looked up, an empty string means the local computer.
+// szNameBuffer is the (localized) name of a performance object or counter.
+func pdhLookupPerfIndexByName(szMachineName string, szNameBuffer string, pdwIndex *uint32) uint32 {
+	var machine *uint16
+	if szMachineName != "" {
+		machine, _ = syscall.UTF16PtrFromString(szMachineName)
+	}
+	pname, _ := syscall.UTF16PtrFromString(szNameBuffer)
+	ret, _, _ := pdhLookupPerfIndexByNameWProc.Call(
+		uintptr(unsafe.Pointer(machine)),  //nolint:gosec // G103: Valid use of unsafe call to pass machine
+		uintptr(unsafe.Pointer(pname)),    //nolint:gosec // G103: Valid use of unsafe call to pass pname
+		uintptr(unsafe.Pointer(pdwIndex))) //nolint:gosec // G103: Valid use of unsafe call to pass pdwIndex
+
+	return uint32(ret)
+}
+
+// pdhLookupPerfNameByIndex returns the (localized) performance object or counter name corresponding to the
+// specified index. szMachineName is the computer on which the index is looked up, an empty string means the
+// local computer. pcchNameBufferSize is the size of szNameBuffer in characters.
+func pdhLookupPerfNameByIndex(szMachineName string, dwNameIndex uint32, szNameBuffer *uint16, pcchNameBufferSize *uint32) uint32 {
+	var machine *uint16
+	if szMachineName != "" {
+		machine, _ = syscall.UTF16PtrFromString(szMachineName)
+	}
+	ret, _, _ := pdhLookupPerfNameByIndexWProc.Call(
+		uintptr(unsafe.Pointer(machine)), //nolint:gosec // G103: Valid use of unsafe call to pass machine
+		uintptr(dwNameIndex),
+		uintptr(unsafe.Pointer(szNameBuffer)),       //nolint:gosec // G103: Valid use of unsafe call to pass szNameBuffer
+		uintptr(unsafe.Pointer(pcchNameBufferSize))) //nolint:gosec // G103: Valid use of unsafe call to pass pcchNameBufferSize
+
+	return uint32(ret)
+}
+
+// pdhEnumObjects returns a list of objects available on the specified computer as a multi-string in mszObjectList.
+// szMachineName is the computer (e.g. \\SERVER) to enumerate, an empty string means the local computer.
+// pcchBufferSize is the size of mszObjectList in characters; if the buffer is too small PDH_MORE_DATA is returned
+// and the required size is set. bRefresh requests to refresh the cached object list of the computer.
+func pdhEnumObjects(szMachineName string, mszObjectList *uint16, pcchBufferSize *uint32, dwDetailLevel uint32, bRefresh bool) uint32 {
+	var machine *uint16
+	if szMachineName != "" {
+		machine, _ = syscall.UTF16PtrFromString(szMachineName)
+	}
+	var refresh uintptr
+	if bRefresh {
+		refresh = 1
+	}
+	ret, _, _ := pdhEnumObjectsWProc.Call(
+		0,                                       // use the current real-time data source
+		uintptr(unsafe.Pointer(machine)),        //nolint:gosec // G103: Valid use of unsafe call to pass machine
+		uintptr(unsafe.Pointer(mszObjectList)),  //nolint:gosec // G103: Valid use of unsafe call to pass mszObjectList
+		uintptr(unsafe.Pointer(pcchBufferSize)), //nolint:gosec // G103: Valid use of unsafe call to pass pcchBufferSize
+		uintptr(dwDetailLevel),
+		refresh)
+
+	return uint32(ret)
+}
+
+// pdhEnumObjectItems returns the counters and instances of the specified object as multi-strings in
+// mszCounterList and mszInstanceList. The buffers may be nil with a size of zero to query the required sizes,
+// in which case PDH_MORE_DATA is returned. Objects without instances return an instance list size of zero.
+func pdhEnumObjectItems(
+	szMachineName string,
+	szObjectName string,
+	mszCounterList *uint16,
+	pcchCounterListLength *uint32,
+	mszInstanceList *uint16,
+	pcchInstanceListLength *uint32,
+	dwDetailLevel uint32,
+) uint32 {
+	var machine *uint16
+	if szMachineName != "" {
+		machine, _ = syscall.UTF16PtrFromString(szMachineName)
+	}
+	pobject, _ := syscall.UTF16PtrFromString(szObjectName)
+	ret, _, _ := pdhEnumObjectItemsWProc.Call(
+		0,                                       // use the current real-time data source
+		uintptr(unsafe.Pointer(machine)),        //nolint:gosec // G103: Valid use of unsafe call to pass machine
+		uintptr(unsafe.Pointer(pobject)),        //nolint:gosec // G103: Valid use of unsafe call to pass pobject
+		uintptr(unsafe.Pointer(mszCounterList)), //nolint:gosec // G103: Valid use of unsafe call to pass mszCounterList
+		uintptr(unsafe.Pointer(pcchCounterListLength)),  //nolint:gosec // G103: Valid use of unsafe call to pass pcchCounterListLength
+		uintptr(unsafe.Pointer(mszInstanceList)),        //nolint:gosec // G103: Valid use of unsafe call to pass mszInstanceList
+		uintptr(unsafe.Pointer(pcchInstanceListLength)), //nolint:gosec // G103: Valid use of unsafe call to pass pcchInstanceListLength
+		uintptr(dwDetailLevel),
+		0) // dwFlags must be zero
+
+	return uint32(ret)
+}
+
+// pdhEnumMachines returns a list of the computers PDH has connected to in this process as a multi-string in
+// mszMachineList. pcchBufferSize is the size of mszMachineList in characters; if the buffer is too small
+// PDH_MORE_DATA is returned and the required size is set.
+func pdhEnumMachines(mszMachineList *uint16, pcchBufferSize *uint32) uint32 {
+	ret, _, _ := pdhEnumMachinesWProc.Call(
+		0,                                       // use the current real-time data source
+		uintptr(unsafe.Pointer(mszMachineList)), //nolint:gosec // G103: Valid use of unsafe call to pass mszMachineList
+		uintptr(unsafe.Pointer(pcchBufferSize))) //nolint:gosec // G103: Valid use of unsafe call to pass pcchBufferSize
+
+	return uint32(ret)
+}
+
+// pdhConnectMachine connects to the specified computer (e.g. \\SERVER) and adds it to the list of machines
+// returned by pdhEnumMachines. An empty szMachineName connects to the local computer.
+func pdhConnectMachine(szMachineName string) uint32 {
+	var machine *uint16
+	if szMachineName != "" {
+		machine, _ = syscall.UTF16PtrFromString(szMachineName)
+	}
+	ret, _, _ := pdhConnectMachineWProc.Call(uintptr(unsafe.Pointer(machine))) //nolint:gosec // G103: Valid use of unsafe call to pass machine
+
+	return uint32(ret)
+}
+
+// pdhOpenLog opens the log file szLogFileName for writing the counters of hQuery. dwAccessFlags is a combination of
+// pdhLogWriteAccess and a creation flag such as pdhLogCreateAlways, dwLogType is one of the pdhLogType constants.
+// phLog is the handle to the log, and must be used in subsequent calls.
+func pdhOpenLog(szLogFileName string, dwAccessFlags uint32, dwLogType uint32, hQuery pdhQueryHandle, phLog *pdhLogHandle) uint32 {
+	pname, _ := syscall.UTF16PtrFromString(szLogFileName)
+	logType := dwLogType
+	ret, _, _ := pdhOpenLogWProc.Call(
+		uintptr(unsafe.Pointer(pname)), //nolint:gosec // G103: Valid use of unsafe call to pass pname
+		uintptr(dwAccessFlags),
+		uintptr(unsafe.Pointer(&logType)), //nolint:gosec // G103: Valid use of unsafe call to pass logType
+		uintptr(hQuery),
+		0,                              // no maximum log file size
+		0,                              // no user caption
+		uintptr(unsafe.Pointer(phLog))) //nolint:gosec // G103: Valid use of unsafe call to pass phLog
+
+	return uint32(ret)
+}
+
+// pdhUpdateLog collects the data of the query associated with hLog and writes it as a new record to the log.
+func pdhUpdateLog(hLog pdhLogHandle) uint32 {
+	ret, _, _ := pdhUpdateLogWProc.Call(uintptr(hLog), 0)
+
+	return uint32(ret)
+}
+
+// pdhCloseLog closes the log file hLog. The query associated with the log is closed as well.
+func pdhCloseLog(hLog pdhLogHandle) uint32 {
+	const pdhFlagsCloseQuery = 0x00000001
+	ret, _, _ := pdhCloseLogProc.Call(uintptr(hLog), pdhFlagsCloseQuery)
+
+	return uint32(ret)
+}
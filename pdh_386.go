@@ -110,3 +110,9 @@ type pdhRawCounterItem struct {
 	//A pdhRawCounter structure that contains the raw counter value of the instance
 	RawValue pdhRawCounter
 }
+
+// ulongLongArgs 将 64 位参数转换为系统调用的参数，32 位 x86 的 stdcall 按低、高两个 32 位参数传递，
+// 直接转换为 uintptr 会截断高 32 位。
+func ulongLongArgs(value uint64) []uintptr {
+	return []uintptr{uintptr(uint32(value)), uintptr(value >> 32)}
+}
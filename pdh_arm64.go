@@ -103,3 +103,8 @@ type pdhRawCounterItem struct {
 	//A pdhRawCounter structure that contains the raw counter value of the instance
 	RawValue pdhRawCounter
 }
+
+// ulongLongArgs 将 64 位参数转换为系统调用的参数。
+func ulongLongArgs(value uint64) []uintptr {
+	return []uintptr{uintptr(value)}
+}
@@ -0,0 +1,155 @@
+//go:build ignore
+
+// pdh_errors_gen.go 从 Windows SDK 或 mingw-w64 的 pdhmsg.h 生成 pdh_errors.go 中的 PDH 错误码表（错误码、名称与说明文字），
+// 不依赖 Windows，可以在任意平台运行：
+//
+//	go run pdh_errors_gen.go -header "C:\Program Files (x86)\Windows Kits\10\Include\10.0.22621.0\um\pdhmsg.h"
+//
+// 未指定 -header 时依次使用 PDHMSG_H 环境变量、最新的 Windows SDK 以及常见的 mingw-w64 路径。
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// defineLine 形如 `#define PDH_CSTATUS_NO_OBJECT ((DWORD)0xC0000BB8L)` 的错误码定义。
+var defineLine = regexp.MustCompile(`^#define\s+(PDH_\w+)\s+\(\((?:DWORD|PDH_STATUS)\)\s*(0x[0-9A-Fa-f]+)L?\)`)
+
+type pdhError struct {
+	code        uint32
+	name        string
+	description string
+}
+
+func main() {
+	header := flag.String("header", "", "path of pdhmsg.h")
+	output := flag.String("o", "pdh_errors.go", "output file")
+	flag.Parse()
+
+	path := *header
+	if path == "" {
+		path = findHeader()
+	}
+	if path == "" {
+		log.Fatal("pdhmsg.h not found, specify it with -header or PDHMSG_H")
+	}
+	errs, err := parseHeader(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	source, err := generate(errs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("wrote %d PDH errors from %s to %s\n", len(errs), path, *output)
+}
+
+// findHeader 返回 PDHMSG_H 环境变量、最新的 Windows SDK 或 mingw-w64 中的 pdhmsg.h。
+func findHeader() string {
+	if path := os.Getenv("PDHMSG_H"); path != "" {
+		return path
+	}
+	// SDK 的版本目录按名称排序后最后一个为最新版本
+	sdk, _ := filepath.Glob(`C:\Program Files (x86)\Windows Kits\10\Include\*\um\pdhmsg.h`)
+	if len(sdk) > 0 {
+		slices.Sort(sdk)
+		return sdk[len(sdk)-1]
+	}
+	for _, path := range []string{
+		"/usr/share/mingw-w64/include/pdhmsg.h",
+		"/usr/x86_64-w64-mingw32/include/pdhmsg.h",
+		"/usr/i686-w64-mingw32/include/pdhmsg.h",
+	} {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// parseHeader 解析 pdhmsg.h 中由 mc.exe 生成的注释块：
+//
+//	// MessageId: PDH_CSTATUS_NO_OBJECT
+//	//
+//	// MessageText:
+//	//
+//	// The specified object was not found on the computer.
+//	//
+//	#define PDH_CSTATUS_NO_OBJECT            ((DWORD)0xC0000BB8L)
+func parseHeader(path string) ([]pdhError, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var errs []pdhError
+	var text []string
+	inText := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "// MessageId:"):
+			text, inText = nil, false
+		case strings.HasPrefix(line, "// MessageText:"):
+			inText = true
+		case inText && strings.HasPrefix(line, "//"):
+			if part := strings.TrimSpace(strings.TrimPrefix(line, "//")); part != "" {
+				text = append(text, part)
+			}
+		case defineLine.MatchString(line):
+			match := defineLine.FindStringSubmatch(line)
+			code, err := strconv.ParseUint(match[2], 0, 32)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", match[1], err)
+			}
+			errs = append(errs, pdhError{code: uint32(code), name: match[1], description: strings.Join(text, " ")})
+			text, inText = nil, false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no PDH errors found in %s", path)
+	}
+	// 按不含严重级别的编号排序，与头文件中的顺序一致
+	slices.SortFunc(errs, func(a, b pdhError) int { return cmp.Compare(a.code&0xFFFF, b.code&0xFFFF) })
+	return errs, nil
+}
+
+// generate 生成 pdh_errors.go 的源代码。
+func generate(errs []pdhError) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by pdh_errors_gen.go from pdhmsg.h; DO NOT EDIT.\n\n")
+	b.WriteString("package win_perf_counters\n\n")
+	b.WriteString("// pdhErrors PDH 错误码到其名称的映射。\n")
+	b.WriteString("var pdhErrors = map[uint32]string{\n")
+	for _, e := range errs {
+		fmt.Fprintf(&b, "\t0x%08X: %q,\n", e.code, e.name)
+	}
+	b.WriteString("}\n\n")
+	b.WriteString("// pdhErrorDescriptions PDH 错误码到 pdhmsg.h 中说明文字的映射。\n")
+	b.WriteString("var pdhErrorDescriptions = map[uint32]string{\n")
+	for _, e := range errs {
+		fmt.Fprintf(&b, "\t0x%08X: %q,\n", e.code, e.description)
+	}
+	b.WriteString("}\n")
+	return format.Source(b.Bytes())
+}
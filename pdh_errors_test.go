@@ -0,0 +1,54 @@
+package win_perf_counters
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribePDHError(t *testing.T) {
+	tests := []struct {
+		code uint32
+		want string
+	}{
+		{0xC0000BB8, "PDH_CSTATUS_NO_OBJECT (0xC0000BB8): The specified object was not found on the computer."},
+		{0xC0000BE3, "PDH_SQL_FETCH_FAILED (0xC0000BE3): Call to SQLFetch failed with."},
+		{0xC0000BE9, "PDH_PLA_COLLECTION_ALREADY_RUNNING (0xC0000BE9): Collection is already running."},
+		{0x12345678, "unknown PDH error (0x12345678)"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, DescribePDHError(tt.code))
+	}
+
+	for code := range pdhErrors {
+		require.NotContains(t, DescribePDHError(code), "%", "0x%08X", code)
+	}
+}
+
+func TestStripMessageInserts(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"The specified object was not found on the computer.\r\n", "The specified object was not found on the computer."},
+		{"Call to SQLFetch failed with %1.", "Call to SQLFetch failed with."},
+		{`Collection "%1!s!" does not exist.`, "Collection does not exist."},
+		{"%1 supports %2 or later. Check the operating system version on the computer named %3.",
+			"supports or later. Check the operating system version on the computer named."},
+		{"Processor usage is 100%.", "Processor usage is 100%."},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, stripMessageInserts(tt.message), tt.message)
+	}
+}
+
+func TestPDHErrorTables(t *testing.T) {
+	for code, description := range pdhErrorDescriptions {
+		name, ok := pdhErrors[code]
+		require.True(t, ok, "0x%08X has a description but no name", code)
+		require.True(t, strings.HasPrefix(name, "PDH_"), name)
+		require.NotEmpty(t, description, name)
+	}
+}
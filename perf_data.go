@@ -0,0 +1,316 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode/utf16"
+)
+
+// winperf.h 中 PERF_DATA_BLOCK、PERF_OBJECT_TYPE、PERF_COUNTER_DEFINITION 与 PERF_INSTANCE_DEFINITION 各字段的偏移。
+// 这些结构在 32 位与 64 位系统上布局相同（名称指针字段在 64 位系统上定义为 DWORD）。
+const (
+	perfDataSignature       = "PERF"
+	perfDataTotalLength     = 20
+	perfDataHeaderLength    = 24
+	perfDataNumObjectTypes  = 28
+	perfDataPerfTime        = 56
+	perfDataPerfFreq        = 64
+	perfDataPerfTime100nSec = 72
+	perfDataMinHeader       = 88
+
+	perfObjectTotalLength      = 0
+	perfObjectDefinitionLength = 4
+	perfObjectHeaderLength     = 8
+	perfObjectNameTitleIndex   = 12
+	perfObjectNumCounters      = 32
+	perfObjectNumInstances     = 40
+	perfObjectCodePage         = 44
+	perfObjectPerfTime         = 48
+	perfObjectPerfFreq         = 56
+	perfObjectMinHeader        = 64
+
+	perfCounterByteLength     = 0
+	perfCounterNameTitleIndex = 4
+	perfCounterType           = 28
+	perfCounterSize           = 32
+	perfCounterOffset         = 36
+	perfCounterMinLength      = 40
+
+	perfInstanceByteLength = 0
+	perfInstanceNameOffset = 16
+	perfInstanceNameLength = 20
+	perfInstanceMinLength  = 24
+
+	// perfNoInstances NumInstances 为该值时对象没有实例，计数器数据块紧随计数器定义之后。
+	perfNoInstances = -1
+)
+
+// winperf.h 中的计数器类型，用于按与 PDH 相同的公式计算格式化值。
+const (
+	perfTimer100ns  = 0x00100000 // PERF_TIMER_100NS：以 100 纳秒为时间基准
+	perfObjectTimer = 0x00200000 // PERF_OBJECT_TIMER：使用对象自身的时间戳与频率
+	perfCounterBase = 0x00030000 // PERF_COUNTER_BASE：分数类计数器的分母
+
+	perfCounterBulkCount       = 0x10410500
+	perfCounterTimer           = 0x20410500
+	perfCounterTimerInv        = 0x21410500
+	perf100nsecTimer           = 0x20510500
+	perf100nsecTimerInv        = 0x21510500
+	perfRawFraction            = 0x20020400
+	perfLargeRawFraction       = 0x20020500
+	perfSampleFraction         = 0x20C20400
+	perfAverageTimer           = 0x30020400
+	perfAverageBulk            = 0x40020500
+	perfElapsedTime            = 0x30240500
+	perfCounterDelta           = 0x00400400
+	perfCounterLargeDelta      = 0x00400500
+	perfCounterQueuelen        = 0x00450400
+	perfCounterLargeQueuelen   = 0x00450500
+	perfCounter100nsQueuelen   = 0x00550500
+	perfCounterObjTimeQueuelen = 0x00650500
+)
+
+// perfDataBlock 解析后的 PERF_DATA_BLOCK。
+type perfDataBlock struct {
+	perfTime      int64
+	perfFreq      int64
+	perfTime100ns int64
+	objects       []perfObject
+}
+
+// perfObject 解析后的 PERF_OBJECT_TYPE。
+type perfObject struct {
+	// index 对象名称在名称表中的索引。
+	index    uint32
+	perfTime int64
+	perfFreq int64
+	counters []perfCounterDef
+	// instances 对象的实例，没有实例的对象只有一个名称为空的实例。
+	instances []perfInstance
+}
+
+// perfCounterDef 解析后的 PERF_COUNTER_DEFINITION。
+type perfCounterDef struct {
+	// index 计数器名称在名称表中的索引。
+	index       uint32
+	counterType uint32
+	size        uint32
+	offset      uint32
+	// base 分数类计数器的分母在 counters 中的位置，没有分母时为 -1。
+	base int
+}
+
+// perfInstance 一个实例的名称及其 PERF_COUNTER_BLOCK。
+type perfInstance struct {
+	name string
+	data []byte
+}
+
+// errPerfDataTruncated 性能数据中的长度或偏移超出了数据范围。
+var errPerfDataTruncated = errors.New("performance data is truncated")
+
+// perfReader 带边界检查地读取性能数据中的小端整数。
+type perfReader []byte
+
+func (r perfReader) u32(offset int) (uint32, error) {
+	if offset < 0 || offset+4 > len(r) {
+		return 0, errPerfDataTruncated
+	}
+	return binary.LittleEndian.Uint32(r[offset:]), nil
+}
+
+func (r perfReader) i64(offset int) (int64, error) {
+	if offset < 0 || offset+8 > len(r) {
+		return 0, errPerfDataTruncated
+	}
+	return int64(binary.LittleEndian.Uint64(r[offset:])), nil //nolint:gosec // G115: LARGE_INTEGER is signed
+}
+
+// slice 返回 [offset, offset+length) 范围的数据。
+func (r perfReader) slice(offset, length int) (perfReader, error) {
+	if offset < 0 || length < 0 || offset+length > len(r) {
+		return nil, errPerfDataTruncated
+	}
+	return r[offset : offset+length], nil
+}
+
+// parsePerfData 解析从 HKEY_PERFORMANCE_DATA 读取的 PERF_DATA_BLOCK。
+func parsePerfData(data []byte) (*perfDataBlock, error) {
+	r := perfReader(data)
+	if len(r) < perfDataMinHeader {
+		return nil, errPerfDataTruncated
+	}
+	if signature := string(utf16.Decode(utf16Words(r[:8]))); signature != perfDataSignature {
+		return nil, fmt.Errorf("invalid performance data signature %q", signature)
+	}
+	total, _ := r.u32(perfDataTotalLength)
+	header, _ := r.u32(perfDataHeaderLength)
+	numObjects, _ := r.u32(perfDataNumObjectTypes)
+	block := &perfDataBlock{}
+	block.perfTime, _ = r.i64(perfDataPerfTime)
+	block.perfFreq, _ = r.i64(perfDataPerfFreq)
+	block.perfTime100ns, _ = r.i64(perfDataPerfTime100nSec)
+	if int(total) < len(r) {
+		r = r[:total]
+	}
+
+	offset := int(header)
+	for i := uint32(0); i < numObjects; i++ {
+		length, err := r.u32(offset + perfObjectTotalLength)
+		if err != nil {
+			return nil, err
+		}
+		objectData, err := r.slice(offset, int(length))
+		if err != nil {
+			return nil, err
+		}
+		object, err := parsePerfObject(objectData)
+		if err != nil {
+			return nil, err
+		}
+		block.objects = append(block.objects, object)
+		offset += int(length)
+	}
+	return block, nil
+}
+
+// parsePerfObject 解析一个 PERF_OBJECT_TYPE 及其计数器定义和实例。
+func parsePerfObject(r perfReader) (perfObject, error) {
+	if len(r) < perfObjectMinHeader {
+		return perfObject{}, errPerfDataTruncated
+	}
+	definitionLength, _ := r.u32(perfObjectDefinitionLength)
+	headerLength, _ := r.u32(perfObjectHeaderLength)
+	numCounters, _ := r.u32(perfObjectNumCounters)
+	numInstances, _ := r.u32(perfObjectNumInstances)
+	codePage, _ := r.u32(perfObjectCodePage)
+	object := perfObject{}
+	object.index, _ = r.u32(perfObjectNameTitleIndex)
+	object.perfTime, _ = r.i64(perfObjectPerfTime)
+	object.perfFreq, _ = r.i64(perfObjectPerfFreq)
+
+	offset := int(headerLength)
+	for i := uint32(0); i < numCounters; i++ {
+		length, err := r.u32(offset + perfCounterByteLength)
+		if err != nil {
+			return perfObject{}, err
+		}
+		definition, err := r.slice(offset, int(length))
+		if err != nil || len(definition) < perfCounterMinLength {
+			return perfObject{}, errPerfDataTruncated
+		}
+		counter := perfCounterDef{base: -1}
+		counter.index, _ = definition.u32(perfCounterNameTitleIndex)
+		counter.counterType, _ = definition.u32(perfCounterType)
+		counter.size, _ = definition.u32(perfCounterSize)
+		counter.offset, _ = definition.u32(perfCounterOffset)
+		object.counters = append(object.counters, counter)
+		offset += int(length)
+	}
+	// 分数类计数器的分母是紧随其后的 PERF_COUNTER_BASE 计数器
+	for i := range object.counters {
+		if i+1 < len(object.counters) && isPerfBaseType(object.counters[i+1].counterType) {
+			object.counters[i].base = i + 1
+		}
+	}
+
+	offset = int(definitionLength)
+	if int32(numInstances) == perfNoInstances { //nolint:gosec // G115: NumInstances is a LONG
+		data, err := counterBlock(r, offset)
+		if err != nil {
+			return perfObject{}, err
+		}
+		object.instances = []perfInstance{{data: data}}
+		return object, nil
+	}
+
+	occurrences := make(map[string]int)
+	for i := uint32(0); i < numInstances; i++ {
+		length, err := r.u32(offset + perfInstanceByteLength)
+		if err != nil {
+			return perfObject{}, err
+		}
+		definition, err := r.slice(offset, int(length))
+		if err != nil || len(definition) < perfInstanceMinLength {
+			return perfObject{}, errPerfDataTruncated
+		}
+		nameOffset, _ := definition.u32(perfInstanceNameOffset)
+		nameLength, _ := definition.u32(perfInstanceNameLength)
+		rawName, err := definition.slice(int(nameOffset), int(nameLength))
+		if err != nil {
+			return perfObject{}, err
+		}
+		name := perfInstanceName(rawName, codePage)
+		// 与 PDH 相同，同名的实例依次命名为 "名称"、"名称#1"、"名称#2"
+		if n := occurrences[name]; n > 0 {
+			occurrences[name] = n + 1
+			name += "#" + strconv.Itoa(n)
+		} else {
+			occurrences[name] = 1
+		}
+
+		data, err := counterBlock(r, offset+int(length))
+		if err != nil {
+			return perfObject{}, err
+		}
+		object.instances = append(object.instances, perfInstance{name: name, data: data})
+		offset += int(length) + len(data)
+	}
+	return object, nil
+}
+
+// counterBlock 返回 offset 处的 PERF_COUNTER_BLOCK，其第一个 DWORD 为数据块的长度。
+func counterBlock(r perfReader, offset int) (perfReader, error) {
+	length, err := r.u32(offset)
+	if err != nil {
+		return nil, err
+	}
+	return r.slice(offset, int(length))
+}
+
+// perfInstanceName 解码实例名称，CodePage 为 0 时为 UTF-16，否则按单字节字符处理，去掉结尾的空字符。
+func perfInstanceName(raw []byte, codePage uint32) string {
+	if codePage != 0 {
+		for len(raw) > 0 && raw[len(raw)-1] == 0 {
+			raw = raw[:len(raw)-1]
+		}
+		return string(raw)
+	}
+	words := utf16Words(raw)
+	for len(words) > 0 && words[len(words)-1] == 0 {
+		words = words[:len(words)-1]
+	}
+	return string(utf16.Decode(words))
+}
+
+// utf16Words 将小端字节序列转换为 UTF-16 编码单元。
+func utf16Words(raw []byte) []uint16 {
+	words := make([]uint16, len(raw)/2)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint16(raw[2*i:])
+	}
+	return words
+}
+
+// isPerfBaseType 判断计数器类型是否为 PERF_COUNTER_BASE 类的分母计数器。
+func isPerfBaseType(counterType uint32) bool {
+	return counterType&perfTypeMask == perfTypeCounter && counterType&perfCounterSubMask == perfCounterBase
+}
+
+// value 返回计数器在实例中的原始值，按计数器大小读取 32 位或 64 位整数。
+func (c perfCounterDef) value(instance perfInstance) (uint64, bool) {
+	r := perfReader(instance.data)
+	offset := int(c.offset)
+	if c.size == 8 || c.counterType&perfSizeMask == perfSizeLarge {
+		if offset+8 > len(r) {
+			return 0, false
+		}
+		return binary.LittleEndian.Uint64(r[offset:]), true
+	}
+	v, err := r.u32(offset)
+	return uint64(v), err == nil
+}
@@ -0,0 +1,258 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testPerfCounter 构造 PERF_COUNTER_DEFINITION 使用的计数器定义。
+type testPerfCounter struct {
+	index       uint32
+	counterType uint32
+	size        uint32
+	offset      uint32
+}
+
+// testPerfInstance 构造 PERF_INSTANCE_DEFINITION 使用的实例，data 为不含长度字段的计数器数据。
+type testPerfInstance struct {
+	name string
+	data []byte
+}
+
+// pad8 将数据补齐到 8 字节的倍数。
+func pad8(b []byte) []byte {
+	for len(b)%8 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// testCounterBlock 构造 PERF_COUNTER_BLOCK，第一个 DWORD 为数据块的长度。
+func testCounterBlock(data []byte) []byte {
+	block := pad8(append(make([]byte, 4), data...))
+	binary.LittleEndian.PutUint32(block, uint32(len(block)))
+	return block
+}
+
+// testPerfObjectBlob 构造 PERF_OBJECT_TYPE，instances 为 nil 时对象没有实例，计数器数据为 single。
+func testPerfObjectBlob(index uint32, counters []testPerfCounter, instances []testPerfInstance, single []byte) []byte {
+	header := make([]byte, perfObjectMinHeader)
+	binary.LittleEndian.PutUint32(header[perfObjectHeaderLength:], perfObjectMinHeader)
+	binary.LittleEndian.PutUint32(header[perfObjectNameTitleIndex:], index)
+	binary.LittleEndian.PutUint32(header[perfObjectNumCounters:], uint32(len(counters)))
+	binary.LittleEndian.PutUint64(header[perfObjectPerfTime:], 500)
+	binary.LittleEndian.PutUint64(header[perfObjectPerfFreq:], 1000)
+	for _, c := range counters {
+		definition := make([]byte, perfCounterMinLength)
+		binary.LittleEndian.PutUint32(definition[perfCounterByteLength:], perfCounterMinLength)
+		binary.LittleEndian.PutUint32(definition[perfCounterNameTitleIndex:], c.index)
+		binary.LittleEndian.PutUint32(definition[perfCounterType:], c.counterType)
+		binary.LittleEndian.PutUint32(definition[perfCounterSize:], c.size)
+		binary.LittleEndian.PutUint32(definition[perfCounterOffset:], c.offset)
+		header = append(header, definition...)
+	}
+	binary.LittleEndian.PutUint32(header[perfObjectDefinitionLength:], uint32(len(header)))
+
+	blob := header
+	if instances == nil {
+		binary.LittleEndian.PutUint32(blob[perfObjectNumInstances:], 0xFFFFFFFF)
+		blob = append(blob, testCounterBlock(single)...)
+	} else {
+		binary.LittleEndian.PutUint32(blob[perfObjectNumInstances:], uint32(len(instances)))
+		for _, instance := range instances {
+			name := make([]byte, 0, 2*len(instance.name)+2)
+			for _, w := range utf16.Encode([]rune(instance.name + "\x00")) {
+				name = binary.LittleEndian.AppendUint16(name, w)
+			}
+			definition := make([]byte, perfInstanceMinLength)
+			binary.LittleEndian.PutUint32(definition[perfInstanceNameOffset:], perfInstanceMinLength)
+			binary.LittleEndian.PutUint32(definition[perfInstanceNameLength:], uint32(len(name)))
+			definition = pad8(append(definition, name...))
+			binary.LittleEndian.PutUint32(definition[perfInstanceByteLength:], uint32(len(definition)))
+			blob = append(blob, definition...)
+			blob = append(blob, testCounterBlock(instance.data)...)
+		}
+	}
+	binary.LittleEndian.PutUint32(blob[perfObjectTotalLength:], uint32(len(blob)))
+	return blob
+}
+
+// testPerfDataBlob 构造包含 objects 的 PERF_DATA_BLOCK。
+func testPerfDataBlob(objects ...[]byte) []byte {
+	blob := make([]byte, perfDataMinHeader)
+	for i, w := range utf16.Encode([]rune(perfDataSignature)) {
+		binary.LittleEndian.PutUint16(blob[2*i:], w)
+	}
+	binary.LittleEndian.PutUint32(blob[perfDataHeaderLength:], perfDataMinHeader)
+	binary.LittleEndian.PutUint32(blob[perfDataNumObjectTypes:], uint32(len(objects)))
+	binary.LittleEndian.PutUint64(blob[perfDataPerfTime:], 100)
+	binary.LittleEndian.PutUint64(blob[perfDataPerfFreq:], 10)
+	binary.LittleEndian.PutUint64(blob[perfDataPerfTime100nSec:], 1000)
+	for _, object := range objects {
+		blob = append(blob, object...)
+	}
+	binary.LittleEndian.PutUint32(blob[perfDataTotalLength:], uint32(len(blob)))
+	return blob
+}
+
+// testInstanceData 构造偏移 4 处为 DWORD value、偏移 8 处为 DWORD base、偏移 12 处为 QWORD large 的计数器数据。
+func testInstanceData(value, base uint32, large uint64) []byte {
+	data := binary.LittleEndian.AppendUint32(nil, value)
+	data = binary.LittleEndian.AppendUint32(data, base)
+	return binary.LittleEndian.AppendUint64(data, large)
+}
+
+// goldenPerfData 包含一个多实例对象（索引 230，含同名实例）和一个没有实例的对象（索引 2）。
+func goldenPerfData() []byte {
+	counters := []testPerfCounter{
+		{index: 6, counterType: perfRawFraction, size: 4, offset: 4},
+		{index: 7, counterType: 0x40030403, size: 4, offset: 8}, // PERF_RAW_BASE
+		{index: 8, counterType: perfCounterBulkCount, size: 8, offset: 12},
+	}
+	processes := testPerfObjectBlob(230, counters, []testPerfInstance{
+		{name: "svchost", data: testInstanceData(25, 200, 1<<40)},
+		{name: "svchost", data: testInstanceData(1, 2, 3)},
+		{name: "_Total", data: testInstanceData(26, 202, 1<<40+3)},
+	}, nil)
+	system := testPerfObjectBlob(2, []testPerfCounter{{index: 44, counterType: perfCounterRawcount, size: 4, offset: 4}},
+		nil, binary.LittleEndian.AppendUint32(nil, 7))
+	return testPerfDataBlob(processes, system)
+}
+
+func TestParsePerfData(t *testing.T) {
+	block, err := parsePerfData(goldenPerfData())
+	require.NoError(t, err)
+	require.Equal(t, int64(100), block.perfTime)
+	require.Equal(t, int64(10), block.perfFreq)
+	require.Equal(t, int64(1000), block.perfTime100ns)
+	require.Len(t, block.objects, 2)
+
+	processes := block.objects[0]
+	require.Equal(t, uint32(230), processes.index)
+	require.Equal(t, int64(500), processes.perfTime)
+	require.Equal(t, int64(1000), processes.perfFreq)
+	require.Len(t, processes.counters, 3)
+	// 分数类计数器的分母为紧随其后的 PERF_COUNTER_BASE
+	require.Equal(t, 1, processes.counters[0].base)
+	require.Equal(t, -1, processes.counters[1].base)
+	require.Equal(t, -1, processes.counters[2].base)
+
+	names := make([]string, 0, len(processes.instances))
+	for _, instance := range processes.instances {
+		names = append(names, instance.name)
+	}
+	require.Equal(t, []string{"svchost", "svchost#1", "_Total"}, names)
+	for i, expected := range [][3]uint64{{25, 200, 1 << 40}, {1, 2, 3}, {26, 202, 1<<40 + 3}} {
+		for j, counter := range processes.counters {
+			value, ok := counter.value(processes.instances[i])
+			require.True(t, ok)
+			require.Equal(t, expected[j], value, "instance %d counter %d", i, j)
+		}
+	}
+
+	system := block.objects[1]
+	require.Equal(t, uint32(2), system.index)
+	require.Len(t, system.instances, 1)
+	require.Empty(t, system.instances[0].name)
+	value, ok := system.counters[0].value(system.instances[0])
+	require.True(t, ok)
+	require.Equal(t, uint64(7), value)
+}
+
+// TestParsePerfDataTruncated 截断在任意位置的数据都返回错误而不是越界。
+func TestParsePerfDataTruncated(t *testing.T) {
+	data := goldenPerfData()
+	for n := range len(data) {
+		_, err := parsePerfData(data[:n])
+		require.Error(t, err, "length %d", n)
+	}
+
+	_, err := parsePerfData(append([]byte("XXXX"), data[4:]...))
+	require.ErrorContains(t, err, "invalid performance data signature")
+}
+
+// TestParsePerfDataCorrupt 长度与偏移字段指向数据之外时返回 errPerfDataTruncated。
+func TestParsePerfDataCorrupt(t *testing.T) {
+	objectOffset := perfDataMinHeader
+	tests := []struct {
+		name   string
+		offset int
+		value  uint32
+	}{
+		{"object count", perfDataNumObjectTypes, 3},
+		{"object length", objectOffset + perfObjectTotalLength, 1 << 30},
+		{"object header shorter than minimum", objectOffset + perfObjectTotalLength, perfObjectMinHeader - 1},
+		{"counter count", objectOffset + perfObjectNumCounters, 1000},
+		{"counter definition length", objectOffset + perfObjectMinHeader + perfCounterByteLength, 8},
+		{"instance count", objectOffset + perfObjectNumInstances, 1000},
+		{"definition length", objectOffset + perfObjectDefinitionLength, 1 << 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := goldenPerfData()
+			binary.LittleEndian.PutUint32(data[tt.offset:], tt.value)
+			_, err := parsePerfData(data)
+			require.ErrorIs(t, err, errPerfDataTruncated)
+		})
+	}
+}
+
+func TestPerfInstanceName(t *testing.T) {
+	require.Equal(t, "w3wp", perfInstanceName([]byte("w3wp\x00\x00"), 1252))
+	require.Equal(t, "w3wp", perfInstanceName([]byte{'w', 0, '3', 0, 'w', 0, 'p', 0, 0, 0}, 0))
+	require.Empty(t, perfInstanceName(nil, 0))
+}
+
+func TestFormatPerfValue(t *testing.T) {
+	sample := func(value, base uint64, time, freq int64) registrySample {
+		return registrySample{value: value, base: base, time: time, freq: freq}
+	}
+	tests := []struct {
+		name        string
+		counterType uint32
+		current     registrySample
+		previous    registrySample
+		hasPrevious bool
+		expected    float64
+		ok          bool
+	}{
+		{"raw fraction", perfRawFraction, sample(25, 200, 0, 0), registrySample{}, false, 12.5, true},
+		{"raw fraction without base", perfLargeRawFraction, sample(25, 0, 0, 0), registrySample{}, false, 0, false},
+		{"elapsed time", perfElapsedTime, sample(10_000_000, 0, 50_000_000, 10_000_000), registrySample{}, false, 4, true},
+		{"elapsed time without frequency", perfElapsedTime, sample(1, 0, 5, 0), registrySample{}, false, 0, false},
+		{"raw count", perfCounterRawcount, sample(42, 0, 0, 0), registrySample{}, false, 42, true},
+		{"raw count divided by 1000", perfNumberDec1000, sample(4200, 0, 0, 0), registrySample{}, false, 4.2, true},
+		{"rate without previous", perfCounterCounter, sample(100, 0, 20, 10), registrySample{}, false, 0, false},
+		{"rate wrapped", perfCounterCounter, sample(50, 0, 20, 10), sample(100, 0, 10, 10), true, 0, false},
+		{"rate", perfCounterCounter, sample(150, 0, 20, 10), sample(100, 0, 10, 10), true, 50, true},
+		{"bulk count", perfCounterBulkCount, sample(300, 0, 40, 10), sample(100, 0, 20, 10), true, 100, true},
+		{"rate without elapsed time", perfCounterCounter, sample(150, 0, 10, 10), sample(100, 0, 10, 10), true, 0, false},
+		{"rate without frequency", perfCounterCounter, sample(150, 0, 20, 0), sample(100, 0, 10, 0), true, 0, false},
+		{"delta", perfCounterDelta, sample(150, 0, 0, 0), sample(100, 0, 0, 0), true, 50, true},
+		{"large delta", perfCounterLargeDelta, sample(150, 0, 0, 0), sample(100, 0, 0, 0), true, 50, true},
+		{"sample fraction", perfSampleFraction, sample(30, 40, 0, 0), sample(10, 20, 0, 0), true, 100, true},
+		{"sample fraction without base delta", perfSampleFraction, sample(30, 20, 0, 0), sample(10, 20, 0, 0), true, 0, false},
+		{"average bulk", perfAverageBulk, sample(300, 12, 0, 0), sample(100, 8, 0, 0), true, 50, true},
+		{"average timer", perfAverageTimer, sample(300, 12, 0, 10), sample(100, 8, 0, 10), true, 5, true},
+		{"average timer without frequency", perfAverageTimer, sample(300, 12, 0, 0), sample(100, 8, 0, 0), true, 0, false},
+		{"timer", perfCounterTimer, sample(150, 0, 200, 10), sample(100, 0, 100, 10), true, 50, true},
+		{"100ns timer", perf100nsecTimer, sample(125, 0, 200, 0), sample(100, 0, 100, 0), true, 25, true},
+		{"inverse timer", perfCounterTimerInv, sample(125, 0, 200, 10), sample(100, 0, 100, 10), true, 75, true},
+		{"100ns inverse timer", perf100nsecTimerInv, sample(150, 0, 200, 0), sample(100, 0, 100, 0), true, 50, true},
+		{"queue length", perfCounterQueuelen, sample(300, 0, 200, 10), sample(100, 0, 100, 10), true, 2, true},
+		{"100ns queue length", perfCounter100nsQueuelen, sample(300, 0, 200, 0), sample(100, 0, 100, 0), true, 2, true},
+		{"unsupported", 0x00000800, sample(300, 0, 200, 10), sample(100, 0, 100, 10), true, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok := formatPerfValue(tt.counterType, tt.current, tt.previous, tt.hasPrevious)
+			require.Equal(t, tt.ok, ok)
+			require.InDelta(t, tt.expected, value, 1e-9)
+		})
+	}
+}
@@ -5,6 +5,8 @@ package win_perf_counters
 
 import (
 	"errors"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 	"unsafe"
@@ -13,15 +15,14 @@ import (
 // Initial buffer size for return buffers
 const initialBufferSize = uint32(1024) // 1kB
 
-var (
-	errBufferLimitReached = errors.New("buffer limit reached")
-	errUninitializedQuery = errors.New("uninitialized query")
-)
+var errUninitializedQuery = errors.New("uninitialized query")
 
 // counterValue is abstraction for pdhFmtCountervalueItemDouble
 type counterValue struct {
 	Name  string
 	Value interface{}
+	// Timestamp is the time the provider collected a raw value, zero for formatted values
+	Timestamp time.Time
 }
 
 type longValue struct {
@@ -39,6 +40,44 @@ type doubleValue struct {
 	Value float64
 }
 
+// RawSample is a raw counter value as returned by PDH, which can be stored and later turned into a displayable
+// value with CalculateFormattedFromRaw, e.g. to compute rates in a TSDB instead of on the collecting host
+type RawSample struct {
+	// FirstValue is the first raw counter value
+	FirstValue int64 `json:"first_value"`
+	// SecondValue is the second raw counter value, e.g. the time base of rate counters
+	SecondValue int64 `json:"second_value"`
+	// MultiCount is the additional data used by counter types with the PERF_MULTI_COUNTER flag
+	MultiCount uint32 `json:"multi_count"`
+	// FileTime is the local FILETIME the provider collected the value at, in 100ns intervals since 1601-01-01
+	FileTime uint64 `json:"file_time"`
+	// Time is FileTime converted to a GO time, zero if the conversion failed
+	Time time.Time `json:"time"`
+}
+
+// newRawSample converts the raw counter value returned by PDH
+func newRawSample(value *pdhRawCounter) RawSample {
+	sample := RawSample{
+		FirstValue:  value.FirstValue,
+		SecondValue: value.SecondValue,
+		MultiCount:  value.MultiCount,
+		FileTime:    uint64(value.TimeStamp.dwHighDateTime)<<32 | uint64(value.TimeStamp.dwLowDateTime),
+	}
+	sample.Time, _ = localFileTimeToTime(value.TimeStamp)
+	return sample
+}
+
+// pdhRawCounter converts the sample back to the raw counter value expected by PDH
+func (s RawSample) pdhRawCounter() pdhRawCounter {
+	return pdhRawCounter{
+		CStatus:     pdhCstatusValidData,
+		TimeStamp:   fileTime{dwLowDateTime: uint32(s.FileTime), dwHighDateTime: uint32(s.FileTime >> 32)},
+		FirstValue:  s.FirstValue,
+		SecondValue: s.SecondValue,
+		MultiCount:  s.MultiCount,
+	}
+}
+
 // PerformanceQuery provides wrappers around Windows performance counters API for easy usage in GO
 //
 //nolint:interfacebloat // conditionally allow to contain more methods
@@ -48,10 +87,15 @@ type PerformanceQuery interface {
 	AddCounterToQuery(counterPath string) (pdhCounterHandle, error)
 	MustAddCounterToQuery(counterPath string) pdhCounterHandle
 	AddEnglishCounterToQuery(counterPath string) (pdhCounterHandle, error)
+	RemoveCounter(counterHandle pdhCounterHandle) error
 	GetCounterPath(counterHandle pdhCounterHandle) (string, error)
+	GetCounterType(counterHandle pdhCounterHandle) (uint32, error)
 	ExpandWildCardPath(counterPath string) ([]string, error)
 
 	GetRawCounterValue(hCounter pdhCounterHandle) (int64, error)
+	GetRawCounterValueWithTime(hCounter pdhCounterHandle) (int64, time.Time, error)
+	GetRawCounterSample(hCounter pdhCounterHandle) (RawSample, error)
+	CalculateFormattedFromRaw(hCounter pdhCounterHandle, oldSample, newSample RawSample) (float64, error)
 	GetFormattedCounterValueLong(hCounter pdhCounterHandle) (int32, error)
 	GetFormattedCounterValueLarge(hCounter pdhCounterHandle) (int64, error)
 	GetFormattedCounterValueDouble(hCounter pdhCounterHandle) (float64, error)
@@ -63,9 +107,41 @@ type PerformanceQuery interface {
 
 	CollectData() error
 	CollectDataWithTime() (time.Time, error)
+	Capabilities() Capabilities
+	// Deprecated: use Capabilities instead.
 	IsVistaOrNewer() bool
 }
 
+// UserDataCounterAdder is implemented by queries that can associate user data with a counter handle.
+// The value is returned in CounterMeta.UserData; counters added without user data report 0.
+type UserDataCounterAdder interface {
+	AddCounterToQueryWithUserData(counterPath string, userData uintptr) (pdhCounterHandle, error)
+	AddEnglishCounterToQueryWithUserData(counterPath string, userData uintptr) (pdhCounterHandle, error)
+}
+
+// CounterMetaReader is implemented by queries that can read the type, scale and explain text of a counter.
+type CounterMetaReader interface {
+	GetCounterMeta(counterHandle pdhCounterHandle) (CounterMeta, error)
+}
+
+// WildCardFlagsExpander is implemented by queries that accept the PDH_NOEXPAND* flags when expanding wildcard paths.
+type WildCardFlagsExpander interface {
+	ExpandWildCardPathWithFlags(counterPath string, flags uint32) ([]string, error)
+}
+
+// QueryStatsReporter is implemented by queries that keep statistics about their PDH calls.
+type QueryStatsReporter interface {
+	Stats() QueryStats
+}
+
+// Capabilities describes the optional PDH functions available on the running system
+type Capabilities struct {
+	// AddEnglishCounter reports whether language-neutral counter paths can be added (Vista and newer)
+	AddEnglishCounter bool `json:"add_english_counter"`
+	// CollectDataWithTime reports whether the collection timestamp of the queried node can be retrieved
+	CollectDataWithTime bool `json:"collect_data_with_time"`
+}
+
 type performanceQueryCreator interface {
 	newPerformanceQuery(string, uint32) PerformanceQuery
 }
@@ -87,10 +163,27 @@ func newPdhError(code uint32) error {
 	}
 }
 
+var (
+	_ UserDataCounterAdder  = (*performanceQueryImpl)(nil)
+	_ CounterMetaReader     = (*performanceQueryImpl)(nil)
+	_ WildCardFlagsExpander = (*performanceQueryImpl)(nil)
+	_ QueryStatsReporter    = (*performanceQueryImpl)(nil)
+)
+
 // performanceQueryImpl is implementation of performanceQuery interface, which calls phd.dll functions
 type performanceQueryImpl struct {
 	maxBufferSize uint32
 	queryHandle   pdhQueryHandle
+	// dataSource is the log file to read from, empty for real-time data
+	dataSource string
+	// computer is the remote computer of real-time data, empty for the local computer and log files
+	computer string
+	// arraySizes remembers the buffer size of the last successful array call per counter
+	arraySizes arraySizes
+	// names caches the instance names decoded from the array calls
+	names instanceNames
+	// stats records the activity reported by Stats
+	stats queryStats
 }
 
 type performanceQueryCreatorImpl struct{}
@@ -99,22 +192,73 @@ func NewPerformanceQueryCreator() performanceQueryCreator {
 	return &performanceQueryCreatorImpl{}
 }
 
-func (performanceQueryCreatorImpl) newPerformanceQuery(_ string, maxBufferSize uint32) PerformanceQuery {
-	return &performanceQueryImpl{maxBufferSize: maxBufferSize}
+func (performanceQueryCreatorImpl) newPerformanceQuery(computer string, maxBufferSize uint32) PerformanceQuery {
+	dataSource := logSourcePath(computer)
+	if dataSource != "" || computer == "localhost" {
+		computer = ""
+	}
+	return &performanceQueryImpl{maxBufferSize: maxBufferSize, dataSource: dataSource, computer: computer}
 }
 
 func NewPerformanceQuery(maxBufferSize uint32) PerformanceQuery {
 	return NewPerformanceQueryCreator().newPerformanceQuery("", maxBufferSize)
 }
 
+// NewOpenPerformanceQuery creates and opens a query on the local computer, it is the non-panicking variant of
+// MustNewOpenPerformanceQuery. The returned error is a *CounterError carrying the failed operation.
+func NewOpenPerformanceQuery(maxBufferSize uint32) (PerformanceQuery, error) {
+	return NewPerformanceQueryWithOptions(PerformanceQueryOptions{MaxBufferSize: maxBufferSize, Open: true})
+}
+
+// MustNewOpenPerformanceQuery is like NewOpenPerformanceQuery but panics with the *CounterError if the query
+// cannot be opened. Library consumers should prefer NewOpenPerformanceQuery.
 func MustNewOpenPerformanceQuery(maxBufferSize uint32) PerformanceQuery {
-	query := NewPerformanceQuery(maxBufferSize)
-	if err := query.Open(); err != nil {
+	query, err := NewOpenPerformanceQuery(maxBufferSize)
+	if err != nil {
 		panic(err)
 	}
 	return query
 }
 
+// PerformanceQueryOptions configures a query created by NewPerformanceQueryWithOptions
+type PerformanceQueryOptions struct {
+	// MaxBufferSize is the maximum size in bytes of the buffers used by the array calls, 100 MiB when 0
+	MaxBufferSize uint32
+	// Computer is the remote computer to query, e.g. "SERVER01"; empty or "localhost" for the local computer
+	Computer string
+	// LogFile is a performance log (.blg, .csv or .tsv) to read instead of real-time data, it can't be combined with Computer
+	LogFile string
+	// Open opens the query before returning it, otherwise the caller has to call Open
+	Open bool
+}
+
+// NewPerformanceQueryWithOptions creates a query as configured by options without panicking.
+// Errors are returned as *CounterError with the operation ("configure" or "open") and the computer or log file,
+// so they can be inspected with errors.As instead of recovering from a panic.
+func NewPerformanceQueryWithOptions(options PerformanceQueryOptions) (PerformanceQuery, error) {
+	if options.Computer != "" && options.LogFile != "" {
+		return nil, wrapCounterError("configure", options.Computer, "", "", errors.New("Computer and LogFile can't be used together"))
+	}
+	maxBufferSize := options.MaxBufferSize
+	if maxBufferSize == 0 {
+		maxBufferSize = uint32(defaultMaxBufferSize)
+	}
+	source := options.Computer
+	if source == "" {
+		source = "localhost"
+	}
+	if options.LogFile != "" {
+		source = logSourcePrefix + options.LogFile
+	}
+	query := NewPerformanceQueryCreator().newPerformanceQuery(source, maxBufferSize)
+	if options.Open {
+		if err := query.Open(); err != nil {
+			return nil, wrapCounterError("open", source, "", "", err)
+		}
+	}
+	return query, nil
+}
+
 // Open creates a new counterPath that is used to manage the collection of performance data.
 // It returns counterPath handle used for subsequent calls for adding counters and querying data
 func (m *performanceQueryImpl) Open() error {
@@ -125,8 +269,16 @@ func (m *performanceQueryImpl) Open() error {
 		}
 	}
 	var handle pdhQueryHandle
+	var dataSource uintptr
+	if m.dataSource != "" {
+		source, err := syscall.UTF16PtrFromString(m.dataSource)
+		if err != nil {
+			return err
+		}
+		dataSource = uintptr(unsafe.Pointer(source)) //nolint:gosec // G103: Valid use of unsafe call to pass source
+	}
 
-	if ret := pdhOpenQuery(0, 0, &handle); ret != errorSuccess {
+	if ret := pdhOpenQuery(dataSource, 0, &handle); ret != errorSuccess {
 		return newPdhError(ret)
 	}
 	m.queryHandle = handle
@@ -143,42 +295,90 @@ func (m *performanceQueryImpl) Close() error {
 		return newPdhError(ret)
 	}
 	m.queryHandle = 0
+	m.arraySizes.reset()
+	m.stats.closed()
 	return nil
 }
 
 func (m *performanceQueryImpl) AddCounterToQuery(counterPath string) (pdhCounterHandle, error) {
+	return m.AddCounterToQueryWithUserData(counterPath, 0)
+}
+
+// AddCounterToQueryWithUserData adds the counter and associates userData with its handle, e.g. a correlation ID.
+// The value is reported back as CounterMeta.UserData by GetCounterMeta.
+func (m *performanceQueryImpl) AddCounterToQueryWithUserData(counterPath string, userData uintptr) (pdhCounterHandle, error) {
 	var counterHandle pdhCounterHandle
 	if m.queryHandle == 0 {
 		return 0, errUninitializedQuery
 	}
 
-	if ret := pdhAddCounter(m.queryHandle, counterPath, 0, &counterHandle); ret != errorSuccess {
+	if ret := pdhAddCounter(m.queryHandle, counterPath, userData, &counterHandle); ret != errorSuccess {
 		return 0, newPdhError(ret)
 	}
+	m.stats.added()
 	return counterHandle, nil
 }
 
+// MustAddCounterToQuery is like AddCounterToQuery but panics with a *CounterError carrying the counter path
+// if the counter can't be added. Library consumers should prefer AddCounterToQuery.
 func (m *performanceQueryImpl) MustAddCounterToQuery(counterPath string) pdhCounterHandle {
 	counterHandle, err := m.AddCounterToQuery(counterPath)
 	if err != nil {
-		panic(err)
+		panic(wrapCounterError("add", m.computer, "", counterPath, err))
 	}
 	return counterHandle
 }
 
 func (m *performanceQueryImpl) AddEnglishCounterToQuery(counterPath string) (pdhCounterHandle, error) {
+	return m.AddEnglishCounterToQueryWithUserData(counterPath, 0)
+}
+
+// AddEnglishCounterToQueryWithUserData is the language-neutral variant of AddCounterToQueryWithUserData.
+func (m *performanceQueryImpl) AddEnglishCounterToQueryWithUserData(counterPath string, userData uintptr) (pdhCounterHandle, error) {
 	var counterHandle pdhCounterHandle
 	if m.queryHandle == 0 {
 		return 0, errUninitializedQuery
 	}
-	if ret := pdhAddEnglishCounter(m.queryHandle, counterPath, 0, &counterHandle); ret != errorSuccess {
+	if ret := pdhAddEnglishCounter(m.queryHandle, counterPath, userData, &counterHandle); ret != errorSuccess {
 		return 0, newPdhError(ret)
 	}
+	m.stats.added()
 	return counterHandle, nil
 }
 
+// RemoveCounter removes the counter from the query without affecting the other counters
+func (m *performanceQueryImpl) RemoveCounter(counterHandle pdhCounterHandle) error {
+	if m.queryHandle == 0 {
+		return errUninitializedQuery
+	}
+	if ret := pdhRemoveCounter(counterHandle); ret != errorSuccess {
+		return newPdhError(ret)
+	}
+	m.stats.removed(counterHandle)
+	return nil
+}
+
 // GetCounterPath returns counter information for given handle
 func (m *performanceQueryImpl) GetCounterPath(counterHandle pdhCounterHandle) (string, error) {
+	ci, err := m.getCounterInfo(counterHandle)
+	if err != nil {
+		return "", err
+	}
+	return utf16PtrToString(ci.SzFullPath), nil
+}
+
+// GetCounterType returns the counter type of the given counter as defined in winperf.h, e.g. PERF_COUNTER_RAWCOUNT
+func (m *performanceQueryImpl) GetCounterType(counterHandle pdhCounterHandle) (uint32, error) {
+	ci, err := m.getCounterInfo(counterHandle)
+	if err != nil {
+		return 0, err
+	}
+	return ci.DwType, nil
+}
+
+// getCounterInfo retrieves the PDH_COUNTER_INFO of the given counter, growing the buffer as needed
+func (m *performanceQueryImpl) getCounterInfo(counterHandle pdhCounterHandle) (*pdhCounterInfo, error) {
+	var required uint32
 	for buflen := initialBufferSize; buflen <= m.maxBufferSize; buflen *= 2 {
 		buf := make([]byte, buflen)
 
@@ -186,8 +386,7 @@ func (m *performanceQueryImpl) GetCounterPath(counterHandle pdhCounterHandle) (s
 		size := buflen
 		ret := pdhGetCounterInfo(counterHandle, 0, &size, &buf[0])
 		if ret == errorSuccess {
-			ci := (*pdhCounterInfo)(unsafe.Pointer(&buf[0])) //nolint:gosec // G103: Valid use of unsafe call to create PDH_COUNTER_INFO
-			return utf16PtrToString(ci.SzFullPath), nil
+			return (*pdhCounterInfo)(unsafe.Pointer(&buf[0])), nil //nolint:gosec // G103: Valid use of unsafe call to create PDH_COUNTER_INFO
 		}
 
 		// Use the size as a hint if it exceeds the current buffer size
@@ -197,21 +396,61 @@ func (m *performanceQueryImpl) GetCounterPath(counterHandle pdhCounterHandle) (s
 
 		// We got a non-recoverable error so exit here
 		if ret != pdhMoreData {
-			return "", newPdhError(ret)
+			return nil, m.stats.counterError(counterHandle, newPdhError(ret))
 		}
+		required = size
+		m.stats.retried()
 	}
 
-	return "", errBufferLimitReached
+	// The path itself is not available without the counter info
+	return nil, m.stats.counterError(counterHandle, m.bufferLimitError("", required))
 }
 
-// ExpandWildCardPath examines local computer and returns those counter paths that match the given counter path which contains wildcard characters.
+// GetCounterMeta returns the type, scale and explain text of the given counter
+func (m *performanceQueryImpl) GetCounterMeta(counterHandle pdhCounterHandle) (CounterMeta, error) {
+	ci, err := m.getCounterInfo(counterHandle)
+	if err != nil {
+		return CounterMeta{}, err
+	}
+	meta := CounterMeta{
+		Path:         utf16PtrToString(ci.SzFullPath),
+		Type:         ci.DwType,
+		TypeClass:    counterTypeClass(ci.DwType),
+		Scale:        ci.LScale,
+		DefaultScale: ci.LDefaultScale,
+		// DwUserData holds the DWORD_PTR value itself, not a pointer to it
+		UserData: uintptr(unsafe.Pointer(ci.DwUserData)), //nolint:gosec // G103: reading the pointer-sized user data value
+	}
+	if ci.SzExplainText != nil {
+		meta.ExplainText = utf16PtrToString(ci.SzExplainText)
+	}
+	return meta, nil
+}
+
+// ExpandWildCardPath examines the computer of the query (or its log file) and returns those counter paths that match the given counter path which contains wildcard characters.
+// Paths of remote queries are expanded against the remote computer, paths without a computer are qualified with the computer of the query.
 func (m *performanceQueryImpl) ExpandWildCardPath(counterPath string) ([]string, error) {
+	return m.ExpandWildCardPathWithFlags(counterPath, 0)
+}
+
+// ExpandWildCardPathWithFlags is ExpandWildCardPath with PdhExpandWildCardPath flags, pdhNoExpandCounters or pdhNoExpandInstances
+// leave the wildcards of that part of the path unexpanded.
+func (m *performanceQueryImpl) ExpandWildCardPathWithFlags(counterPath string, flags uint32) ([]string, error) {
+	if m.computer != "" && !strings.HasPrefix(counterPath, `\\`) {
+		counterPath = `\\` + m.computer + counterPath
+	}
+	var required uint32
 	for buflen := initialBufferSize; buflen <= m.maxBufferSize; buflen *= 2 {
 		buf := make([]uint16, buflen)
 
 		// Get the info with the current buffer size
 		size := buflen
-		ret := pdhExpandWildCardPath(counterPath, &buf[0], &size)
+		var ret uint32
+		if m.dataSource == "" && pdhExpandWildCardPathHWProc != nil {
+			ret = pdhExpandWildCardPathH(0, counterPath, &buf[0], &size, flags)
+		} else {
+			ret = pdhExpandWildCardPath(m.dataSource, counterPath, &buf[0], &size, flags)
+		}
 		if ret == errorSuccess {
 			return utf16ToStringArray(buf), nil
 		}
@@ -225,9 +464,30 @@ func (m *performanceQueryImpl) ExpandWildCardPath(counterPath string) ([]string,
 		if ret != pdhMoreData {
 			return nil, newPdhError(ret)
 		}
+		required = size
+		m.stats.retried()
 	}
 
-	return nil, errBufferLimitReached
+	return nil, m.bufferLimitError(counterPath, required)
+}
+
+// bufferLimitError returns an *ErrBufferLimit for the given counter path and the last size hint of PDH
+func (m *performanceQueryImpl) bufferLimitError(counterPath string, required uint32) error {
+	return &ErrBufferLimit{
+		CounterPath: counterPath,
+		Required:    required,
+		Limit:       m.maxBufferSize,
+		Suggested:   suggestBufferSize(initialBufferSize, required, m.maxBufferSize),
+	}
+}
+
+// counterPathOf returns the full path of the given counter for error messages, or an empty string if it is not available
+func (m *performanceQueryImpl) counterPathOf(counterHandle pdhCounterHandle) string {
+	path, err := m.GetCounterPath(counterHandle)
+	if err != nil {
+		return ""
+	}
+	return path
 }
 
 func (m *performanceQueryImpl) GetFormattedCounterValueLong(hCounter pdhCounterHandle) (int32, error) {
@@ -235,12 +495,12 @@ func (m *performanceQueryImpl) GetFormattedCounterValueLong(hCounter pdhCounterH
 	var value pdhFmtCounterValueLong
 
 	if ret := pdhGetFormattedCounterValueLong(hCounter, &counterType, &value); ret != errorSuccess {
-		return 0, newPdhError(ret)
+		return 0, m.stats.counterError(hCounter, newPdhError(ret))
 	}
 	if value.CStatus == pdhCstatusValidData || value.CStatus == pdhCstatusNewData {
 		return value.LongValue, nil
 	}
-	return 0, newPdhError(value.CStatus)
+	return 0, m.stats.counterError(hCounter, newPdhError(value.CStatus))
 }
 
 func (m *performanceQueryImpl) GetFormattedCounterValueLarge(hCounter pdhCounterHandle) (int64, error) {
@@ -248,166 +508,91 @@ func (m *performanceQueryImpl) GetFormattedCounterValueLarge(hCounter pdhCounter
 	var value pdhFmtCounterValueLarge
 
 	if ret := pdhGetFormattedCounterValueLarge(hCounter, &counterType, &value); ret != errorSuccess {
-		return 0, newPdhError(ret)
+		return 0, m.stats.counterError(hCounter, newPdhError(ret))
 	}
 	if value.CStatus == pdhCstatusValidData || value.CStatus == pdhCstatusNewData {
 		return value.LargeValue, nil
 	}
-	return 0, newPdhError(value.CStatus)
+	return 0, m.stats.counterError(hCounter, newPdhError(value.CStatus))
 }
 
 // GetFormattedCounterValueDouble computes a displayable value for the specified counter
-func (*performanceQueryImpl) GetFormattedCounterValueDouble(hCounter pdhCounterHandle) (float64, error) {
+func (m *performanceQueryImpl) GetFormattedCounterValueDouble(hCounter pdhCounterHandle) (float64, error) {
 	var counterType uint32
 	var value pdhFmtCounterValueDouble
 
 	if ret := pdhGetFormattedCounterValueDouble(hCounter, &counterType, &value); ret != errorSuccess {
-		return 0, newPdhError(ret)
+		return 0, m.stats.counterError(hCounter, newPdhError(ret))
 	}
 	if value.CStatus == pdhCstatusValidData || value.CStatus == pdhCstatusNewData {
 		return value.DoubleValue, nil
 	}
-	return 0, newPdhError(value.CStatus)
+	return 0, m.stats.counterError(hCounter, newPdhError(value.CStatus))
 }
 
 func (m *performanceQueryImpl) GetFormattedCounterArrayLong(hCounter pdhCounterHandle) ([]longValue, error) {
-	for buflen := initialBufferSize; buflen <= m.maxBufferSize; buflen *= 2 {
-		buf := make([]byte, buflen)
-
-		// Get the info with the current buffer size
-		var itemCount uint32
-		size := buflen
-		ret := pdhGetFormattedCounterArrayLong(hCounter, &size, &itemCount, &buf[0])
-		if ret == errorSuccess {
-			//nolint:gosec // G103: Valid use of unsafe call to create PDH_FMT_COUNTERVALUE_ITEM_LONG
-			items := (*[1 << 20]pdhFmtCounterValueItemLong)(unsafe.Pointer(&buf[0]))[:itemCount]
-			values := make([]longValue, 0, itemCount)
-			for _, item := range items {
-				if item.FmtValue.CStatus == pdhCstatusValidData || item.FmtValue.CStatus == pdhCstatusNewData {
-					val := longValue{utf16PtrToString(item.SzName), item.FmtValue.LongValue}
-					values = append(values, val)
-				}
+	var values []longValue
+	err := m.getCounterArray(hCounter, pdhGetFormattedCounterArrayLong, func(buf *byte, itemCount uint32) {
+		//nolint:gosec // G103: Valid use of unsafe call to create PDH_FMT_COUNTERVALUE_ITEM_LONG
+		items := (*[1 << 20]pdhFmtCounterValueItemLong)(unsafe.Pointer(buf))[:itemCount]
+		values = make([]longValue, 0, itemCount)
+		for _, item := range items {
+			if item.FmtValue.CStatus == pdhCstatusValidData || item.FmtValue.CStatus == pdhCstatusNewData {
+				val := longValue{m.names.name(item.SzName), item.FmtValue.LongValue}
+				values = append(values, val)
 			}
-			return values, nil
-		}
-
-		// Use the size as a hint if it exceeds the current buffer size
-		if size > buflen {
-			buflen = size
-		}
-
-		// We got a non-recoverable error so exit here
-		if ret != pdhMoreData {
-			return nil, newPdhError(ret)
 		}
-	}
-
-	return nil, errBufferLimitReached
+	})
+	return values, err
 }
 
 func (m *performanceQueryImpl) GetFormattedCounterArrayLarge(hCounter pdhCounterHandle) ([]largeValue, error) {
-	for buflen := initialBufferSize; buflen <= m.maxBufferSize; buflen *= 2 {
-		buf := make([]byte, buflen)
-
-		// Get the info with the current buffer size
-		var itemCount uint32
-		size := buflen
-		ret := pdhGetFormattedCounterArrayLarge(hCounter, &size, &itemCount, &buf[0])
-		if ret == errorSuccess {
-			//nolint:gosec // G103: Valid use of unsafe call to create PDH_FMT_COUNTERVALUE_ITEM_LARGE
-			items := (*[1 << 20]pdhFmtCounterValueItemLarge)(unsafe.Pointer(&buf[0]))[:itemCount]
-			values := make([]largeValue, 0, itemCount)
-			for _, item := range items {
-				if item.FmtValue.CStatus == pdhCstatusValidData || item.FmtValue.CStatus == pdhCstatusNewData {
-					val := largeValue{utf16PtrToString(item.SzName), item.FmtValue.LargeValue}
-					values = append(values, val)
-				}
+	var values []largeValue
+	err := m.getCounterArray(hCounter, pdhGetFormattedCounterArrayLarge, func(buf *byte, itemCount uint32) {
+		//nolint:gosec // G103: Valid use of unsafe call to create PDH_FMT_COUNTERVALUE_ITEM_LARGE
+		items := (*[1 << 20]pdhFmtCounterValueItemLarge)(unsafe.Pointer(buf))[:itemCount]
+		values = make([]largeValue, 0, itemCount)
+		for _, item := range items {
+			if item.FmtValue.CStatus == pdhCstatusValidData || item.FmtValue.CStatus == pdhCstatusNewData {
+				val := largeValue{m.names.name(item.SzName), item.FmtValue.LargeValue}
+				values = append(values, val)
 			}
-			return values, nil
-		}
-
-		// Use the size as a hint if it exceeds the current buffer size
-		if size > buflen {
-			buflen = size
-		}
-
-		// We got a non-recoverable error so exit here
-		if ret != pdhMoreData {
-			return nil, newPdhError(ret)
 		}
-	}
-
-	return nil, errBufferLimitReached
+	})
+	return values, err
 }
 
 func (m *performanceQueryImpl) GetFormattedCounterArrayDouble(hCounter pdhCounterHandle) ([]doubleValue, error) {
-	for buflen := initialBufferSize; buflen <= m.maxBufferSize; buflen *= 2 {
-		buf := make([]byte, buflen)
-
-		// Get the info with the current buffer size
-		var itemCount uint32
-		size := buflen
-		ret := pdhGetFormattedCounterArrayDouble(hCounter, &size, &itemCount, &buf[0])
-		if ret == errorSuccess {
-			//nolint:gosec // G103: Valid use of unsafe call to create PDH_FMT_COUNTERVALUE_ITEM_DOUBLE
-			items := (*[1 << 20]pdhFmtCounterValueItemDouble)(unsafe.Pointer(&buf[0]))[:itemCount]
-			values := make([]doubleValue, 0, itemCount)
-			for _, item := range items {
-				if item.FmtValue.CStatus == pdhCstatusValidData || item.FmtValue.CStatus == pdhCstatusNewData {
-					val := doubleValue{utf16PtrToString(item.SzName), item.FmtValue.DoubleValue}
-					values = append(values, val)
-				}
+	var values []doubleValue
+	err := m.getCounterArray(hCounter, pdhGetFormattedCounterArrayDouble, func(buf *byte, itemCount uint32) {
+		//nolint:gosec // G103: Valid use of unsafe call to create PDH_FMT_COUNTERVALUE_ITEM_DOUBLE
+		items := (*[1 << 20]pdhFmtCounterValueItemDouble)(unsafe.Pointer(buf))[:itemCount]
+		values = make([]doubleValue, 0, itemCount)
+		for _, item := range items {
+			if item.FmtValue.CStatus == pdhCstatusValidData || item.FmtValue.CStatus == pdhCstatusNewData {
+				val := doubleValue{m.names.name(item.SzName), item.FmtValue.DoubleValue}
+				values = append(values, val)
 			}
-			return values, nil
-		}
-
-		// Use the size as a hint if it exceeds the current buffer size
-		if size > buflen {
-			buflen = size
 		}
-
-		// We got a non-recoverable error so exit here
-		if ret != pdhMoreData {
-			return nil, newPdhError(ret)
-		}
-	}
-
-	return nil, errBufferLimitReached
+	})
+	return values, err
 }
 
 func (m *performanceQueryImpl) GetRawCounterArray(hCounter pdhCounterHandle) ([]counterValue, error) {
-	for buflen := initialBufferSize; buflen <= m.maxBufferSize; buflen *= 2 {
-		buf := make([]byte, buflen)
-
-		// Get the info with the current buffer size
-		var itemCount uint32
-		size := buflen
-		ret := pdhGetRawCounterArray(hCounter, &size, &itemCount, &buf[0])
-		if ret == errorSuccess {
-			//nolint:gosec // G103: Valid use of unsafe call to create PDH_RAW_COUNTER_ITEM
-			items := (*[1 << 20]pdhRawCounterItem)(unsafe.Pointer(&buf[0]))[:itemCount]
-			values := make([]counterValue, 0, itemCount)
-			for _, item := range items {
-				if item.RawValue.CStatus == pdhCstatusValidData || item.RawValue.CStatus == pdhCstatusNewData {
-					val := counterValue{utf16PtrToString(item.SzName), item.RawValue.FirstValue}
-					values = append(values, val)
-				}
+	var values []counterValue
+	err := m.getCounterArray(hCounter, pdhGetRawCounterArray, func(buf *byte, itemCount uint32) {
+		//nolint:gosec // G103: Valid use of unsafe call to create PDH_RAW_COUNTER_ITEM
+		items := (*[1 << 20]pdhRawCounterItem)(unsafe.Pointer(buf))[:itemCount]
+		values = make([]counterValue, 0, itemCount)
+		for _, item := range items {
+			if item.RawValue.CStatus == pdhCstatusValidData || item.RawValue.CStatus == pdhCstatusNewData {
+				timestamp, _ := localFileTimeToTime(item.RawValue.TimeStamp)
+				val := counterValue{Name: m.names.name(item.SzName), Value: item.RawValue.FirstValue, Timestamp: timestamp}
+				values = append(values, val)
 			}
-			return values, nil
-		}
-
-		// Use the size as a hint if it exceeds the current buffer size
-		if size > buflen {
-			buflen = size
 		}
-
-		// We got a non-recoverable error so exit here
-		if ret != pdhMoreData {
-			return nil, newPdhError(ret)
-		}
-	}
-
-	return nil, errBufferLimitReached
+	})
+	return values, err
 }
 
 func (m *performanceQueryImpl) CollectData() error {
@@ -416,9 +601,12 @@ func (m *performanceQueryImpl) CollectData() error {
 		return errUninitializedQuery
 	}
 
+	start := time.Now()
 	if ret = pdhCollectQueryData(m.queryHandle); ret != errorSuccess {
 		return newPdhError(ret)
 	}
+	m.stats.collected(time.Since(start))
+	m.names.rotate()
 	return nil
 }
 
@@ -426,20 +614,55 @@ func (m *performanceQueryImpl) CollectDataWithTime() (time.Time, error) {
 	if m.queryHandle == 0 {
 		return time.Now(), errUninitializedQuery
 	}
+	start := time.Now()
 	ret, mtime := pdhCollectQueryDataWithTime(m.queryHandle)
 	if ret != errorSuccess {
 		return time.Now(), newPdhError(ret)
 	}
+	m.stats.collected(time.Since(start))
+	m.names.rotate()
 	return mtime, nil
 }
 
-func (*performanceQueryImpl) IsVistaOrNewer() bool {
-	return pdhAddEnglishCounterSupported()
+// Capabilities probes pdh.dll for the optional functions used by the query
+func (*performanceQueryImpl) Capabilities() Capabilities {
+	return Capabilities{
+		AddEnglishCounter:   pdhAddEnglishCounterSupported(),
+		CollectDataWithTime: pdhCollectQueryDataWithTimeSupported(),
+	}
+}
+
+// Stats returns the number of counters added, collections, the duration of the last collection, buffer retries
+// and the last error of each counter handle
+func (m *performanceQueryImpl) Stats() QueryStats {
+	return m.stats.snapshot()
+}
+
+// IsVistaOrNewer reports whether language-neutral counter paths are supported.
+//
+// Deprecated: use Capabilities instead.
+func (m *performanceQueryImpl) IsVistaOrNewer() bool {
+	return m.Capabilities().AddEnglishCounter
 }
 
 func (m *performanceQueryImpl) GetRawCounterValue(hCounter pdhCounterHandle) (int64, error) {
+	value, _, err := m.GetRawCounterValueWithTime(hCounter)
+	return value, err
+}
+
+// GetRawCounterValueWithTime returns the raw value of the counter together with the time the provider collected it.
+func (m *performanceQueryImpl) GetRawCounterValueWithTime(hCounter pdhCounterHandle) (int64, time.Time, error) {
+	sample, err := m.GetRawCounterSample(hCounter)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return sample.FirstValue, sample.Time, nil
+}
+
+// GetRawCounterSample returns the complete raw value of the counter, including the second value, multi count and timestamp.
+func (m *performanceQueryImpl) GetRawCounterSample(hCounter pdhCounterHandle) (RawSample, error) {
 	if m.queryHandle == 0 {
-		return 0, errUninitializedQuery
+		return RawSample{}, errUninitializedQuery
 	}
 
 	var counterType uint32
@@ -448,32 +671,62 @@ func (m *performanceQueryImpl) GetRawCounterValue(hCounter pdhCounterHandle) (in
 
 	if ret = pdhGetRawCounterValue(hCounter, &counterType, &value); ret == errorSuccess {
 		if value.CStatus == pdhCstatusValidData || value.CStatus == pdhCstatusNewData {
-			return value.FirstValue, nil
+			return newRawSample(&value), nil
 		}
-		return 0, newPdhError(value.CStatus)
+		return RawSample{}, m.stats.counterError(hCounter, newPdhError(value.CStatus))
 	}
-	return 0, newPdhError(ret)
+	return RawSample{}, m.stats.counterError(hCounter, newPdhError(ret))
 }
 
-// utf16PtrToString converts Windows API LPTSTR (pointer to string) to go string
+// CalculateFormattedFromRaw calculates the displayable value of the counter from two raw samples, using the counter type
+// and scale of the given counter. The samples may have been collected by any query containing the same counter path.
+func (m *performanceQueryImpl) CalculateFormattedFromRaw(hCounter pdhCounterHandle, oldSample, newSample RawSample) (float64, error) {
+	if m.queryHandle == 0 {
+		return 0, errUninitializedQuery
+	}
+
+	newValue := newSample.pdhRawCounter()
+	oldValue := oldSample.pdhRawCounter()
+	var value pdhFmtCounterValueDouble
+	if ret := pdhCalculateCounterFromRawValue(hCounter, &newValue, &oldValue, &value); ret != errorSuccess {
+		return 0, m.stats.counterError(hCounter, newPdhError(ret))
+	}
+	if value.CStatus != pdhCstatusValidData && value.CStatus != pdhCstatusNewData {
+		return 0, m.stats.counterError(hCounter, newPdhError(value.CStatus))
+	}
+	return value.DoubleValue, nil
+}
+
+// utf16PtrToString converts Windows API LPTSTR (pointer to string) to go string.
+// A pooled decoder is used, so only the resulting string is allocated.
 func utf16PtrToString(s *uint16) string {
 	if s == nil {
 		return ""
 	}
-	//nolint:gosec // G103: Valid use of unsafe call to create string from Windows API LPTSTR (pointer to string)
-	return syscall.UTF16ToString((*[1 << 29]uint16)(unsafe.Pointer(s))[0:])
+	decoder := utf16Decoders.Get().(*utf16Decoder)
+	str := decoder.decode(utf16Units(s))
+	if cap(decoder.buf) <= maxPooledDecoderBuffer {
+		utf16Decoders.Put(decoder)
+	}
+	return str
 }
 
-// utf16ToStringArray converts list of Windows API NULL terminated strings  to go string array
+// utf16ToStringArray converts list of Windows API NULL terminated strings  to go string array.
+// Strings are split on the UTF-16 code units, so characters outside the BMP (e.g. emoji) encoded as
+// surrogate pairs don't shift the start of the following strings.
 func utf16ToStringArray(buf []uint16) []string {
 	var strings []string
-	nextLineStart := 0
-	stringLine := utf16PtrToString(&buf[0])
-	for stringLine != "" {
-		strings = append(strings, stringLine)
-		nextLineStart += len([]rune(stringLine)) + 1
-		remainingBuf := buf[nextLineStart:]
-		stringLine = utf16PtrToString(&remainingBuf[0])
+	var decoder utf16Decoder
+	for len(buf) > 0 && buf[0] != 0 {
+		end := slices.Index(buf, 0)
+		if end < 0 {
+			end = len(buf)
+		}
+		strings = append(strings, decoder.decode(buf[:end]))
+		if end == len(buf) {
+			break
+		}
+		buf = buf[end+1:]
 	}
 	return strings
 }
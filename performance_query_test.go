@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -18,7 +19,7 @@ func TestPerformanceQueryImplIntegration(t *testing.T) {
 	t.Logf("Test close before open")
 	err := query.Close()
 	require.ErrorIs(t, err, errUninitializedQuery)
-	
+
 	t.Logf("Test addCounterToQuery before open")
 	_, err = query.AddCounterToQuery("")
 	require.ErrorIs(t, err, errUninitializedQuery)
@@ -30,7 +31,7 @@ func TestPerformanceQueryImplIntegration(t *testing.T) {
 	t.Logf("Test collectData before open")
 	err = query.CollectData()
 	require.ErrorIs(t, err, errUninitializedQuery)
-	
+
 	counterPath := "\\Processor Information(_Total)\\% Processor Time"
 
 	t.Logf("Test addCounterToQuery")
@@ -94,7 +95,7 @@ func TestPerformanceQueryImplIntegration(t *testing.T) {
 	t.Logf("paths %s: %v", counterPath, paths)
 
 	counterPath = "\\Process(*)\\% Processor Time"
-	
+
 	t.Logf("Test addEnglishCounterToQuery")
 	require.NoError(t, query.Open())
 	hCounter, err = query.AddEnglishCounterToQuery(counterPath)
@@ -151,4 +152,108 @@ func ExampleNewPerformanceQueryCreator() {
 
 	// Output:
 	// \Processor Information(_Total)\% Processor Time: 0.000000
-}
\ No newline at end of file
+}
+
+func BenchmarkGetFormattedCounterArrayDouble(b *testing.B) {
+	query := &performanceQueryImpl{maxBufferSize: uint32(defaultMaxBufferSize)}
+	require.NoError(b, query.Open())
+	defer query.Close()
+
+	hCounter, err := query.AddEnglishCounterToQuery(`\Process(*)\% Processor Time`)
+	require.NoError(b, err)
+	require.NoError(b, query.CollectData())
+	time.Sleep(time.Second)
+	require.NoError(b, query.CollectData())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := query.GetFormattedCounterArrayDouble(hCounter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetRawCounterArray(b *testing.B) {
+	query := &performanceQueryImpl{maxBufferSize: uint32(defaultMaxBufferSize)}
+	require.NoError(b, query.Open())
+	defer query.Close()
+
+	hCounter, err := query.AddEnglishCounterToQuery(`\Process(*)\Working Set`)
+	require.NoError(b, err)
+	require.NoError(b, query.CollectData())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := query.GetRawCounterArray(hCounter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+var benchmarkInstanceNames = []string{
+	"svchost#12", "chrome#3", "System", "Idle", "_Total", `\Device\HarddiskVolume3`,
+	"Intel[R] Ethernet Connection (7) I219-LM", "sqlservr", "explorer", "Prozessorzeit äöü",
+}
+
+func benchmarkUTF16Names(b *testing.B) [][]uint16 {
+	names := make([][]uint16, 0, len(benchmarkInstanceNames))
+	for _, name := range benchmarkInstanceNames {
+		u, err := syscall.UTF16FromString(name)
+		require.NoError(b, err)
+		names = append(names, u)
+	}
+	return names
+}
+
+func BenchmarkSyscallUTF16ToString(b *testing.B) {
+	names := benchmarkUTF16Names(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, name := range names {
+			benchmarkString = syscall.UTF16ToString(name)
+		}
+	}
+}
+
+func BenchmarkUTF16PtrToString(b *testing.B) {
+	names := benchmarkUTF16Names(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, name := range names {
+			benchmarkString = utf16PtrToString(&name[0])
+		}
+	}
+}
+
+func BenchmarkInstanceNames(b *testing.B) {
+	names := benchmarkUTF16Names(b)
+	var cache instanceNames
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, name := range names {
+			benchmarkString = cache.name(&name[0])
+		}
+		cache.rotate()
+	}
+}
+
+func TestUTF16DecoderMatchesSyscall(t *testing.T) {
+	var decoder utf16Decoder
+	inputs := [][]uint16{
+		{},
+		{'a', 'b', 'c'},
+		{0x00E4, 0x65E5, 0x672C},
+		{0xD83D, 0xDE00, 'x'},
+		{'a', 0xD800, 'b', 0xDC00},
+	}
+	for _, input := range inputs {
+		require.Equal(t, syscall.UTF16ToString(input), decoder.decode(input))
+	}
+}
+
+var benchmarkString string
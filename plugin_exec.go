@@ -0,0 +1,276 @@
+package win_perf_counters
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultExecPluginTimeout = 5 * time.Second
+	maxExecPluginBackoff     = 5 * time.Minute
+)
+
+var (
+	errExecPluginTimeout      = errors.New("exec plugin did not respond in time")
+	errExecPluginWriteTimeout = errors.New("exec plugin did not read its input in time")
+)
+
+// ExecPlugin 通过子进程的标准输入/输出以 JSON Lines 协议扩展输出或增强功能，第三方无需修改本项目即可接入。
+//
+// 每条指标以一行 Metric JSON 写入子进程的标准输入。作为增强插件时，子进程必须对每一行输入回复一行：
+// 修改后的 Metric JSON，或 null 表示丢弃该指标。
+//
+// 子进程退出、写入失败、写入或响应超时时插件会被停止，并在指数退避后自动重启；
+// 故障期间作为输出插件的指标会被丢弃，作为增强插件的指标则原样放行，不会影响采集。
+type ExecPlugin struct {
+	// Log 日志记录器。
+	Log Logger
+	// Timeout 写入一条指标以及增强插件等待响应的超时时间。
+	Timeout time.Duration
+
+	command  []string
+	enricher bool
+
+	lock     sync.Mutex
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	stdout   *bufio.Reader
+	failures int
+	retryAt  time.Time
+	closed   bool
+}
+
+// NewExecSink 创建以 command 为子进程的输出插件。
+func NewExecSink(command ...string) *ExecPlugin {
+	return newExecPlugin(command, false)
+}
+
+// NewExecEnricher 创建以 command 为子进程的增强插件。
+func NewExecEnricher(command ...string) *ExecPlugin {
+	return newExecPlugin(command, true)
+}
+
+func newExecPlugin(command []string, enricher bool) *ExecPlugin {
+	return &ExecPlugin{
+		Log:      Logger{Name: "win_perf_counters"},
+		Timeout:  defaultExecPluginTimeout,
+		command:  command,
+		enricher: enricher,
+	}
+}
+
+// Start 启动子进程。
+func (p *ExecPlugin) Start() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.start()
+}
+
+// start 启动子进程，调用方需持有 lock。
+func (p *ExecPlugin) start() error {
+	if len(p.command) == 0 {
+		return errors.New("no command configured for exec plugin")
+	}
+	cmd := exec.Command(p.command[0], p.command[1:]...) //nolint:gosec // G204: command is provided by the embedder
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting exec plugin %q failed: %w", p.command[0], err)
+	}
+	p.cmd = cmd
+	p.stdin = stdin
+	p.stdout = bufio.NewReader(stdout)
+	return nil
+}
+
+// stop 终止子进程，调用方需持有 lock。
+func (p *ExecPlugin) stop() {
+	if p.cmd == nil {
+		return
+	}
+	_ = p.stdin.Close()
+	_ = p.cmd.Process.Kill()
+	_ = p.cmd.Wait()
+	p.cmd = nil
+	p.stdin = nil
+	p.stdout = nil
+}
+
+// fail 记录一次故障并停止子进程，调用方需持有 lock。
+func (p *ExecPlugin) fail(err error) {
+	p.stop()
+	p.failures++
+	backoff := time.Second << min(p.failures, 16)
+	if backoff > maxExecPluginBackoff {
+		backoff = maxExecPluginBackoff
+	}
+	p.retryAt = time.Now().Add(backoff)
+	p.Log.Errorf("Exec plugin %q failed, restarting in %v: %v", p.command[0], backoff, err)
+}
+
+// ready 确保子进程正在运行，必要时在退避期结束后重启，调用方需持有 lock。
+func (p *ExecPlugin) ready() bool {
+	if p.closed {
+		return false
+	}
+	if p.cmd != nil {
+		return true
+	}
+	if time.Now().Before(p.retryAt) {
+		return false
+	}
+	if err := p.start(); err != nil {
+		p.fail(err)
+		return false
+	}
+	return true
+}
+
+// send 将指标写入子进程的标准输入，调用方需持有 lock。
+//
+// 子进程不再读取标准输入时管道写满后写入会一直阻塞，并连带阻塞持有 lock 的采集，
+// 因此超过 Timeout 时终止子进程，使阻塞的写入返回。
+func (p *ExecPlugin) send(metric *Metric) error {
+	data, err := json.Marshal(metric)
+	if err != nil {
+		return err
+	}
+	written := make(chan error, 1)
+	stdin := p.stdin
+	go func() {
+		_, err := stdin.Write(append(data, '\n'))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		return err
+	case <-time.After(p.Timeout):
+		_ = p.cmd.Process.Kill()
+		return errExecPluginWriteTimeout
+	}
+}
+
+// Collect 将指标发送给输出插件，可直接作为 CollectFunc 注册。
+func (p *ExecPlugin) Collect(measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.ready() {
+		return
+	}
+	if err := p.send(&Metric{Measurement: measurement, Tags: tags, Fields: fields, Timestamp: timestamp}); err != nil {
+		p.fail(err)
+		return
+	}
+	p.failures = 0
+}
+
+// Enrich 将指标交给增强插件处理，可直接作为 EnrichFunc 注册。
+func (p *ExecPlugin) Enrich(metric *Metric) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.ready() {
+		return true
+	}
+	if err := p.send(metric); err != nil {
+		p.fail(err)
+		return true
+	}
+
+	type response struct {
+		line []byte
+		err  error
+	}
+	result := make(chan response, 1)
+	stdout := p.stdout
+	go func() {
+		line, err := stdout.ReadBytes('\n')
+		result <- response{line, err}
+	}()
+
+	var resp response
+	select {
+	case resp = <-result:
+	case <-time.After(p.Timeout):
+		resp.err = errExecPluginTimeout
+	}
+	if resp.err != nil {
+		p.fail(resp.err)
+		return true
+	}
+
+	var enriched *Metric
+	decoder := json.NewDecoder(bytes.NewReader(resp.line))
+	// 保留数值的原文，按原字段的类型转换，避免整数字段变为浮点数
+	decoder.UseNumber()
+	if err := decoder.Decode(&enriched); err != nil {
+		p.fail(fmt.Errorf("invalid response: %w", err))
+		return true
+	}
+	p.failures = 0
+	if enriched == nil {
+		return false
+	}
+	for field, value := range enriched.Fields {
+		if number, ok := value.(json.Number); ok {
+			enriched.Fields[field] = numberFieldValue(number, metric.Fields[field])
+		}
+	}
+	*metric = *enriched
+	return true
+}
+
+// numberFieldValue 将增强插件返回的数值转换为原字段 original 的类型，使 FieldTypes 等设置的类型不会因 JSON 编码而丢失。
+// 原来没有该字段或无法按原类型表示时，整数转换为 int64，其它数值转换为 float64。
+func numberFieldValue(number json.Number, original interface{}) interface{} {
+	switch original.(type) {
+	case int64:
+		if v, err := number.Int64(); err == nil {
+			return v
+		}
+	case uint64:
+		if v, err := strconv.ParseUint(number.String(), 10, 64); err == nil {
+			return v
+		}
+	case float64:
+		if v, err := number.Float64(); err == nil {
+			return v
+		}
+	case nil:
+		if v, err := number.Int64(); err == nil {
+			return v
+		}
+	}
+	v, err := number.Float64()
+	if err != nil {
+		return number.String()
+	}
+	return v
+}
+
+// Close 停止子进程，之后的指标不再发送给插件。
+func (p *ExecPlugin) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.closed = true
+	p.stop()
+	return nil
+}
@@ -0,0 +1,96 @@
+package win_perf_counters
+
+import (
+	"encoding/json"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestExecPluginWriteTimeout 子进程不读取标准输入时，写满管道后的写入在 Timeout 后终止子进程，Collect 不会一直阻塞。
+func TestExecPluginWriteTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep is not available")
+	}
+	sink := NewExecSink("sleep", "60")
+	sink.Timeout = 100 * time.Millisecond
+	require.NoError(t, sink.Start())
+	t.Cleanup(func() { _ = sink.Close() })
+
+	fields := map[string]interface{}{"payload": string(make([]byte, 4096))}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// 管道缓冲区写满后写入超时，子进程被终止并进入退避
+		for range 1024 {
+			sink.Collect("win_cpu", fields, nil, time.Now())
+			sink.lock.Lock()
+			stopped := sink.cmd == nil
+			sink.lock.Unlock()
+			if stopped {
+				return
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Collect blocked on a plugin that does not read its input")
+	}
+
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	require.Nil(t, sink.cmd)
+	require.Equal(t, 1, sink.failures)
+}
+
+// TestExecPluginEnrichKeepsFieldTypes 增强插件原样返回的指标保持原来的字段类型。
+func TestExecPluginEnrichKeepsFieldTypes(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat is not available")
+	}
+	enricher := NewExecEnricher("cat")
+	require.NoError(t, enricher.Start())
+	t.Cleanup(func() { _ = enricher.Close() })
+
+	metric := &Metric{
+		Measurement: "win_cpu",
+		Tags:        map[string]string{"instance": "_Total"},
+		Fields: map[string]interface{}{
+			"Handle_Count":   int64(1234),
+			"Bytes_Total":    uint64(18446744073709551615),
+			"Percent_Idle":   12.0,
+			"Is_Responding":  true,
+			"Process_Status": "running",
+		},
+		Timestamp: time.Unix(1700000000, 0).UTC(),
+	}
+	want := *metric
+	require.True(t, enricher.Enrich(metric))
+	require.Equal(t, want.Fields, metric.Fields)
+	require.Equal(t, want.Tags, metric.Tags)
+}
+
+func TestNumberFieldValue(t *testing.T) {
+	tests := []struct {
+		number   json.Number
+		original interface{}
+		want     interface{}
+	}{
+		{"42", int64(1), int64(42)},
+		{"-42", int64(1), int64(-42)},
+		{"1.5", int64(1), 1.5},
+		{"18446744073709551615", uint64(1), uint64(18446744073709551615)},
+		{"-1", uint64(1), -1.0},
+		{"42", 1.0, 42.0},
+		{"1e3", 1.0, 1000.0},
+		{"42", true, 42.0},
+		{"42", nil, int64(42)},
+		{"4.2", nil, 4.2},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, numberFieldValue(tt.number, tt.original), "%s as %T", tt.number, tt.original)
+	}
+}
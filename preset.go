@@ -0,0 +1,427 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// objectPreset 表示一个预置的性能对象配置，以及基于采集结果计算的派生字段。
+type objectPreset struct {
+	// objectName 预置的性能对象名称。
+	objectName string
+	// instances 预置的实例列表。
+	instances []string
+	// counters 预置的计数器列表。
+	counters []string
+	// required 派生字段依赖的计数器，用户自定义 Counters 时也会自动追加。
+	required []string
+	// measurement 预置的测量名称。
+	measurement string
+	// requireService 服务器角色对应的 Windows 服务，对象未配置 RequireService 与 RequireObjectExists 时
+	// 只在安装了该服务的主机上采集。
+	requireService string
+	// derive 根据采集到的字段计算派生字段。
+	derive func(m *WinPerfCounters, hostInfo *hostCountersInfo, object *ObjectConfig, fields map[string]interface{})
+	// tags 根据实例名称添加的标签。
+	tags func(object *ObjectConfig, instance string, tags map[string]string)
+	// accept 判断实例是否需要输出，为 nil 时输出所有实例。
+	accept func(object *ObjectConfig, instance string) bool
+}
+
+var objectPresets = map[string]objectPreset{
+	"memory": {
+		objectName: "Memory",
+		instances:  []string{emptyInstance},
+		counters: []string{
+			"Available Bytes",
+			"Cache Faults/sec",
+			"Demand Zero Faults/sec",
+			"Page Faults/sec",
+			"Pages/sec",
+			"Transition Faults/sec",
+			"Pool Nonpaged Bytes",
+			"Pool Paged Bytes",
+			"Standby Cache Reserve Bytes",
+			"Standby Cache Normal Priority Bytes",
+			"Standby Cache Core Bytes",
+		},
+		required:    []string{"Available Bytes"},
+		measurement: "win_mem",
+		derive:      deriveMemoryPercent,
+	},
+	"cpu": {
+		objectName: "Processor",
+		instances:  []string{"*"},
+		counters: []string{
+			"% Idle Time",
+			"% Interrupt Time",
+			"% Privileged Time",
+			"% User Time",
+			"% Processor Time",
+			"% DPC Time",
+		},
+		measurement: "win_cpu",
+	},
+	"disk": {
+		objectName: "LogicalDisk",
+		instances:  []string{"*"},
+		counters: []string{
+			"% Idle Time",
+			"% Disk Time",
+			"% Disk Read Time",
+			"% Disk Write Time",
+			"% Free Space",
+			"Free Megabytes",
+			"Current Disk Queue Length",
+			"Avg. Disk sec/Read",
+			"Avg. Disk sec/Write",
+			"Disk Reads/sec",
+			"Disk Writes/sec",
+			"Disk Read Bytes/sec",
+			"Disk Write Bytes/sec",
+		},
+		measurement: "win_disk",
+	},
+	"network": {
+		objectName: "Network Interface",
+		instances:  []string{"*"},
+		counters: []string{
+			"Bytes Received/sec",
+			"Bytes Sent/sec",
+			"Packets Received/sec",
+			"Packets Sent/sec",
+			"Packets Received Discarded",
+			"Packets Outbound Discarded",
+			"Packets Received Errors",
+			"Packets Outbound Errors",
+			"Current Bandwidth",
+		},
+		measurement: "win_net",
+	},
+	"system": {
+		objectName: "System",
+		instances:  []string{emptyInstance},
+		counters: []string{
+			"Context Switches/sec",
+			"System Calls/sec",
+			"Processor Queue Length",
+			"System Up Time",
+			"Processes",
+			"Threads",
+		},
+		measurement: "win_system",
+	},
+	"iis": {
+		objectName: "Web Service",
+		instances:  []string{"*"},
+		counters: []string{
+			"Get Requests/sec",
+			"Post Requests/sec",
+			"Connection Attempts/sec",
+			"Current Connections",
+			"ISAPI Extension Requests/sec",
+			"Bytes Received/sec",
+			"Bytes Sent/sec",
+			"Not Found Errors/sec",
+		},
+		measurement: "win_websvc",
+	},
+	"sqlserver_general": {
+		objectName: "SQLServer:General Statistics",
+		instances:  []string{emptyInstance},
+		counters: []string{
+			"User Connections",
+			"Logins/sec",
+			"Logouts/sec",
+			"Processes blocked",
+		},
+		measurement: "win_sqlserver",
+	},
+	"sqlserver_buffers": {
+		objectName: "SQLServer:Buffer Manager",
+		instances:  []string{emptyInstance},
+		counters: []string{
+			"Buffer cache hit ratio",
+			"Page life expectancy",
+			"Page reads/sec",
+			"Page writes/sec",
+			"Lazy writes/sec",
+			"Checkpoint pages/sec",
+		},
+		measurement: "win_sqlserver",
+	},
+	"sqlserver_sql": {
+		objectName: "SQLServer:SQL Statistics",
+		instances:  []string{emptyInstance},
+		counters: []string{
+			"Batch Requests/sec",
+			"SQL Compilations/sec",
+			"SQL Re-Compilations/sec",
+		},
+		measurement: "win_sqlserver",
+	},
+	"msmq": {
+		objectName: "MSMQ Queue",
+		instances:  []string{"*"},
+		counters: []string{
+			"Messages in Queue",
+			"Bytes in Queue",
+			"Messages in Journal Queue",
+			"Bytes in Journal Queue",
+		},
+		measurement: "win_msmq",
+		tags:        applyMSMQTags,
+		accept:      acceptMSMQQueue,
+	},
+	"ad": {
+		objectName: "NTDS",
+		instances:  []string{emptyInstance},
+		counters: []string{
+			"DRA Inbound Objects Applied/sec",
+			"DRA Inbound Bytes Total/sec",
+			"DRA Outbound Bytes Total/sec",
+			"DRA Pending Replication Synchronizations",
+			"DRA Remaining Replication Updates",
+			"DRA Sync Requests Made",
+			"DRA Sync Requests Successful",
+			"DS Threads in Use",
+			"LDAP Client Sessions",
+			"LDAP Bind Time",
+			"LDAP Searches/sec",
+			"LDAP Writes/sec",
+			"ATQ Outstanding Queued Requests",
+		},
+		required: []string{
+			"DRA Inbound Objects Applied/sec",
+			"DRA Remaining Replication Updates",
+			"DRA Sync Requests Made",
+			"DRA Sync Requests Successful",
+		},
+		measurement:    "win_ad",
+		requireService: "NTDS",
+		derive:         deriveReplicationLatency,
+	},
+	"dns": {
+		objectName: "DNS",
+		instances:  []string{emptyInstance},
+		counters: []string{
+			"Total Query Received/sec",
+			"Total Response Sent/sec",
+			"Recursive Queries/sec",
+			"Recursive Query Failure/sec",
+			"Recursive TimeOut/sec",
+			"Dynamic Update Received/sec",
+			"Dynamic Update Rejected",
+			"Secure Update Failure",
+			"Zone Transfer Success",
+			"Zone Transfer Failure",
+		},
+		measurement:    "win_dns",
+		requireService: "DNS",
+	},
+	"dhcp": {
+		objectName: "DHCP Server",
+		instances:  []string{emptyInstance},
+		counters: []string{
+			"Packets Received/sec",
+			"Duplicates Dropped/sec",
+			"Packets Expired/sec",
+			"Active Queue Length",
+			"Conflict Check Queue Length",
+			"Discovers/sec",
+			"Offers/sec",
+			"Requests/sec",
+			"Acks/sec",
+			"Nacks/sec",
+			"Declines/sec",
+			"Releases/sec",
+		},
+		measurement:    "win_dhcp",
+		requireService: "DHCPServer",
+	},
+	"exchange_database": {
+		objectName: "MSExchange Database ==> Instances",
+		instances:  []string{"*"},
+		counters: []string{
+			"I/O Database Reads (Attached) Average Latency",
+			"I/O Database Writes (Attached) Average Latency",
+			"I/O Database Reads (Attached)/sec",
+			"I/O Database Writes (Attached)/sec",
+			"I/O Log Writes Average Latency",
+			"Log Record Stalls/sec",
+			"Log Threads Waiting",
+			"Database Cache Size (MB)",
+		},
+		measurement:    "win_exchange_db",
+		requireService: "MSExchangeIS",
+		tags:           applyExchangeDatabaseTags,
+	},
+	"exchange_rpc": {
+		objectName: "MSExchange RpcClientAccess",
+		instances:  []string{emptyInstance},
+		counters: []string{
+			"RPC Averaged Latency",
+			"RPC Requests",
+			"RPC Operations/sec",
+			"Active User Count",
+			"Connection Count",
+			"User Count",
+		},
+		measurement:    "win_exchange",
+		requireService: "MSExchangeRPC",
+	},
+	"exchange_transport": {
+		objectName: "MSExchangeTransport Queues",
+		instances:  []string{"_Total"},
+		counters: []string{
+			"Aggregate Delivery Queue Length (All Queues)",
+			"Active Remote Delivery Queue Length",
+			"Active Mailbox Delivery Queue Length",
+			"Retry Mailbox Delivery Queue Length",
+			"Submission Queue Length",
+			"Unreachable Queue Length",
+			"Poison Queue Length",
+		},
+		measurement:    "win_exchange_transport",
+		requireService: "MSExchangeTransport",
+	},
+}
+
+// presetBundles 全局 Presets 中可以使用的组合名称，展开为多个预置对象。
+var presetBundles = map[string][]string{
+	"sqlserver":         {"sqlserver_general", "sqlserver_buffers", "sqlserver_sql"},
+	"domain_controller": {"ad", "dns", "dhcp"},
+	"exchange":          {"exchange_database", "exchange_rpc", "exchange_transport"},
+}
+
+// expandPresets 为全局 Presets 中的每个预置添加一个对象，已有对象使用了同一预置时不再重复添加。
+func (m *WinPerfCounters) expandPresets(objects []ObjectConfig) ([]ObjectConfig, error) {
+	for _, name := range m.Presets {
+		names, ok := presetBundles[strings.ToLower(name)]
+		if !ok {
+			if _, ok := objectPresets[strings.ToLower(name)]; !ok {
+				return nil, fmt.Errorf("unknown preset %q", name)
+			}
+			names = []string{name}
+		}
+		for _, name := range names {
+			configured := slices.ContainsFunc(objects, func(object ObjectConfig) bool {
+				return strings.EqualFold(object.Preset, name)
+			})
+			if !configured {
+				objects = append(objects, ObjectConfig{Preset: name})
+			}
+		}
+	}
+	return objects, nil
+}
+
+// applyPresets 将预置配置合并到使用了 Preset 的对象中，已显式配置的项保持不变，
+// 全局 Presets 中的预置先展开为对象，合并后启用 TotalOnly 的对象只保留 _Total 实例。
+func (m *WinPerfCounters) applyPresets(objects []ObjectConfig) ([]ObjectConfig, error) {
+	objects, err := m.expandPresets(objects)
+	if err != nil {
+		return nil, err
+	}
+	for i := range objects {
+		object := &objects[i]
+		if object.Preset == "" {
+			object.applyTotalOnly()
+			continue
+		}
+		preset, ok := objectPresets[strings.ToLower(object.Preset)]
+		if !ok {
+			return nil, fmt.Errorf("unknown preset %q for object %q", object.Preset, object.ObjectName)
+		}
+		if object.ObjectName == "" {
+			object.ObjectName = preset.objectName
+		}
+		if len(object.Instances) == 0 {
+			object.Instances = preset.instances
+		}
+		if len(object.Counters) == 0 {
+			object.Counters = preset.counters
+		}
+		for _, counter := range preset.required {
+			if !slices.Contains(object.Counters, counter) {
+				object.Counters = append(slices.Clone(object.Counters), counter)
+			}
+		}
+		if object.Measurement == "" {
+			object.Measurement = preset.measurement
+		}
+		if object.RequireService == "" && !object.RequireObjectExists {
+			object.RequireService = preset.requireService
+		}
+		object.applyTotalOnly()
+	}
+	return objects, nil
+}
+
+// applyPresetFields 为使用了 Preset 的对象追加派生字段。
+func (m *WinPerfCounters) applyPresetFields(hostInfo *hostCountersInfo, object *ObjectConfig, fields map[string]interface{}) {
+	if object == nil || object.Preset == "" {
+		return
+	}
+	if preset, ok := objectPresets[strings.ToLower(object.Preset)]; ok && preset.derive != nil {
+		preset.derive(m, hostInfo, object, fields)
+	}
+}
+
+// presetOf 返回对象使用的预置，未使用预置时 ok 为 false。
+func presetOf(object *ObjectConfig) (preset objectPreset, ok bool) {
+	if object == nil || object.Preset == "" {
+		return objectPreset{}, false
+	}
+	preset, ok = objectPresets[strings.ToLower(object.Preset)]
+	return preset, ok
+}
+
+// filterPresetInstances 丢弃预置不需要输出的实例组。
+func filterPresetInstances(collectedFields fieldGrouping, groupObjects map[instanceGrouping]*ObjectConfig) {
+	for grouping := range collectedFields {
+		object := groupObjects[grouping]
+		if preset, ok := presetOf(object); ok && preset.accept != nil && !preset.accept(object, grouping.instance) {
+			delete(collectedFields, grouping)
+			delete(groupObjects, grouping)
+		}
+	}
+}
+
+// applyPresetTags 为使用了 Preset 的对象添加预置根据实例名称解析的标签。
+func applyPresetTags(object *ObjectConfig, instance string, tags map[string]string) {
+	if preset, ok := presetOf(object); ok && preset.tags != nil {
+		preset.tags(object, instance, tags)
+	}
+}
+
+// deriveMemoryPercent 结合 Available Bytes 与 GlobalMemoryStatusEx 获取的物理内存总量，
+// 计算 used_percent 与 available_percent 字段。物理内存总量只能在本机获取，因此只对本机数据生效。
+func deriveMemoryPercent(m *WinPerfCounters, hostInfo *hostCountersInfo, object *ObjectConfig, fields map[string]interface{}) {
+	if hostInfo.computer != "localhost" && !strings.EqualFold(hostInfo.computer, m.hostname()) {
+		return
+	}
+
+	field := object.fieldName("Available Bytes")
+	var available float64
+	switch v := fields[field].(type) {
+	case float64:
+		available = v
+	case int64:
+		available = float64(v)
+	default:
+		return
+	}
+
+	total, err := totalPhysicalMemory()
+	if err != nil || total == 0 {
+		m.Log.Debugf("Querying total physical memory failed: %v", err)
+		return
+	}
+	availablePercent := available / float64(total) * 100
+	fields["available_percent"] = availablePercent
+	fields["used_percent"] = 100 - availablePercent
+}
@@ -0,0 +1,88 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"sync"
+	"time"
+)
+
+// previousValueTimeout 序列超过该时长未再更新时丢弃其上一次的值，例如已退出的进程。
+const previousValueTimeout = time.Hour
+
+// previousRoute 表示一个带过滤条件的 CollectWithPreviousFunc。
+type previousRoute struct {
+	predicate CollectPredicate
+	collect   CollectWithPreviousFunc
+}
+
+// previousValues 记录每条序列各字段上一次的值。
+type previousValues struct {
+	lock   sync.Mutex
+	series map[string]map[string]PreviousValue
+	seen   map[string]time.Time
+}
+
+// swap 返回序列各字段上一次的值，并以本次的值替换。
+func (p *previousValues) swap(key string, fields map[string]interface{}, timestamp time.Time) map[string]PreviousValue {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.series == nil {
+		p.series = make(map[string]map[string]PreviousValue)
+		p.seen = make(map[string]time.Time)
+	}
+	previous := p.series[key]
+	current := make(map[string]PreviousValue, len(fields))
+	for field, value := range previous {
+		current[field] = value
+	}
+	for field, value := range fields {
+		current[field] = PreviousValue{Value: value, Timestamp: timestamp}
+	}
+	p.series[key] = current
+	p.seen[key] = time.Now()
+	if previous == nil {
+		previous = make(map[string]PreviousValue)
+	}
+	return previous
+}
+
+// prune 丢弃长时间未更新的序列。
+func (p *previousValues) prune(now time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for key, seen := range p.seen {
+		if now.Sub(seen) > previousValueTimeout {
+			delete(p.series, key)
+			delete(p.seen, key)
+		}
+	}
+}
+
+// AddCollectWithPreviousFunc 注册附带上一次字段值的采集回调，predicate 为 nil 时接收全部指标。
+func (m *WinPerfCounters) AddCollectWithPreviousFunc(predicate CollectPredicate, collectFunc CollectWithPreviousFunc) {
+	if collectFunc == nil {
+		return
+	}
+	m.routesLock.Lock()
+	defer m.routesLock.Unlock()
+	m.previousRoutes = append(m.previousRoutes, previousRoute{predicate: predicate, collect: collectFunc})
+}
+
+// emitWithPrevious 将指标及其上一次的字段值分发给匹配的回调，回调在释放 routesLock 之后调用。
+func (m *WinPerfCounters) emitWithPrevious(measurement string, fields map[string]interface{}, tags, output map[string]string, timestamp time.Time) {
+	m.routesLock.RLock()
+	previousRoutes := m.previousRoutes
+	m.routesLock.RUnlock()
+	if len(previousRoutes) == 0 {
+		return
+	}
+	previous := m.previous.swap(snapshotKey(measurement, tags), fields, timestamp)
+	for _, route := range previousRoutes {
+		if route.predicate == nil || route.predicate(measurement, tags) {
+			route.collect(measurement, fields, previous, output, timestamp)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+// processV2Object "Process V2" 对象的实例名称为 "名称:PID" 的形式，无需额外的计数器即可得到 PID。
+const processV2Object = "Process V2"
+
+// processPaths 记录本机进程 PID 到可执行文件完整路径的映射，PID 被其它进程复用时按进程名称重新查询。
+// 每次采集结束时丢弃本次采集没有出现的进程，进程频繁启停的主机上缓存不会无限增长。
+type processPaths struct {
+	lock  sync.Mutex
+	paths map[uint32]*processPath
+}
+
+// processPath 一个进程的名称及其可执行文件路径，查询失败时 path 为空，seen 记录本次采集是否出现过该进程。
+type processPath struct {
+	name string
+	path string
+	seen bool
+}
+
+// get 返回进程的可执行文件路径，缓存中没有该进程时查询并记录。
+func (p *processPaths) get(pid uint32, name string) string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if cached, ok := p.paths[pid]; ok && cached.name == name {
+		cached.seen = true
+		return cached.path
+	}
+	if p.paths == nil {
+		p.paths = make(map[uint32]*processPath)
+	}
+	path, _ := queryProcessPath(pid)
+	p.paths[pid] = &processPath{name: name, path: path, seen: true}
+	return path
+}
+
+// prune 丢弃上一次 prune 之后没有出现过的进程。
+func (p *processPaths) prune() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for pid, cached := range p.paths {
+		if !cached.seen {
+			delete(p.paths, pid)
+			continue
+		}
+		cached.seen = false
+	}
+}
+
+// queryProcessPath 查询本机进程的可执行文件完整路径。
+func queryProcessPath(pid uint32) (string, error) {
+	process, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseHandle(process)
+
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(process, 0, &buf[0], &size); err != nil {
+		return "", err
+	}
+	return windows.UTF16ToString(buf[:size]), nil
+}
+
+// isProcessV2 判断对象是否为 "Process V2" 对象。
+func (o *ObjectConfig) isProcessV2() bool {
+	return strings.EqualFold(o.ObjectName, processV2Object)
+}
+
+// processPIDCounter 返回为 ProcessPID 或 ProcessPath 需要追加的计数器，"Process V2" 对象从实例名称中解析 PID，不需要额外的计数器。
+func (o *ObjectConfig) processPIDCounter() string {
+	if !(o.ProcessPID || o.ProcessPath) || o.isProcessV2() {
+		return ""
+	}
+	return processIDCounter
+}
+
+// validateProcessTags 校验所有对象的 ProcessPID 与 ProcessPath 配置。
+func (m *WinPerfCounters) validateProcessTags(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].validateProcessTags(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateProcessTags 校验对象的 ProcessPID 与 ProcessPath 配置，只有 Process 与 Process V2 对象可以添加进程标签。
+func (o *ObjectConfig) validateProcessTags() error {
+	if !o.ProcessPID && !o.ProcessPath {
+		return nil
+	}
+	if !strings.EqualFold(o.ObjectName, "Process") && !o.isProcessV2() {
+		return fmt.Errorf("process tags of object %q require the Process or Process V2 object", o.ObjectName)
+	}
+	if o.usesWMI() {
+		return fmt.Errorf("process tags of object %q are not supported by the wmi provider", o.ObjectName)
+	}
+	return nil
+}
+
+// instancePID 返回进程实例的 PID，"Process V2" 对象从 "名称:PID" 形式的实例名称中解析，其它对象读取 ID Process 计数器。
+func instancePID(object *ObjectConfig, instance string, fields map[string]interface{}) (pid uint32, name string, ok bool) {
+	if object.isProcessV2() {
+		name, id, found := strings.Cut(instance, ":")
+		if !found {
+			return 0, "", false
+		}
+		value, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return 0, "", false
+		}
+		return uint32(value), name, true
+	}
+	value, ok := toFloat(fields[object.fieldName(processIDCounter)])
+	if !ok {
+		return 0, "", false
+	}
+	name, _, _ = strings.Cut(instance, "#")
+	return uint32(value), name, true
+}
+
+// applyProcessTags 为进程实例添加 pid 标签，启用 ProcessPath 时为本机进程添加可执行文件完整路径的 process_path 标签。
+//
+// "名称#索引" 形式的实例名称在进程重启后会漂移，PID 与路径可以稳定地标识进程实例。
+func (m *WinPerfCounters) applyProcessTags(hostInfo *hostCountersInfo, object *ObjectConfig, instance string, fields map[string]interface{}, tags map[string]string) {
+	if object == nil || (!object.ProcessPID && !object.ProcessPath) {
+		return
+	}
+	pid, name, ok := instancePID(object, instance, fields)
+	if !ok || pid == 0 {
+		return
+	}
+	if object.ProcessPID {
+		tags["pid"] = strconv.FormatUint(uint64(pid), 10)
+	}
+	// 远程主机的进程无法通过 OpenProcess 查询
+	if object.ProcessPath && hostInfo.computer == "localhost" {
+		if path := m.processPaths.get(pid, name); path != "" {
+			tags["process_path"] = path
+		}
+	}
+}
@@ -0,0 +1,56 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstancePID(t *testing.T) {
+	v2 := &ObjectConfig{ObjectName: processV2Object}
+	pid, name, ok := instancePID(v2, "w3wp:4242", nil)
+	require.True(t, ok)
+	require.Equal(t, uint32(4242), pid)
+	require.Equal(t, "w3wp", name)
+	_, _, ok = instancePID(v2, "w3wp", nil)
+	require.False(t, ok)
+	_, _, ok = instancePID(v2, "w3wp:abc", nil)
+	require.False(t, ok)
+
+	process := &ObjectConfig{ObjectName: "Process"}
+	pid, name, ok = instancePID(process, "w3wp#1", map[string]interface{}{process.fieldName(processIDCounter): 17.0})
+	require.True(t, ok)
+	require.Equal(t, uint32(17), pid)
+	require.Equal(t, "w3wp", name)
+	_, _, ok = instancePID(process, "w3wp#1", map[string]interface{}{})
+	require.False(t, ok)
+}
+
+func TestProcessPathsPrune(t *testing.T) {
+	var paths processPaths
+	pid := uint32(os.Getpid())
+	path := paths.get(pid, "self")
+	require.NotEmpty(t, path)
+	paths.get(0, "Idle")
+	paths.prune()
+	require.Len(t, paths.paths, 2)
+
+	// 本次采集只出现了当前进程
+	require.Equal(t, path, paths.get(pid, "self"))
+	paths.prune()
+	require.Len(t, paths.paths, 1)
+	require.Contains(t, paths.paths, pid)
+
+	paths.prune()
+	require.Empty(t, paths.paths)
+}
+
+func TestValidateProcessTags(t *testing.T) {
+	require.NoError(t, (&ObjectConfig{ObjectName: "Processor"}).validateProcessTags())
+	require.NoError(t, (&ObjectConfig{ObjectName: "process", ProcessPID: true}).validateProcessTags())
+	require.NoError(t, (&ObjectConfig{ObjectName: processV2Object, ProcessPath: true}).validateProcessTags())
+	require.ErrorContains(t, (&ObjectConfig{ObjectName: "Processor", ProcessPID: true}).validateProcessTags(), "require the Process or Process V2 object")
+}
@@ -0,0 +1,211 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认的延迟直方图桶上限，单位为毫秒。
+var defaultDiskLatencyBuckets = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+
+const (
+	// diskLatencyFrequencyRetry 获取主机性能计数器频率失败后重试的间隔。
+	diskLatencyFrequencyRetry = time.Minute
+	// systemObjectIndex System 对象在名称表中的固定索引，读取远程主机的性能数据时只请求该对象。
+	systemObjectIndex = "2"
+)
+
+// diskLatencyDirection 描述一个方向（读或写）的原始计数器字段。
+type diskLatencyDirection struct {
+	// name 输出字段前缀。
+	name string
+	// timeField Avg. Disk sec/xxx 原始值对应的字段，即累计耗时的 tick 数。
+	timeField string
+	// countField Disk xxx/sec 原始值对应的字段，即累计操作次数。
+	countField string
+}
+
+var diskLatencyDirections = []diskLatencyDirection{
+	{name: "read", timeField: sanitizedChars.Replace("Avg. Disk sec/Read") + "_Raw", countField: sanitizedChars.Replace("Disk Reads/sec") + "_Raw"},
+	{name: "write", timeField: sanitizedChars.Replace("Avg. Disk sec/Write") + "_Raw", countField: sanitizedChars.Replace("Disk Writes/sec") + "_Raw"},
+}
+
+// diskLatencyState 保存一个实例在某一方向上的上次采样值和累计直方图。
+type diskLatencyState struct {
+	ticks   int64
+	count   int64
+	buckets []uint64
+}
+
+// DiskLatencyProcessor 根据 UseRawValues 采集的 `Avg. Disk sec/Read|Write` 与 `Disk Reads|Writes/sec` 原始值，
+// 计算每个采集周期内的平均延迟和近似的延迟直方图。
+//
+// PDH 格式化后的平均延迟在采集间隔内没有 IO 或计数器回绕时经常失真，直接使用原始值做差可以得到准确的区间平均值。
+// 直方图是近似的：一个周期内的全部操作都计入该周期平均延迟所在的桶，桶计数在多次采集间累计。
+// 耗时的 tick 数按 source 标签所在主机的性能计数器频率换算，远程主机的频率从其性能数据中读取。
+//
+// 输出字段（以 read 为例）：
+//
+//	read_ops                  本周期内的读操作次数
+//	read_latency_ms           本周期内的平均读延迟，无读操作时不输出
+//	read_latency_bucket_le_N  平均延迟不超过 N 毫秒的累计操作次数
+//	read_latency_bucket_le_inf 累计操作总次数
+type DiskLatencyProcessor struct {
+	// Buckets 直方图桶上限，单位为毫秒，需按升序排列。
+	Buckets []float64
+
+	lock   sync.Mutex
+	states map[string]*diskLatencyState
+
+	frequencyLock sync.Mutex
+	// frequencies 按主机缓存的性能计数器频率，本机的键为 localhost。
+	frequencies map[string]hostFrequency
+	// localHost 本机的主机名，与之相同的 source 标签按本机处理。
+	localHost string
+	// queryFrequency 获取主机的性能计数器频率。
+	queryFrequency func(computer string) (int64, error)
+}
+
+// hostFrequency 一个主机的性能计数器频率，获取失败时 value 为 0，在 retryAt 之后重试。
+type hostFrequency struct {
+	value   float64
+	retryAt time.Time
+}
+
+// NewDiskLatencyProcessor 创建磁盘延迟处理器，buckets 为空时使用默认的桶上限。
+func NewDiskLatencyProcessor(buckets ...float64) *DiskLatencyProcessor {
+	if len(buckets) == 0 {
+		buckets = defaultDiskLatencyBuckets
+	}
+	localHost, _ := os.Hostname()
+	return &DiskLatencyProcessor{
+		Buckets:        buckets,
+		states:         make(map[string]*diskLatencyState),
+		frequencies:    make(map[string]hostFrequency),
+		localHost:      localHost,
+		queryFrequency: hostPerfFrequency,
+	}
+}
+
+// hostPerfFrequency 返回主机的性能计数器频率，本机通过 QueryPerformanceFrequency 获取，
+// 远程主机从其 HKEY_PERFORMANCE_DATA 的 PERF_DATA_BLOCK 中读取。
+func hostPerfFrequency(computer string) (int64, error) {
+	if computer == "localhost" {
+		if frequency := queryPerformanceFrequency(); frequency > 0 {
+			return frequency, nil
+		}
+		return 0, errors.New("QueryPerformanceFrequency failed")
+	}
+	data, err := queryRemotePerfData(context.Background(), computer, systemObjectIndex)
+	if err != nil {
+		return 0, err
+	}
+	block, err := parsePerfData(data)
+	if err != nil {
+		return 0, err
+	}
+	if block.perfFreq <= 0 {
+		return 0, errors.New("performance data has no counter frequency")
+	}
+	return block.perfFreq, nil
+}
+
+// frequency 返回 source 标签所在主机的性能计数器频率，source 为空或为本机主机名时为本机，获取失败时返回 0。
+func (p *DiskLatencyProcessor) frequency(source string) float64 {
+	computer := source
+	if source == "" || strings.EqualFold(source, p.localHost) {
+		computer = "localhost"
+	}
+
+	p.frequencyLock.Lock()
+	defer p.frequencyLock.Unlock()
+	cached, ok := p.frequencies[computer]
+	if ok && (cached.value > 0 || time.Now().Before(cached.retryAt)) {
+		return cached.value
+	}
+	frequency, err := p.queryFrequency(computer)
+	if err != nil {
+		p.frequencies[computer] = hostFrequency{retryAt: time.Now().Add(diskLatencyFrequencyRetry)}
+		return 0
+	}
+	p.frequencies[computer] = hostFrequency{value: float64(frequency)}
+	return float64(frequency)
+}
+
+// Enrich 为包含原始磁盘计数器的指标追加延迟字段，可直接作为 EnrichFunc 注册。
+func (p *DiskLatencyProcessor) Enrich(metric *Metric) bool {
+	if !hasDiskLatencyFields(metric.Fields) {
+		return true
+	}
+	frequency := p.frequency(metric.Tags["source"])
+	if frequency <= 0 {
+		return true
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	key := snapshotKey(metric.Measurement, metric.Tags)
+	for _, dir := range diskLatencyDirections {
+		ticks, ok1 := metric.Fields[dir.timeField].(int64)
+		count, ok2 := metric.Fields[dir.countField].(int64)
+		if !ok1 || !ok2 {
+			continue
+		}
+
+		stateKey := key + "|" + dir.name
+		state, ok := p.states[stateKey]
+		if !ok {
+			p.states[stateKey] = &diskLatencyState{ticks: ticks, count: count, buckets: make([]uint64, len(p.Buckets)+1)}
+			continue
+		}
+		deltaTicks, deltaCount := ticks-state.ticks, count-state.count
+		state.ticks, state.count = ticks, count
+		if deltaTicks < 0 || deltaCount < 0 {
+			// 计数器被重置，以本次采样作为新的基准
+			continue
+		}
+
+		metric.Fields[dir.name+"_ops"] = deltaCount
+		if deltaCount > 0 {
+			latency := float64(deltaTicks) / frequency / float64(deltaCount) * 1000
+			metric.Fields[dir.name+"_latency_ms"] = latency
+			state.buckets[p.bucketIndex(latency)] += uint64(deltaCount)
+		}
+
+		var cumulative uint64
+		for i, upper := range p.Buckets {
+			cumulative += state.buckets[i]
+			metric.Fields[dir.name+"_latency_bucket_le_"+strconv.FormatFloat(upper, 'f', -1, 64)] = cumulative
+		}
+		metric.Fields[dir.name+"_latency_bucket_le_inf"] = cumulative + state.buckets[len(p.Buckets)]
+	}
+	return true
+}
+
+// hasDiskLatencyFields 判断指标是否包含任一方向的原始耗时字段，其它指标不需要获取主机的计数器频率。
+func hasDiskLatencyFields(fields map[string]interface{}) bool {
+	for _, dir := range diskLatencyDirections {
+		if _, ok := fields[dir.timeField]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// bucketIndex 返回延迟所在的桶下标，超出所有桶上限时返回最后一个溢出桶。
+func (p *DiskLatencyProcessor) bucketIndex(latency float64) int {
+	for i, upper := range p.Buckets {
+		if latency <= upper {
+			return i
+		}
+	}
+	return len(p.Buckets)
+}
@@ -0,0 +1,95 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDiskLatencyProcessor 创建使用固定主机频率的磁盘延迟处理器，记录每个主机查询频率的次数。
+func newTestDiskLatencyProcessor(frequencies map[string]int64) (*DiskLatencyProcessor, map[string]int) {
+	p := NewDiskLatencyProcessor(1, 10)
+	p.localHost = "LOCAL"
+	queries := make(map[string]int)
+	p.queryFrequency = func(computer string) (int64, error) {
+		queries[computer]++
+		frequency, ok := frequencies[computer]
+		if !ok {
+			return 0, errors.New("host unreachable")
+		}
+		return frequency, nil
+	}
+	return p, queries
+}
+
+func diskMetric(source string, ticks, count int64) *Metric {
+	metric := &Metric{
+		Measurement: "win_diskio",
+		Tags:        map[string]string{"instance": "C:"},
+		Fields: map[string]interface{}{
+			diskLatencyDirections[0].timeField:  ticks,
+			diskLatencyDirections[0].countField: count,
+		},
+	}
+	if source != "" {
+		metric.Tags["source"] = source
+	}
+	return metric
+}
+
+func TestDiskLatencyProcessorFrequencyPerHost(t *testing.T) {
+	p, queries := newTestDiskLatencyProcessor(map[string]int64{"localhost": 10_000_000, "hostA": 1_000_000})
+
+	for _, source := range []string{"", "local", "hostA"} {
+		require.True(t, p.Enrich(diskMetric(source, 0, 0)))
+	}
+	// 10 次操作共 50000 tick：本机为 0.5 毫秒，hostA 的频率低 10 倍，为 5 毫秒
+	local := diskMetric("", 50_000, 10)
+	p.Enrich(local)
+	require.InDelta(t, 0.5, local.Fields["read_latency_ms"], 1e-9)
+	require.Equal(t, int64(10), local.Fields["read_ops"])
+
+	named := diskMetric("local", 100_000, 20)
+	p.Enrich(named)
+	require.InDelta(t, 0.5, named.Fields["read_latency_ms"], 1e-9)
+
+	remote := diskMetric("hostA", 50_000, 10)
+	p.Enrich(remote)
+	require.InDelta(t, 5.0, remote.Fields["read_latency_ms"], 1e-9)
+	require.Equal(t, uint64(0), remote.Fields["read_latency_bucket_le_1"])
+	require.Equal(t, uint64(10), remote.Fields["read_latency_bucket_le_10"])
+
+	// 频率按主机缓存
+	require.Equal(t, map[string]int{"localhost": 1, "hostA": 1}, queries)
+
+	// 其它指标不查询频率
+	require.True(t, p.Enrich(&Metric{Tags: map[string]string{"source": "hostB"}, Fields: map[string]interface{}{"value": 1.0}}))
+	require.NotContains(t, queries, "hostB")
+}
+
+func TestDiskLatencyProcessorUnknownFrequency(t *testing.T) {
+	p, queries := newTestDiskLatencyProcessor(nil)
+	for range 3 {
+		metric := diskMetric("hostB", 50_000, 10)
+		require.True(t, p.Enrich(metric))
+		require.NotContains(t, metric.Fields, "read_ops")
+	}
+	// 失败后在重试间隔内不再查询
+	require.Equal(t, 1, queries["hostB"])
+}
+
+func TestDiskLatencyProcessorReset(t *testing.T) {
+	p, _ := newTestDiskLatencyProcessor(map[string]int64{"localhost": 10_000_000})
+	p.Enrich(diskMetric("", 50_000, 10))
+	// 计数器被重置时以本次采样作为新的基准
+	reset := diskMetric("", 100, 1)
+	p.Enrich(reset)
+	require.NotContains(t, reset.Fields, "read_ops")
+	next := diskMetric("", 10_100, 2)
+	p.Enrich(next)
+	require.Equal(t, int64(1), next.Fields["read_ops"])
+	require.InDelta(t, 1.0, next.Fields["read_latency_ms"], 1e-9)
+}
@@ -0,0 +1,94 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// aggregateByProcessorGroup AggregateBy 按处理器组分别聚合 Processor Information 的实例。
+const aggregateByProcessorGroup = "processor_group"
+
+// validateProcessorGroups 校验所有对象的 ProcessorGroupTags 与 AggregateBy 配置。
+func (m *WinPerfCounters) validateProcessorGroups(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].validateProcessorGroups(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateProcessorGroups 校验对象的 ProcessorGroupTags 与 AggregateBy 配置，二者只用于 Processor Information 对象。
+func (o *ObjectConfig) validateProcessorGroups() error {
+	if !o.ProcessorGroupTags && o.AggregateBy == "" {
+		return nil
+	}
+	if !strings.EqualFold(o.ObjectName, "Processor Information") {
+		return fmt.Errorf("processor groups of object %q require the Processor Information object", o.ObjectName)
+	}
+	switch o.AggregateBy {
+	case "":
+	case aggregateByProcessorGroup:
+		if len(o.Aggregate) == 0 {
+			return fmt.Errorf("AggregateBy of object %q requires Aggregate", o.ObjectName)
+		}
+	default:
+		return fmt.Errorf("invalid AggregateBy %q of object %q, expected %q", o.AggregateBy, o.ObjectName, aggregateByProcessorGroup)
+	}
+	return nil
+}
+
+// parseProcessorInstance 解析 Processor Information 的 "组,编号" 形式的实例名称，组的汇总实例 "组,_Total" 的编号为 "_Total"。
+func parseProcessorInstance(instance string) (group, index string, ok bool) {
+	group, index, ok = strings.Cut(instance, ",")
+	if !ok {
+		return "", "", false
+	}
+	if _, err := strconv.ParseUint(group, 10, 32); err != nil {
+		return "", "", false
+	}
+	if _, err := strconv.ParseUint(index, 10, 32); err != nil && index != "_Total" {
+		return "", "", false
+	}
+	return group, index, true
+}
+
+// aggregateKeyInstance 返回实例聚合后所属分组的实例名称，按处理器组聚合时为处理器组，否则为空。
+// 不参与聚合的汇总实例（_Total，按处理器组聚合时还有各组的 _Total）ok 为 false，避免重复计入各实例的值。
+func (o *ObjectConfig) aggregateKeyInstance(instance string) (string, bool) {
+	if o.AggregateBy != aggregateByProcessorGroup {
+		return "", instance != "_Total"
+	}
+	group, index, ok := parseProcessorInstance(instance)
+	if !ok || index == "_Total" {
+		return "", false
+	}
+	return group, true
+}
+
+// applyProcessorGroupTags 为 Processor Information 的实例添加 processor_group 与 processor_index 标签，
+// 按处理器组聚合的结果以 processor_group 标签代替 instance 标签。
+func applyProcessorGroupTags(object *ObjectConfig, instance string, tags map[string]string) {
+	if object == nil || instance == "" {
+		return
+	}
+	if object.AggregateBy == aggregateByProcessorGroup && !strings.Contains(instance, ",") {
+		if _, err := strconv.ParseUint(instance, 10, 32); err == nil {
+			delete(tags, "instance")
+			tags["processor_group"] = instance
+			return
+		}
+	}
+	if !object.ProcessorGroupTags {
+		return
+	}
+	if group, index, ok := parseProcessorInstance(instance); ok {
+		tags["processor_group"] = group
+		if index != "_Total" {
+			tags["processor_index"] = index
+		}
+	}
+}
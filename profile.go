@@ -0,0 +1,148 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// collectionProfile 表示一个具名的采集档位，例如 baseline、incident、deep-debug。
+type collectionProfile struct {
+	// Name 档位名称。
+	Name string `toml:"Name"`
+	// Interval 该档位建议的采集间隔，通过 GatherInterval 提供给调用方，为 0 时由调用方自行决定。
+	Interval Duration `toml:"Interval"`
+}
+
+// initProfiles 校验档位配置并激活 Profile 指定的初始档位。
+func (m *WinPerfCounters) initProfiles() error {
+	names := make([]string, 0, len(m.Profiles))
+	for _, profile := range m.Profiles {
+		if profile.Name == "" {
+			return errors.New("profile without name")
+		}
+		if slices.Contains(names, profile.Name) {
+			return fmt.Errorf("duplicate profile %q", profile.Name)
+		}
+		names = append(names, profile.Name)
+	}
+	if m.Profile != "" && !slices.Contains(names, m.Profile) {
+		return fmt.Errorf("unknown profile %q", m.Profile)
+	}
+
+	m.profileLock.Lock()
+	defer m.profileLock.Unlock()
+	m.activeProfile = m.Profile
+	return nil
+}
+
+// checkObjectProfiles 校验对象引用的档位均已配置。
+func (m *WinPerfCounters) checkObjectProfiles(objects []ObjectConfig) error {
+	for _, object := range objects {
+		for _, name := range object.Profiles {
+			if !slices.ContainsFunc(m.Profiles, func(profile collectionProfile) bool { return profile.Name == name }) {
+				return fmt.Errorf("object %q references unknown profile %q", object.ObjectName, name)
+			}
+		}
+	}
+	return nil
+}
+
+// SetProfile 切换到名为 name 的采集档位，name 为空时只采集不属于任何档位的对象。
+// 计数器会在下一次 Gather 时按新档位重新解析。正在进行的突发采集会被取消。
+func (m *WinPerfCounters) SetProfile(name string) error {
+	if name != "" && !m.hasProfile(name) {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+
+	m.profileLock.Lock()
+	defer m.profileLock.Unlock()
+	// 手动切换会结束正在进行的突发采集
+	m.burstActive = false
+	m.burstUntil = time.Time{}
+	m.switchProfile(name)
+	return nil
+}
+
+// switchProfile 切换当前档位，调用方需持有 profileLock。
+func (m *WinPerfCounters) switchProfile(name string) {
+	if m.activeProfile != name {
+		m.Log.Infof("Switching collection profile from %q to %q", m.activeProfile, name)
+		m.activeProfile = name
+		m.profileChanged = true
+	}
+}
+
+// ActiveProfile 返回当前生效的采集档位名称。
+func (m *WinPerfCounters) ActiveProfile() string {
+	m.profileLock.Lock()
+	defer m.profileLock.Unlock()
+	return m.activeProfile
+}
+
+// GatherInterval 返回当前档位建议的采集间隔，未配置时返回 0。
+func (m *WinPerfCounters) GatherInterval() time.Duration {
+	active := m.ActiveProfile()
+	for _, profile := range m.Profiles {
+		if profile.Name == active {
+			return time.Duration(profile.Interval)
+		}
+	}
+	return 0
+}
+
+// takeProfileChange 返回档位自上次调用以来是否发生了切换。
+func (m *WinPerfCounters) takeProfileChange() bool {
+	m.profileLock.Lock()
+	defer m.profileLock.Unlock()
+	changed := m.profileChanged
+	m.profileChanged = false
+	return changed
+}
+
+// inProfile 判断对象在档位 profile 下是否需要采集，未配置 Profiles 的对象在所有档位下都会采集。
+func (o *ObjectConfig) inProfile(profile string) bool {
+	return len(o.Profiles) == 0 || slices.Contains(o.Profiles, profile)
+}
+
+// profileStatus 是 ProfileHandler 返回的档位状态。
+type profileStatus struct {
+	Profile  string   `json:"profile"`
+	Profiles []string `json:"profiles"`
+}
+
+// ProfileHandler 返回用于查看和切换采集档位的 HTTP 端点。
+//
+// GET 返回当前档位及全部可用档位；POST 通过查询参数 name 切换档位，例如：
+//
+//	curl -X POST "http://127.0.0.1:8089/admin/profile?name=incident"
+//
+// 端点本身不做认证，暴露在回环地址以外时应使用 RequireToken 包装。
+func (m *WinPerfCounters) ProfileHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			if err := m.SetProfile(r.URL.Query().Get("name")); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		status := profileStatus{Profile: m.ActiveProfile(), Profiles: make([]string, 0, len(m.Profiles))}
+		for _, profile := range m.Profiles {
+			status.Profiles = append(status.Profiles, profile.Name)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(status)
+	})
+}
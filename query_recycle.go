@@ -0,0 +1,26 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"time"
+)
+
+// recycleDue 判断是否需要按 QueryRecycleInterval 完全重建所有查询。首次刷新开始计时，未配置时总是返回 false。
+func (m *WinPerfCounters) recycleDue(now time.Time) bool {
+	if m.QueryRecycleInterval <= 0 || m.lastRecycled.IsZero() || m.hostCounters == nil {
+		return false
+	}
+	return now.Sub(m.lastRecycled) >= time.Duration(m.QueryRecycleInterval)
+}
+
+// noteRefresh 在每次刷新计数器后调用，首次刷新或重建查询后重新开始计时。
+func (m *WinPerfCounters) noteRefresh(now time.Time, recycled bool) {
+	if recycled {
+		m.Log.Infof("Recycled all PDH queries after %v", now.Sub(m.lastRecycled).Round(time.Second))
+		m.stats.incr(map[string]string{}, "query_recycles", 1)
+	}
+	if recycled || m.lastRecycled.IsZero() {
+		m.lastRecycled = now
+	}
+}
@@ -0,0 +1,17 @@
+package win_perf_counters
+
+import "time"
+
+// QuerySource 是不依赖 PDH 的计数器数据源，通过 WithQueryCreator 注入后代替真实的性能计数器查询，
+// 便于在没有 Windows 的环境中测试配置与 CollectFunc 回调。winperftest 子包提供了可编排的实现。
+type QuerySource interface {
+	// Expand 将计数器路径中的通配符展开为具体的计数器路径。
+	Expand(counterPath string) ([]string, error)
+	// Collect 采集一轮数据并返回采样时间。
+	Collect() (time.Time, error)
+	// Value 返回计数器在最近一轮采集中的格式化值。
+	Value(counterPath string) (float64, error)
+}
+
+// QueryCreator 为采集主机 computer 创建 QuerySource，本机为 "localhost"。
+type QueryCreator func(computer string) QuerySource
@@ -0,0 +1,85 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+// QueryStats 是 PerformanceQuery.Stats 返回的查询统计，用于在低层 API 之上排查问题。
+type QueryStats struct {
+	// CountersAdded 成功添加的计数器总数，包括之后被移除的计数器
+	CountersAdded int64 `json:"counters_added"`
+	// Collects 成功调用 CollectData 或 CollectDataWithTime 的次数
+	Collects int64 `json:"collects"`
+	// LastCollectDuration 最近一次成功采集的耗时
+	LastCollectDuration time.Duration `json:"last_collect_duration"`
+	// BufferRetries 缓冲区不足（PDH_MORE_DATA）后加大缓冲区重试的次数
+	BufferRetries int64 `json:"buffer_retries"`
+	// LastErrors 各计数器句柄最近一次读取失败的错误，移除计数器或关闭查询后清除
+	LastErrors map[pdhCounterHandle]error `json:"-"`
+}
+
+// queryStats 记录 QueryStats，可以在多个 goroutine 中同时使用。
+type queryStats struct {
+	lock  sync.Mutex
+	stats QueryStats
+}
+
+func (s *queryStats) added() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stats.CountersAdded++
+}
+
+func (s *queryStats) collected(duration time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stats.Collects++
+	s.stats.LastCollectDuration = duration
+}
+
+func (s *queryStats) retried() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stats.BufferRetries++
+}
+
+// counterError 记录计数器句柄的错误并原样返回 err，err 为 nil 时不做记录。
+func (s *queryStats) counterError(hCounter pdhCounterHandle, err error) error {
+	if err == nil {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.stats.LastErrors == nil {
+		s.stats.LastErrors = make(map[pdhCounterHandle]error)
+	}
+	s.stats.LastErrors[hCounter] = err
+	return err
+}
+
+// removed 清除计数器句柄的错误，句柄之后可能被重新使用。
+func (s *queryStats) removed(hCounter pdhCounterHandle) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.stats.LastErrors, hCounter)
+}
+
+// closed 清除所有句柄的错误，关闭查询后句柄全部失效。
+func (s *queryStats) closed() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stats.LastErrors = nil
+}
+
+// snapshot 返回统计的副本。
+func (s *queryStats) snapshot() QueryStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stats := s.stats
+	stats.LastErrors = maps.Clone(s.stats.LastErrors)
+	return stats
+}
@@ -0,0 +1,76 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"time"
+)
+
+const (
+	// refreshCostWarnPercent 刷新计数器的耗时超过采集间隔的该百分比时给出警告。
+	refreshCostWarnPercent = 50
+	// refreshEveryGatherWarnCount 连续多少次采集都刷新了计数器时给出警告。
+	refreshEveryGatherWarnCount = 3
+)
+
+// refreshCheck 记录刷新计数器的开销，用于发现不合理的刷新配置。
+type refreshCheck struct {
+	// lastGather 上一次采集开始的时间。
+	lastGather time.Time
+	// consecutive 连续刷新了计数器的采集次数。
+	consecutive int
+	// warnedCost 是否已经就刷新耗时给出过警告。
+	warnedCost bool
+	// warnedEveryGather 是否已经就每次采集都刷新给出过警告。
+	warnedEveryGather bool
+}
+
+// warnRefreshInterval 在配置的 CountersRefreshInterval 短于采集间隔时给出警告，此时每次采集都会重新添加全部计数器。
+// 只检查显式配置的采集间隔，外部按其它间隔调用 Gather 的情况在运行时由 checkRefresh 发现。
+func (m *WinPerfCounters) warnRefreshInterval() {
+	if m.CountersRefreshInterval <= 0 {
+		return
+	}
+	interval := m.GatherInterval()
+	if interval <= 0 {
+		interval = time.Duration(m.Interval)
+	}
+	if interval > 0 && time.Duration(m.CountersRefreshInterval) < interval {
+		m.Log.Warnf("CountersRefreshInterval (%v) is shorter than the gather interval (%v), counters are refreshed on every gather; "+
+			"consider a multiple of the gather interval or 0 to disable refreshing", time.Duration(m.CountersRefreshInterval), interval)
+	}
+}
+
+// checkRefresh 在每次采集开始时调用，记录本次刷新计数器的耗时及其占采集间隔的比例，
+// 刷新耗时过长或连续多次采集都刷新了计数器时给出一次警告。
+func (m *WinPerfCounters) checkRefresh(start time.Time, refreshed bool, duration time.Duration) {
+	check := &m.refreshCheck
+	interval := start.Sub(check.lastGather)
+	first := check.lastGather.IsZero()
+	check.lastGather = start
+	if !refreshed {
+		check.consecutive = 0
+		return
+	}
+
+	tags := map[string]string{}
+	m.stats.set(tags, "refresh_duration_ms", duration.Milliseconds())
+	// 首次采集总是需要添加计数器
+	if first || interval <= 0 {
+		return
+	}
+	check.consecutive++
+
+	percent := int64(duration * 100 / interval)
+	m.stats.set(tags, "refresh_cycle_percent", percent)
+	if percent > refreshCostWarnPercent && !check.warnedCost {
+		m.Log.Warnf("Refreshing counters took %v, %d%% of the %v gather interval; consider a longer CountersRefreshInterval or TwoPhaseRefresh",
+			duration, percent, interval.Round(time.Millisecond))
+		check.warnedCost = true
+	}
+	if check.consecutive >= refreshEveryGatherWarnCount && !check.warnedEveryGather {
+		m.Log.Warnf("Counters were refreshed on %d consecutive gathers, CountersRefreshInterval (%v) is not longer than the gather interval (%v)",
+			check.consecutive, time.Duration(m.CountersRefreshInterval), interval.Round(time.Millisecond))
+		check.warnedEveryGather = true
+	}
+}
@@ -0,0 +1,114 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// refreshAll 关闭所有主机的查询，重新展开配置的计数器并完成首次采样，等待一秒使速率类计数器在下一次采集时有值。
+// 个别主机首次采样失败不影响刷新，这些主机的错误合并后作为 hostErrs 返回；err 不为 nil 时刷新失败。
+func (m *WinPerfCounters) refreshAll(ctx context.Context) (hostErrs error, err error) {
+	if err := m.cleanQueries(); err != nil {
+		return nil, err
+	}
+	if err := m.parseConfig(); err != nil {
+		return nil, err
+	}
+	hostErrs = m.collectInitialSamples(m.hostCounters)
+	// minimum time between collecting two samples
+	select {
+	case <-ctx.Done():
+		return hostErrs, ctx.Err()
+	case <-time.After(time.Second):
+	}
+	return hostErrs, nil
+}
+
+// collectInitialSamples 为各主机的查询完成首次采样。一个主机失败不影响其它主机，失败的主机保留其计数器，
+// 在之后的采集中照常采集；各主机的错误（被 IgnoredErrors 忽略的除外）合并后返回。
+func (m *WinPerfCounters) collectInitialSamples(hosts map[string]*hostCountersInfo) error {
+	var errs []error
+	for _, hostCounterSet := range hosts {
+		if hostCounterSet.query == nil {
+			continue
+		}
+		// some counters need two data samples before computing a value
+		if err := hostCounterSet.query.CollectData(); err != nil {
+			m.countPdhError(hostCounterSet, err)
+			errs = append(errs, m.checkError(wrapCounterError("collect", hostCounterSet.computer, "", "", err)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// discardPendingRefresh 关闭两阶段刷新准备好的计数器集合，立即刷新计数器时该集合随之失效。
+func (m *WinPerfCounters) discardPendingRefresh() error {
+	if m.pendingHostCounters == nil {
+		return nil
+	}
+	err := m.closeHosts(m.pendingHostCounters)
+	m.pendingHostCounters = nil
+	return err
+}
+
+// RefreshNow 立即关闭所有查询并重新展开计数器，不等待 CountersRefreshInterval，
+// 适用于已知实例集合发生变化的场景（例如部署新版本后）。
+//
+// 与 Gather 互斥，会等待正在进行的采集结束。尚未进行首次采集，或有超过 CollectTimeout 被放弃等待的采集仍在进行时，
+// 只标记下一次采集时刷新。刷新失败时下一次采集会再次刷新。
+func (m *WinPerfCounters) RefreshNow(ctx context.Context) error {
+	m.gatherLock.Lock()
+	defer m.gatherLock.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if m.lastRefreshed.IsZero() {
+		return nil
+	}
+	m.lastRefreshed = time.Time{}
+	if m.hostsBusy() {
+		m.Log.Infof("Collection still running, counters will be refreshed on the next gather")
+		return nil
+	}
+
+	if err := m.discardPendingRefresh(); err != nil {
+		return err
+	}
+	hostErrs, err := m.refreshAll(ctx)
+	if err != nil {
+		return err
+	}
+	m.lastRefreshed = time.Now()
+	m.stats.incr(map[string]string{}, "refreshes", 1)
+	m.Log.Infof("Counters refreshed on request")
+	return hostErrs
+}
+
+// RefreshHandler 返回用于立即刷新计数器的 HTTP 端点，仅接受 POST，刷新完成后返回 204，例如：
+//
+//	curl -X POST "http://127.0.0.1:8089/admin/refresh"
+//
+// 端点本身不做认证，暴露在回环地址以外时应使用 RequireToken 包装。
+func (m *WinPerfCounters) RefreshHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := m.RefreshNow(r.Context()); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrClosed) {
+				status = http.StatusServiceUnavailable
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,97 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// refreshPacing 记录一次刷新中添加计数器的进度，配置了 RefreshBatchSize 时每添加一批计数器让出一次 CPU。
+// 每次刷新创建一个新的实例，进度可以在不持有 gatherLock 时读取。
+type refreshPacing struct {
+	// queued 本次刷新需要添加的计数器数量，随通配符的展开逐步增加，只计展开后的计数器。
+	queued atomic.Int64
+	// added 本次刷新已经添加的计数器数量。
+	added atomic.Int64
+	// batches 本次刷新中让出 CPU 的次数。
+	batches atomic.Int64
+}
+
+// validateRefreshBatches 校验 RefreshBatchSize 与 RefreshBatchDelay。
+func (m *WinPerfCounters) validateRefreshBatches() error {
+	if m.RefreshBatchSize < 0 {
+		return errors.New("RefreshBatchSize must not be negative")
+	}
+	if m.RefreshBatchDelay < 0 {
+		return errors.New("RefreshBatchDelay must not be negative")
+	}
+	return nil
+}
+
+// beginRefreshPacing 在解析配置、添加计数器之前调用，开始记录本次刷新的进度。
+func (m *WinPerfCounters) beginRefreshPacing() {
+	m.refreshPacing.Store(&refreshPacing{})
+	tags := map[string]string{}
+	m.stats.set(tags, "refresh_in_progress", int64(1))
+	m.stats.set(tags, "refresh_counters_queued", int64(0))
+	m.stats.set(tags, "refresh_counters_done", int64(0))
+}
+
+// endRefreshPacing 在添加完计数器后调用，记录本次刷新让出 CPU 的次数。之后在刷新以外添加的计数器
+// （DeferRemoteOpen、RetryMissingCounters）不计入进度，也不分批等待。
+func (m *WinPerfCounters) endRefreshPacing() {
+	pacing := m.refreshPacing.Swap(nil)
+	tags := map[string]string{}
+	m.stats.set(tags, "refresh_in_progress", int64(0))
+	if pacing != nil {
+		m.stats.set(tags, "refresh_batches", pacing.batches.Load())
+	}
+}
+
+// queueCounterAdds 记录即将添加的 n 个计数器。
+func (m *WinPerfCounters) queueCounterAdds(n int) {
+	pacing := m.refreshPacing.Load()
+	if pacing == nil {
+		return
+	}
+	m.stats.set(map[string]string{}, "refresh_counters_queued", pacing.queued.Add(int64(n)))
+}
+
+// paceCounterAdd 在添加每个计数器之前调用，记录进度。配置了 RefreshBatchSize 时每添加一批计数器后让出 CPU，
+// 使数千个计数器的添加不会占满 CPU 而影响同时运行的其它 goroutine 与程序。
+//
+// RefreshBatchDelay 只在 BackgroundInitialRefresh 的后台刷新中生效：等待期间释放 gatherLock，
+// 此时采集立即返回 ErrNotReady，不会被等待拖慢。在采集中进行的刷新持有 gatherLock，只让出 CPU 而不等待，
+// 以免推迟本次采集。等待后插件已被关闭时返回 ErrClosed，刷新随之中止。
+func (m *WinPerfCounters) paceCounterAdd() error {
+	pacing := m.refreshPacing.Load()
+	if pacing == nil {
+		return nil
+	}
+	if added := pacing.added.Load(); m.RefreshBatchSize > 0 && added > 0 && added%int64(m.RefreshBatchSize) == 0 {
+		pacing.batches.Add(1)
+		runtime.Gosched()
+		if m.RefreshBatchDelay > 0 && m.initialRefresh.running.Load() {
+			m.gatherLock.Unlock()
+			time.Sleep(time.Duration(m.RefreshBatchDelay))
+			m.gatherLock.Lock()
+			if m.closed {
+				return ErrClosed
+			}
+		}
+	}
+	m.stats.set(map[string]string{}, "refresh_counters_done", pacing.added.Add(1))
+	return nil
+}
+
+// refreshProgress 返回正在进行的刷新已经添加和需要添加的计数器数量，没有正在进行的刷新时均为 0，可以在不持有 gatherLock 时调用。
+func (m *WinPerfCounters) refreshProgress() (done, queued int64) {
+	pacing := m.refreshPacing.Load()
+	if pacing == nil {
+		return 0, 0
+	}
+	return pacing.added.Load(), pacing.queued.Load()
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshPacingCountsExpandedCounters(t *testing.T) {
+	source := &fakeSource{value: 1}
+	m := newFakeSourcePlugin(source)
+	m.UseWildcardsExpansion = true
+	m.Object[0].Counters = []string{"% Processor Time", "% User Time", "% Idle Time"}
+	m.RefreshBatchSize = 2
+	require.NoError(t, m.Init())
+	t.Cleanup(func() { _ = m.Close() })
+
+	require.NoError(t, m.parseConfig())
+
+	// 展开用的模板不计入进度，只计展开后的 3 个计数器
+	tags := map[string]string{}
+	require.Equal(t, int64(3), m.stats.value(tags, "refresh_counters_queued"))
+	require.Equal(t, int64(3), m.stats.value(tags, "refresh_counters_done"))
+	require.Equal(t, int64(1), m.stats.value(tags, "refresh_batches"))
+	require.Equal(t, int64(0), m.stats.value(tags, "refresh_in_progress"))
+
+	// 刷新结束后添加的计数器不计入进度
+	done, queued := m.refreshProgress()
+	require.Zero(t, done)
+	require.Zero(t, queued)
+}
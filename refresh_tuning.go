@@ -0,0 +1,177 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// refreshChurnHighPercent 一次刷新中增加与消失的计数器超过该百分比时建议缩短刷新间隔。
+	refreshChurnHighPercent = 10
+	// refreshStableCount 连续多少次刷新计数器集合都没有变化时建议延长刷新间隔。
+	refreshStableCount = 3
+	// defaultAutoTuneRefreshMin 未配置 AutoTuneRefreshMin 时自动调整的刷新间隔下限。
+	defaultAutoTuneRefreshMin = 30 * time.Second
+	// defaultAutoTuneRefreshMax 未配置 AutoTuneRefreshMax 时自动调整的刷新间隔上限。
+	defaultAutoTuneRefreshMax = time.Hour
+)
+
+// refreshTuning 记录相邻两次刷新之间展开的计数器集合的变化，据此给出建议的刷新间隔。
+type refreshTuning struct {
+	// paths 上一次刷新后的计数器集合，键为主机与计数器路径。
+	paths map[string]struct{}
+	// stable 连续没有变化的刷新次数。
+	stable int
+	// interval 当前使用的刷新间隔，启用 AutoTuneRefresh 后随建议调整，为 0 时使用 CountersRefreshInterval。
+	interval time.Duration
+
+	// lock 保护 report，RefreshDiagnostics 不持有 gatherLock 读取。
+	lock sync.Mutex
+	// report 最近一次比较计数器集合的结果。
+	report RefreshReport
+}
+
+// RefreshDiagnostics 返回最近一次定期刷新时计数器集合的变化与建议的 CountersRefreshInterval，
+// 不等待正在进行的采集。尚未比较过计数器集合时 Time 为零值。
+func (m *WinPerfCounters) RefreshDiagnostics() RefreshReport {
+	m.refreshTuning.lock.Lock()
+	defer m.refreshTuning.lock.Unlock()
+	return m.refreshTuning.report
+}
+
+// validateAutoTuneRefresh 校验自动调整刷新间隔的上下限。
+func (m *WinPerfCounters) validateAutoTuneRefresh() error {
+	if m.AutoTuneRefreshMin < 0 || m.AutoTuneRefreshMax < 0 {
+		return errors.New("AutoTuneRefreshMin and AutoTuneRefreshMax must not be negative")
+	}
+	minimum, maximum := m.autoTuneRefreshBounds()
+	if minimum > maximum {
+		return fmt.Errorf("AutoTuneRefreshMin (%v) is longer than AutoTuneRefreshMax (%v)", minimum, maximum)
+	}
+	return nil
+}
+
+// autoTuneRefreshBounds 返回自动调整的刷新间隔上下限，未配置时使用默认值。
+func (m *WinPerfCounters) autoTuneRefreshBounds() (time.Duration, time.Duration) {
+	minimum, maximum := time.Duration(m.AutoTuneRefreshMin), time.Duration(m.AutoTuneRefreshMax)
+	if minimum == 0 {
+		minimum = defaultAutoTuneRefreshMin
+	}
+	if maximum == 0 {
+		maximum = defaultAutoTuneRefreshMax
+	}
+	return minimum, maximum
+}
+
+// refreshInterval 返回当前使用的刷新间隔，启用 AutoTuneRefresh 时为自动调整后的间隔。
+// CountersRefreshInterval 为 0 时不定期刷新，也不做调整。
+func (m *WinPerfCounters) refreshInterval() time.Duration {
+	if m.AutoTuneRefresh && m.CountersRefreshInterval > 0 && m.refreshTuning.interval > 0 {
+		return m.refreshTuning.interval
+	}
+	return time.Duration(m.CountersRefreshInterval)
+}
+
+// expandedPaths 返回刷新后的计数器集合，两阶段刷新时为准备好的新集合。
+func (m *WinPerfCounters) expandedPaths() map[string]struct{} {
+	hosts := m.hostCounters
+	if m.pendingHostCounters != nil {
+		hosts = m.pendingHostCounters
+	}
+	paths := make(map[string]struct{})
+	for _, hostInfo := range hosts {
+		for _, c := range hostInfo.counters {
+			paths[hostInfo.computer+"\x00"+c.counterPath] = struct{}{}
+		}
+	}
+	return paths
+}
+
+// tuneRefresh 在每次刷新后比较展开的计数器集合与上一次刷新的差异，记录变化比例和建议的刷新间隔：
+// 连续多次没有变化时建议加倍，变化超过 10% 时建议减半，结果限制在上下限之间。
+// 启用 AutoTuneRefresh 时直接使用建议的间隔。baseline 为 true 时（首次刷新、热更新或切换档位后）
+// 计数器集合的变化来自配置而不是实例，只记录集合。
+func (m *WinPerfCounters) tuneRefresh(baseline bool) {
+	tuning := &m.refreshTuning
+	paths := m.expandedPaths()
+	previous := tuning.paths
+	tuning.paths = paths
+	if baseline || previous == nil || m.CountersRefreshInterval <= 0 {
+		tuning.stable = 0
+		return
+	}
+
+	added, removed, churn := refreshChurn(previous, paths)
+	current := m.refreshInterval()
+	minimum, maximum := m.autoTuneRefreshBounds()
+	var suggested time.Duration
+	suggested, tuning.stable = suggestRefreshInterval(current, minimum, maximum, churn, added+removed, tuning.stable)
+
+	tuning.lock.Lock()
+	tuning.report = RefreshReport{
+		Time:               time.Now(),
+		Counters:           len(paths),
+		Added:              added,
+		Removed:            removed,
+		ChurnPercent:       churn,
+		StableRefreshes:    tuning.stable,
+		ConfiguredInterval: m.CountersRefreshInterval,
+		CurrentInterval:    Duration(current),
+		SuggestedInterval:  Duration(suggested),
+		AutoTune:           m.AutoTuneRefresh,
+	}
+	tuning.lock.Unlock()
+
+	tags := map[string]string{}
+	m.stats.set(tags, "refresh_counters_added", int64(added))
+	m.stats.set(tags, "refresh_counters_removed", int64(removed))
+	m.stats.set(tags, "refresh_churn_percent", churn)
+	m.stats.set(tags, "suggested_refresh_interval_ms", suggested.Milliseconds())
+	if m.AutoTuneRefresh && suggested != current {
+		m.Log.Infof("Counter set changed by %d%% (%d added, %d removed), refresh interval adjusted from %v to %v",
+			churn, added, removed, current, suggested)
+		tuning.interval = suggested
+	}
+}
+
+// refreshChurn 返回 paths 相对 previous 新增与消失的计数器数量，以及两者之和占较大集合的百分比。
+func refreshChurn(previous, paths map[string]struct{}) (added, removed int, churn int64) {
+	for path := range paths {
+		if _, ok := previous[path]; !ok {
+			added++
+		}
+	}
+	for path := range previous {
+		if _, ok := paths[path]; !ok {
+			removed++
+		}
+	}
+	if total := max(len(paths), len(previous)); total > 0 {
+		churn = int64((added + removed) * 100 / total)
+	}
+	return added, removed, churn
+}
+
+// suggestRefreshInterval 根据一次刷新的变化返回建议的刷新间隔和新的连续稳定次数：变化比例超过 refreshChurnHighPercent
+// 时减半，连续 refreshStableCount 次没有变化时加倍，结果限制在 minimum 与 maximum 之间。
+func suggestRefreshInterval(current, minimum, maximum time.Duration, churn int64, changed, stable int) (time.Duration, int) {
+	suggested := current
+	switch {
+	case churn > refreshChurnHighPercent:
+		stable = 0
+		suggested = current / 2
+	case changed == 0:
+		stable++
+		if stable >= refreshStableCount {
+			stable = 0
+			suggested = current * 2
+		}
+	default:
+		stable = 0
+	}
+	return min(max(suggested, minimum), maximum), stable
+}
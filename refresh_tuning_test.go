@@ -0,0 +1,132 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoTuneRefreshBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max Duration
+		wantMin  time.Duration
+		wantMax  time.Duration
+		wantErr  string
+	}{
+		{name: "defaults", wantMin: defaultAutoTuneRefreshMin, wantMax: defaultAutoTuneRefreshMax},
+		{name: "configured", min: Duration(time.Minute), max: Duration(30 * time.Minute), wantMin: time.Minute, wantMax: 30 * time.Minute},
+		{name: "only min", min: Duration(2 * time.Hour), wantMin: 2 * time.Hour, wantMax: defaultAutoTuneRefreshMax,
+			wantErr: "is longer than AutoTuneRefreshMax"},
+		{name: "only max", max: Duration(10 * time.Second), wantMin: defaultAutoTuneRefreshMin, wantMax: 10 * time.Second,
+			wantErr: "is longer than AutoTuneRefreshMax"},
+		{name: "negative", min: Duration(-time.Second), wantMin: -time.Second, wantMax: defaultAutoTuneRefreshMax,
+			wantErr: "must not be negative"},
+	}
+	for _, tt := range tests {
+		m := &WinPerfCounters{AutoTuneRefreshMin: tt.min, AutoTuneRefreshMax: tt.max}
+		minimum, maximum := m.autoTuneRefreshBounds()
+		require.Equal(t, tt.wantMin, minimum, tt.name)
+		require.Equal(t, tt.wantMax, maximum, tt.name)
+		if tt.wantErr == "" {
+			require.NoError(t, m.validateAutoTuneRefresh(), tt.name)
+		} else {
+			require.ErrorContains(t, m.validateAutoTuneRefresh(), tt.wantErr, tt.name)
+		}
+	}
+}
+
+func TestSuggestRefreshInterval(t *testing.T) {
+	tests := []struct {
+		name          string
+		current       time.Duration
+		churn         int64
+		changed       int
+		stable        int
+		wantSuggested time.Duration
+		wantStable    int
+	}{
+		{"first stable refresh", 10 * time.Minute, 0, 0, 0, 10 * time.Minute, 1},
+		{"third stable refresh doubles", 10 * time.Minute, 0, 0, 2, 20 * time.Minute, 0},
+		{"doubling is capped", 40 * time.Minute, 0, 0, 2, time.Hour, 0},
+		{"small churn resets stability", 10 * time.Minute, 5, 3, 2, 10 * time.Minute, 0},
+		{"churn at threshold keeps interval", 10 * time.Minute, refreshChurnHighPercent, 10, 0, 10 * time.Minute, 0},
+		{"high churn halves", 10 * time.Minute, 50, 60, 1, 5 * time.Minute, 0},
+		{"halving is floored", 40 * time.Second, 50, 60, 0, 30 * time.Second, 0},
+	}
+	for _, tt := range tests {
+		suggested, stable := suggestRefreshInterval(tt.current, 30*time.Second, time.Hour, tt.churn, tt.changed, tt.stable)
+		require.Equal(t, tt.wantSuggested, suggested, tt.name)
+		require.Equal(t, tt.wantStable, stable, tt.name)
+	}
+}
+
+func TestRefreshChurn(t *testing.T) {
+	set := func(paths ...string) map[string]struct{} {
+		result := make(map[string]struct{}, len(paths))
+		for _, path := range paths {
+			result[path] = struct{}{}
+		}
+		return result
+	}
+	tests := []struct {
+		name                string
+		previous, paths     map[string]struct{}
+		wantAdded, wantGone int
+		wantChurn           int64
+	}{
+		{"unchanged", set("a", "b"), set("a", "b"), 0, 0, 0},
+		{"added", set("a", "b", "c", "d"), set("a", "b", "c", "d", "e"), 1, 0, 20},
+		{"replaced", set("a", "b"), set("a", "c"), 1, 1, 100},
+		{"emptied", set("a"), set(), 0, 1, 100},
+		{"both empty", set(), set(), 0, 0, 0},
+	}
+	for _, tt := range tests {
+		added, removed, churn := refreshChurn(tt.previous, tt.paths)
+		require.Equal(t, tt.wantAdded, added, tt.name)
+		require.Equal(t, tt.wantGone, removed, tt.name)
+		require.Equal(t, tt.wantChurn, churn, tt.name)
+	}
+}
+
+func TestTuneRefresh(t *testing.T) {
+	m := NewWinPerfCounters(func(string, map[string]interface{}, map[string]string, time.Time) {})
+	m.CountersRefreshInterval = Duration(10 * time.Minute)
+	m.AutoTuneRefresh = true
+	setCounters := func(n int) {
+		hostInfo := &hostCountersInfo{computer: "localhost"}
+		for i := range n {
+			hostInfo.counters = append(hostInfo.counters, &counter{counterPath: fmt.Sprintf(`\Process(p%d)\ID Process`, i)})
+		}
+		m.hostCounters = map[string]*hostCountersInfo{"localhost": hostInfo}
+	}
+
+	setCounters(10)
+	m.tuneRefresh(true)
+	require.True(t, m.RefreshDiagnostics().Time.IsZero(), "the baseline refresh is not compared")
+
+	// 连续三次没有变化后加倍
+	for range refreshStableCount {
+		m.tuneRefresh(false)
+	}
+	report := m.RefreshDiagnostics()
+	require.Equal(t, 10, report.Counters)
+	require.Equal(t, Duration(10*time.Minute), report.CurrentInterval)
+	require.Equal(t, Duration(20*time.Minute), report.SuggestedInterval)
+	require.True(t, report.AutoTune)
+	require.Equal(t, 20*time.Minute, m.refreshInterval())
+
+	// 变化超过 10% 时减半
+	setCounters(20)
+	m.tuneRefresh(false)
+	report = m.RefreshDiagnostics()
+	require.Equal(t, 10, report.Added)
+	require.Equal(t, int64(50), report.ChurnPercent)
+	require.Equal(t, Duration(10*time.Minute), report.SuggestedInterval)
+	require.Equal(t, 10*time.Minute, m.refreshInterval())
+	require.Equal(t, int64(10*time.Minute/time.Millisecond), m.stats.value(map[string]string{}, "suggested_refresh_interval_ms"))
+}
@@ -0,0 +1,422 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// providerRegistry 直接读取 HKEY_PERFORMANCE_DATA 的数据提供程序。
+const providerRegistry = "registry"
+
+const (
+	// registryStalePrefix 区分同一主机上注册表与 PDH 采集的序列。
+	registryStalePrefix = "registry:"
+	// perfDataInitialSize 读取 HKEY_PERFORMANCE_DATA 的初始缓冲区大小，不足时加倍。
+	perfDataInitialSize = 64 * 1024
+	// perfDataMaxSize 读取 HKEY_PERFORMANCE_DATA 的最大缓冲区大小。
+	perfDataMaxSize = 64 * 1024 * 1024
+	// perfNamesReload 名称表的缓存时长，计数器注册表重建后对象和计数器的索引会变化。
+	perfNamesReload = 10 * time.Minute
+	// remotePerfDataTimeout 读取远程主机 HKEY_PERFORMANCE_DATA 的最长时间，主机不可达时远程注册表调用可能阻塞数分钟。
+	remotePerfDataTimeout = 30 * time.Second
+)
+
+var errRemotePerfDataTimeout = errors.New("reading remote performance data timed out")
+
+// usesRegistry 判断对象是否直接读取 HKEY_PERFORMANCE_DATA 采集。
+func (o *ObjectConfig) usesRegistry() bool {
+	return strings.EqualFold(o.Provider, providerRegistry)
+}
+
+// usesPDH 判断对象是否通过 PDH 查询采集。
+func (o *ObjectConfig) usesPDH() bool {
+	return !o.usesWMI() && !o.usesRegistry()
+}
+
+// perfNameTable 一个主机的英文名称表。
+type perfNameTable struct {
+	names  map[uint32]string
+	loaded time.Time
+}
+
+// registryNames 按主机缓存英文名称表，用于将对象和计数器名称对应到性能数据中的索引。
+type registryNames struct {
+	lock  sync.Mutex
+	hosts map[string]perfNameTable
+}
+
+// table 返回主机的英文名称表，缓存超过 perfNamesReload 时重新读取，远程主机读取其自身的名称表。
+func (n *registryNames) table(computer string) (map[uint32]string, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if table, ok := n.hosts[computer]; ok && time.Since(table.loaded) < perfNamesReload {
+		return table.names, nil
+	}
+	root := registry.LOCAL_MACHINE
+	if computer != "localhost" {
+		remote, err := registry.OpenRemoteKey(`\\`+strings.TrimPrefix(computer, `\\`), registry.LOCAL_MACHINE)
+		if err != nil {
+			return nil, err
+		}
+		defer remote.Close()
+		root = remote
+	}
+	names, err := loadEnglishNamesFrom(root)
+	if err != nil {
+		return nil, err
+	}
+	if n.hosts == nil {
+		n.hosts = make(map[string]perfNameTable)
+	}
+	n.hosts[computer] = perfNameTable{names: names, loaded: time.Now()}
+	return names, nil
+}
+
+// queryRemotePerfData 与 queryPerfData 相同，远程主机最多等待 remotePerfDataTimeout，ctx 结束时也不再等待。
+// 超时后读取在后台继续，其结果被丢弃。
+func queryRemotePerfData(ctx context.Context, computer, indexes string) ([]byte, error) {
+	if computer == "localhost" {
+		return queryPerfData(computer, indexes)
+	}
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := queryPerfData(computer, indexes)
+		done <- result{data, err}
+	}()
+	timer := time.NewTimer(remotePerfDataTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.data, r.err
+	case <-timer.C:
+		return nil, fmt.Errorf("%w after %s", errRemotePerfDataTimeout, remotePerfDataTimeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// queryPerfData 读取主机上 indexes（以空格分隔的对象索引）对应对象的性能数据，一次调用得到所有对象的快照。
+func queryPerfData(computer, indexes string) ([]byte, error) {
+	key := registry.PERFORMANCE_DATA
+	if computer != "localhost" {
+		remote, err := registry.OpenRemoteKey(`\\`+strings.TrimPrefix(computer, `\\`), registry.PERFORMANCE_DATA)
+		if err != nil {
+			return nil, err
+		}
+		key = remote
+	}
+	defer key.Close()
+
+	for size := perfDataInitialSize; size <= perfDataMaxSize; size *= 2 {
+		buf := make([]byte, size)
+		n, _, err := key.GetValue(indexes, buf)
+		if err == nil {
+			return buf[:n], nil
+		}
+		// HKEY_PERFORMANCE_DATA 不返回所需的大小，只能加倍重试
+		if !errors.Is(err, syscall.ERROR_MORE_DATA) {
+			return nil, err
+		}
+	}
+	// HKEY_PERFORMANCE_DATA 的上限是固定的，没有可以调整的 MaxBufferSize
+	return nil, &ErrBufferLimit{Limit: perfDataMaxSize}
+}
+
+// registrySample 计数器上一次的原始值及采样时间，用于计算速率类计数器的格式化值。
+type registrySample struct {
+	value  uint64
+	base   uint64
+	time   int64
+	freq   int64
+	seenAt time.Time
+}
+
+// registrySamples 记录各计数器上一次的原始值。
+type registrySamples struct {
+	lock    sync.Mutex
+	samples map[string]registrySample
+}
+
+// swap 记录本次的原始值并返回上一次的原始值。
+func (s *registrySamples) swap(key string, sample registrySample) (registrySample, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.samples == nil {
+		s.samples = make(map[string]registrySample)
+	}
+	previous, ok := s.samples[key]
+	s.samples[key] = sample
+	return previous, ok
+}
+
+// reset 丢弃所有记录的值。
+func (s *registrySamples) reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.samples = nil
+}
+
+// prune 丢弃长时间未更新的值，例如已退出的进程。
+func (s *registrySamples) prune(now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for key, sample := range s.samples {
+		if now.Sub(sample.seenAt) > previousValueTimeout {
+			delete(s.samples, key)
+		}
+	}
+}
+
+// sampleTime 返回计数器类型使用的时间戳与频率：100 纳秒计时器使用数据块的 100 纳秒时间，
+// PERF_OBJECT_TIMER 使用对象自身的时间戳，其它使用数据块的性能计数器时间。
+func sampleTime(counterType uint32, block *perfDataBlock, object *perfObject) (int64, int64) {
+	switch {
+	case counterType&perfTimer100ns != 0:
+		return block.perfTime100ns, 10000000
+	case counterType&perfObjectTimer != 0:
+		return object.perfTime, object.perfFreq
+	}
+	return block.perfTime, block.perfFreq
+}
+
+// formatPerfValue 按与 PDH 相同的公式由本次与上一次的原始值计算格式化值，需要上一次的值而没有时 ok 为 false。
+// 不支持的计数器类型返回 false。
+func formatPerfValue(counterType uint32, current, previous registrySample, hasPrevious bool) (float64, bool) {
+	n1, b1 := float64(current.value), float64(current.base)
+	dn := float64(current.value) - float64(previous.value)
+	dt := float64(current.time - previous.time)
+	db := float64(current.base) - float64(previous.base)
+
+	switch counterType {
+	case perfRawFraction, perfLargeRawFraction:
+		if b1 == 0 {
+			return 0, false
+		}
+		return 100 * n1 / b1, true
+	case perfElapsedTime:
+		if current.freq <= 0 {
+			return 0, false
+		}
+		return (float64(current.time) - n1) / float64(current.freq), true
+	}
+	if counterType&perfTypeMask == perfTypeNumber {
+		if counterType&perfNumberMask == perfNumberDec1000 {
+			return n1 / 1000, true
+		}
+		return n1, true
+	}
+
+	if !hasPrevious || current.value < previous.value {
+		return 0, false
+	}
+	switch counterType {
+	case perfCounterDelta, perfCounterLargeDelta:
+		return dn, true
+	case perfSampleFraction:
+		if db <= 0 {
+			return 0, false
+		}
+		return 100 * dn / db, true
+	case perfAverageBulk:
+		if db <= 0 {
+			return 0, false
+		}
+		return dn / db, true
+	case perfAverageTimer:
+		if db <= 0 || current.freq <= 0 {
+			return 0, false
+		}
+		return dn / float64(current.freq) / db, true
+	}
+
+	if dt <= 0 {
+		return 0, false
+	}
+	switch counterType {
+	case perfCounterCounter, perfCounterBulkCount:
+		if current.freq <= 0 {
+			return 0, false
+		}
+		return dn / (dt / float64(current.freq)), true
+	case perfCounterTimer, perf100nsecTimer:
+		return 100 * dn / dt, true
+	case perfCounterTimerInv, perf100nsecTimerInv:
+		return 100 * (1 - dn/dt), true
+	case perfCounterQueuelen, perfCounterLargeQueuelen, perfCounter100nsQueuelen, perfCounterObjTimeQueuelen:
+		return dn / dt, true
+	}
+	return 0, false
+}
+
+// registryObjects 按主机分组返回本次需要直接读取 HKEY_PERFORMANCE_DATA 采集的对象，模拟模式下所有对象都按 PDH 处理。
+func (m *WinPerfCounters) registryObjects(due dueObjects) map[string][]*ObjectConfig {
+	if m.Simulate {
+		return nil
+	}
+	return m.providerObjects(due, (*ObjectConfig).usesRegistry)
+}
+
+// gatherRegistryHost 通过一次 HKEY_PERFORMANCE_DATA 查询读取一个主机上所有对象的快照，不经过 PDH，
+// 输出与 PDH 采集相同的测量、字段和标签。找不到的对象或计数器不影响同一主机上的其它对象，其错误合并后返回。
+func (m *WinPerfCounters) gatherRegistryHost(ctx context.Context, computer string, objects []*ObjectConfig) error {
+	hostInfo := &hostCountersInfo{computer: computer, tag: m.sourceTag(computer), timestamp: time.Now()}
+	if err := m.connectSource(computer); err != nil {
+		return wrapCounterError("collect", computer, "", "", err)
+	}
+	names, err := m.registryNames.table(computer)
+	if err != nil {
+		return wrapCounterError("collect", computer, "", englishPerfNamesKey, err)
+	}
+
+	var failed []error
+	indexes := make(map[*ObjectConfig][]uint32, len(objects))
+	var query []string
+	for _, object := range objects {
+		for index, name := range names {
+			if strings.EqualFold(name, object.ObjectName) {
+				indexes[object] = append(indexes[object], index)
+				query = append(query, strconv.FormatUint(uint64(index), 10))
+			}
+		}
+		if len(indexes[object]) == 0 {
+			m.stats.incr(map[string]string{"source": hostInfo.tag, "objectname": object.ObjectName}, "read_errors", 1)
+			failed = append(failed, wrapCounterError("read", computer, object.ObjectName, "", errors.New("object not found in the counter name table")))
+		}
+	}
+	if len(query) == 0 {
+		return errors.Join(failed...)
+	}
+
+	data, err := queryRemotePerfData(ctx, computer, strings.Join(query, " "))
+	if err != nil {
+		return wrapCounterError("collect", computer, "", "", err)
+	}
+	block, err := parsePerfData(data)
+	if err != nil {
+		return wrapCounterError("collect", computer, "", "", err)
+	}
+	// 已放弃等待的采集不再输出数据
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	collectedFields := make(fieldGrouping)
+	groupObjects := make(map[instanceGrouping]*ObjectConfig)
+	gathered := make(dueObjects)
+	for _, object := range objects {
+		i := -1
+		for j := range block.objects {
+			for _, index := range indexes[object] {
+				if block.objects[j].index == index {
+					i = j
+				}
+			}
+		}
+		if i < 0 {
+			if len(indexes[object]) > 0 {
+				m.stats.incr(map[string]string{"source": hostInfo.tag, "objectname": object.ObjectName}, "read_errors", 1)
+				failed = append(failed, wrapCounterError("read", computer, object.ObjectName, "", errors.New("object returned no performance data")))
+			}
+			continue
+		}
+		gathered.add(object)
+		if err := m.addRegistryObject(hostInfo, object, block, &block.objects[i], names, collectedFields, groupObjects); err != nil {
+			failed = append(failed, err)
+		}
+	}
+
+	seen := make(seenSeries)
+	m.emitGroups(hostInfo, collectedFields, groupObjects, nil, seen)
+	m.emitStaleMarkers(registryStalePrefix+computer, gathered, seen, hostInfo.timestamp)
+	m.reportMissingInstances(registryStalePrefix+computer, gathered, seen, hostInfo.timestamp)
+	m.reportQuality(hostInfo, registryStalePrefix+computer, gathered, seen, hostInfo.timestamp)
+	m.registrySamples.prune(time.Now())
+	return errors.Join(failed...)
+}
+
+// addRegistryObject 将一个对象的性能数据按实例添加到收集字段中，字段名称与 PDH 采集时相同。
+// 速率类计数器需要两次采样，第一次采集时只输出即时值类计数器。
+//
+//nolint:revive //argument-limit conditionally more arguments allowed
+func (m *WinPerfCounters) addRegistryObject(hostInfo *hostCountersInfo, o *ObjectConfig, block *perfDataBlock, object *perfObject,
+	names map[uint32]string, collectFields fieldGrouping, groupObjects map[instanceGrouping]*ObjectConfig) error {
+	definitions := make(map[string]perfCounterDef)
+	var missing []string
+	for _, counterName := range o.counterNames() {
+		for _, definition := range object.counters {
+			if strings.EqualFold(names[definition.index], counterName) {
+				definitions[counterName] = definition
+				break
+			}
+		}
+		if _, ok := definitions[counterName]; !ok {
+			missing = append(missing, counterName)
+		}
+	}
+
+	measurement := o.providerMeasurement()
+	for _, instance := range object.instances {
+		if instance.name != "" && !o.acceptWMIInstance(instance.name) {
+			continue
+		}
+		fields := make(map[string]interface{})
+		for counterName, definition := range definitions {
+			value, ok := definition.value(instance)
+			if !ok {
+				continue
+			}
+			fieldName := o.fieldName(counterName)
+			if o.UseRawValues {
+				fields[fieldName] = int64(value) //nolint:gosec // G115: raw counter values fit in int64 as with PDH
+			} else {
+				current := registrySample{value: value, seenAt: hostInfo.timestamp}
+				current.time, current.freq = sampleTime(definition.counterType, block, object)
+				if definition.base >= 0 {
+					current.base, _ = object.counters[definition.base].value(instance)
+				}
+				key := strings.Join([]string{hostInfo.computer, o.ObjectName, instance.name, counterName}, "\x00")
+				previous, hasPrevious := m.registrySamples.swap(key, current)
+				formatted, ok := formatPerfValue(definition.counterType, current, previous, hasPrevious)
+				if !ok {
+					continue
+				}
+				fields[fieldName] = formatted
+			}
+			if o.IncludeCounterPath {
+				pathInstance := instance.name
+				if pathInstance == "" {
+					pathInstance = emptyInstance
+				}
+				fields[fieldName+"_path"] = formatPath(hostInfo.computer, o.ObjectName, pathInstance, counterName)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		grouping := instanceGrouping{measurement, instance.name, o.ObjectName}
+		collectFields[grouping] = fields
+		groupObjects[grouping] = o
+	}
+
+	if len(missing) > 0 {
+		return wrapCounterError("read", hostInfo.computer, o.ObjectName, "", fmt.Errorf("counters not found: %s", strings.Join(missing, ", ")))
+	}
+	return nil
+}
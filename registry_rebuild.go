@@ -0,0 +1,74 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// registryRebuildCooldown 自动重建查询后的这段时间内不再因相同的错误重建，避免计数器确实缺失时反复重建。
+	registryRebuildCooldown = 5 * time.Minute
+	// registryRebuildMeasurement 自动重建查询时输出的事件指标的测量名称。
+	registryRebuildMeasurement = "win_perf_counters_event"
+)
+
+// isRegistryRebuildError 判断已添加的计数器在采集时返回的错误是否表明性能计数器注册表被重建（例如执行了 lodctr /R），
+// 此时原有的对象和计数器索引失效，查询中的计数器不再能读取。
+func isRegistryRebuildError(err error) bool {
+	var pdhErr *pdhError
+	if !errors.As(err, &pdhErr) {
+		return false
+	}
+	switch pdhErr.errorCode {
+	case pdhCstatusNoObject, pdhCstatusNoCounter, pdhCstatusItemNotValidated, pdhInvalidHandle:
+		return true
+	}
+	return false
+}
+
+// noteRegistryRebuild 在采集主机时遇到表明计数器注册表被重建的错误时调用，本次采集结束后重建所有查询。
+func (m *WinPerfCounters) noteRegistryRebuild(err error) {
+	if isRegistryRebuildError(err) {
+		m.registryRebuilt.Store(true)
+	}
+}
+
+// rebuildAfterRegistryChange 在本次采集中发现计数器注册表被重建时，立即关闭并重新创建所有查询，
+// 而不是在下一次计划的刷新之前每次采集都报告错误，并输出一条 event=counter_registry_rebuilt 的事件指标。
+// 距上一次自动重建不足 registryRebuildCooldown 时不再重建，仍有被放弃等待的采集时改为在下一次采集时刷新。
+func (m *WinPerfCounters) rebuildAfterRegistryChange(ctx context.Context) error {
+	if !m.registryRebuilt.Swap(false) {
+		return nil
+	}
+	now := time.Now()
+	if !m.lastRegistryRebuild.IsZero() && now.Sub(m.lastRegistryRebuild) < registryRebuildCooldown {
+		return nil
+	}
+	m.lastRegistryRebuild = now
+	m.localizedPaths.reset()
+	m.counterPaths.reset()
+	m.perfNames.reset()
+	m.localizedObjects.reset()
+	m.Log.Warnf("Performance counter registry appears to have been rebuilt, recreating all queries")
+	m.stats.incr(map[string]string{}, "registry_rebuilds", 1)
+	m.emit(registryRebuildMeasurement, map[string]interface{}{"count": int64(1)},
+		map[string]string{"event": "counter_registry_rebuilt"}, now)
+
+	if m.hostsBusy() {
+		m.lastRefreshed = time.Time{}
+		return nil
+	}
+	if err := m.discardPendingRefresh(); err != nil {
+		return err
+	}
+	hostErrs, err := m.refreshAll(ctx)
+	if err != nil {
+		return err
+	}
+	m.lastRefreshed = time.Now()
+	m.stats.incr(map[string]string{}, "refreshes", 1)
+	return hostErrs
+}
@@ -0,0 +1,89 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/BurntSushi/toml"
+)
+
+// reloadConfig Reload 支持热更新的配置项。
+type reloadConfig struct {
+	Sources []string       `toml:"Sources"`
+	Object  []ObjectConfig `toml:"object"`
+}
+
+// Reload 从 TOML 配置热更新采集的主机和计数器集合（Sources 与 [[object]]），无需重新创建 WinPerfCounters，
+// 配置中的其它选项会被忽略。新配置校验通过后在下一次 Gather 时生效：新的计数器集合在后台完成首次采样，
+// 该次采集仍使用旧集合输出数据，再下一次采集时切换，速率类计数器不会出现缺失或为零的数据。
+// [[object]] 中以及顶层无法识别的键（例如拼错的 Intances 或 Sorces）在启用 StrictConfig 时以 *UnknownConfigKeysError 返回，
+// 否则记录警告。不支持热更新的已知选项不会被报告。
+func (m *WinPerfCounters) Reload(newConfig []byte) error {
+	var config reloadConfig
+	meta, err := toml.Decode(string(newConfig), &config)
+	if err != nil {
+		return fmt.Errorf("decoding config failed: %w", err)
+	}
+	var undecoded []toml.Key
+	configType := reflect.TypeOf(WinPerfCounters{})
+	for _, key := range meta.Undecoded() {
+		// 拼错的 Sources 会使热更新改为采集本机，顶层的未知键同样需要报告
+		if _, known := tomlField(configType, key[0]); key[0] == "object" || !known {
+			undecoded = append(undecoded, key)
+		}
+	}
+	if err := m.checkUnknownKeys(newConfig, true, undecoded); err != nil {
+		return err
+	}
+	return m.ReloadObjects(config.Sources, config.Object)
+}
+
+// ReloadObjects 与 Reload 相同，使用代码构造的主机和对象配置，sources 为空时采集本机。
+// 新配置与 Init 经过相同的对象和主机校验，objects 在副本上展开，不会被修改。
+func (m *WinPerfCounters) ReloadObjects(sources []string, objects []ObjectConfig) error {
+	staged, err := m.prepareObjects(sources, slices.Clone(objects))
+	if err != nil {
+		return err
+	}
+
+	m.reloadLock.Lock()
+	defer m.reloadLock.Unlock()
+	m.pendingReload = &reloadConfig{Sources: slices.Clone(sources), Object: staged}
+	return nil
+}
+
+// applyReload 应用 Reload 暂存的配置，返回被替换的对象配置，没有待应用的配置时 ok 为 false。
+func (m *WinPerfCounters) applyReload() (replaced []ObjectConfig, ok bool) {
+	m.reloadLock.Lock()
+	config := m.pendingReload
+	m.pendingReload = nil
+	m.reloadLock.Unlock()
+	if config == nil {
+		return nil, false
+	}
+
+	m.configLock.Lock()
+	replaced = m.Object
+	m.Sources = config.Sources
+	m.Object = config.Object
+	m.configLock.Unlock()
+	// 配置未变化的对象标识不变，继续使用之前的采集时间，只丢弃已删除或被修改的对象的状态
+	ids := objectIDSet(m.Object)
+	for id := range m.lastGathered {
+		if !ids[id] {
+			delete(m.lastGathered, id)
+		}
+	}
+	m.stale.retain(ids)
+	m.quality.retain(ids)
+	m.missing.retain(ids)
+	// 注册表提供程序记录的原始值替换对象后重新开始记录
+	m.registrySamples.reset()
+	m.initMetadata()
+	m.updateConfigFingerprint()
+	m.Log.Infof("Configuration reloaded with %d objects", len(m.Object))
+	return replaced, true
+}
@@ -0,0 +1,141 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadUnknownKeys(t *testing.T) {
+	const object = `
+[[object]]
+  ObjectName = "Processor"
+  Counters = ["% Processor Time"]
+  Instances = ["_Total"]
+  Measurement = "win_cpu"
+`
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name:   "known keys",
+			config: "Sources = [\"localhost\"]\n" + object,
+		},
+		{
+			name:   "non-reloadable options are ignored",
+			config: "CountersRefreshInterval = \"1m\"\nUseWildcardsExpansion = true\n" + object,
+		},
+		{
+			name:   "misspelled Sources",
+			config: "Sorces = [\"hostA\"]\n" + object,
+			want:   []string{"Sorces"},
+		},
+		{
+			name:   "misspelled object key",
+			config: object + "  Intances = [\"*\"]\n",
+			want:   []string{"object.Intances"},
+		},
+		{
+			name:   "unknown table",
+			config: "[Sourcez]\n  hostA = true\n" + object,
+			want:   []string{"Sourcez"},
+		},
+	}
+	for _, tt := range tests {
+		m := NewWinPerfCounters(func(string, map[string]interface{}, map[string]string, time.Time) {})
+		m.StrictConfig = true
+		err := m.Reload([]byte(tt.config))
+		if len(tt.want) == 0 {
+			require.NoError(t, err, tt.name)
+			continue
+		}
+		var unknown *UnknownConfigKeysError
+		require.True(t, errors.As(err, &unknown), tt.name)
+		keys := make([]string, 0, len(unknown.Keys))
+		for _, key := range unknown.Keys {
+			keys = append(keys, key.Key)
+		}
+		require.Equal(t, tt.want, keys, tt.name)
+	}
+
+	// 未启用 StrictConfig 时只记录警告
+	var buf bytes.Buffer
+	m := NewWinPerfCounters(func(string, map[string]interface{}, map[string]string, time.Time) {})
+	m.Log = Logger{Output: log.New(&buf, "", 0)}
+	require.NoError(t, m.Reload([]byte("Sorces = [\"hostA\"]\n"+object)))
+	require.Contains(t, buf.String(), `"Sorces" at line 1 (did you mean "Sources"?)`)
+}
+
+func TestReloadObjectsMatchesInit(t *testing.T) {
+	counter := ObjectConfig{ObjectName: "Processor", Counters: []string{"% Processor Time"}, Instances: []string{"_Total"}}
+	withSources := counter
+	withSources.Sources = []string{"http://agent01:8080"}
+	withTags := counter
+	withTags.ExtraTags = map[string]string{"": "x"}
+	withProfile := counter
+	withProfile.Profiles = []string{"night"}
+	withRange := counter
+	withRange.Instances = []string{"2-1"}
+	wildcard := counter
+	wildcard.ObjectName = "Processor*"
+
+	tests := []struct {
+		name      string
+		objects   []ObjectConfig
+		configure func(m *WinPerfCounters)
+	}{
+		{name: "unknown preset", objects: []ObjectConfig{{Preset: "nope"}}},
+		{name: "agent source of object", objects: []ObjectConfig{withSources}},
+		{name: "empty extra tag key", objects: []ObjectConfig{withTags}},
+		{name: "unknown profile", objects: []ObjectConfig{withProfile}},
+		{name: "invalid instance range", objects: []ObjectConfig{withRange}},
+		{
+			name:    "wildcard object name",
+			objects: []ObjectConfig{wildcard},
+			configure: func(m *WinPerfCounters) {
+				m.UseWildcardsExpansion = true
+				m.LocalizeWildcardsExpansion = false
+			},
+		},
+		{
+			name:    "log source in source group",
+			objects: []ObjectConfig{counter},
+			configure: func(m *WinPerfCounters) {
+				m.SourceGroups = [][]string{{`file://C:\perf\baseline.blg`}}
+			},
+		},
+	}
+	for _, tt := range tests {
+		newPlugin := func() *WinPerfCounters {
+			m := NewWinPerfCounters(func(string, map[string]interface{}, map[string]string, time.Time) {})
+			if tt.configure != nil {
+				tt.configure(m)
+			}
+			return m
+		}
+		m := newPlugin()
+		m.Object = slices.Clone(tt.objects)
+		initErr := m.Init()
+		require.Error(t, initErr, tt.name)
+
+		// 热更新与 Init 使用相同的校验，Init 拒绝的配置同样不能通过 ReloadObjects 生效
+		err := newPlugin().ReloadObjects(nil, tt.objects)
+		require.EqualError(t, err, initErr.Error(), tt.name)
+	}
+
+	// 预置在暂存的副本上展开，不修改调用方传入的对象
+	objects := []ObjectConfig{{Preset: "memory"}}
+	m := NewWinPerfCounters(func(string, map[string]interface{}, map[string]string, time.Time) {})
+	require.NoError(t, m.ReloadObjects(nil, objects))
+	require.Empty(t, objects[0].Counters)
+	require.NotEmpty(t, m.pendingReload.Object[0].Counters)
+}
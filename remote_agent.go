@@ -0,0 +1,173 @@
+package win_perf_counters
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// AgentMetricsPath 采集代理提供本次采集指标的 HTTP 端点路径。
+	AgentMetricsPath = "/v1/metrics"
+	// agentFetchTimeout 未指定 HTTPClient 时读取采集代理的超时时间。
+	agentFetchTimeout = time.Minute
+	// agentSnapshotInterval 采集代理两次采集的最短间隔，间隔内的请求返回上一次采集的结果。
+	agentSnapshotInterval = time.Second
+)
+
+// isAgentSource 判断 Sources 中的条目是否为远程采集代理，例如 "http://winhost:7090"。
+func isAgentSource(source string) bool {
+	lower := strings.ToLower(source)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// validateAgentSource 校验 Sources 中的远程采集代理条目，只支持 HTTP+JSON。
+func validateAgentSource(source string) error {
+	if strings.HasPrefix(strings.ToLower(source), "grpc://") {
+		return fmt.Errorf("agent source %q uses gRPC, which is not supported, use http:// or https:// instead", source)
+	}
+	return nil
+}
+
+// AgentClient 从远程采集代理（cmd/agent）拉取指标，不依赖 DCOM/RPC 远程 PDH，
+// 可以在 Linux 等非 Windows 平台上使用。
+type AgentClient struct {
+	// URL 采集代理的地址，例如 "http://winhost:7090"。
+	URL string
+	// HTTPClient 发送请求使用的客户端，为 nil 时使用超时一分钟的默认客户端。
+	HTTPClient *http.Client
+	// Token 以 "Authorization: Bearer" 发送的令牌，与采集代理的 -token 一致，为空时不发送。
+	Token string
+}
+
+// NewAgentClient 创建读取 url 处采集代理的客户端。
+func NewAgentClient(url string) *AgentClient {
+	return &AgentClient{URL: url}
+}
+
+// Fetch 请求采集代理执行一次采集，并返回本次输出的全部指标。
+func (c *AgentClient) Fetch(ctx context.Context) ([]Metric, error) {
+	client := c.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: agentFetchTimeout}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.URL, "/")+AgentMetricsPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("agent %q returned %s: %s", c.URL, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return decodeAgentMetrics(resp.Body)
+}
+
+// decodeAgentMetrics 解析采集代理返回的指标，整数字段还原为 int64，其它数值为 float64。
+func decodeAgentMetrics(r io.Reader) ([]Metric, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var metrics []Metric
+	if err := decoder.Decode(&metrics); err != nil {
+		return nil, fmt.Errorf("decoding agent metrics failed: %w", err)
+	}
+	for _, metric := range metrics {
+		for name, value := range metric.Fields {
+			number, ok := value.(json.Number)
+			if !ok {
+				continue
+			}
+			if i, err := number.Int64(); err == nil {
+				metric.Fields[name] = i
+			} else if f, err := number.Float64(); err == nil {
+				metric.Fields[name] = f
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// agentHandler 返回以 JSON 输出 gather 采集结果的 HTTP 端点，仅接受 GET。距上一次采集不到 minInterval 的请求
+// 返回上一次的结果而不是重新采集，请求再频繁也不会增加 PDH 的负载。JSON 无法表示 NaN 与 Inf，这些字段不输出。
+func agentHandler(gather func() ([]Metric, error), minInterval time.Duration) http.Handler {
+	var lock sync.Mutex
+	var last time.Time
+	var snapshot []byte
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// 同时到达的请求等待同一次采集
+		lock.Lock()
+		defer lock.Unlock()
+		if snapshot == nil || time.Since(last) >= minInterval {
+			metrics, err := gather()
+			if err != nil && len(metrics) == 0 {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for i := range metrics {
+				metrics[i].Fields = finiteFields(metrics[i].Fields)
+			}
+			if snapshot, err = json.Marshal(metrics); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			last = time.Now()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(snapshot)
+	})
+}
+
+// RequireToken 返回只接受带有 "Authorization: Bearer <token>" 请求头的请求的 handler，其它请求返回 401，
+// 用于保护 AgentHandler、RefreshHandler 等没有自带认证的端点。token 为空时直接返回 handler。
+func RequireToken(token string, handler http.Handler) http.Handler {
+	if token == "" {
+		return handler
+	}
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// finiteFields 返回不包含 NaN 与 Inf 的字段，没有这类字段时返回 fields 本身，不修改回调可能持有的映射。
+func finiteFields(fields map[string]interface{}) map[string]interface{} {
+	var finite map[string]interface{}
+	for name, value := range fields {
+		if f, ok := value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			if finite == nil {
+				finite = maps.Clone(fields)
+			}
+			delete(finite, name)
+		}
+	}
+	if finite == nil {
+		return fields
+	}
+	return finite
+}
@@ -0,0 +1,126 @@
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeAgentMetrics(t *testing.T) {
+	metrics, err := decodeAgentMetrics(strings.NewReader(`[
+		{"measurement": "win_cpu", "tags": {"instance": "_Total"},
+		 "fields": {"count": 42, "ratio": 0.5, "big": 1e300, "name": "x", "ok": true},
+		 "timestamp": "2024-01-02T03:04:05Z"}
+	]`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, "win_cpu", metrics[0].Measurement)
+	require.Equal(t, map[string]string{"instance": "_Total"}, metrics[0].Tags)
+	require.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), metrics[0].Timestamp)
+	require.Equal(t, map[string]interface{}{
+		"count": int64(42),
+		"ratio": 0.5,
+		"big":   1e300,
+		"name":  "x",
+		"ok":    true,
+	}, metrics[0].Fields)
+
+	metrics, err = decodeAgentMetrics(strings.NewReader(`[]`))
+	require.NoError(t, err)
+	require.Empty(t, metrics)
+
+	_, err = decodeAgentMetrics(strings.NewReader(`{"measurement": "win_cpu"}`))
+	require.ErrorContains(t, err, "decoding agent metrics failed")
+	_, err = decodeAgentMetrics(strings.NewReader(`[{"measurement": `))
+	require.Error(t, err)
+}
+
+func TestAgentHandlerSnapshot(t *testing.T) {
+	gathers := 0
+	gather := func() ([]Metric, error) {
+		gathers++
+		return []Metric{{
+			Measurement: "win_cpu",
+			Fields:      map[string]interface{}{"value": float64(gathers), "nan": math.NaN()},
+		}}, nil
+	}
+	handler := agentHandler(gather, time.Hour)
+
+	get := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, AgentMetricsPath, nil))
+		return recorder
+	}
+	first := get()
+	require.Equal(t, http.StatusOK, first.Code)
+	require.Equal(t, "application/json", first.Header().Get("Content-Type"))
+	require.NotContains(t, first.Body.String(), "nan")
+
+	// 间隔内的请求返回上一次的结果，不再采集
+	second := get()
+	require.Equal(t, first.Body.String(), second.Body.String())
+	require.Equal(t, 1, gathers)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, AgentMetricsPath, nil))
+	require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	require.Equal(t, "GET", recorder.Header().Get("Allow"))
+}
+
+func TestAgentHandlerError(t *testing.T) {
+	handler := agentHandler(func() ([]Metric, error) { return nil, errors.New("pdh failed") }, 0)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, AgentMetricsPath, nil))
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "pdh failed")
+}
+
+func TestRequireToken(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	recorder := httptest.NewRecorder()
+	RequireToken("", ok).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+
+	handler := RequireToken("s3cr3t", ok)
+	tests := []struct {
+		authorization string
+		status        int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"s3cr3t", http.StatusUnauthorized},
+		{"Bearer s3cr3t", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		require.Equal(t, tt.status, recorder.Code, tt.authorization)
+	}
+}
+
+func TestAgentClientFetch(t *testing.T) {
+	metrics := func() ([]Metric, error) {
+		return []Metric{{Measurement: "win_mem", Fields: map[string]interface{}{"available": int64(1024)}}}, nil
+	}
+	server := httptest.NewServer(RequireToken("s3cr3t", agentHandler(metrics, 0)))
+	defer server.Close()
+
+	fetched, err := (&AgentClient{URL: server.URL + "/", Token: "s3cr3t"}).Fetch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, fetched, 1)
+	require.Equal(t, int64(1024), fetched[0].Fields["available"])
+
+	_, err = NewAgentClient(server.URL).Fetch(context.Background())
+	require.ErrorContains(t, err, "401")
+}
@@ -0,0 +1,156 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ResolveConfig 返回每个配置的计数器路径模式在各主机上实际匹配到的计数器路径，可用于校验配置、
+// 以及在管理端点或界面中展示配置与实际采集的差异。不在当前档位中的对象以及 wmi、registry 提供程序的对象不包含在内。
+//
+// 尚未进行首次采集时先添加计数器（与首次采集相同，会等待一秒完成首次采样）。与采集互斥，会等待正在进行的采集结束。
+func (m *WinPerfCounters) ResolveConfig() ([]ResolvedPattern, error) {
+	m.gatherLock.Lock()
+	defer m.gatherLock.Unlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if err := m.initialRefreshPending(); err != nil {
+		return nil, err
+	}
+	if m.lastRefreshed.IsZero() && !m.hostsBusy() {
+		// 首次采样失败的主机仍列出其计数器，错误在下一次采集时上报
+		if _, err := m.refreshAll(context.Background()); err != nil {
+			return nil, err
+		}
+		m.lastRefreshed = time.Now()
+		m.stats.incr(map[string]string{}, "refreshes", 1)
+	}
+
+	var resolved []ResolvedPattern
+	profile := m.ActiveProfile()
+	for i := range m.Object {
+		object := &m.Object[i]
+		if !object.inProfile(profile) || (!object.usesPDH() && !m.Simulate) {
+			continue
+		}
+		computers := object.Sources
+		if len(computers) == 0 {
+			computers = m.hostSources()
+		}
+		for _, computer := range computers {
+			if computer == "" {
+				computer = "localhost"
+			}
+			for _, counter := range object.counterNames() {
+				for _, instance := range object.queryInstances() {
+					pattern := ResolvedPattern{
+						Computer:    computer,
+						Object:      object.ObjectName,
+						Measurement: object.Measurement,
+						Counter:     counter,
+						Instance:    instance,
+						Pattern:     formatPath(computer, object.ObjectName, instance, counter),
+					}
+					pattern.Paths, pattern.Error = m.resolvePattern(object, computer, instance, pattern.Pattern)
+					resolved = append(resolved, pattern)
+				}
+			}
+		}
+	}
+	return resolved, nil
+}
+
+// resolvePattern 返回计数器路径模式在主机上匹配到的具体路径，无法匹配时返回原因。
+func (m *WinPerfCounters) resolvePattern(object *ObjectConfig, computer, instance, pattern string) ([]string, string) {
+	switch {
+	case m.skipDuplicate(object, computer):
+		return nil, "object is collected by another process"
+	case m.unmetObjects[objectHostKey{computer: computer, id: object.id}]:
+		return nil, "object conditions are not met on this host"
+	case m.counterIgnored(pattern):
+		return nil, "counter is ignored"
+	}
+	hostInfo, ok := m.hostCounters[queryKey(computer, object)]
+	if !ok || hostInfo.query == nil {
+		if m.DeferRemoteOpen {
+			return nil, "query of the host is not opened yet"
+		}
+		return nil, "counter could not be added"
+	}
+
+	var added []*counter
+	for _, metric := range hostInfo.counters {
+		if metric.object == object && metric.pattern == pattern {
+			added = append(added, metric)
+		}
+	}
+	if len(added) == 0 {
+		if slices.ContainsFunc(hostInfo.retries, func(item retryItem) bool { return item.object == object && item.counterPath == pattern }) {
+			return nil, "counter could not be added, retrying"
+		}
+		return nil, "counter could not be added"
+	}
+
+	var paths []string
+	if m.UseWildcardsExpansion || !strings.ContainsAny(pattern, "*?") {
+		for _, metric := range added {
+			paths = append(paths, metric.counterPath)
+		}
+	} else {
+		// 未展开通配符时每次采集通过数组读取所有实例，按当前的实例集合展开
+		expanded, err := hostInfo.query.ExpandWildCardPath(added[0].counterPath)
+		if err != nil {
+			return nil, err.Error()
+		}
+		for _, counterPath := range expanded {
+			_, _, expandedInstance, _, err := extractCounterInfoFromCounterPath(counterPath)
+			if err != nil {
+				continue
+			}
+			if expandedInstance == "_Total" && instance == "*" && !object.IncludeTotal {
+				continue
+			}
+			if !object.acceptInstance(instance, expandedInstance) || m.counterIgnored(counterPath) {
+				continue
+			}
+			paths = append(paths, counterPath)
+		}
+	}
+	slices.Sort(paths)
+	return slices.Compact(paths), ""
+}
+
+// ResolveHandler 返回以 JSON 输出 ResolveConfig 结果的 HTTP 端点，仅接受 GET，例如：
+//
+//	curl "http://127.0.0.1:8089/admin/resolve"
+//
+// 端点本身不做认证，暴露在回环地址以外时应使用 RequireToken 包装。
+func (m *WinPerfCounters) ResolveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		resolved, err := m.ResolveConfig()
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrClosed) {
+				status = http.StatusServiceUnavailable
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resolved)
+	})
+}
@@ -0,0 +1,80 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// retryMissingDelay 启用 RetryMissingCounters 时第一次重试添加计数器前的等待时间，之后每次加倍。
+	retryMissingDelay = 5 * time.Second
+	// retryMissingMaxDelay 重试添加计数器的最长间隔。
+	retryMissingMaxDelay = 5 * time.Minute
+)
+
+// retryItem 添加失败、等待重试的计数器。
+type retryItem struct {
+	deferredItem
+	// attempts 已经重试的次数。
+	attempts int
+	// next 下一次重试的时间。
+	next time.Time
+}
+
+// retryDelay 返回第 attempts 次重试失败后到下一次重试的间隔。
+func retryDelay(attempts int) time.Duration {
+	delay := retryMissingDelay
+	for i := 0; i < attempts && delay < retryMissingMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMissingMaxDelay)
+}
+
+// retryLater 在启用 RetryMissingCounters 时记录添加失败的计数器，在之后的采集中按指数退避重试。
+// 主机的查询未能打开以及字段重名导致的失败不会重试。
+func (m *WinPerfCounters) retryLater(computer string, item deferredItem, err error) {
+	if !m.RetryMissingCounters || errors.Is(err, errDuplicateField) {
+		return
+	}
+	hostInfo, ok := m.hostCounters[queryKey(computer, item.object)]
+	if !ok || hostInfo.query == nil {
+		return
+	}
+	hostInfo.retries = append(hostInfo.retries, retryItem{deferredItem: item, next: time.Now().Add(retryDelay(0))})
+}
+
+// retryMissing 在采集主机前重新添加到期的失败计数器，添加成功的计数器从本次采集开始输出，
+// 速率类计数器在完成首次采样后的下一次采集才有值。超过 MaxRetries 次仍失败的计数器不再重试，直到下一次刷新计数器。
+// addItem 会修改 hostCounters 与各个缓存，因此在持有 gatherLock 的采集 goroutine 中、启动各主机的采集之前逐个调用。
+func (m *WinPerfCounters) retryMissing(hostInfo *hostCountersInfo) {
+	if len(hostInfo.retries) == 0 {
+		return
+	}
+	now := time.Now()
+	remaining := hostInfo.retries[:0]
+	for _, item := range hostInfo.retries {
+		if now.Before(item.next) {
+			remaining = append(remaining, item)
+			continue
+		}
+		m.stats.incr(map[string]string{"source": hostInfo.tag}, "counter_retries", 1)
+		err := m.addItem(item.counterPath, hostInfo.computer, item.objectName, item.instance, item.counterName,
+			item.measurement, item.includeTotal, item.useRawValue, item.object)
+		if err == nil {
+			m.Log.Infof("Counter %q added after %d retries", item.counterPath, item.attempts+1)
+			continue
+		}
+		item.attempts++
+		if errors.Is(err, errDuplicateField) || (m.MaxRetries > 0 && item.attempts >= m.MaxRetries) {
+			m.Log.Warnf("Giving up adding counter %q after %d retries: %v", item.counterPath, item.attempts, err)
+			continue
+		}
+		item.next = now.Add(retryDelay(item.attempts))
+		m.Log.Debugf("Adding counter %q failed, retrying in %v: %v", item.counterPath, retryDelay(item.attempts), err)
+		remaining = append(remaining, item)
+	}
+	clear(hostInfo.retries[len(remaining):])
+	hostInfo.retries = remaining
+}
@@ -0,0 +1,57 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryDelay(t *testing.T) {
+	require.Equal(t, 5*time.Second, retryDelay(0))
+	require.Equal(t, 10*time.Second, retryDelay(1))
+	require.Equal(t, 20*time.Second, retryDelay(2))
+	require.Equal(t, retryMissingMaxDelay, retryDelay(10))
+	require.Equal(t, retryMissingMaxDelay, retryDelay(1000))
+}
+
+func TestRetryMissingCounters(t *testing.T) {
+	source := &fakeSource{value: 7}
+	m := newFakeSourcePlugin(source)
+	m.Sources = []string{"localhost", "hostB"}
+	m.UseWildcardsExpansion = true
+	m.RetryMissingCounters = true
+
+	var lock sync.Mutex
+	var gathered int
+	m.collect = func(string, map[string]interface{}, map[string]string, time.Time) {
+		lock.Lock()
+		defer lock.Unlock()
+		gathered++
+	}
+	require.NoError(t, m.Init())
+	t.Cleanup(func() { _ = m.Close() })
+
+	source.failExpand(errors.New("object not registered yet"))
+	require.NoError(t, m.Gather())
+	for _, hostInfo := range m.hostCounters {
+		require.Empty(t, hostInfo.counters)
+		require.Len(t, hostInfo.retries, 1)
+		hostInfo.retries[0].next = time.Time{}
+	}
+
+	// 两个主机的重试在同一次采集中逐个添加，添加成功后从本次采集开始输出
+	source.failExpand(nil)
+	require.NoError(t, m.Gather())
+	for _, hostInfo := range m.hostCounters {
+		require.Len(t, hostInfo.counters, 1)
+		require.Empty(t, hostInfo.retries)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, 2, gathered)
+}
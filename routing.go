@@ -0,0 +1,78 @@
+//go:build windows
+
+package win_perf_counters
+
+import "fmt"
+
+// routeRule 表示一条路由规则，匹配的指标会发送给 Sinks 中列出的输出。
+// Measurements、Objects、Tags 为空时不做限制。
+type routeRule struct {
+	// Measurements 匹配的测量名称列表。
+	Measurements []string `toml:"Measurements"`
+	// Objects 匹配的性能对象名称列表。
+	Objects []string `toml:"Objects"`
+	// Tags 需要完全匹配的标签。
+	Tags map[string]string `toml:"Tags"`
+	// Sinks 通过 RegisterSink 注册的输出名称列表。
+	Sinks []string `toml:"Sinks"`
+
+	predicate CollectPredicate
+}
+
+// RegisterSink 注册一个具名输出，该输出只接收 [[route]] 规则路由给它的指标。
+func (m *WinPerfCounters) RegisterSink(name string, collectFunc CollectFunc) {
+	m.routesLock.Lock()
+	defer m.routesLock.Unlock()
+
+	if m.sinks == nil {
+		m.sinks = make(map[string]CollectFunc)
+	}
+	m.sinks[name] = collectFunc
+}
+
+// initRoutes 校验路由规则并生成匹配条件，规则引用了未注册的输出时返回错误。
+func (m *WinPerfCounters) initRoutes() error {
+	m.routesLock.Lock()
+	defer m.routesLock.Unlock()
+
+	for i := range m.Route {
+		rule := &m.Route[i]
+		for _, sink := range rule.Sinks {
+			if _, ok := m.sinks[sink]; !ok {
+				return fmt.Errorf("route %d references unknown sink %q", i, sink)
+			}
+		}
+		rule.predicate = PipeFilter{Measurements: rule.Measurements, Objects: rule.Objects, Tags: rule.Tags}.predicate()
+	}
+	return nil
+}
+
+// matchingSinks 返回指标应发送到的输出，即所有匹配规则中列出的输出，同一输出只出现一次。
+func (m *WinPerfCounters) matchingSinks(measurement string, tags map[string]string) []CollectFunc {
+	if len(m.Route) == 0 {
+		return nil
+	}
+	m.routesLock.RLock()
+	defer m.routesLock.RUnlock()
+
+	var sinks []CollectFunc
+	var sent map[string]bool
+	for _, rule := range m.Route {
+		if rule.predicate == nil || !rule.predicate(measurement, tags) {
+			continue
+		}
+		for _, name := range rule.Sinks {
+			if sent[name] {
+				continue
+			}
+			if sent == nil {
+				sent = make(map[string]bool)
+			}
+			sent[name] = true
+			if sink := m.sinks[name]; sink != nil {
+				sinks = append(sinks, sink)
+			}
+		}
+	}
+	return sinks
+}
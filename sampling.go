@@ -0,0 +1,207 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// flushPercentile FlushInterval 窗口输出的百分位数。
+	flushPercentile = 0.95
+	// maxFlushSamples 每个字段在 FlushInterval 窗口内保留用于计算百分位数的样本上限。
+	maxFlushSamples = 1024
+)
+
+// sampleWindow 汇总一条序列在两次输出之间的样本。
+type sampleWindow struct {
+	count int
+	min   map[string]float64
+	max   map[string]float64
+	sum   map[string]float64
+	seen  time.Time
+}
+
+// flushWindow 汇总一条序列在 FlushInterval 时间窗口内的样本。
+type flushWindow struct {
+	start  time.Time
+	seen   time.Time
+	fields map[string]*flushField
+}
+
+// flushField 一个数值字段在窗口内的统计。min、max 与 sum 由全部样本计算；samples 用于计算百分位数，
+// 最多保留 maxFlushSamples 个，满后隔一个丢弃并将抽样间隔 stride 加倍，保留的样本在窗口内仍均匀分布。
+type flushField struct {
+	count   int
+	min     float64
+	max     float64
+	sum     float64
+	samples []float64
+	stride  int
+}
+
+// add 将样本 v 计入字段的统计。
+func (f *flushField) add(v float64) {
+	if f.count == 0 || v < f.min {
+		f.min = v
+	}
+	if f.count == 0 || v > f.max {
+		f.max = v
+	}
+	f.sum += v
+	f.count++
+
+	if f.stride == 0 {
+		f.stride = 1
+	}
+	if (f.count-1)%f.stride != 0 {
+		return
+	}
+	if len(f.samples) == maxFlushSamples {
+		kept := f.samples[:0]
+		for i := 0; i < len(f.samples); i += 2 {
+			kept = append(kept, f.samples[i])
+		}
+		f.samples = kept
+		f.stride *= 2
+		if (f.count-1)%f.stride != 0 {
+			return
+		}
+	}
+	f.samples = append(f.samples, v)
+}
+
+// emissionSampler 按对象的 EmitEvery 或 FlushInterval 对高频采集的序列抽样输出。
+type emissionSampler struct {
+	lock    sync.Mutex
+	windows map[string]*sampleWindow
+	flushes map[string]*flushWindow
+}
+
+// add 将本次样本计入序列的窗口，窗口满 every 个样本时返回 true，
+// 并在 fields 中加入窗口内各数值字段的 "<字段>_min"、"<字段>_max" 与 "<字段>_avg"。
+func (s *emissionSampler) add(key string, every int, fields map[string]interface{}) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.windows == nil {
+		s.windows = make(map[string]*sampleWindow)
+	}
+	window, ok := s.windows[key]
+	if !ok {
+		window = &sampleWindow{
+			min: make(map[string]float64),
+			max: make(map[string]float64),
+			sum: make(map[string]float64),
+		}
+		s.windows[key] = window
+	}
+	window.count++
+	window.seen = time.Now()
+	for field, value := range fields {
+		v, ok := toFloat(value)
+		if !ok {
+			continue
+		}
+		if current, ok := window.min[field]; !ok || v < current {
+			window.min[field] = v
+		}
+		if current, ok := window.max[field]; !ok || v > current {
+			window.max[field] = v
+		}
+		window.sum[field] += v
+	}
+	if window.count < every {
+		return false
+	}
+
+	for field, sum := range window.sum {
+		fields[field+"_min"] = window.min[field]
+		fields[field+"_max"] = window.max[field]
+		fields[field+"_avg"] = sum / float64(window.count)
+	}
+	delete(s.windows, key)
+	return true
+}
+
+// addTimed 将本次样本计入序列的时间窗口，窗口开始后经过 interval 时返回 true，
+// 并在 fields 中加入窗口内各数值字段的 "<字段>_min"、"<字段>_max"、"<字段>_mean" 与 "<字段>_p95"，字段本身为最后一个值。
+func (s *emissionSampler) addTimed(key string, interval time.Duration, now time.Time, fields map[string]interface{}) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.flushes == nil {
+		s.flushes = make(map[string]*flushWindow)
+	}
+	window, ok := s.flushes[key]
+	if !ok {
+		window = &flushWindow{start: now, fields: make(map[string]*flushField)}
+		s.flushes[key] = window
+	}
+	window.seen = now
+	for field, value := range fields {
+		v, ok := toFloat(value)
+		if !ok {
+			continue
+		}
+		stats, ok := window.fields[field]
+		if !ok {
+			stats = &flushField{}
+			window.fields[field] = stats
+		}
+		stats.add(v)
+	}
+	if now.Sub(window.start) < interval-intervalTolerance {
+		return false
+	}
+
+	for field, stats := range window.fields {
+		slices.Sort(stats.samples)
+		fields[field+"_min"] = stats.min
+		fields[field+"_max"] = stats.max
+		fields[field+"_mean"] = stats.sum / float64(stats.count)
+		fields[field+"_p95"] = nearestRank(stats.samples, flushPercentile)
+	}
+	delete(s.flushes, key)
+	return true
+}
+
+// nearestRank 按最近秩法返回已排序样本的百分位数。
+func nearestRank(sorted []float64, percentile float64) float64 {
+	rank := int(math.Ceil(percentile*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+// prune 丢弃长时间未更新的序列窗口，例如已退出的进程。
+func (s *emissionSampler) prune(now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for key, window := range s.windows {
+		if now.Sub(window.seen) > previousValueTimeout {
+			delete(s.windows, key)
+		}
+	}
+	for key, window := range s.flushes {
+		if now.Sub(window.seen) > previousValueTimeout {
+			delete(s.flushes, key)
+		}
+	}
+}
+
+// sampleEmission 判断对象的本次数据是否需要输出，未配置 EmitEvery 与 FlushInterval 时总是输出。
+func (m *WinPerfCounters) sampleEmission(object *ObjectConfig, measurement string, fields map[string]interface{}, tags map[string]string) bool {
+	if object == nil {
+		return true
+	}
+	if object.FlushInterval > 0 {
+		return m.sampler.addTimed(snapshotKey(measurement, tags), time.Duration(object.FlushInterval), time.Now(), fields)
+	}
+	if object.EmitEvery <= 1 {
+		return true
+	}
+	return m.sampler.add(snapshotKey(measurement, tags), object.EmitEvery, fields)
+}
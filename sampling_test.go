@@ -0,0 +1,173 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNearestRank(t *testing.T) {
+	tests := []struct {
+		sorted     []float64
+		percentile float64
+		want       float64
+	}{
+		{[]float64{7}, 0.95, 7},
+		{[]float64{1, 2}, 0.5, 1},
+		{[]float64{1, 2}, 0.95, 2},
+		{[]float64{15, 20, 35, 40, 50}, 0.3, 20},
+		{[]float64{15, 20, 35, 40, 50}, 0.4, 20},
+		{[]float64{15, 20, 35, 40, 50}, 0.5, 35},
+		{[]float64{15, 20, 35, 40, 50}, 1, 50},
+		{[]float64{15, 20, 35, 40, 50}, 0, 15},
+	}
+	for _, tt := range tests {
+		require.InDelta(t, tt.want, nearestRank(tt.sorted, tt.percentile), 0, "%v p%v", tt.sorted, tt.percentile)
+	}
+
+	sorted := make([]float64, 100)
+	for i := range sorted {
+		sorted[i] = float64(i + 1)
+	}
+	require.InDelta(t, 95.0, nearestRank(sorted, flushPercentile), 0)
+}
+
+func TestSamplerAdd(t *testing.T) {
+	tests := []struct {
+		name   string
+		every  int
+		values []interface{}
+		want   map[string]interface{}
+	}{
+		{
+			name:   "window of three",
+			every:  3,
+			values: []interface{}{30.0, 10.0, 50.0},
+			want: map[string]interface{}{
+				"value":     50.0,
+				"value_min": 10.0,
+				"value_max": 50.0,
+				"value_avg": 30.0,
+			},
+		},
+		{
+			name:   "integer values",
+			every:  2,
+			values: []interface{}{int64(4), int64(8)},
+			want: map[string]interface{}{
+				"value":     int64(8),
+				"value_min": 4.0,
+				"value_max": 8.0,
+				"value_avg": 6.0,
+			},
+		},
+		{
+			name:   "non-numeric values are not aggregated",
+			every:  2,
+			values: []interface{}{"a", "b"},
+			want:   map[string]interface{}{"value": "b"},
+		},
+	}
+	for _, tt := range tests {
+		var sampler emissionSampler
+		var fields map[string]interface{}
+		for i, value := range tt.values {
+			fields = map[string]interface{}{"value": value}
+			emit := sampler.add("cpu", tt.every, fields)
+			require.Equal(t, i == len(tt.values)-1, emit, "%s: sample %d", tt.name, i)
+		}
+		require.Equal(t, tt.want, fields, tt.name)
+		// 输出后窗口重新开始
+		require.Empty(t, sampler.windows, tt.name)
+	}
+
+	// 各序列的窗口相互独立
+	var sampler emissionSampler
+	require.False(t, sampler.add("cpu0", 2, map[string]interface{}{"value": 1.0}))
+	require.False(t, sampler.add("cpu1", 2, map[string]interface{}{"value": 1.0}))
+	require.True(t, sampler.add("cpu0", 2, map[string]interface{}{"value": 1.0}))
+}
+
+func TestSamplerPrune(t *testing.T) {
+	var sampler emissionSampler
+	now := time.Now()
+	require.False(t, sampler.add("cpu", 2, map[string]interface{}{"value": 1.0}))
+	require.False(t, sampler.addTimed("cpu", time.Hour, now, map[string]interface{}{"value": 1.0}))
+
+	sampler.prune(now.Add(previousValueTimeout / 2))
+	require.Len(t, sampler.windows, 1)
+	require.Len(t, sampler.flushes, 1)
+
+	sampler.prune(now.Add(2 * previousValueTimeout))
+	require.Empty(t, sampler.windows)
+	require.Empty(t, sampler.flushes)
+}
+
+func TestSampleEmission(t *testing.T) {
+	m := &WinPerfCounters{}
+	tags := map[string]string{"instance": "0"}
+	require.True(t, m.sampleEmission(nil, "win_cpu", map[string]interface{}{"value": 1.0}, tags))
+	require.True(t, m.sampleEmission(&ObjectConfig{EmitEvery: 1}, "win_cpu", map[string]interface{}{"value": 1.0}, tags))
+
+	object := &ObjectConfig{EmitEvery: 2}
+	require.False(t, m.sampleEmission(object, "win_cpu", map[string]interface{}{"value": 1.0}, tags))
+	// 不同实例是不同的序列
+	require.False(t, m.sampleEmission(object, "win_cpu", map[string]interface{}{"value": 1.0}, map[string]string{"instance": "1"}))
+	require.True(t, m.sampleEmission(object, "win_cpu", map[string]interface{}{"value": 1.0}, tags))
+}
+
+func TestAddTimed(t *testing.T) {
+	var sampler emissionSampler
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var fields map[string]interface{}
+	for i, value := range []float64{30, 10, 50, 20} {
+		fields = map[string]interface{}{"value": value, "name": "x"}
+		emit := sampler.addTimed("cpu", time.Minute, start.Add(time.Duration(i)*20*time.Second), fields)
+		require.Equal(t, i == 3, emit, i)
+	}
+	require.Equal(t, map[string]interface{}{
+		"value":      20.0,
+		"value_min":  10.0,
+		"value_max":  50.0,
+		"value_mean": 27.5,
+		"value_p95":  50.0,
+		"name":       "x",
+	}, fields)
+	require.Empty(t, sampler.flushes)
+}
+
+func TestAddTimedBoundsSamples(t *testing.T) {
+	var sampler emissionSampler
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	const samples = 10 * maxFlushSamples
+	for i := range samples - 1 {
+		require.False(t, sampler.addTimed("cpu", time.Hour, start.Add(time.Duration(i)*time.Millisecond), map[string]interface{}{"value": float64(i + 1)}))
+	}
+	stats := sampler.flushes["cpu"].fields["value"]
+	require.LessOrEqual(t, len(stats.samples), maxFlushSamples)
+	require.Equal(t, samples-1, stats.count)
+
+	fields := map[string]interface{}{"value": float64(samples)}
+	require.True(t, sampler.addTimed("cpu", time.Hour, start.Add(time.Hour), fields))
+	// min、max 与 mean 由全部样本计算，p95 由均匀抽样的样本近似
+	require.InDelta(t, 1.0, fields["value_min"], 0)
+	require.InDelta(t, float64(samples), fields["value_max"], 0)
+	require.InDelta(t, float64(samples+1)/2, fields["value_mean"], 1e-9)
+	require.InDelta(t, 0.95*samples, fields["value_p95"], 0.01*samples)
+}
+
+func TestFlushFieldStride(t *testing.T) {
+	var field flushField
+	for i := range 2 * maxFlushSamples {
+		field.add(float64(i))
+	}
+	// 满后隔一个丢弃，保留的样本仍是等间隔的
+	require.Equal(t, 2, field.stride)
+	require.Len(t, field.samples, maxFlushSamples)
+	for i, v := range field.samples {
+		require.InDelta(t, float64(2*i), v, 0)
+	}
+}
@@ -0,0 +1,87 @@
+package win_perf_counters
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 测量名称、字段名称与标签名称的转换策略
+const (
+	// namePolicyTelegraf 与 Telegraf 相同的默认转换：空格替换为下划线，"%" 替换为 "Percent"，"/sec" 替换为 "_persec"，去掉反斜杠。
+	namePolicyTelegraf = "telegraf"
+	// namePolicyPrometheus 转换为 Prometheus 的指标与标签名称：小写，只包含字母、数字和下划线。
+	namePolicyPrometheus = "prometheus"
+	// namePolicyNone 不做转换，原样使用配置中的名称。
+	namePolicyNone = "none"
+)
+
+// prometheusNameReplacer 在按字符替换之前转换有含义的符号。
+var prometheusNameReplacer = strings.NewReplacer("%", "percent", "/sec", "_per_second", "/Sec", "_per_second", "/", "_per_", "#", "number")
+
+// prometheusName 将名称转换为 Prometheus 的指标或标签名称，例如 "% Processor Time" 转换为 "percent_processor_time"。
+func prometheusName(name string) string {
+	name = prometheusNameReplacer.Replace(name)
+	var b strings.Builder
+	underscore := true
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	result := strings.TrimSuffix(b.String(), "_")
+	if result != "" && result[0] >= '0' && result[0] <= '9' {
+		result = "_" + result
+	}
+	return result
+}
+
+// SanitizeFieldName 返回计数器名称 counterName 在输出中的字段名称，与采集时一样按 FieldNameSanitizer 或 NamePolicy 转换，
+// 启用 UnitSuffixes 时单位移到末尾，例如默认配置下 "% Processor Time" 转换为 "Percent_Processor_Time"。
+// 供查询构建和仪表盘生成工具由原始计数器名称预先得到字段名称，不需要调用 Init，在非 Windows 平台同样可用。
+// 对象级的 CounterAliases 不在此处理，UseRawValues 的对象在结果后追加 "_Raw"。
+func (m *WinPerfCounters) SanitizeFieldName(counterName string) string {
+	if m.UnitSuffixes {
+		counterName = unitSuffixed(counterName)
+	}
+	return m.sanitizeName(counterName)
+}
+
+// SanitizeMeasurementName 返回对象的 Measurement 配置在输出中的测量名称，按 FieldNameSanitizer 或 NamePolicy 转换，
+// 转换结果为空时为 "win_perf_counters"。对象的 NameOverride 按原样使用，MeasurementRules 与 Transliterate 不在此处理。
+func (m *WinPerfCounters) SanitizeMeasurementName(measurement string) string {
+	if name := m.sanitizeName(measurement); name != "" {
+		return name
+	}
+	return "win_perf_counters"
+}
+
+// nameSanitizers 按 FieldNameSanitizer 或 NamePolicy 返回测量与字段名称、以及非标准标签名称的转换函数，
+// 为 nil 时名称使用默认的 telegraf 转换、标签名称不转换。initNameSanitizers 与 sanitizeName 都由此得到转换函数。
+func (m *WinPerfCounters) nameSanitizers() (names, tagKeys func(string) string, err error) {
+	switch strings.ToLower(m.NamePolicy) {
+	case "", namePolicyTelegraf:
+	case namePolicyPrometheus:
+		names, tagKeys = prometheusName, prometheusName
+	case namePolicyNone:
+		names = func(name string) string { return name }
+	default:
+		return nil, nil, fmt.Errorf("invalid NamePolicy %q, expected %q, %q or %q", m.NamePolicy, namePolicyTelegraf, namePolicyPrometheus, namePolicyNone)
+	}
+	if m.FieldNameSanitizer != nil {
+		names, tagKeys = m.FieldNameSanitizer, m.FieldNameSanitizer
+	}
+	return names, tagKeys, nil
+}
+
+// sanitizeName 按 FieldNameSanitizer 或 NamePolicy 转换名称，与 initNameSanitizers 为对象设置的转换一致，
+// NamePolicy 无效时使用默认的 telegraf 转换。
+func (m *WinPerfCounters) sanitizeName(name string) string {
+	if names, _, _ := m.nameSanitizers(); names != nil {
+		return names(name)
+	}
+	return sanitizedChars.Replace(name)
+}
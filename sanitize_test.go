@@ -0,0 +1,77 @@
+package win_perf_counters
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeFieldName(t *testing.T) {
+	tests := []struct {
+		name        string
+		plugin      *WinPerfCounters
+		counterName string
+		want        string
+	}{
+		{"telegraf", &WinPerfCounters{}, "% Processor Time", "Percent_Processor_Time"},
+		{"telegraf rate", &WinPerfCounters{NamePolicy: "Telegraf"}, "Disk Read Bytes/sec", "Disk_Read_Bytes_persec"},
+		{"prometheus", &WinPerfCounters{NamePolicy: "prometheus"}, "% Processor Time", "percent_processor_time"},
+		{"none", &WinPerfCounters{NamePolicy: "none"}, "% Processor Time", "% Processor Time"},
+		{"invalid policy", &WinPerfCounters{NamePolicy: "bogus"}, "% Processor Time", "Percent_Processor_Time"},
+		{"unit suffixes", &WinPerfCounters{UnitSuffixes: true}, "% Processor Time", "Processor_Time_percent"},
+		{"custom sanitizer", &WinPerfCounters{NamePolicy: "prometheus", FieldNameSanitizer: strings.ToUpper}, "% Processor Time", "% PROCESSOR TIME"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, tt.plugin.SanitizeFieldName(tt.counterName), tt.name)
+	}
+}
+
+func TestSanitizeMeasurementName(t *testing.T) {
+	require.Equal(t, "win_cpu", (&WinPerfCounters{}).SanitizeMeasurementName("win cpu"))
+	require.Equal(t, "win_cpu", (&WinPerfCounters{NamePolicy: "prometheus"}).SanitizeMeasurementName("Win CPU"))
+	require.Equal(t, "win_perf_counters", (&WinPerfCounters{}).SanitizeMeasurementName(""))
+	require.Equal(t, "win_perf_counters", (&WinPerfCounters{NamePolicy: "prometheus"}).SanitizeMeasurementName("(-)"))
+}
+
+func TestNameSanitizers(t *testing.T) {
+	names, tagKeys, err := (&WinPerfCounters{}).nameSanitizers()
+	require.NoError(t, err)
+	require.Nil(t, names)
+	require.Nil(t, tagKeys)
+
+	names, tagKeys, err = (&WinPerfCounters{NamePolicy: "none"}).nameSanitizers()
+	require.NoError(t, err)
+	require.Equal(t, "a b", names("a b"))
+	require.Nil(t, tagKeys)
+
+	_, tagKeys, err = (&WinPerfCounters{NamePolicy: "PROMETHEUS"}).nameSanitizers()
+	require.NoError(t, err)
+	require.Equal(t, "service_name", tagKeys("Service Name"))
+
+	_, _, err = (&WinPerfCounters{NamePolicy: "bogus"}).nameSanitizers()
+	require.ErrorContains(t, err, `invalid NamePolicy "bogus"`)
+}
+
+func TestPrometheusName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"% Processor Time", "percent_processor_time"},
+		{"Disk Read Bytes/sec", "disk_read_bytes_per_second"},
+		{"Pages/Sec", "pages_per_second"},
+		{"Avg. Disk sec/Read", "avg_disk_sec_per_read"},
+		{"# of Exceps Thrown", "number_of_exceps_thrown"},
+		{"IO Data Bytes/sec", "io_data_bytes_per_second"},
+		{"  Service  Name  ", "service_name"},
+		{"win_cpu", "win_cpu"},
+		{"Größe", "gr_e"},
+		{"2xx Responses", "_2xx_responses"},
+		{"(-)", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, prometheusName(tt.name), tt.name)
+	}
+}
@@ -0,0 +1,193 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// defaultSchedulerInterval 调度器中未配置 Interval 的对象使用的默认采集间隔。
+	defaultSchedulerInterval = 10 * time.Second
+	// intervalTolerance 判断对象是否到期时允许的时间误差，避免定时器抖动导致错过一个周期。
+	intervalTolerance = 500 * time.Millisecond
+)
+
+// dueObjects 按对象的标识记录本次采集需要采集的对象。
+type dueObjects map[objectID]*ObjectConfig
+
+// contains 判断对象本次是否需要采集，未关联对象配置的计数器始终采集。
+func (d dueObjects) contains(object *ObjectConfig) bool {
+	return object == nil || d[object.id] != nil
+}
+
+// has 按标识判断对象本次是否需要采集，空标识表示未关联对象配置，始终采集。
+func (d dueObjects) has(id objectID) bool {
+	return id == "" || d[id] != nil
+}
+
+// add 记录对象，未关联对象配置时不做任何处理。
+func (d dueObjects) add(object *ObjectConfig) {
+	if object != nil {
+		d[object.id] = object
+	}
+}
+
+// dueObjects 计算第 cycle 次采集时需要采集的对象，同时考虑 GatherEvery、Interval 和输出背压，并记录到期对象的采集时间。
+func (m *WinPerfCounters) dueObjects(cycle uint64, now time.Time) dueObjects {
+	if m.lastGathered == nil {
+		m.lastGathered = make(map[objectID]time.Time)
+	}
+	slowdown := m.updateBackpressure()
+	due := make(dueObjects, len(m.Object))
+	for i := range m.Object {
+		object := &m.Object[i]
+		if !object.dueAt(cycle) {
+			continue
+		}
+		if object.throttled(cycle, slowdown) {
+			m.stats.incr(map[string]string{"objectname": object.ObjectName}, "backpressure_skipped", 1)
+			continue
+		}
+		interval := time.Duration(object.Interval)
+		if interval <= 0 && m.isScheduled() {
+			interval = m.defaultInterval()
+		}
+		if last, ok := m.lastGathered[object.id]; ok && interval > 0 && now.Sub(last) < interval-intervalTolerance {
+			continue
+		}
+		due[object.id] = object
+		m.lastGathered[object.id] = now
+	}
+	return due
+}
+
+// without 返回去掉 excluded 中对象后的副本。
+func (d dueObjects) without(excluded dueObjects) dueObjects {
+	if len(excluded) == 0 {
+		return d
+	}
+	result := make(dueObjects, len(d))
+	for id, object := range d {
+		if _, ok := excluded[id]; !ok {
+			result[id] = object
+		}
+	}
+	return result
+}
+
+// hasDueCounters 判断主机上是否有本次需要采集的计数器。
+func (h *hostCountersInfo) hasDueCounters(due dueObjects) bool {
+	for _, metric := range h.counters {
+		if due.contains(metric.object) {
+			return true
+		}
+	}
+	for _, item := range h.deferred {
+		if due.contains(item.object) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultInterval 返回未配置 Interval 的对象在调度器中的采集间隔：依次取当前档位的 Interval、全局 Interval 和默认值。
+func (m *WinPerfCounters) defaultInterval() time.Duration {
+	if interval := m.GatherInterval(); interval > 0 {
+		return interval
+	}
+	if m.Interval > 0 {
+		return time.Duration(m.Interval)
+	}
+	return defaultSchedulerInterval
+}
+
+// schedulerTick 返回调度器的节拍，即默认间隔与所有对象 Interval 的最大公约数。
+func (m *WinPerfCounters) schedulerTick() time.Duration {
+	tick := m.defaultInterval()
+	m.configLock.RLock()
+	defer m.configLock.RUnlock()
+	for _, object := range m.Object {
+		if object.Interval > 0 {
+			tick = gcdDuration(tick, time.Duration(object.Interval))
+		}
+	}
+	// 避免间隔互质时节拍过小
+	return max(tick, time.Second)
+}
+
+// gcdDuration 返回两个时长的最大公约数。
+func gcdDuration(a, b time.Duration) time.Duration {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// isScheduled 判断调度器是否正在运行。
+func (m *WinPerfCounters) isScheduled() bool {
+	m.schedulerLock.Lock()
+	defer m.schedulerLock.Unlock()
+	return m.schedulerCancel != nil
+}
+
+// Start 启动内部调度器，按各对象的 Interval 自动采集，直到 ctx 被取消或调用 Stop。
+// 调度器运行期间不应再由调用方调用 Gather。
+func (m *WinPerfCounters) Start(ctx context.Context) error {
+	m.schedulerLock.Lock()
+	defer m.schedulerLock.Unlock()
+
+	if m.schedulerCancel != nil {
+		return errors.New("scheduler already started")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	m.schedulerCancel = cancel
+	m.schedulerDone = done
+
+	go func() {
+		defer close(done)
+		m.runScheduler(ctx)
+	}()
+	return nil
+}
+
+// Stop 停止内部调度器，并等待正在进行的采集结束。
+func (m *WinPerfCounters) Stop() {
+	m.schedulerLock.Lock()
+	cancel, done := m.schedulerCancel, m.schedulerDone
+	m.schedulerLock.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+
+	m.schedulerLock.Lock()
+	m.schedulerCancel = nil
+	m.schedulerDone = nil
+	m.schedulerLock.Unlock()
+}
+
+// runScheduler 按节拍调用 GatherContext，节拍在每次采集后重新计算以适应档位切换。
+func (m *WinPerfCounters) runScheduler(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		start := time.Now()
+		if err := m.GatherContext(ctx); errors.Is(err, ErrNotReady) {
+			m.Log.Infof("Scheduled gather skipped: %v", err)
+		} else if err != nil && ctx.Err() == nil {
+			m.Log.Errorf("Scheduled gather failed: %v", err)
+		}
+		timer.Reset(max(m.schedulerTick()-time.Since(start), 0))
+	}
+}
@@ -0,0 +1,135 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+// selfMeasurement 插件自身运行状态指标的测量名称。
+const selfMeasurement = "win_perf_counters_internal"
+
+// selfSeries 表示一组标签对应的自身状态字段。
+type selfSeries struct {
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+// selfMetrics 记录插件自身的运行状态，启用 SelfMetrics 时在每次 Gather 结束后输出。
+type selfMetrics struct {
+	lock   sync.Mutex
+	series map[string]*selfSeries
+}
+
+// get 返回指定标签对应的序列，不存在时创建，调用方需持有 lock。
+func (s *selfMetrics) get(tags map[string]string) *selfSeries {
+	if s.series == nil {
+		s.series = make(map[string]*selfSeries)
+	}
+	key := snapshotKey(selfMeasurement, tags)
+	series, ok := s.series[key]
+	if !ok {
+		series = &selfSeries{tags: tags, fields: make(map[string]interface{})}
+		s.series[key] = series
+	}
+	return series
+}
+
+// incr 将指定字段累加 delta。
+func (s *selfMetrics) incr(tags map[string]string, field string, delta int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	series := s.get(tags)
+	value, _ := series.fields[field].(int64)
+	series.fields[field] = value + delta
+}
+
+// set 设置指定字段的值。
+func (s *selfMetrics) set(tags map[string]string, field string, value interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.get(tags).fields[field] = value
+}
+
+// value 返回指定标签对应序列中字段的值，不存在时返回 nil，不会创建序列。
+func (s *selfMetrics) value(tags map[string]string, field string) interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	series, ok := s.series[snapshotKey(selfMeasurement, tags)]
+	if !ok {
+		return nil
+	}
+	return series.fields[field]
+}
+
+// flush 通过 emit 输出所有自身状态指标。
+func (s *selfMetrics) flush(emit CollectFunc, timestamp time.Time) {
+	s.lock.Lock()
+	series := make([]selfSeries, 0, len(s.series))
+	for _, v := range s.series {
+		series = append(series, selfSeries{tags: maps.Clone(v.tags), fields: maps.Clone(v.fields)})
+	}
+	s.lock.Unlock()
+
+	for _, v := range series {
+		emit(selfMeasurement, v.fields, v.tags, timestamp)
+	}
+}
+
+// snapshot 返回所有自身状态指标的副本。
+func (s *selfMetrics) snapshot(timestamp time.Time) []Metric {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	metrics := make([]Metric, 0, len(s.series))
+	for _, v := range s.series {
+		metrics = append(metrics, Metric{
+			Measurement: selfMeasurement,
+			Tags:        maps.Clone(v.tags),
+			Fields:      maps.Clone(v.fields),
+			Timestamp:   timestamp,
+		})
+	}
+	return metrics
+}
+
+// Stats 返回插件自身的运行状态指标，与启用 SelfMetrics 时输出的 win_perf_counters_internal 指标相同。
+// 未启用 SelfMetrics 时同样会记录这些状态。
+func (m *WinPerfCounters) Stats() []Metric {
+	return m.stats.snapshot(time.Now())
+}
+
+// recordHostStats 记录一个主机本次采集的耗时和有效计数器数量。
+func (m *WinPerfCounters) recordHostStats(hostInfo *hostCountersInfo, duration time.Duration) {
+	active := 0
+	for _, c := range hostInfo.counters {
+		if !c.quarantined {
+			active++
+		}
+	}
+	tags := hostInfo.statsTags()
+	m.stats.incr(tags, "gathers", 1)
+	m.stats.set(tags, "gather_duration_ms", float64(duration)/float64(time.Millisecond))
+	m.stats.set(tags, "active_counters", int64(active))
+}
+
+// countPdhError 将主机上发生的 PDH 错误按错误名称计入自身状态指标，并记录表明计数器注册表被重建的错误。
+func (m *WinPerfCounters) countPdhError(hostInfo *hostCountersInfo, err error) {
+	var pdhErr *pdhError
+	if !errors.As(err, &pdhErr) {
+		return
+	}
+	errorName, ok := pdhErrors[pdhErr.errorCode]
+	if !ok {
+		errorName = fmt.Sprintf("0x%08X", pdhErr.errorCode)
+	}
+	m.stats.incr(map[string]string{"source": hostInfo.tag, "error": errorName}, "pdh_errors", 1)
+	m.noteRegistryRebuild(err)
+}
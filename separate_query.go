@@ -0,0 +1,76 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validateSeparateQueries 校验所有对象的 SeparateQuery 配置。
+func (m *WinPerfCounters) validateSeparateQueries(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].validateSeparateQuery(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSeparateQuery 校验对象的 SeparateQuery 配置，单独的查询只用于 pdh 提供程序。
+func (o *ObjectConfig) validateSeparateQuery() error {
+	if o.SeparateQuery && !o.usesPDH() {
+		return fmt.Errorf("SeparateQuery of object %q is only supported by the pdh provider", o.ObjectName)
+	}
+	return nil
+}
+
+// queryKey 返回对象的计数器所属查询在 hostCounters 中的键。配置了 SeparateQuery 的对象在每个主机上使用单独的查询，
+// 键为主机名加对象名称；其它对象共用主机的查询，键为主机名。
+func queryKey(computer string, object *ObjectConfig) string {
+	if object == nil || !object.SeparateQuery {
+		return computer
+	}
+	return computer + "\x00" + strings.ToLower(object.ObjectName)
+}
+
+// newObjectHostInfo 创建对象在主机 computer 上所属查询的 hostCountersInfo，查询由调用方打开。
+func (m *WinPerfCounters) newObjectHostInfo(computer, tag string, object *ObjectConfig) *hostCountersInfo {
+	hostInfo := &hostCountersInfo{computer: computer, tag: tag}
+	if object != nil && object.SeparateQuery {
+		// 单独的查询不与 SourceGroups 分组中的其它主机共用
+		hostInfo.separateObject = object.ObjectName
+		hostInfo.query = m.queryCreator.newPerformanceQuery(computer, uint32(m.MaxBufferSize))
+		return hostInfo
+	}
+	hostInfo.query = m.newHostQuery(computer)
+	return hostInfo
+}
+
+// owns 判断对象的计数器是否属于该查询。
+func (h *hostCountersInfo) owns(object *ObjectConfig) bool {
+	if object == nil || !object.SeparateQuery {
+		return h.separateObject == ""
+	}
+	return strings.EqualFold(object.ObjectName, h.separateObject)
+}
+
+// ownedObjects 返回 due 中计数器属于该查询的对象，同一主机的各个查询只为自己的对象输出过期标记、缺失实例与数据质量。
+func (h *hostCountersInfo) ownedObjects(due dueObjects) dueObjects {
+	owned := make(dueObjects, len(due))
+	for id, object := range due {
+		if h.owns(object) {
+			owned[id] = object
+		}
+	}
+	return owned
+}
+
+// statsTags 返回该查询的自身状态指标标签，单独的查询带有 objectname 标签，避免覆盖主机共用查询的指标。
+func (h *hostCountersInfo) statsTags() map[string]string {
+	tags := map[string]string{"source": h.tag}
+	if h.separateObject != "" {
+		tags["objectname"] = h.separateObject
+	}
+	return tags
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"sync"
+)
+
+// batchSequences 记录每个来源已分配的批次序号。
+type batchSequences struct {
+	lock sync.Mutex
+	last map[string]uint64
+}
+
+// next 为来源 source 分配下一个批次序号，第一个批次的序号为 1。
+func (s *batchSequences) next(source string) uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.last == nil {
+		s.last = make(map[string]uint64)
+	}
+	s.last[source]++
+	return s.last[source]
+}
+
+// batchSequence 在一批指标中第一次输出时为主机分配批次序号，之后的指标沿用该序号，未配置 SequenceField 时不分配。
+type batchSequence struct {
+	m      *WinPerfCounters
+	source string
+	seq    uint64
+}
+
+// apply 将批次序号写入字段 SequenceField。
+func (b *batchSequence) apply(fields map[string]interface{}) {
+	if b.m.SequenceField == "" {
+		return
+	}
+	if b.seq == 0 {
+		b.seq = b.m.sequences.next(b.source)
+	}
+	fields[b.m.SequenceField] = b.seq
+}
@@ -0,0 +1,117 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchSequencesNext(t *testing.T) {
+	var sequences batchSequences
+	require.Equal(t, uint64(1), sequences.next("hostA"))
+	require.Equal(t, uint64(2), sequences.next("hostA"))
+	require.Equal(t, uint64(1), sequences.next("hostB"))
+	require.Equal(t, uint64(3), sequences.next("hostA"))
+
+	// 并发分配的序号不重复也不跳号
+	var wg sync.WaitGroup
+	seen := make(chan uint64, 100)
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			seen <- sequences.next("hostC")
+		}()
+	}
+	wg.Wait()
+	close(seen)
+	got := make(map[uint64]bool)
+	for seq := range seen {
+		got[seq] = true
+	}
+	require.Len(t, got, 100)
+	for seq := uint64(1); seq <= 100; seq++ {
+		require.True(t, got[seq], seq)
+	}
+}
+
+func TestBatchSequenceApply(t *testing.T) {
+	m := &WinPerfCounters{}
+	batch := batchSequence{m: m, source: "hostA"}
+	fields := map[string]interface{}{"value": 1.0}
+	batch.apply(fields)
+	require.NotContains(t, fields, "batch_seq")
+	require.Empty(t, m.sequences.last, "no sequence is allocated without SequenceField")
+
+	m.SequenceField = "batch_seq"
+	first, second := map[string]interface{}{}, map[string]interface{}{}
+	batch.apply(first)
+	batch.apply(second)
+	require.Equal(t, uint64(1), first["batch_seq"])
+	require.Equal(t, uint64(1), second["batch_seq"])
+
+	next := batchSequence{m: m, source: "hostA"}
+	fields = map[string]interface{}{}
+	next.apply(fields)
+	require.Equal(t, uint64(2), fields["batch_seq"])
+}
+
+// TestSequenceFieldSeparateQuery 每个来源的批次序号从 1 开始连续递增，SeparateQuery 的对象单独成批，
+// 与同一来源的其它批次共用序号。
+func TestSequenceFieldSeparateQuery(t *testing.T) {
+	source := &fakeSource{value: 1}
+	m := newFakeSourcePlugin(source)
+	m.Sources = []string{"hostA", "hostB"}
+	m.SequenceField = "batch_seq"
+	m.Object = append(m.Object, ObjectConfig{
+		ObjectName:    "PhysicalDisk",
+		Counters:      []string{"Disk Reads/sec"},
+		Instances:     []string{"_Total"},
+		Measurement:   "win_disk",
+		SeparateQuery: true,
+	})
+
+	var lock sync.Mutex
+	// batches 各来源每个序号对应批次中的测量名称
+	batches := make(map[string]map[uint64]map[string]bool)
+	var unsequenced []string
+	m.collect = func(measurement string, fields map[string]interface{}, tags map[string]string, _ time.Time) {
+		lock.Lock()
+		defer lock.Unlock()
+		seq, ok := fields["batch_seq"].(uint64)
+		if !ok {
+			unsequenced = append(unsequenced, measurement)
+			return
+		}
+		if batches[tags["source"]] == nil {
+			batches[tags["source"]] = make(map[uint64]map[string]bool)
+		}
+		if batches[tags["source"]][seq] == nil {
+			batches[tags["source"]][seq] = make(map[string]bool)
+		}
+		batches[tags["source"]][seq][measurement] = true
+	}
+	require.NoError(t, m.Init())
+	t.Cleanup(func() { _ = m.Close() })
+
+	const gathers = 3
+	for range gathers {
+		require.NoError(t, m.Gather())
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Empty(t, unsequenced)
+	require.Len(t, batches, 2)
+	for host, sequences := range batches {
+		// 两个查询各自成批，每次采集每个来源占用两个序号
+		require.Len(t, sequences, 2*gathers, host)
+		for seq := uint64(1); seq <= 2*gathers; seq++ {
+			require.Len(t, sequences[seq], 1, "%s batch %d mixes objects of different queries", host, seq)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package win_perf_counters
+
+// deriveReplicationLatency 根据 NTDS 对象的复制计数器计算派生字段：
+// replication_sync_success_percent 为成功的同步请求占已发出同步请求的百分比，两个计数器都是域控制器服务启动以来的累计值，
+// 因此该比例是启动以来的整体成功率而不是本次采集间隔内的成功率，近期的失败在运行很久的服务上变化很小；
+// replication_latency_seconds 为按当前应用速率处理完剩余复制更新所需的估计秒数，速率为 0 时不输出。
+// 两者都基于格式化后的值，启用 UseRawValues 时不计算。
+func deriveReplicationLatency(_ *WinPerfCounters, _ *hostCountersInfo, object *ObjectConfig, fields map[string]interface{}) {
+	if object.UseRawValues {
+		return
+	}
+	made, okMade := toFloat(fields[object.fieldName("DRA Sync Requests Made")])
+	successful, okSuccessful := toFloat(fields[object.fieldName("DRA Sync Requests Successful")])
+	if okMade && okSuccessful && made > 0 {
+		fields["replication_sync_success_percent"] = successful / made * 100
+	}
+	remaining, okRemaining := toFloat(fields[object.fieldName("DRA Remaining Replication Updates")])
+	applied, okApplied := toFloat(fields[object.fieldName("DRA Inbound Objects Applied/sec")])
+	if okRemaining && okApplied && applied > 0 {
+		fields["replication_latency_seconds"] = remaining / applied
+	}
+}
@@ -0,0 +1,69 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveReplicationLatency(t *testing.T) {
+	object := &ObjectConfig{}
+	made := object.fieldName("DRA Sync Requests Made")
+	successful := object.fieldName("DRA Sync Requests Successful")
+	remaining := object.fieldName("DRA Remaining Replication Updates")
+	applied := object.fieldName("DRA Inbound Objects Applied/sec")
+	require.Equal(t, "DRA_Inbound_Objects_Applied_persec", applied)
+
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{
+			name:   "both derived",
+			fields: map[string]interface{}{made: 200.0, successful: 150.0, remaining: 500.0, applied: 25.0},
+			want: map[string]interface{}{
+				"replication_sync_success_percent": 75.0,
+				"replication_latency_seconds":      20.0,
+			},
+		},
+		{
+			name:   "integer counters",
+			fields: map[string]interface{}{made: int64(4), successful: int64(4), remaining: int64(0), applied: int64(10)},
+			want: map[string]interface{}{
+				"replication_sync_success_percent": 100.0,
+				"replication_latency_seconds":      0.0,
+			},
+		},
+		{
+			name:   "no requests and nothing applied",
+			fields: map[string]interface{}{made: 0.0, successful: 0.0, remaining: 500.0, applied: 0.0},
+		},
+		{
+			name:   "missing counters",
+			fields: map[string]interface{}{made: 10.0, remaining: 500.0},
+		},
+	}
+	for _, tt := range tests {
+		deriveReplicationLatency(nil, nil, object, tt.fields)
+		for _, field := range []string{"replication_sync_success_percent", "replication_latency_seconds"} {
+			want, ok := tt.want[field]
+			if !ok {
+				require.NotContains(t, tt.fields, field, tt.name)
+				continue
+			}
+			require.InDelta(t, want, tt.fields[field], 1e-9, "%s: %s", tt.name, field)
+		}
+	}
+
+	// 原始值不是格式化后的速率，不计算派生字段
+	raw := &ObjectConfig{UseRawValues: true}
+	fields := map[string]interface{}{
+		raw.fieldName("DRA Sync Requests Made"):       10.0,
+		raw.fieldName("DRA Sync Requests Successful"): 5.0,
+	}
+	deriveReplicationLatency(nil, nil, raw, fields)
+	require.NotContains(t, fields, "replication_sync_success_percent")
+}
@@ -0,0 +1,171 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// processIDCounter Process 对象中进程 PID 的计数器。
+const processIDCounter = "ID Process"
+
+// objectHostKey 按对象的标识标识一个主机上的一个对象，Reload 重新分配对象后配置未变化的对象仍对应同一个键。
+type objectHostKey struct {
+	computer string
+	id       objectID
+}
+
+// serviceProcesses 记录刷新计数器时解析得到的服务进程，PID 到服务名称（多个服务共享进程时以逗号分隔）的映射。
+type serviceProcesses struct {
+	lock sync.Mutex
+	pids map[objectHostKey]map[uint32]string
+}
+
+func (s *serviceProcesses) set(key objectHostKey, pids map[uint32]string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.pids == nil {
+		s.pids = make(map[objectHostKey]map[uint32]string)
+	}
+	s.pids[key] = pids
+}
+
+func (s *serviceProcesses) get(key objectHostKey) map[uint32]string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.pids[key]
+}
+
+// validateServices 校验所有对象的 Services 配置。
+func (m *WinPerfCounters) validateServices(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].validateServices(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateServices 校验对象的 Services 配置，只有 Process 对象可以按服务选择实例。
+func (o *ObjectConfig) validateServices() error {
+	if len(o.Services) == 0 {
+		return nil
+	}
+	if !strings.EqualFold(o.ObjectName, "Process") {
+		return fmt.Errorf("services of object %q require the Process object", o.ObjectName)
+	}
+	if !o.usesPDH() {
+		return fmt.Errorf("services of object %q are only supported by the pdh provider", o.ObjectName)
+	}
+	return nil
+}
+
+// openServiceManager 连接主机 computer 的服务控制管理器，使用完毕后需要调用 windows.CloseServiceHandle。
+func openServiceManager(computer string) (windows.Handle, error) {
+	var machine *uint16
+	if computer != "localhost" {
+		var err error
+		if machine, err = windows.UTF16PtrFromString(computer); err != nil {
+			return 0, err
+		}
+	}
+	return windows.OpenSCManager(machine, nil, windows.SC_MANAGER_CONNECT)
+}
+
+// queryServiceProcesses 通过主机 computer 的服务控制管理器查询服务的进程 PID，未运行的服务被忽略。
+func queryServiceProcesses(computer string, services []string) (map[uint32]string, error) {
+	manager, err := openServiceManager(computer)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseServiceHandle(manager)
+
+	pids := make(map[uint32]string, len(services))
+	var errs []error
+	for _, name := range services {
+		pid, err := queryServiceProcess(manager, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("querying service %q failed: %w", name, err))
+			continue
+		}
+		if pid == 0 {
+			continue
+		}
+		if existing, ok := pids[pid]; ok {
+			pids[pid] = existing + "," + name
+		} else {
+			pids[pid] = name
+		}
+	}
+	return pids, errors.Join(errs...)
+}
+
+// queryServiceProcess 返回服务的进程 PID，服务未运行时返回 0。
+func queryServiceProcess(manager windows.Handle, name string) (uint32, error) {
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+	service, err := windows.OpenService(manager, namePtr, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseServiceHandle(service)
+
+	var status windows.SERVICE_STATUS_PROCESS
+	var needed uint32
+	//nolint:gosec // G103: Valid use of unsafe call to pass status
+	err = windows.QueryServiceStatusEx(service, windows.SC_STATUS_PROCESS_INFO, (*byte)(unsafe.Pointer(&status)), uint32(unsafe.Sizeof(status)), &needed)
+	if err != nil {
+		return 0, err
+	}
+	return status.ProcessId, nil
+}
+
+// resolveServices 在刷新计数器时解析对象配置的服务当前的进程，查询失败的服务在日志中给出警告。
+func (m *WinPerfCounters) resolveServices(object *ObjectConfig, computer string) {
+	pids, err := queryServiceProcesses(computer, object.Services)
+	if err != nil {
+		m.Log.Warnf("Resolving services of host %q failed: %v", computer, err)
+	}
+	m.serviceProcesses.set(objectHostKey{computer: computer, id: object.id}, pids)
+}
+
+// serviceProcess 返回实例组对应的服务名称，对象未配置 Services 时 ok 为 true 且名称为空，
+// 实例不是所配置服务的进程时 ok 为 false。
+func (m *WinPerfCounters) serviceProcess(hostInfo *hostCountersInfo, object *ObjectConfig, fields map[string]interface{}) (service string, ok bool) {
+	if object == nil || len(object.Services) == 0 {
+		return "", true
+	}
+	pid, ok := toFloat(fields[object.fieldName(processIDCounter)])
+	if !ok {
+		return "", false
+	}
+	service, ok = m.serviceProcesses.get(objectHostKey{computer: hostInfo.computer, id: object.id})[uint32(pid)]
+	return service, ok
+}
+
+// filterServiceProcesses 丢弃配置了 Services 的对象中不属于这些服务的进程实例。
+func (m *WinPerfCounters) filterServiceProcesses(hostInfo *hostCountersInfo, collectedFields fieldGrouping, groupObjects map[instanceGrouping]*ObjectConfig) {
+	for grouping, fields := range collectedFields {
+		if _, ok := m.serviceProcess(hostInfo, groupObjects[grouping], fields); !ok {
+			delete(collectedFields, grouping)
+			delete(groupObjects, grouping)
+		}
+	}
+}
+
+// applyServiceTag 为服务进程的实例添加 service 标签。
+func (m *WinPerfCounters) applyServiceTag(hostInfo *hostCountersInfo, object *ObjectConfig, fields map[string]interface{}, tags map[string]string) {
+	if service, _ := m.serviceProcess(hostInfo, object, fields); service != "" {
+		tags["service"] = service
+	}
+}
@@ -0,0 +1,34 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceProcessAfterReload(t *testing.T) {
+	objects := []ObjectConfig{{ObjectName: "Process", Services: []string{"W3SVC"}, Counters: []string{processIDCounter}}}
+	require.NoError(t, assignObjectIDs(objects))
+	m := &WinPerfCounters{}
+	m.serviceProcesses.set(objectHostKey{computer: "localhost", id: objects[0].id}, map[uint32]string{42: "W3SVC"})
+
+	// Reload 重新分配对象后，配置未变化的对象仍使用刷新时解析的服务进程
+	reloaded := slices.Clone(objects)
+	require.NoError(t, assignObjectIDs(reloaded))
+	hostInfo := &hostCountersInfo{computer: "localhost"}
+	fields := map[string]interface{}{reloaded[0].fieldName(processIDCounter): 42.0}
+	service, ok := m.serviceProcess(hostInfo, &reloaded[0], fields)
+	require.True(t, ok)
+	require.Equal(t, "W3SVC", service)
+
+	fields[reloaded[0].fieldName(processIDCounter)] = 7.0
+	_, ok = m.serviceProcess(hostInfo, &reloaded[0], fields)
+	require.False(t, ok)
+
+	service, ok = m.serviceProcess(hostInfo, nil, fields)
+	require.True(t, ok)
+	require.Empty(t, service)
+}
@@ -0,0 +1,100 @@
+package win_perf_counters
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syntheticInstances 模拟模式下通配符实例展开得到的实例名称。
+var syntheticInstances = []string{"_Total", "0", "1", "2", "3"}
+
+// syntheticSeries 保存一条合成序列的状态。
+type syntheticSeries struct {
+	// walk 随机游走的当前值。
+	walk float64
+	// raw 原始值的累计量。
+	raw float64
+	// last 上一次生成值的时间。
+	last time.Time
+}
+
+// syntheticGenerator 为模拟模式生成看起来合理的计数器数据：
+// 按序列名称的哈希值确定生成方式（正弦波或随机游走）、量级和相位，相同名称的序列在每次运行中形态一致。
+type syntheticGenerator struct {
+	lock   sync.Mutex
+	series map[string]*syntheticSeries
+}
+
+// value 生成名为 key 的序列在 t 时刻的格式化值，counterName 以 % 开头时取值范围为 0~100。
+func (g *syntheticGenerator) value(key, counterName string, t time.Time) float64 {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.next(key, counterName, t)
+}
+
+// raw 生成名为 key 的序列在 t 时刻的原始值，为格式化值对时间的累计，单调递增。
+func (g *syntheticGenerator) raw(key, counterName string, t time.Time) int64 {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	s := g.state(key)
+	last := s.last
+	v := g.next(key, counterName, t)
+	if !last.IsZero() && t.After(last) {
+		s.raw += v * t.Sub(last).Seconds()
+	}
+	return int64(s.raw)
+}
+
+// state 返回序列的状态，调用方需持有 lock。
+func (g *syntheticGenerator) state(key string) *syntheticSeries {
+	if g.series == nil {
+		g.series = make(map[string]*syntheticSeries)
+	}
+	s, ok := g.series[key]
+	if !ok {
+		s = &syntheticSeries{}
+		g.series[key] = s
+	}
+	return s
+}
+
+// next 生成下一个值，调用方需持有 lock。
+func (g *syntheticGenerator) next(key, counterName string, t time.Time) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	hash := h.Sum64()
+
+	percent := strings.HasPrefix(counterName, "%")
+	base := math.Pow(10, float64(hash%6))
+	if percent {
+		base = 10 + float64(hash%60)
+	}
+	amplitude := base / 2
+
+	s := g.state(key)
+	var v float64
+	if hash%2 == 0 {
+		period := float64(60 + hash%240)
+		phase := float64(hash%360) * math.Pi / 180
+		v = base + amplitude*math.Sin(2*math.Pi*float64(t.Unix())/period+phase)
+	} else {
+		if s.last.IsZero() {
+			s.walk = base
+		}
+		s.walk += (rand.Float64()*2 - 1) * amplitude / 10 //nolint:gosec // G404: synthetic data does not need a secure random source
+		v = s.walk
+	}
+	s.last = t
+
+	if percent {
+		v = math.Min(v, 100)
+	}
+	v = math.Max(v, 0)
+	s.walk = v
+	return v
+}
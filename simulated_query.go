@@ -0,0 +1,337 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errUnknownSimulatedCounter = errors.New("unknown simulated counter")
+
+// simulatedQueryCreator 创建生成合成数据的 PerformanceQuery，用于 Simulate 模式。
+type simulatedQueryCreator struct {
+	generator *syntheticGenerator
+}
+
+func (c simulatedQueryCreator) newPerformanceQuery(string, uint32) PerformanceQuery {
+	return &simulatedQuery{generator: c.generator}
+}
+
+// simulatedQuery 是不访问 PDH 的 PerformanceQuery 实现，计数器的值由 syntheticGenerator 生成。
+type simulatedQuery struct {
+	generator *syntheticGenerator
+
+	lock     sync.Mutex
+	open     bool
+	next     pdhCounterHandle
+	counters map[pdhCounterHandle]string
+	userData map[pdhCounterHandle]uintptr
+	now      time.Time
+	stats    queryStats
+}
+
+func (q *simulatedQuery) Open() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.open = true
+	q.counters = make(map[pdhCounterHandle]string)
+	q.userData = make(map[pdhCounterHandle]uintptr)
+	q.now = time.Now()
+	return nil
+}
+
+func (q *simulatedQuery) Close() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.open = false
+	q.counters = nil
+	q.userData = nil
+	q.stats.closed()
+	return nil
+}
+
+func (q *simulatedQuery) AddCounterToQuery(counterPath string) (pdhCounterHandle, error) {
+	return q.AddCounterToQueryWithUserData(counterPath, 0)
+}
+
+func (q *simulatedQuery) AddCounterToQueryWithUserData(counterPath string, userData uintptr) (pdhCounterHandle, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if !q.open {
+		return 0, errUninitializedQuery
+	}
+	if _, _, _, _, err := extractCounterInfoFromCounterPath(counterPath); err != nil {
+		return 0, err
+	}
+	q.next++
+	q.counters[q.next] = counterPath
+	if userData != 0 {
+		q.userData[q.next] = userData
+	}
+	q.stats.added()
+	return q.next, nil
+}
+
+func (q *simulatedQuery) MustAddCounterToQuery(counterPath string) pdhCounterHandle {
+	counterHandle, err := q.AddCounterToQuery(counterPath)
+	if err != nil {
+		panic(err)
+	}
+	return counterHandle
+}
+
+func (q *simulatedQuery) AddEnglishCounterToQuery(counterPath string) (pdhCounterHandle, error) {
+	return q.AddCounterToQuery(counterPath)
+}
+
+func (q *simulatedQuery) AddEnglishCounterToQueryWithUserData(counterPath string, userData uintptr) (pdhCounterHandle, error) {
+	return q.AddCounterToQueryWithUserData(counterPath, userData)
+}
+
+func (q *simulatedQuery) RemoveCounter(counterHandle pdhCounterHandle) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if !q.open {
+		return errUninitializedQuery
+	}
+	delete(q.counters, counterHandle)
+	delete(q.userData, counterHandle)
+	q.stats.removed(counterHandle)
+	return nil
+}
+
+func (q *simulatedQuery) GetCounterPath(counterHandle pdhCounterHandle) (string, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	path, ok := q.counters[counterHandle]
+	if !ok {
+		return "", errUnknownSimulatedCounter
+	}
+	return path, nil
+}
+
+// GetCounterType 计数类计数器（名称以 Count 结尾）返回 PERF_COUNTER_RAWCOUNT，其它返回 PERF_COUNTER_COUNTER。
+func (q *simulatedQuery) GetCounterType(counterHandle pdhCounterHandle) (uint32, error) {
+	path, err := q.GetCounterPath(counterHandle)
+	if err != nil {
+		return 0, err
+	}
+	if strings.HasSuffix(path, "Count") {
+		return perfCounterRawcount, nil
+	}
+	return perfCounterCounter, nil
+}
+
+func (q *simulatedQuery) GetCounterMeta(counterHandle pdhCounterHandle) (CounterMeta, error) {
+	path, err := q.GetCounterPath(counterHandle)
+	if err != nil {
+		return CounterMeta{}, err
+	}
+	counterType, err := q.GetCounterType(counterHandle)
+	if err != nil {
+		return CounterMeta{}, err
+	}
+	q.lock.Lock()
+	userData := q.userData[counterHandle]
+	q.lock.Unlock()
+	return CounterMeta{
+		Path:        path,
+		Type:        counterType,
+		TypeClass:   counterTypeClass(counterType),
+		ExplainText: "Simulated counter.",
+		UserData:    userData,
+	}, nil
+}
+
+// ExpandWildCardPath 将实例中的通配符展开为 syntheticInstances，计数器名称保持不变。
+func (q *simulatedQuery) ExpandWildCardPath(counterPath string) ([]string, error) {
+	return q.ExpandWildCardPathWithFlags(counterPath, 0)
+}
+
+// ExpandWildCardPathWithFlags 与 ExpandWildCardPath 相同，flags 包含 pdhNoExpandInstances 时不展开实例。
+func (*simulatedQuery) ExpandWildCardPathWithFlags(counterPath string, flags uint32) ([]string, error) {
+	computer, object, instance, counter, err := extractCounterInfoFromCounterPath(counterPath)
+	if err != nil {
+		return nil, err
+	}
+	if flags&pdhNoExpandInstances != 0 || !strings.ContainsAny(instance, "*?") {
+		return []string{counterPath}, nil
+	}
+	paths := make([]string, 0, len(syntheticInstances))
+	for _, name := range syntheticInstances {
+		paths = append(paths, formatPath(computer, object, name, counter))
+	}
+	return paths, nil
+}
+
+// sample 返回计数器路径及当前的采样时间。
+func (q *simulatedQuery) sample(hCounter pdhCounterHandle) (string, string, time.Time, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	path, ok := q.counters[hCounter]
+	if !ok {
+		return "", "", time.Time{}, q.stats.counterError(hCounter, errUnknownSimulatedCounter)
+	}
+	_, _, _, counter, err := extractCounterInfoFromCounterPath(path)
+	return path, counter, q.now, q.stats.counterError(hCounter, err)
+}
+
+func (q *simulatedQuery) GetRawCounterValue(hCounter pdhCounterHandle) (int64, error) {
+	path, counter, now, err := q.sample(hCounter)
+	if err != nil {
+		return 0, err
+	}
+	return q.generator.raw(path, counter, now), nil
+}
+
+func (q *simulatedQuery) GetRawCounterValueWithTime(hCounter pdhCounterHandle) (int64, time.Time, error) {
+	path, counter, now, err := q.sample(hCounter)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return q.generator.raw(path, counter, now), now, nil
+}
+
+// GetRawCounterSample 返回合成的原始值，FileTime 为采样时间对应的 FILETIME。
+func (q *simulatedQuery) GetRawCounterSample(hCounter pdhCounterHandle) (RawSample, error) {
+	path, counter, now, err := q.sample(hCounter)
+	if err != nil {
+		return RawSample{}, err
+	}
+	return RawSample{
+		FirstValue: q.generator.raw(path, counter, now),
+		FileTime:   uint64(now.UnixNano()/100 + epochDifferenceMicros*10),
+		Time:       now,
+	}, nil
+}
+
+// CalculateFormattedFromRaw 将两个合成原始值之差按每秒速率计算，时间相同时返回较新的原始值。
+func (*simulatedQuery) CalculateFormattedFromRaw(_ pdhCounterHandle, oldSample, newSample RawSample) (float64, error) {
+	if newSample.FileTime <= oldSample.FileTime {
+		return float64(newSample.FirstValue), nil
+	}
+	seconds := float64(newSample.FileTime-oldSample.FileTime) / 1e7
+	return float64(newSample.FirstValue-oldSample.FirstValue) / seconds, nil
+}
+
+func (q *simulatedQuery) GetFormattedCounterValueLong(hCounter pdhCounterHandle) (int32, error) {
+	v, err := q.GetFormattedCounterValueDouble(hCounter)
+	return int32(v), err
+}
+
+func (q *simulatedQuery) GetFormattedCounterValueLarge(hCounter pdhCounterHandle) (int64, error) {
+	v, err := q.GetFormattedCounterValueDouble(hCounter)
+	return int64(v), err
+}
+
+func (q *simulatedQuery) GetFormattedCounterValueDouble(hCounter pdhCounterHandle) (float64, error) {
+	path, counter, now, err := q.sample(hCounter)
+	if err != nil {
+		return 0, err
+	}
+	return q.generator.value(path, counter, now), nil
+}
+
+// instancePaths 返回计数器对应的各实例名称及其路径，实例含通配符时展开为 syntheticInstances。
+func (q *simulatedQuery) instancePaths(hCounter pdhCounterHandle) (names []string, paths []string, counter string, now time.Time, err error) {
+	path, counter, now, err := q.sample(hCounter)
+	if err != nil {
+		return nil, nil, "", now, err
+	}
+	paths, err = q.ExpandWildCardPath(path)
+	if err != nil {
+		return nil, nil, "", now, err
+	}
+	for _, p := range paths {
+		_, _, instance, _, err := extractCounterInfoFromCounterPath(p)
+		if err != nil {
+			return nil, nil, "", now, err
+		}
+		names = append(names, instance)
+	}
+	return names, paths, counter, now, nil
+}
+
+func (q *simulatedQuery) GetRawCounterArray(hCounter pdhCounterHandle) ([]counterValue, error) {
+	names, paths, counter, now, err := q.instancePaths(hCounter)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]counterValue, 0, len(paths))
+	for i, path := range paths {
+		values = append(values, counterValue{Name: names[i], Value: q.generator.raw(path, counter, now), Timestamp: now})
+	}
+	return values, nil
+}
+
+func (q *simulatedQuery) GetFormattedCounterArrayLong(hCounter pdhCounterHandle) ([]longValue, error) {
+	doubles, err := q.GetFormattedCounterArrayDouble(hCounter)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]longValue, 0, len(doubles))
+	for _, v := range doubles {
+		values = append(values, longValue{Name: v.Name, Value: int32(v.Value)})
+	}
+	return values, nil
+}
+
+func (q *simulatedQuery) GetFormattedCounterArrayLarge(hCounter pdhCounterHandle) ([]largeValue, error) {
+	doubles, err := q.GetFormattedCounterArrayDouble(hCounter)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]largeValue, 0, len(doubles))
+	for _, v := range doubles {
+		values = append(values, largeValue{Name: v.Name, Value: int64(v.Value)})
+	}
+	return values, nil
+}
+
+func (q *simulatedQuery) GetFormattedCounterArrayDouble(hCounter pdhCounterHandle) ([]doubleValue, error) {
+	names, paths, counter, now, err := q.instancePaths(hCounter)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]doubleValue, 0, len(paths))
+	for i, path := range paths {
+		values = append(values, doubleValue{Name: names[i], Value: q.generator.value(path, counter, now)})
+	}
+	return values, nil
+}
+
+func (q *simulatedQuery) CollectData() error {
+	_, err := q.CollectDataWithTime()
+	return err
+}
+
+func (q *simulatedQuery) CollectDataWithTime() (time.Time, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if !q.open {
+		return time.Now(), errUninitializedQuery
+	}
+	q.now = time.Now()
+	q.stats.collected(0)
+	return q.now, nil
+}
+
+func (*simulatedQuery) Capabilities() Capabilities {
+	return Capabilities{AddEnglishCounter: true, CollectDataWithTime: true}
+}
+
+func (q *simulatedQuery) Stats() QueryStats {
+	return q.stats.snapshot()
+}
+
+func (*simulatedQuery) IsVistaOrNewer() bool {
+	return true
+}
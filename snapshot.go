@@ -0,0 +1,74 @@
+package win_perf_counters
+
+import (
+	"maps"
+	"time"
+)
+
+// Snapshot 一次采集输出的全部指标，创建后不再改变。多个独立的读取方（不同的输出、处理器）可以从同一个快照读取
+// 时间点一致的数据，而不必各自触发采集。每次读取都返回指标的副本，读取方修改数据不会影响其它读取方。
+type Snapshot struct {
+	timestamp time.Time
+	metrics   []Metric
+}
+
+// Snapshot 执行一次采集并返回本次输出的全部指标组成的快照。已注册的采集回调仍会照常收到这些指标。
+// 采集部分失败时同时返回快照和错误，快照中包含成功采集的指标。
+func (m *WinPerfCounters) Snapshot() (*Snapshot, error) {
+	timestamp := time.Now()
+	metrics, err := m.GatherMetrics()
+	return &Snapshot{timestamp: timestamp, metrics: metrics}, err
+}
+
+// Timestamp 返回快照采集开始的时间。
+func (s *Snapshot) Timestamp() time.Time {
+	return s.timestamp
+}
+
+// Len 返回快照中指标的数量。
+func (s *Snapshot) Len() int {
+	return len(s.metrics)
+}
+
+// Metrics 返回快照中所有指标的副本。
+func (s *Snapshot) Metrics() []Metric {
+	return s.Select(nil)
+}
+
+// Select 返回快照中满足 predicate 的指标的副本，predicate 为 nil 时返回全部指标。
+func (s *Snapshot) Select(predicate CollectPredicate) []Metric {
+	metrics := make([]Metric, 0, len(s.metrics))
+	for _, metric := range s.metrics {
+		if predicate == nil || predicate(metric.Measurement, metric.Tags) {
+			metrics = append(metrics, metric.clone())
+		}
+	}
+	return metrics
+}
+
+// ByMeasurement 返回按测量名称分组的全部指标的副本，所有指标的 Timestamp 统一为快照的 Timestamp，
+// 便于跨计数器计算派生值（例如磁盘队列长度与 IOPS）时得到同一逻辑时刻的一致视图，而不依赖回调的先后顺序。
+func (s *Snapshot) ByMeasurement() map[string][]Metric {
+	result := make(map[string][]Metric)
+	for _, metric := range s.Metrics() {
+		metric.Timestamp = s.timestamp
+		result[metric.Measurement] = append(result[metric.Measurement], metric)
+	}
+	return result
+}
+
+// Replay 将快照中满足 predicate 的指标依次交给 collectFunc，predicate 为 nil 时交给全部指标，
+// 用于将同一快照分发给按回调方式工作的输出。
+func (s *Snapshot) Replay(predicate CollectPredicate, collectFunc CollectFunc) {
+	for _, metric := range s.Select(predicate) {
+		collectFunc(metric.Measurement, metric.Fields, metric.Tags, metric.Timestamp)
+	}
+}
+
+// clone 返回指标的副本，标签、字段和元数据映射均为新的实例。
+func (metric Metric) clone() Metric {
+	metric.Tags = maps.Clone(metric.Tags)
+	metric.Fields = maps.Clone(metric.Fields)
+	metric.Metadata = maps.Clone(metric.Metadata)
+	return metric
+}
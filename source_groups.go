@@ -0,0 +1,135 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// validateSourceGroups 校验 SourceGroups：每个主机最多属于一个分组，日志数据源不能分组。
+func (m *WinPerfCounters) validateSourceGroups() error {
+	seen := make(map[string]bool)
+	for i, group := range m.SourceGroups {
+		for _, computer := range group {
+			if logSourcePath(computer) != "" {
+				return fmt.Errorf("log source %q cannot be part of source group %d", computer, i)
+			}
+			key := strings.ToLower(sourceName(computer))
+			if seen[key] {
+				return fmt.Errorf("source %q is part of more than one source group", computer)
+			}
+			seen[key] = true
+		}
+	}
+	return nil
+}
+
+// sourceName 返回主机在 hostCounters 中使用的名称，空字符串表示本机。
+func sourceName(computer string) string {
+	if computer == "" {
+		return "localhost"
+	}
+	return computer
+}
+
+// sourceGroup 返回主机 computer 所属分组的序号，不属于任何分组时 ok 为 false。
+func (m *WinPerfCounters) sourceGroup(computer string) (group int, ok bool) {
+	for i, sources := range m.SourceGroups {
+		for _, source := range sources {
+			if strings.EqualFold(sourceName(source), computer) {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// newHostQuery 创建主机 computer 的查询，属于 SourceGroups 分组的主机共用本次刷新中为该分组创建的查询。
+func (m *WinPerfCounters) newHostQuery(computer string) PerformanceQuery {
+	group, ok := m.sourceGroup(computer)
+	if !ok {
+		return m.queryCreator.newPerformanceQuery(computer, uint32(m.MaxBufferSize))
+	}
+	if query, ok := m.sharedQueries[group]; ok {
+		return query
+	}
+	if m.sharedQueries == nil {
+		m.sharedQueries = make(map[int]*sharedQuery)
+	}
+	query := &sharedQuery{PerformanceQuery: m.queryCreator.newPerformanceQuery(computer, uint32(m.MaxBufferSize))}
+	m.sharedQueries[group] = query
+	return query
+}
+
+// sharedQuery 分组中的多个主机共用的查询，计数器路径带有主机名。每个主机各自调用一次 Open 与 Close，
+// 第一次 Open 时打开查询，最后一次 Close 时关闭查询；每次采集只调用一次 CollectQueryData。
+type sharedQuery struct {
+	PerformanceQuery
+
+	lock sync.Mutex
+	// refs 已打开该查询的主机数量。
+	refs int
+	// collected 是否已完成 cycle 轮的采集。
+	collected bool
+	// cycle 最近一次采集的轮次。
+	cycle uint64
+	// timestamp 与 err 为最近一次采集的结果，同一轮的其它主机直接使用。
+	timestamp time.Time
+	err       error
+}
+
+func (q *sharedQuery) Open() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.refs == 0 {
+		if err := q.PerformanceQuery.Open(); err != nil {
+			return err
+		}
+	}
+	q.refs++
+	return nil
+}
+
+func (q *sharedQuery) Close() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.refs == 0 {
+		return nil
+	}
+	q.refs--
+	if q.refs > 0 {
+		return nil
+	}
+	q.collected = false
+	return q.PerformanceQuery.Close()
+}
+
+func (q *sharedQuery) GetCounterMeta(counterHandle pdhCounterHandle) (CounterMeta, error) {
+	return counterMeta(q.PerformanceQuery, counterHandle)
+}
+
+func (q *sharedQuery) ExpandWildCardPathWithFlags(counterPath string, flags uint32) ([]string, error) {
+	return expandWildCardPath(q.PerformanceQuery, counterPath, flags)
+}
+
+// collect 在第 cycle 轮采集中采集一次数据，同一轮中分组内的其它主机直接得到相同的时间戳和错误。
+func (q *sharedQuery) collect(cycle uint64, withTime bool) (time.Time, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.collected && q.cycle == cycle {
+		return q.timestamp, q.err
+	}
+	if withTime {
+		q.timestamp, q.err = q.PerformanceQuery.CollectDataWithTime()
+	} else {
+		q.timestamp, q.err = time.Now(), q.PerformanceQuery.CollectData()
+	}
+	q.collected, q.cycle = true, cycle
+	return q.timestamp, q.err
+}
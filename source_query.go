@@ -0,0 +1,331 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var errUnknownSourceCounter = errors.New("unknown counter")
+
+// WithQueryCreator 使用 creator 创建的 QuerySource 代替 PDH 查询，需在 Init 之前调用；creator 为 nil 时恢复使用 PDH。
+// 启用 Simulate 时 Init 会改用合成数据，creator 不生效。
+func (m *WinPerfCounters) WithQueryCreator(creator QueryCreator) *WinPerfCounters {
+	if creator == nil {
+		m.queryCreator = NewPerformanceQueryCreator()
+		return m
+	}
+	m.queryCreator = sourceQueryCreator{creator: creator}
+	return m
+}
+
+// sourceQueryCreator 为每个主机创建包装 QuerySource 的 PerformanceQuery。
+type sourceQueryCreator struct {
+	creator QueryCreator
+}
+
+func (c sourceQueryCreator) newPerformanceQuery(computer string, _ uint32) PerformanceQuery {
+	return &sourceQuery{source: c.creator(computer)}
+}
+
+// sourceQuery 是由 QuerySource 提供数据的 PerformanceQuery 实现，原始值与格式化值相同。
+type sourceQuery struct {
+	source QuerySource
+
+	lock     sync.Mutex
+	open     bool
+	next     pdhCounterHandle
+	counters map[pdhCounterHandle]string
+	userData map[pdhCounterHandle]uintptr
+	now      time.Time
+	stats    queryStats
+}
+
+func (q *sourceQuery) Open() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.open = true
+	q.counters = make(map[pdhCounterHandle]string)
+	q.userData = make(map[pdhCounterHandle]uintptr)
+	return nil
+}
+
+func (q *sourceQuery) Close() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.open = false
+	q.counters = nil
+	q.userData = nil
+	q.stats.closed()
+	return nil
+}
+
+func (q *sourceQuery) AddCounterToQuery(counterPath string) (pdhCounterHandle, error) {
+	return q.AddCounterToQueryWithUserData(counterPath, 0)
+}
+
+func (q *sourceQuery) AddCounterToQueryWithUserData(counterPath string, userData uintptr) (pdhCounterHandle, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if !q.open {
+		return 0, errUninitializedQuery
+	}
+	if _, _, _, _, err := extractCounterInfoFromCounterPath(counterPath); err != nil {
+		return 0, err
+	}
+	q.next++
+	q.counters[q.next] = counterPath
+	if userData != 0 {
+		q.userData[q.next] = userData
+	}
+	q.stats.added()
+	return q.next, nil
+}
+
+func (q *sourceQuery) MustAddCounterToQuery(counterPath string) pdhCounterHandle {
+	counterHandle, err := q.AddCounterToQuery(counterPath)
+	if err != nil {
+		panic(err)
+	}
+	return counterHandle
+}
+
+func (q *sourceQuery) AddEnglishCounterToQuery(counterPath string) (pdhCounterHandle, error) {
+	return q.AddCounterToQuery(counterPath)
+}
+
+func (q *sourceQuery) AddEnglishCounterToQueryWithUserData(counterPath string, userData uintptr) (pdhCounterHandle, error) {
+	return q.AddCounterToQueryWithUserData(counterPath, userData)
+}
+
+func (q *sourceQuery) RemoveCounter(counterHandle pdhCounterHandle) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if !q.open {
+		return errUninitializedQuery
+	}
+	delete(q.counters, counterHandle)
+	delete(q.userData, counterHandle)
+	q.stats.removed(counterHandle)
+	return nil
+}
+
+func (q *sourceQuery) GetCounterPath(counterHandle pdhCounterHandle) (string, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	path, ok := q.counters[counterHandle]
+	if !ok {
+		return "", errUnknownSourceCounter
+	}
+	return path, nil
+}
+
+// GetCounterType 所有计数器均视为 PERF_COUNTER_RAWCOUNT，即值按原样输出。
+func (q *sourceQuery) GetCounterType(counterHandle pdhCounterHandle) (uint32, error) {
+	if _, err := q.GetCounterPath(counterHandle); err != nil {
+		return 0, err
+	}
+	return perfCounterRawcount, nil
+}
+
+func (q *sourceQuery) GetCounterMeta(counterHandle pdhCounterHandle) (CounterMeta, error) {
+	path, err := q.GetCounterPath(counterHandle)
+	if err != nil {
+		return CounterMeta{}, err
+	}
+	q.lock.Lock()
+	userData := q.userData[counterHandle]
+	q.lock.Unlock()
+	return CounterMeta{
+		Path:      path,
+		Type:      perfCounterRawcount,
+		TypeClass: counterTypeClass(perfCounterRawcount),
+		UserData:  userData,
+	}, nil
+}
+
+func (q *sourceQuery) ExpandWildCardPath(counterPath string) ([]string, error) {
+	return q.source.Expand(counterPath)
+}
+
+// ExpandWildCardPathWithFlags 与 ExpandWildCardPath 相同，flags 包含 pdhNoExpandInstances 时不展开实例。
+func (q *sourceQuery) ExpandWildCardPathWithFlags(counterPath string, flags uint32) ([]string, error) {
+	if flags&pdhNoExpandInstances != 0 {
+		return []string{counterPath}, nil
+	}
+	return q.source.Expand(counterPath)
+}
+
+// sample 返回计数器路径在最近一轮采集中的值及采样时间。
+func (q *sourceQuery) sample(hCounter pdhCounterHandle) (float64, time.Time, error) {
+	q.lock.Lock()
+	path, ok := q.counters[hCounter]
+	now := q.now
+	q.lock.Unlock()
+
+	if !ok {
+		return 0, now, q.stats.counterError(hCounter, errUnknownSourceCounter)
+	}
+	value, err := q.source.Value(path)
+	return value, now, q.stats.counterError(hCounter, err)
+}
+
+func (q *sourceQuery) GetRawCounterValue(hCounter pdhCounterHandle) (int64, error) {
+	value, _, err := q.sample(hCounter)
+	return int64(value), err
+}
+
+func (q *sourceQuery) GetRawCounterValueWithTime(hCounter pdhCounterHandle) (int64, time.Time, error) {
+	value, now, err := q.sample(hCounter)
+	return int64(value), now, err
+}
+
+func (q *sourceQuery) GetRawCounterSample(hCounter pdhCounterHandle) (RawSample, error) {
+	value, now, err := q.sample(hCounter)
+	if err != nil {
+		return RawSample{}, err
+	}
+	return RawSample{
+		FirstValue: int64(value),
+		FileTime:   uint64(now.UnixNano()/100 + epochDifferenceMicros*10),
+		Time:       now,
+	}, nil
+}
+
+// CalculateFormattedFromRaw 原始值即格式化值，直接返回较新的原始值。
+func (*sourceQuery) CalculateFormattedFromRaw(_ pdhCounterHandle, _, newSample RawSample) (float64, error) {
+	return float64(newSample.FirstValue), nil
+}
+
+func (q *sourceQuery) GetFormattedCounterValueLong(hCounter pdhCounterHandle) (int32, error) {
+	value, _, err := q.sample(hCounter)
+	return int32(value), err
+}
+
+func (q *sourceQuery) GetFormattedCounterValueLarge(hCounter pdhCounterHandle) (int64, error) {
+	value, _, err := q.sample(hCounter)
+	return int64(value), err
+}
+
+func (q *sourceQuery) GetFormattedCounterValueDouble(hCounter pdhCounterHandle) (float64, error) {
+	value, _, err := q.sample(hCounter)
+	return value, err
+}
+
+// instanceValues 展开计数器路径，返回各实例名称及其值，读取失败的实例被跳过。
+func (q *sourceQuery) instanceValues(hCounter pdhCounterHandle) ([]string, []float64, time.Time, error) {
+	q.lock.Lock()
+	path, ok := q.counters[hCounter]
+	now := q.now
+	q.lock.Unlock()
+
+	if !ok {
+		return nil, nil, now, errUnknownSourceCounter
+	}
+	paths, err := q.source.Expand(path)
+	if err != nil {
+		return nil, nil, now, err
+	}
+	names := make([]string, 0, len(paths))
+	values := make([]float64, 0, len(paths))
+	for _, p := range paths {
+		_, _, instance, _, err := extractCounterInfoFromCounterPath(p)
+		if err != nil {
+			return nil, nil, now, err
+		}
+		value, err := q.source.Value(p)
+		if err != nil {
+			continue
+		}
+		names = append(names, instance)
+		values = append(values, value)
+	}
+	return names, values, now, nil
+}
+
+func (q *sourceQuery) GetRawCounterArray(hCounter pdhCounterHandle) ([]counterValue, error) {
+	names, values, now, err := q.instanceValues(hCounter)
+	if err != nil {
+		return nil, err
+	}
+	array := make([]counterValue, 0, len(values))
+	for i, value := range values {
+		array = append(array, counterValue{Name: names[i], Value: int64(value), Timestamp: now})
+	}
+	return array, nil
+}
+
+func (q *sourceQuery) GetFormattedCounterArrayLong(hCounter pdhCounterHandle) ([]longValue, error) {
+	names, values, _, err := q.instanceValues(hCounter)
+	if err != nil {
+		return nil, err
+	}
+	array := make([]longValue, 0, len(values))
+	for i, value := range values {
+		array = append(array, longValue{Name: names[i], Value: int32(value)})
+	}
+	return array, nil
+}
+
+func (q *sourceQuery) GetFormattedCounterArrayLarge(hCounter pdhCounterHandle) ([]largeValue, error) {
+	names, values, _, err := q.instanceValues(hCounter)
+	if err != nil {
+		return nil, err
+	}
+	array := make([]largeValue, 0, len(values))
+	for i, value := range values {
+		array = append(array, largeValue{Name: names[i], Value: int64(value)})
+	}
+	return array, nil
+}
+
+func (q *sourceQuery) GetFormattedCounterArrayDouble(hCounter pdhCounterHandle) ([]doubleValue, error) {
+	names, values, _, err := q.instanceValues(hCounter)
+	if err != nil {
+		return nil, err
+	}
+	array := make([]doubleValue, 0, len(values))
+	for i, value := range values {
+		array = append(array, doubleValue{Name: names[i], Value: value})
+	}
+	return array, nil
+}
+
+func (q *sourceQuery) CollectData() error {
+	_, err := q.CollectDataWithTime()
+	return err
+}
+
+func (q *sourceQuery) CollectDataWithTime() (time.Time, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if !q.open {
+		return time.Now(), errUninitializedQuery
+	}
+	start := time.Now()
+	now, err := q.source.Collect()
+	if err != nil {
+		return time.Now(), err
+	}
+	q.stats.collected(time.Since(start))
+	q.now = now
+	return now, nil
+}
+
+func (*sourceQuery) Capabilities() Capabilities {
+	return Capabilities{AddEnglishCounter: true, CollectDataWithTime: true}
+}
+
+func (q *sourceQuery) Stats() QueryStats {
+	return q.stats.snapshot()
+}
+
+func (*sourceQuery) IsVistaOrNewer() bool {
+	return true
+}
@@ -0,0 +1,145 @@
+//go:build !windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WithQueryCreator 使用 creator 创建的 QuerySource 采集配置的对象，需在 Init 之前调用；creator 为 nil 时不再从数据源采集。
+// 启用 Simulate 时使用合成数据，creator 不生效。
+func (w *WinPerfCounters) WithQueryCreator(creator QueryCreator) *WinPerfCounters {
+	w.queryCreator = creator
+	w.querySources = nil
+	return w
+}
+
+// gatherSources 对每个主机的数据源采集一轮数据并输出配置的对象，采集失败的主机被跳过。
+func (w *WinPerfCounters) gatherSources() error {
+	if w.querySources == nil {
+		w.querySources = make(map[string]QuerySource)
+	}
+	var errs []error
+	collected := make(map[string]time.Time)
+	failed := make(map[string]bool)
+	for _, object := range w.Object {
+		measurement := objectMeasurement(object)
+		for _, host := range w.objectHosts(object) {
+			if failed[host] {
+				continue
+			}
+			timestamp, ok := collected[host]
+			if !ok {
+				source, exists := w.querySources[host]
+				if !exists {
+					source = w.queryCreator(host)
+					w.querySources[host] = source
+				}
+				var err error
+				if timestamp, err = source.Collect(); err != nil {
+					failed[host] = true
+					errs = append(errs, fmt.Errorf("collecting from %q failed: %w", host, err))
+					continue
+				}
+				collected[host] = timestamp
+			}
+			errs = append(errs, w.gatherSourceObject(object, host, measurement, timestamp))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// gatherSourceObject 读取对象在主机上的计数器并按实例输出，读取失败的计数器被跳过。
+func (w *WinPerfCounters) gatherSourceObject(object ObjectConfig, host, measurement string, timestamp time.Time) error {
+	source := w.querySources[host]
+	instances := object.Instances
+	if len(instances) == 0 {
+		instances = []string{emptyInstance}
+	}
+	var errs []error
+	var order []string
+	fields := make(map[string]map[string]interface{})
+	for _, instance := range instances {
+		for _, counter := range object.Counters {
+			paths, err := source.Expand(sourceCounterPath(host, object.ObjectName, instance, counter))
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, path := range paths {
+				name, counterName := splitSourcePath(path)
+				if name == "_Total" && instance != "_Total" && !object.IncludeTotal {
+					continue
+				}
+				value, err := source.Value(path)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("reading %q failed: %w", path, err))
+					continue
+				}
+				instanceFields, ok := fields[name]
+				if !ok {
+					instanceFields = make(map[string]interface{}, len(object.Counters))
+					fields[name] = instanceFields
+					order = append(order, name)
+				}
+				if object.UseRawValues {
+					instanceFields[sanitizedChars.Replace(counterName)+"_Raw"] = int64(value)
+				} else {
+					instanceFields[sanitizedChars.Replace(counterName)] = value
+				}
+			}
+		}
+	}
+	sourceTag := hostTag(host)
+	for _, name := range order {
+		tags := map[string]string{
+			"objectname": object.ObjectName,
+			"source":     sourceTag,
+		}
+		if name != emptyInstance {
+			tags["instance"] = name
+		}
+		w.collect(measurement, fields[name], tags, timestamp)
+	}
+	return errors.Join(errs...)
+}
+
+// sourceCounterPath 按 PDH 的格式拼接计数器路径，远程主机的路径以 \\computer 开头。
+func sourceCounterPath(host, objectName, instance, counter string) string {
+	path := `\` + objectName + `\` + counter
+	if instance != emptyInstance {
+		path = `\` + objectName + "(" + instance + `)\` + counter
+	}
+	if host != "" && host != "localhost" {
+		path = `\\` + host + path
+	}
+	return path
+}
+
+// splitSourcePath 返回计数器路径中的实例名称与计数器名称，没有实例时实例名称为 emptyInstance。
+func splitSourcePath(path string) (instance string, counter string) {
+	separator := strings.LastIndex(path, `\`)
+	if separator < 0 {
+		return emptyInstance, path
+	}
+	object, counter := path[:separator], path[separator+1:]
+	if !strings.HasSuffix(object, ")") {
+		return emptyInstance, counter
+	}
+	depth := 0
+	for i := len(object) - 1; i >= 0; i-- {
+		switch object[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				return object[i+1 : len(object)-1], counter
+			}
+		}
+	}
+	return emptyInstance, counter
+}
@@ -0,0 +1,151 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// StaleMarker 支持的取值。
+const (
+	// staleMarkerNaN 以 Prometheus 的 staleness NaN 输出序列的全部字段。
+	staleMarkerNaN = "nan"
+	// staleMarkerTombstone 输出一条 stale=true 的墓碑指标。
+	staleMarkerTombstone = "tombstone"
+)
+
+// staleNaN Prometheus 用于标记序列结束的特殊 NaN 值。
+var staleNaN = math.Float64frombits(0x7ff0000000000002)
+
+// staleSeries 记录一条序列最近一次采集到的测量名称、标签和字段名称。
+type staleSeries struct {
+	measurement string
+	tags        map[string]string
+	fields      []string
+	// values 数值字段的取值，仅启用 DataQuality 时记录。
+	values map[string]float64
+	// missing 实例消失时按 ReportMissingInstancesAs 输出的字段，仅对启用了该选项的对象记录。
+	missing map[string]interface{}
+}
+
+// staleTracker 按对象的标识记录各主机、各对象上一次采集到的序列，用于发现消失的实例。
+type staleTracker struct {
+	lock  sync.Mutex
+	hosts map[string]map[objectID]map[string]staleSeries
+}
+
+// seenSeries 本次采集中各对象出现的序列。
+type seenSeries map[*ObjectConfig]map[string]staleSeries
+
+// add 记录本次采集中出现的序列。
+func (s seenSeries) add(object *ObjectConfig, measurement string, fields map[string]interface{}, tags map[string]string, withValues bool) {
+	series, ok := s[object]
+	if !ok {
+		series = make(map[string]staleSeries)
+		s[object] = series
+	}
+	seen := staleSeries{
+		measurement: measurement,
+		tags:        maps.Clone(tags),
+		fields:      slices.Sorted(maps.Keys(fields)),
+	}
+	if withValues {
+		seen.values = make(map[string]float64, len(fields))
+		for field, value := range fields {
+			if v, ok := toFloat(value); ok {
+				seen.values[field] = v
+			}
+		}
+	}
+	if object != nil && object.ReportMissingInstancesAs != "" && object.ReportMissingInstancesAs != missingAsDrop {
+		seen.missing = missingValues(object.ReportMissingInstancesAs, fields)
+	}
+	series[snapshotKey(measurement, tags)] = seen
+}
+
+// update 用本次采集的序列替换主机上本次已采集对象的记录，返回上一次出现而本次消失的序列。
+func (t *staleTracker) update(computer string, due dueObjects, seen seenSeries) []staleSeries {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.hosts == nil {
+		t.hosts = make(map[string]map[objectID]map[string]staleSeries)
+	}
+	objects, ok := t.hosts[computer]
+	if !ok {
+		objects = make(map[objectID]map[string]staleSeries)
+		t.hosts[computer] = objects
+	}
+
+	current := make(map[objectID]map[string]staleSeries, len(seen))
+	for object, series := range seen {
+		current[object.identity()] = series
+	}
+	var gone []staleSeries
+	for id, series := range objects {
+		if !due.has(id) {
+			continue
+		}
+		for key, previous := range series {
+			if _, ok := current[id][key]; !ok {
+				gone = append(gone, previous)
+			}
+		}
+		delete(objects, id)
+	}
+	maps.Copy(objects, current)
+	return gone
+}
+
+// retain 只保留 ids 中的对象的记录，Reload 后配置未变化的对象继续与上一次采集比较。
+func (t *staleTracker) retain(ids map[objectID]bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, objects := range t.hosts {
+		for id := range objects {
+			if !ids[id] {
+				delete(objects, id)
+			}
+		}
+	}
+}
+
+// validateStaleMarker 校验 StaleMarker 配置。
+func (m *WinPerfCounters) validateStaleMarker() error {
+	switch m.StaleMarker {
+	case "", staleMarkerNaN, staleMarkerTombstone:
+		return nil
+	}
+	return fmt.Errorf("invalid StaleMarker %q, expected nan or tombstone", m.StaleMarker)
+}
+
+// emitStaleMarkers 为本次采集中消失的序列输出结束标记，未配置 StaleMarker 时不做任何处理。
+func (m *WinPerfCounters) emitStaleMarkers(computer string, due dueObjects, seen seenSeries, timestamp time.Time) {
+	if m.StaleMarker == "" {
+		return
+	}
+	for _, series := range m.stale.update(computer, due, seen) {
+		if m.StaleMarker == staleMarkerNaN {
+			fields := make(map[string]interface{}, len(series.fields))
+			for _, field := range series.fields {
+				fields[field] = staleNaN
+			}
+			m.emit(series.measurement, fields, series.tags, timestamp)
+			continue
+		}
+
+		measurement := series.measurement
+		tags := series.tags
+		if m.StaleMeasurement != "" {
+			measurement = m.StaleMeasurement
+			tags = maps.Clone(series.tags)
+			tags["measurement"] = series.measurement
+		}
+		m.emit(measurement, map[string]interface{}{"stale": true}, tags, timestamp)
+	}
+}
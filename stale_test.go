@@ -0,0 +1,50 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaleTrackerUpdate(t *testing.T) {
+	objects := []ObjectConfig{{ObjectName: "Process"}, {ObjectName: "Memory"}}
+	require.NoError(t, assignObjectIDs(objects))
+	seenOf := func(object *ObjectConfig, instances ...string) seenSeries {
+		seen := make(seenSeries)
+		for _, instance := range instances {
+			seen.add(object, "win_proc", map[string]interface{}{"value": 1.0}, map[string]string{"instance": instance}, false)
+		}
+		return seen
+	}
+	due := make(dueObjects)
+	due.add(&objects[0])
+
+	var tracker staleTracker
+	require.Empty(t, tracker.update("local", due, seenOf(&objects[0], "a", "b")))
+	gone := tracker.update("local", due, seenOf(&objects[0], "a"))
+	require.Len(t, gone, 1)
+	require.Equal(t, "b", gone[0].tags["instance"])
+
+	// 本次未采集的对象不比较
+	require.Empty(t, tracker.update("local", make(dueObjects), seenOf(&objects[1])))
+	// 其它主机的记录互不影响
+	require.Empty(t, tracker.update("remote", due, seenOf(&objects[0])))
+
+	// Reload 重新分配对象后，配置未变化的对象继续与上一次采集比较
+	reloaded := slices.Clone(objects[:1])
+	require.NoError(t, assignObjectIDs(reloaded))
+	tracker.retain(objectIDSet(reloaded))
+	due = make(dueObjects)
+	due.add(&reloaded[0])
+	gone = tracker.update("local", due, seenOf(&reloaded[0]))
+	require.Len(t, gone, 1)
+	require.Equal(t, "a", gone[0].tags["instance"])
+
+	// 已删除或被修改的对象的记录被丢弃
+	require.Empty(t, tracker.update("local", due, seenOf(&reloaded[0], "c")))
+	tracker.retain(map[objectID]bool{})
+	require.Empty(t, tracker.update("local", due, seenOf(&reloaded[0])))
+}
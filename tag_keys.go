@@ -0,0 +1,44 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+)
+
+// validateTagKeyOverrides 校验 TagKeyOverrides 中不存在两个标签改名为同一个名称。
+func (m *WinPerfCounters) validateTagKeyOverrides() error {
+	targets := make(map[string]string, len(m.TagKeyOverrides))
+	for key, target := range m.TagKeyOverrides {
+		if target == "" {
+			continue
+		}
+		if other, ok := targets[target]; ok {
+			return fmt.Errorf("tags %q and %q are both renamed to %q", other, key, target)
+		}
+		targets[target] = key
+	}
+	return nil
+}
+
+// tagKey 返回标签 key 输出时使用的名称，配置为省略时返回空字符串。
+func (m *WinPerfCounters) tagKey(key string) string {
+	if target, ok := m.TagKeyOverrides[key]; ok {
+		return target
+	}
+	return key
+}
+
+// renameTags 按 TagKeyOverrides 改名或省略标签，返回新的映射，未配置时原样返回 tags。
+func (m *WinPerfCounters) renameTags(tags map[string]string) map[string]string {
+	if len(m.TagKeyOverrides) == 0 {
+		return tags
+	}
+	renamed := make(map[string]string, len(tags))
+	for key, value := range tags {
+		if key = m.tagKey(key); key != "" {
+			renamed[key] = value
+		}
+	}
+	return renamed
+}
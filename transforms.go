@@ -0,0 +1,81 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// namedTransforms Transforms 支持的命名换算及其乘数。
+var namedTransforms = map[string]float64{
+	"bytes_to_kb":      1.0 / 1024,
+	"bytes_to_mb":      1.0 / (1024 * 1024),
+	"bytes_to_gb":      1.0 / (1024 * 1024 * 1024),
+	"percent_to_ratio": 1.0 / 100,
+	"ms_to_seconds":    1.0 / 1000,
+	"100ns_to_seconds": 1.0 / 10000000,
+}
+
+// parseTransform 解析 Transforms 中的一项，可以是命名换算（例如 "bytes_to_mb"）、数值（例如 "0.001"）或分数（例如 "1/1048576"），
+// 返回对应的乘数。
+func parseTransform(transform string) (float64, error) {
+	transform = strings.TrimSpace(transform)
+	if scale, ok := namedTransforms[strings.ToLower(transform)]; ok {
+		return scale, nil
+	}
+	numerator, denominator, isFraction := strings.Cut(transform, "/")
+	scale, err := strconv.ParseFloat(strings.TrimSpace(numerator), 64)
+	if err != nil {
+		return 0, fmt.Errorf("expected a named conversion, a number or a fraction, got %q", transform)
+	}
+	if isFraction {
+		d, err := strconv.ParseFloat(strings.TrimSpace(denominator), 64)
+		if err != nil || d == 0 {
+			return 0, fmt.Errorf("invalid denominator in %q", transform)
+		}
+		scale /= d
+	}
+	return scale, nil
+}
+
+// validateTransforms 校验所有对象的 Transforms 配置。
+func (m *WinPerfCounters) validateTransforms(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].validateTransforms(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTransforms 校验对象的 Transforms 配置。
+func (o *ObjectConfig) validateTransforms() error {
+	for counter, transform := range o.Transforms {
+		if _, err := parseTransform(transform); err != nil {
+			return fmt.Errorf("invalid transform for counter %q of object %q: %w", counter, o.ObjectName, err)
+		}
+	}
+	return nil
+}
+
+// applyTransforms 按对象的 Transforms 配置换算计数器的值，例如将字节换算为 MB、将 100 纳秒换算为秒，
+// 计数器的 "_persec" 变化率字段按相同的乘数换算。换算后的值为 float64，之后再按 FieldTypes 转换类型。
+func applyTransforms(object *ObjectConfig, fields map[string]interface{}) {
+	if object == nil || len(object.Transforms) == 0 {
+		return
+	}
+	for counter, transform := range object.Transforms {
+		scale, err := parseTransform(transform)
+		if err != nil {
+			continue
+		}
+		field := object.fieldName(counter)
+		for _, name := range []string{field, field + derivativeSuffix} {
+			if v, ok := toFloat(fields[name]); ok {
+				fields[name] = v * scale
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTransform(t *testing.T) {
+	tests := []struct {
+		transform string
+		want      float64
+		wantErr   string
+	}{
+		{"bytes_to_mb", 1.0 / (1024 * 1024), ""},
+		{"Bytes_To_GB", 1.0 / (1024 * 1024 * 1024), ""},
+		{" ms_to_seconds ", 0.001, ""},
+		{"100ns_to_seconds", 1e-7, ""},
+		{"0.001", 0.001, ""},
+		{"-1", -1, ""},
+		{"1/1048576", 1.0 / 1048576, ""},
+		{" 8 / 1000 ", 0.008, ""},
+		{"kb_to_bytes", 0, `expected a named conversion, a number or a fraction, got "kb_to_bytes"`},
+		{"", 0, "expected a named conversion"},
+		{"1/0", 0, `invalid denominator in "1/0"`},
+		{"1/x", 0, `invalid denominator in "1/x"`},
+	}
+	for _, tt := range tests {
+		got, err := parseTransform(tt.transform)
+		if tt.wantErr != "" {
+			require.ErrorContains(t, err, tt.wantErr, tt.transform)
+			continue
+		}
+		require.NoError(t, err, tt.transform)
+		require.InDelta(t, tt.want, got, 1e-15, tt.transform)
+	}
+}
+
+func TestApplyTransforms(t *testing.T) {
+	object := &ObjectConfig{
+		ObjectName: "Memory",
+		Transforms: map[string]string{
+			"Available Bytes": "bytes_to_mb",
+			"Pages/sec":       "1/2",
+			"Cache Bytes":     "bytes_to_kb",
+		},
+	}
+	fields := map[string]interface{}{
+		"Available_Bytes":        float64(512 * 1024 * 1024),
+		"Available_Bytes_persec": int64(2 * 1024 * 1024),
+		"Pages_persec":           10.0,
+		"Committed_Bytes":        1024.0,
+	}
+	applyTransforms(object, fields)
+	// 变化率字段按相同的乘数换算，没有配置换算或本次没有数据的计数器保持不变
+	require.Equal(t, map[string]interface{}{
+		"Available_Bytes":        512.0,
+		"Available_Bytes_persec": 2.0,
+		"Pages_persec":           5.0,
+		"Committed_Bytes":        1024.0,
+	}, fields)
+
+	fields = map[string]interface{}{"Available_Bytes": 1024.0}
+	applyTransforms(nil, fields)
+	require.Equal(t, map[string]interface{}{"Available_Bytes": 1024.0}, fields)
+}
+
+func TestValidateTransforms(t *testing.T) {
+	object := &ObjectConfig{ObjectName: "Memory", Transforms: map[string]string{"Available Bytes": "bytes_to_mb"}}
+	require.NoError(t, object.validateTransforms())
+
+	object.Transforms["Available Bytes"] = "bytes_to_tb"
+	require.ErrorContains(t, object.validateTransforms(), `invalid transform for counter "Available Bytes" of object "Memory"`)
+}
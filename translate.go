@@ -0,0 +1,206 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// englishPerfNamesKey 存放英文性能对象和计数器名称的注册表项，其 Counter 值为 "索引, 名称" 交替排列的多字符串。
+const englishPerfNamesKey = `SOFTWARE\Microsoft\Windows NT\CurrentVersion\Perflib\009`
+
+// loadEnglishNamesFrom 从 root（本机或远程主机的 HKEY_LOCAL_MACHINE）读取英文名称表。
+func loadEnglishNamesFrom(root registry.Key) (map[uint32]string, error) {
+	key, err := registry.OpenKey(root, englishPerfNamesKey, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Close()
+
+	values, _, err := key.GetStringsValue("Counter")
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uint32]string, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		index, err := strconv.ParseUint(values[i], 10, 32)
+		if err != nil {
+			continue
+		}
+		// 同一索引可能出现多次，保留第一个
+		if _, ok := names[uint32(index)]; !ok {
+			names[uint32(index)] = values[i+1]
+		}
+	}
+	return names, nil
+}
+
+// translateObjectName 在启用 TranslateObjectName 时将主机 computer 上本地化的性能对象名称翻译为英文，
+// 远程主机使用其自身的名称表，无法翻译时保持原样。
+func (m *WinPerfCounters) translateObjectName(computer, objectName string) string {
+	if !m.TranslateObjectName {
+		return objectName
+	}
+	return m.englishPerfName(computer, objectName)
+}
+
+// pdhMaxCounterNameLength PdhLookupPerfNameByIndex 返回的名称的最大长度（字符数）。
+const pdhMaxCounterNameLength = 1024
+
+// perfNameMapping 按主机缓存英文名称与本地化名称之间通过名称索引得到的翻译，键为小写的名称，
+// 无法翻译的名称记为空字符串，避免重复查询。
+type perfNameMapping struct {
+	lock      sync.Mutex
+	localized map[string]map[string]string
+	english   map[string]map[string]string
+}
+
+// get 返回主机上已缓存的翻译，toLocalized 为 true 时查询英文名称到本地化名称的翻译。
+func (p *perfNameMapping) get(computer, name string, toLocalized bool) (string, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	hosts := p.english
+	if toLocalized {
+		hosts = p.localized
+	}
+	translated, ok := hosts[computer][strings.ToLower(name)]
+	return translated, ok
+}
+
+// set 缓存主机上的翻译。
+func (p *perfNameMapping) set(computer, name, translated string, toLocalized bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.localized == nil {
+		p.localized = make(map[string]map[string]string)
+		p.english = make(map[string]map[string]string)
+	}
+	hosts := p.english
+	if toLocalized {
+		hosts = p.localized
+	}
+	if hosts[computer] == nil {
+		hosts[computer] = make(map[string]string)
+	}
+	hosts[computer][strings.ToLower(name)] = translated
+}
+
+// reset 丢弃所有缓存的翻译，计数器注册表重建后名称索引可能变化。
+func (p *perfNameMapping) reset() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.localized = nil
+	p.english = nil
+}
+
+// lookupPerfNameByIndex 返回主机上名称索引对应的本地化名称。
+func lookupPerfNameByIndex(computer string, index uint32) (string, error) {
+	buf := make([]uint16, pdhMaxCounterNameLength)
+	size := uint32(len(buf))
+	if ret := pdhLookupPerfNameByIndex(machineName(computer), index, &buf[0], &size); ret != errorSuccess {
+		return "", newPdhError(ret)
+	}
+	return syscall.UTF16ToString(buf), nil
+}
+
+// translationHost 返回翻译名称时使用的主机，computer 为空时为本机，日志数据源没有名称表。
+func translationHost(computer string) (string, error) {
+	if logSourcePath(computer) != "" {
+		return "", fmt.Errorf("names of log source %q can't be translated", computer)
+	}
+	if computer == "" {
+		return "localhost", nil
+	}
+	return computer, nil
+}
+
+// LocalizedName 返回英文性能对象或计数器名称在主机 computer（为空或 localhost 时为本机）上的本地化名称。
+// 先从主机的英文名称表中得到名称索引，再通过 PdhLookupPerfNameByIndex 取得主机语言的名称，结果按主机缓存。
+func (m *WinPerfCounters) LocalizedName(computer, englishName string) (string, error) {
+	computer, err := translationHost(computer)
+	if err != nil {
+		return "", err
+	}
+	if localized, ok := m.perfNames.get(computer, englishName, true); ok {
+		if localized == "" {
+			return "", fmt.Errorf("no localized name for %q on %q", englishName, computer)
+		}
+		return localized, nil
+	}
+	names, err := m.registryNames.table(computer)
+	if err != nil {
+		return "", err
+	}
+	var indexes []uint32
+	for index, name := range names {
+		if strings.EqualFold(name, englishName) {
+			indexes = append(indexes, index)
+		}
+	}
+	// 同一名称可能对应多个索引，按索引从小到大取第一个能查到的名称
+	slices.Sort(indexes)
+	var localized string
+	for _, index := range indexes {
+		if name, err := lookupPerfNameByIndex(computer, index); err == nil && name != "" {
+			localized = name
+			break
+		}
+	}
+	m.perfNames.set(computer, englishName, localized, true)
+	if localized == "" {
+		return "", fmt.Errorf("no localized name for %q on %q", englishName, computer)
+	}
+	return localized, nil
+}
+
+// EnglishName 返回主机 computer（为空或 localhost 时为本机）上本地化的性能对象或计数器名称对应的英文名称，
+// 通过 PdhLookupPerfIndexByName 得到名称索引后在主机的英文名称表中查找，结果按主机缓存。
+func (m *WinPerfCounters) EnglishName(computer, localizedName string) (string, error) {
+	computer, err := translationHost(computer)
+	if err != nil {
+		return "", err
+	}
+	if english, ok := m.perfNames.get(computer, localizedName, false); ok {
+		if english == "" {
+			return "", fmt.Errorf("no English name for %q on %q", localizedName, computer)
+		}
+		return english, nil
+	}
+	names, err := m.registryNames.table(computer)
+	if err != nil {
+		return "", err
+	}
+	var english string
+	var index uint32
+	if ret := pdhLookupPerfIndexByName(machineName(computer), localizedName, &index); ret == errorSuccess {
+		english = names[index]
+	}
+	m.perfNames.set(computer, localizedName, english, false)
+	if english == "" {
+		return "", fmt.Errorf("no English name for %q on %q", localizedName, computer)
+	}
+	return english, nil
+}
+
+// localizePerfName 返回英文名称在主机上的本地化名称，无法通过名称索引翻译时使用词典，词典中也没有时原样返回。
+func (m *WinPerfCounters) localizePerfName(computer, name string) string {
+	if localized, err := m.LocalizedName(computer, name); err == nil {
+		return localized
+	}
+	return m.localizeName(name)
+}
+
+// englishPerfName 返回主机上本地化名称对应的英文名称，无法翻译时原样返回。
+func (m *WinPerfCounters) englishPerfName(computer, name string) string {
+	if english, err := m.EnglishName(computer, name); err == nil {
+		return english
+	}
+	return name
+}
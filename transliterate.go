@@ -0,0 +1,128 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// latinASCII 常见带变音符号的拉丁字母到 ASCII 的映射。
+var latinASCII = map[rune]string{
+	'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Ä': "Ae", 'Å': "A", 'Æ': "AE", 'Ç': "C",
+	'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E", 'Ì': "I", 'Í': "I", 'Î': "I", 'Ï': "I",
+	'Ð': "D", 'Ñ': "N", 'Ò': "O", 'Ó': "O", 'Ô': "O", 'Õ': "O", 'Ö': "Oe", 'Ø': "O",
+	'Ù': "U", 'Ú': "U", 'Û': "U", 'Ü': "Ue", 'Ý': "Y", 'Þ': "Th", 'ß': "ss",
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "ae", 'å': "a", 'æ': "ae", 'ç': "c",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i",
+	'ð': "d", 'ñ': "n", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "oe", 'ø': "o",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "ue", 'ý': "y", 'þ': "th", 'ÿ': "y",
+	'Ł': "L", 'ł': "l", 'Œ': "OE", 'œ': "oe", 'Š': "S", 'š': "s", 'Ž': "Z", 'ž': "z",
+}
+
+// transliterate 将字符串转换为 ASCII：常见拉丁字母去掉变音符号，其它非 ASCII 字符替换为 "_uXXXX"，
+// 不同的字符不会映射到相同的结果。
+func transliterate(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r < utf8.RuneSelf:
+			b.WriteRune(r)
+		case latinASCII[r] != "":
+			b.WriteString(latinASCII[r])
+		default:
+			fmt.Fprintf(&b, "_u%04X", r)
+		}
+	}
+	return b.String()
+}
+
+// transliterationCheck 记录一批指标中各标签的值转换前后的对应关系，用于发现不同的值转换为相同结果的冲突，
+// 这些值对应的序列在输出端会合并为一条。
+type transliterationCheck map[string]map[string]string
+
+// record 记录标签 key 的值 original 转换为 result，result 已由其它值转换得到时返回该值。
+func (c transliterationCheck) record(key, original, result string) string {
+	results, ok := c[key]
+	if !ok {
+		results = make(map[string]string)
+		c[key] = results
+	}
+	if previous, ok := results[result]; ok && previous != original {
+		return previous
+	}
+	results[result] = original
+	return ""
+}
+
+// transliterationCollisions 记录已经在日志中报告过的冲突，每个冲突只警告一次。
+type transliterationCollisions struct {
+	lock     sync.Mutex
+	reported map[string]bool
+}
+
+// first 判断冲突 key 是否第一次出现。
+func (c *transliterationCollisions) first(key string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.reported[key] {
+		return false
+	}
+	if c.reported == nil {
+		c.reported = make(map[string]bool)
+	}
+	c.reported[key] = true
+	return true
+}
+
+// reportTransliterationCollision 记录 Transliterate 将 first 与 second 转换为相同结果 result 的冲突，
+// 计入 SelfMetrics 的 transliteration_collisions，并在第一次出现时给出警告。
+func (m *WinPerfCounters) reportTransliterationCollision(kind, first, second, result string) {
+	m.stats.incr(map[string]string{}, "transliteration_collisions", 1)
+	if m.transliterationCollisions.first(kind + "\x00" + result) {
+		m.Log.Warnf("Transliterate maps %s %q and %q to the same value %q", kind, first, second, result)
+	}
+}
+
+// applyTransliteration 启用 Transliterate 时将测量名称、字段名称和标签值转换为 ASCII。check 记录同一批指标中标签值的转换，
+// 不同的值转换为相同结果时报告冲突；字段名称转换后与同一指标中的其它字段同名时报告冲突并保留原始名称，不覆盖该字段。
+func (m *WinPerfCounters) applyTransliteration(check transliterationCheck, measurement string, fields map[string]interface{}, tags map[string]string) string {
+	if !m.Transliterate {
+		return measurement
+	}
+	for key, value := range tags {
+		result := transliterate(value)
+		if previous := check.record(key, value, result); previous != "" {
+			m.reportTransliterationCollision("tag "+key+" values", previous, value, result)
+		}
+		tags[key] = result
+	}
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		name := transliterate(key)
+		if name == key {
+			continue
+		}
+		if _, ok := fields[name]; ok {
+			m.reportTransliterationCollision("fields", name, key, name)
+			continue
+		}
+		fields[name] = fields[key]
+		delete(fields, key)
+	}
+	return transliterate(measurement)
+}
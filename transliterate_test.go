@@ -0,0 +1,83 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUTF16ToStringArrayUnicode(t *testing.T) {
+	names := []string{"chrome", "プロセス", "node🔥", "Système", "x"}
+	var buf []uint16
+	for _, name := range names {
+		buf = append(buf, utf16.Encode([]rune(name))...)
+		buf = append(buf, 0)
+	}
+	buf = append(buf, 0)
+
+	require.Equal(t, names, utf16ToStringArray(buf))
+}
+
+func TestUnicodeInstanceSurvivesSanitizing(t *testing.T) {
+	require.Equal(t, "Temps_processeur_(Percent)", sanitizedChars.Replace("Temps processeur (%)"))
+	require.Equal(t, "node🔥", sanitizedChars.Replace("node🔥"))
+	require.Equal(t, "プロセス_ID", sanitizedChars.Replace("プロセス ID"))
+}
+
+func TestTransliterate(t *testing.T) {
+	tests := map[string]string{
+		"chrome":       "chrome",
+		"Système":      "Systeme",
+		"Prozessgröße": "Prozessgroesse",
+		"node🔥":        "node_u1F525",
+		"プロセス":         "_u30D7_u30ED_u30BB_u30B9",
+	}
+	for input, expected := range tests {
+		require.Equal(t, expected, transliterate(input), input)
+	}
+}
+
+func TestApplyTransliteration(t *testing.T) {
+	m := &WinPerfCounters{Transliterate: true}
+	fields := map[string]interface{}{"Größe": 1.0, "Count": 2.0}
+	tags := map[string]string{"instance": "node🔥", "objectname": "Process"}
+
+	measurement := m.applyTransliteration(make(transliterationCheck), "win_proc", fields, tags)
+	require.Equal(t, "win_proc", measurement)
+	require.Equal(t, map[string]interface{}{"Groesse": 1.0, "Count": 2.0}, fields)
+	require.Equal(t, map[string]string{"instance": "node_u1F525", "objectname": "Process"}, tags)
+}
+
+func TestApplyTransliterationCollisions(t *testing.T) {
+	var buf bytes.Buffer
+	m := &WinPerfCounters{Transliterate: true, Log: Logger{Output: log.New(&buf, "", 0)}}
+	check := make(transliterationCheck)
+
+	// 不同的实例转换为相同的标签值时报告冲突，相同的实例不报告
+	for _, instance := range []string{"Système", "Système", "Systeme", "Other"} {
+		m.applyTransliteration(check, "win_proc", map[string]interface{}{}, map[string]string{"instance": instance, "objectname": "Process"})
+	}
+	require.Equal(t, int64(1), m.stats.value(map[string]string{}, "transliteration_collisions"))
+	require.Contains(t, buf.String(), `Transliterate maps tag instance values "Système" and "Systeme" to the same value "Systeme"`)
+
+	// 字段转换后与已有字段同名时保留原始名称
+	fields := map[string]interface{}{"Größe": 1.0, "Groesse": 2.0, "Grösse": 3.0}
+	m.applyTransliteration(check, "win_proc", fields, map[string]string{})
+	require.Equal(t, map[string]interface{}{"Größe": 1.0, "Groesse": 2.0, "Grösse": 3.0}, fields)
+	require.Equal(t, int64(3), m.stats.value(map[string]string{}, "transliteration_collisions"))
+
+	// 同一冲突只警告一次，每次发生都计数
+	buf.Reset()
+	m.applyTransliteration(make(transliterationCheck), "win_proc", map[string]interface{}{"Groesse": 1.0, "Größe": 2.0}, map[string]string{})
+	require.Empty(t, buf.String())
+	require.Equal(t, int64(4), m.stats.value(map[string]string{}, "transliteration_collisions"))
+
+	fields = map[string]interface{}{"Größe": 1.0, "Zähler": 2.0}
+	m.applyTransliteration(make(transliterationCheck), "win_proc", fields, map[string]string{})
+	require.Equal(t, map[string]interface{}{"Groesse": 1.0, "Zaehler": 2.0}, fields)
+}
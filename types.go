@@ -0,0 +1,364 @@
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CollectFunc 接收一条采集到的指标。fields 与 tags 由同一条指标的所有回调共用，回调不得修改，需要修改时先复制；
+// 启用 InternTags 时，内容相同的 tags 在多次回调之间也共享同一个映射。回调中可以调用 AddCollectFunc 等方法注册回调。
+type CollectFunc func(measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time)
+
+// Metric 表示一条采集到的指标。
+type Metric struct {
+	Measurement string                 `json:"measurement"`
+	Tags        map[string]string      `json:"tags"`
+	Fields      map[string]interface{} `json:"fields"`
+	Timestamp   time.Time              `json:"timestamp"`
+	// Metadata 字段名称到其配置的元数据（说明、单位、指标类型）的映射，只由 GatherMetrics 与 GatherBySource 填充，没有配置时为 nil。
+	Metadata map[string]FieldMetadata `json:"metadata,omitempty"`
+}
+
+// FieldMetadata 字段的静态元数据，供导出程序生成 HELP 说明和单位等信息。
+type FieldMetadata struct {
+	// Description 字段的说明文字。
+	Description string `toml:"Description" json:"description,omitempty"`
+	// Unit 字段的单位，例如 "bytes"、"percent"、"seconds"。
+	Unit string `toml:"Unit" json:"unit,omitempty"`
+	// Kind 指标类型，"gauge" 或 "counter"。
+	Kind string `toml:"Kind" json:"kind,omitempty"`
+}
+
+type Duration time.Duration
+
+// UnmarshalText 支持在 TOML 中以 "1m"、"10s" 等字符串形式配置时长。
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// MarshalText 将时长序列化为 "1m0s" 等字符串形式，与 UnmarshalText 对应。
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// secretString 是解析配置时照常读取、序列化时输出为空字符串的字符串，用于密码等不能随配置导出的值。
+type secretString string
+
+// MarshalText 总是返回空内容，使 MarshalConfig 与配置指纹不包含密码和令牌。
+func (secretString) MarshalText() ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (s *secretString) UnmarshalText(text []byte) error {
+	*s = secretString(text)
+	return nil
+}
+
+var sanitizedChars = strings.NewReplacer("/sec", "_persec", "/Sec", "_persec", " ", "_", "%", "Percent", `\`, "")
+
+const emptyInstance = "------"
+
+// ErrClosed 调用 Close 之后再采集时返回的错误，重新调用 Init 后可以继续采集。
+var ErrClosed = errors.New("win_perf_counters: collector is closed")
+
+// ErrNotReady 启用 BackgroundInitialRefresh 时，计数器仍在后台添加期间采集返回的错误，可通过 errors.Is 判断。
+var ErrNotReady = errors.New("win_perf_counters: counters are not ready yet")
+
+// CollectPredicate 用于判断一条指标是否需要交给对应的 CollectFunc 处理。
+type CollectPredicate func(measurement string, tags map[string]string) bool
+
+// EnrichFunc 在指标分发前对其进行修改，返回 false 时丢弃该指标。
+type EnrichFunc func(metric *Metric) bool
+
+// CollectWithPreviousFunc 是附带上一次字段值的采集回调，便于调用方自行计算速率或告警，而无需维护状态。
+// previous 中只包含之前采集到过的字段，序列首次出现时为空。与 CollectFunc 相同，fields 与 tags 为只读。
+type CollectWithPreviousFunc func(measurement string, fields map[string]interface{}, previous map[string]PreviousValue, tags map[string]string, timestamp time.Time)
+
+// PreviousValue 表示字段上一次采集到的值及其时间戳。
+type PreviousValue struct {
+	Value     interface{}
+	Timestamp time.Time
+}
+
+// BackpressureFunc 报告输出端当前是否存在背压，例如发送队列堆积或本地缓存持续增长。
+type BackpressureFunc func() bool
+
+// FlushFunc 在 Close 时将输出端缓存的数据发送出去，应在 ctx 结束前返回。
+type FlushFunc func(ctx context.Context) error
+
+// GatherDoneFunc 在每次采集的全部指标输出之后调用，输出端可据此清理本次未再出现的序列。
+type GatherDoneFunc func()
+
+// MatchMeasurement 返回按测量名称匹配的过滤条件。
+func MatchMeasurement(measurements ...string) CollectPredicate {
+	return func(measurement string, _ map[string]string) bool {
+		return slices.Contains(measurements, measurement)
+	}
+}
+
+// MatchObject 返回按性能对象名称（objectname 标签）匹配的过滤条件。
+func MatchObject(objectNames ...string) CollectPredicate {
+	return func(_ string, tags map[string]string) bool {
+		return slices.Contains(objectNames, tags["objectname"])
+	}
+}
+
+// MatchTag 返回按标签值匹配的过滤条件，values 为空时只要求标签存在。
+func MatchTag(key string, values ...string) CollectPredicate {
+	return func(_ string, tags map[string]string) bool {
+		value, ok := tags[key]
+		if !ok {
+			return false
+		}
+		return len(values) == 0 || slices.Contains(values, value)
+	}
+}
+
+// Not 返回对给定过滤条件取反的过滤条件。
+func Not(predicate CollectPredicate) CollectPredicate {
+	return func(measurement string, tags map[string]string) bool {
+		return !predicate(measurement, tags)
+	}
+}
+
+// CollectionError 采集中出现的一个错误，以结构化的形式提供给无人值守的部署，
+// 便于写入事件日志、告警系统等 stdout 以外的位置。
+type CollectionError struct {
+	// Time 错误出现的时间。
+	Time time.Time `json:"time"`
+	// Host 出错的主机，可能为空。
+	Host string `json:"host,omitempty"`
+	// Object 出错的性能对象名称，可能为空。
+	Object string `json:"object,omitempty"`
+	// CounterPath 出错的计数器路径，可能为空。
+	CounterPath string `json:"counter_path,omitempty"`
+	// Op 出错时执行的操作，例如 "add"、"collect"、"read"，可能为空。
+	Op string `json:"op,omitempty"`
+	// Code PDH 状态码，不是 PDH 错误时为 0。
+	Code uint32 `json:"code,omitempty"`
+	// CodeName PDH 状态码的名称，例如 "PDH_CSTATUS_NO_OBJECT"，未知的状态码以十六进制表示。
+	CodeName string `json:"code_name,omitempty"`
+	// Message 错误的描述。
+	Message string `json:"message"`
+	// Err 原始错误。
+	Err error `json:"-"`
+}
+
+func (e CollectionError) Error() string {
+	return e.Message
+}
+
+func (e CollectionError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError 表示采集过程中发生并被恢复的 panic。
+type PanicError struct {
+	// Host 发生 panic 的主机。
+	Host string
+	// CounterPath 发生 panic 时正在读取的计数器路径，为空表示无法定位到具体计数器。
+	CounterPath string
+	// Value recover 得到的值。
+	Value interface{}
+	// Stack panic 时的调用栈。
+	Stack string
+}
+
+func (e *PanicError) Error() string {
+	if e.CounterPath != "" {
+		return fmt.Sprintf("panic while gathering counter %q on host %q: %v", e.CounterPath, e.Host, e.Value)
+	}
+	return fmt.Sprintf("panic while gathering host %q: %v", e.Host, e.Value)
+}
+
+// CounterPath 计数器路径的各个组成部分，路径的一般形式为 \\computer\object(parent/instance#index)\counter。
+type CounterPath struct {
+	// Computer 主机名称，路径不带主机名时为空。
+	Computer string
+	// Object 性能对象名称。
+	Object string
+	// Instance 实例名称，单实例对象（例如 Memory）为空。
+	Instance string
+	// Counter 计数器名称。
+	Counter string
+}
+
+// CounterMeta describes a counter as reported by PdhGetCounterInfo
+type CounterMeta struct {
+	// Path is the full counter path
+	Path string `json:"path"`
+	// Type is the counter type as defined in winperf.h, e.g. PERF_COUNTER_RAWCOUNT
+	Type uint32 `json:"type"`
+	// TypeClass is a short description of the counter type, e.g. "number", "rate" or "fraction"
+	TypeClass string `json:"type_class"`
+	// Scale is the power of ten applied to the displayable value
+	Scale int32 `json:"scale"`
+	// DefaultScale is the scale suggested by the counter's provider
+	DefaultScale int32 `json:"default_scale"`
+	// ExplainText is the help text of the counter, empty for log file sources
+	ExplainText string `json:"explain_text"`
+	// UserData is the value passed to AddCounterToQueryWithUserData, 0 for counters added without one
+	UserData uintptr `json:"user_data,omitempty"`
+}
+
+// CounterDescriptor 描述一个已添加到查询中的计数器，通配符已展开。
+type CounterDescriptor struct {
+	// Computer 计数器所属的主机，本机为 localhost。
+	Computer string `json:"computer"`
+	// Source 输出指标时 source 标签的值。
+	Source string `json:"source"`
+	// Object 性能对象名称。
+	Object string `json:"object"`
+	// Instance 实例名称，没有实例时为空。
+	Instance string `json:"instance"`
+	// Counter 计数器名称。
+	Counter string `json:"counter"`
+	// Field 输出的字段名称。
+	Field string `json:"field"`
+	// Measurement 测量名称。
+	Measurement string `json:"measurement"`
+	// Path 添加到查询中的完整计数器路径。
+	Path string `json:"path"`
+	// Raw 是否采集原始值，否则采集格式化后的值。
+	Raw bool `json:"raw"`
+	// Quarantined 计数器或其主机是否因 panic 被隔离而不再采集。
+	Quarantined bool `json:"quarantined"`
+}
+
+// CounterSpec 描述一个可采集的性能计数器。
+type CounterSpec struct {
+	// Object 所属的性能对象名称。
+	Object string `json:"object"`
+	// Name 计数器名称。
+	Name string `json:"name"`
+	// Path 可直接用于配置的计数器路径，多实例对象使用 * 匹配全部实例。
+	Path string `json:"path"`
+	// HasInstances 所属对象是否有多个实例。
+	HasInstances bool `json:"has_instances"`
+}
+
+// ResolvedPattern 一个配置的计数器路径模式（对象、主机、计数器与实例的组合）及其匹配到的具体计数器路径。
+type ResolvedPattern struct {
+	// Computer 主机名称，本机为 localhost。
+	Computer string `json:"computer"`
+	// Object 性能对象名称。
+	Object string `json:"object"`
+	// Measurement 对象配置的测量名称。
+	Measurement string `json:"measurement"`
+	// Counter 配置的计数器名称，可以包含通配符。
+	Counter string `json:"counter"`
+	// Instance 配置的实例名称，可以包含通配符，"re:" 正则表达式以 "*" 查询。
+	Instance string `json:"instance"`
+	// Pattern 由以上各项组成的计数器路径。
+	Pattern string `json:"pattern"`
+	// Paths 匹配到的具体计数器路径，已排除 InstancesExclude、IgnoredCounters 等过滤掉的计数器，没有匹配时为空。
+	Paths []string `json:"paths"`
+	// Error 无法解析该模式的原因，例如计数器不存在或该主机上跳过了该对象。
+	Error string `json:"error,omitempty"`
+}
+
+// TimeRange 表示查询的时间范围，Start 或 End 为零值时不做限制。
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Sample 表示一条时间序列在某一时刻的字段值。
+type Sample struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Fields    map[string]interface{} `json:"fields"`
+}
+
+// Series 表示一条时间序列及其在查询范围内的样本，按时间升序排列。
+type Series struct {
+	Measurement string            `json:"measurement"`
+	Tags        map[string]string `json:"tags"`
+	Samples     []Sample          `json:"samples"`
+}
+
+// MeasurementRule 按标签的值将实例输出到不同的测量，例如将 LogicalDisk 的 _Total 实例输出到 disk_total，
+// 以便下游为汇总数据和各实例的数据使用不同的保留策略。
+type MeasurementRule struct {
+	// Tag 匹配的标签名，默认为 "instance"。
+	Tag string `toml:"Tag"`
+	// Values 匹配的标签值，支持 "re:" 前缀的正则表达式，为空时只要求存在该标签。
+	Values []string `toml:"Values"`
+	// Measurement 匹配时使用的测量名称模板，{标签名} 替换为该标签的值，{measurement} 替换为原来的测量名称。
+	Measurement string `toml:"Measurement"`
+
+	patterns []*regexp.Regexp
+}
+
+// Health 采集器的健康状态，由 HealthHandler 以 JSON 提供。
+type Health struct {
+	// Status 总是 "ok"，能够响应即表示采集器在运行。
+	Status string `json:"status"`
+	// Build 采集器的构建信息。
+	Build VersionInfo `json:"build"`
+	// ConfigFingerprint 当前生效配置的指纹。
+	ConfigFingerprint string `json:"config_fingerprint"`
+	// Profile 当前的采集档位，未配置档位时为空。
+	Profile string `json:"profile,omitempty"`
+	// Refresh 最近一次定期刷新的计数器集合变化与建议的刷新间隔，尚未定期刷新过时省略。
+	Refresh *RefreshReport `json:"refresh,omitempty"`
+}
+
+// RefreshReport 最近一次定期刷新时计数器集合的变化与建议的刷新间隔，由 RefreshDiagnostics 返回，
+// 也包含在 HealthHandler 的响应中。
+type RefreshReport struct {
+	// Time 比较计数器集合的时间，尚未比较过时为零值。
+	Time time.Time `json:"time"`
+	// Counters 刷新后各主机的计数器总数，包括通配符展开的实例。
+	Counters int `json:"counters"`
+	// Added 与 Removed 相对上一次刷新新增与消失的计数器数量。
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	// ChurnPercent 新增与消失的计数器占计数器总数的百分比。
+	ChurnPercent int64 `json:"churn_percent"`
+	// StableRefreshes 连续没有变化的刷新次数，达到 3 次时建议延长刷新间隔并重新计数。
+	StableRefreshes int `json:"stable_refreshes"`
+	// ConfiguredInterval 配置的 CountersRefreshInterval。
+	ConfiguredInterval Duration `json:"configured_interval"`
+	// CurrentInterval 本次刷新时使用的刷新间隔，启用 AutoTuneRefresh 时可能与配置不同。
+	CurrentInterval Duration `json:"current_interval"`
+	// SuggestedInterval 建议的 CountersRefreshInterval。
+	SuggestedInterval Duration `json:"suggested_interval"`
+	// AutoTune 是否启用了 AutoTuneRefresh，启用时建议的间隔会被直接使用。
+	AutoTune bool `json:"auto_tune"`
+}
+
+// PipeFilter 是客户端连接后发送的第一行 JSON，用于只订阅部分指标。
+// 各字段为空时不做限制，全部为空时订阅全部指标。
+type PipeFilter struct {
+	Measurements []string          `json:"measurements"`
+	Objects      []string          `json:"objects"`
+	Tags         map[string]string `json:"tags"`
+}
+
+// PerfCounterPublisherConfig 描述如何将指标重新发布为自定义性能计数器。
+//
+// 计数器集合需要先通过清单文件注册（lodctr /m:manifest.man），
+// ProviderGUID、CounterSetGUID 以及各计数器 ID 必须与清单中的定义一致，
+// 清单中的计数器类型应为 perf_counter_large_rawcount。
+type PerfCounterPublisherConfig struct {
+	// ProviderGUID 清单中 provider 的 GUID，形如 "{xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx}"。
+	ProviderGUID string
+	// CounterSetGUID 清单中 counterSet 的 GUID。
+	CounterSetGUID string
+	// Measurement 需要发布的测量名称。
+	Measurement string
+	// InstanceTag 用作计数器实例名称的标签，默认为 "instance"。
+	InstanceTag string
+	// Counters 字段名称到清单中计数器 ID 的映射。
+	Counters map[string]uint32
+}
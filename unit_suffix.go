@@ -0,0 +1,60 @@
+package win_perf_counters
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// rateUnitPattern 每秒速率计数器名称中的 "/sec"，例如 "Disk Reads/sec"。
+	rateUnitPattern = regexp.MustCompile(`(?i)/sec\b`)
+	// secondsUnitPattern 以秒为单位的计数器名称中的 "sec/"，例如 "Avg. Disk sec/Read"。
+	secondsUnitPattern = regexp.MustCompile(`(?i)\bsec/`)
+	// sizeUnits 计数器名称中的大小单位及其后缀，按顺序匹配第一个。
+	sizeUnits = []struct {
+		pattern *regexp.Regexp
+		unit    string
+	}{
+		{regexp.MustCompile(`(?i)\b(MBytes|Megabytes)\b`), "megabytes"},
+		{regexp.MustCompile(`(?i)\b(KBytes|Kilobytes)\b`), "kilobytes"},
+		{regexp.MustCompile(`(?i)\bBytes\b`), "bytes"},
+		{regexp.MustCompile(`(?i)\bBits\b`), "bits"},
+	}
+)
+
+// unitSuffixed 将计数器名称中的单位统一移到末尾，例如 "Disk Read Bytes/sec" 改为 "Disk Read bytes/sec"，
+// "% Processor Time" 改为 "Processor Time percent"，"Avg. Disk sec/Read" 改为 "Avg. Disk Read seconds"。
+// 名称中没有可识别的单位时原样返回。
+func unitSuffixed(counterName string) string {
+	base := counterName
+	var units []string
+	rate := false
+	if rest, ok := strings.CutPrefix(base, "%"); ok {
+		base = rest
+		units = append(units, "percent")
+	}
+	if secondsUnitPattern.MatchString(base) {
+		base = secondsUnitPattern.ReplaceAllString(base, "")
+		units = append(units, "seconds")
+	}
+	for _, size := range sizeUnits {
+		if size.pattern.MatchString(base) {
+			base = size.pattern.ReplaceAllString(base, "")
+			units = append(units, size.unit)
+			break
+		}
+	}
+	if rateUnitPattern.MatchString(base) {
+		base = rateUnitPattern.ReplaceAllString(base, "")
+		rate = true
+	}
+	base = strings.Join(strings.Fields(base), " ")
+	if (len(units) == 0 && !rate) || base == "" {
+		return counterName
+	}
+	name := strings.Join(append([]string{base}, units...), " ")
+	if rate {
+		name += "/sec"
+	}
+	return name
+}
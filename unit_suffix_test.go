@@ -0,0 +1,36 @@
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitSuffixed(t *testing.T) {
+	tests := []struct {
+		counterName string
+		want        string
+	}{
+		{"% Processor Time", "Processor Time percent"},
+		{"Disk Read Bytes/sec", "Disk Read bytes/sec"},
+		{"Bytes Total/sec", "Total bytes/sec"},
+		{"Avg. Disk sec/Read", "Avg. Disk Read seconds"},
+		{"Available MBytes", "Available megabytes"},
+		{"Available KBytes", "Available kilobytes"},
+		{"Pool Paged Bytes", "Pool Paged bytes"},
+		{"Current Bandwidth Bits", "Current Bandwidth bits"},
+		{"% Disk Read Bytes", "Disk Read percent bytes"},
+		// 只有速率时单位保持在末尾，名称不变
+		{"Disk Reads/sec", "Disk Reads/sec"},
+		// 没有可识别的单位
+		{"Processor Queue Length", "Processor Queue Length"},
+		{"Bytessss Received", "Bytessss Received"},
+		// 去掉单位后名称为空时原样返回
+		{"Bytes", "Bytes"},
+		{"Bits/sec", "Bits/sec"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, unitSuffixed(tt.counterName), tt.counterName)
+	}
+}
@@ -0,0 +1,293 @@
+//go:build !windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 非 Windows 平台的这些函数和方法只为与 Windows 平台保持相同的 API 而提供，便于多平台程序编译后在运行时决定是否启用采集器。
+// 依赖 PDH 或 Windows 系统服务的函数返回 ErrUnsupportedPlatform，没有错误返回值的方法不做任何事情。
+
+// 事件日志中各级别日志的事件 ID。
+const (
+	EventIDError   = 1
+	EventIDWarning = 2
+	EventIDInfo    = 3
+	EventIDDebug   = 4
+)
+
+// New 按 options 创建并初始化 WinPerfCounters，非 Windows 平台只使用 Simulate、Sources、Objects 与 QueryCreator。
+func New(options Options, collectFunc CollectFunc) (*WinPerfCounters, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	m := NewWinPerfCounters(collectFunc)
+	m.Sources = options.Sources
+	m.Object = options.Objects
+	m.Presets = options.Presets
+	m.Simulate = options.Simulate
+	if options.QueryCreator != nil {
+		m.WithQueryCreator(options.QueryCreator)
+	}
+	if err := m.Init(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Validate 校验所有对象的配置。
+func (o Options) Validate() error {
+	for i := range o.Objects {
+		if err := o.Objects[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate 校验对象名称与计数器，其它配置项在非 Windows 平台不生效，不做校验。
+func (o *ObjectConfig) Validate() error {
+	if o.ObjectName == "" {
+		return errors.New("object name is required")
+	}
+	if len(o.Counters) == 0 {
+		return fmt.Errorf("no counters configured for object %q", o.ObjectName)
+	}
+	for i, counter := range o.Counters {
+		if strings.TrimSpace(counter) == "" {
+			return fmt.Errorf("counter %d of object %q is empty", i+1, o.ObjectName)
+		}
+	}
+	return nil
+}
+
+func LoadConfig(string) (*WinPerfCounters, error) { return nil, ErrUnsupportedPlatform }
+
+func ParseConfig([]byte, ConfigFormat) (*WinPerfCounters, error) { return nil, ErrUnsupportedPlatform }
+
+func EnumerateMachines() ([]string, error) { return nil, ErrUnsupportedPlatform }
+
+func ListObjects(string) ([]string, error) { return nil, ErrUnsupportedPlatform }
+
+func ListInstances(string, string) ([]string, error) { return nil, ErrUnsupportedPlatform }
+
+func ListCounters(string, string) ([]CounterSpec, error) { return nil, ErrUnsupportedPlatform }
+
+func ParseCounterPath(string) (CounterPath, error) { return CounterPath{}, ErrUnsupportedPlatform }
+
+func IsEndOfLog(error) bool { return false }
+
+// GatherContext 在 ctx 未结束时采集一次数据。
+func (w *WinPerfCounters) GatherContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return w.Gather()
+}
+
+func (*WinPerfCounters) GatherMetrics() ([]Metric, error) { return nil, ErrUnsupportedPlatform }
+
+func (*WinPerfCounters) GatherBySource() (map[string][]Metric, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (*WinPerfCounters) Start(context.Context) error { return ErrUnsupportedPlatform }
+
+func (*WinPerfCounters) Stop() {}
+
+func (*WinPerfCounters) RefreshNow(context.Context) error { return ErrUnsupportedPlatform }
+
+func (*WinPerfCounters) Reload([]byte) error { return ErrUnsupportedPlatform }
+
+func (*WinPerfCounters) ReloadObjects([]string, []ObjectConfig) error { return ErrUnsupportedPlatform }
+
+func (*WinPerfCounters) ResolveConfig() ([]ResolvedPattern, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (*WinPerfCounters) MarshalConfig() ([]byte, error) { return nil, ErrUnsupportedPlatform }
+
+func (*WinPerfCounters) ExportTelegrafConfig() (string, error) { return "", ErrUnsupportedPlatform }
+
+func (*WinPerfCounters) ConfigFingerprint() string { return "" }
+
+func (*WinPerfCounters) CounterInfo(string) (CounterMeta, error) {
+	return CounterMeta{}, ErrUnsupportedPlatform
+}
+
+func (*WinPerfCounters) LocalizedName(string, string) (string, error) {
+	return "", ErrUnsupportedPlatform
+}
+
+func (*WinPerfCounters) EnglishName(string, string) (string, error) {
+	return "", ErrUnsupportedPlatform
+}
+
+func (*WinPerfCounters) DumpPerfmonCSV(io.Writer, CollectPredicate, TimeRange) error {
+	return ErrUnsupportedPlatform
+}
+
+func (*WinPerfCounters) Query(CollectPredicate, TimeRange) []Series { return nil }
+
+func (*WinPerfCounters) ActiveCounters() []CounterDescriptor { return nil }
+
+func (*WinPerfCounters) CheckSources() []string { return nil }
+
+func (*WinPerfCounters) Stats() []Metric { return nil }
+
+func (*WinPerfCounters) RefreshDiagnostics() RefreshReport { return RefreshReport{} }
+
+// Errors 返回 nil 通道，非 Windows 平台不会发送采集错误，错误由 Gather 返回。
+func (*WinPerfCounters) Errors() <-chan CollectionError { return nil }
+
+func (w *WinPerfCounters) ActiveProfile() string { return w.Profile }
+
+func (*WinPerfCounters) SetProfile(string) error { return ErrUnsupportedPlatform }
+
+func (*WinPerfCounters) TriggerBurst(string, time.Duration) error { return ErrUnsupportedPlatform }
+
+func (w *WinPerfCounters) GatherInterval() time.Duration { return time.Duration(w.Interval) }
+
+func (*WinPerfCounters) AddCollectFunc(CollectPredicate, CollectFunc) {}
+
+func (*WinPerfCounters) AddCollectWithPreviousFunc(CollectPredicate, CollectWithPreviousFunc) {}
+
+func (*WinPerfCounters) AddEnrichFunc(EnrichFunc) {}
+
+func (*WinPerfCounters) AddBackpressureFunc(BackpressureFunc) {}
+
+func (*WinPerfCounters) AddFlushFunc(FlushFunc) {}
+
+func (*WinPerfCounters) AddGatherDoneFunc(GatherDoneFunc) {}
+
+func (*WinPerfCounters) RegisterSink(string, CollectFunc) {}
+
+func (*WinPerfCounters) AgentHandler() http.Handler { return unsupportedHandler }
+
+func (*WinPerfCounters) DumpHandler() http.Handler { return unsupportedHandler }
+
+func (*WinPerfCounters) HealthHandler() http.Handler { return unsupportedHandler }
+
+func (*WinPerfCounters) ProfileHandler() http.Handler { return unsupportedHandler }
+
+func (*WinPerfCounters) RefreshHandler() http.Handler { return unsupportedHandler }
+
+func (*WinPerfCounters) ResolveHandler() http.Handler { return unsupportedHandler }
+
+// unsupportedHandler 对所有请求返回 501 Not Implemented。
+var unsupportedHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	http.Error(w, ErrUnsupportedPlatform.Error(), http.StatusNotImplemented)
+})
+
+// ETWProvider 描述 ETW 会话中启用的一个提供程序。
+type ETWProvider struct {
+	Name            string   `toml:"Name"`
+	Level           uint8    `toml:"Level"`
+	MatchAnyKeyword uint64   `toml:"MatchAnyKeyword"`
+	EventIDs        []uint16 `toml:"EventIDs"`
+}
+
+// ETWSession 在非 Windows 平台无法创建。
+type ETWSession struct {
+	Log Logger
+}
+
+func NewETWSession(string, []ETWProvider, CollectFunc) (*ETWSession, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (*ETWSession) Close() error { return nil }
+
+// EventLogger 在非 Windows 平台无法创建。
+type EventLogger struct {
+	Debug bool
+}
+
+func NewEventLogger(string) (*EventLogger, error) { return nil, ErrUnsupportedPlatform }
+
+func (*EventLogger) Close() error { return nil }
+
+func (*EventLogger) Debugf(string, ...interface{}) {}
+
+func (*EventLogger) Infof(string, ...interface{}) {}
+
+func (*EventLogger) Warnf(string, ...interface{}) {}
+
+func (*EventLogger) Errorf(string, ...interface{}) {}
+
+// NamedPipeOutput 在非 Windows 平台无法创建。
+type NamedPipeOutput struct {
+	Log Logger
+}
+
+func NewNamedPipeOutput(string) (*NamedPipeOutput, error) { return nil, ErrUnsupportedPlatform }
+
+func (*NamedPipeOutput) Backpressure() bool { return false }
+
+func (*NamedPipeOutput) Close() error { return nil }
+
+func (*NamedPipeOutput) Collect(string, map[string]interface{}, map[string]string, time.Time) {}
+
+// SharedMemoryOutput 在非 Windows 平台无法创建。
+type SharedMemoryOutput struct {
+	Log Logger
+}
+
+func NewSharedMemoryOutput(string, uint32) (*SharedMemoryOutput, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (*SharedMemoryOutput) Close() error { return nil }
+
+func (*SharedMemoryOutput) Collect(string, map[string]interface{}, map[string]string, time.Time) {}
+
+func (*SharedMemoryOutput) GatherDone() {}
+
+// PerfCounterPublisher 在非 Windows 平台无法创建。
+type PerfCounterPublisher struct{}
+
+func NewPerfCounterPublisher(PerfCounterPublisherConfig) (*PerfCounterPublisher, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (*PerfCounterPublisher) Close() error { return nil }
+
+func (*PerfCounterPublisher) Collect(string, map[string]interface{}, map[string]string, time.Time) {}
+
+// PdhLogWriter 在非 Windows 平台无法创建。
+type PdhLogWriter struct{}
+
+func NewPdhLogWriter(string, string, []string) (*PdhLogWriter, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (*PdhLogWriter) Close() error { return nil }
+
+func (*PdhLogWriter) Update() error { return ErrUnsupportedPlatform }
+
+// DiskLatencyProcessor 在非 Windows 平台不处理指标。
+type DiskLatencyProcessor struct {
+	Buckets []float64
+}
+
+func NewDiskLatencyProcessor(buckets ...float64) *DiskLatencyProcessor {
+	return &DiskLatencyProcessor{Buckets: buckets}
+}
+
+func (*DiskLatencyProcessor) Enrich(*Metric) bool { return true }
+
+// TailHandler 在非 Windows 平台对所有请求返回 501 Not Implemented。
+type TailHandler struct{}
+
+func NewTailHandler() *TailHandler { return &TailHandler{} }
+
+func (*TailHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) { unsupportedHandler(w, r) }
+
+func (*TailHandler) Collect(string, map[string]interface{}, map[string]string, time.Time) {}
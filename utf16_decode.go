@@ -0,0 +1,67 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// maxPooledDecoderBuffer 归还到 utf16Decoders 的解码器保留的最大缓冲区，避免偶尔解码的长说明文字长期占用内存。
+const maxPooledDecoderBuffer = 64 * 1024
+
+// utf16Decoders 供没有自己的解码器的调用方复用 utf16Decoder。
+var utf16Decoders = sync.Pool{New: func() any { return new(utf16Decoder) }}
+
+// utf16Decoder 将 UTF-16 字符串解码为 UTF-8，各次解码之间复用字节缓冲区，只为最终的字符串分配内存。
+// 与 syscall.UTF16ToString 一样，不成对的代理项按 WTF-8 编码，转换回 UTF-16 后与原来的名称相同。
+// 不能在多个 goroutine 中同时使用。
+type utf16Decoder struct {
+	buf []byte
+}
+
+// decodeBytes 返回 s 解码后的字节，结果在下一次解码前有效。
+func (d *utf16Decoder) decodeBytes(s []uint16) []byte {
+	buf := d.buf[:0]
+	for i := 0; i < len(s); i++ {
+		c := rune(s[i])
+		switch {
+		case c < utf8.RuneSelf:
+			buf = append(buf, byte(c))
+		case utf16.IsSurrogate(c):
+			if i+1 < len(s) {
+				if r := utf16.DecodeRune(c, rune(s[i+1])); r != utf8.RuneError {
+					buf = utf8.AppendRune(buf, r)
+					i++
+					continue
+				}
+			}
+			// 不成对的代理项按 WTF-8 写成三个字节
+			buf = append(buf, byte(0xE0|c>>12), byte(0x80|(c>>6)&0x3F), byte(0x80|c&0x3F))
+		default:
+			buf = utf8.AppendRune(buf, c)
+		}
+	}
+	d.buf = buf
+	return buf
+}
+
+// decode 返回 s 解码后的字符串。
+func (d *utf16Decoder) decode(s []uint16) string {
+	return string(d.decodeBytes(s))
+}
+
+// utf16Units 返回以 0 结尾的 UTF-16 字符串中 0 之前的部分，s 为 nil 时返回 nil。
+func utf16Units(s *uint16) []uint16 {
+	if s == nil {
+		return nil
+	}
+	length := 0
+	//nolint:gosec // G103: Valid use of unsafe call to scan the NULL terminated Windows API string
+	for p := unsafe.Pointer(s); *(*uint16)(p) != 0; p = unsafe.Add(p, 2) {
+		length++
+	}
+	return unsafe.Slice(s, length)
+}
@@ -93,8 +93,18 @@ func newCounter(
 	if useRawValue {
 		newCounterName += "_Raw"
 	}
-	return &counter{counterPath, computer, objectName, newCounterName, instance, measurementName,
-		includeTotal, useRawValue, counterHandle}
+	return &counter{
+		counterPath:   counterPath,
+		computer:      computer,
+		objectName:    objectName,
+		counter:       newCounterName,
+		name:          counterName,
+		instance:      instance,
+		measurement:   measurementName,
+		includeTotal:  includeTotal,
+		useRawValue:   useRawValue,
+		counterHandle: counterHandle,
+	}
 }
 
 func formatPath(computer, objectName, instance, counter string) string {
@@ -104,7 +114,7 @@ func formatPath(computer, objectName, instance, counter string) string {
 	} else {
 		path = fmt.Sprintf(`\%s(%s)\%s`, objectName, instance, counter)
 	}
-	if computer != "" && computer != "localhost" {
+	if computer != "" && computer != "localhost" && logSourcePath(computer) == "" {
 		path = fmt.Sprintf(`\\%s%s`, computer, path)
 	}
 	return path
@@ -121,10 +131,12 @@ func formatPath(computer, objectName, instance, counter string) string {
 // 说明：
 //   该函数会检查错误是否为 PDH 错误，如果是且该错误码在 IgnoredErrors 列表中，
 //   则忽略该错误并返回 nil。否则返回原始错误。
+//   被忽略的错误会按错误名称和主机计入自身状态指标 ignored_errors。
 func (m *WinPerfCounters) checkError(err error) error {
 	var pdhErr *pdhError
 	if errors.As(err, &pdhErr) {
-		if slices.Contains(m.IgnoredErrors, pdhErrors[pdhErr.errorCode]) {
+		if errorName := pdhErrors[pdhErr.errorCode]; slices.Contains(m.IgnoredErrors, errorName) {
+			m.countIgnoredError(err, errorName)
 			return nil
 		}
 		return err
@@ -132,6 +144,17 @@ func (m *WinPerfCounters) checkError(err error) error {
 	return err
 }
 
+// countIgnoredError 将被忽略的错误计入自身状态指标。
+func (m *WinPerfCounters) countIgnoredError(err error, errorName string) {
+	tags := map[string]string{"error": errorName}
+	var counterErr *CounterError
+	if errors.As(err, &counterErr) && counterErr.Host != "" {
+		// 被放弃等待的采集可能在刷新计数器期间调用，不能读取 hostCounters
+		tags["source"] = m.sourceTag(counterErr.Host)
+	}
+	m.stats.incr(tags, "ignored_errors", 1)
+}
+
 // isKnownCounterDataError 判断错误是否为已知的性能计数器数据错误。
 //
 // 参数：
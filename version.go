@@ -0,0 +1,91 @@
+package win_perf_counters
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// modulePath 本模块的路径，作为依赖使用时从构建信息中读取其版本。
+const modulePath = "github.com/rokukoo/win_perf_counters"
+
+// 构建时通过 -ldflags 注入的版本信息，例如：
+//
+//	go build -ldflags "-X github.com/rokukoo/win_perf_counters.version=v1.2.0 -X github.com/rokukoo/win_perf_counters.commit=$(git rev-parse HEAD) -X github.com/rokukoo/win_perf_counters.buildDate=2024-05-01T00:00:00Z" ./cmd
+//
+// 未注入时从 Go 工具链记录的构建信息（模块版本与 vcs.revision、vcs.time）中读取。
+var (
+	version   string
+	commit    string
+	buildDate string
+)
+
+// VersionInfo 采集器的构建信息，用于确认机群中各采集器的构建。
+type VersionInfo struct {
+	// Version 版本号，未知时为 "(devel)"。
+	Version string `json:"version"`
+	// Commit 构建所用的提交，未知时为空。
+	Commit string `json:"commit,omitempty"`
+	// BuildDate 构建时间（或提交时间），未知时为空。
+	BuildDate string `json:"build_date,omitempty"`
+	// Modified 构建时工作区是否有未提交的修改。
+	Modified bool `json:"modified,omitempty"`
+	// GoVersion 构建所用的 Go 版本。
+	GoVersion string `json:"go_version"`
+}
+
+var (
+	buildInfoOnce sync.Once
+	buildInfo     VersionInfo
+)
+
+// BuildInfo 返回采集器的构建信息，-ldflags 注入的值优先于 Go 工具链记录的构建信息。
+func BuildInfo() VersionInfo {
+	buildInfoOnce.Do(func() {
+		buildInfo = VersionInfo{Version: version, Commit: commit, BuildDate: buildDate, GoVersion: runtime.Version()}
+		if info, ok := debug.ReadBuildInfo(); ok {
+			if buildInfo.Version == "" {
+				buildInfo.Version = moduleVersion(info)
+			}
+			for _, setting := range info.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					if buildInfo.Commit == "" {
+						buildInfo.Commit = setting.Value
+					}
+				case "vcs.time":
+					if buildInfo.BuildDate == "" {
+						buildInfo.BuildDate = setting.Value
+					}
+				case "vcs.modified":
+					buildInfo.Modified = setting.Value == "true"
+				}
+			}
+		}
+		if buildInfo.Version == "" {
+			buildInfo.Version = "(devel)"
+		}
+	})
+	return buildInfo
+}
+
+// moduleVersion 返回构建信息中本模块的版本：作为主模块构建时为主模块的版本，作为依赖时为依赖的版本。
+func moduleVersion(info *debug.BuildInfo) string {
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return ""
+}
+
+// Version 返回采集器的版本号，与 BuildInfo().Version 相同。
+func Version() string {
+	return BuildInfo().Version
+}
@@ -3,6 +3,7 @@
 package win_perf_counters
 
 import (
+	"context"
 	_ "embed"
 	"errors"
 	"fmt"
@@ -10,25 +11,15 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-)
 
-type CollectFunc func(measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time)
+	"golang.org/x/sys/windows"
+)
 
 //go:embed sample.conf
 var sampleConfig string
 
-// Size is an int64
-type Size int64
-type Duration time.Duration
-
-var (
-	defaultMaxBufferSize = Size(100 * 1024 * 1024)
-	sanitizedChars       = strings.NewReplacer("/sec", "_persec", "/Sec", "_persec", " ", "_", "%", "Percent", `\`, "")
-)
-
-const emptyInstance = "------"
-
 func NewWinPerfCounters(collectFunc CollectFunc) *WinPerfCounters {
 	return &WinPerfCounters{
 		CountersRefreshInterval:    Duration(time.Second * 60),
@@ -47,49 +38,344 @@ func NewWinPerfCounters(collectFunc CollectFunc) *WinPerfCounters {
 type WinPerfCounters struct {
 	// PrintValid 是否打印有效的计数器路径。
 	PrintValid bool `toml:"PrintValid"`
+	// StrictConfig 配置中有无法识别的键时 ParseConfig、LoadConfig 与 Reload 是否返回错误，为 false 时只记录警告。
+	StrictConfig bool `toml:"StrictConfig"`
 	// PreVistaSupport 是否支持 Vista 之前的系统（已废弃，动态判断）。
-	PreVistaSupport bool `toml:"PreVistaSupport" deprecated:"1.7.0;1.35.0;determined dynamically"`
+	PreVistaSupport bool `toml:"PreVistaSupport,omitempty" deprecated:"1.7.0;1.35.0;determined dynamically"`
 	// UsePerfCounterTime 是否使用性能计数器的时间戳。
 	UsePerfCounterTime bool `toml:"UsePerfCounterTime"`
+	// CompensateClockSkew 启用 UsePerfCounterTime 时是否按估计的时钟偏差校正远程主机的时间戳，
+	// 使时钟不准的主机的指标与本机时间对齐。
+	CompensateClockSkew bool `toml:"CompensateClockSkew"`
+	// Presets 内置预置的名称列表，例如 ["cpu", "memory", "iis"]，每个预置展开为一个或多个对象。
+	Presets []string `toml:"Presets"`
 	// Object 配置的性能对象列表。
-	Object []perfObject `toml:"object"`
+	Object []ObjectConfig `toml:"object"`
+	// Route 指标到具名输出的路由规则。
+	Route []routeRule `toml:"route"`
+	// Profile 初始生效的采集档位名称。
+	Profile string `toml:"Profile"`
+	// Profiles 可在运行时切换的采集档位列表。
+	Profiles []collectionProfile `toml:"profile"`
+	// Credential 访问远程主机时使用的凭据。
+	Credential []sourceCredential `toml:"credential"`
+	// AgentToken 从 Sources 中的远程采集代理拉取指标时发送的令牌（Authorization: Bearer），与代理的 -token 一致，为空时不发送。
+	AgentToken secretString `toml:"AgentToken"`
+	// Burst 临时切换到更密集采集档位的触发规则。
+	Burst []burstTrigger `toml:"burst"`
 	// CountersRefreshInterval 性能计数器刷新间隔。
 	CountersRefreshInterval Duration `toml:"CountersRefreshInterval"`
+	// AutoTuneRefresh 是否按每次刷新时展开的计数器集合的变化自动调整刷新间隔，计数器集合稳定时延长、变化频繁时缩短。
+	AutoTuneRefresh bool `toml:"AutoTuneRefresh"`
+	// AutoTuneRefreshMin 自动调整的刷新间隔下限，默认为 30 秒。
+	AutoTuneRefreshMin Duration `toml:"AutoTuneRefreshMin"`
+	// AutoTuneRefreshMax 自动调整的刷新间隔上限，默认为 1 小时。
+	AutoTuneRefreshMax Duration `toml:"AutoTuneRefreshMax"`
+	// QueryRecycleInterval 定期完全关闭并重新创建所有 PDH 查询的间隔，用于规避长期运行时部分提供程序的句柄泄漏，为 0 时不重建。
+	QueryRecycleInterval Duration `toml:"QueryRecycleInterval"`
 	// UseWildcardsExpansion 是否启用通配符展开。
 	UseWildcardsExpansion bool `toml:"UseWildcardsExpansion"`
+	// TwoPhaseRefresh 刷新计数器时是否先在后台准备新的计数器集合，下一次采集时再切换。
+	TwoPhaseRefresh bool `toml:"TwoPhaseRefresh"`
+	// IncrementalRefresh 刷新计数器时是否保留现有查询，只添加新出现的计数器并移除消失的计数器。
+	IncrementalRefresh bool `toml:"IncrementalRefresh"`
+	// DeferRemoteOpen 是否推迟到首次采集时才打开远程主机的查询，刷新计数器时不受远程主机可用性的影响。
+	DeferRemoteOpen bool `toml:"DeferRemoteOpen"`
+	// SourceGroups 共用同一个查询的主机分组，例如同一集群的节点，减少查询句柄和 CollectQueryData 调用。
+	SourceGroups [][]string `toml:"SourceGroups"`
 	// LocalizeWildcardsExpansion 是否本地化通配符展开。
 	LocalizeWildcardsExpansion bool `toml:"LocalizeWildcardsExpansion"`
+	// CacheLocalizedNames 未启用 LocalizeWildcardsExpansion 时是否按主机缓存英文对象与计数器名称的本地化翻译，
+	// 刷新时直接按本地化路径添加计数器，减少大型本地化主机上的刷新时间。
+	CacheLocalizedNames bool `toml:"CacheLocalizedNames"`
+	// CounterPathCache 保存通配符展开结果的文件路径，为空时不保存。重启后的首次刷新直接使用文件中的结果，
+	// 只在添加计数器时校验路径；文件由其它机器写入或主机的计数器注册表版本变化时重新展开。
+	CounterPathCache string `toml:"CounterPathCache"`
+	// TranslateObjectName 本地化通配符展开时是否将 objectname 标签翻译为英文。
+	TranslateObjectName bool `toml:"TranslateObjectName"`
+	// LocalizedNameTags 是否附加 objectname_localized 标签，为对象在主机语言下的名称。
+	LocalizedNameTags bool `toml:"LocalizedNameTags"`
+	// CounterLanguage 不支持 AddEnglishCounter 时用于翻译名称的词典语言，为空时使用系统界面语言。
+	CounterLanguage string `toml:"CounterLanguage"`
+	// CounterAliases 补充或覆盖内置词典，按语言记录本地化名称到英文名称的映射。
+	CounterAliases map[string]map[string]string `toml:"CounterAliases"`
+	// DuplicateFields 不同计数器映射到相同字段名称时的处理策略（suffix、error、keep_first），默认为 suffix。
+	DuplicateFields string `toml:"DuplicateFields"`
+	// StaleMarker 实例消失时输出的结束标记（nan、tombstone），为空时不输出。
+	StaleMarker string `toml:"StaleMarker"`
+	// StaleMeasurement StaleMarker 为 tombstone 时墓碑指标的测量名称，为空时使用原序列的测量名称。
+	StaleMeasurement string `toml:"StaleMeasurement"`
+	// SequenceField 批次序号字段的名称，配置后每个来源每次采集输出的一批指标都带有该字段，
+	// 序号按来源从 1 开始单调递增，下游可以据此发现缺失或乱序的批次，为空时不输出。
+	SequenceField string `toml:"SequenceField"`
+	// BackgroundInitialRefresh 是否在后台进行首次解析配置和添加计数器，期间采集立即返回 ErrNotReady 而不是阻塞，
+	// 适用于有数万个计数器路径的配置。
+	BackgroundInitialRefresh bool `toml:"BackgroundInitialRefresh"`
+	// DataQuality 是否比较相邻两次采集并输出数据质量报告（消失、持续为 0、跳变的序列）。
+	DataQuality bool `toml:"DataQuality"`
+	// DataQualityMeasurement 数据质量报告的测量名称，默认为 win_perf_quality。
+	DataQualityMeasurement string `toml:"DataQualityMeasurement"`
+	// DataQualityFlatlineGathers 字段连续多少次采集为 0 后判定为持平，默认为 5。
+	DataQualityFlatlineGathers int `toml:"DataQualityFlatlineGathers"`
+	// DataQualityJumpFactor 相邻两次采集的值相差多少倍判定为跳变，默认为 100。
+	DataQualityJumpFactor float64 `toml:"DataQualityJumpFactor"`
+	// DataQualityDetails 是否为每条有问题的序列额外输出一条明细指标。
+	DataQualityDetails bool `toml:"DataQualityDetails"`
+	// BackpressureSlowdown 输出端持续背压时，LowPriority 对象的采集频率降低的倍数，小于等于 1 时不降频。
+	BackpressureSlowdown int `toml:"BackpressureSlowdown"`
+	// BackpressureCycles 连续多少次采集出现背压后开始降频，默认为 3。
+	BackpressureCycles int `toml:"BackpressureCycles"`
 	// IgnoredErrors 需要忽略的错误列表。
 	IgnoredErrors []string `toml:"IgnoredErrors"`
+	// IgnoredCounters 需要静默丢弃的计数器路径通配符模式，在通配符展开后匹配，例如 `\Thread(*)\Context Switches/sec`。
+	IgnoredCounters []string `toml:"IgnoredCounters"`
 	// MaxBufferSize 最大缓冲区大小。
 	MaxBufferSize Size `toml:"MaxBufferSize"`
 	// Sources 数据源主机列表。
 	Sources []string `toml:"Sources"`
+	// Interval 调度器中未配置 Interval 的对象的默认采集间隔，为 0 时使用 10s。
+	Interval Duration `toml:"Interval"`
+	// CollectTimeout 每个主机单次采集的超时时间，为 0 时不限制。
+	CollectTimeout Duration `toml:"CollectTimeout"`
+	// ArrayWorkers 未启用 UseWildcardsExpansion 时，每个主机并行读取计数器数组值的 goroutine 数量，小于等于 1 时逐个读取。
+	ArrayWorkers int `toml:"ArrayWorkers"`
+	// RefreshBatchSize 刷新时每添加多少个计数器让出一次 CPU，为 0 时连续添加。
+	RefreshBatchSize int `toml:"RefreshBatchSize"`
+	// RefreshBatchDelay 后台首次刷新（BackgroundInitialRefresh）时每批计数器添加后的等待时间，仅在 RefreshBatchSize 大于 0 时生效。
+	RefreshBatchDelay Duration `toml:"RefreshBatchDelay"`
+	// FinalGather Close 时是否先进行最后一次采集，便于计划任务等短时运行的场景在退出前至少输出一组完整的样本。
+	FinalGather bool `toml:"FinalGather"`
+	// ShutdownTimeout Close 时最后一次采集与刷新输出的总超时时间，默认为 10s。
+	ShutdownTimeout Duration `toml:"ShutdownTimeout"`
+	// MaxConcurrentHosts 同时采集的主机数量上限，为 0 时不限制。
+	MaxConcurrentHosts int `toml:"MaxConcurrentHosts"`
+	// NameRetries 刷新计数器时对象或计数器名称无法解析的重试次数，为 0 时不重试。
+	NameRetries int `toml:"NameRetries"`
+	// RetryMissingCounters 刷新计数器时添加失败的计数器是否在之后的采集中按指数退避重试，而不是等到下一次刷新。
+	RetryMissingCounters bool `toml:"RetryMissingCounters"`
+	// MaxRetries 启用 RetryMissingCounters 时每个计数器的最大重试次数，为 0 时一直重试到下一次刷新。
+	MaxRetries int `toml:"MaxRetries"`
+	// InternTags 是否为内容相同的标签复用同一个映射实例，启用后回调收到的 tags 不得修改。
+	InternTags bool `toml:"InternTags"`
+	// ExtraTags 添加到所有指标的静态标签，不会覆盖标准标签。
+	ExtraTags map[string]string `toml:"ExtraTags"`
+	// SourceTags 按主机（Sources 中的名称）添加的静态标签，优先于 ExtraTags。
+	SourceTags map[string]map[string]string `toml:"SourceTags"`
+	// TagKeyOverrides 输出时标准标签 source、objectname、instance 等改用的名称，名称为空字符串时省略该标签。
+	TagKeyOverrides map[string]string `toml:"TagKeyOverrides"`
+	// DuplicateCollectors 同一台机器上的其它进程采集相同的对象时的处理方式，"warn" 给出警告，"skip" 不再采集，为空时不检测。
+	DuplicateCollectors string `toml:"DuplicateCollectors"`
+	// KeepAliveInterval 远程主机空闲超过该时长时采集一次保活计数器，避免 RPC 会话断开，为 0 时不保活。
+	KeepAliveInterval Duration `toml:"KeepAliveInterval"`
+	// SelfMetrics 是否在每次采集后输出插件自身的运行状态指标。
+	SelfMetrics bool `toml:"SelfMetrics"`
+	// History 在内存中保留每条时间序列历史样本的时长，为 0 时不保留。
+	History Duration `toml:"History"`
+	// Simulate 是否使用合成数据代替真实的性能计数器，便于开发仪表盘和输出插件。
+	Simulate bool `toml:"Simulate"`
+	// Transliterate 是否将测量名称、字段名称和标签值转换为 ASCII，用于只接受 ASCII 序列名称的输出。
+	Transliterate bool `toml:"Transliterate"`
+	// NamePolicy 测量名称、字段名称与非标准标签名称的转换策略，"telegraf"（默认）、"prometheus" 或 "none"。
+	NamePolicy string `toml:"NamePolicy"`
+	// FieldNameSanitizer 自定义的名称转换函数，设置后代替 NamePolicy 用于测量名称、字段名称与非标准标签名称。
+	FieldNameSanitizer func(string) string `toml:"-"`
+	// UnitSuffixes 是否按计数器名称中的单位（%、Bytes、MBytes、Bits、sec/、/sec 等）统一在字段名称末尾添加单位后缀，
+	// 例如 "Disk Read Bytes/sec" 输出为 "Disk_Read_bytes_persec"。
+	UnitSuffixes bool `toml:"UnitSuffixes"`
+	// OnError 每次采集返回的错误被拆分后逐个调用，host 为出错的主机（可能为空），被 IgnoredErrors 忽略的错误不会传入。
+	// 在采集的 goroutine 中同步调用，不能在其中调用采集方法或 Close。
+	OnError func(host string, err error) `toml:"-"`
+	// OnObjectGathered 每个主机每次采集后为每个需要采集的对象调用一次，samples 为本次采集到的样本（计数器实例的值）数量，
+	// err 为该对象的读取错误（被 IgnoredErrors 忽略的除外），主机整体采集失败时为该错误。
+	// 在各主机的采集 goroutine 中同步调用，可能被并发调用，不能在其中调用采集方法或 Close。
+	OnObjectGathered func(host, objectName string, samples int, err error) `toml:"-"`
+	// LogOutputPath 同时将采集的计数器写入的 perfmon 日志文件路径，为空时不写入。
+	LogOutputPath string `toml:"LogOutputPath"`
+	// LogFormat 日志格式（binary、csv、tsv），为空时按 LogOutputPath 的扩展名判断。
+	LogFormat string `toml:"LogFormat"`
 	// Log 日志记录器。
 	Log Logger `toml:"-"`
 	// lastRefreshed 上次刷新时间。
 	lastRefreshed time.Time
+	// lastRecycled 上次完全重建查询（或首次刷新）的时间。
+	lastRecycled time.Time
+	// refreshCheck 刷新计数器的开销记录。
+	refreshCheck refreshCheck
+	// refreshTuning 刷新时计数器集合的变化及自动调整的刷新间隔。
+	refreshTuning refreshTuning
+	// refreshPacing 正在进行的刷新中添加计数器的进度，没有正在进行的刷新时为 nil。
+	refreshPacing atomic.Pointer[refreshPacing]
+	// lateErrors 被放弃等待的主机在之后返回的错误。
+	lateErrors lateErrors
+	// registryRebuilt 本次采集中是否遇到表明计数器注册表被重建的错误。
+	registryRebuilt atomic.Bool
+	// lastRegistryRebuild 上一次因计数器注册表被重建而自动重建查询的时间。
+	lastRegistryRebuild time.Time
+	// gatherCycle 当前采集周期序号，用于 GatherEvery。
+	gatherCycle uint64
+	// lastGathered 各对象上一次采集的时间，用于 Interval。
+	lastGathered map[objectID]time.Time
+	// pendingReload 通过 Reload 暂存、等待下一次采集时应用的配置。
+	pendingReload *reloadConfig
+	// configLock 保护热更新时对 Sources 与 Object 的替换，采集以外读取二者时需持有读锁。
+	configLock sync.RWMutex
+	// reloadLock 保护 pendingReload 与 configFingerprint。
+	reloadLock sync.Mutex
+	// configFingerprint 当前生效配置的指纹，由 updateConfigFingerprint 计算。
+	configFingerprint string
+	// schedulerCancel 停止内部调度器的函数，调度器未运行时为 nil。
+	schedulerCancel context.CancelFunc
+	// schedulerDone 调度器退出时关闭。
+	schedulerDone chan struct{}
+	// schedulerLock 保护 schedulerCancel 和 schedulerDone。
+	schedulerLock sync.Mutex
 	// queryCreator 性能查询创建器。
 	queryCreator performanceQueryCreator
 	// hostCounters 主机计数器信息映射。
 	hostCounters map[string]*hostCountersInfo
+	// pendingHostCounters 两阶段刷新中已完成首次采样、等待切换的主机计数器信息映射。
+	pendingHostCounters map[string]*hostCountersInfo
 	// cachedHostname 缓存的主机名。
 	cachedHostname string
+	// hostnameOnce 保证主机名只查询一次。
+	hostnameOnce sync.Once
+	// stats 插件自身的运行状态。
+	stats selfMetrics
+	// transliterationCollisions 已经报告过的 Transliterate 冲突。
+	transliterationCollisions transliterationCollisions
+	// instanceIDs 实例到稳定标识的映射，刷新计数器后依然保留。
+	instanceIDs instanceIDCache
+	// history 最近 History 时长内的历史样本。
+	history historyBuffer
+	// localizedNames 英文名称（小写）到本地化名称的映射，用于不支持 AddEnglishCounter 的系统。
+	localizedNames map[string]string
+	// counterNames 字段名到原始计数器名称的映射，用于导出 perfmon CSV。
+	counterNames counterNameIndex
+	// activeProfile 当前生效的采集档位。
+	activeProfile string
+	// profileChanged 档位切换后是否尚未重新解析计数器。
+	profileChanged bool
+	// burstActive 是否正在进行突发采集。
+	burstActive bool
+	// burstUntil 突发采集的结束时间。
+	burstUntil time.Time
+	// burstPrevious 突发采集开始前的档位，结束后切回。
+	burstPrevious string
+	// profileLock 保护档位和突发采集状态。
+	profileLock sync.Mutex
 
 	// collector 采集器。
 	collect CollectFunc
+	// capture 用于 GatherBySource 收集本次采集输出的指标。
+	capture CollectFunc
+	// routes 通过 AddCollectFunc 注册的附加采集回调。
+	routes []collectRoute
+	// enrichers 通过 AddEnrichFunc 注册的指标增强函数。
+	enrichers []EnrichFunc
+	// sinks 通过 RegisterSink 注册的具名输出。
+	sinks map[string]CollectFunc
+	// previousRoutes 通过 AddCollectWithPreviousFunc 注册的采集回调。
+	previousRoutes []previousRoute
+	// backpressureFuncs 通过 AddBackpressureFunc 注册的背压检测函数。
+	backpressureFuncs []BackpressureFunc
+	// flushFuncs 通过 AddFlushFunc 注册的刷新函数。
+	flushFuncs []FlushFunc
+	// gatherDoneFuncs 通过 AddGatherDoneFunc 注册的采集结束回调。
+	gatherDoneFuncs []GatherDoneFunc
+	// backpressure 输出背压状态。
+	backpressure backpressureState
+	// previous 各序列字段上一次的值。
+	previous previousValues
+	// sampler 按 EmitEvery 或 FlushInterval 抽样输出的序列窗口。
+	sampler emissionSampler
+	// gatherLock 保证同一时间只有一次采集，Close 在持有该锁时释放查询。
+	gatherLock sync.Mutex
+	// closed 是否已调用 Close，由 gatherLock 保护。
+	closed bool
+	// collectionErrors 通过 Errors 取得的错误通道，未调用 Errors 时为 nil。
+	collectionErrors chan CollectionError
+	// collectionErrorsLock 保护 collectionErrors。
+	collectionErrorsLock sync.Mutex
+	// derivatives 按 Derivative 计算变化率所需的上一次原始值。
+	derivatives derivatives
+	// serviceProcesses 按 Services 解析得到的服务进程。
+	serviceProcesses serviceProcesses
+	// processPaths 按 ProcessPath 查询得到的本机进程路径。
+	processPaths processPaths
+	// volumeLetters NormalizeInstanceNames 使用的本机卷到盘符的映射。
+	volumeLetters volumeLetters
+	// tagInterner 启用 InternTags 时共享的标签映射。
+	tagInterner tagInterner
+	// fieldMetadata 测量名称到各字段元数据的索引，由 initMetadata 建立。
+	fieldMetadata map[string]map[string]FieldMetadata
+	// collectorMutexes 启用 DuplicateCollectors 时为采集的对象持有的命名互斥体，以 collectorKey 为键。
+	collectorMutexes map[string]windows.Handle
+	// duplicateObjects 上一次刷新时发现由其它进程采集的对象，以 collectorKey 为键。
+	duplicateObjects map[string]bool
+	// sharedQueries 本次刷新中按 SourceGroups 分组创建的共用查询，以分组序号为键。
+	sharedQueries map[int]*sharedQuery
+	// unmetObjects 上一次刷新时不满足 RequireService 或 RequireObjectExists 条件的对象。
+	unmetObjects map[objectHostKey]bool
+	// ignoredCounters 编译后的 IgnoredCounters 模式。
+	ignoredCounters []ignoredCounterPattern
+	// stale 各主机上一次采集到的序列，用于 StaleMarker。
+	stale staleTracker
+	// initialRefresh BackgroundInitialRefresh 在后台进行的首次解析配置。
+	initialRefresh initialRefresh
+	// instancesFilesChecked 最近一次检查 InstancesFile 是否被修改的时间。
+	instancesFilesChecked time.Time
+	// sequences 各来源已分配的批次序号，用于 SequenceField。
+	sequences batchSequences
+	// quality 各主机上一次采集到的序列及数值，用于 DataQuality。
+	quality qualityTracker
+	// missing 各主机上启用 ReportMissingInstancesAs 的对象出现过的序列。
+	missing missingTracker
+	// registryNames Provider 为 "registry" 的对象使用的各主机英文名称表。
+	registryNames registryNames
+	// registrySamples Provider 为 "registry" 的对象中各计数器上一次的原始值。
+	registrySamples registrySamples
+	// localizedPaths CacheLocalizedNames 缓存的本地化名称。
+	localizedPaths localizedNameCache
+	// counterPaths CounterPathCache 持久化的通配符展开结果。
+	counterPaths counterPathCache
+	// perfNames 通过名称索引得到的各主机英文名称与本地化名称之间的翻译。
+	perfNames perfNameMapping
+	// localizedObjects LocalizedNameTags 使用的各主机对象名称的本地化名称。
+	localizedObjects localizedObjectNames
+	// clockOffsets 各远程主机时钟偏差的估计值。
+	clockOffsets clockOffsets
+	// logWriter 写入 LogOutputPath 的日志。
+	logWriter *PdhLogWriter
+	// keepAlive 远程主机的保活状态。
+	keepAlive keepAliveState
+	// connections 使用 Credential 建立的远程会话。
+	connections map[string]bool
+	// connectionsLock 保护 connections。
+	connectionsLock sync.Mutex
+	// routesLock 保护 capture、routes、enrichers、sinks、previousRoutes、backpressureFuncs、flushFuncs、gatherDoneFuncs 以及路由规则。
+	routesLock sync.RWMutex
 }
 
-// perfObject 表示一个性能对象的配置项，用于指定需要采集的性能计数器及其实例。
-type perfObject struct {
+// ObjectConfig 表示一个性能对象的配置项，用于指定需要采集的性能计数器及其实例。
+// 对应 TOML 中的 [[object]]，也可以通过 NewObjectConfig 在代码中构造。
+type ObjectConfig struct {
 	// Sources 指定采集该对象的主机列表。
 	Sources []string `toml:"Sources"`
 	// ObjectName 性能对象名称。
 	ObjectName string `toml:"ObjectName"`
+	// Paths 完整的计数器路径列表，例如 typeperf 使用的 `\Processor(_Total)\% Processor Time`，不能与 ObjectName、Counters、Instances 同时配置。
+	Paths []string `toml:"Paths"`
 	// Counters 需要采集的计数器名称列表。
 	Counters []string `toml:"Counters"`
 	// Instances 需要采集的实例名称列表。
 	Instances []string `toml:"Instances"`
+	// InstancesFile 实例列表文件，每行一个实例名称，与 Instances 合并采集。刷新计数器时重新读取，
+	// 文件被修改后在下一次采集时立即刷新，不需要修改主配置。
+	InstancesFile string `toml:"InstancesFile"`
+	// ExpandWildcards 启用 UseWildcardsExpansion 时展开的通配符，"all"（默认）、"instances"（只展开实例）或 "counters"（只展开计数器）。
+	ExpandWildcards string `toml:"ExpandWildcards"`
+	// InstancesExclude 需要排除的实例名称列表，支持 "re:" 前缀的正则表达式。
+	InstancesExclude []string `toml:"InstancesExclude"`
 	// Measurement 采集数据对应的测量名称。
 	Measurement string `toml:"Measurement"`
 	// WarnOnMissing 缺失计数器时是否警告。
@@ -98,8 +384,113 @@ type perfObject struct {
 	FailOnMissing bool `toml:"FailOnMissing"`
 	// IncludeTotal 是否包含 _Total 实例。
 	IncludeTotal bool `toml:"IncludeTotal"`
+	// TotalOnly 是否只采集 _Total 实例，优先于包含通配符的 Instances、InstancesExclude 以及预置的实例，
+	// 单实例对象以及只列出具体实例名称的对象不受影响。
+	TotalOnly bool `toml:"TotalOnly"`
 	// UseRawValues 是否采集原始值。
 	UseRawValues bool `toml:"UseRawValues"`
+	// InstanceIDCounter 提供实例稳定标识的计数器，例如 Process 对象的 "ID Process"。
+	InstanceIDCounter string `toml:"InstanceIDCounter"`
+	// RewriteInstance 是否将 instance 标签改写为 "名称_稳定标识" 的形式。
+	RewriteInstance bool `toml:"RewriteInstance"`
+	// GatherEvery 每 N 次采集才采集一次该对象，小于等于 1 时每次都采集。
+	GatherEvery int `toml:"GatherEvery"`
+	// PerFieldTimestamps 采集原始值时，是否将每个计数器作为单独的数据点输出，并使用提供程序采集该值的时间戳。
+	PerFieldTimestamps bool `toml:"PerFieldTimestamps"`
+	// EmitEvery 每 N 个样本才输出一次，并附带期间各数值字段的最小值、最大值和平均值，小于等于 1 时每次都输出。
+	EmitEvery int `toml:"EmitEvery"`
+	// FlushInterval 按时间窗口输出：以 Interval 采集，每个窗口只输出一次，并附带窗口内各数值字段的最小值、最大值、平均值和 p95。
+	FlushInterval Duration `toml:"FlushInterval"`
+	// LowPriority 是否为低优先级对象，输出端持续背压时按 BackpressureSlowdown 降低其采集频率。
+	LowPriority bool `toml:"LowPriority"`
+	// FormatByCounterType 是否按 PDH 计数器类型格式化数值，整数计数类计数器输出为 int64 而不是 float64。
+	FormatByCounterType bool `toml:"FormatByCounterType"`
+	// Interval 该对象的采集间隔，为 0 时每次采集（调度器中为默认间隔）都会采集。
+	Interval Duration `toml:"Interval"`
+	// IncludeCounterPath 是否为每个计数器附加 "<字段名>_path" 字段，记录其完整的 PDH 路径。
+	IncludeCounterPath bool `toml:"IncludeCounterPath"`
+	// IncludeCounterMetadata 是否为每个计数器附加 "<字段名>_type" 与 "<字段名>_description" 字段，记录计数器的类型分类和说明文字。
+	IncludeCounterMetadata bool `toml:"IncludeCounterMetadata"`
+	// NormalizeCPU 是否输出按逻辑 CPU 数归一化的 "% ... Time" 字段以及 cpu_count 字段。
+	NormalizeCPU bool `toml:"NormalizeCPU"`
+	// Preset 预置配置名称，例如 "memory"，未显式配置的项使用预置值，并输出预置的派生字段。
+	Preset string `toml:"Preset"`
+	// Profiles 对象所属的采集档位，为空时在所有档位下都会采集。
+	Profiles []string `toml:"Profiles"`
+	// CounterAliases 计数器名称到输出字段名称的映射，别名按原样使用，不经过 sanitizedChars 处理。
+	CounterAliases map[string]string `toml:"CounterAliases"`
+	// NameOverride 按原样使用的测量名称，优先于 Measurement。
+	NameOverride string `toml:"NameOverride"`
+	// TagOverrides 输出前添加或覆盖的标签。
+	TagOverrides map[string]string `toml:"TagOverrides"`
+	// ExtraTags 添加到该对象指标的静态标签，优先于全局的 ExtraTags 和 SourceTags，不会覆盖标准标签。
+	ExtraTags map[string]string `toml:"ExtraTags"`
+	// EmitAsBool 以布尔值（非 0 为 true）输出的状态类计数器名称列表。
+	EmitAsBool []string `toml:"EmitAsBool"`
+	// Thresholds 按阈值追加的布尔字段，字段名到表达式 "<计数器> <运算符> <数值>" 的映射，例如 "Pages/sec > 1000"。
+	Thresholds map[string]string `toml:"Thresholds"`
+	// MeasurementRules 按标签的值将实例输出到不同测量的规则，第一条满足的规则生效。
+	MeasurementRules []MeasurementRule `toml:"MeasurementRules"`
+	// Transforms 计数器名称到换算方式的映射，可以是命名换算（例如 "bytes_to_mb"）、数值或分数（例如 "1/1048576"）。
+	Transforms map[string]string `toml:"Transforms"`
+	// FieldTypes 字段名到输出类型（int、uint、float、bool）的映射。
+	FieldTypes map[string]string `toml:"FieldTypes"`
+	// Derivative 需要计算每秒变化率的计数器名称列表，"*" 表示所有计数器，要求 UseRawValues。
+	Derivative []string `toml:"Derivative"`
+	// CollapseDuplicates 将 "名称#1"、"名称#2" 等同名实例合并为一个实例的方式，"sum" 或 "avg"，为空时不合并。
+	CollapseDuplicates string `toml:"CollapseDuplicates"`
+	// Aggregate 计数器名称到跨实例聚合函数（sum、avg、min、max）的映射，"*" 表示其它所有计数器，配置后只输出聚合结果。
+	Aggregate map[string]string `toml:"Aggregate"`
+	// Metadata 对象中所有字段共用的说明、单位和指标类型，通过 Metric.Metadata 提供给导出程序。
+	Metadata FieldMetadata `toml:"Metadata"`
+	// CounterMetadata 计数器名称到其元数据的映射，非空的项覆盖 Metadata 中的对应项。
+	CounterMetadata map[string]FieldMetadata `toml:"CounterMetadata"`
+	// Provider 数据提供程序，"pdh"（默认）、"wmi" 或 "registry"，PDH 性能库损坏时可以改为通过 WMI 性能数据类采集，
+	// 或直接读取 HKEY_PERFORMANCE_DATA 中的性能数据。
+	Provider string `toml:"Provider"`
+	// Services 服务名称列表，只采集这些服务的进程，仅用于 Process 对象，未配置 Instances 时查询所有实例。
+	Services []string `toml:"Services"`
+	// ProcessPID 是否为进程实例添加 pid 标签，仅用于 Process 与 Process V2 对象。
+	ProcessPID bool `toml:"ProcessPID"`
+	// ProcessPath 是否为本机进程实例添加可执行文件完整路径的 process_path 标签，仅用于 Process 与 Process V2 对象。
+	ProcessPath bool `toml:"ProcessPath"`
+	// ReportMissingInstancesAs 实例消失后的报告方式，"zero"、"null" 或 "drop"，配置后为实例添加 instance_state 标签，为空时不处理。
+	ReportMissingInstancesAs string `toml:"ReportMissingInstancesAs"`
+	// MissingInstanceTimeout 持续报告消失实例的时长，为 0 时为 10 分钟。
+	MissingInstanceTimeout Duration `toml:"MissingInstanceTimeout"`
+	// InstanceFormat 数字实例名称的格式，例如 "%02d" 将 "3" 补零为 "03"，"core%02d" 输出 "core03"，为空时保持原样。
+	InstanceFormat string `toml:"InstanceFormat"`
+	// NormalizeInstanceNames 是否清理 instance 标签中的实例名称：去掉 "#序号" 后缀，将本机的 HarddiskVolumeN 映射为盘符，
+	// 并去掉网络接口名称中的 GUID 修饰。
+	NormalizeInstanceNames bool `toml:"NormalizeInstanceNames"`
+	// ProcessorGroupTags 是否将 Processor Information 的 "组,编号" 实例名称解析为 processor_group 与 processor_index 标签。
+	ProcessorGroupTags bool `toml:"ProcessorGroupTags"`
+	// ExcludeSystemQueues 是否丢弃 msmq 预置中的系统队列（以 "$" 结尾的队列）以及 "Computer Queues" 汇总实例。
+	ExcludeSystemQueues bool `toml:"ExcludeSystemQueues"`
+	// AggregateBy Aggregate 的分组方式，"processor_group" 按处理器组分别聚合 Processor Information 的实例，为空时合并所有实例。
+	AggregateBy string `toml:"AggregateBy"`
+	// SeparateQuery 是否在每个主机上为该对象使用单独的查询，单独调用 CollectQueryData(WithTime)，
+	// 该对象的指标使用自己的时间戳，而不是与主机上的其它对象共用一个时间戳。
+	SeparateQuery bool `toml:"SeparateQuery"`
+	// RequireService 只在安装了该 Windows 服务的主机上采集该对象，例如 "MSSQLSERVER"。
+	RequireService string `toml:"RequireService"`
+	// RequireObjectExists 只在存在该性能对象的主机上采集该对象。
+	RequireObjectExists bool `toml:"RequireObjectExists"`
+	// WMIClass Provider 为 "wmi" 时采集的 WMI 类，例如 "Win32_PerfFormattedData_PerfOS_Processor"。
+	WMIClass string `toml:"WMIClass"`
+
+	// instanceFilter 编译后的实例过滤规则，没有正则表达式和排除项时为 nil。
+	instanceFilter *instanceFilter
+	// instancesFile 最近一次读取的 InstancesFile。
+	instancesFile instancesFileState
+	// nameSanitizer 转换测量名称与计数器名称的函数，为 nil 时使用默认的 telegraf 转换。
+	nameSanitizer func(string) string
+	// tagKeySanitizer 转换非标准标签名称的函数，为 nil 时不转换。
+	tagKeySanitizer func(string) string
+	// unitSuffixes 是否将字段名称中的单位移到末尾，来自全局的 UnitSuffixes。
+	unitSuffixes bool
+	// id 对象的稳定标识，在 Init 或 Reload 时由生效的配置计算。
+	id objectID
 }
 
 // hostCountersInfo 存储主机性能计数器的相关信息。
@@ -114,6 +505,32 @@ type hostCountersInfo struct {
 	query PerformanceQuery
 	// timestamp 最近一次查询的时间戳。
 	timestamp time.Time
+	// current 正在读取的计数器，用于定位 panic。
+	current *counter
+	// quarantined 主机是否因 panic 被隔离。
+	quarantined bool
+	// busy 主机是否仍有未完成的采集，超时放弃等待的查询返回前为 true。
+	busy atomic.Bool
+	// cpuCount 主机所有处理器组的逻辑 CPU 数，用于 NormalizeCPU，首次使用时获取，获取失败时为 0。
+	cpuCount     int
+	cpuCountOnce sync.Once
+	// releaseLock 保护采集结束时 busy 的清除与 closeWhenIdle。
+	releaseLock sync.Mutex
+	// closeWhenIdle 查询在采集仍在进行时被关闭，由采集的 goroutine 返回时关闭。
+	closeWhenIdle bool
+	// abandoned 超时被放弃等待、仍未返回的采集。
+	abandoned abandonedCollections
+	// fieldNames 已使用的字段名称到计数器名称的映射，用于检测重名字段。
+	fieldNames map[string]string
+	// cycle 本主机正在进行的采集轮次，用于共用查询的分组每轮只采集一次。
+	cycle uint64
+	// deferred 查询打开前等待添加的计数器，启用 DeferRemoteOpen 时远程主机的 query 在采集时才创建。
+	deferred []deferredItem
+	// retries 添加失败、按 RetryMissingCounters 等待重试的计数器。
+	retries []retryItem
+	// separateObject 配置了 SeparateQuery 的对象名称，该查询只包含这个对象的计数器，单独采集并使用自己的时间戳；
+	// 为空时为主机共用的查询。
+	separateObject string
 }
 
 // counter 表示一个性能计数器的配置和状态信息。
@@ -124,8 +541,10 @@ type counter struct {
 	computer string
 	// objectName 计数器所属的性能对象名称。
 	objectName string
-	// counter 计数器名称。
+	// counter 清洗后的计数器名称，用作字段名。
 	counter string
+	// name 原始的计数器名称。
+	name string
 	// instance 计数器实例名称。
 	instance string
 	// measurement 计数器对应的测量名称。
@@ -136,6 +555,20 @@ type counter struct {
 	useRawValue bool
 	// counterHandle 计数器句柄。
 	counterHandle pdhCounterHandle
+	// quarantined 计数器是否因 panic 被隔离。
+	quarantined bool
+	// object 计数器所属的性能对象配置。
+	object *ObjectConfig
+	// pattern 展开前配置的计数器路径，用于 ResolveConfig。
+	pattern string
+	// counterType PDH 计数器类型，仅在启用 FormatByCounterType 时获取。
+	counterType uint32
+	// integer 是否按 64 位整数读取格式化值。
+	integer bool
+	// typeClass 计数器类型的分类，仅在启用 IncludeCounterMetadata 时获取。
+	typeClass string
+	// description 计数器的说明文字，仅在启用 IncludeCounterMetadata 时获取。
+	description string
 }
 
 // instanceGrouping 用于将计数器数据分组为实例组。
@@ -155,6 +588,15 @@ func (*WinPerfCounters) SampleConfig() string {
 }
 
 func (m *WinPerfCounters) Init() error {
+	if m.Log.Name == "" && m.Log.Handler == nil {
+		m.Log.Name = "win_perf_counters"
+	}
+	m.gatherLock.Lock()
+	m.closed = false
+	m.gatherLock.Unlock()
+	if err := m.applyFeatureFlags(); err != nil {
+		return err
+	}
 	// Check the buffer size
 	if m.MaxBufferSize < Size(initialBufferSize) {
 		return fmt.Errorf("maximum buffer size should at least be %d", 2*initialBufferSize)
@@ -162,147 +604,585 @@ func (m *WinPerfCounters) Init() error {
 	if m.MaxBufferSize > math.MaxUint32 {
 		return fmt.Errorf("maximum buffer size should be smaller than %d", uint32(math.MaxUint32))
 	}
+	if m.MaxConcurrentHosts < 0 {
+		return fmt.Errorf("invalid MaxConcurrentHosts %d, expected 0 or a positive number", m.MaxConcurrentHosts)
+	}
+	if m.NameRetries < 0 {
+		return fmt.Errorf("invalid NameRetries %d, expected 0 or a positive number", m.NameRetries)
+	}
+	if m.MaxRetries < 0 {
+		return fmt.Errorf("invalid MaxRetries %d, expected 0 or a positive number", m.MaxRetries)
+	}
 
-	if m.UseWildcardsExpansion && !m.LocalizeWildcardsExpansion {
-		// Counters must not have wildcards with this option
-		found := false
-		wildcards := []string{"*", "?"}
-
-		for _, object := range m.Object {
-			for _, wildcard := range wildcards {
-				if strings.Contains(object.ObjectName, wildcard) {
-					found = true
-					m.Log.Errorf("Object: %s, contains wildcard %s", object.ObjectName, wildcard)
-				}
-			}
-			for _, counter := range object.Counters {
-				for _, wildcard := range wildcards {
-					if strings.Contains(counter, wildcard) {
-						found = true
-						m.Log.Errorf("Object: %s, counter: %s contains wildcard %s", object.ObjectName, counter, wildcard)
-					}
-				}
-			}
-		}
+	if m.Simulate {
+		m.queryCreator = simulatedQueryCreator{generator: &syntheticGenerator{}}
+	}
+	objects, err := m.prepareObjects(m.Sources, m.Object)
+	if err != nil {
+		return err
+	}
+	m.Object = objects
+	if err := m.initIgnoredCounters(); err != nil {
+		return err
+	}
+	if err := m.validateDuplicateFields(); err != nil {
+		return err
+	}
+	if err := m.validateStaleMarker(); err != nil {
+		return err
+	}
+	if err := m.validateAutoTuneRefresh(); err != nil {
+		return err
+	}
+	if err := m.validateRefreshBatches(); err != nil {
+		return err
+	}
+	if err := m.validateDataQuality(); err != nil {
+		return err
+	}
+	if err := m.validateDuplicateCollectors(); err != nil {
+		return err
+	}
+	if err := m.validateTagKeyOverrides(); err != nil {
+		return err
+	}
 
-		if found {
-			return errors.New("wildcards can't be used with LocalizeWildcardsExpansion=false")
+	m.initAliases()
+	m.initMetadata()
+	if err := m.initProfiles(); err != nil {
+		return err
+	}
+	m.warnRefreshInterval()
+	if err := m.initBursts(); err != nil {
+		return err
+	}
+	if err := m.initRoutes(); err != nil {
+		return err
+	}
+	if m.cachesCounterPaths() {
+		if err := m.loadCounterPaths(); err != nil {
+			return err
 		}
 	}
+	m.updateConfigFingerprint()
+	m.recordBuildInfo()
+	m.startInitialRefresh()
 	return nil
 }
 
-// Gather 收集性能计数器数据。
-// 如果需要刷新计数器(根据 CountersRefreshInterval 配置)，会先清理旧的查询，重新解析配置并收集初始数据。
-// 然后对每个主机并发收集计数器数据。
+// prepareObjects 展开 objects 中的计数器路径、实例编号范围与预置，校验展开后的对象以及主机 sources，
+// 并初始化各对象的实例过滤、名称处理与对象标识，返回可直接采集的对象配置。
+// Init 与 ReloadObjects 共用，热更新的配置与启动时的配置经过相同的校验。
+func (m *WinPerfCounters) prepareObjects(sources []string, objects []ObjectConfig) ([]ObjectConfig, error) {
+	objects, err := m.expandPaths(objects)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.expandInstanceRanges(objects); err != nil {
+		return nil, err
+	}
+	if objects, err = m.applyPresets(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateFieldTypes(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateMetadata(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validatePostProcessing(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateServices(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateProcessTags(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateExpandWildcards(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateCollapseDuplicates(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateTransforms(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateMissingInstances(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateInstanceFormats(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateProcessorGroups(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateSeparateQueries(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateProviders(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateAgentSources(sources, objects); err != nil {
+		return nil, err
+	}
+	if err := m.loadInstancesFiles(objects); err != nil {
+		return nil, err
+	}
+	if err := m.initInstanceFilters(objects); err != nil {
+		return nil, err
+	}
+	if err := m.initNameSanitizers(objects); err != nil {
+		return nil, err
+	}
+	if err := m.initMeasurementRules(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateExtraTags(objects); err != nil {
+		return nil, err
+	}
+	if err := m.validateSourceGroups(); err != nil {
+		return nil, err
+	}
+	m.warnLogSourceRefresh(sources, objects)
+
+	if err := m.checkWildcards(objects); err != nil {
+		return nil, err
+	}
+	if err := m.checkObjectProfiles(objects); err != nil {
+		return nil, err
+	}
+	if err := assignObjectIDs(objects); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// Gather 收集性能计数器数据，等价于以 context.Background() 调用 GatherContext。
 func (m *WinPerfCounters) Gather() error {
-	// Parse the config once
-	var err error
+	return m.GatherContext(context.Background())
+}
+
+// GatherContext 收集性能计数器数据。
+// 如果需要刷新计数器(根据 CountersRefreshInterval 配置)，会先清理旧的查询，重新解析配置并收集初始数据。
+// 然后对每个主机并发收集计数器数据，同时采集的主机数量受 MaxConcurrentHosts 限制。
+// 各主机相互独立，一个主机失败（包括刷新计数器时的首次采样失败）不影响其它主机的数据，
+// 所有主机的错误合并后返回，并逐个传给 OnError。
+//
+// ctx 被取消或某个主机超过 CollectTimeout 仍未完成时不再等待该主机，其本次的数据会被丢弃。
+// PDH 查询本身无法中断，该主机会在之前的查询返回前被跳过。
+//
+// 可以在多个 goroutine 中同时调用，同一时间只有一次采集，其它调用等待该次采集结束后依次进行。
+// 启用 BackgroundInitialRefresh 时，计数器在后台添加期间立即返回包装了 ErrNotReady 的错误。
+func (m *WinPerfCounters) GatherContext(ctx context.Context) error {
+	if err := m.initialRefreshPending(); err != nil {
+		return err
+	}
+	m.gatherLock.Lock()
+	defer m.gatherLock.Unlock()
+	return m.gatherLocked(ctx)
+}
+
+// gatherLocked 执行一次采集并上报错误，调用方需持有 gatherLock。
+func (m *WinPerfCounters) gatherLocked(ctx context.Context) error {
+	if m.closed {
+		return ErrClosed
+	}
+	// 后台刷新在批次之间等待时释放了 gatherLock，此时计数器集合尚不完整
+	if err := m.initialRefreshPending(); err != nil {
+		return err
+	}
+	err := m.gatherContext(ctx)
+	m.reportErrors(err)
+	return err
+}
+
+// gatherContext 执行 GatherContext 的一次采集。
+func (m *WinPerfCounters) gatherContext(ctx context.Context) error {
+	start := time.Now()
+	refreshed := false
 
-	// 检查是否需要刷新计数器
-	if m.lastRefreshed.IsZero() || (m.CountersRefreshInterval > 0 && m.lastRefreshed.Add(time.Duration(m.CountersRefreshInterval)).Before(time.Now())) {
+	// 两阶段刷新时，上一次准备好的计数器集合已完成首次采样，在此切换
+	if m.pendingHostCounters != nil {
 		if err := m.cleanQueries(); err != nil {
 			return err
 		}
+		m.hostCounters = m.pendingHostCounters
+		m.pendingHostCounters = nil
+	}
 
-		if err := m.parseConfig(); err != nil {
-			return err
-		}
-		for _, hostCounterSet := range m.hostCounters {
-			// some counters need two data samples before computing a value
-			if err = hostCounterSet.query.CollectData(); err != nil {
-				return m.checkError(err)
+	// 突发采集到期后切回原档位，切换档位后立即刷新计数器
+	m.expireBurst()
+	if m.takeProfileChange() {
+		m.lastRefreshed = time.Time{}
+	}
+
+	// 热更新的配置在此生效，总是使用两阶段刷新，本次仍按旧配置采集
+	replaced, reloaded := m.applyReload()
+
+	// 检查是否需要刷新计数器，个别主机的首次采样失败不影响其它主机的采集，其错误在最后一并返回
+	var refreshErrs error
+	// 按 QueryRecycleInterval 重建查询时总是创建新的查询，并以两阶段刷新的方式切换，不丢失采样
+	recycle := m.recycleDue(time.Now())
+	if reloaded || recycle || m.instancesFilesChanged(time.Now()) || m.lastRefreshed.IsZero() || (m.CountersRefreshInterval > 0 && m.lastRefreshed.Add(m.refreshInterval()).Before(time.Now())) {
+		baseline := reloaded || m.lastRefreshed.IsZero()
+		var err error
+		if m.initialRefresh.done {
+			// 后台添加的计数器已完成首次采样，热更新的配置在其基础上以两阶段刷新生效
+			refreshErrs, err = m.takeInitialRefresh()
+			if err == nil && reloaded {
+				var reloadErrs error
+				reloadErrs, err = m.prepareRefresh()
+				refreshErrs = errors.Join(refreshErrs, reloadErrs)
 			}
+		} else if m.hostCounters == nil && m.lastRefreshed.IsZero() && m.startInitialRefresh() {
+			return m.notReadyError()
+		} else if m.IncrementalRefresh && !reloaded && !recycle && m.hostCounters != nil && !m.hostsBusy() {
+			refreshErrs, err = m.refreshIncremental()
+		} else if (m.TwoPhaseRefresh || reloaded || recycle) && m.hostCounters != nil {
+			refreshErrs, err = m.prepareRefresh()
+		} else {
+			refreshErrs, err = m.refreshAll(ctx)
+		}
+		if err != nil {
+			return errors.Join(refreshErrs, err)
 		}
 		m.lastRefreshed = time.Now()
-		// minimum time between collecting two samples
-		time.Sleep(time.Second)
+		m.stats.incr(map[string]string{}, "refreshes", 1)
+		refreshed = true
+		m.noteRefresh(m.lastRefreshed, recycle)
+		m.tuneRefresh(baseline)
+		if err := m.saveCounterPaths(); err != nil {
+			m.Log.Warnf("Saving counter path cache failed: %v", err)
+		}
 	}
+	m.checkRefresh(start, refreshed, time.Since(start))
 
-	// 收集每个主机的计数器数据
-	for _, hostCounterSet := range m.hostCounters {
-		if m.UsePerfCounterTime && hostCounterSet.query.IsVistaOrNewer() {
-			// 使用性能计数器时间戳
-			hostCounterSet.timestamp, err = hostCounterSet.query.CollectDataWithTime()
-			if err != nil {
-				return err
-			}
-		} else {
-			// 使用当前时间作为时间戳
-			hostCounterSet.timestamp = time.Now()
-			if err := hostCounterSet.query.CollectData(); err != nil {
-				return err
-			}
+	// 日志在首次解析计数器后创建，之后刷新计数器时保持不变
+	if m.LogOutputPath != "" && m.logWriter == nil {
+		if err := m.openLogWriter(); err != nil {
+			return err
+		}
+	}
+
+	cycle := m.gatherCycle
+	m.gatherCycle++
+	due := m.dueObjects(cycle, time.Now())
+	// 旧计数器集合关联的是被替换的对象配置，本次全部采集
+	for i := range replaced {
+		if _, ok := due[replaced[i].id]; !ok {
+			due[replaced[i].id] = &replaced[i]
 		}
 	}
 
 	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	errs := []error{refreshErrs}
+	pool := newHostPool(m.MaxConcurrentHosts)
 	// iterate over computers
 	for _, hostCounterInfo := range m.hostCounters {
+		if hostCounterInfo.quarantined || !hostCounterInfo.hasDueCounters(due) {
+			continue
+		}
+		if !hostCounterInfo.busy.CompareAndSwap(false, true) {
+			m.Log.Warnf("Skipping host %q, previous collection has not finished yet", hostCounterInfo.computer)
+			m.stats.incr(map[string]string{"source": hostCounterInfo.tag}, "skipped_gathers", 1)
+			continue
+		}
+		hostCounterInfo.cycle = cycle
+		// 推迟打开的查询和等待重试的计数器在此逐个添加，addItem 修改的共享状态只在持有 gatherLock 的 goroutine 中访问
+		if hostCounterInfo.query == nil {
+			err := m.checkErrors(m.openDeferred(ctx, hostCounterInfo))
+			hostCounterInfo.busy.Store(false)
+			if err != nil {
+				errLock.Lock()
+				errs = append(errs, err)
+				errLock.Unlock()
+			}
+			continue
+		}
+		m.retryMissing(hostCounterInfo)
 		wg.Add(1)
 		go func(hostInfo *hostCountersInfo) {
-			m.Log.Debugf("Gathering from %s", hostInfo.computer)
-			start := time.Now()
-			err := m.gatherComputerCounters(hostInfo)
-			m.Log.Debugf("Gathering from %s finished in %v", hostInfo.computer, time.Since(start))
-			if err != nil && m.checkError(err) != nil {
-				_ = fmt.Errorf("error during collecting data on host %q: %w", hostInfo.computer, err)
-			}
-			wg.Done()
+			defer wg.Done()
+			if err := pool.acquire(ctx); err != nil {
+				hostInfo.busy.Store(false)
+				errLock.Lock()
+				errs = append(errs, wrapCounterError("collect", hostInfo.computer, "", "", err))
+				errLock.Unlock()
+				return
+			}
+			defer pool.release()
+			if err := m.checkErrors(m.gatherHost(ctx, hostInfo, due)); err != nil {
+				errLock.Lock()
+				errs = append(errs, err)
+				errLock.Unlock()
+			}
+			m.touchKeepAlive(hostInfo.computer, time.Now())
 		}(hostCounterInfo)
 	}
+	for computer, objects := range m.wmiObjects(due) {
+		wg.Add(1)
+		go func(computer string, objects []*ObjectConfig) {
+			defer wg.Done()
+			if err := pool.acquire(ctx); err != nil {
+				errLock.Lock()
+				errs = append(errs, wrapCounterError("collect", computer, "", "", err))
+				errLock.Unlock()
+				return
+			}
+			defer pool.release()
+			if err := m.checkErrors(m.gatherSafe(computer, m.sourceTag(computer), nil, func() error {
+				return m.gatherWMIHost(ctx, computer, objects)
+			})); err != nil {
+				errLock.Lock()
+				errs = append(errs, err)
+				errLock.Unlock()
+			}
+		}(computer, objects)
+	}
+	for _, source := range m.agentSources() {
+		wg.Add(1)
+		go func(source string) {
+			defer wg.Done()
+			if err := pool.acquire(ctx); err != nil {
+				errLock.Lock()
+				errs = append(errs, wrapCounterError("collect", source, "", "", err))
+				errLock.Unlock()
+				return
+			}
+			defer pool.release()
+			if err := m.checkErrors(m.gatherSafe(source, source, nil, func() error {
+				return m.gatherAgent(ctx, source)
+			})); err != nil {
+				errLock.Lock()
+				errs = append(errs, err)
+				errLock.Unlock()
+			}
+		}(source)
+	}
+	for computer, objects := range m.registryObjects(due) {
+		wg.Add(1)
+		go func(computer string, objects []*ObjectConfig) {
+			defer wg.Done()
+			if err := pool.acquire(ctx); err != nil {
+				errLock.Lock()
+				errs = append(errs, wrapCounterError("collect", computer, "", "", err))
+				errLock.Unlock()
+				return
+			}
+			defer pool.release()
+			if err := m.checkErrors(m.gatherSafe(computer, m.sourceTag(computer), nil, func() error {
+				return m.gatherRegistryHost(ctx, computer, objects)
+			})); err != nil {
+				errLock.Lock()
+				errs = append(errs, err)
+				errLock.Unlock()
+			}
+		}(computer, objects)
+	}
 
 	wg.Wait()
-	return nil
+	if err := m.rebuildAfterRegistryChange(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if m.logWriter != nil {
+		if err := m.logWriter.Update(); err != nil {
+			errs = append(errs, fmt.Errorf("updating log %q failed: %w", m.LogOutputPath, err))
+		}
+	}
+	errs = append(errs, m.takeLateErrors())
+	err := errors.Join(errs...)
+	m.recordGatherErrors(err)
+	if m.SelfMetrics {
+		m.stats.flush(m.emit, time.Now())
+	}
+	m.notifyGatherDone()
+	if m.History > 0 {
+		m.history.prune(time.Duration(m.History), time.Now())
+	}
+	m.previous.prune(time.Now())
+	m.sampler.prune(time.Now())
+	m.derivatives.prune(time.Now())
+	m.tagInterner.prune(time.Now())
+	m.instanceIDs.prune()
+	m.processPaths.prune()
+	return err
 }
 
-func (m *WinPerfCounters) hostname() string {
-	if m.cachedHostname != "" {
-		return m.cachedHostname
+// gatherHost 在单独的 goroutine 中收集一个主机的数据，并在 ctx 取消或超过 CollectTimeout 时停止等待。
+//
+// 返回值：
+//
+//	error：收集数据失败、读取计数器失败或等待超时时返回相应错误，被 IgnoredErrors 忽略的错误除外。
+func (m *WinPerfCounters) gatherHost(ctx context.Context, hostInfo *hostCountersInfo, due dueObjects) error {
+	if m.CollectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(m.CollectTimeout))
+		defer cancel()
 	}
-	hostname, err := os.Hostname()
-	if err != nil {
-		m.cachedHostname = "localhost"
+
+	done := make(chan error, 1)
+	go func() {
+		defer hostInfo.finishCollect()
+		done <- m.gatherSafe(hostInfo.computer, hostInfo.tag, hostInfo, func() error {
+			return m.collectHost(ctx, hostInfo, due)
+		})
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		m.awaitAbandoned(hostInfo, done)
+		return wrapCounterError("collect", hostInfo.computer, "", "", ctx.Err())
+	}
+}
+
+// collectHost 收集一个主机的数据并输出指标。
+func (m *WinPerfCounters) collectHost(ctx context.Context, hostInfo *hostCountersInfo, due dueObjects) error {
+	var err error
+	withTime := (m.UsePerfCounterTime || logSourcePath(hostInfo.computer) != "") && hostInfo.query.Capabilities().CollectDataWithTime
+	before := time.Now()
+	if shared, ok := hostInfo.query.(*sharedQuery); ok {
+		hostInfo.timestamp, err = shared.collect(hostInfo.cycle, withTime)
+	} else if withTime {
+		// 使用性能计数器时间戳，日志数据源总是使用记录的时间戳
+		hostInfo.timestamp, err = hostInfo.query.CollectDataWithTime()
 	} else {
-		m.cachedHostname = hostname
+		// 使用当前时间作为时间戳
+		hostInfo.timestamp = time.Now()
+		err = hostInfo.query.CollectData()
+	}
+	if err != nil {
+		m.countPdhError(hostInfo, err)
+		// 被 IgnoredErrors 忽略的错误不传给 OnObjectGathered，各对象以 0 个样本通知
+		filtered := m.checkError(wrapCounterError("collect", hostInfo.computer, "", "", err))
+		m.notifyObjectsGathered(hostInfo, due, &objectOutcome{}, filtered)
+		return filtered
+	}
+	if m.compensatesClockSkew(hostInfo, withTime) {
+		m.compensateClockSkew(hostInfo, before, time.Now())
 	}
+
+	m.Log.Debugf("Gathering from %s", hostInfo.computer)
+	start := time.Now()
+	err = m.gatherComputerCounters(ctx, hostInfo, due)
+	m.Log.Debugf("Gathering from %s finished in %v", hostInfo.computer, time.Since(start))
+	m.recordHostStats(hostInfo, time.Since(start))
+	if err != nil && ctx.Err() == nil {
+		return m.checkError(err)
+	}
+	return nil
+}
+
+// prepareRefresh 在不影响当前计数器集合的情况下重新解析配置并完成首次采样。
+//
+// 本次采集仍使用旧的计数器集合，下一次 Gather 时切换到新集合，
+// 此时新集合已有两次采样，速率类计数器不会出现缺失或为零的数据。
+// 个别主机首次采样失败不影响切换，这些主机的错误合并后作为 hostErrs 返回。
+func (m *WinPerfCounters) prepareRefresh() (hostErrs error, err error) {
+	current := m.hostCounters
+	m.hostCounters = nil
+	err = m.parseConfig()
+	pending := m.hostCounters
+	m.hostCounters = current
+
+	if err != nil {
+		for _, hostCounterSet := range pending {
+			if hostCounterSet.query != nil {
+				_ = hostCounterSet.query.Close()
+			}
+		}
+		return nil, err
+	}
+	m.pendingHostCounters = pending
+	return m.collectInitialSamples(pending), nil
+}
+
+// hostname 返回本机的主机名，只在第一次调用时查询，可以在多个 goroutine 中同时调用。
+func (m *WinPerfCounters) hostname() string {
+	m.hostnameOnce.Do(func() {
+		hostname, err := os.Hostname()
+		if err != nil {
+			m.cachedHostname = "localhost"
+		} else {
+			m.cachedHostname = hostname
+		}
+	})
 	return m.cachedHostname
 }
 
+// sourceTag 返回主机 computer 的 source 标签值，本机使用主机名。
+func (m *WinPerfCounters) sourceTag(computer string) string {
+	if computer == "localhost" {
+		return m.hostname()
+	}
+	return computer
+}
+
 //nolint:revive //argument-limit conditionally more arguments allowed
-func (m *WinPerfCounters) addItem(counterPath, computer, objectName, instance, counterName, measurement string, includeTotal bool, useRawValue bool) error {
+func (m *WinPerfCounters) addItem(counterPath, computer, objectName, instance, counterName, measurement string, includeTotal bool, useRawValue bool, object *ObjectConfig) error {
 	origCounterPath := counterPath
 	var err error
 	var counterHandle pdhCounterHandle
 
-	sourceTag := computer
-	if computer == "localhost" {
-		sourceTag = m.hostname()
+	if m.counterIgnored(counterPath) {
+		m.Log.Debugf("Ignoring counter %q", counterPath)
+		return nil
 	}
+
+	// 启用通配符展开时这里添加的只是展开用的模板，进度按展开后的计数器记录
+	if !m.UseWildcardsExpansion {
+		m.queueCounterAdds(1)
+		if err := m.paceCounterAdd(); err != nil {
+			return err
+		}
+	}
+
+	sourceTag := m.sourceTag(computer)
 	if m.hostCounters == nil {
 		m.hostCounters = make(map[string]*hostCountersInfo)
 	}
-	hostCounter, ok := m.hostCounters[computer]
+	key := queryKey(computer, object)
+	hostCounter, ok := m.hostCounters[key]
 	if !ok {
-		hostCounter = &hostCountersInfo{computer: computer, tag: sourceTag}
-		m.hostCounters[computer] = hostCounter
-		hostCounter.query = m.queryCreator.newPerformanceQuery(computer, uint32(m.MaxBufferSize))
+		if err := m.connectSource(computer); err != nil {
+			return err
+		}
+		hostCounter = m.newObjectHostInfo(computer, sourceTag, object)
+		m.hostCounters[key] = hostCounter
 		if err := hostCounter.query.Open(); err != nil {
 			return err
 		}
 		hostCounter.counters = make([]*counter, 0)
 	}
 
-	if !hostCounter.query.IsVistaOrNewer() {
-		counterHandle, err = hostCounter.query.AddCounterToQuery(counterPath)
-		if err != nil {
+	err = m.retryNameResolution(origCounterPath, func() error {
+		if handle, ok := m.addCachedLocalizedCounter(hostCounter.query, hostCounter.computer, objectName, instance, counterName); ok {
+			counterHandle = handle
+			return nil
+		}
+		if !hostCounter.query.Capabilities().AddEnglishCounter {
+			// 只能使用本地化名称，通过名称索引（或词典）翻译英文的对象和计数器名称
+			counterPath = formatPath(computer, m.localizePerfName(hostCounter.computer, objectName), instance, m.localizePerfName(hostCounter.computer, counterName))
+			var err error
+			counterHandle, err = hostCounter.query.AddCounterToQuery(counterPath)
 			return err
 		}
-	} else {
+		var err error
 		counterHandle, err = hostCounter.query.AddEnglishCounterToQuery(counterPath)
 		if err != nil {
-			return err
+			// 配置中可能使用的是本地化名称，回退为按本地化路径添加
+			var localizedErr error
+			if counterHandle, localizedErr = hostCounter.query.AddCounterToQuery(counterPath); localizedErr != nil {
+				return err
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	if m.UseWildcardsExpansion {
@@ -311,26 +1191,52 @@ func (m *WinPerfCounters) addItem(counterPath, computer, objectName, instance, c
 		if err != nil {
 			return err
 		}
-		counters, err := hostCounter.query.ExpandWildCardPath(counterPath)
-		if err != nil {
-			return err
+		counters, cached := m.cachedCounterPaths(hostCounter.computer, counterPath, object.expandFlags())
+		if !cached {
+			err = m.retryNameResolution(origCounterPath, func() error {
+				var err error
+				counters, err = expandWildCardPath(hostCounter.query, counterPath, object.expandFlags())
+				return err
+			})
+			if err != nil {
+				return err
+			}
 		}
+		m.storeCounterPaths(hostCounter.computer, counterPath, object.expandFlags(), counters)
 
 		_, origObjectName, _, origCounterName, err := extractCounterInfoFromCounterPath(origCounterPath)
 		if err != nil {
 			return err
 		}
 
+		m.queueCounterAdds(len(counters))
 		for _, counterPath := range counters {
-			_, err := hostCounter.query.AddCounterToQuery(counterPath)
+			if err := m.paceCounterAdd(); err != nil {
+				return err
+			}
+			if m.counterIgnored(counterPath) {
+				m.Log.Debugf("Ignoring counter %q", counterPath)
+				continue
+			}
+			localizedHandle, err := hostCounter.query.AddCounterToQuery(counterPath)
+			if err != nil && cached {
+				// 缓存的实例可能已不存在，跳过即可，下一次刷新时重新展开
+				m.Log.Debugf("Skipping cached counter %q: %v", counterPath, err)
+				continue
+			}
 			if err != nil {
 				return err
 			}
 
-			computer, objectName, instance, counterName, err = extractCounterInfoFromCounterPath(counterPath)
+			var expandedComputer string
+			expandedComputer, objectName, instance, counterName, err = extractCounterInfoFromCounterPath(counterPath)
 			if err != nil {
 				return err
 			}
+			// 展开结果不带主机名时仍属于该查询的主机，不能当作本机的计数器
+			if expandedComputer != "" {
+				computer = expandedComputer
+			}
 
 			var newItem *counter
 			if !m.LocalizeWildcardsExpansion {
@@ -346,17 +1252,34 @@ func (m *WinPerfCounters) addItem(counterPath, computer, objectName, instance, c
 				} else {
 					newInstance = instance
 				}
-				counterPath = formatPath(computer, origObjectName, newInstance, origCounterName)
-				counterHandle, err = hostCounter.query.AddEnglishCounterToQuery(counterPath)
-				if err != nil {
-					return err
+				englishCounterName := origCounterName
+				if strings.ContainsAny(origCounterName, "*?") {
+					// 计数器名称中的通配符展开为本地化名称，通过名称索引翻译回英文
+					englishCounterName = m.englishPerfName(hostCounter.computer, counterName)
+				}
+				counterPath = formatPath(computer, origObjectName, newInstance, englishCounterName)
+				if m.counterIgnored(counterPath) {
+					m.Log.Debugf("Ignoring counter %q", counterPath)
+					continue
+				}
+				if m.cachesLocalizedNames() || !hostCounter.query.Capabilities().AddEnglishCounter {
+					// 展开结果中的本地化路径即为该实例的计数器，直接使用，不再按英文路径重复添加（Vista 之前的系统也无法添加英文路径）
+					if m.cachesLocalizedNames() {
+						m.localizedPaths.set(hostCounter.computer, origObjectName, englishCounterName, localizedName{object: objectName, counter: counterName})
+					}
+					counterHandle = localizedHandle
+				} else {
+					counterHandle, err = hostCounter.query.AddEnglishCounterToQuery(counterPath)
+					if err != nil {
+						return err
+					}
 				}
 				newItem = newCounter(
 					counterHandle,
 					counterPath,
 					computer,
 					origObjectName, instance,
-					origCounterName,
+					englishCounterName,
 					measurement,
 					includeTotal,
 					useRawValue,
@@ -370,7 +1293,7 @@ func (m *WinPerfCounters) addItem(counterPath, computer, objectName, instance, c
 					counterHandle,
 					counterPath,
 					computer,
-					objectName,
+					m.translateObjectName(computer, objectName),
 					instance,
 					counterName,
 					measurement,
@@ -382,8 +1305,23 @@ func (m *WinPerfCounters) addItem(counterPath, computer, objectName, instance, c
 			if instance == "_Total" && origInstance == "*" && !includeTotal {
 				continue
 			}
+			if !object.acceptInstance(origInstance, instance) {
+				continue
+			}
+			newItem.object = object
+			newItem.pattern = origCounterPath
+			object.applyNaming(newItem)
+			m.applyCounterType(hostCounter, newItem)
+			m.applyCounterMetadata(hostCounter, newItem)
+			if ok, err := m.claimFieldName(hostCounter, newItem); !ok {
+				if err != nil {
+					return err
+				}
+				continue
+			}
 
 			hostCounter.counters = append(hostCounter.counters, newItem)
+			m.counterNames.set(newItem.objectName, newItem.counter, newItem.name)
 
 			if m.PrintValid {
 				m.Log.Infof("Valid: %s", counterPath)
@@ -401,7 +1339,16 @@ func (m *WinPerfCounters) addItem(counterPath, computer, objectName, instance, c
 			includeTotal,
 			useRawValue,
 		)
+		newItem.object = object
+		newItem.pattern = origCounterPath
+		object.applyNaming(newItem)
+		m.applyCounterType(hostCounter, newItem)
+		m.applyCounterMetadata(hostCounter, newItem)
+		if ok, err := m.claimFieldName(hostCounter, newItem); !ok {
+			return err
+		}
 		hostCounter.counters = append(hostCounter.counters, newItem)
+		m.counterNames.set(newItem.objectName, newItem.counter, newItem.name)
 		if m.PrintValid {
 			m.Log.Infof("Valid: %s", counterPath)
 		}
@@ -413,42 +1360,83 @@ func (m *WinPerfCounters) addItem(counterPath, computer, objectName, instance, c
 func (m *WinPerfCounters) parseConfig() error {
 	var counterPath string
 
+	m.beginRefreshPacing()
+	defer m.endRefreshPacing()
+
 	if len(m.Sources) == 0 {
 		m.Sources = []string{"localhost"}
 	}
 
 	if len(m.Object) == 0 {
+		// 只从远程采集代理拉取时不需要配置对象
+		if len(m.agentSources()) > 0 {
+			return nil
+		}
 		err := errors.New("no performance objects configured")
 		return err
 	}
 
-	for _, PerfObject := range m.Object {
+	if m.DuplicateCollectors != "" {
+		m.claimObjects()
+	}
+	m.evaluateConditions()
+	m.reloadInstancesFiles()
+	// 每次刷新为分组创建新的查询，两阶段刷新期间旧的计数器集合仍使用原来的查询
+	m.sharedQueries = nil
+
+	profile := m.ActiveProfile()
+	for i, PerfObject := range m.Object {
+		if !PerfObject.inProfile(profile) || (!PerfObject.usesPDH() && !m.Simulate) {
+			continue
+		}
 		computers := PerfObject.Sources
 		if len(computers) == 0 {
-			computers = m.Sources
+			computers = m.hostSources()
 		}
 		for _, computer := range computers {
 			if computer == "" {
 				// localhost as a computer name in counter path doesn't work
 				computer = "localhost"
 			}
-			for _, counter := range PerfObject.Counters {
-				if len(PerfObject.Instances) == 0 {
+			if m.skipDuplicate(&m.Object[i], computer) || m.unmetObjects[objectHostKey{computer: computer, id: m.Object[i].id}] {
+				continue
+			}
+			if len(PerfObject.Services) > 0 {
+				m.resolveServices(&m.Object[i], computer)
+			}
+			for _, counter := range PerfObject.counterNames() {
+				if len(PerfObject.Instances) == 0 && len(PerfObject.Services) == 0 && PerfObject.InstancesFile == "" {
 					m.Log.Warnf("Missing 'Instances' param for object %q", PerfObject.ObjectName)
 				}
-				for _, instance := range PerfObject.Instances {
+				for _, instance := range m.Object[i].queryInstances() {
 					objectName := PerfObject.ObjectName
 					counterPath = formatPath(computer, objectName, instance, counter)
+					if m.deferOpen(computer, &m.Object[i]) {
+						m.deferItem(counterPath, computer, objectName, instance, counter,
+							PerfObject.Measurement, PerfObject.IncludeTotal, PerfObject.UseRawValues, &m.Object[i])
+						continue
+					}
 
 					err := m.addItem(counterPath, computer, objectName, instance, counter,
-						PerfObject.Measurement, PerfObject.IncludeTotal, PerfObject.UseRawValues)
+						PerfObject.Measurement, PerfObject.IncludeTotal, PerfObject.UseRawValues, &m.Object[i])
 					if err != nil {
+						err = wrapCounterError("add", computer, objectName, counterPath, err)
 						if PerfObject.FailOnMissing || PerfObject.WarnOnMissing {
 							m.Log.Errorf("Invalid counterPath %q: %s", counterPath, err.Error())
 						}
-						if PerfObject.FailOnMissing {
+						if PerfObject.FailOnMissing || errors.Is(err, errDuplicateField) || errors.Is(err, ErrClosed) {
 							return err
 						}
+						m.retryLater(computer, deferredItem{
+							counterPath:  counterPath,
+							objectName:   objectName,
+							instance:     instance,
+							counterName:  counter,
+							measurement:  PerfObject.Measurement,
+							includeTotal: PerfObject.IncludeTotal,
+							useRawValue:  PerfObject.UseRawValues,
+							object:       &m.Object[i],
+						}, err)
 					}
 				}
 			}
@@ -458,48 +1446,79 @@ func (m *WinPerfCounters) parseConfig() error {
 	return nil
 }
 
-func (m *WinPerfCounters) gatherComputerCounters(hostCounterInfo *hostCountersInfo) error {
+func (m *WinPerfCounters) gatherComputerCounters(ctx context.Context, hostCounterInfo *hostCountersInfo, due dueObjects) error {
 	var value interface{}
 	var err error
 	collectedFields := make(fieldGrouping)
+	groupObjects := make(map[instanceGrouping]*ObjectConfig)
+	collectedTimes := make(fieldTimes)
+	seen := make(seenSeries)
+	failedObjects := make(dueObjects)
+	failedCounters := 0
+	var failed []error
+	var outcome objectOutcome
+	prefetched := m.prefetchArrays(hostCounterInfo, due)
 	// For iterate over the known metrics and get the samples.
-	for _, metric := range hostCounterInfo.counters {
+	for i, metric := range hostCounterInfo.counters {
+		if metric.quarantined || !due.contains(metric.object) {
+			continue
+		}
+		hostCounterInfo.current = metric
 		// collect
 		if m.UseWildcardsExpansion {
+			var timestamp time.Time
 			if metric.useRawValue {
-				value, err = hostCounterInfo.query.GetRawCounterValue(metric.counterHandle)
+				var rawValue int64
+				rawValue, timestamp, err = hostCounterInfo.query.GetRawCounterValueWithTime(metric.counterHandle)
+				value = rawValue
+			} else if metric.integer {
+				value, err = hostCounterInfo.query.GetFormattedCounterValueLarge(metric.counterHandle)
 			} else {
 				value, err = hostCounterInfo.query.GetFormattedCounterValueDouble(metric.counterHandle)
 			}
 			if err != nil {
 				// ignore invalid data  as some counters from process instances returns this sometimes
 				if !isKnownCounterDataError(err) {
-					return fmt.Errorf("error while getting value for counter %q: %w", metric.counterPath, err)
+					// 只跳过出错的计数器，同一主机的其它计数器照常输出
+					failedCounters++
+					failedObjects.add(metric.object)
+					if err := m.readFailed(hostCounterInfo, metric, err); err != nil {
+						failed = append(failed, err)
+						outcome.failed(metric.object, err)
+					}
+					continue
 				}
 				m.Log.Warnf("Error while getting value for counter %q, instance: %s, will skip metric: %v", metric.counterPath, metric.instance, err)
+				m.countPdhError(hostCounterInfo, err)
+				m.stats.incr(map[string]string{"source": hostCounterInfo.tag}, "skipped_samples", 1)
 				continue
 			}
-			addCounterMeasurement(metric, metric.instance, value, collectedFields)
+			grouping := addCounterMeasurement(metric, metric.instance, value, collectedFields)
+			groupObjects[grouping] = metric.object
+			collectedTimes.record(metric, grouping, timestamp)
+			outcome.sampled(metric.object)
 		} else {
 			var counterValues []counterValue
-			if metric.useRawValue {
-				counterValues, err = hostCounterInfo.query.GetRawCounterArray(metric.counterHandle)
+			if prefetched != nil {
+				counterValues, err = prefetched[i].values, prefetched[i].err
 			} else {
-				// counterValues, err = hostCounterInfo.query.GetFormattedCounterArrayDouble(metric.counterHandle)
-				doubleValues, err := hostCounterInfo.query.GetFormattedCounterArrayDouble(metric.counterHandle)
-				if err == nil {
-					counterValues = make([]counterValue, len(doubleValues))
-					for i, v := range doubleValues {
-						counterValues[i] = counterValue{Name: v.Name, Value: v.Value}
-					}
-				}
+				counterValues, err = readCounterArray(hostCounterInfo.query, metric)
 			}
 			if err != nil {
 				// ignore invalid data  as some counters from process instances returns this sometimes
 				if !isKnownCounterDataError(err) {
-					return fmt.Errorf("error while getting value for counter %q: %w", metric.counterPath, err)
+					// 只跳过出错的计数器，同一主机的其它计数器照常输出
+					failedCounters++
+					failedObjects.add(metric.object)
+					if err := m.readFailed(hostCounterInfo, metric, err); err != nil {
+						failed = append(failed, err)
+						outcome.failed(metric.object, err)
+					}
+					continue
 				}
 				m.Log.Warnf("Error while getting value for counter %q, instance: %s, will skip metric: %v", metric.counterPath, metric.instance, err)
+				m.countPdhError(hostCounterInfo, err)
+				m.stats.incr(map[string]string{"source": hostCounterInfo.tag}, "skipped_samples", 1)
 				continue
 			}
 			for _, cValue := range counterValues {
@@ -509,12 +1528,43 @@ func (m *WinPerfCounters) gatherComputerCounters(hostCounterInfo *hostCountersIn
 					cValue.Name = metric.instance
 				}
 
+				if len(m.ignoredCounters) > 0 && m.counterIgnored(formatPath(metric.computer, metric.objectName, cValue.Name, metric.name)) {
+					continue
+				}
 				if shouldIncludeMetric(metric, cValue) {
-					addCounterMeasurement(metric, cValue.Name, cValue.Value, collectedFields)
+					grouping := addCounterMeasurement(metric, cValue.Name, cValue.Value, collectedFields)
+					groupObjects[grouping] = metric.object
+					collectedTimes.record(metric, grouping, cValue.Timestamp)
+					outcome.sampled(metric.object)
 				}
 			}
 		}
 	}
+	hostCounterInfo.current = nil
+	// 已放弃等待的采集不再输出数据
+	if err := ctx.Err(); err != nil {
+		m.notifyObjectsGathered(hostCounterInfo, due, nil, err)
+		return err
+	}
+	m.emitGroups(hostCounterInfo, collectedFields, groupObjects, collectedTimes, seen)
+	m.stats.set(hostCounterInfo.statsTags(), "failed_counters", int64(failedCounters))
+	owned := hostCounterInfo.ownedObjects(due).without(failedObjects)
+	m.emitStaleMarkers(hostCounterInfo.computer, owned, seen, hostCounterInfo.timestamp)
+	m.reportMissingInstances(hostCounterInfo.computer, owned, seen, hostCounterInfo.timestamp)
+	m.reportQuality(hostCounterInfo, hostCounterInfo.computer, owned, seen, hostCounterInfo.timestamp)
+	m.notifyObjectsGathered(hostCounterInfo, due, &outcome, nil)
+	return errors.Join(failed...)
+}
+
+// emitGroups 按实例组处理并输出采集到的字段，PDH 与 WMI 数据源共用同一套处理流程。
+func (m *WinPerfCounters) emitGroups(hostInfo *hostCountersInfo, collectedFields fieldGrouping, groupObjects map[instanceGrouping]*ObjectConfig, collectedTimes fieldTimes, seen seenSeries) {
+	m.filterServiceProcesses(hostInfo, collectedFields, groupObjects)
+	filterPresetInstances(collectedFields, groupObjects)
+	m.applyDerivatives(hostInfo, collectedFields, groupObjects)
+	m.applyDuplicateCollapse(hostInfo, collectedFields, groupObjects)
+	m.applyAggregation(collectedFields, groupObjects)
+	batch := batchSequence{m: m, source: hostInfo.tag}
+	transliterations := make(transliterationCheck)
 	for instance, fields := range collectedFields {
 		var tags = map[string]string{
 			"objectname": instance.objectName,
@@ -522,19 +1572,53 @@ func (m *WinPerfCounters) gatherComputerCounters(hostCounterInfo *hostCountersIn
 		if len(instance.instance) > 0 {
 			tags["instance"] = instance.instance
 		}
-		if len(hostCounterInfo.tag) > 0 {
-			tags["source"] = hostCounterInfo.tag
+		if len(hostInfo.tag) > 0 {
+			tags["source"] = hostInfo.tag
+		}
+		m.applyInstanceID(hostInfo, groupObjects[instance], instance, fields, tags)
+		applyInstanceFormat(groupObjects[instance], tags)
+		m.applyInstanceNormalization(hostInfo, groupObjects[instance], tags)
+		m.applyLocalizedNameTags(hostInfo, instance.objectName, tags)
+		applyProcessorGroupTags(groupObjects[instance], instance.instance, tags)
+		applyPresetTags(groupObjects[instance], instance.instance, tags)
+		if len(instance.instance) > 0 {
+			m.applyServiceTag(hostInfo, groupObjects[instance], fields, tags)
+			m.applyProcessTags(hostInfo, groupObjects[instance], instance.instance, fields, tags)
+		}
+		m.applyCPUNormalization(hostInfo, groupObjects[instance], fields)
+		m.applyPresetFields(hostInfo, groupObjects[instance], fields)
+		applyTransforms(groupObjects[instance], fields)
+		applyFieldTypes(groupObjects[instance], fields)
+		m.applyExtraTags(hostInfo, groupObjects[instance], tags)
+		applyTagOverrides(groupObjects[instance], tags)
+		applyTagKeySanitizer(groupObjects[instance], tags)
+		applyInstanceState(groupObjects[instance], tags)
+		measurement := m.applyTransliteration(transliterations, applyMeasurementRules(groupObjects[instance], instance.name, tags), fields, tags)
+		if m.StaleMarker != "" || m.DataQuality || (groupObjects[instance] != nil && groupObjects[instance].ReportMissingInstancesAs != "") {
+			seen.add(groupObjects[instance], measurement, fields, tags, m.DataQuality)
+		}
+		if !m.sampleEmission(groupObjects[instance], measurement, fields, tags) {
+			continue
 		}
-		if m.collect != nil {
-			m.collect(instance.name, fields, tags, hostCounterInfo.timestamp)
+		batch.apply(fields)
+		if times := collectedTimes[instance]; len(times) > 0 {
+			m.emitPerField(measurement, fields, tags, times, hostInfo.timestamp)
+			continue
 		}
+		m.emit(measurement, fields, tags, hostInfo.timestamp)
 	}
-	return nil
+}
+
+// readFailed 记录计数器的读取错误并返回需要上报的错误，被 IgnoredErrors 忽略时返回 nil。
+func (m *WinPerfCounters) readFailed(hostInfo *hostCountersInfo, metric *counter, err error) error {
+	m.countPdhError(hostInfo, err)
+	m.stats.incr(map[string]string{"source": hostInfo.tag, "objectname": metric.objectName}, "read_errors", 1)
+	return m.checkError(wrapCounterError("read", hostInfo.computer, metric.objectName, metric.counterPath, err))
 }
 
 // cleanQueries 清理所有主机的性能计数器查询。
 //
-// 该方法会关闭所有主机的性能计数器查询，并清空 hostCounters 映射。
+// 该方法会关闭所有主机的性能计数器查询，并清空 hostCounters 映射。被放弃等待的采集仍在使用的查询在其返回后关闭。
 // 在重新解析配置和刷新计数器之前需要调用此方法。
 //
 // 返回值：
@@ -542,14 +1626,47 @@ func (m *WinPerfCounters) gatherComputerCounters(hostCounterInfo *hostCountersIn
 //	error：如果关闭查询时发生错误则返回相应错误，否则返回 nil。
 func (m *WinPerfCounters) cleanQueries() error {
 	for _, hostCounterInfo := range m.hostCounters {
-		if err := hostCounterInfo.query.Close(); err != nil {
-			return err
+		if err := hostCounterInfo.closeQuery(); err != nil {
+			return wrapCounterError("close", hostCounterInfo.computer, "", "", err)
 		}
 	}
 	m.hostCounters = nil
 	return nil
 }
 
+// checkWildcards 在 UseWildcardsExpansion 且未启用 LocalizeWildcardsExpansion 时检查对象名称中不包含通配符，
+// 计数器名称中的通配符展开后通过名称索引翻译回英文。
+func (m *WinPerfCounters) checkWildcards(objects []ObjectConfig) error {
+	if !m.UseWildcardsExpansion || m.LocalizeWildcardsExpansion {
+		return nil
+	}
+	// Object names must not have wildcards with this option
+	found := false
+	wildcards := []string{"*", "?"}
+
+	for _, object := range objects {
+		for _, wildcard := range wildcards {
+			if strings.Contains(object.ObjectName, wildcard) {
+				found = true
+				m.Log.Errorf("Object: %s, contains wildcard %s", object.ObjectName, wildcard)
+			}
+		}
+	}
+
+	if found {
+		return errors.New("wildcards in object names can't be used with LocalizeWildcardsExpansion=false")
+	}
+	return nil
+}
+
+// dueAt 判断对象在第 cycle 次采集时是否需要采集。
+func (o *ObjectConfig) dueAt(cycle uint64) bool {
+	if o == nil || o.GatherEvery <= 1 {
+		return true
+	}
+	return cycle%uint64(o.GatherEvery) == 0
+}
+
 // shouldIncludeMetric 判断是否应该包含某个性能计数器指标。
 //
 // 参数：
@@ -561,6 +1678,9 @@ func (m *WinPerfCounters) cleanQueries() error {
 //
 //	bool：如果应该包含该指标返回 true，否则返回 false。
 func shouldIncludeMetric(metric *counter, cValue counterValue) bool {
+	if !metric.object.acceptInstance(metric.instance, cValue.Name) {
+		return false
+	}
 	if metric.includeTotal {
 		// 如果设置了 includeTotal，包含所有计数器
 		return true
@@ -588,10 +1708,23 @@ func shouldIncludeMetric(metric *counter, cValue counterValue) bool {
 //	instanceName string：实例名称，用于区分不同的计数器实例。
 //	value interface{}：计数器采集到的值。
 //	collectFields fieldGrouping：用于收集所有计数器字段的映射。
-func addCounterMeasurement(metric *counter, instanceName string, value interface{}, collectFields fieldGrouping) {
+//
+// 返回值：
+//
+//	instanceGrouping：数据所属的实例组。
+func addCounterMeasurement(metric *counter, instanceName string, value interface{}, collectFields fieldGrouping) instanceGrouping {
 	var instance = instanceGrouping{metric.measurement, instanceName, metric.objectName}
 	if collectFields[instance] == nil {
 		collectFields[instance] = make(map[string]interface{})
 	}
-	collectFields[instance][sanitizedChars.Replace(metric.counter)] = value
+	fieldName := metric.counter
+	collectFields[instance][fieldName] = value
+	if metric.object != nil && metric.object.IncludeCounterPath {
+		collectFields[instance][fieldName+"_path"] = metric.counterPath
+	}
+	if metric.object != nil && metric.object.IncludeCounterMetadata && metric.typeClass != "" {
+		collectFields[instance][fieldName+"_type"] = metric.typeClass
+		collectFields[instance][fieldName+"_description"] = metric.description
+	}
+	return instance
 }
@@ -3,19 +3,353 @@
 package win_perf_counters
 
 import (
+	"context"
 	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
 )
 
 //go:embed sample.conf
 var sampleConfig string
 
+// NewWinPerfCounters 创建 WinPerfCounters 实例，非 Windows 平台仅支持 Simulate 模式、远程采集代理以及 WithQueryCreator 注入的数据源。
+func NewWinPerfCounters(collectFunc CollectFunc) *WinPerfCounters {
+	return &WinPerfCounters{
+		CountersRefreshInterval:    Duration(time.Second * 60),
+		LocalizeWildcardsExpansion: true,
+		MaxBufferSize:              defaultMaxBufferSize,
+		Log: Logger{
+			Name:  "win_perf_counters",
+			Quiet: false,
+		},
+		collect: collectFunc,
+	}
+}
+
+// WinPerfCounters 与 Windows 平台同名类型具有相同的配置项，便于多平台程序共用同一份代码和配置。
+// 非 Windows 平台只有 Sources 中的远程采集代理、Simulate 以及 WithQueryCreator 注入的数据源生效，其它配置项被忽略，
+// 依赖 PDH 的方法返回 ErrUnsupportedPlatform。
 type WinPerfCounters struct {
-	Log Logger `toml:"-"`
+	PrintValid                 bool                                                  `toml:"PrintValid"`
+	StrictConfig               bool                                                  `toml:"StrictConfig"`
+	PreVistaSupport            bool                                                  `toml:"PreVistaSupport,omitempty" deprecated:"1.7.0;1.35.0;determined dynamically"`
+	UsePerfCounterTime         bool                                                  `toml:"UsePerfCounterTime"`
+	CompensateClockSkew        bool                                                  `toml:"CompensateClockSkew"`
+	Presets                    []string                                              `toml:"Presets"`
+	Object                     []ObjectConfig                                        `toml:"object"`
+	Route                      []routeRule                                           `toml:"route"`
+	Profile                    string                                                `toml:"Profile"`
+	Profiles                   []collectionProfile                                   `toml:"profile"`
+	Credential                 []sourceCredential                                    `toml:"credential"`
+	AgentToken                 secretString                                          `toml:"AgentToken"`
+	Burst                      []burstTrigger                                        `toml:"burst"`
+	CountersRefreshInterval    Duration                                              `toml:"CountersRefreshInterval"`
+	AutoTuneRefresh            bool                                                  `toml:"AutoTuneRefresh"`
+	AutoTuneRefreshMin         Duration                                              `toml:"AutoTuneRefreshMin"`
+	AutoTuneRefreshMax         Duration                                              `toml:"AutoTuneRefreshMax"`
+	QueryRecycleInterval       Duration                                              `toml:"QueryRecycleInterval"`
+	UseWildcardsExpansion      bool                                                  `toml:"UseWildcardsExpansion"`
+	TwoPhaseRefresh            bool                                                  `toml:"TwoPhaseRefresh"`
+	IncrementalRefresh         bool                                                  `toml:"IncrementalRefresh"`
+	DeferRemoteOpen            bool                                                  `toml:"DeferRemoteOpen"`
+	SourceGroups               [][]string                                            `toml:"SourceGroups"`
+	LocalizeWildcardsExpansion bool                                                  `toml:"LocalizeWildcardsExpansion"`
+	CacheLocalizedNames        bool                                                  `toml:"CacheLocalizedNames"`
+	CounterPathCache           string                                                `toml:"CounterPathCache"`
+	TranslateObjectName        bool                                                  `toml:"TranslateObjectName"`
+	LocalizedNameTags          bool                                                  `toml:"LocalizedNameTags"`
+	CounterLanguage            string                                                `toml:"CounterLanguage"`
+	CounterAliases             map[string]map[string]string                          `toml:"CounterAliases"`
+	DuplicateFields            string                                                `toml:"DuplicateFields"`
+	StaleMarker                string                                                `toml:"StaleMarker"`
+	StaleMeasurement           string                                                `toml:"StaleMeasurement"`
+	BackgroundInitialRefresh   bool                                                  `toml:"BackgroundInitialRefresh"`
+	SequenceField              string                                                `toml:"SequenceField"`
+	DataQuality                bool                                                  `toml:"DataQuality"`
+	DataQualityMeasurement     string                                                `toml:"DataQualityMeasurement"`
+	DataQualityFlatlineGathers int                                                   `toml:"DataQualityFlatlineGathers"`
+	DataQualityJumpFactor      float64                                               `toml:"DataQualityJumpFactor"`
+	DataQualityDetails         bool                                                  `toml:"DataQualityDetails"`
+	BackpressureSlowdown       int                                                   `toml:"BackpressureSlowdown"`
+	BackpressureCycles         int                                                   `toml:"BackpressureCycles"`
+	IgnoredErrors              []string                                              `toml:"IgnoredErrors"`
+	IgnoredCounters            []string                                              `toml:"IgnoredCounters"`
+	MaxBufferSize              Size                                                  `toml:"MaxBufferSize"`
+	Sources                    []string                                              `toml:"Sources"`
+	Interval                   Duration                                              `toml:"Interval"`
+	CollectTimeout             Duration                                              `toml:"CollectTimeout"`
+	ArrayWorkers               int                                                   `toml:"ArrayWorkers"`
+	RefreshBatchSize           int                                                   `toml:"RefreshBatchSize"`
+	RefreshBatchDelay          Duration                                              `toml:"RefreshBatchDelay"`
+	FinalGather                bool                                                  `toml:"FinalGather"`
+	ShutdownTimeout            Duration                                              `toml:"ShutdownTimeout"`
+	MaxConcurrentHosts         int                                                   `toml:"MaxConcurrentHosts"`
+	NameRetries                int                                                   `toml:"NameRetries"`
+	RetryMissingCounters       bool                                                  `toml:"RetryMissingCounters"`
+	MaxRetries                 int                                                   `toml:"MaxRetries"`
+	InternTags                 bool                                                  `toml:"InternTags"`
+	ExtraTags                  map[string]string                                     `toml:"ExtraTags"`
+	SourceTags                 map[string]map[string]string                          `toml:"SourceTags"`
+	TagKeyOverrides            map[string]string                                     `toml:"TagKeyOverrides"`
+	DuplicateCollectors        string                                                `toml:"DuplicateCollectors"`
+	KeepAliveInterval          Duration                                              `toml:"KeepAliveInterval"`
+	SelfMetrics                bool                                                  `toml:"SelfMetrics"`
+	History                    Duration                                              `toml:"History"`
+	Simulate                   bool                                                  `toml:"Simulate"`
+	Transliterate              bool                                                  `toml:"Transliterate"`
+	NamePolicy                 string                                                `toml:"NamePolicy"`
+	FieldNameSanitizer         func(string) string                                   `toml:"-"`
+	UnitSuffixes               bool                                                  `toml:"UnitSuffixes"`
+	OnError                    func(host string, err error)                          `toml:"-"`
+	OnObjectGathered           func(host, objectName string, samples int, err error) `toml:"-"`
+	LogOutputPath              string                                                `toml:"LogOutputPath"`
+	LogFormat                  string                                                `toml:"LogFormat"`
+	Log                        Logger                                                `toml:"-"`
+
+	collect      CollectFunc
+	generator    *syntheticGenerator
+	queryCreator QueryCreator
+	// querySources 每个主机的 QuerySource，首次采集时创建。
+	querySources map[string]QuerySource
+	// configLock 与 Windows 平台相同，保护 ObjectBuilder.Add 对 Object 的追加。
+	configLock sync.RWMutex
+}
+
+// ObjectConfig 与 Windows 平台同名类型具有相同的配置项，Simulate 模式与 WithQueryCreator 注入的数据源
+// 只使用 Sources、ObjectName、Counters、Instances、Measurement、IncludeTotal、TotalOnly、UseRawValues，其它配置项被忽略。
+type ObjectConfig struct {
+	Sources                  []string                 `toml:"Sources"`
+	ObjectName               string                   `toml:"ObjectName"`
+	Paths                    []string                 `toml:"Paths"`
+	Counters                 []string                 `toml:"Counters"`
+	Instances                []string                 `toml:"Instances"`
+	InstancesFile            string                   `toml:"InstancesFile"`
+	ExpandWildcards          string                   `toml:"ExpandWildcards"`
+	InstancesExclude         []string                 `toml:"InstancesExclude"`
+	Measurement              string                   `toml:"Measurement"`
+	WarnOnMissing            bool                     `toml:"WarnOnMissing"`
+	FailOnMissing            bool                     `toml:"FailOnMissing"`
+	IncludeTotal             bool                     `toml:"IncludeTotal"`
+	TotalOnly                bool                     `toml:"TotalOnly"`
+	UseRawValues             bool                     `toml:"UseRawValues"`
+	InstanceIDCounter        string                   `toml:"InstanceIDCounter"`
+	RewriteInstance          bool                     `toml:"RewriteInstance"`
+	GatherEvery              int                      `toml:"GatherEvery"`
+	PerFieldTimestamps       bool                     `toml:"PerFieldTimestamps"`
+	EmitEvery                int                      `toml:"EmitEvery"`
+	FlushInterval            Duration                 `toml:"FlushInterval"`
+	LowPriority              bool                     `toml:"LowPriority"`
+	FormatByCounterType      bool                     `toml:"FormatByCounterType"`
+	Interval                 Duration                 `toml:"Interval"`
+	IncludeCounterPath       bool                     `toml:"IncludeCounterPath"`
+	IncludeCounterMetadata   bool                     `toml:"IncludeCounterMetadata"`
+	NormalizeCPU             bool                     `toml:"NormalizeCPU"`
+	Preset                   string                   `toml:"Preset"`
+	Profiles                 []string                 `toml:"Profiles"`
+	CounterAliases           map[string]string        `toml:"CounterAliases"`
+	NameOverride             string                   `toml:"NameOverride"`
+	TagOverrides             map[string]string        `toml:"TagOverrides"`
+	ExtraTags                map[string]string        `toml:"ExtraTags"`
+	EmitAsBool               []string                 `toml:"EmitAsBool"`
+	Thresholds               map[string]string        `toml:"Thresholds"`
+	MeasurementRules         []MeasurementRule        `toml:"MeasurementRules"`
+	Transforms               map[string]string        `toml:"Transforms"`
+	FieldTypes               map[string]string        `toml:"FieldTypes"`
+	Derivative               []string                 `toml:"Derivative"`
+	CollapseDuplicates       string                   `toml:"CollapseDuplicates"`
+	Aggregate                map[string]string        `toml:"Aggregate"`
+	Metadata                 FieldMetadata            `toml:"Metadata"`
+	CounterMetadata          map[string]FieldMetadata `toml:"CounterMetadata"`
+	Provider                 string                   `toml:"Provider"`
+	Services                 []string                 `toml:"Services"`
+	ProcessPID               bool                     `toml:"ProcessPID"`
+	ProcessPath              bool                     `toml:"ProcessPath"`
+	ReportMissingInstancesAs string                   `toml:"ReportMissingInstancesAs"`
+	MissingInstanceTimeout   Duration                 `toml:"MissingInstanceTimeout"`
+	InstanceFormat           string                   `toml:"InstanceFormat"`
+	NormalizeInstanceNames   bool                     `toml:"NormalizeInstanceNames"`
+	ProcessorGroupTags       bool                     `toml:"ProcessorGroupTags"`
+	ExcludeSystemQueues      bool                     `toml:"ExcludeSystemQueues"`
+	AggregateBy              string                   `toml:"AggregateBy"`
+	SeparateQuery            bool                     `toml:"SeparateQuery"`
+	RequireService           string                   `toml:"RequireService"`
+	RequireObjectExists      bool                     `toml:"RequireObjectExists"`
+	WMIClass                 string                   `toml:"WMIClass"`
+}
+
+// routeRule、collectionProfile、sourceCredential 与 burstTrigger 只用于解析配置，在非 Windows 平台不生效。
+type routeRule struct {
+	Measurements []string          `toml:"Measurements"`
+	Objects      []string          `toml:"Objects"`
+	Tags         map[string]string `toml:"Tags"`
+	Sinks        []string          `toml:"Sinks"`
+}
+
+type collectionProfile struct {
+	Name     string   `toml:"Name"`
+	Interval Duration `toml:"Interval"`
+}
+
+type sourceCredential struct {
+	Source   string       `toml:"Source"`
+	Username string       `toml:"Username"`
+	Password secretString `toml:"Password"`
+	Domain   string       `toml:"Domain"`
+}
+
+type burstTrigger struct {
+	Profile     string            `toml:"Profile"`
+	Duration    Duration          `toml:"Duration"`
+	Measurement string            `toml:"Measurement"`
+	Tags        map[string]string `toml:"Tags"`
+	Field       string            `toml:"Field"`
+	Above       *float64          `toml:"Above"`
+	Below       *float64          `toml:"Below"`
 }
 
 func (*WinPerfCounters) SampleConfig() string { return sampleConfig }
 
+// Init 校验 Sources 中的远程采集代理，没有启用 Simulate、远程采集代理或 WithQueryCreator 注入的数据源时返回 ErrUnsupportedPlatform。
 func (w *WinPerfCounters) Init() error {
-	w.Log.Warn("Current platform is not supported")
+	if w.Log.Name == "" && w.Log.Handler == nil {
+		w.Log.Name = "win_perf_counters"
+	}
+	for _, source := range w.Sources {
+		if err := validateAgentSource(source); err != nil {
+			return err
+		}
+	}
+	for i := range w.Object {
+		w.Object[i].applyTotalOnly()
+	}
+	if !w.Simulate {
+		if !slices.ContainsFunc(w.Sources, isAgentSource) && w.queryCreator == nil {
+			return ErrUnsupportedPlatform
+		}
+		return nil
+	}
+	w.generator = &syntheticGenerator{}
 	return nil
-}
\ No newline at end of file
+}
+
+// Close 没有需要释放的资源，直接返回 nil。
+func (*WinPerfCounters) Close() error { return nil }
+
+// Gather 从 Sources 中的远程采集代理（如 "http://winhost:7090"）拉取指标，并在 Simulate 模式下为配置的对象生成一轮合成数据，
+// 设置了 WithQueryCreator 时从其数据源采集。各采集代理与数据源的错误合并后返回。
+func (w *WinPerfCounters) Gather() error {
+	if w.collect == nil {
+		return nil
+	}
+	var errs []error
+	for _, source := range w.Sources {
+		if !isAgentSource(source) {
+			continue
+		}
+		metrics, err := w.agentClient(source).Fetch(context.Background())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("collecting from agent %q failed: %w", source, err))
+			continue
+		}
+		for _, metric := range metrics {
+			w.collect(metric.Measurement, metric.Fields, metric.Tags, metric.Timestamp)
+		}
+	}
+	if w.Simulate && w.generator != nil {
+		w.simulate()
+	} else if w.queryCreator != nil {
+		errs = append(errs, w.gatherSources())
+	}
+	return errors.Join(errs...)
+}
+
+// agentClient 返回从远程采集代理 source 拉取指标的客户端，带有 AgentToken。
+func (w *WinPerfCounters) agentClient(source string) *AgentClient {
+	return &AgentClient{URL: source, Token: string(w.AgentToken)}
+}
+
+// simulate 为配置的对象生成一轮合成数据，远程采集代理不参与模拟。
+func (w *WinPerfCounters) simulate() {
+	now := time.Now()
+	for _, object := range w.Object {
+		measurement := objectMeasurement(object)
+		for _, source := range w.objectHosts(object) {
+			sourceTag := hostTag(source)
+			for _, instance := range simulatedInstances(object) {
+				fields := make(map[string]interface{}, len(object.Counters))
+				for _, counter := range object.Counters {
+					key := source + "\\" + object.ObjectName + "(" + instance + ")\\" + counter
+					if object.UseRawValues {
+						fields[sanitizedChars.Replace(counter)+"_Raw"] = w.generator.raw(key, counter, now)
+					} else {
+						fields[sanitizedChars.Replace(counter)] = w.generator.value(key, counter, now)
+					}
+				}
+				tags := map[string]string{
+					"objectname": object.ObjectName,
+					"source":     sourceTag,
+				}
+				if instance != emptyInstance {
+					tags["instance"] = instance
+				}
+				w.collect(measurement, fields, tags, now)
+			}
+		}
+	}
+}
+
+// simulatedInstances 返回对象在 Simulate 模式下的实例名称，通配符展开为 syntheticInstances。
+func simulatedInstances(object ObjectConfig) []string {
+	if len(object.Instances) == 0 {
+		return []string{emptyInstance}
+	}
+	var instances []string
+	for _, instance := range object.Instances {
+		if instance != "*" {
+			instances = append(instances, instance)
+			continue
+		}
+		for _, name := range syntheticInstances {
+			if name == "_Total" && !object.IncludeTotal {
+				continue
+			}
+			instances = append(instances, name)
+		}
+	}
+	return instances
+}
+
+// objectHosts 返回对象的采集主机，对象未配置 Sources 时使用全局 Sources 中的非代理主机，均未配置时为本机。
+func (w *WinPerfCounters) objectHosts(object ObjectConfig) []string {
+	if len(object.Sources) > 0 {
+		return object.Sources
+	}
+	hosts := slices.DeleteFunc(slices.Clone(w.Sources), isAgentSource)
+	if len(hosts) == 0 {
+		return []string{"localhost"}
+	}
+	return hosts
+}
+
+// objectMeasurement 返回对象的 measurement 名称，未配置时为 "win_perf_counters"。
+func objectMeasurement(object ObjectConfig) string {
+	measurement := sanitizedChars.Replace(object.Measurement)
+	if measurement == "" {
+		measurement = "win_perf_counters"
+	}
+	return measurement
+}
+
+// hostTag 返回主机的 source 标签，本机使用主机名。
+func hostTag(host string) string {
+	if host == "localhost" {
+		if hostname, err := os.Hostname(); err == nil {
+			return hostname
+		}
+	}
+	return host
+}
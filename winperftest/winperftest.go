@@ -0,0 +1,243 @@
+// Package winperftest 提供可编排的 win_perf_counters.QuerySource 实现与记录指标的 CollectFunc，
+// 通过 WinPerfCounters.WithQueryCreator 注入后可在没有 PDH 的环境（包括非 Windows 的 CI）中测试配置与采集回调。
+package winperftest
+
+import (
+	"errors"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rokukoo/win_perf_counters"
+)
+
+var (
+	// ErrUnknownCounter 读取未编排的计数器时返回。
+	ErrUnknownCounter = errors.New("unknown counter")
+	// ErrUnknownHost 从 Hosts 中没有对应 Query 的主机采集时返回。
+	ErrUnknownHost = errors.New("unknown host")
+)
+
+// Query 是可编排的 QuerySource。计数器路径不含主机部分，例如 `\Processor(_Total)\% Processor Time`，
+// 匹配时忽略大小写；实例或计数器名称中的通配符展开为已编排的计数器。
+type Query struct {
+	lock       sync.Mutex
+	paths      []string
+	values     map[string][]float64
+	errs       map[string]error
+	collectErr error
+	start      time.Time
+	step       time.Duration
+	collects   int
+}
+
+// NewQuery 创建没有任何计数器的 Query。
+func NewQuery() *Query {
+	return &Query{
+		values: make(map[string][]float64),
+		errs:   make(map[string]error),
+	}
+}
+
+// Set 编排计数器在各轮采集中的值，第 n 轮采集读取 values[n-1]，超出后重复最后一个值。
+func (q *Query) Set(counterPath string, values ...float64) *Query {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	key := q.add(counterPath)
+	q.values[key] = values
+	delete(q.errs, key)
+	return q
+}
+
+// SetError 使读取计数器时返回 err。
+func (q *Query) SetError(counterPath string, err error) *Query {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	key := q.add(counterPath)
+	q.errs[key] = err
+	return q
+}
+
+// FailCollect 使之后的采集返回 err，err 为 nil 时恢复正常。
+func (q *Query) FailCollect(err error) *Query {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.collectErr = err
+	return q
+}
+
+// SetTime 使第 n 轮采集的时间为 start+(n-1)*step，未设置时使用当前时间。
+func (q *Query) SetTime(start time.Time, step time.Duration) *Query {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.start = start
+	q.step = step
+	return q
+}
+
+// Collects 返回成功采集的轮数。
+func (q *Query) Collects() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.collects
+}
+
+// Creator 返回为所有主机提供 q 的 QueryCreator。
+func (q *Query) Creator() win_perf_counters.QueryCreator {
+	return func(string) win_perf_counters.QuerySource { return q }
+}
+
+// Expand 将实例或计数器名称中的通配符展开为已编排的计数器路径，保留原路径的主机部分。
+func (q *Query) Expand(counterPath string) ([]string, error) {
+	host, local := splitHost(counterPath)
+	object, instance, counter, ok := splitPath(local)
+	if !ok {
+		return nil, errors.New("cannot parse counter path: " + counterPath)
+	}
+	if !strings.ContainsAny(instance+counter, "*?") {
+		return []string{counterPath}, nil
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	var paths []string
+	for _, p := range q.paths {
+		o, i, c, _ := splitPath(p)
+		if !strings.EqualFold(o, object) || !match(instance, i) || !match(counter, c) {
+			continue
+		}
+		paths = append(paths, host+p)
+	}
+	return paths, nil
+}
+
+// Collect 开始新一轮采集并返回采样时间。
+func (q *Query) Collect() (time.Time, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.collectErr != nil {
+		return time.Time{}, q.collectErr
+	}
+	q.collects++
+	if q.start.IsZero() {
+		return time.Now(), nil
+	}
+	return q.start.Add(time.Duration(q.collects-1) * q.step), nil
+}
+
+// Value 返回计数器在当前一轮采集中的值。
+func (q *Query) Value(counterPath string) (float64, error) {
+	_, local := splitHost(counterPath)
+	key := strings.ToLower(local)
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if err, ok := q.errs[key]; ok {
+		return 0, err
+	}
+	values, ok := q.values[key]
+	if !ok || len(values) == 0 {
+		return 0, ErrUnknownCounter
+	}
+	return values[min(max(q.collects, 1), len(values))-1], nil
+}
+
+// add 记录计数器路径并返回其键，需持有锁。
+func (q *Query) add(counterPath string) string {
+	_, local := splitHost(counterPath)
+	key := strings.ToLower(local)
+	if !slices.ContainsFunc(q.paths, func(p string) bool { return strings.EqualFold(p, local) }) {
+		q.paths = append(q.paths, local)
+	}
+	return key
+}
+
+// Hosts 返回按主机名（忽略大小写）提供 Query 的 QueryCreator，没有对应 Query 的主机采集时返回 ErrUnknownHost。
+func Hosts(queries map[string]*Query) win_perf_counters.QueryCreator {
+	return func(computer string) win_perf_counters.QuerySource {
+		for host, query := range queries {
+			if strings.EqualFold(host, computer) {
+				return query
+			}
+		}
+		return NewQuery().FailCollect(ErrUnknownHost)
+	}
+}
+
+// Recorder 记录 CollectFunc 收到的指标，可并发使用。
+type Recorder struct {
+	lock    sync.Mutex
+	metrics []win_perf_counters.Metric
+}
+
+// Collect 记录一条指标，签名与 CollectFunc 一致。
+func (r *Recorder) Collect(measurement string, fields map[string]interface{}, tags map[string]string, timestamp time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.metrics = append(r.metrics, win_perf_counters.Metric{
+		Measurement: measurement,
+		Tags:        maps.Clone(tags),
+		Fields:      maps.Clone(fields),
+		Timestamp:   timestamp,
+	})
+}
+
+// Metrics 返回已记录指标的副本。
+func (r *Recorder) Metrics() []win_perf_counters.Metric {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return slices.Clone(r.metrics)
+}
+
+// Reset 清空已记录的指标。
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.metrics = nil
+}
+
+// splitHost 将计数器路径分为 \\computer 部分与其余部分。
+func splitHost(counterPath string) (string, string) {
+	if !strings.HasPrefix(counterPath, `\\`) {
+		return "", counterPath
+	}
+	i := strings.Index(counterPath[2:], `\`)
+	if i < 0 {
+		return "", counterPath
+	}
+	return counterPath[:i+2], counterPath[i+2:]
+}
+
+// splitPath 解析不含主机部分的计数器路径 \object(instance)\counter，单实例对象的实例为空。
+func splitPath(local string) (object, instance, counter string, ok bool) {
+	separator := strings.LastIndex(local, `\`)
+	if separator <= 0 || !strings.HasPrefix(local, `\`) {
+		return "", "", "", false
+	}
+	object, counter = local[1:separator], local[separator+1:]
+	if !strings.HasSuffix(object, ")") {
+		return object, "", counter, true
+	}
+	depth := 0
+	for i := len(object) - 1; i >= 0; i-- {
+		switch object[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				return object[:i], object[i+1 : len(object)-1], counter, true
+			}
+		}
+	}
+	return "", "", "", false
+}
+
+// match 忽略大小写地按通配符 pattern 匹配 name。
+func match(pattern, name string) bool {
+	matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return err == nil && matched
+}
@@ -0,0 +1,168 @@
+package winperftest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		object   string
+		instance string
+		counter  string
+		ok       bool
+	}{
+		{`\Processor(_Total)\% Processor Time`, "Processor", "_Total", "% Processor Time", true},
+		{`\Memory\Available Bytes`, "Memory", "", "Available Bytes", true},
+		{`\Process(svchost (1))\ID Process`, "Process", "svchost (1)", "ID Process", true},
+		{`\Network Interface(Intel(R) Ethernet)\Bytes Total/sec`, "Network Interface", "Intel(R) Ethernet", "Bytes Total/sec", true},
+		{`\Processor(*)\*`, "Processor", "*", "*", true},
+		{`Processor(_Total)\% Processor Time`, "", "", "", false},
+		{`\Processor`, "", "", "", false},
+		{`\Process(svchost))\ID Process`, "", "", "", false},
+	}
+	for _, tt := range tests {
+		object, instance, counter, ok := splitPath(tt.path)
+		require.Equal(t, tt.ok, ok, tt.path)
+		require.Equal(t, tt.object, object, tt.path)
+		require.Equal(t, tt.instance, instance, tt.path)
+		require.Equal(t, tt.counter, counter, tt.path)
+	}
+}
+
+func TestSplitHost(t *testing.T) {
+	host, local := splitHost(`\\SERVER1\Memory\Available Bytes`)
+	require.Equal(t, `\\SERVER1`, host)
+	require.Equal(t, `\Memory\Available Bytes`, local)
+
+	host, local = splitHost(`\Memory\Available Bytes`)
+	require.Empty(t, host)
+	require.Equal(t, `\Memory\Available Bytes`, local)
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*", "_Total", true},
+		{"_total", "_Total", true},
+		{"svchost*", "SVCHOST#1", true},
+		{"disk?", "Disk1", true},
+		{"disk?", "Disk10", false},
+		{"% Processor Time", "% User Time", false},
+		// 非法的模式不匹配任何名称
+		{"[", "[", false},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, match(tt.pattern, tt.name), "%s ~ %s", tt.pattern, tt.name)
+	}
+}
+
+func TestQueryExpand(t *testing.T) {
+	q := NewQuery().
+		Set(`\Processor(0)\% Processor Time`, 1).
+		Set(`\Processor(1)\% Processor Time`, 2).
+		Set(`\Processor(_Total)\% User Time`, 3).
+		Set(`\Memory\Available Bytes`, 4)
+
+	paths, err := q.Expand(`\processor(*)\% Processor Time`)
+	require.NoError(t, err)
+	require.Equal(t, []string{`\Processor(0)\% Processor Time`, `\Processor(1)\% Processor Time`}, paths)
+
+	paths, err = q.Expand(`\\SERVER1\Processor(_Total)\*`)
+	require.NoError(t, err)
+	require.Equal(t, []string{`\\SERVER1\Processor(_Total)\% User Time`}, paths)
+
+	// 不含通配符的路径原样返回，即使没有编排
+	paths, err = q.Expand(`\Memory\Committed Bytes`)
+	require.NoError(t, err)
+	require.Equal(t, []string{`\Memory\Committed Bytes`}, paths)
+
+	paths, err = q.Expand(`\LogicalDisk(*)\Free Megabytes`)
+	require.NoError(t, err)
+	require.Empty(t, paths)
+
+	_, err = q.Expand(`Memory\Available Bytes`)
+	require.ErrorContains(t, err, "cannot parse counter path")
+}
+
+func TestQueryValue(t *testing.T) {
+	failed := errors.New("counter failed")
+	q := NewQuery().
+		Set(`\Memory\Available Bytes`, 10, 20).
+		SetError(`\Memory\Committed Bytes`, failed)
+
+	// 第一轮采集之前读取第一个值
+	value, err := q.Value(`\Memory\Available Bytes`)
+	require.NoError(t, err)
+	require.InDelta(t, 10.0, value, 0)
+
+	for _, want := range []float64{10, 20, 20} {
+		_, err := q.Collect()
+		require.NoError(t, err)
+		value, err := q.Value(`\\SERVER1\memory\available bytes`)
+		require.NoError(t, err)
+		require.InDelta(t, want, value, 0)
+	}
+	require.Equal(t, 3, q.Collects())
+
+	_, err = q.Value(`\Memory\Committed Bytes`)
+	require.ErrorIs(t, err, failed)
+	_, err = q.Value(`\Memory\Cache Bytes`)
+	require.ErrorIs(t, err, ErrUnknownCounter)
+
+	// Set 清除之前编排的错误
+	q.Set(`\Memory\Committed Bytes`, 5)
+	value, err = q.Value(`\Memory\Committed Bytes`)
+	require.NoError(t, err)
+	require.InDelta(t, 5.0, value, 0)
+}
+
+func TestQueryCollect(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	q := NewQuery().SetTime(start, time.Second)
+	for i := range 3 {
+		timestamp, err := q.Collect()
+		require.NoError(t, err)
+		require.Equal(t, start.Add(time.Duration(i)*time.Second), timestamp)
+	}
+
+	failed := errors.New("collect failed")
+	q.FailCollect(failed)
+	_, err := q.Collect()
+	require.ErrorIs(t, err, failed)
+	require.Equal(t, 3, q.Collects())
+}
+
+func TestHosts(t *testing.T) {
+	q := NewQuery()
+	creator := Hosts(map[string]*Query{"Server1": q})
+	require.Same(t, q, creator("SERVER1"))
+
+	_, err := creator("server2").Collect()
+	require.ErrorIs(t, err, ErrUnknownHost)
+}
+
+func TestRecorder(t *testing.T) {
+	var r Recorder
+	fields := map[string]interface{}{"value": 1.0}
+	tags := map[string]string{"instance": "_Total"}
+	r.Collect("win_cpu", fields, tags, time.Time{})
+
+	// 记录的是副本，之后修改原映射不影响已记录的指标
+	fields["value"] = 2.0
+	tags["instance"] = "0"
+	metrics := r.Metrics()
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"value": 1.0}, metrics[0].Fields)
+	require.Equal(t, map[string]string{"instance": "_Total"}, metrics[0].Tags)
+
+	r.Reset()
+	require.Empty(t, r.Metrics())
+}
@@ -0,0 +1,404 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	coinitMultithreaded = 0x0
+	clsctxInprocServer  = 0x1
+	rpcEChangedMode     = 0x80010106
+
+	// CoSetProxyBlanket values that keep the authentication of the proxy and only raise the impersonation level
+	rpcCAuthnDefault        = 0xFFFFFFFF
+	rpcCAuthzDefault        = 0xFFFFFFFF
+	rpcCAuthnLevelCall      = 3
+	rpcCImpLevelImpersonate = 3
+	eoacNone                = 0
+	coleDefaultPrincipal    = ^uintptr(0)
+	coleDefaultAuthInfo     = ^uintptr(0)
+
+	wbemFlagReturnImmediately = 0x10
+	wbemFlagForwardOnly       = 0x20
+	wbemInfinite              = 0xFFFFFFFF
+	wbemQueryLanguage         = "WQL"
+)
+
+// vtable indices of the used COM methods
+const (
+	methodRelease       = 2  // IUnknown
+	methodConnectServer = 3  // IWbemLocator
+	methodExecQuery     = 20 // IWbemServices
+	methodNext          = 4  // IEnumWbemClassObject
+	methodGet           = 4  // IWbemClassObject
+	maxVtableMethods    = 24
+)
+
+// VARTYPE values of the property values that can be decoded
+const (
+	vtEmpty = 0
+	vtNull  = 1
+	vtI2    = 2
+	vtI4    = 3
+	vtR4    = 4
+	vtR8    = 5
+	vtBSTR  = 8
+	vtBool  = 11
+	vtI1    = 16
+	vtUI1   = 17
+	vtUI2   = 18
+	vtUI4   = 19
+	vtI8    = 20
+	vtUI8   = 21
+)
+
+var (
+	clsidWbemLocator = windows.GUID{Data1: 0x4590F811, Data2: 0x1D3A, Data3: 0x11D0, Data4: [8]byte{0x89, 0x1F, 0x00, 0xAA, 0x00, 0x4B, 0x2E, 0x24}}
+	iidIWbemLocator  = windows.GUID{Data1: 0xDC12A687, Data2: 0x737F, Data3: 0x11CF, Data4: [8]byte{0x88, 0x4D, 0x00, 0xAA, 0x00, 0x4B, 0xD2, 0x4E}}
+)
+
+// comObject is the memory layout of a COM interface pointer
+type comObject struct {
+	vtable *[maxVtableMethods]uintptr
+}
+
+// variant mirrors VARIANT, the trailing pointer pads the union to its size on 64-bit systems
+type variant struct {
+	VT        uint16
+	reserved1 uint16
+	reserved2 uint16
+	reserved3 uint16
+	Val       int64
+	_         uintptr
+}
+
+var (
+	// Library
+	libOle32Dll    *syscall.DLL
+	libOleAut32Dll *syscall.DLL
+
+	// Functions
+	ole32CoInitializeEx    *syscall.Proc
+	ole32CoUninitialize    *syscall.Proc
+	ole32CoCreateInstance  *syscall.Proc
+	ole32CoSetProxyBlanket *syscall.Proc
+	oleAut32SysAllocString *syscall.Proc
+	oleAut32SysFreeString  *syscall.Proc
+	oleAut32VariantClear   *syscall.Proc
+)
+
+func init() {
+	var err error
+	if libOle32Dll, err = syscall.LoadDLL("ole32.dll"); err != nil {
+		return
+	}
+	if libOleAut32Dll, err = syscall.LoadDLL("oleaut32.dll"); err != nil {
+		return
+	}
+	ole32CoInitializeEx, _ = libOle32Dll.FindProc("CoInitializeEx")
+	ole32CoUninitialize, _ = libOle32Dll.FindProc("CoUninitialize")
+	ole32CoCreateInstance, _ = libOle32Dll.FindProc("CoCreateInstance")
+	ole32CoSetProxyBlanket, _ = libOle32Dll.FindProc("CoSetProxyBlanket")
+	oleAut32SysAllocString, _ = libOleAut32Dll.FindProc("SysAllocString")
+	oleAut32SysFreeString, _ = libOleAut32Dll.FindProc("SysFreeString")
+	oleAut32VariantClear, _ = libOleAut32Dll.FindProc("VariantClear")
+}
+
+// errWMIUnavailable 系统上无法加载 COM 相关的函数。
+var errWMIUnavailable = errors.New("WMI is not supported on this system")
+
+// hresultError 表示 COM 方法返回的失败 HRESULT。
+type hresultError struct {
+	method string
+	hr     uint32
+}
+
+func (e *hresultError) Error() string {
+	return fmt.Sprintf("%s failed with HRESULT 0x%08X", e.method, e.hr)
+}
+
+// checkHResult 在 ret 为失败的 HRESULT 时返回错误。
+func checkHResult(method string, ret uintptr) error {
+	if int32(ret) < 0 { //nolint:gosec // G115: HRESULT is a signed 32-bit value
+		return &hresultError{method: method, hr: uint32(ret)}
+	}
+	return nil
+}
+
+// call 调用 COM 对象 vtable 中的第 method 个方法。
+func (o *comObject) call(method int, args ...uintptr) uintptr {
+	ret, _, _ := syscall.SyscallN(o.vtable[method], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...) //nolint:gosec // G103: Valid use of unsafe call to pass the interface pointer
+	return ret
+}
+
+// release 释放 COM 对象的引用。
+func (o *comObject) release() {
+	o.call(methodRelease)
+}
+
+// setProxyBlanket 提高代理的模拟级别，WMI 要求至少为 impersonate，认证信息保持 ConnectServer 时使用的凭据。
+func (o *comObject) setProxyBlanket() error {
+	ret, _, _ := ole32CoSetProxyBlanket.Call(
+		uintptr(unsafe.Pointer(o)), //nolint:gosec // G103: Valid use of unsafe call to pass the proxy
+		rpcCAuthnDefault,
+		rpcCAuthzDefault,
+		coleDefaultPrincipal,
+		rpcCAuthnLevelCall,
+		rpcCImpLevelImpersonate,
+		coleDefaultAuthInfo,
+		eoacNone)
+	return checkHResult("CoSetProxyBlanket", ret)
+}
+
+// bstr 分配 BSTR 字符串，s 为空时返回 0，表示 NULL。
+func bstr(s string) (uintptr, error) {
+	if s == "" {
+		return 0, nil
+	}
+	p, err := syscall.UTF16PtrFromString(s)
+	if err != nil {
+		return 0, err
+	}
+	ret, _, _ := oleAut32SysAllocString.Call(uintptr(unsafe.Pointer(p))) //nolint:gosec // G103: Valid use of unsafe call to pass the string
+	if ret == 0 {
+		return 0, errors.New("allocating BSTR failed")
+	}
+	return ret, nil
+}
+
+// freeBSTR 释放 bstr 分配的字符串。
+func freeBSTR(s uintptr) {
+	if s != 0 {
+		_, _, _ = oleAut32SysFreeString.Call(s)
+	}
+}
+
+// value 将 VARIANT 转换为 Go 的值，空值和不支持的类型返回 nil。
+// WMI 以字符串传递 64 位整数，由调用方按需解析。
+func (v *variant) value() interface{} {
+	switch v.VT {
+	case vtI1:
+		return int64(int8(v.Val))
+	case vtI2:
+		return int64(int16(v.Val))
+	case vtI4:
+		return int64(int32(v.Val))
+	case vtI8:
+		return v.Val
+	case vtUI1:
+		return uint64(uint8(v.Val))
+	case vtUI2:
+		return uint64(uint16(v.Val))
+	case vtUI4:
+		return uint64(uint32(v.Val))
+	case vtUI8:
+		return uint64(v.Val)
+	case vtR4:
+		return float64(math.Float32frombits(uint32(v.Val)))
+	case vtR8:
+		return math.Float64frombits(uint64(v.Val))
+	case vtBool:
+		return int16(v.Val) != 0
+	case vtBSTR:
+		return windows.UTF16PtrToString(*(**uint16)(unsafe.Pointer(&v.Val))) //nolint:gosec // G103: Valid use of unsafe call to read the BSTR
+	}
+	return nil
+}
+
+// wmiSession 一个 goroutine 上到 WMI 命名空间的连接。COM 的初始化状态属于线程，会话在打开时锁定当前线程，
+// 同一主机的所有对象共用一次初始化和连接，必须在打开会话的 goroutine 中使用并关闭。
+type wmiSession struct {
+	locator  *comObject
+	services *comObject
+	// uninitialize 会话初始化了 COM，关闭时需要调用 CoUninitialize。
+	uninitialize bool
+}
+
+// openWMISession 初始化当前线程的 COM 并连接到命名空间 namespace。
+// user 为空时使用当前进程的身份，WMI 不接受为本机连接指定凭据。
+func openWMISession(namespace, user, password string) (*wmiSession, error) {
+	if ole32CoInitializeEx == nil || ole32CoUninitialize == nil || ole32CoCreateInstance == nil || ole32CoSetProxyBlanket == nil || oleAut32SysAllocString == nil || oleAut32VariantClear == nil {
+		return nil, errWMIUnavailable
+	}
+
+	runtime.LockOSThread()
+	session := &wmiSession{}
+	ret, _, _ := ole32CoInitializeEx.Call(0, coinitMultithreaded)
+	if uint32(ret) != rpcEChangedMode {
+		if err := checkHResult("CoInitializeEx", ret); err != nil {
+			runtime.UnlockOSThread()
+			return nil, err
+		}
+		session.uninitialize = true
+	}
+
+	ret, _, _ = ole32CoCreateInstance.Call(
+		uintptr(unsafe.Pointer(&clsidWbemLocator)), //nolint:gosec // G103: Valid use of unsafe call to pass CLSID
+		0,
+		clsctxInprocServer,
+		uintptr(unsafe.Pointer(&iidIWbemLocator)), //nolint:gosec // G103: Valid use of unsafe call to pass IID
+		uintptr(unsafe.Pointer(&session.locator))) //nolint:gosec // G103: Valid use of unsafe call to receive the locator
+	if err := checkHResult("CoCreateInstance", ret); err != nil {
+		session.locator = nil
+		session.close()
+		return nil, err
+	}
+
+	services, err := connectServer(session.locator, namespace, user, password)
+	if err != nil {
+		session.close()
+		return nil, err
+	}
+	session.services = services
+	return session, nil
+}
+
+// close 释放连接并恢复线程的 COM 状态。
+func (s *wmiSession) close() {
+	if s.services != nil {
+		s.services.release()
+	}
+	if s.locator != nil {
+		s.locator.release()
+	}
+	if s.uninitialize {
+		ole32CoUninitialize.Call() //nolint:errcheck // CoUninitialize has no return value
+	}
+	runtime.UnlockOSThread()
+}
+
+// query 执行 WQL 查询，返回每个结果对象中 properties 属性的值。
+func (s *wmiSession) query(query string, properties []string) ([]map[string]interface{}, error) {
+	enum, err := execQuery(s.services, query)
+	if err != nil {
+		return nil, err
+	}
+	defer enum.release()
+
+	var rows []map[string]interface{}
+	for {
+		var object *comObject
+		var returned uint32
+		ret := enum.call(methodNext,
+			wbemInfinite,
+			1,
+			uintptr(unsafe.Pointer(&object)),   //nolint:gosec // G103: Valid use of unsafe call to receive the object
+			uintptr(unsafe.Pointer(&returned))) //nolint:gosec // G103: Valid use of unsafe call to receive the count
+		if err := checkHResult("IEnumWbemClassObject::Next", ret); err != nil {
+			return nil, err
+		}
+		if returned == 0 {
+			return rows, nil
+		}
+		row, err := getProperties(object, properties)
+		object.release()
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+// connectServer 连接到 WMI 命名空间，并设置返回的 IWbemServices 代理的安全选项。
+func connectServer(locator *comObject, namespace, user, password string) (*comObject, error) {
+	resource, err := bstr(namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer freeBSTR(resource)
+	userName, err := bstr(user)
+	if err != nil {
+		return nil, err
+	}
+	defer freeBSTR(userName)
+	userPassword, err := bstr(password)
+	if err != nil {
+		return nil, err
+	}
+	defer freeBSTR(userPassword)
+
+	var services *comObject
+	ret := locator.call(methodConnectServer,
+		resource,
+		userName,
+		userPassword,
+		0,
+		0,
+		0,
+		0,
+		uintptr(unsafe.Pointer(&services))) //nolint:gosec // G103: Valid use of unsafe call to receive the services
+	if err := checkHResult("IWbemLocator::ConnectServer", ret); err != nil {
+		return nil, fmt.Errorf("connecting to %q: %w", namespace, err)
+	}
+	if err := services.setProxyBlanket(); err != nil {
+		services.release()
+		return nil, err
+	}
+	return services, nil
+}
+
+// execQuery 执行 WQL 查询，返回仅向前的结果枚举器。
+func execQuery(services *comObject, query string) (*comObject, error) {
+	language, err := bstr(wbemQueryLanguage)
+	if err != nil {
+		return nil, err
+	}
+	defer freeBSTR(language)
+	text, err := bstr(query)
+	if err != nil {
+		return nil, err
+	}
+	defer freeBSTR(text)
+
+	var enum *comObject
+	ret := services.call(methodExecQuery,
+		language,
+		text,
+		wbemFlagReturnImmediately|wbemFlagForwardOnly,
+		0,
+		uintptr(unsafe.Pointer(&enum))) //nolint:gosec // G103: Valid use of unsafe call to receive the enumerator
+	if err := checkHResult("IWbemServices::ExecQuery", ret); err != nil {
+		return nil, fmt.Errorf("query %q: %w", query, err)
+	}
+	// 远程连接时枚举器是单独的代理，同样需要设置安全选项
+	if err := enum.setProxyBlanket(); err != nil {
+		enum.release()
+		return nil, err
+	}
+	return enum, nil
+}
+
+// getProperties 读取 WMI 对象的属性值。
+func getProperties(object *comObject, properties []string) (map[string]interface{}, error) {
+	row := make(map[string]interface{}, len(properties))
+	for _, property := range properties {
+		name, err := syscall.UTF16PtrFromString(property)
+		if err != nil {
+			return nil, err
+		}
+		var v variant
+		ret := object.call(methodGet,
+			uintptr(unsafe.Pointer(name)), //nolint:gosec // G103: Valid use of unsafe call to pass the property name
+			0,
+			uintptr(unsafe.Pointer(&v)), //nolint:gosec // G103: Valid use of unsafe call to receive the value
+			0,
+			0)
+		if err := checkHResult("IWbemClassObject::Get", ret); err != nil {
+			return nil, fmt.Errorf("property %q: %w", property, err)
+		}
+		if value := v.value(); value != nil {
+			row[property] = value
+		}
+		_, _, _ = oleAut32VariantClear.Call(uintptr(unsafe.Pointer(&v))) //nolint:gosec // G103: Valid use of unsafe call to clear the VARIANT
+	}
+	return row, nil
+}
@@ -0,0 +1,282 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 对象的数据提供程序
+const (
+	providerPDH = "pdh"
+	providerWMI = "wmi"
+)
+
+const (
+	// wmiNamespace 性能数据类所在的 WMI 命名空间。
+	wmiNamespace = `root\cimv2`
+	// wmiInstanceProperty 多实例性能数据类中保存实例名称的属性。
+	wmiInstanceProperty = "Name"
+	// wmiStalePrefix 区分同一主机上 WMI 与 PDH 采集的序列。
+	wmiStalePrefix = "wmi:"
+)
+
+// wmiPropertyReplacer 将计数器名称转换为 WMI 性能数据类的属性名称，
+// 例如 "% Processor Time" 对应 "PercentProcessorTime"，"Disk Reads/sec" 对应 "DiskReadsPersec"。
+var wmiPropertyReplacer = strings.NewReplacer("%", "Percent", "/", "Per")
+
+// validateProviders 校验所有对象的 Provider 配置。
+func (m *WinPerfCounters) validateProviders(objects []ObjectConfig) error {
+	for i := range objects {
+		if err := objects[i].validateProvider(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateProvider 校验对象的 Provider 与 WMIClass 配置。
+func (o *ObjectConfig) validateProvider() error {
+	switch strings.ToLower(o.Provider) {
+	case "", providerPDH:
+		return nil
+	case providerWMI:
+		if o.WMIClass == "" {
+			return fmt.Errorf("object %q uses the WMI provider but has no WMIClass configured", o.ObjectName)
+		}
+		return nil
+	case providerRegistry:
+		return nil
+	}
+	return fmt.Errorf("invalid provider %q of object %q, expected %q, %q or %q", o.Provider, o.ObjectName, providerPDH, providerWMI, providerRegistry)
+}
+
+// usesWMI 判断对象是否通过 WMI 采集。
+func (o *ObjectConfig) usesWMI() bool {
+	return strings.EqualFold(o.Provider, providerWMI)
+}
+
+// wmiClassName 返回采集使用的 WMI 类，采集原始值时将格式化数据类替换为对应的原始数据类。
+func (o *ObjectConfig) wmiClassName() string {
+	if o.UseRawValues {
+		return strings.Replace(o.WMIClass, "Win32_PerfFormattedData_", "Win32_PerfRawData_", 1)
+	}
+	return o.WMIClass
+}
+
+// wmiSingleton 判断对象是否为单实例对象，单实例的性能数据类没有 Name 属性。
+func (o *ObjectConfig) wmiSingleton() bool {
+	return len(o.Instances) == 0 || (len(o.Instances) == 1 && o.Instances[0] == emptyInstance)
+}
+
+// wmiPropertyName 返回计数器对应的 WMI 属性名称。
+func wmiPropertyName(counterName string) string {
+	name := wmiPropertyReplacer.Replace(counterName)
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return -1
+	}, name)
+}
+
+// wmiQueryText 返回对象的 WQL 查询语句及需要读取的属性。
+func (o *ObjectConfig) wmiQueryText() (string, []string) {
+	properties := make([]string, 0, len(o.Counters)+1)
+	if !o.wmiSingleton() {
+		properties = append(properties, wmiInstanceProperty)
+	}
+	for _, counterName := range o.counterNames() {
+		properties = append(properties, wmiPropertyName(counterName))
+	}
+	return fmt.Sprintf("SELECT %s FROM %s", strings.Join(properties, ", "), o.wmiClassName()), properties
+}
+
+// acceptWMIInstance 按 Instances、InstancesExclude 与 IncludeTotal 判断是否采集实例 name，规则与 PDH 查询一致。
+func (o *ObjectConfig) acceptWMIInstance(name string) bool {
+	for _, queried := range o.queryInstances() {
+		var match bool
+		if queried == "*" {
+			match = o.IncludeTotal || !strings.Contains(name, "_Total")
+		} else {
+			match, _ = path.Match(strings.ToLower(queried), strings.ToLower(name))
+		}
+		if match && o.acceptInstance(queried, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// wmiFieldValue 将 WMI 属性值转换为与 PDH 相同的字段类型：原始值为 int64，格式化值为 float64。
+func wmiFieldValue(value interface{}, raw bool) (interface{}, bool) {
+	var i int64
+	var f float64
+	switch v := value.(type) {
+	case int64:
+		i, f = v, float64(v)
+	case uint64:
+		i, f = int64(v), float64(v) //nolint:gosec // G115: raw counter values fit in int64 as with PDH
+	case float64:
+		i, f = int64(v), v
+	case string:
+		// WMI 以字符串传递 64 位整数
+		if u, err := strconv.ParseUint(v, 10, 64); err == nil {
+			i, f = int64(u), float64(u) //nolint:gosec // G115: raw counter values fit in int64 as with PDH
+		} else if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
+			i, f = parsed, float64(parsed)
+		} else if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+			i, f = int64(parsed), parsed
+		} else {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	if raw {
+		return i, true
+	}
+	return f, true
+}
+
+// wmiObjects 按主机分组返回本次需要通过 WMI 采集的对象，模拟模式下所有对象都按 PDH 处理。
+func (m *WinPerfCounters) wmiObjects(due dueObjects) map[string][]*ObjectConfig {
+	if m.Simulate {
+		return nil
+	}
+	return m.providerObjects(due, (*ObjectConfig).usesWMI)
+}
+
+// providerObjects 按主机分组返回本次需要采集且满足 uses 的对象，跳过由其它进程采集或不满足条件的对象。
+func (m *WinPerfCounters) providerObjects(due dueObjects, uses func(*ObjectConfig) bool) map[string][]*ObjectConfig {
+	hosts := make(map[string][]*ObjectConfig)
+	profile := m.ActiveProfile()
+	for i := range m.Object {
+		object := &m.Object[i]
+		if !uses(object) || !object.inProfile(profile) || !due.contains(object) {
+			continue
+		}
+		computers := object.Sources
+		if len(computers) == 0 {
+			computers = m.hostSources()
+		}
+		for _, computer := range computers {
+			if computer == "" {
+				computer = "localhost"
+			}
+			if m.skipDuplicate(object, computer) || m.unmetObjects[objectHostKey{computer: computer, id: object.id}] {
+				continue
+			}
+			hosts[computer] = append(hosts[computer], object)
+		}
+	}
+	return hosts
+}
+
+// gatherWMIHost 通过 WMI 采集一个主机上的对象，输出与 PDH 采集相同的测量、字段和标签。
+// 读取失败的对象不影响同一主机上的其它对象，其错误合并后返回。
+func (m *WinPerfCounters) gatherWMIHost(ctx context.Context, computer string, objects []*ObjectConfig) error {
+	hostInfo := &hostCountersInfo{computer: computer, tag: computer, timestamp: time.Now()}
+	namespace := wmiNamespace
+	var user, password string
+	if computer == "localhost" {
+		hostInfo.tag = m.hostname()
+	} else {
+		namespace = `\\` + strings.TrimPrefix(computer, `\\`) + `\` + wmiNamespace
+		if credential := m.sourceCredential(computer); credential != nil {
+			user, password = credential.user(), string(credential.Password)
+		}
+	}
+
+	collectedFields := make(fieldGrouping)
+	groupObjects := make(map[instanceGrouping]*ObjectConfig)
+	gathered := make(dueObjects)
+	var failed []error
+	// 同一主机的对象在本 goroutine 中共用一次 COM 初始化和连接，连接失败时所有对象都视为读取失败
+	session, sessionErr := openWMISession(namespace, user, password)
+	if sessionErr == nil {
+		defer session.close()
+	}
+	for _, object := range objects {
+		var rows []map[string]interface{}
+		err := sessionErr
+		if err == nil {
+			rows, err = session.query(object.wmiQueryText())
+		}
+		if err != nil {
+			m.stats.incr(map[string]string{"source": hostInfo.tag, "objectname": object.ObjectName}, "read_errors", 1)
+			if err := m.checkError(wrapCounterError("read", computer, object.ObjectName, object.wmiClassName(), err)); err != nil {
+				failed = append(failed, err)
+			}
+			continue
+		}
+		gathered.add(object)
+		object.addWMIRows(computer, rows, collectedFields, groupObjects)
+	}
+	// 已放弃等待的采集不再输出数据
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	seen := make(seenSeries)
+	m.emitGroups(hostInfo, collectedFields, groupObjects, nil, seen)
+	m.emitStaleMarkers(wmiStalePrefix+computer, gathered, seen, hostInfo.timestamp)
+	m.reportMissingInstances(wmiStalePrefix+computer, gathered, seen, hostInfo.timestamp)
+	m.reportQuality(hostInfo, wmiStalePrefix+computer, gathered, seen, hostInfo.timestamp)
+	return errors.Join(failed...)
+}
+
+// addWMIRows 将 WMI 查询结果按实例添加到收集字段中，字段名称与 PDH 采集时相同。
+func (o *ObjectConfig) addWMIRows(computer string, rows []map[string]interface{}, collectFields fieldGrouping, groupObjects map[instanceGrouping]*ObjectConfig) {
+	measurement := o.providerMeasurement()
+
+	for _, row := range rows {
+		instance := ""
+		if !o.wmiSingleton() {
+			instance, _ = row[wmiInstanceProperty].(string)
+			if !o.acceptWMIInstance(instance) {
+				continue
+			}
+		}
+		fields := make(map[string]interface{})
+		for _, counterName := range o.counterNames() {
+			value, ok := wmiFieldValue(row[wmiPropertyName(counterName)], o.UseRawValues)
+			if !ok {
+				continue
+			}
+			fieldName := o.fieldName(counterName)
+			fields[fieldName] = value
+			if o.IncludeCounterPath {
+				pathInstance := instance
+				if pathInstance == "" {
+					pathInstance = emptyInstance
+				}
+				fields[fieldName+"_path"] = formatPath(computer, o.ObjectName, pathInstance, counterName)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		grouping := instanceGrouping{measurement, instance, o.ObjectName}
+		collectFields[grouping] = fields
+		groupObjects[grouping] = o
+	}
+}
+
+// providerMeasurement 返回不经过 PDH 查询采集的对象的测量名称，与 PDH 采集时相同。
+func (o *ObjectConfig) providerMeasurement() string {
+	measurement := o.NameOverride
+	if measurement == "" {
+		measurement = o.sanitizeName(o.Measurement)
+	}
+	if measurement == "" {
+		measurement = "win_perf_counters"
+	}
+	return measurement
+}
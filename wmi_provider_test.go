@@ -0,0 +1,93 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWMIPropertyName(t *testing.T) {
+	tests := map[string]string{
+		"% Processor Time":          "PercentProcessorTime",
+		"Disk Reads/sec":            "DiskReadsPersec",
+		"Avg. Disk sec/Read":        "AvgDisksecPerRead",
+		"Pages Input/sec":           "PagesInputPersec",
+		"Current Disk Queue Length": "CurrentDiskQueueLength",
+		"IO_Other_Bytes":            "IO_Other_Bytes",
+	}
+	for counter, want := range tests {
+		require.Equal(t, want, wmiPropertyName(counter), counter)
+	}
+}
+
+func TestWMIQueryText(t *testing.T) {
+	object := &ObjectConfig{
+		WMIClass:  "Win32_PerfFormattedData_PerfDisk_LogicalDisk",
+		Counters:  []string{"% Idle Time", "Disk Reads/sec"},
+		Instances: []string{"*"},
+	}
+	query, properties := object.wmiQueryText()
+	require.Equal(t, "SELECT Name, PercentIdleTime, DiskReadsPersec FROM Win32_PerfFormattedData_PerfDisk_LogicalDisk", query)
+	require.Equal(t, []string{"Name", "PercentIdleTime", "DiskReadsPersec"}, properties)
+
+	// 单实例对象没有 Name 属性，采集原始值时使用原始数据类
+	object = &ObjectConfig{
+		WMIClass:     "Win32_PerfFormattedData_PerfOS_Memory",
+		Counters:     []string{"Available Bytes"},
+		Instances:    []string{emptyInstance},
+		UseRawValues: true,
+	}
+	query, properties = object.wmiQueryText()
+	require.Equal(t, "SELECT AvailableBytes FROM Win32_PerfRawData_PerfOS_Memory", query)
+	require.Equal(t, []string{"AvailableBytes"}, properties)
+}
+
+func TestWMIFieldValue(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		raw     bool
+		want    interface{}
+		wantErr bool
+	}{
+		{int64(-5), false, float64(-5), false},
+		{int64(-5), true, int64(-5), false},
+		{uint64(7), true, int64(7), false},
+		{1.5, false, 1.5, false},
+		{1.5, true, int64(1), false},
+		{"18446744073709551615", false, float64(18446744073709551615), false},
+		{"-12", true, int64(-12), false},
+		{"2.5", false, 2.5, false},
+		{"n/a", false, nil, true},
+		{true, false, nil, true},
+	}
+	for _, tt := range tests {
+		got, ok := wmiFieldValue(tt.value, tt.raw)
+		require.Equal(t, !tt.wantErr, ok, "%v", tt.value)
+		require.Equal(t, tt.want, got, "%v", tt.value)
+	}
+}
+
+func TestAddWMIRows(t *testing.T) {
+	object := &ObjectConfig{
+		ObjectName:  "LogicalDisk",
+		Measurement: "win_disk",
+		Counters:    []string{"% Idle Time"},
+		Instances:   []string{"*"},
+	}
+	rows := []map[string]interface{}{
+		{"Name": "C:", "PercentIdleTime": "97"},
+		{"Name": "_Total", "PercentIdleTime": "98"},
+		{"Name": "D:"},
+	}
+	collected := make(fieldGrouping)
+	groupObjects := make(map[instanceGrouping]*ObjectConfig)
+	object.addWMIRows("localhost", rows, collected, groupObjects)
+
+	// 未配置 IncludeTotal 时不采集 _Total，没有任何字段的实例被忽略
+	grouping := instanceGrouping{"win_disk", "C:", "LogicalDisk"}
+	require.Len(t, collected, 1)
+	require.Equal(t, map[string]interface{}{object.fieldName("% Idle Time"): float64(97)}, collected[grouping])
+	require.Same(t, object, groupObjects[grouping])
+}
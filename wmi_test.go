@@ -0,0 +1,54 @@
+//go:build windows
+
+package win_perf_counters
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariantValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value variant
+		want  interface{}
+	}{
+		{"empty", variant{VT: vtEmpty}, nil},
+		{"null", variant{VT: vtNull}, nil},
+		{"int8", variant{VT: vtI1, Val: 0xFF}, int64(-1)},
+		{"int16", variant{VT: vtI2, Val: 0xFFFE}, int64(-2)},
+		{"int32", variant{VT: vtI4, Val: 0xFFFFFFFD}, int64(-3)},
+		{"int64", variant{VT: vtI8, Val: -4}, int64(-4)},
+		{"uint8", variant{VT: vtUI1, Val: 0x1FF}, uint64(0xFF)},
+		{"uint16", variant{VT: vtUI2, Val: 0x1FFFF}, uint64(0xFFFF)},
+		{"uint32", variant{VT: vtUI4, Val: 0x1FFFFFFFF}, uint64(0xFFFFFFFF)},
+		{"uint64", variant{VT: vtUI8, Val: -1}, uint64(math.MaxUint64)},
+		{"float32", variant{VT: vtR4, Val: int64(math.Float32bits(1.5))}, 1.5},
+		{"float64", variant{VT: vtR8, Val: int64(math.Float64bits(2.25))}, 2.25},
+		{"true", variant{VT: vtBool, Val: 0xFFFF}, true},
+		{"false", variant{VT: vtBool}, false},
+		{"unsupported", variant{VT: 0x2000}, nil},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, tt.value.value(), tt.name)
+	}
+}
+
+func TestCheckHResult(t *testing.T) {
+	require.NoError(t, checkHResult("CoInitializeEx", 0))
+	// S_FALSE 表示线程已经初始化过 COM，不是错误
+	require.NoError(t, checkHResult("CoInitializeEx", 1))
+	require.EqualError(t, checkHResult("IWbemServices::ExecQuery", 0x80041017), "IWbemServices::ExecQuery failed with HRESULT 0x80041017")
+}
+
+func TestBSTR(t *testing.T) {
+	s, err := bstr("")
+	require.NoError(t, err)
+	require.Zero(t, s)
+	freeBSTR(0)
+
+	_, err = bstr("a\x00b")
+	require.Error(t, err)
+}